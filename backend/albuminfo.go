@@ -0,0 +1,208 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// AlbumInfoEnricher fills in the album notes supplied by the server, if empty,
+// with the album's wiki from Last.fm or its MusicBrainz annotation.
+// The external notes are cached on disk so that repeat visits to
+// an album don't query the external services again.
+type AlbumInfoEnricher struct {
+	sm       *ServerManager
+	cfg      *AppConfig
+	cacheDir string
+
+	mu sync.Mutex // guards the on-disk cache
+}
+
+// externalAlbumInfo is the info about an album fetched from
+// external services, as cached on disk.
+type externalAlbumInfo struct {
+	Fetched       time.Time `json:"fetched"`
+	Notes         string    `json:"notes"`
+	LastFMURL     string    `json:"lastFmUrl"`
+	MusicBrainzID string    `json:"musicBrainzId"`
+	Source        string    `json:"source"`
+}
+
+func NewAlbumInfoEnricher(sm *ServerManager, cfg *AppConfig, cacheDir string) *AlbumInfoEnricher {
+	return &AlbumInfoEnricher{sm: sm, cfg: cfg, cacheDir: cacheDir}
+}
+
+// GetAlbumInfo returns the server's info for the album, with the notes
+// from an external service if the server had none.
+func (e *AlbumInfoEnricher) GetAlbumInfo(albumID string) (*mediaprovider.AlbumInfo, error) {
+	mp := e.sm.Server
+	if mp == nil {
+		return nil, errors.New("not connected to a server")
+	}
+	info, err := mp.GetAlbumInfo(albumID)
+	if err != nil {
+		log.Printf("error getting album info from server: %v", err)
+		info = &mediaprovider.AlbumInfo{}
+	}
+	if info.Notes != "" {
+		return info, nil
+	}
+	album, alErr := mp.GetAlbum(albumID)
+	if alErr != nil {
+		if err == nil {
+			err = alErr
+		}
+		return nil, err
+	}
+	var artistName string
+	if len(album.ArtistNames) > 0 {
+		artistName = album.ArtistNames[0]
+	}
+
+	ext := e.externalInfo(album.Name, artistName, info.MusicBrainzID)
+	if info.LastFmUrl == "" {
+		info.LastFmUrl = ext.LastFMURL
+	}
+	if info.MusicBrainzID == "" {
+		info.MusicBrainzID = ext.MusicBrainzID
+	}
+	if ext.Notes != "" {
+		info.Notes = ext.Notes
+		info.Sources = []string{ext.Source}
+	}
+	return info, nil
+}
+
+// externalInfo returns the external info for the album,
+// from the on-disk cache if it hasn't expired.
+func (e *AlbumInfoEnricher) externalInfo(albumName, artistName, mbid string) *externalAlbumInfo {
+	lang := infoLanguage(e.cfg)
+	path := externalInfoCachePath(e.cacheDir, lang, artistName, albumName)
+	var cached externalAlbumInfo
+	e.mu.Lock()
+	haveCached := readCachedJSON(path, &cached)
+	e.mu.Unlock()
+	if haveCached && time.Since(cached.Fetched) < externalInfoCacheTTL {
+		return &cached
+	}
+
+	ext := &externalAlbumInfo{Fetched: time.Now(), MusicBrainzID: mbid}
+	var fetchErr error
+	if key := e.cfg.LastFMAPIKey; key != "" && artistName != "" {
+		if err := fetchLastFMAlbumInfo(key, albumName, artistName, lang, ext); err != nil {
+			log.Printf("error fetching album info from Last.fm: %v", err)
+			fetchErr = err
+		} else if ext.Notes != "" {
+			ext.Source = InfoSourceLastFM
+		}
+	}
+	if ext.Notes == "" {
+		if err := fetchMusicBrainzAnnotation(albumName, artistName, ext); err != nil {
+			log.Printf("error fetching album annotation from MusicBrainz: %v", err)
+			fetchErr = err
+		} else if ext.Notes != "" {
+			ext.Source = InfoSourceMusicBrainz
+		}
+	}
+	if fetchErr != nil {
+		// don't cache, so it can be retried, but keep showing the stale info
+		if haveCached {
+			return &cached
+		}
+		return ext
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := writeCachedJSON(path, ext); err != nil {
+		log.Printf("error caching album info: %v", err)
+	}
+	return ext
+}
+
+func fetchLastFMAlbumInfo(apiKey, albumName, artistName, lang string, ext *externalAlbumInfo) error {
+	var parsed struct {
+		Album struct {
+			URL  string `json:"url"`
+			MBID string `json:"mbid"`
+			Wiki struct {
+				Summary string `json:"summary"`
+				Content string `json:"content"`
+			} `json:"wiki"`
+		} `json:"album"`
+	}
+	err := lastFMRequest(apiKey, url.Values{
+		"method":      {"album.getinfo"},
+		"album":       {albumName},
+		"artist":      {artistName},
+		"autocorrect": {"1"},
+		"lang":        {lang},
+	}, &parsed)
+	if err != nil {
+		return err
+	}
+	al := parsed.Album
+	ext.LastFMURL = al.URL
+	if ext.MusicBrainzID == "" {
+		ext.MusicBrainzID = al.MBID
+	}
+	ext.Notes = al.Wiki.Content
+	if ext.Notes == "" {
+		ext.Notes = al.Wiki.Summary
+	}
+	return nil
+}
+
+// fetchMusicBrainzAnnotation looks up the annotation of the album's release,
+// first looking up the release if its MusicBrainz ID isn't known.
+func fetchMusicBrainzAnnotation(albumName, artistName string, ext *externalAlbumInfo) error {
+	if ext.MusicBrainzID == "" {
+		var parsed struct {
+			Releases []struct {
+				ID    string `json:"id"`
+				Score int    `json:"score"`
+			} `json:"releases"`
+		}
+		query := fmt.Sprintf("release:%q", albumName)
+		if artistName != "" {
+			query += fmt.Sprintf(" AND artist:%q", artistName)
+		}
+		err := musicBrainzRequest("release", url.Values{
+			"query": {query},
+			"limit": {"1"},
+		}, &parsed)
+		if err != nil {
+			return err
+		}
+		// only trust a confident match
+		if len(parsed.Releases) == 0 || parsed.Releases[0].Score < 90 {
+			return nil
+		}
+		ext.MusicBrainzID = parsed.Releases[0].ID
+	}
+
+	var parsed struct {
+		Annotations []struct {
+			Entity string `json:"entity"`
+			Text   string `json:"text"`
+		} `json:"annotations"`
+	}
+	err := musicBrainzRequest("annotation", url.Values{
+		"query": {"entity:" + ext.MusicBrainzID},
+	}, &parsed)
+	if err != nil {
+		return err
+	}
+	for _, a := range parsed.Annotations {
+		if a.Entity == ext.MusicBrainzID {
+			ext.Notes = a.Text
+			break
+		}
+	}
+	return nil
+}
@@ -10,17 +10,20 @@ import (
 	"path/filepath"
 	"reflect"
 	"slices"
+	"sync"
 	"time"
 
 	"github.com/dweymouth/supersonic/backend/ipc"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
 	"github.com/dweymouth/supersonic/backend/player"
 	"github.com/dweymouth/supersonic/backend/player/mpv"
+	"github.com/dweymouth/supersonic/backend/remote"
+	"github.com/dweymouth/supersonic/backend/tagwriter"
 	"github.com/dweymouth/supersonic/backend/util"
 	"github.com/google/uuid"
 
 	"github.com/20after4/configdir"
-	"github.com/zalando/go-keyring"
 )
 
 const (
@@ -28,6 +31,10 @@ const (
 	portableDir    = "supersonic_portable"
 	savedQueueFile = "saved_queue.json"
 	themesDir      = "themes"
+
+	// settings applied in large library mode
+	largeLibraryPageSize              = 100
+	largeLibraryMaxInMemoryThumbnails = 500
 )
 
 var (
@@ -37,13 +44,53 @@ var (
 
 type App struct {
 	Config          *Config
+	Credentials     CredentialStore
 	ServerManager   *ServerManager
 	ImageManager    *ImageManager
 	PlaybackManager *PlaybackManager
+	SleepTimer      *SleepTimer
+	Renderers       *RendererManager
+	EventBus        *EventBus
+	Metrics         *Metrics
+	RequestLog      *RequestLog
+	ContentFilter   *mediaprovider.ContentFilter
+	FavoritesCache  *FavoritesCache
+	Genres          *GenreMapper
+	RatingFavWriter *RatingFavoriteWriter
 	LocalPlayer     *mpv.Player
+	NetworkMonitor  *NetworkMonitor
+	Equalizer       *EqualizerManager
+	AudioOutput     *AudioOutputManager
+	Snapcast        *SnapcastOutput
+	AudioEvents     *AudioEventsManager
 	UpdateChecker   UpdateChecker
 	MPRISHandler    *MPRISHandler
 	ipcServer       ipc.IPCServer
+	remoteServer    *remote.Server
+	DiscordPresence *DiscordPresence
+	NowPlaying      *NowPlayingBroadcaster
+	CoverPalettes   *CoverPalettes
+	History         *ListeningHistory
+	SearchHistory   *SearchHistory
+	ArtistInfo      *ArtistInfoEnricher
+	AlbumInfo       *AlbumInfoEnricher
+	SmartPlaylists  *SmartPlaylistManager
+	Rediscover      *RediscoverGenerator
+	PlaylistFolders *PlaylistOrganizer
+	RadioSeeds      *RadioSeedCache
+	HomeSections    *HomeSectionsManager
+	NewMusicWatcher *NewMusicWatcher
+	TrackCache      *TrackCache
+	Waveforms       *WaveformGenerator
+	Visualizer      *Visualizer
+	ChangePoller    *ChangePoller
+	ScanMonitor     *ScanMonitor
+	Downloads       *DownloadManager
+	Lyrics          *LyricsFetcher
+	BatchOps        *BatchOperations
+	PlaylistTracks  *PlaylistTracksCache
+	queueAutosaver  *queueAutosaver
+	coverArtServer  *coverArtServer
 
 	// UI callbacks to be set in main
 	OnReactivate func()
@@ -59,6 +106,9 @@ type App struct {
 	bgrndCtx      context.Context
 	cancel        context.CancelFunc
 
+	downloadAlbumMu sync.Mutex
+	downloadAlbum   *mediaprovider.Album // cached by downloadTagsAlbum
+
 	lastWrittenCfg Config
 }
 
@@ -108,6 +158,10 @@ func StartupApp(appName, displayAppName, appVersionTag, latestReleaseURL string)
 	log.Printf("Using config dir: %s", confDir)
 	log.Printf("Using cache dir: %s", cacheDir)
 
+	if err := migrateLocalData(a.configDir, localDataStores); err != nil {
+		log.Printf("error checking local data: %v", err)
+	}
+
 	a.UpdateChecker = NewUpdateChecker(appVersionTag, latestReleaseURL, &a.Config.Application.LastCheckedVersion)
 	a.UpdateChecker.Start(a.bgrndCtx, 24*time.Hour)
 
@@ -118,21 +172,65 @@ func StartupApp(appName, displayAppName, appVersionTag, latestReleaseURL string)
 		return nil, err
 	}
 
-	a.ServerManager = NewServerManager(appName, a.Config, !portableMode /*use keyring*/)
-	a.PlaybackManager = NewPlaybackManager(a.bgrndCtx, a.ServerManager, a.LocalPlayer, &a.Config.Scrobbling, &a.Config.Transcoding)
+	a.Metrics = NewMetrics()
+	a.Metrics.SetSlowRequestThreshold(time.Duration(a.Config.Application.SlowRequestLogMillis) * time.Millisecond)
+	a.Credentials = NewCredentialStore(appName, !portableMode /*use keyring*/)
+	if !portableMode {
+		a.Config.RemoteControl.useCredentialStore(a.Credentials)
+	}
+	a.ServerManager = NewServerManager(appName, a.Config, a.Credentials)
+	a.ServerManager.SetMetrics(a.Metrics)
+	a.RequestLog = NewRequestLog()
+	a.RequestLog.SetEnabled(a.Config.Application.EnableRequestLog)
+	a.ServerManager.SetRequestLog(a.RequestLog)
+	a.ServerManager.SetCacheDir(cacheDir)
+	a.ContentFilter = mediaprovider.NewContentFilter(a.Config.ContentFilter.Options())
+	a.ServerManager.SetContentFilter(a.ContentFilter)
+	a.NetworkMonitor = NewNetworkMonitor(&a.Config.Transcoding)
+	a.ServerManager.SetNetworkMonitor(a.NetworkMonitor)
+	a.LocalPlayer.OnBufferUnderrun(a.NetworkMonitor.ReportUnderrun)
+	a.PlaybackManager = NewPlaybackManager(a.bgrndCtx, a.ServerManager, a.LocalPlayer, &a.Config.Scrobbling, &a.Config.Transcoding, &a.Config.TrackFade)
+	a.SleepTimer = NewSleepTimer(a.PlaybackManager, &a.Config.SleepTimer)
+	a.AudioEvents = NewAudioEventsManager(&a.Config.AudioEvents, a.PlaybackManager, a.LocalPlayer, a.AudioOutput)
+	if err := a.AudioEvents.Apply(); err != nil {
+		log.Printf("error watching system audio events: %v", err)
+	}
+	a.TrackCache = NewTrackCache(a.bgrndCtx, a.ServerManager, a.PlaybackManager,
+		&a.Config.LocalPlayback, path.Join(cacheDir, "tracks"))
+	a.PlaybackManager.engine.trackCache = a.TrackCache
+	a.Waveforms = NewWaveformGenerator(a.bgrndCtx, a.ServerManager, a.PlaybackManager,
+		&a.Config.Waveform, a.TrackCache, path.Join(cacheDir, "waveforms"))
+	a.Config.Visualizer.FrameRate = clamp(a.Config.Visualizer.FrameRate, 1, 60)
+	a.Config.Visualizer.Bands = clamp(a.Config.Visualizer.Bands, 1, 128)
+	a.Visualizer = NewVisualizer(a.bgrndCtx, a.ServerManager, a.PlaybackManager,
+		&a.Config.Visualizer, a.TrackCache, path.Join(cacheDir, "visualizer"))
+	a.Renderers = NewRendererManager(a.PlaybackManager, a.ServerManager, a.LocalPlayer)
 	a.ImageManager = NewImageManager(a.bgrndCtx, a.ServerManager, cacheDir)
+	a.EventBus = NewEventBus()
+	a.FavoritesCache = NewFavoritesCache(a.ServerManager, a.EventBus)
+	a.Genres = NewGenreMapper(a.ServerManager, &a.Config.Genres)
+	a.RatingFavWriter = NewRatingFavoriteWriter(a.bgrndCtx, a.ServerManager, a.PlaybackManager, a.FavoritesCache, a.EventBus)
 	a.Config.Application.MaxImageCacheSizeMB = clamp(a.Config.Application.MaxImageCacheSizeMB, 1, 500)
 	a.ImageManager.SetMaxOnDiskCacheSizeBytes(int64(a.Config.Application.MaxImageCacheSizeMB) * 1_048_576)
+	a.ImageManager.SetMetrics(a.Metrics)
 	a.ServerManager.SetPrefetchAlbumCoverCallback(func(coverID string) {
+		if a.Config.Application.LargeLibraryMode {
+			return // covers are loaded on demand as they are scrolled into view
+		}
 		_, _ = a.ImageManager.GetCoverThumbnail(coverID)
 	})
+	if a.Config.Application.LargeLibraryMode {
+		helpers.IteratorPageSize = largeLibraryPageSize
+		a.ImageManager.SetMaxInMemoryThumbnails(largeLibraryMaxInMemoryThumbnails)
+	}
 
 	// Start IPC server if another not already running in a different instance
 	if cli == nil {
 		ipc.DestroyConn() // cleanup socket possibly orphaned by crashed process
 		listener, err := ipc.Listen()
 		if err == nil {
-			a.ipcServer = ipc.NewServer(a.PlaybackManager, a.callOnReactivate,
+			a.ipcServer = ipc.NewServer(a.PlaybackManager,
+				&ipcQueueHandler{sm: a.ServerManager, pm: a.PlaybackManager}, a.callOnReactivate,
 				func() { _ = a.callOnExit() })
 			go a.ipcServer.Serve(listener)
 		} else {
@@ -140,12 +238,43 @@ func StartupApp(appName, displayAppName, appVersionTag, latestReleaseURL string)
 		}
 	}
 
+	a.Downloads = NewDownloadManager(a.bgrndCtx, a.ServerManager, a.ImageManager, &a.Config.Downloads,
+		func(tr *mediaprovider.Track) *tagwriter.Tags {
+			if !a.Config.Application.EmbedTagsInDownloads {
+				return nil
+			}
+			return a.DownloadTags(tr)
+		})
+	a.Lyrics = NewLyricsFetcher(a.ServerManager, a.TrackCache, a.Downloads, &a.Config.Application)
+	a.coverArtServer = newCoverArtServer(a.ImageManager)
+	a.CoverPalettes = NewCoverPalettes(a.ImageManager)
+	a.NowPlaying = NewNowPlayingBroadcaster(a.PlaybackManager, a.ServerManager, a.ImageManager, a.coverArtServer, a.Lyrics, a.CoverPalettes)
+	a.startRemoteControlServer()
+	a.setupRemoteSession()
+	a.DiscordPresence = NewDiscordPresence(a.bgrndCtx, a.PlaybackManager, a.NowPlaying, &a.Config.DiscordRPC)
+	a.History = NewListeningHistory(path.Join(a.configDir, listeningHistoryFile), a.ServerManager)
+	a.History.SetupRecording(a.PlaybackManager, func() bool { return a.Config.Application.RecordListeningHistory })
+	a.SearchHistory = NewSearchHistory(path.Join(a.configDir, searchHistoryFile), a.ServerManager, a.FavoritesCache)
+	a.ArtistInfo = NewArtistInfoEnricher(a.ServerManager, &a.Config.Application, path.Join(cacheDir, "artistinfo"))
+	a.AlbumInfo = NewAlbumInfoEnricher(a.ServerManager, &a.Config.Application, path.Join(cacheDir, "albuminfo"))
+	a.queueAutosaver = newQueueAutosaver(a.bgrndCtx, a.PlaybackManager, a.ServerManager,
+		path.Join(a.configDir, savedQueueFile), func() bool { return a.Config.Application.SavePlayQueue })
+	a.SmartPlaylists = NewSmartPlaylistManager(a.ServerManager, a.History, a.Config)
+	a.Rediscover = NewRediscoverGenerator(a.ServerManager, a.History)
+	a.PlaylistFolders = NewPlaylistOrganizer(a.ServerManager, a.Config)
+	a.ServerManager.OnServerConnected(func() { go a.SmartPlaylists.RefreshMaterialized() })
+	a.RadioSeeds = NewRadioSeedCache(path.Join(a.configDir, radioSeedsFile), a.ServerManager, &a.Config.Radio)
+	a.PlaybackManager.radioSeeds = a.RadioSeeds
+	a.HomeSections = NewHomeSectionsManager(a.ServerManager, a.FavoritesCache, a.History, &a.Config.Home)
+	a.NewMusicWatcher = NewNewMusicWatcher(a.bgrndCtx, a.ServerManager, a.EventBus)
+	a.ChangePoller = NewChangePoller(a.bgrndCtx, a.ServerManager, a.FavoritesCache, a.EventBus, &a.Config.Application)
+	a.ScanMonitor = NewScanMonitor(a.bgrndCtx, a.ServerManager, a.EventBus)
+	a.PlaylistTracks = NewPlaylistTracksCache(a.ServerManager, a.EventBus)
+	a.BatchOps = NewBatchOperations(a.ServerManager, a.PlaylistTracks)
+
 	// OS media center integrations
 	a.setupMPRIS(displayAppName)
-	InitMPMediaHandler(a.PlaybackManager, func(id string) (string, error) {
-		a.ImageManager.GetCoverThumbnail(id) // ensure image is cached locally
-		return a.ImageManager.GetCoverArtUrl(id)
-	})
+	InitMPMediaHandler(a.PlaybackManager, a.NowPlaying)
 
 	a.startConfigWriter(a.bgrndCtx)
 
@@ -243,27 +372,15 @@ func (a *App) setupMPV() error {
 	a.Config.LocalPlayback.Volume = clamp(a.Config.LocalPlayback.Volume, 0, 100)
 	a.LocalPlayer.SetVolume(a.Config.LocalPlayback.Volume)
 
-	devs, err := a.LocalPlayer.ListAudioDevices()
-	if err != nil {
+	a.Equalizer = NewEqualizerManager(&a.Config.LocalPlayback, a.LocalPlayer)
+	a.AudioOutput = NewAudioOutputManager(&a.Config.LocalPlayback, a.LocalPlayer, a.Equalizer)
+	if err := a.AudioOutput.checkAvailabilityWithErr(); err != nil {
 		return err
 	}
-
-	desiredDevice := a.Config.LocalPlayback.AudioDeviceName
-	var desiredDeviceAvailable bool
-	for _, dev := range devs {
-		if dev.Name == desiredDevice {
-			desiredDeviceAvailable = true
-			break
-		}
-	}
-	if !desiredDeviceAvailable {
-		// The audio device the user has configured is not available.
-		// Use the default (autoselect) device but leave the setting unchanged,
-		// in case the device is later available on a subsequent run of the app
-		// (e.g. a USB audio device that is currently unplugged)
-		desiredDevice = "auto"
+	a.Snapcast = NewSnapcastOutput(&a.Config.Snapcast, a.LocalPlayer)
+	if err := a.Snapcast.Apply(); err != nil {
+		log.Printf("error starting Snapcast output: %v", err)
 	}
-	a.LocalPlayer.SetAudioDevice(desiredDevice)
 
 	rgainOpts := []string{ReplayGainNone, ReplayGainAlbum, ReplayGainTrack, ReplayGainAuto}
 	if !slices.Contains(rgainOpts, a.Config.ReplayGain.Mode) {
@@ -285,22 +402,29 @@ func (a *App) setupMPV() error {
 		PreampGain:      a.Config.ReplayGain.PreampGainDB,
 	})
 	a.LocalPlayer.SetAudioExclusive(a.Config.LocalPlayback.AudioExclusive)
+	a.LocalPlayer.SetBitPerfect(a.Config.LocalPlayback.BitPerfect)
+	a.LocalPlayer.SetOutputLimiter(a.Config.LocalPlayback.OutputLimiter)
+	a.LocalPlayer.SetMonoDownmix(a.Config.LocalPlayback.MonoDownmix)
+	a.Config.SkipSilence.MaxGapSeconds = max(a.Config.SkipSilence.MaxGapSeconds, 0)
+	a.LocalPlayer.SetSkipSilenceOptions(player.SkipSilenceOptions{
+		Enabled:       a.Config.SkipSilence.Enabled,
+		ThresholdDB:   a.Config.SkipSilence.ThresholdDB,
+		MaxGapSeconds: a.Config.SkipSilence.MaxGapSeconds,
+	})
 
-	eq := &mpv.ISO15BandEqualizer{
-		EQPreamp: a.Config.LocalPlayback.EqualizerPreamp,
-		Disabled: !a.Config.LocalPlayback.EqualizerEnabled,
-	}
-	copy(eq.BandGains[:], a.Config.LocalPlayback.GraphicEqualizerBands)
-	a.LocalPlayer.SetEqualizer(eq)
+	a.Equalizer.Apply()
 
 	return nil
 }
 
 func (a *App) setupMPRIS(mprisAppName string) {
-	a.MPRISHandler = NewMPRISHandler(mprisAppName, a.PlaybackManager)
-	a.MPRISHandler.ArtURLLookup = func(id string) (string, error) {
-		a.ImageManager.GetCoverThumbnail(id) // ensure image is cached locally
-		return a.ImageManager.GetCoverArtUrl(id)
+	a.MPRISHandler = NewMPRISHandler(mprisAppName, a.PlaybackManager, a.NowPlaying)
+	a.MPRISHandler.ArtURLLookup = a.coverArtServer.ArtURL
+	a.MPRISHandler.PlaylistsLookup = func() ([]*mediaprovider.Playlist, error) {
+		if a.ServerManager.Server == nil {
+			return nil, nil
+		}
+		return a.ServerManager.Server.GetPlaylists()
 	}
 	a.MPRISHandler.OnRaise = func() error { a.callOnReactivate(); return nil }
 	a.MPRISHandler.OnQuit = a.callOnExit
@@ -312,9 +436,9 @@ func (a *App) LoginToDefaultServer(string) error {
 	if serverCfg == nil {
 		return ErrNoServers
 	}
-	pass, err := keyring.Get(a.appName, serverCfg.ID.String())
+	pass, err := a.ServerManager.GetServerPassword(serverCfg.ActiveAccount().ID)
 	if err != nil {
-		return fmt.Errorf("error reading keyring credentials: %v", err)
+		return fmt.Errorf("error reading saved credentials: %v", err)
 	}
 	return a.ServerManager.ConnectToServer(serverCfg, pass)
 }
@@ -329,25 +453,34 @@ func (a *App) Shutdown() {
 	if a.ipcServer != nil {
 		a.ipcServer.Shutdown(a.bgrndCtx)
 	}
+	if a.remoteServer != nil {
+		a.remoteServer.Shutdown(a.bgrndCtx)
+	}
 	a.MPRISHandler.Shutdown()
+	a.coverArtServer.Shutdown(a.bgrndCtx)
 	a.PlaybackManager.DisableCallbacks()
 	if a.Config.Application.SavePlayQueue {
 		var queueServer mediaprovider.CanSavePlayQueue = nil
 		if a.Config.Application.SaveQueueToServer {
-			if qs, ok := a.ServerManager.Server.(mediaprovider.CanSavePlayQueue); ok {
+			if qs, ok := mediaprovider.As[mediaprovider.CanSavePlayQueue](a.ServerManager.Server); ok {
 				queueServer = qs
 			}
 		}
-		SavePlayQueue(a.ServerManager.ServerID.String(), a.PlaybackManager, path.Join(a.configDir, savedQueueFile), queueServer)
+		SavePlayQueue(a.ServerManager.DataKey(), a.PlaybackManager, path.Join(a.configDir, savedQueueFile), queueServer)
 	}
+	a.AudioEvents.Shutdown()
 	a.PlaybackManager.Stop() // will trigger scrobble check
+	a.Renderers.Shutdown()
 	a.Config.LocalPlayback.Volume = a.LocalPlayer.GetVolume()
 	a.cancel()
 	a.LocalPlayer.Destroy()
+	a.Snapcast.Shutdown()
 	a.Config.WriteConfigFile(a.configFilePath())
+	a.applyPendingImport()
 }
 
 func (a *App) LoadSavedPlayQueue() error {
+	defer a.queueAutosaver.SetRestored()
 	queueFilePath := path.Join(a.configDir, savedQueueFile)
 	queue, err := LoadPlayQueue(queueFilePath, a.ServerManager, a.Config.Application.SaveQueueToServer)
 	if err != nil {
@@ -364,12 +497,17 @@ func (a *App) LoadSavedPlayQueue() error {
 	if err := a.PlaybackManager.LoadTracks(queue.Tracks, Replace, false); err != nil {
 		return err
 	}
+	a.PlaybackManager.SetLoopMode(queue.LoopMode)
 	if queue.TrackIndex >= 0 && queue.TrackIndex < len(queue.Tracks) {
 		// TODO: This isn't ideal but doesn't seem to cause an audible play-for-a-split-second artifact
 		a.PlaybackManager.PlayTrackAt(queue.TrackIndex)
 		a.PlaybackManager.Pause()
 		time.Sleep(100 * time.Millisecond) // MPV seek fails if run quickly after
 		a.PlaybackManager.SeekSeconds(queue.TimePos)
+		if queue.WasPlaying && a.Config.Application.ResumePlaybackOnStartup {
+			// app was closed (or crashed) while playing
+			a.PlaybackManager.Continue()
+		}
 	}
 	return nil
 }
@@ -398,6 +536,17 @@ func (a *App) checkFlagsAndSendIPCMsg(cli *ipc.Client) error {
 		return cli.SetVolume(VolumeCLIArg)
 	case SeekToCLIArg >= 0:
 		return cli.SeekSeconds(SeekToCLIArg)
+	case *FlagNowPlaying:
+		np, err := cli.NowPlaying()
+		if err != nil {
+			return err
+		}
+		printNowPlaying(np)
+		return nil
+	case *FlagEnqueueAlbum != "":
+		return cli.EnqueueBySearch(ipc.EnqueueKindAlbum, *FlagEnqueueAlbum, *FlagEnqueueMode)
+	case *FlagEnqueuePlaylist != "":
+		return cli.EnqueueBySearch(ipc.EnqueueKindPlaylist, *FlagEnqueuePlaylist, *FlagEnqueueMode)
 	default:
 		return nil
 	}
@@ -0,0 +1,174 @@
+package backend
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	appDataManifestFile = "manifest.json"
+	// dir (in the config dir) an imported archive is staged in until shutdown
+	pendingImportDir = "pending_import"
+	// dir (in the config dir) the files replaced by an import are moved to
+	preImportBackupDir = "backup_before_import"
+)
+
+// appDataFiles are the files of the config dir included in an app data
+// archive: the settings (including per-server settings, smart playlists,
+// and playlist folders), the versioned local data stores (listening and
+// search history, the saved play queue, and radio seeds) and their versions.
+// Caches can be rebuilt and aren't included. Server passwords are kept
+// in the OS keychain and aren't included either.
+var appDataFiles = append([]string{configFile, dataVersionsFile}, localDataStoreNames()...)
+
+type appDataManifest struct {
+	AppName    string
+	AppVersion string
+	ExportedAt time.Time
+	Files      []string
+}
+
+// ExportAppData writes a zip archive of the local app data to w,
+// to back it up or migrate it to another computer with ImportAppData.
+func (a *App) ExportAppData(w io.Writer) error {
+	a.SaveConfigFile()
+	zw := zip.NewWriter(w)
+	manifest := appDataManifest{AppName: a.appName, AppVersion: a.appVersionTag, ExportedAt: time.Now()}
+	for _, name := range appDataFiles {
+		b, err := os.ReadFile(filepath.Join(a.configDir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(b); err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, name)
+	}
+	f, err := zw.Create(appDataManifestFile)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ImportAppData reads an archive written by ExportAppData and stages it to
+// replace the local app data when the app is shut down, since the running app
+// holds the data in memory. The replaced files are kept in a backup folder.
+func (a *App) ImportAppData(r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return fmt.Errorf("not an app data archive: %w", err)
+	}
+	var manifest *appDataManifest
+	for _, f := range zr.File {
+		if f.Name == appDataManifestFile {
+			manifest = &appDataManifest{}
+			if err := readZipJSON(f, manifest); err != nil {
+				return fmt.Errorf("invalid app data manifest: %w", err)
+			}
+		}
+	}
+	if manifest == nil || manifest.AppName != a.appName {
+		return errors.New("not an app data archive")
+	}
+
+	dir := filepath.Join(a.configDir, pendingImportDir)
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		// only extract the known files, which also guards against
+		// names which would be extracted outside of the dir
+		if !slices.Contains(appDataFiles, f.Name) {
+			continue
+		}
+		if err := extractZipFile(f, filepath.Join(dir, f.Name)); err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+	}
+	log.Printf("Staged app data exported %s by version %s for import", manifest.ExportedAt.Format(time.DateTime), manifest.AppVersion)
+	return nil
+}
+
+// applyPendingImport moves the files of a staged import into the config dir,
+// moving the files they replace to the backup dir. Must be called after
+// all local data has been written for the last time (at shutdown).
+func (a *App) applyPendingImport() {
+	dir := filepath.Join(a.configDir, pendingImportDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return // no pending import
+	}
+	backupDir := filepath.Join(a.configDir, preImportBackupDir)
+	os.RemoveAll(backupDir)
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		log.Printf("error applying app data import: %v", err)
+		return
+	}
+	// the archive's set of files replaces the existing set, so that
+	// e.g. no local history is left if the archive has none
+	for _, name := range appDataFiles {
+		if err := os.Rename(filepath.Join(a.configDir, name), filepath.Join(backupDir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("error backing up %s before import: %v", name, err)
+		}
+	}
+	for _, e := range entries {
+		if err := os.Rename(filepath.Join(dir, e.Name()), filepath.Join(a.configDir, e.Name())); err != nil {
+			log.Printf("error importing %s: %v", e.Name(), err)
+		}
+	}
+	os.RemoveAll(dir)
+	log.Printf("Imported app data; previous data backed up to %s", backupDir)
+}
+
+func readZipJSON(f *zip.File, v any) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(rc).Decode(v)
+}
+
+func extractZipFile(f *zip.File, path string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,293 @@
+package backend
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
+)
+
+const (
+	// external services which artist and album info is supplemented from
+	InfoSourceLastFM      = "Last.fm"
+	InfoSourceMusicBrainz = "MusicBrainz"
+
+	externalInfoCacheTTL = 7 * 24 * time.Hour
+	// max number of tags and similar artists to look up
+	artistInfoMaxTags    = 5
+	artistInfoMaxSimilar = 8
+)
+
+// ArtistInfoEnricher fills in the artist info supplied by the server with
+// biography, tags and similar artists from Last.fm and MusicBrainz.
+// The external info is cached on disk, and the sources used are
+// recorded in ArtistInfo.Sources so they can be credited.
+type ArtistInfoEnricher struct {
+	sm       *ServerManager
+	cfg      *AppConfig
+	cacheDir string
+
+	mu sync.Mutex // guards the on-disk cache
+}
+
+// externalArtistInfo is the info about an artist fetched from
+// external services, as cached on disk.
+type externalArtistInfo struct {
+	Fetched   time.Time `json:"fetched"`
+	Biography string    `json:"biography"`
+	URL       string    `json:"url"`
+	Tags      []string  `json:"tags"`
+	Similar   []string  `json:"similar"` // artist names
+	Sources   []string  `json:"sources"`
+}
+
+func NewArtistInfoEnricher(sm *ServerManager, cfg *AppConfig, cacheDir string) *ArtistInfoEnricher {
+	return &ArtistInfoEnricher{sm: sm, cfg: cfg, cacheDir: cacheDir}
+}
+
+// GetArtistInfo returns the server's info for the artist, merged with
+// the info from external services for whatever the server left empty.
+// Server info takes precedence. Similar artists from external
+// services are only included if they are in the library.
+func (e *ArtistInfoEnricher) GetArtistInfo(artist mediaprovider.Artist) (*mediaprovider.ArtistInfo, error) {
+	mp := e.sm.Server
+	if mp == nil {
+		return nil, errors.New("not connected to a server")
+	}
+	info, err := mp.GetArtistInfo(artist.ID)
+	if err != nil {
+		log.Printf("error getting artist info from server: %v", err)
+		info = &mediaprovider.ArtistInfo{}
+	}
+	if info.Biography != "" && len(info.Tags) > 0 && len(info.SimilarArtists) > 0 {
+		return info, nil
+	}
+
+	ext := e.externalInfo(artist.Name)
+	var used bool
+	if info.Biography == "" && ext.Biography != "" {
+		info.Biography = ext.Biography
+		used = true
+	}
+	if info.LastFMUrl == "" {
+		info.LastFMUrl = ext.URL
+	}
+	if len(info.Tags) == 0 && len(ext.Tags) > 0 {
+		info.Tags = ext.Tags
+		used = true
+	}
+	if len(info.SimilarArtists) == 0 && len(ext.Similar) > 0 {
+		info.SimilarArtists = findLibraryArtists(mp, ext.Similar)
+		used = used || len(info.SimilarArtists) > 0
+	}
+	if used {
+		info.Sources = ext.Sources
+	}
+	return info, nil
+}
+
+// externalInfo returns the external info for the artist,
+// from the on-disk cache if it hasn't expired.
+func (e *ArtistInfoEnricher) externalInfo(artistName string) *externalArtistInfo {
+	lang := infoLanguage(e.cfg)
+	path := externalInfoCachePath(e.cacheDir, lang, artistName)
+	var cached externalArtistInfo
+	e.mu.Lock()
+	haveCached := readCachedJSON(path, &cached)
+	e.mu.Unlock()
+	if haveCached && time.Since(cached.Fetched) < externalInfoCacheTTL {
+		return &cached
+	}
+
+	ext := &externalArtistInfo{Fetched: time.Now()}
+	var fetchErr error
+	if key := e.cfg.LastFMAPIKey; key != "" {
+		if err := fetchLastFMArtistInfo(key, artistName, lang, ext); err != nil {
+			log.Printf("error fetching artist info from Last.fm: %v", err)
+			fetchErr = err
+		} else {
+			ext.Sources = append(ext.Sources, InfoSourceLastFM)
+		}
+	}
+	if len(ext.Tags) == 0 {
+		if err := fetchMusicBrainzArtistTags(artistName, ext); err != nil {
+			log.Printf("error fetching artist tags from MusicBrainz: %v", err)
+			fetchErr = err
+		} else if len(ext.Tags) > 0 {
+			ext.Sources = append(ext.Sources, InfoSourceMusicBrainz)
+		}
+	}
+	if fetchErr != nil {
+		// don't cache, so it can be retried, but keep showing the stale info
+		if haveCached {
+			return &cached
+		}
+		return ext
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := writeCachedJSON(path, ext); err != nil {
+		log.Printf("error caching artist info: %v", err)
+	}
+	return ext
+}
+
+// infoLanguage returns the ISO 639-1 code of the language
+// to request biographies and album wikis in.
+func infoLanguage(cfg *AppConfig) string {
+	if l := strings.ToLower(strings.TrimSpace(cfg.ArtistInfoLanguage)); l != "" {
+		return l
+	}
+	return "en"
+}
+
+func fetchLastFMArtistInfo(apiKey, artistName, lang string, ext *externalArtistInfo) error {
+	var parsed struct {
+		Artist struct {
+			URL     string `json:"url"`
+			Similar struct {
+				Artist []struct {
+					Name string `json:"name"`
+				} `json:"artist"`
+			} `json:"similar"`
+			Tags struct {
+				Tag []struct {
+					Name string `json:"name"`
+				} `json:"tag"`
+			} `json:"tags"`
+			Bio struct {
+				Summary string `json:"summary"`
+				Content string `json:"content"`
+			} `json:"bio"`
+		} `json:"artist"`
+	}
+	err := lastFMRequest(apiKey, url.Values{
+		"method":      {"artist.getinfo"},
+		"artist":      {artistName},
+		"autocorrect": {"1"},
+		"lang":        {lang},
+	}, &parsed)
+	if err != nil {
+		return err
+	}
+	ar := parsed.Artist
+	ext.URL = ar.URL
+	ext.Biography = ar.Bio.Content
+	if ext.Biography == "" {
+		ext.Biography = ar.Bio.Summary
+	}
+	for i, t := range ar.Tags.Tag {
+		if i == artistInfoMaxTags {
+			break
+		}
+		ext.Tags = append(ext.Tags, t.Name)
+	}
+	for _, s := range ar.Similar.Artist {
+		ext.Similar = append(ext.Similar, s.Name)
+	}
+	return nil
+}
+
+func fetchMusicBrainzArtistTags(artistName string, ext *externalArtistInfo) error {
+	var parsed struct {
+		Artists []struct {
+			Name string `json:"name"`
+			Tags []struct {
+				Name  string `json:"name"`
+				Count int    `json:"count"`
+			} `json:"tags"`
+		} `json:"artists"`
+	}
+	err := musicBrainzRequest("artist", url.Values{
+		"query": {fmt.Sprintf("artist:%q", artistName)},
+		"limit": {"1"},
+	}, &parsed)
+	if err != nil {
+		return err
+	}
+	if len(parsed.Artists) == 0 || !strings.EqualFold(parsed.Artists[0].Name, artistName) {
+		return nil
+	}
+	tags := parsed.Artists[0].Tags
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].Count > tags[j].Count })
+	for i, t := range tags {
+		if i == artistInfoMaxTags {
+			break
+		}
+		ext.Tags = append(ext.Tags, t.Name)
+	}
+	return nil
+}
+
+// findLibraryArtists searches the library for the named artists concurrently,
+// returning up to artistInfoMaxSimilar of those found, in the given order.
+func findLibraryArtists(mp mediaprovider.MediaProvider, names []string) []*mediaprovider.Artist {
+	names = names[:min(len(names), 2*artistInfoMaxSimilar)]
+	found := make([]*mediaprovider.Artist, len(names))
+	opts := mediaprovider.SearchOptions{Limits: map[mediaprovider.ContentType]int{mediaprovider.ContentTypeArtist: 5}}
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results, err := helpers.SearchWithOptions(mp, name, opts)
+			if err != nil {
+				log.Printf("error searching for similar artist: %v", err)
+				return
+			}
+			for _, r := range results {
+				if r.Type == mediaprovider.ContentTypeArtist && normalizeName(r.Name) == normalizeName(name) {
+					found[i] = &mediaprovider.Artist{ID: r.ID, CoverArtID: r.CoverID, Name: r.Name, AlbumCount: r.Size}
+					return
+				}
+			}
+		}(i, name)
+	}
+	wg.Wait()
+
+	artists := make([]*mediaprovider.Artist, 0, artistInfoMaxSimilar)
+	for _, ar := range found {
+		if ar != nil && len(artists) < artistInfoMaxSimilar {
+			artists = append(artists, ar)
+		}
+	}
+	return artists
+}
+
+// externalInfoCachePath returns the path in dir of the cache file for the given keys.
+func externalInfoCachePath(dir string, keys ...string) string {
+	h := sha1.Sum([]byte(strings.ToLower(strings.Join(keys, "\x00"))))
+	return filepath.Join(dir, hex.EncodeToString(h[:])+".json")
+}
+
+func readCachedJSON(path string, v any) bool {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(b, v) == nil
+}
+
+func writeCachedJSON(path string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
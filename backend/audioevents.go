@@ -0,0 +1,153 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/dweymouth/supersonic/backend/player/mpv"
+)
+
+// systemAudioEvent is an event of the OS audio system
+// which the player may react to.
+type systemAudioEvent int
+
+const (
+	// a stream with the phone role (a call) started or ended
+	audioEventCallStarted systemAudioEvent = iota
+	audioEventCallEnded
+	// a notification sound started or ended
+	audioEventNotificationStarted
+	audioEventNotificationEnded
+	// the system default output device, or its active port
+	// (e.g. headphones being unplugged from the jack), changed
+	audioEventDefaultDeviceChanged
+)
+
+var errAudioEventsUnsupported = errors.New("system audio events are not supported on this platform")
+
+// AudioEventsManager ducks or pauses the local player in response to
+// system audio events: calls and notification sounds (via PulseAudio
+// stream roles, on Linux), the audio device in use being removed, such
+// as headphones being unplugged, and the default device changing.
+type AudioEventsManager struct {
+	cfg    *AudioEventsConfig
+	pm     *PlaybackManager
+	player *mpv.Player
+	output *AudioOutputManager
+
+	mu            sync.Mutex
+	cancelWatch   context.CancelFunc
+	inCall        bool
+	notifications bool // whether notification sounds are playing
+	pausedForCall bool
+}
+
+func NewAudioEventsManager(cfg *AudioEventsConfig, pm *PlaybackManager, p *mpv.Player, output *AudioOutputManager) *AudioEventsManager {
+	a := &AudioEventsManager{cfg: cfg, pm: pm, player: p, output: output}
+	output.OnDevicesRemoved(func(_ []string, activeRemoved bool) {
+		if activeRemoved {
+			a.pauseForOutputChange("audio device removed")
+		}
+	})
+	return a
+}
+
+// Apply starts or stops watching for system audio events according to the
+// config, and undoes any ducking which is no longer enabled.
+func (a *AudioEventsManager) Apply() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.updateDuckLocked()
+
+	wantRoles := a.cfg.CallAction == AudioEventActionDuck || a.cfg.CallAction == AudioEventActionPause ||
+		a.cfg.DuckForNotifications
+	if !wantRoles && !a.cfg.PauseOnOutputChange {
+		a.stopWatchLocked()
+		return nil
+	}
+	if a.cancelWatch != nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := watchSystemAudioEvents(ctx, func(e systemAudioEvent) { a.handleEvent(ctx, e) }); err != nil {
+		cancel()
+		if errors.Is(err, errAudioEventsUnsupported) && !wantRoles {
+			return nil // device removal is still detected through the player
+		}
+		return err
+	}
+	a.cancelWatch = cancel
+	return nil
+}
+
+// Shutdown stops watching for system audio events.
+func (a *AudioEventsManager) Shutdown() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stopWatchLocked()
+}
+
+func (a *AudioEventsManager) stopWatchLocked() {
+	if a.cancelWatch != nil {
+		a.cancelWatch()
+		a.cancelWatch = nil
+	}
+	a.inCall, a.notifications, a.pausedForCall = false, false, false
+	a.updateDuckLocked()
+}
+
+func (a *AudioEventsManager) handleEvent(watchCtx context.Context, e systemAudioEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if watchCtx.Err() != nil {
+		return // from a watch which has since been stopped
+	}
+	switch e {
+	case audioEventCallStarted:
+		a.inCall = true
+		if a.cfg.CallAction == AudioEventActionPause && a.pm.PlayerStatus().State == player.Playing {
+			log.Println("pausing playback for call")
+			a.pausedForCall = a.pm.Pause() == nil
+		}
+	case audioEventCallEnded:
+		a.inCall = false
+		if a.pausedForCall && a.cfg.ResumeAfterCall && a.pm.PlayerStatus().State == player.Paused {
+			log.Println("resuming playback after call")
+			a.pm.Continue()
+		}
+		a.pausedForCall = false
+	case audioEventNotificationStarted:
+		a.notifications = true
+	case audioEventNotificationEnded:
+		a.notifications = false
+	case audioEventDefaultDeviceChanged:
+		// only matters if the player follows the default device
+		if a.output.ActiveDevice() == autoAudioDevice {
+			go a.pauseForOutputChange("default audio device changed")
+		}
+	}
+	a.updateDuckLocked()
+}
+
+func (a *AudioEventsManager) updateDuckLocked() {
+	duck := (a.inCall && a.cfg.CallAction == AudioEventActionDuck) ||
+		(a.notifications && a.cfg.DuckForNotifications)
+	fraction := 1.0
+	if duck {
+		fraction = float64(clamp(a.cfg.DuckVolumePercent, 0, 100)) / 100
+	}
+	if err := a.player.SetDucked(fraction); err != nil {
+		log.Printf("error setting ducked volume: %v", err)
+	}
+}
+
+func (a *AudioEventsManager) pauseForOutputChange(reason string) {
+	if !a.cfg.PauseOnOutputChange || a.pm.PlayerStatus().State != player.Playing {
+		return
+	}
+	log.Printf("pausing playback: %s", reason)
+	a.pm.Pause()
+}
@@ -0,0 +1,58 @@
+//go:build darwin
+
+package backend
+
+/*
+#cgo LDFLAGS: -framework CoreAudio
+#include "audioeventsbridge.h"
+*/
+import "C"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// the onEvent callback of the running watch, since Go
+// pointers can't be passed into C. Only one watch can run at a time.
+var (
+	audioOutputWatchMu sync.Mutex
+	audioOutputWatchCB func(systemAudioEvent)
+)
+
+//export audio_output_changed_callback
+func audio_output_changed_callback() {
+	audioOutputWatchMu.Lock()
+	cb := audioOutputWatchCB
+	audioOutputWatchMu.Unlock()
+	if cb != nil {
+		cb(audioEventDefaultDeviceChanged)
+	}
+}
+
+// watchSystemAudioEvents watches CoreAudio for changes of the default output
+// device or its data source (e.g. headphones being unplugged from the jack),
+// calling onEvent until ctx is canceled. Desktop macOS has no API for
+// call or notification audio interruptions, so those are not reported.
+func watchSystemAudioEvents(ctx context.Context, onEvent func(systemAudioEvent)) error {
+	audioOutputWatchMu.Lock()
+	defer audioOutputWatchMu.Unlock()
+	if audioOutputWatchCB != nil {
+		return errors.New("already watching system audio events")
+	}
+	if status := C.start_audio_output_listeners(); status != 0 {
+		return fmt.Errorf("failed to listen for audio output changes: OSStatus %d", int(status))
+	}
+	audioOutputWatchCB = onEvent
+
+	go func() {
+		<-ctx.Done()
+		C.stop_audio_output_listeners()
+		audioOutputWatchMu.Lock()
+		audioOutputWatchCB = nil
+		audioOutputWatchMu.Unlock()
+	}()
+	return nil
+}
@@ -0,0 +1,128 @@
+package backend
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// watchSystemAudioEvents watches the PulseAudio (or PipeWire) server for
+// streams with the phone and event roles, as used for role ducking, and
+// for changes of the default sink or its active port (unplugging headphones
+// from a jack only changes the port), calling onEvent until ctx is canceled.
+func watchSystemAudioEvents(ctx context.Context, onEvent func(systemAudioEvent)) error {
+	if _, err := exec.LookPath("pactl"); err != nil {
+		return fmt.Errorf("%w: pactl not found", errAudioEventsUnsupported)
+	}
+	cmd := pactlCommand(ctx, "subscribe")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	go func() {
+		defer cmd.Wait()
+		roles := streamRoles()
+		defaultSink := pulseDefaultSink()
+		activePort := pulseActivePort(defaultSink)
+		sc := bufio.NewScanner(out)
+		for sc.Scan() {
+			// e.g. "Event 'new' on sink-input #42", "Event 'change' on server"
+			line := sc.Text()
+			switch {
+			case strings.Contains(line, " on sink-input "):
+				newRoles := streamRoles()
+				notifyRoleChange(roles, newRoles, "phone", audioEventCallStarted, audioEventCallEnded, onEvent)
+				notifyRoleChange(roles, newRoles, "event", audioEventNotificationStarted, audioEventNotificationEnded, onEvent)
+				roles = newRoles
+			case strings.Contains(line, " on server"):
+				if sink := pulseDefaultSink(); sink != defaultSink {
+					defaultSink = sink
+					activePort = pulseActivePort(sink)
+					onEvent(audioEventDefaultDeviceChanged)
+				}
+			case strings.Contains(line, "'change' on sink "):
+				if port := pulseActivePort(defaultSink); port != activePort {
+					activePort = port
+					onEvent(audioEventDefaultDeviceChanged)
+				}
+			}
+		}
+		if ctx.Err() == nil {
+			log.Printf("stopped watching system audio events: %v", sc.Err())
+		}
+	}()
+	return nil
+}
+
+func notifyRoleChange(old, new map[string]bool, role string, started, ended systemAudioEvent, onEvent func(systemAudioEvent)) {
+	if new[role] && !old[role] {
+		onEvent(started)
+	} else if old[role] && !new[role] {
+		onEvent(ended)
+	}
+}
+
+// streamRoles returns the media roles of the playing streams.
+func streamRoles() map[string]bool {
+	roles := make(map[string]bool)
+	out, err := pactlCommand(context.Background(), "list", "sink-inputs").Output()
+	if err != nil {
+		return roles
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		// e.g. `		media.role = "phone"`
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), " = "); ok && k == "media.role" {
+			roles[strings.Trim(v, `"`)] = true
+		}
+	}
+	return roles
+}
+
+func pulseDefaultSink() string {
+	out, err := pactlCommand(context.Background(), "info").Output()
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if sink, ok := strings.CutPrefix(line, "Default Sink: "); ok {
+			return sink
+		}
+	}
+	return ""
+}
+
+// pulseActivePort returns the active port of the named sink,
+// e.g. "analog-output-headphones".
+func pulseActivePort(sink string) string {
+	if sink == "" {
+		return ""
+	}
+	out, err := pactlCommand(context.Background(), "list", "sinks").Output()
+	if err != nil {
+		return ""
+	}
+	var inSink bool
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if name, ok := strings.CutPrefix(line, "Name: "); ok {
+			inSink = name == sink
+		} else if port, ok := strings.CutPrefix(line, "Active Port: "); ok && inSink {
+			return port
+		}
+	}
+	return ""
+}
+
+func pactlCommand(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "pactl", args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C") // unlocalized output
+	return cmd
+}
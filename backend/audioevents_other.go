@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package backend
+
+import "context"
+
+func watchSystemAudioEvents(ctx context.Context, onEvent func(systemAudioEvent)) error {
+	// Windows ducks other streams during calls itself, per the
+	// Communications setting of the Sound control panel.
+	return errAudioEventsUnsupported
+}
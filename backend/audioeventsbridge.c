@@ -0,0 +1,75 @@
+//go:build darwin
+
+#include <pthread.h>
+#include "audioeventsbridge.h"
+
+static pthread_mutex_t listenersMutex = PTHREAD_MUTEX_INITIALIZER;
+
+// the device whose data source is being listened to, if any
+static AudioObjectID dataSourceDevice = kAudioObjectUnknown;
+
+static const AudioObjectPropertyAddress defaultOutputAddress = {
+    kAudioHardwarePropertyDefaultOutputDevice,
+    kAudioObjectPropertyScopeGlobal,
+    0 // kAudioObjectPropertyElementMain, which needs macOS 12
+};
+
+static const AudioObjectPropertyAddress dataSourceAddress = {
+    kAudioDevicePropertyDataSource,
+    kAudioDevicePropertyScopeOutput,
+    0
+};
+
+static OSStatus on_property_changed(AudioObjectID object, UInt32 count,
+                                    const AudioObjectPropertyAddress *addresses, void *data);
+
+// listens to the data source of the current default output device,
+// which only some devices (e.g. built-in outputs) have. Call with listenersMutex held.
+static void listen_to_default_data_source(void) {
+    if (dataSourceDevice != kAudioObjectUnknown) {
+        AudioObjectRemovePropertyListener(dataSourceDevice, &dataSourceAddress, on_property_changed, NULL);
+        dataSourceDevice = kAudioObjectUnknown;
+    }
+    AudioObjectID device = kAudioObjectUnknown;
+    UInt32 size = sizeof(device);
+    if (AudioObjectGetPropertyData(kAudioObjectSystemObject, &defaultOutputAddress, 0, NULL, &size, &device) != noErr) {
+        return;
+    }
+    if (AudioObjectHasProperty(device, &dataSourceAddress) &&
+        AudioObjectAddPropertyListener(device, &dataSourceAddress, on_property_changed, NULL) == noErr) {
+        dataSourceDevice = device;
+    }
+}
+
+static OSStatus on_property_changed(AudioObjectID object, UInt32 count,
+                                    const AudioObjectPropertyAddress *addresses, void *data) {
+    for (UInt32 i = 0; i < count; i++) {
+        if (addresses[i].mSelector == kAudioHardwarePropertyDefaultOutputDevice) {
+            pthread_mutex_lock(&listenersMutex);
+            listen_to_default_data_source();
+            pthread_mutex_unlock(&listenersMutex);
+        }
+    }
+    audio_output_changed_callback();
+    return noErr;
+}
+
+OSStatus start_audio_output_listeners(void) {
+    pthread_mutex_lock(&listenersMutex);
+    OSStatus status = AudioObjectAddPropertyListener(kAudioObjectSystemObject, &defaultOutputAddress, on_property_changed, NULL);
+    if (status == noErr) {
+        listen_to_default_data_source();
+    }
+    pthread_mutex_unlock(&listenersMutex);
+    return status;
+}
+
+void stop_audio_output_listeners(void) {
+    pthread_mutex_lock(&listenersMutex);
+    AudioObjectRemovePropertyListener(kAudioObjectSystemObject, &defaultOutputAddress, on_property_changed, NULL);
+    if (dataSourceDevice != kAudioObjectUnknown) {
+        AudioObjectRemovePropertyListener(dataSourceDevice, &dataSourceAddress, on_property_changed, NULL);
+        dataSourceDevice = kAudioObjectUnknown;
+    }
+    pthread_mutex_unlock(&listenersMutex);
+}
@@ -0,0 +1,28 @@
+//go:build darwin
+
+/**
+ * audioeventsbridge.h
+ *
+ * This file provides a C bridge to the CoreAudio property listeners used to
+ * detect changes of the default output device and of its data source
+ * (e.g. headphones being unplugged from the built-in jack).
+ */
+
+#include <CoreAudio/CoreAudio.h>
+
+/**
+ * Called from a CoreAudio notification thread when the default output
+ * device or its data source changes. Implemented in Go.
+ */
+extern void audio_output_changed_callback(void);
+
+/**
+ * Starts listening for changes of the default output device and its data source.
+ * Returns a non-zero OSStatus on failure.
+ */
+OSStatus start_audio_output_listeners(void);
+
+/**
+ * Stops listening for changes started by start_audio_output_listeners.
+ */
+void stop_audio_output_listeners(void);
@@ -0,0 +1,157 @@
+package backend
+
+import (
+	"log"
+	"slices"
+	"sync"
+
+	"github.com/dweymouth/supersonic/backend/player/mpv"
+)
+
+// the name to pass to MPV for autoselecting the output device
+const autoAudioDevice = "auto"
+
+// AudioOutputManager manages the audio output device of the local player.
+// If the configured device becomes unavailable (e.g. a USB DAC is unplugged),
+// playback falls back to the system default device, and switches back
+// to the configured device if it becomes available again.
+type AudioOutputManager struct {
+	cfg       *LocalPlaybackConfig
+	player    *mpv.Player
+	equalizer *EqualizerManager
+
+	mu      sync.Mutex
+	active  string          // the device currently in use by the player
+	devices map[string]bool // names of the devices last listed
+
+	onDeviceChanged  []func(device string)
+	onDevicesRemoved []func(removed []string, activeRemoved bool)
+}
+
+func NewAudioOutputManager(cfg *LocalPlaybackConfig, p *mpv.Player, eq *EqualizerManager) *AudioOutputManager {
+	a := &AudioOutputManager{cfg: cfg, player: p, equalizer: eq}
+	p.OnAudioDeviceListChanged(a.checkAvailability)
+	return a
+}
+
+// Devices returns the audio output devices currently available.
+func (a *AudioOutputManager) Devices() ([]mpv.AudioDevice, error) {
+	return a.player.ListAudioDevices()
+}
+
+// ConfiguredDevice returns the name of the device the user has selected.
+func (a *AudioOutputManager) ConfiguredDevice() string {
+	return a.cfg.AudioDeviceName
+}
+
+// ActiveDevice returns the name of the device currently in use,
+// which may differ from the configured device if it is unavailable.
+func (a *AudioOutputManager) ActiveDevice() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.active
+}
+
+// IsFallbackActive returns true if the configured device is unavailable
+// and the system default device is being used instead.
+func (a *AudioOutputManager) IsFallbackActive() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.active != a.cfg.AudioDeviceName
+}
+
+// SetDevice switches playback to the given device and saves it as the configured device.
+func (a *AudioOutputManager) SetDevice(name string) error {
+	a.cfg.AudioDeviceName = name
+	if a.equalizer != nil {
+		a.equalizer.SetAudioDevice(name)
+	}
+	return a.checkAvailabilityWithErr()
+}
+
+// Registers a callback which is invoked when the active audio device changes,
+// including automatic fallbacks. May be called from a background goroutine.
+func (a *AudioOutputManager) OnDeviceChanged(cb func(device string)) {
+	a.onDeviceChanged = append(a.onDeviceChanged, cb)
+}
+
+// Registers a callback which is invoked when audio devices are removed
+// (e.g. headphones are unplugged), before falling back to another device
+// if the active one was removed. activeRemoved is false if the default
+// device is active, since it isn't known which device that is; a change
+// of the default device is reported by the system audio events instead.
+// May be called from a background goroutine.
+func (a *AudioOutputManager) OnDevicesRemoved(cb func(removed []string, activeRemoved bool)) {
+	a.onDevicesRemoved = append(a.onDevicesRemoved, cb)
+}
+
+func (a *AudioOutputManager) checkAvailability() {
+	if err := a.checkAvailabilityWithErr(); err != nil {
+		log.Printf("failed to update audio device: %v", err)
+	}
+}
+
+// sets the active device to the configured one if it is available,
+// otherwise falls back to the default device
+func (a *AudioOutputManager) checkAvailabilityWithErr() error {
+	devs, err := a.player.ListAudioDevices()
+	if err != nil {
+		return err
+	}
+
+	desired := a.cfg.AudioDeviceName
+	var desiredAvailable bool
+	listed := make(map[string]bool, len(devs))
+	for _, dev := range devs {
+		listed[dev.Name] = true
+		if dev.Name == desired {
+			desiredAvailable = true
+		}
+	}
+	a.notifyRemovedDevices(listed)
+	if !desiredAvailable {
+		// Leave the setting unchanged, so that the device is used again
+		// once it is available (e.g. a USB audio device is plugged back in)
+		desired = autoAudioDevice
+	}
+
+	a.mu.Lock()
+	if desired == a.active {
+		a.mu.Unlock()
+		return nil
+	}
+	if desired != a.cfg.AudioDeviceName {
+		log.Printf("audio device %q unavailable, falling back to default device", a.cfg.AudioDeviceName)
+	}
+	a.active = desired
+	a.mu.Unlock()
+
+	if err := a.player.SetAudioDevice(desired); err != nil {
+		return err
+	}
+	for _, cb := range a.onDeviceChanged {
+		cb(desired)
+	}
+	return nil
+}
+
+func (a *AudioOutputManager) notifyRemovedDevices(listed map[string]bool) {
+	a.mu.Lock()
+	var removed []string
+	for name := range a.devices {
+		if !listed[name] {
+			removed = append(removed, name)
+		}
+	}
+	a.devices = listed
+	active := a.active
+	a.mu.Unlock()
+
+	if len(removed) == 0 {
+		return
+	}
+	activeRemoved := slices.Contains(removed, active)
+	for _, cb := range a.onDevicesRemoved {
+		cb(removed, activeRemoved)
+	}
+}
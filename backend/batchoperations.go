@@ -0,0 +1,98 @@
+package backend
+
+import (
+	"context"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+// max number of tracks to add to a playlist in one request
+const playlistAddChunkSize = 200
+
+// BatchOperations applies operations to large selections of items through
+// the connected server, with progress reporting and cancelation. Servers
+// which can only operate on one item per request are sent a bounded
+// number of concurrent requests. If some items fail, the error is an
+// *mediaprovider.ItemsError reporting the failed items by ID.
+// (Downloads are queued with the DownloadManager, which reports their progress.)
+type BatchOperations struct {
+	sm             *ServerManager
+	playlistTracks *PlaylistTracksCache
+}
+
+func NewBatchOperations(sm *ServerManager, playlistTracks *PlaylistTracksCache) *BatchOperations {
+	return &BatchOperations{sm: sm, playlistTracks: playlistTracks}
+}
+
+// SetFavorite sets the favorite status of the items.
+func (b *BatchOperations) SetFavorite(ctx context.Context, params mediaprovider.RatingFavoriteParameters, favorite bool, onProgress func(done, total int)) error {
+	if fp, ok := mediaprovider.As[mediaprovider.SupportsSetFavoriteProgress](b.sm.Server); ok {
+		return fp.SetFavoriteWithProgress(ctx, params, favorite, onProgress)
+	}
+	// the server sets all the items in one request
+	return b.single(onProgress, func() error {
+		return b.sm.Server.SetFavorite(params, favorite)
+	})
+}
+
+// SetRating sets the rating of the tracks. The server must implement
+// mediaprovider.SupportsRating.
+func (b *BatchOperations) SetRating(ctx context.Context, trackIDs []string, rating int, onProgress func(done, total int)) error {
+	params := mediaprovider.RatingFavoriteParameters{TrackIDs: trackIDs}
+	if rp, ok := mediaprovider.As[mediaprovider.SupportsSetRatingProgress](b.sm.Server); ok {
+		return rp.SetRatingWithProgress(ctx, params, rating, onProgress)
+	}
+	r, ok := mediaprovider.As[mediaprovider.SupportsRating](b.sm.Server)
+	if !ok {
+		return nil
+	}
+	return b.single(onProgress, func() error {
+		return r.SetRating(params, rating)
+	})
+}
+
+// AddToPlaylist appends the tracks, in order, to the playlist,
+// skipping those already in it if skipDuplicates is true.
+// Large selections are added in several requests; if one fails,
+// the tracks not yet added are reported as failed.
+func (b *BatchOperations) AddToPlaylist(ctx context.Context, playlistID string, trackIDs []string, skipDuplicates bool, onProgress func(done, total int)) error {
+	if skipDuplicates {
+		dups, err := b.playlistTracks.Duplicates(playlistID, trackIDs)
+		if err != nil {
+			return err
+		}
+		dupSet := sharedutil.ToSet(dups)
+		trackIDs = sharedutil.FilterSlice(trackIDs, func(id string) bool {
+			_, ok := dupSet[id]
+			return !ok
+		})
+	}
+	for i := 0; i < len(trackIDs); i += playlistAddChunkSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		chunk := trackIDs[i:min(i+playlistAddChunkSize, len(trackIDs))]
+		if err := b.sm.Server.AddPlaylistTracks(playlistID, chunk); err != nil {
+			failed := make(map[string]error, len(trackIDs)-i)
+			for _, id := range trackIDs[i:] {
+				failed[id] = err
+			}
+			return &mediaprovider.ItemsError{Errors: failed}
+		}
+		if onProgress != nil {
+			onProgress(i+len(chunk), len(trackIDs))
+		}
+	}
+	return nil
+}
+
+func (b *BatchOperations) single(onProgress func(done, total int), op func() error) error {
+	if err := op(); err != nil {
+		return err
+	}
+	if onProgress != nil {
+		onProgress(1, 1)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package backend
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// ChangePoller periodically polls the server for changes made by other
+// clients, refreshing the FavoritesCache (which publishes EventFavoritesChanged)
+// and publishing an EventPlaylistChanged for each changed playlist.
+// The interval is set by AppConfig.ChangePollMinutes.
+type ChangePoller struct {
+	sm     *ServerManager
+	favs   *FavoritesCache
+	bus    *EventBus
+	config *AppConfig
+
+	mu        sync.Mutex
+	cancel    context.CancelFunc
+	playlists map[string]mediaprovider.Playlist // as of the last poll, by ID
+}
+
+func NewChangePoller(ctx context.Context, sm *ServerManager, favs *FavoritesCache, bus *EventBus, config *AppConfig) *ChangePoller {
+	c := &ChangePoller{sm: sm, favs: favs, bus: bus, config: config}
+	sm.OnServerConnected(func() { c.start(ctx) })
+	sm.OnLogout(c.stop)
+	bus.Subscribe(EventLibraryScanCompleted, func(Event) { go c.Poll() })
+	return c
+}
+
+func (c *ChangePoller) start(ctx context.Context) {
+	c.stop()
+	if c.config.ChangePollMinutes <= 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
+	c.cancel = cancel
+	c.mu.Unlock()
+
+	go func() {
+		c.Poll() // record the initial state of the playlists
+		t := time.NewTicker(time.Duration(c.config.ChangePollMinutes) * time.Minute)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				c.Poll()
+			}
+		}
+	}()
+}
+
+func (c *ChangePoller) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+	c.playlists = nil
+}
+
+// Poll checks the server for changes now.
+func (c *ChangePoller) Poll() {
+	server := c.sm.Server
+	if server == nil {
+		return
+	}
+	if c.favs.Cached() != nil {
+		// only refresh if some view has loaded the favorites
+		if err := c.favs.Refresh(); err != nil {
+			log.Printf("error polling favorites: %v", err)
+		}
+	}
+
+	playlists, err := server.GetPlaylists()
+	if err != nil {
+		log.Printf("error polling playlists: %v", err)
+		return
+	}
+	latest := make(map[string]mediaprovider.Playlist, len(playlists))
+	for _, p := range playlists {
+		latest[p.ID] = *p
+	}
+
+	c.mu.Lock()
+	old := c.playlists
+	c.playlists = latest
+	c.mu.Unlock()
+	if old == nil {
+		return // first poll
+	}
+	var changed []string
+	for id, p := range latest {
+		if oldP, ok := old[id]; !ok || oldP != p {
+			changed = append(changed, id)
+		}
+	}
+	for id := range old {
+		if _, ok := latest[id]; !ok {
+			changed = append(changed, id)
+		}
+	}
+	for _, id := range changed {
+		c.bus.Publish(Event{Type: EventPlaylistChanged, Data: id})
+	}
+}
@@ -2,20 +2,32 @@ package backend
 
 import (
 	"flag"
+	"fmt"
 	"strconv"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/ipc"
 )
 
 var (
 	VolumeCLIArg int     = -1
 	SeekToCLIArg float64 = -1
 
-	FlagPlay      = flag.Bool("play", false, "unpause or begin playback")
-	FlagPause     = flag.Bool("pause", false, "pause playback")
-	FlagPlayPause = flag.Bool("play-pause", false, "toggle play/pause state")
-	FlagPrevious  = flag.Bool("previous", false, "seek to previous track or beginning of current")
-	FlagNext      = flag.Bool("next", false, "seek to next track")
-	FlagVersion   = flag.Bool("version", false, "print app version and exit")
-	FlagHelp      = flag.Bool("help", false, "print command line options and exit")
+	FlagPlay       = flag.Bool("play", false, "unpause or begin playback")
+	FlagPause      = flag.Bool("pause", false, "pause playback")
+	FlagPlayPause  = flag.Bool("play-pause", false, "toggle play/pause state")
+	FlagPrevious   = flag.Bool("previous", false, "seek to previous track or beginning of current")
+	FlagNext       = flag.Bool("next", false, "seek to next track")
+	FlagNowPlaying = flag.Bool("now-playing", false, "print the now playing track and playback state")
+
+	FlagEnqueueAlbum    = flag.String("enqueue-album", "", "add the album best matching the given search to the play queue")
+	FlagEnqueuePlaylist = flag.String("enqueue-playlist", "", "add the playlist best matching the given name to the play queue")
+	FlagEnqueueMode     = flag.String("enqueue-mode", "append", "how to add items with -enqueue-album/-playlist (play, next, append)")
+
+	FlagSnapcastControl = flag.Bool("snapcast-control", false, "run as the control script of a Snapcast stream, forwarding metadata and playback control")
+
+	FlagVersion = flag.Bool("version", false, "print app version and exit")
+	FlagHelp    = flag.Bool("help", false, "print command line options and exit")
 )
 
 func init() {
@@ -25,6 +37,11 @@ func init() {
 		return err
 	})
 
+	// passed by snapserver to its control scripts; unused by -snapcast-control
+	for _, name := range []string{"stream", "snapcast-host", "snapcast-port"} {
+		flag.String(name, "", "set by snapserver for -snapcast-control")
+	}
+
 	flag.Func("seek-to", "seeks to the given position in seconds in the current file (0.0 - <trackDur>)", func(s string) error {
 		v, err := strconv.ParseFloat(s, 64)
 		SeekToCLIArg = v
@@ -32,6 +49,34 @@ func init() {
 	})
 }
 
+// printNowPlaying prints the response to a -now-playing request, e.g.
+//
+//	Playing: Artist - Title (Album) [1:23 / 4:56]
+func printNowPlaying(np *ipc.NowPlayingResponse) {
+	state := "Stopped"
+	switch np.State {
+	case "playing":
+		state = "Playing"
+	case "paused":
+		state = "Paused"
+	}
+	if np.Title == "" {
+		fmt.Println(state)
+		return
+	}
+	s := np.Title
+	if len(np.Artists) > 0 {
+		s = strings.Join(np.Artists, ", ") + " - " + s
+	}
+	if np.Album != "" {
+		s += " (" + np.Album + ")"
+	}
+	mmss := func(secs float64) string {
+		return fmt.Sprintf("%d:%02d", int(secs)/60, int(secs)%60)
+	}
+	fmt.Printf("%s: %s [%s / %s]\n", state, s, mmss(np.TimePos), mmss(np.Duration))
+}
+
 func HaveCommandLineOptions() bool {
 	visitedAny := false
 	flag.Visit(func(*flag.Flag) {
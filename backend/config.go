@@ -1,9 +1,11 @@
 package backend
 
 import (
+	"log"
 	"os"
 	"sync"
 
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
 	"github.com/google/uuid"
 	"github.com/pelletier/go-toml/v2"
 )
@@ -13,6 +15,11 @@ type ServerType string
 const (
 	ServerTypeSubsonic ServerType = "Subsonic"
 	ServerTypeJellyfin ServerType = "Jellyfin"
+	// music files on a WebDAV server or a mounted SMB/NFS share,
+	// indexed by the app itself
+	ServerTypeNetworkShare ServerType = "Network Share"
+	// an in-memory library for trying out the app without a server
+	ServerTypeDemo ServerType = "Demo"
 )
 
 type ServerConnection struct {
@@ -21,6 +28,15 @@ type ServerConnection struct {
 	AltHostname string
 	Username    string
 	LegacyAuth  bool
+
+	// Subsonic only: if token auth is rejected (e.g. for LDAP users),
+	// automatically retry with legacy password auth. Opt-in per server
+	// since the password is then sent with every request.
+	AllowLegacyAuthFallback bool
+
+	// Jellyfin only: always request the original file via a static
+	// stream URL instead of letting the server decide whether to transcode
+	ForceDirectStream bool
 }
 
 type ServerConfig struct {
@@ -28,6 +44,80 @@ type ServerConfig struct {
 	ID       uuid.UUID
 	Nickname string
 	Default  bool
+
+	// How often to check the server for newly added albums. 0 disables.
+	NewMusicCheckMinutes int
+	// ID of the most recently added album at the last check
+	NewMusicLastSeenAlbumID string
+
+	// Library (Jellyfin) or music folder (Subsonic) to scope
+	// browsing to, on servers with several. "" for all.
+	MusicLibraryID string
+
+	// Transcoding to request when streaming from the server,
+	// if supported. "" and 0 leave it to the server's defaults.
+	StreamFormat     string
+	StreamMaxBitRate int
+	// Override the above while TranscodingConfig.LowBandwidthMode is on, if set
+	LowBandwidthStreamFormat     string
+	LowBandwidthStreamMaxBitRate int
+	// Tracks which are always streamed as the original file
+	ForceRawTrackIDs []string
+
+	// playlist ID -> ID of the album whose cover to show for the playlist
+	PlaylistCoverAlbums map[string]string
+	// Playlists whose tracks are never scrobbled (e.g. white noise)
+	NoScrobblePlaylistIDs []string
+
+	// Additional user accounts on the server, to switch between
+	Accounts []ServerAccount
+	// ID of the account to log in as, or the zero UUID for the one
+	// in ServerConnection.Username (the primary account)
+	ActiveAccountID uuid.UUID
+
+	// Settings which override the global ones while connected
+	Overrides ServerSettingsOverrides
+}
+
+// ServerSettingsOverrides are settings of a server which override the
+// global settings while connected to it. Nil fields use the global setting.
+// Transcoding is set per server by the ServerConfig stream fields.
+type ServerSettingsOverrides struct {
+	ScrobbleEnabled          *bool
+	ScrobbleThresholdPercent *int
+	ScrobbleThresholdSeconds *int
+	MaxTrackCacheSizeMB      *int
+	HomeSections             []string
+}
+
+// ServerAccount is an additional user account on a server.
+type ServerAccount struct {
+	ID       uuid.UUID // key of the account's password in the keyring
+	Username string
+}
+
+// ActiveAccount returns the account to log in as. The primary
+// account is returned with the ID of the server config, which is
+// also the keyring key of its password.
+func (s *ServerConfig) ActiveAccount() ServerAccount {
+	for _, a := range s.Accounts {
+		if a.ID == s.ActiveAccountID {
+			return a
+		}
+	}
+	return ServerAccount{ID: s.ID, Username: s.Username}
+}
+
+// AllAccounts returns the primary account followed by the additional accounts.
+func (s *ServerConfig) AllAccounts() []ServerAccount {
+	return append([]ServerAccount{{ID: s.ID, Username: s.Username}}, s.Accounts...)
+}
+
+// ActiveConnection returns the connection to log in with the active account.
+func (s *ServerConfig) ActiveConnection() ServerConnection {
+	conn := s.ServerConnection
+	conn.Username = s.ActiveAccount().Username
+	return conn
 }
 
 type AppConfig struct {
@@ -43,9 +133,30 @@ type AppConfig struct {
 	MaxImageCacheSizeMB         int
 	SavePlayQueue               bool
 	SaveQueueToServer           bool
+	ResumePlaybackOnStartup     bool
 	DefaultPlaylistID           string
 	ShowTrackChangeNotification bool
 	EnableLrcLib                bool
+	ShowPerformanceOverlay      bool
+	RecordListeningHistory      bool
+	EmbedTagsInDownloads        bool
+	// how often to poll the server for changes made by other clients, 0 to disable
+	ChangePollMinutes int
+	// API requests slower than this are logged, 0 to disable
+	SlowRequestLogMillis int
+	// record recent API requests for viewing in the request log
+	EnableRequestLog bool
+	// used to look up artist top tracks when the server can't supply them
+	LastFMAPIKey string
+	// ISO 639-1 code of the language for artist biographies and album notes from Last.fm
+	ArtistInfoLanguage string
+
+	// Views detached into their own windows, reopened on next launch
+	DetachedWindows []DetachedWindowConfig
+
+	// LargeLibraryMode tunes pagination and caching for libraries
+	// with 100k+ tracks. See README.md for details.
+	LargeLibraryMode bool
 
 	// Experimental - may be removed in future
 	FontNormalTTF string
@@ -53,12 +164,22 @@ type AppConfig struct {
 	UIScaleSize   string
 }
 
+type DetachedWindowConfig struct {
+	View   string // "Play Queue", "Lyrics" or "Now Playing"
+	Width  int
+	Height int
+}
+
 type AlbumPageConfig struct {
 	TracklistColumns []string
 }
 
 type AlbumsPageConfig struct {
 	SortOrder string
+	// Collapse single-track albums into a "Singles" album per artist
+	CollapseSingles bool
+	// Show multiple editions of an album (e.g. remaster, deluxe) as one entry
+	GroupEditions bool
 }
 
 type ArtistPageConfig struct {
@@ -73,6 +194,9 @@ type ArtistsPageConfig struct {
 type FavoritesPageConfig struct {
 	InitialView      string
 	TracklistColumns []string
+	// order of the favorite artists and songs views,
+	// the name of a mediaprovider.FavoritesSortOrder
+	SortOrder string
 }
 
 type PlaylistPageConfig struct {
@@ -81,6 +205,8 @@ type PlaylistPageConfig struct {
 
 type PlaylistsPageConfig struct {
 	InitialView string
+	// Show the public playlists of other users rather than the user's own
+	ShowOthersPlaylists bool
 }
 
 type TracksPageConfig struct {
@@ -92,25 +218,256 @@ type NowPlayingPageConfig struct {
 }
 
 type LocalPlaybackConfig struct {
-	AudioDeviceName       string
-	AudioExclusive        bool
-	InMemoryCacheSizeMB   int
+	AudioDeviceName     string
+	AudioExclusive      bool
+	InMemoryCacheSizeMB int
+	// number of upcoming queue tracks to download to disk ahead of playback
+	PrecacheTracks        int
+	MaxTrackCacheSizeMB   int
 	Volume                int
 	EqualizerEnabled      bool
+	EqualizerType         string
+	EqualizerPreset       string
 	EqualizerPreamp       float64
 	GraphicEqualizerBands []float64
+
+	// apply a peak limiter to the output to prevent clipping
+	OutputLimiter bool
+	// downmix the output to mono (accessibility and single-speaker setups)
+	MonoDownmix bool
+	// output the audio unaltered with exclusive access to the device,
+	// disabling volume control, ReplayGain and all DSP
+	BitPerfect bool
+
+	// saved equalizer settings for each audio device by name,
+	// restored when switching to that device
+	DeviceEqualizers map[string]*EqualizerConfig
+}
+
+type EqualizerConfig struct {
+	Enabled bool
+	Type    string
+	Preset  string
+	Preamp  float64
+	Bands   []float64
 }
 
 type ScrobbleConfig struct {
 	Enabled              bool
 	ThresholdTimeSeconds int
 	ThresholdPercent     int
+
+	// Rules applied before any scrobble is submitted: tracks listened to
+	// for less than MinListenSeconds, in any of the ignored genres
+	// (case-insensitive), or in a ServerConfig.NoScrobblePlaylistIDs
+	// playlist are not scrobbled.
+	MinListenSeconds int
+	IgnoredGenres    []string
+	// Time listened with the volume at 0 doesn't count toward the thresholds
+	SkipWhenMuted bool
 }
 
 type ReplayGainConfig struct {
 	Mode            string
 	PreampGainDB    float64
 	PreventClipping bool
+	// Normalize loudness based on the audio itself
+	// when a track has no ReplayGain metadata
+	LoudnessFallback bool
+}
+
+// TrackFadeConfig configures fading the volume out at the end of
+// a track and back in at the start of the next one.
+type TrackFadeConfig struct {
+	Enabled         bool
+	DurationSeconds float64
+	// Whether to also fade when the user skips tracks,
+	// rather than only at the natural end of a track
+	ApplyOnManualSkip bool
+}
+
+// AudioEventsConfig configures how the local player
+// reacts to system audio events. See AudioEventsManager.
+type AudioEventsConfig struct {
+	// AudioEventActionNone, AudioEventActionDuck or AudioEventActionPause
+	CallAction string
+	// resume playback when the call ends, if it was paused for the call
+	ResumeAfterCall bool
+	// lower the volume while notification sounds play
+	DuckForNotifications bool
+	// volume percentage while ducked
+	DuckVolumePercent int
+	// pause when the audio device in use is removed, e.g.
+	// headphones are unplugged, or the default device changes
+	PauseOnOutputChange bool
+}
+
+const (
+	AudioEventActionNone  = "None"
+	AudioEventActionDuck  = "Duck"
+	AudioEventActionPause = "Pause"
+)
+
+// SnapcastConfig configures streaming the audio to a
+// Snapcast server for multi-room playback.
+type SnapcastConfig struct {
+	Enabled bool
+	// SnapcastModePipe or SnapcastModeTCP
+	Mode string
+	// named pipe read by a Snapcast "pipe" source on this computer
+	PipePath string
+	// host:port of a Snapcast "tcp" source in server mode
+	TCPAddress string
+}
+
+const (
+	SnapcastModePipe = "Pipe"
+	SnapcastModeTCP  = "TCP"
+)
+
+type GenreConfig struct {
+	// Merge genres which differ only in case, spacing and punctuation
+	MergeSimilar bool
+	// Genres merged into another genre, e.g. "Rap" = "Hip-Hop"
+	Aliases map[string]string
+	// Parent genres which include the albums of their
+	// subgenres, e.g. "Electronic" = ["House", "Techno"]
+	Subgenres map[string][]string
+}
+
+// ContentFilterConfig configures the content hidden from
+// browsing, search and playback, e.g. on a shared family computer.
+type ContentFilterConfig struct {
+	Enabled      bool
+	Genres       []string
+	Keywords     []string
+	HideExplicit bool
+}
+
+// Options returns the options of the content filter,
+// which hide nothing if the filter is disabled.
+func (c ContentFilterConfig) Options() mediaprovider.ContentFilterOptions {
+	if !c.Enabled {
+		return mediaprovider.ContentFilterOptions{}
+	}
+	return mediaprovider.ContentFilterOptions{
+		Genres:       c.Genres,
+		Keywords:     c.Keywords,
+		HideExplicit: c.HideExplicit,
+	}
+}
+
+type WaveformConfig struct {
+	Enabled bool
+	// Decode the stream of tracks which are not pre-cached,
+	// which downloads them a second time
+	FromStream bool
+}
+
+type VisualizerConfig struct {
+	// Number of frames per second delivered to consumers
+	FrameRate int
+	// Number of spectrum bands
+	Bands int
+}
+
+type SkipSilenceConfig struct {
+	Enabled bool
+	// Audio below this level is considered silent
+	ThresholdDB float64
+	// Silences longer than this are shortened to this length
+	MaxGapSeconds float64
+}
+
+type SleepTimerConfig struct {
+	// The volume is faded out over this many seconds before playback stops
+	FadeOutSeconds float64
+}
+
+type RadioConfig struct {
+	// Number of recent radio mixes whose seeds and tracks are remembered,
+	// so new mixes can be steered away from them. 0 disables.
+	RecentMixesToRemember int
+	// How long a radio mix is remembered
+	MixMemoryHours int
+}
+
+type HomeConfig struct {
+	// Sections shown on the home page, in order (see AllHomeSections)
+	Sections        []string
+	ItemsPerSection int
+	// IDs of the playlists pinned to the home page, by ServerManager.DataKey
+	PinnedPlaylistIDs map[string][]string
+}
+
+type DownloadConfig struct {
+	// max number of files downloaded at once
+	MaxConcurrent int
+	// combined download bandwidth limit, or 0 for unlimited
+	MaxKBPerSecond int
+	// number of times a failed file download is retried
+	MaxRetries int
+}
+
+type RemoteControlConfig struct {
+	Enabled bool
+	Port    int
+	// If false, only connections from this computer are accepted
+	ListenOnAllInterfaces bool
+	// Generated on first enable; clients must send it with each request.
+	// Only stored here if the OS keychain isn't used (e.g. portable mode).
+	Token string
+	// If true, other apps connected to the same server (currently Jellyfin only)
+	// can "play on" and control this app. Independent of Enabled.
+	AllowServerSessionControl bool
+
+	tokenStore CredentialStore // stores the token instead of Token, if set
+}
+
+const remoteControlTokenKey = "remote-control-token"
+
+// useCredentialStore moves the token from the config to the store.
+func (r *RemoteControlConfig) useCredentialStore(creds CredentialStore) {
+	if r.Token != "" {
+		if err := creds.Set(remoteControlTokenKey, r.Token); err != nil {
+			log.Printf("error moving remote control token to credential store: %v", err)
+			return
+		}
+		r.Token = ""
+	}
+	r.tokenStore = creds
+}
+
+// CurrentToken returns the auth token, or "" if not yet generated.
+func (r *RemoteControlConfig) CurrentToken() string {
+	if r.tokenStore == nil {
+		return r.Token
+	}
+	token, _ := r.tokenStore.Get(remoteControlTokenKey)
+	return token
+}
+
+// EnsureToken generates the auth token if not yet set, and returns it.
+func (r *RemoteControlConfig) EnsureToken() string {
+	if token := r.CurrentToken(); token != "" {
+		return token
+	}
+	token := uuid.NewString()
+	if r.tokenStore == nil || r.tokenStore.Set(remoteControlTokenKey, token) != nil {
+		r.Token = token
+	}
+	return token
+}
+
+type DiscordRPCConfig struct {
+	Enabled bool
+	// ID of the Discord application to publish the activity as;
+	// its name is shown as "Listening to <name>".
+	// If empty, the app's own (res.DiscordApplicationID) is used.
+	ApplicationID string
+	// Look up album art from an external service (iTunes Search), since
+	// Discord can't load cover art from the (usually private) media server
+	LookUpAlbumArt bool
 }
 
 type ThemeConfig struct {
@@ -120,6 +477,14 @@ type ThemeConfig struct {
 
 type TranscodingConfig struct {
 	ForceRawFile bool
+	// Stream with each server's low bandwidth transcoding settings,
+	// e.g. while on a metered connection
+	LowBandwidthMode bool
+	// Lower or raise the max bit rate for the next track based on
+	// the measured network throughput and buffer underruns
+	AdaptiveBitRate    bool
+	AdaptiveMinBitRate int // kbps
+	AdaptiveMaxBitRate int // kbps
 }
 
 type Config struct {
@@ -138,7 +503,26 @@ type Config struct {
 	Scrobbling       ScrobbleConfig
 	ReplayGain       ReplayGainConfig
 	Transcoding      TranscodingConfig
+	TrackFade        TrackFadeConfig
+	SkipSilence      SkipSilenceConfig
+	Waveform         WaveformConfig
+	Visualizer       VisualizerConfig
+	SleepTimer       SleepTimerConfig
+	Radio            RadioConfig
+	Home             HomeConfig
+	Downloads        DownloadConfig
+	RemoteControl    RemoteControlConfig
+	DiscordRPC       DiscordRPCConfig
 	Theme            ThemeConfig
+	SmartPlaylists   []*SmartPlaylist
+	Genres           GenreConfig
+	ContentFilter    ContentFilterConfig
+	Snapcast         SnapcastConfig
+	AudioEvents      AudioEventsConfig
+
+	// client-side organization of playlists - see PlaylistOrganizer
+	PlaylistFolders    []*PlaylistFolder
+	PlaylistPlacements []*PlaylistPlacement
 }
 
 var SupportedStartupPages = []string{"Albums", "Favorites", "Playlists"}
@@ -161,6 +545,10 @@ func DefaultConfig(appVersionTag string) *Config {
 			SaveQueueToServer:           false,
 			ShowTrackChangeNotification: false,
 			EnableLrcLib:                true,
+			RecordListeningHistory:      true,
+			ChangePollMinutes:           5,
+			SlowRequestLogMillis:        3000,
+			ArtistInfoLanguage:          "en",
 		},
 		AlbumPage: AlbumPageConfig{
 			TracklistColumns: []string{"Artist", "Time", "Plays", "Favorite", "Rating"},
@@ -196,8 +584,11 @@ func DefaultConfig(appVersionTag string) *Config {
 			AudioDeviceName:       "auto",
 			AudioExclusive:        false,
 			InMemoryCacheSizeMB:   30,
+			PrecacheTracks:        0,
+			MaxTrackCacheSizeMB:   500,
 			Volume:                100,
 			EqualizerEnabled:      false,
+			EqualizerType:         EqualizerTypeISO15Band,
 			EqualizerPreamp:       0,
 			GraphicEqualizerBands: make([]float64, 15),
 		},
@@ -212,7 +603,71 @@ func DefaultConfig(appVersionTag string) *Config {
 			PreventClipping: true,
 		},
 		Transcoding: TranscodingConfig{
-			ForceRawFile: false,
+			ForceRawFile:       false,
+			AdaptiveBitRate:    false,
+			AdaptiveMinBitRate: 96,
+			AdaptiveMaxBitRate: 320,
+		},
+		TrackFade: TrackFadeConfig{
+			Enabled:           false,
+			DurationSeconds:   5,
+			ApplyOnManualSkip: false,
+		},
+		AudioEvents: AudioEventsConfig{
+			CallAction:           AudioEventActionNone,
+			ResumeAfterCall:      true,
+			DuckForNotifications: false,
+			DuckVolumePercent:    30,
+			PauseOnOutputChange:  false,
+		},
+		Snapcast: SnapcastConfig{
+			Enabled:    false,
+			Mode:       SnapcastModePipe,
+			PipePath:   "/tmp/snapfifo",
+			TCPAddress: "localhost:4953",
+		},
+		Waveform: WaveformConfig{
+			Enabled:    true,
+			FromStream: true,
+		},
+		Visualizer: VisualizerConfig{
+			FrameRate: 30,
+			Bands:     32,
+		},
+		SkipSilence: SkipSilenceConfig{
+			Enabled:       false,
+			ThresholdDB:   -60,
+			MaxGapSeconds: 2,
+		},
+		SleepTimer: SleepTimerConfig{
+			FadeOutSeconds: 10,
+		},
+		Radio: RadioConfig{
+			RecentMixesToRemember: 20,
+			MixMemoryHours:        72,
+		},
+		Home: HomeConfig{
+			Sections: []string{
+				HomeSectionRecentlyAdded,
+				HomeSectionRecentlyPlayed,
+				HomeSectionPinnedPlaylists,
+				HomeSectionFavoriteArtists,
+				HomeSectionOnThisDay,
+			},
+			ItemsPerSection: 20,
+		},
+		Downloads: DownloadConfig{
+			MaxConcurrent: 3,
+			MaxRetries:    3,
+		},
+		RemoteControl: RemoteControlConfig{
+			Enabled:                   false,
+			Port:                      47431,
+			AllowServerSessionControl: true,
+		},
+		DiscordRPC: DiscordRPCConfig{
+			Enabled:        false,
+			LookUpAlbumArt: true,
 		},
 		Theme: ThemeConfig{
 			Appearance: "Dark",
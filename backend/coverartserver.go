@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const coverArtServerPathPrefix = "/cover/"
+
+// coverArtServer serves the locally cached cover thumbnails over HTTP on
+// the loopback interface. file:// art URLs aren't readable by some MPRIS
+// consumers, such as sandboxed (Flatpak/Snap) desktop widgets and the
+// BlueZ AVRCP bridge which forwards art to car head units.
+type coverArtServer struct {
+	im *ImageManager
+
+	startOnce sync.Once
+	baseURL   string // empty if the server failed to start
+	server    *http.Server
+}
+
+func newCoverArtServer(im *ImageManager) *coverArtServer {
+	return &coverArtServer{im: im}
+}
+
+// ArtURL returns an HTTP URL for the cover, starting the server on first use.
+// The cover is fetched from the media server when first requested,
+// so this returns immediately. Falls back to a file:// URL.
+func (c *coverArtServer) ArtURL(coverID string) (string, error) {
+	c.startOnce.Do(c.start)
+	if c.baseURL == "" {
+		c.im.GetCoverThumbnail(coverID) // ensure image is cached locally
+		return c.im.GetCoverArtUrl(coverID)
+	}
+	// include the ID as a file name so consumers which check the
+	// URL extension to decide whether it's an image are satisfied
+	return c.baseURL + coverArtServerPathPrefix + url.PathEscape(coverID) + ".jpg", nil
+}
+
+func (c *coverArtServer) start() {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Printf("error starting cover art server: %v", err)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(coverArtServerPathPrefix, c.serveCover)
+	c.server = &http.Server{Handler: mux}
+	c.baseURL = fmt.Sprintf("http://%s", l.Addr().String())
+	go func() {
+		if err := c.server.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Printf("cover art server stopped: %v", err)
+		}
+	}()
+}
+
+func (c *coverArtServer) serveCover(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, coverArtServerPathPrefix), ".jpg")
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		http.NotFound(w, r)
+		return
+	}
+	if _, err := c.im.GetCoverThumbnail(id); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	http.ServeFile(w, r, c.im.filePathForCover(id))
+}
+
+func (c *coverArtServer) Shutdown(ctx context.Context) {
+	c.startOnce.Do(func() {}) // wait for, or prevent, a concurrent start
+	if c.server != nil {
+		c.server.Shutdown(ctx)
+	}
+}
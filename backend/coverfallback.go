@@ -0,0 +1,192 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"image"
+	"log"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// how long to remember that a cover could not be fetched
+// before trying to fetch it from the server again
+const missingCoverRetryInterval = 10 * time.Minute
+
+// CoverFallback describes the cover art to display for an item,
+// and the related items whose cover art to fall back to, in order,
+// if the item's own cover is missing.
+type CoverFallback struct {
+	// The item's own cover art ID (e.g. a track's cover)
+	CoverArtID string
+
+	// The album whose cover to fall back to
+	AlbumID string
+
+	// The artist whose image to fall back to
+	ArtistID string
+
+	// The playlist whose album covers to composite into a mosaic,
+	// if the item is a playlist. A cover album chosen for the playlist
+	// takes precedence over the playlist's own cover.
+	PlaylistID string
+
+	// The name of the item, used to generate a placeholder
+	// if no cover art is found in the fallback chain.
+	Name string
+
+	// The genre and artist name of the item, if known, used to
+	// pick the color of the generated placeholder.
+	Genre  string
+	Artist string
+}
+
+// CoverFallbackForItem returns the CoverFallback for the given media item,
+// falling back to its album and then its first artist.
+func CoverFallbackForItem(item mediaprovider.MediaItem) CoverFallback {
+	meta := item.Metadata()
+	fb := CoverFallback{
+		CoverArtID: meta.CoverArtID,
+		AlbumID:    meta.AlbumID,
+		Name:       meta.Name,
+	}
+	if len(meta.ArtistIDs) > 0 {
+		fb.ArtistID = meta.ArtistIDs[0]
+	}
+	if len(meta.Artists) > 0 {
+		fb.Artist = meta.Artists[0]
+	}
+	if tr, ok := item.(*mediaprovider.Track); ok && len(tr.Genres) > 0 {
+		fb.Genre = tr.Genres[0]
+	}
+	if meta.Album != "" {
+		fb.Name = meta.Album
+	}
+	return fb
+}
+
+// GetCoverThumbnailWithFallback is like GetCoverThumbnail, but if the item's own
+// cover is missing, it falls back progressively to the album cover, the artist image,
+// and finally a generated placeholder. It only returns an error if logged out.
+func (i *ImageManager) GetCoverThumbnailWithFallback(fb CoverFallback) (image.Image, error) {
+	return i.getCoverThumbnailWithFallback(context.Background(), fb)
+}
+
+// GetCoverThumbnailWithFallbackAsync is the async version of GetCoverThumbnailWithFallback.
+// Like GetCoverThumbnailAsync, the returned cancel func must be invoked to avoid resource leaks.
+func (i *ImageManager) GetCoverThumbnailWithFallbackAsync(fb CoverFallback, cb func(image.Image, error)) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		img, err := i.getCoverThumbnailWithFallback(ctx, fb)
+		if ctx.Err() == nil {
+			cb(img, err)
+		}
+	}()
+	return cancel
+}
+
+func (i *ImageManager) getCoverThumbnailWithFallback(ctx context.Context, fb CoverFallback) (image.Image, error) {
+	tried := make(map[string]bool)
+	tryCover := func(coverID string) image.Image {
+		if coverID == "" || tried[coverID] || ctx.Err() != nil {
+			return nil
+		}
+		tried[coverID] = true
+		if img, ok := i.GetCoverThumbnailFromCache(coverID); ok {
+			return img
+		}
+		if i.isMissingCover(coverID) {
+			return nil
+		}
+		img, err := i.fetchAndCacheCoverFromDiskOrServer(ctx, coverID, i.thumbnailCache.DefaultTTL, nil)
+		if err != nil {
+			if ctx.Err() == nil {
+				i.missingCovers.Store(coverID, time.Now())
+			}
+			return nil
+		}
+		return img
+	}
+
+	if fb.PlaylistID != "" {
+		if img := tryCover(i.fallbackCoverID("album", i.s.PlaylistCoverAlbum(fb.PlaylistID))); img != nil {
+			return img, nil
+		}
+	}
+	if img := tryCover(fb.CoverArtID); img != nil {
+		return img, nil
+	}
+	if fb.PlaylistID != "" {
+		if img := i.playlistMosaic(ctx, fb.PlaylistID, tryCover); img != nil {
+			return img, nil
+		}
+	}
+	if img := tryCover(i.fallbackCoverID("album", fb.AlbumID)); img != nil {
+		return img, nil
+	}
+	if img := tryCover(i.fallbackCoverID("artist", fb.ArtistID)); img != nil {
+		return img, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if i.s.Server == nil {
+		return nil, errors.New("logged out")
+	}
+	return i.placeholderCover(fb), nil
+}
+
+func (i *ImageManager) isMissingCover(coverID string) bool {
+	t, ok := i.missingCovers.Load(coverID)
+	if ok && time.Since(t.(time.Time)) < missingCoverRetryInterval {
+		return true
+	}
+	i.missingCovers.Delete(coverID)
+	return false
+}
+
+// fallbackCoverID resolves the cover art ID of the album or artist
+// with the given ID, remembering the result for future lookups.
+func (i *ImageManager) fallbackCoverID(kind, id string) string {
+	if id == "" {
+		return ""
+	}
+	key := kind + "-" + id
+	if coverID, ok := i.fallbackCoverIDs.Load(key); ok {
+		return coverID.(string)
+	}
+	server := i.s.Server
+	if server == nil {
+		return ""
+	}
+	var coverID string
+	switch kind {
+	case "album":
+		al, err := server.GetAlbum(id)
+		if err != nil {
+			log.Printf("error fetching album for cover fallback: %v", err)
+			return ""
+		}
+		coverID = al.CoverArtID
+	case "artist":
+		ar, err := server.GetArtist(id)
+		if err != nil {
+			log.Printf("error fetching artist for cover fallback: %v", err)
+			return ""
+		}
+		coverID = ar.CoverArtID
+	}
+	i.fallbackCoverIDs.Store(key, coverID)
+	return coverID
+}
+
+func (i *ImageManager) clearCoverFallbacks() {
+	del := func(k, _ any) bool {
+		i.fallbackCoverIDs.Delete(k)
+		i.missingCovers.Delete(k)
+		return true
+	}
+	i.fallbackCoverIDs.Range(del)
+	i.missingCovers.Range(del)
+}
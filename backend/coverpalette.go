@@ -0,0 +1,155 @@
+package backend
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync"
+
+	"github.com/cenkalti/dominantcolor"
+)
+
+const (
+	// number of color clusters found in a cover
+	coverPaletteClusters = 5
+	// number of palettes kept in memory
+	coverPaletteCacheSize = 300
+)
+
+// CoverPalette is the colors extracted from a cover image,
+// for adapting the UI theme to the playing track.
+type CoverPalette struct {
+	// the most common color which is neither too bright nor too dark
+	Dominant color.RGBA
+	// a vivid color of the cover which stands out from Dominant,
+	// or a shade of Dominant if the cover has no such color
+	Accent color.RGBA
+	// the colors found in the cover, most common first
+	Colors []color.RGBA
+	// whether Dominant is dark, so text drawn over it should be light
+	IsDark bool
+}
+
+// GradientColors returns the start and end colors
+// of a background gradient matching the cover.
+func (p *CoverPalette) GradientColors() (start, end color.RGBA) {
+	end = lerpRGB(lerpRGB(p.Dominant, p.Accent, 0.35), color.RGBA{A: 255}, 0.5)
+	return p.Dominant, end
+}
+
+// CoverPalettes extracts the palettes of cover images,
+// caching them in memory by cover art ID.
+type CoverPalettes struct {
+	im *ImageManager
+
+	mu    sync.Mutex
+	cache map[string]*CoverPalette
+	order []string // cached IDs, oldest first
+}
+
+func NewCoverPalettes(im *ImageManager) *CoverPalettes {
+	return &CoverPalettes{im: im, cache: make(map[string]*CoverPalette)}
+}
+
+// Palette returns the palette of the cover, fetching its thumbnail
+// if the palette isn't cached. It returns nil if the cover can't be loaded.
+func (c *CoverPalettes) Palette(coverID string) *CoverPalette {
+	if p := c.cached(coverID); p != nil {
+		return p
+	}
+	img, err := c.im.GetCoverThumbnail(coverID)
+	if err != nil {
+		return nil
+	}
+	return c.Extract(coverID, img)
+}
+
+// Extract returns the palette of the already loaded cover image,
+// using the cached palette of the cover ID if there is one.
+// Palettes of images without a cover ID aren't cached.
+func (c *CoverPalettes) Extract(coverID string, img image.Image) *CoverPalette {
+	if coverID == "" {
+		return ExtractCoverPalette(img)
+	}
+	if p := c.cached(coverID); p != nil {
+		return p
+	}
+	p := ExtractCoverPalette(img)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.cache[coverID]; !ok {
+		if len(c.order) >= coverPaletteCacheSize {
+			delete(c.cache, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, coverID)
+	}
+	c.cache[coverID] = p
+	return p
+}
+
+func (c *CoverPalettes) cached(coverID string) *CoverPalette {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache[coverID]
+}
+
+// ExtractCoverPalette finds the palette of the image.
+func ExtractCoverPalette(img image.Image) *CoverPalette {
+	p := &CoverPalette{Dominant: dominantcolor.Find(img)}
+	var bestScore float64
+	for _, c := range dominantcolor.FindWeight(img, coverPaletteClusters) {
+		p.Colors = append(p.Colors, c.RGBA)
+		// favor saturated colors, distinct from the dominant
+		// color, and not so rare they're just noise
+		_, s, l := rgbToHSL(c.RGBA)
+		score := s * (1 - math.Abs(l-0.55)) * colorDistance(c.RGBA, p.Dominant) * math.Sqrt(c.Weight)
+		if score > bestScore {
+			bestScore, p.Accent = score, c.RGBA
+		}
+	}
+	if bestScore < 0.02 {
+		// no vivid color; use a lighter or darker shade of the dominant color
+		h, s, l := rgbToHSL(p.Dominant)
+		if l < 0.5 {
+			p.Accent = hslToRGB(h, s, math.Min(l+0.35, 0.9))
+		} else {
+			p.Accent = hslToRGB(h, s, math.Max(l-0.35, 0.1))
+		}
+	}
+	p.IsDark = luminance(p.Dominant) < 0.5
+	return p
+}
+
+// rgbToHSL converts the color to hue (degrees), saturation and lightness (0-1).
+func rgbToHSL(c color.RGBA) (h, s, l float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	mx, mn := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	l = (mx + mn) / 2
+	d := mx - mn
+	if d == 0 {
+		return 0, 0, l
+	}
+	s = d / (1 - math.Abs(2*l-1))
+	switch mx {
+	case r:
+		h = math.Mod((g-b)/d+6, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return h * 60, s, l
+}
+
+// colorDistance returns the euclidean distance of the colors
+// in RGB space, scaled to 0-1.
+func colorDistance(a, b color.RGBA) float64 {
+	dr, dg, db := float64(a.R)-float64(b.R), float64(a.G)-float64(b.G), float64(a.B)-float64(b.B)
+	return math.Sqrt(dr*dr+dg*dg+db*db) / (255 * math.Sqrt(3))
+}
+
+// luminance returns the perceived brightness of the color (0-1).
+func luminance(c color.RGBA) float64 {
+	return (0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)) / 255
+}
@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+)
+
+// ErrCredentialNotFound is returned by a CredentialStore
+// if there is no secret stored under the key.
+var ErrCredentialNotFound = errors.New("credential not found")
+
+// CredentialStore stores secrets, such as server passwords, by key.
+type CredentialStore interface {
+	Get(key string) (string, error)
+	Set(key, secret string) error
+	Delete(key string) error
+}
+
+// NewCredentialStore returns a store backed by the OS keychain
+// (Secret Service, macOS Keychain, or Windows Credential Manager),
+// or an in-memory store which forgets the secrets on exit
+// if useKeyring is false (e.g. in portable mode).
+func NewCredentialStore(appName string, useKeyring bool) CredentialStore {
+	if useKeyring {
+		return keyringCredentialStore{service: appName}
+	}
+	return &memoryCredentialStore{secrets: make(map[string]string)}
+}
+
+type keyringCredentialStore struct {
+	service string
+}
+
+func (k keyringCredentialStore) Get(key string) (string, error) {
+	secret, err := keyring.Get(k.service, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrCredentialNotFound
+	}
+	return secret, err
+}
+
+func (k keyringCredentialStore) Set(key, secret string) error {
+	return keyring.Set(k.service, key, secret)
+}
+
+func (k keyringCredentialStore) Delete(key string) error {
+	err := keyring.Delete(k.service, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+type memoryCredentialStore struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+func (m *memoryCredentialStore) Get(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, ok := m.secrets[key]
+	if !ok {
+		return "", ErrCredentialNotFound
+	}
+	return secret, nil
+}
+
+func (m *memoryCredentialStore) Set(key, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secrets[key] = secret
+	return nil
+}
+
+func (m *memoryCredentialStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.secrets, key)
+	return nil
+}
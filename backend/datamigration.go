@@ -0,0 +1,202 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/util"
+)
+
+const dataVersionsFile = "data_versions.json"
+
+// localDataStore describes a file in the config dir
+// whose on-disk format is versioned.
+type localDataStore struct {
+	Name string // file name relative to the config dir
+
+	// Migrations[i] upgrades the file in place from version i+1 to i+2.
+	// To change a store's format, append a migration; never edit old ones.
+	Migrations []func(path string) error
+
+	// Check verifies that the file is readable in the current format.
+	Check func(path string) error
+}
+
+func (s localDataStore) currentVersion() int {
+	return len(s.Migrations) + 1
+}
+
+// localDataStores lists all versioned local data. Files that aren't
+// listed here (config file, caches) are either tolerant of format
+// changes or safe to discard. All stores are included in app data archives.
+var localDataStores = []localDataStore{
+	{
+		Name:  savedQueueFile,
+		Check: checkJSONFile(&serializedSavedPlayQueue{}),
+	},
+	{
+		Name:  listeningHistoryFile,
+		Check: checkJSONLinesFile(),
+	},
+	{
+		Name:  searchHistoryFile,
+		Check: checkJSONFile(&map[string]*serverSearchHistory{}),
+	},
+	{
+		Name:  radioSeedsFile,
+		Check: checkJSONFile(&[]RadioMix{}),
+	},
+}
+
+func localDataStoreNames() []string {
+	names := make([]string, len(localDataStores))
+	for i, s := range localDataStores {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// migrateLocalData checks the integrity of each store in dir and
+// upgrades it to the current version, backing it up first.
+// A store which fails its integrity check is moved aside so the app can
+// start fresh without losing the data for good.
+func migrateLocalData(dir string, stores []localDataStore) error {
+	versionsPath := filepath.Join(dir, dataVersionsFile)
+	versions := make(map[string]int)
+	if b, err := os.ReadFile(versionsPath); err == nil {
+		if err := json.Unmarshal(b, &versions); err != nil {
+			log.Printf("ignoring unreadable %s: %v", dataVersionsFile, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	for _, s := range stores {
+		path := filepath.Join(dir, s.Name)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			versions[s.Name] = s.currentVersion()
+			continue
+		}
+		v, ok := versions[s.Name]
+		if !ok {
+			v = 1 // store predates version tracking
+		}
+		if v > s.currentVersion() {
+			// written by a newer version of the app; leave it untouched
+			log.Printf("%s has version %d, newer than supported version %d", s.Name, v, s.currentVersion())
+			continue
+		}
+		if v < s.currentVersion() {
+			newV, err := migrateStore(s, path, v)
+			if err != nil {
+				log.Printf("error migrating %s from version %d: %v", s.Name, v, err)
+			}
+			versions[s.Name] = newV
+			if newV < s.currentVersion() {
+				continue // don't integrity check a store we couldn't upgrade
+			}
+		}
+		if s.Check != nil {
+			if err := s.Check(path); err != nil {
+				log.Printf("integrity check failed for %s: %v", s.Name, err)
+				quarantineFile(path)
+			}
+		}
+		versions[s.Name] = s.currentVersion()
+	}
+
+	b, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(versionsPath, b)
+}
+
+// migrateStore runs the migrations from version v to current, after backing
+// up the file. If a migration fails, the backup is restored and the version
+// it was at is returned along with the error.
+func migrateStore(s localDataStore, path string, v int) (int, error) {
+	backupPath := fmt.Sprintf("%s.v%d.bak", path, v)
+	if err := util.CopyFile(path, backupPath); err != nil {
+		return v, fmt.Errorf("backup failed: %w", err)
+	}
+	log.Printf("migrating %s from version %d to %d (backup saved to %s)",
+		s.Name, v, s.currentVersion(), filepath.Base(backupPath))
+	for i := v - 1; i < len(s.Migrations); i++ {
+		if err := s.Migrations[i](path); err != nil {
+			if rerr := util.CopyFile(backupPath, path); rerr != nil {
+				log.Printf("error restoring %s from backup: %v", s.Name, rerr)
+			}
+			return v, err
+		}
+	}
+	return s.currentVersion(), nil
+}
+
+// quarantineFile renames a corrupt file out of the way, keeping it for recovery.
+func quarantineFile(path string) {
+	dest := fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102-150405"))
+	if err := os.Rename(path, dest); err != nil {
+		log.Printf("error moving aside corrupt file: %v", err)
+		return
+	}
+	log.Printf("moved corrupt file to %s", filepath.Base(dest))
+}
+
+func checkJSONFile(v any) func(string) error {
+	return func(path string) error {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(b, v)
+	}
+}
+
+// checkJSONLinesFile checks that the file is a JSON lines file.
+// Individual bad lines (e.g. truncated by a crash) are tolerated since
+// the store's loader skips them; a file with no valid lines at all is not.
+func checkJSONLinesFile() func(string) error {
+	return func(path string) error {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		var lines, valid int
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			lines++
+			if json.Valid(scanner.Bytes()) {
+				valid++
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		if lines > 0 && valid == 0 {
+			return errors.New("no valid JSON lines")
+		}
+		return nil
+	}
+}
+
+// writeFileAtomic writes to a temp file and renames it into place,
+// so that a crash mid-write can't leave a corrupted file.
+func writeFileAtomic(path string, b []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
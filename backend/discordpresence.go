@@ -0,0 +1,201 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/discordrpc"
+	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/dweymouth/supersonic/res"
+)
+
+const (
+	// minimum time between attempts to connect to Discord, if it's not running
+	discordReconnectInterval = 30 * time.Second
+	albumArtLookupTimeout    = 5 * time.Second
+)
+
+// DiscordPresence publishes the currently playing track
+// to Discord Rich Presence, if enabled.
+type DiscordPresence struct {
+	pm   *PlaybackManager
+	np   *NowPlayingBroadcaster
+	conf *DiscordRPCConfig
+
+	updateCh chan struct{}
+
+	// copy of conf taken by SettingsChanged, since conf is
+	// edited from the UI while the update goroutine runs
+	settingsMu sync.Mutex
+	settings   DiscordRPCConfig
+
+	// accessed only from the update goroutine
+	client             *discordrpc.Client
+	clientAppID        string
+	lastConnectAttempt time.Time
+}
+
+func NewDiscordPresence(ctx context.Context, pm *PlaybackManager, np *NowPlayingBroadcaster, conf *DiscordRPCConfig) *DiscordPresence {
+	d := &DiscordPresence{
+		pm:       pm,
+		np:       np,
+		conf:     conf,
+		updateCh: make(chan struct{}, 1),
+		settings: *conf,
+	}
+	update := func() { d.requestUpdate() }
+	np.OnChange(func(*NowPlayingInfo) { update() })
+	pm.OnPlaying(update)
+	pm.OnPaused(update)
+	pm.OnStopped(update)
+	pm.OnSeek(update)
+	go d.run(ctx)
+	return d
+}
+
+// SettingsChanged applies changes to the DiscordRPCConfig,
+// e.g. enabling or disabling the integration at runtime.
+func (d *DiscordPresence) SettingsChanged() {
+	d.settingsMu.Lock()
+	d.settings = *d.conf
+	d.settingsMu.Unlock()
+	d.requestUpdate()
+}
+
+func (d *DiscordPresence) currentSettings() DiscordRPCConfig {
+	d.settingsMu.Lock()
+	defer d.settingsMu.Unlock()
+	return d.settings
+}
+
+func (d *DiscordPresence) requestUpdate() {
+	select {
+	case d.updateCh <- struct{}{}:
+	default: // an update is already pending
+	}
+}
+
+func (d *DiscordPresence) run(ctx context.Context) {
+	// periodically retry so the activity appears if Discord is started later
+	t := time.NewTicker(discordReconnectInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			d.disconnect()
+			return
+		case <-d.updateCh:
+			d.update()
+		case <-t.C:
+			if d.client == nil && d.currentSettings().Enabled {
+				d.update()
+			}
+		}
+	}
+}
+
+func (d *DiscordPresence) update() {
+	settings := d.currentSettings()
+	appID := settings.ApplicationID
+	if appID == "" {
+		appID = res.DiscordApplicationID
+	}
+	if d.client != nil && (!settings.Enabled || appID != d.clientAppID) {
+		_ = d.client.SetActivity(nil)
+		d.disconnect()
+		d.lastConnectAttempt = time.Time{}
+	}
+	if !settings.Enabled || appID == "" {
+		return
+	}
+	if d.client == nil {
+		// the periodic retry ticks at the same interval, so allow some slack
+		if time.Since(d.lastConnectAttempt) < discordReconnectInterval/2 {
+			return
+		}
+		d.lastConnectAttempt = time.Now()
+		c, err := discordrpc.Connect(appID)
+		if err != nil {
+			return // Discord is probably not running; not worth logging
+		}
+		d.client = c
+		d.clientAppID = appID
+	}
+	if err := d.client.SetActivity(d.buildActivity(settings.LookUpAlbumArt)); err != nil {
+		log.Printf("error updating Discord presence: %v", err)
+		d.disconnect()
+	}
+}
+
+func (d *DiscordPresence) disconnect() {
+	if d.client != nil {
+		d.client.Close()
+		d.client = nil
+	}
+}
+
+// buildActivity returns the activity for the current playback state,
+// or nil to clear it.
+func (d *DiscordPresence) buildActivity(lookUpAlbumArt bool) *discordrpc.Activity {
+	info := d.np.Current()
+	status := d.pm.PlayerStatus()
+	if info.Item == nil || status.State == player.Stopped {
+		return nil
+	}
+	meta := info.Metadata
+	activity := &discordrpc.Activity{
+		Type:    discordrpc.ActivityTypeListening,
+		Details: meta.Name,
+		State:   strings.Join(meta.Artists, ", "),
+	}
+	if status.State == player.Paused {
+		activity.State = "Paused"
+	} else if status.Duration > 0 {
+		start := time.Now().Add(-time.Duration(status.TimePos * float64(time.Second)))
+		activity.Timestamps = &discordrpc.Timestamps{
+			Start: start.UnixMilli(),
+			End:   start.Add(time.Duration(status.Duration * float64(time.Second))).UnixMilli(),
+		}
+	}
+	if meta.Album != "" {
+		activity.Assets = &discordrpc.Assets{LargeText: meta.Album}
+		if lookUpAlbumArt {
+			// Discord can't load images from the media server
+			activity.Assets.LargeImage = d.np.ExternalArtURL(info)
+		}
+	}
+	return activity
+}
+
+func lookUpITunesArtURL(artist, album string) (string, error) {
+	q := url.Values{
+		"term":   {artist + " " + album},
+		"entity": {"album"},
+		"limit":  {"1"},
+	}
+	cli := http.Client{Timeout: albumArtLookupTimeout}
+	resp, err := cli.Get("https://itunes.apple.com/search?" + q.Encode())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Results []struct {
+			ArtworkURL100 string `json:"artworkUrl100"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if len(result.Results) == 0 {
+		return "", nil
+	}
+	// request a larger size than the 100px thumbnail
+	return strings.Replace(result.Results[0].ArtworkURL100, "100x100bb", "512x512bb", 1), nil
+}
@@ -0,0 +1,164 @@
+// Package discordrpc implements a minimal client for Discord's local
+// IPC protocol, sufficient for publishing Rich Presence activities.
+package discordrpc
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// IPC frame opcodes
+const (
+	opHandshake uint32 = 0
+	opFrame     uint32 = 1
+	opClose     uint32 = 2
+)
+
+// ActivityTypeListening shows the activity as "Listening to <app name>".
+const ActivityTypeListening = 2
+
+// how long to wait for Discord to respond before giving up
+const ioTimeout = 5 * time.Second
+
+var ErrClosed = errors.New("connection closed by Discord")
+
+type Activity struct {
+	Type       int         `json:"type"`
+	Details    string      `json:"details,omitempty"`
+	State      string      `json:"state,omitempty"`
+	Timestamps *Timestamps `json:"timestamps,omitempty"`
+	Assets     *Assets     `json:"assets,omitempty"`
+}
+
+// Timestamps are unix times in milliseconds. Discord renders the
+// elapsed or remaining time from them.
+type Timestamps struct {
+	Start int64 `json:"start,omitempty"`
+	End   int64 `json:"end,omitempty"`
+}
+
+type Assets struct {
+	// Either the key of an asset uploaded to the Discord app,
+	// or an external https:// image URL
+	LargeImage string `json:"large_image,omitempty"`
+	LargeText  string `json:"large_text,omitempty"`
+}
+
+type Client struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Connect connects to the locally running Discord client and performs
+// the handshake for the given Discord application ID. It returns an error
+// if Discord is not running.
+func Connect(applicationID string) (*Client, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{conn: conn}
+	handshake := map[string]any{"v": 1, "client_id": applicationID}
+	if _, err := c.send(opHandshake, handshake); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("discord handshake failed: %w", err)
+	}
+	return c, nil
+}
+
+// SetActivity sets the Rich Presence activity, or clears it if nil.
+func (c *Client) SetActivity(activity *Activity) error {
+	payload := map[string]any{
+		"cmd":   "SET_ACTIVITY",
+		"nonce": newNonce(),
+		"args": map[string]any{
+			"pid":      os.Getpid(),
+			"activity": activity,
+		},
+	}
+	resp, err := c.send(opFrame, payload)
+	if err != nil {
+		return err
+	}
+	var r struct {
+		Evt  string `json:"evt"`
+		Data struct {
+			Message string `json:"message"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(resp, &r) == nil && r.Evt == "ERROR" {
+		return fmt.Errorf("discord: %s", r.Data.Message)
+	}
+	return nil
+}
+
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = writeFrame(c.conn, opClose, []byte("{}"))
+	return c.conn.Close()
+}
+
+// send writes a frame and reads Discord's response to it.
+func (c *Client) send(opcode uint32, payload any) ([]byte, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetDeadline(time.Now().Add(ioTimeout))
+	if err := writeFrame(c.conn, opcode, b); err != nil {
+		return nil, err
+	}
+	op, resp, err := readFrame(c.conn)
+	if err != nil {
+		return nil, err
+	}
+	if op == opClose {
+		return nil, ErrClosed
+	}
+	return resp, nil
+}
+
+// frames are a little-endian opcode and length, followed by the JSON payload
+func writeFrame(w io.Writer, opcode uint32, payload []byte) error {
+	buf := make([]byte, 8+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], opcode)
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(len(payload)))
+	copy(buf[8:], payload)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readFrame(r io.Reader) (uint32, []byte, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := binary.LittleEndian.Uint32(header[0:4])
+	length := binary.LittleEndian.Uint32(header[4:8])
+	if length > 1<<20 {
+		return 0, nil, errors.New("discord frame too large")
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return opcode, payload, nil
+}
+
+func newNonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,23 @@
+package discordrpc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeFrame(&buf, opFrame, []byte(`{"cmd":"SET_ACTIVITY"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 8+22 {
+		t.Errorf("unexpected frame length %d", buf.Len())
+	}
+	op, payload, err := readFrame(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op != opFrame || string(payload) != `{"cmd":"SET_ACTIVITY"}` {
+		t.Errorf("got op %d, payload %q", op, payload)
+	}
+}
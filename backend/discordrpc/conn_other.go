@@ -0,0 +1,35 @@
+//go:build !windows
+
+package discordrpc
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// dial connects to the first available Discord IPC socket.
+// Discord creates it in the runtime or temp dir, numbered 0-9.
+func dial() (net.Conn, error) {
+	dir := os.TempDir()
+	for _, env := range []string{"XDG_RUNTIME_DIR", "TMPDIR", "TMP", "TEMP"} {
+		if d := os.Getenv(env); d != "" {
+			dir = d
+			break
+		}
+	}
+	// Flatpak and Snap installs of Discord put the socket in a subdirectory
+	subdirs := []string{"", "app/com.discordapp.Discord", "snap.discord"}
+	var lastErr error
+	for _, sub := range subdirs {
+		for i := 0; i < 10; i++ {
+			conn, err := net.Dial("unix", filepath.Join(dir, sub, fmt.Sprintf("discord-ipc-%d", i)))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
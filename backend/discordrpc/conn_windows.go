@@ -0,0 +1,25 @@
+//go:build windows
+
+package discordrpc
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// dial connects to the first available Discord IPC pipe, numbered 0-9.
+func dial() (net.Conn, error) {
+	timeout := 2 * time.Second
+	var lastErr error
+	for i := 0; i < 10; i++ {
+		conn, err := winio.DialPipe(fmt.Sprintf(`\\.\pipe\discord-ipc-%d`, i), &timeout)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,607 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/tagwriter"
+)
+
+// DownloadStatus is the state of a DownloadJob.
+type DownloadStatus int
+
+const (
+	DownloadQueued DownloadStatus = iota
+	DownloadRunning
+	DownloadCompleted
+	DownloadFailed
+	DownloadCanceled
+)
+
+func (s DownloadStatus) String() string {
+	switch s {
+	case DownloadQueued:
+		return "Queued"
+	case DownloadRunning:
+		return "Downloading"
+	case DownloadCompleted:
+		return "Completed"
+	case DownloadFailed:
+		return "Failed"
+	case DownloadCanceled:
+		return "Canceled"
+	}
+	return ""
+}
+
+// DownloadProgress is a snapshot of the progress of a DownloadJob.
+type DownloadProgress struct {
+	Status      DownloadStatus
+	TracksDone  int
+	TracksTotal int
+	BytesDone   int64
+	// total size of the job's files, counting only those whose size is known
+	BytesTotal int64
+	// error of the first track which failed, if any
+	Err error
+}
+
+// DownloadJob is a queued download of one track to a file,
+// or of several tracks into a folder structure.
+type DownloadJob struct {
+	Name   string
+	Tracks []*mediaprovider.Track
+
+	// Called (from a background goroutine) as the job progresses.
+	// Must be set before the job is started.
+	OnProgress func(DownloadProgress)
+
+	dest       string // file path for a single file job, else the base folder
+	singleFile bool
+
+	mu       sync.Mutex
+	progress DownloadProgress
+	done     []bool  // whether each track is done
+	sizes    []int64 // size of each track's file, if known
+	read     []int64 // bytes downloaded of each track's file
+	cancel   context.CancelFunc
+}
+
+// Progress returns the current progress of the job.
+func (j *DownloadJob) Progress() DownloadProgress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.progress
+}
+
+// Destination returns the file (for a single track) or folder the job saves into.
+func (j *DownloadJob) Destination() string {
+	return j.dest
+}
+
+func (j *DownloadJob) update(f func()) {
+	j.mu.Lock()
+	f()
+	j.progress.TracksDone, j.progress.BytesDone, j.progress.BytesTotal = 0, 0, 0
+	for i := range j.Tracks {
+		if j.done[i] {
+			j.progress.TracksDone++
+		}
+		j.progress.BytesDone += j.read[i]
+		j.progress.BytesTotal += max(j.sizes[i], 0)
+	}
+	p := j.progress
+	j.mu.Unlock()
+	if j.OnProgress != nil {
+		j.OnProgress(p)
+	}
+}
+
+// DownloadManager runs queued downloads in the background, limiting the number
+// of files downloaded at once and the total bandwidth used. Failed files are
+// retried, resuming partway through if the server supports it.
+type DownloadManager struct {
+	sm         *ServerManager
+	im         *ImageManager
+	config     *DownloadConfig
+	embedTags  func(*mediaprovider.Track) *tagwriter.Tags
+	throttle   downloadThrottle
+	ctx        context.Context
+	slotsMu    sync.Mutex
+	slotsCond  *sync.Cond
+	slotsInUse int
+
+	jobsMu sync.Mutex
+	jobs   []*DownloadJob
+
+	localMu sync.Mutex
+	local   map[string]string // track ID -> path of files downloaded this session
+}
+
+// NewDownloadManager creates a DownloadManager. embedTags returns the tags
+// to embed into each downloaded file, or nil to save the file unchanged.
+func NewDownloadManager(ctx context.Context, sm *ServerManager, im *ImageManager, config *DownloadConfig, embedTags func(*mediaprovider.Track) *tagwriter.Tags) *DownloadManager {
+	d := &DownloadManager{sm: sm, im: im, config: config, embedTags: embedTags, ctx: ctx, local: make(map[string]string)}
+	d.slotsCond = sync.NewCond(&d.slotsMu)
+	return d
+}
+
+// NewTrackJob creates a job to download a single track to the given file path.
+func (d *DownloadManager) NewTrackJob(track *mediaprovider.Track, filePath string) *DownloadJob {
+	return d.newJob(track.Title, []*mediaprovider.Track{track}, filePath, true)
+}
+
+// NewBatchJob creates a job to download tracks (eg of an album or playlist)
+// into folder, saving them as <folder>/<artist>/<album>/<track>,
+// along with the album cover.
+func (d *DownloadManager) NewBatchJob(name string, tracks []*mediaprovider.Track, folder string) *DownloadJob {
+	return d.newJob(name, tracks, folder, false)
+}
+
+func (d *DownloadManager) newJob(name string, tracks []*mediaprovider.Track, dest string, singleFile bool) *DownloadJob {
+	n := len(tracks)
+	j := &DownloadJob{
+		Name:       name,
+		Tracks:     tracks,
+		dest:       dest,
+		singleFile: singleFile,
+		done:       make([]bool, n),
+		sizes:      make([]int64, n),
+		read:       make([]int64, n),
+	}
+	j.progress.TracksTotal = n
+	for i, tr := range tracks {
+		j.sizes[i] = tr.Size
+	}
+	return j
+}
+
+// Start queues the job to run in the background. A failed or
+// canceled job can be started again to retry its unfinished tracks.
+func (d *DownloadManager) Start(job *DownloadJob) {
+	job.mu.Lock()
+	if job.cancel != nil && (job.progress.Status == DownloadQueued || job.progress.Status == DownloadRunning) {
+		job.mu.Unlock()
+		return // already started
+	}
+	job.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	job.update(func() {
+		job.progress.Status = DownloadQueued
+		job.progress.Err = nil
+		job.cancel = cancel
+	})
+	d.jobsMu.Lock()
+	if !containsJob(d.jobs, job) {
+		d.jobs = append(d.jobs, job)
+	}
+	d.jobsMu.Unlock()
+	go d.runJob(ctx, job)
+}
+
+// Cancel stops the job, discarding partially downloaded files.
+func (d *DownloadManager) Cancel(job *DownloadJob) {
+	job.mu.Lock()
+	cancel := job.cancel
+	job.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// Jobs returns the jobs which have been started, oldest first.
+func (d *DownloadManager) Jobs() []*DownloadJob {
+	d.jobsMu.Lock()
+	defer d.jobsMu.Unlock()
+	return append([]*DownloadJob(nil), d.jobs...)
+}
+
+// ClearFinished removes completed jobs from the list returned by Jobs.
+func (d *DownloadManager) ClearFinished() {
+	d.jobsMu.Lock()
+	defer d.jobsMu.Unlock()
+	jobs := d.jobs[:0]
+	for _, j := range d.jobs {
+		if j.Progress().Status != DownloadCompleted {
+			jobs = append(jobs, j)
+		}
+	}
+	d.jobs = jobs
+}
+
+func containsJob(jobs []*DownloadJob, job *DownloadJob) bool {
+	for _, j := range jobs {
+		if j == job {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *DownloadManager) runJob(ctx context.Context, job *DownloadJob) {
+	server := d.sm.Server
+	if server == nil {
+		job.update(func() {
+			job.progress.Status = DownloadFailed
+			job.progress.Err = errors.New("not connected to a server")
+		})
+		return
+	}
+
+	job.mu.Lock()
+	done := append([]bool(nil), job.done...)
+	job.mu.Unlock()
+
+	var pending []int
+	for i := range job.Tracks {
+		if !done[i] { // else finished in an earlier run
+			pending = append(pending, i)
+		}
+	}
+
+	var coversMu sync.Mutex
+	coversSaved := make(map[string]bool)
+	download := func(i int) {
+		tr := job.Tracks[i]
+		path := job.dest
+		if !job.singleFile {
+			dir := filepath.Join(job.dest, trackFolder(tr))
+			coversMu.Lock()
+			if !coversSaved[dir] && tr.CoverArtID != "" {
+				coversSaved[dir] = true
+				coversMu.Unlock()
+				d.saveCover(dir, tr.CoverArtID)
+			} else {
+				coversMu.Unlock()
+			}
+			path = filepath.Join(dir, trackFileName(tr))
+		}
+		err := d.downloadWithRetry(ctx, server, job, i, path)
+		job.update(func() {
+			if err == nil {
+				job.done[i] = true
+			} else if job.progress.Err == nil && ctx.Err() == nil {
+				job.progress.Err = err
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Printf("error downloading %s: %v", tr.Title, err)
+		}
+	}
+
+	// a fixed number of workers per job, which also share the
+	// download slots with the workers of other running jobs
+	tracks := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(d.config.MaxConcurrent, 1), len(pending)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range tracks {
+				if !d.acquireSlot(ctx) {
+					return
+				}
+				job.update(func() { job.progress.Status = DownloadRunning })
+				download(i)
+				d.releaseSlot()
+			}
+		}()
+	}
+feed:
+	for _, i := range pending {
+		select {
+		case tracks <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(tracks)
+	wg.Wait()
+
+	job.update(func() {
+		switch {
+		case ctx.Err() != nil:
+			job.progress.Status = DownloadCanceled
+		case job.progress.Err != nil:
+			job.progress.Status = DownloadFailed
+		default:
+			job.progress.Status = DownloadCompleted
+		}
+	})
+}
+
+// acquireSlot waits until fewer than the configured max number
+// of files are downloading. Returns false if ctx is canceled first.
+func (d *DownloadManager) acquireSlot(ctx context.Context) bool {
+	stop := context.AfterFunc(ctx, func() {
+		d.slotsMu.Lock()
+		d.slotsCond.Broadcast()
+		d.slotsMu.Unlock()
+	})
+	defer stop()
+
+	d.slotsMu.Lock()
+	defer d.slotsMu.Unlock()
+	for d.slotsInUse >= max(d.config.MaxConcurrent, 1) {
+		if ctx.Err() != nil {
+			return false
+		}
+		d.slotsCond.Wait()
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	d.slotsInUse++
+	return true
+}
+
+func (d *DownloadManager) releaseSlot() {
+	d.slotsMu.Lock()
+	d.slotsInUse--
+	d.slotsCond.Broadcast()
+	d.slotsMu.Unlock()
+}
+
+// downloadWithRetry downloads the job's ith track to path, retrying on failure.
+// The file is downloaded to path+".part", so it can be resumed if the
+// server supports it, and then moved to path, embedding tags if enabled.
+func (d *DownloadManager) downloadWithRetry(ctx context.Context, server mediaprovider.MediaProvider, job *DownloadJob, i int, path string) error {
+	track := job.Tracks[i]
+	partPath := path + ".part"
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	var suggestedName string
+	var err error
+	for attempt := 0; attempt <= d.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			log.Printf("retrying download of %s (attempt %d): %v", track.Title, attempt+1, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Duration(attempt) * 2 * time.Second):
+			}
+		}
+		if ctx.Err() != nil {
+			os.Remove(partPath)
+			return ctx.Err()
+		}
+		if suggestedName, err = d.downloadPart(ctx, server, job, i, partPath); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			os.Remove(partPath)
+		}
+		return err
+	}
+	if filepath.Ext(path) == "" {
+		path += filepath.Ext(suggestedName)
+	}
+	if err := d.finishFile(partPath, path, track); err != nil {
+		return err
+	}
+	d.localMu.Lock()
+	d.local[track.ID] = path
+	d.localMu.Unlock()
+	return nil
+}
+
+// LocalPath returns the path of the track's file if it was downloaded
+// during this session and the file still exists.
+func (d *DownloadManager) LocalPath(trackID string) (string, bool) {
+	d.localMu.Lock()
+	path, ok := d.local[trackID]
+	d.localMu.Unlock()
+	if !ok {
+		return "", false
+	}
+	_, err := os.Stat(path)
+	return path, err == nil
+}
+
+// downloadPart downloads the track to partPath, continuing from the end of an
+// existing partial file if possible. Returns the file name suggested by the server.
+func (d *DownloadManager) downloadPart(ctx context.Context, server mediaprovider.MediaProvider, job *DownloadJob, i int, partPath string) (string, error) {
+	track := job.Tracks[i]
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	var r io.Reader
+	var err error
+	resumable, canResume := mediaprovider.As[mediaprovider.SupportsDownloadResume](server)
+	if offset > 0 && canResume {
+		r, err = resumable.DownloadTrackFrom(track.ID, offset)
+	} else {
+		offset = 0
+		r, err = server.DownloadTrack(track.ID)
+	}
+	if err != nil {
+		return "", err
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+	var suggestedName string
+	if td, ok := r.(*mediaprovider.TrackDownload); ok {
+		suggestedName = td.FileName
+		if td.Size >= 0 {
+			job.update(func() { job.sizes[i] = td.Size })
+		}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	job.update(func() { job.read[i] = offset })
+	buf := make([]byte, 32*1024)
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			if err := d.throttle.wait(ctx, n, d.config.MaxKBPerSecond*1024); err != nil {
+				return "", err
+			}
+			if _, err := f.Write(buf[:n]); err != nil {
+				return "", err
+			}
+			job.update(func() { job.read[i] += int64(n) })
+		}
+		if rerr == io.EOF {
+			return suggestedName, nil
+		} else if rerr != nil {
+			return "", rerr
+		}
+	}
+}
+
+// finishFile moves the completed download at partPath to path, embedding tags if enabled.
+func (d *DownloadManager) finishFile(partPath, path string, track *mediaprovider.Track) error {
+	var tags *tagwriter.Tags
+	if d.embedTags != nil {
+		tags = d.embedTags(track)
+	}
+	if tags == nil {
+		return os.Rename(partPath, path)
+	}
+
+	in, err := os.Open(partPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(partPath)
+	defer in.Close()
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	embedded, err := tagwriter.Copy(out, in, tags)
+	if err == nil && !embedded {
+		log.Printf("Embedding tags not supported for file format of %s", track.FilePath)
+	}
+	return err
+}
+
+// saveCover saves the album cover as cover.jpg in dir, if not already present.
+func (d *DownloadManager) saveCover(dir, coverArtID string) {
+	path := filepath.Join(dir, "cover.jpg")
+	if _, err := os.Stat(path); err == nil {
+		return
+	}
+	img, err := d.im.GetFullSizeCoverArt(coverArtID)
+	if err != nil {
+		log.Printf("error fetching cover art for download: %v", err)
+		return
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("error saving cover art: %v", err)
+		return
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		log.Printf("error saving cover art: %v", err)
+		return
+	}
+	defer f.Close()
+	if err := jpeg.Encode(f, img, &jpeg.Options{Quality: 90}); err != nil {
+		log.Printf("error saving cover art: %v", err)
+	}
+}
+
+// trackFolder returns the relative <album artist>/<album> folder to save a track into.
+func trackFolder(tr *mediaprovider.Track) string {
+	artist := "Unknown Artist"
+	if len(tr.ArtistNames) > 0 {
+		artist = tr.ArtistNames[0]
+	}
+	album := tr.Album
+	if album == "" {
+		album = "Unknown Album"
+	}
+	return filepath.Join(sanitizeFileName(artist), sanitizeFileName(album))
+}
+
+// trackFileName returns the file name to save a track as, eg "1-02 Title.flac".
+func trackFileName(tr *mediaprovider.Track) string {
+	name := tr.Title
+	if tr.TrackNumber > 0 {
+		name = fmt.Sprintf("%02d %s", tr.TrackNumber, name)
+		if tr.DiscNumber > 1 {
+			name = fmt.Sprintf("%d-%s", tr.DiscNumber, name)
+		}
+	}
+	return sanitizeFileName(name) + filepath.Ext(tr.FilePath)
+}
+
+// sanitizeFileName replaces characters which are not allowed in file names
+// on some platforms.
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	// Windows doesn't allow trailing dots or spaces
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// downloadThrottle limits the combined rate of all downloads.
+type downloadThrottle struct {
+	mu   sync.Mutex
+	next time.Time // when the next bytes may be written
+}
+
+// wait blocks as needed so that bytes are downloaded no faster
+// than bytesPerSec, or returns immediately if bytesPerSec <= 0.
+func (t *downloadThrottle) wait(ctx context.Context, bytes, bytesPerSec int) error {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	t.mu.Lock()
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	delay := t.next.Sub(now)
+	t.next = t.next.Add(time.Duration(bytes) * time.Second / time.Duration(bytesPerSec))
+	t.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
@@ -0,0 +1,95 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"log"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/tagwriter"
+)
+
+// DownloadTags returns the tags to embed into a downloaded track file,
+// fetching its lyrics and full size cover art from the server.
+func (a *App) DownloadTags(track *mediaprovider.Track) *tagwriter.Tags {
+	tags := &tagwriter.Tags{
+		Title:       track.Title,
+		Artists:     track.ArtistNames,
+		Album:       track.Album,
+		Genres:      track.Genres,
+		Composer:    track.Composer,
+		Year:        track.Year,
+		TrackNumber: track.TrackNumber,
+		DiscNumber:  track.DiscNumber,
+		BPM:         track.BPM,
+
+		MusicBrainzRecordingID: track.MusicBrainzRecordingID,
+		MusicBrainzReleaseID:   track.MusicBrainzReleaseID,
+	}
+	if album := a.downloadTagsAlbum(track.AlbumID); album != nil {
+		tags.AlbumArtists = album.ArtistNames
+		if !album.ReleaseDate.IsZero() {
+			tags.ReleaseDate = album.ReleaseDate.String()
+		}
+	}
+	if rg := track.ReplayGain; rg != nil {
+		tags.Extra = map[string]string{
+			"REPLAYGAIN_TRACK_GAIN": fmt.Sprintf("%.2f dB", rg.TrackGain),
+			"REPLAYGAIN_ALBUM_GAIN": fmt.Sprintf("%.2f dB", rg.AlbumGain),
+			"REPLAYGAIN_TRACK_PEAK": fmt.Sprintf("%.6f", rg.TrackPeak),
+			"REPLAYGAIN_ALBUM_PEAK": fmt.Sprintf("%.6f", rg.AlbumPeak),
+		}
+	}
+	if lyrics := a.Lyrics.FetchLyrics(track); lyrics != nil {
+		tags.Lyrics = formatLyrics(lyrics)
+	}
+	if track.CoverArtID != "" {
+		if img, err := a.ImageManager.GetFullSizeCoverArt(track.CoverArtID); err == nil {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err == nil {
+				tags.CoverJPEG = buf.Bytes()
+			}
+		} else {
+			log.Printf("error fetching cover art to embed: %v", err)
+		}
+	}
+	return tags
+}
+
+// downloadTagsAlbum fetches the track's album for its album artists and
+// release date. The last album is cached, since downloads of several
+// tracks are usually of the same album.
+func (a *App) downloadTagsAlbum(albumID string) *mediaprovider.Album {
+	if albumID == "" {
+		return nil
+	}
+	a.downloadAlbumMu.Lock()
+	defer a.downloadAlbumMu.Unlock()
+	if al := a.downloadAlbum; al != nil && al.ID == albumID {
+		return al
+	}
+	al, err := a.ServerManager.Server.GetAlbum(albumID)
+	if err != nil {
+		log.Printf("error fetching album for download tags: %v", err)
+		return nil
+	}
+	a.downloadAlbum = &al.Album
+	return &al.Album
+}
+
+// formatLyrics returns the lyrics as plain text, or in LRC format if synced,
+// which most players recognize in an embedded lyrics tag.
+func formatLyrics(lyrics *mediaprovider.Lyrics) string {
+	var sb strings.Builder
+	for _, l := range lyrics.Lines {
+		if lyrics.Synced {
+			mins := int(l.Start) / 60
+			fmt.Fprintf(&sb, "[%02d:%05.2f]", mins, l.Start-float64(mins*60))
+		}
+		sb.WriteString(l.Text)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
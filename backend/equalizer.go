@@ -0,0 +1,247 @@
+package backend
+
+import (
+	"math"
+
+	"github.com/dweymouth/supersonic/backend/player/mpv"
+)
+
+const (
+	EqualizerTypeISO10Band = "ISO10Band"
+	EqualizerTypeISO15Band = "ISO15Band"
+)
+
+var SupportedEqualizerTypes = []string{EqualizerTypeISO10Band, EqualizerTypeISO15Band}
+
+// An equalizer preset. Gains are specified for the
+// ISO 10-band center frequencies (31 Hz - 16 kHz) and
+// are interpolated for equalizers with other band layouts.
+type EqualizerPreset struct {
+	Name   string
+	Preamp float64
+	Gains  [10]float64
+}
+
+var EqualizerPresets = []EqualizerPreset{
+	{Name: "Flat"},
+	{Name: "Bass Boost", Preamp: -5, Gains: [10]float64{6, 5, 4, 2, 0.5, 0, 0, 0, 0, 0}},
+	{Name: "Bass Reducer", Gains: [10]float64{-6, -5, -4, -2, -0.5, 0, 0, 0, 0, 0}},
+	{Name: "Treble Boost", Preamp: -5, Gains: [10]float64{0, 0, 0, 0, 0, 0.5, 2, 4, 5, 6}},
+	{Name: "Treble Reducer", Gains: [10]float64{0, 0, 0, 0, 0, -0.5, -2, -4, -5, -6}},
+	{Name: "Vocal", Preamp: -3, Gains: [10]float64{-3, -2, -1, 1, 3, 3, 2, 1, 0, -1}},
+	{Name: "Rock", Preamp: -4, Gains: [10]float64{4, 3, 2, 0, -1, -1, 1, 2, 3, 4}},
+	{Name: "Pop", Preamp: -3, Gains: [10]float64{-1, 0, 1, 2, 3, 3, 2, 1, 0, -1}},
+	{Name: "Classical", Preamp: -3, Gains: [10]float64{3, 2, 1, 0, 0, 0, 0, 1, 2, 3}},
+	{Name: "Loudness", Preamp: -5, Gains: [10]float64{5, 4, 2, 0, -1, 0, 0, 2, 4, 5}},
+}
+
+// EqualizerManager is the backend API for the graphic equalizer of the local player.
+// The current settings are stored in the LocalPlaybackConfig, and are saved and
+// restored per audio output device as the device is changed.
+type EqualizerManager struct {
+	cfg    *LocalPlaybackConfig
+	player *mpv.Player
+	device string
+}
+
+func NewEqualizerManager(cfg *LocalPlaybackConfig, p *mpv.Player) *EqualizerManager {
+	e := &EqualizerManager{cfg: cfg, player: p, device: cfg.AudioDeviceName}
+	e.ensureBandCount()
+	return e
+}
+
+// Apply applies the current equalizer settings to the player.
+func (e *EqualizerManager) Apply() error {
+	e.ensureBandCount()
+	var eq mpv.Equalizer
+	switch e.cfg.EqualizerType {
+	case EqualizerTypeISO10Band:
+		eq10 := &mpv.ISO10BandEqualizer{
+			EQPreamp: e.cfg.EqualizerPreamp,
+			Disabled: !e.cfg.EqualizerEnabled,
+		}
+		copy(eq10.BandGains[:], e.cfg.GraphicEqualizerBands)
+		eq = eq10
+	default:
+		eq15 := &mpv.ISO15BandEqualizer{
+			EQPreamp: e.cfg.EqualizerPreamp,
+			Disabled: !e.cfg.EqualizerEnabled,
+		}
+		copy(eq15.BandGains[:], e.cfg.GraphicEqualizerBands)
+		eq = eq15
+	}
+	return e.player.SetEqualizer(eq)
+}
+
+// BandFrequencies returns the band frequencies of the
+// current equalizer type as strings friendly for display.
+func (e *EqualizerManager) BandFrequencies() []string {
+	if e.cfg.EqualizerType == EqualizerTypeISO10Band {
+		return (&mpv.ISO10BandEqualizer{}).BandFrequencies()
+	}
+	return (&mpv.ISO15BandEqualizer{}).BandFrequencies()
+}
+
+func (e *EqualizerManager) Enabled() bool {
+	return e.cfg.EqualizerEnabled
+}
+
+func (e *EqualizerManager) SetEnabled(enabled bool) error {
+	e.cfg.EqualizerEnabled = enabled
+	return e.Apply()
+}
+
+func (e *EqualizerManager) Preamp() float64 {
+	return e.cfg.EqualizerPreamp
+}
+
+// SetPreamp sets the preamp gain. Like SetBandGain, it does not apply
+// the change to the player, so that it can be bound to a slider;
+// call Apply afterwards (usually debounced).
+func (e *EqualizerManager) SetPreamp(gain float64) {
+	e.cfg.EqualizerPreamp = gain
+	e.cfg.EqualizerPreset = ""
+}
+
+// BandGains returns a copy of the current band gains.
+func (e *EqualizerManager) BandGains() []float64 {
+	e.ensureBandCount()
+	gains := make([]float64, len(e.cfg.GraphicEqualizerBands))
+	copy(gains, e.cfg.GraphicEqualizerBands)
+	return gains
+}
+
+// SetBandGain sets the gain of the given band, without applying it to the player.
+func (e *EqualizerManager) SetBandGain(band int, gain float64) {
+	e.ensureBandCount()
+	if band < 0 || band >= len(e.cfg.GraphicEqualizerBands) {
+		return
+	}
+	e.cfg.GraphicEqualizerBands[band] = gain
+	e.cfg.EqualizerPreset = ""
+}
+
+func (e *EqualizerManager) Type() string {
+	return e.cfg.EqualizerType
+}
+
+// SetType switches the equalizer type, converting the current band gains
+// to the new band layout.
+func (e *EqualizerManager) SetType(eqType string) error {
+	if eqType == e.cfg.EqualizerType {
+		return nil
+	}
+	oldFreqs := bandCenterFrequencies(e.cfg.EqualizerType)
+	oldGains := e.BandGains()
+	e.cfg.EqualizerType = eqType
+	newFreqs := bandCenterFrequencies(eqType)
+	e.cfg.GraphicEqualizerBands = make([]float64, len(newFreqs))
+	for i, f := range newFreqs {
+		e.cfg.GraphicEqualizerBands[i] = interpolateGain(oldFreqs, oldGains, f)
+	}
+	return e.Apply()
+}
+
+// CurrentPreset returns the name of the active preset,
+// or the empty string if the bands have been customized.
+func (e *EqualizerManager) CurrentPreset() string {
+	return e.cfg.EqualizerPreset
+}
+
+func (e *EqualizerManager) PresetNames() []string {
+	names := make([]string, len(EqualizerPresets))
+	for i, p := range EqualizerPresets {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// ApplyPreset sets the preamp and band gains from the named preset.
+func (e *EqualizerManager) ApplyPreset(name string) error {
+	for _, p := range EqualizerPresets {
+		if p.Name != name {
+			continue
+		}
+		freqs := bandCenterFrequencies(e.cfg.EqualizerType)
+		iso10Freqs := bandCenterFrequencies(EqualizerTypeISO10Band)
+		e.cfg.GraphicEqualizerBands = make([]float64, len(freqs))
+		for i, f := range freqs {
+			e.cfg.GraphicEqualizerBands[i] = interpolateGain(iso10Freqs, p.Gains[:], f)
+		}
+		e.cfg.EqualizerPreamp = p.Preamp
+		e.cfg.EqualizerPreset = p.Name
+		return e.Apply()
+	}
+	return nil
+}
+
+// SetAudioDevice saves the current equalizer settings for the
+// previously selected output device, and restores the saved
+// settings, if any, for the newly selected one.
+func (e *EqualizerManager) SetAudioDevice(deviceName string) error {
+	if deviceName == e.device {
+		return nil
+	}
+	if e.cfg.DeviceEqualizers == nil {
+		e.cfg.DeviceEqualizers = make(map[string]*EqualizerConfig)
+	}
+	e.cfg.DeviceEqualizers[e.device] = &EqualizerConfig{
+		Enabled: e.cfg.EqualizerEnabled,
+		Type:    e.cfg.EqualizerType,
+		Preset:  e.cfg.EqualizerPreset,
+		Preamp:  e.cfg.EqualizerPreamp,
+		Bands:   e.BandGains(),
+	}
+	if saved, ok := e.cfg.DeviceEqualizers[deviceName]; ok {
+		e.cfg.EqualizerEnabled = saved.Enabled
+		e.cfg.EqualizerType = saved.Type
+		e.cfg.EqualizerPreset = saved.Preset
+		e.cfg.EqualizerPreamp = saved.Preamp
+		e.cfg.GraphicEqualizerBands = append([]float64(nil), saved.Bands...)
+	}
+	e.device = deviceName
+	return e.Apply()
+}
+
+func (e *EqualizerManager) ensureBandCount() {
+	if e.cfg.EqualizerType != EqualizerTypeISO10Band {
+		e.cfg.EqualizerType = EqualizerTypeISO15Band
+	}
+	n := len(bandCenterFrequencies(e.cfg.EqualizerType))
+	if len(e.cfg.GraphicEqualizerBands) != n {
+		bands := make([]float64, n)
+		copy(bands, e.cfg.GraphicEqualizerBands)
+		e.cfg.GraphicEqualizerBands = bands
+	}
+}
+
+func bandCenterFrequencies(eqType string) []float64 {
+	var curve mpv.EqualizerCurve
+	if eqType == EqualizerTypeISO10Band {
+		curve = (&mpv.ISO10BandEqualizer{}).Curve()
+	} else {
+		curve = (&mpv.ISO15BandEqualizer{}).Curve()
+	}
+	freqs := make([]float64, len(curve))
+	for i, band := range curve {
+		freqs[i] = float64(band.Frequency)
+	}
+	return freqs
+}
+
+// interpolates the gain at frequency f, linearly on a log-frequency scale
+func interpolateGain(freqs, gains []float64, f float64) float64 {
+	if len(freqs) == 0 {
+		return 0
+	}
+	if f <= freqs[0] {
+		return gains[0]
+	}
+	for i := 1; i < len(freqs); i++ {
+		if f <= freqs[i] {
+			t := (math.Log(f) - math.Log(freqs[i-1])) / (math.Log(freqs[i]) - math.Log(freqs[i-1]))
+			return gains[i-1] + t*(gains[i]-gains[i-1])
+		}
+	}
+	return gains[len(gains)-1]
+}
@@ -0,0 +1,93 @@
+package backend
+
+import "sync"
+
+// EventType identifies the kind of change an Event describes.
+type EventType int
+
+const (
+	// The set of favorited items on the server has changed.
+	// Event.Data is a *FavoritesDiff.
+	EventFavoritesChanged EventType = iota
+
+	// New albums were found on the server by the NewMusicWatcher.
+	// Event.Data is a []*mediaprovider.Album, most recently added first.
+	EventNewAlbums
+
+	// A playlist was created, modified or deleted. Event.Data is the
+	// playlist ID, or "" if not known (e.g. for a newly created playlist).
+	EventPlaylistChanged
+
+	// The rating of tracks was set. Event.Data is a *RatingChange.
+	EventRatingChanged
+
+	// A library rescan was started on the server. Event.Data is nil.
+	EventLibraryRescanned
+
+	// A favorite or rating change made in this app failed on the server,
+	// and was reverted locally. Event.Data is a *RollbackInfo.
+	EventLocalChangeRolledBack
+
+	// The progress of a library scan has been updated by the ScanMonitor.
+	// Event.Data is a mediaprovider.ScanStatus.
+	EventLibraryScanProgress
+
+	// A library scan has completed. Event.Data is nil.
+	EventLibraryScanCompleted
+)
+
+// RatingChange is the Data of an EventRatingChanged.
+type RatingChange struct {
+	TrackIDs []string
+	Rating   int
+}
+
+// Event is a change notification broadcast through the EventBus.
+type Event struct {
+	Type EventType
+	Data any
+}
+
+// EventBus broadcasts change notifications to any interested subscribers
+// (e.g. open pages), so they can refresh without a manual reload.
+type EventBus struct {
+	mu          sync.Mutex
+	nextID      int
+	subscribers map[EventType]map[int]func(Event)
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[EventType]map[int]func(Event))}
+}
+
+// Subscribe registers a callback to be invoked whenever an event of
+// the given type is published. Returns a function to unsubscribe.
+func (e *EventBus) Subscribe(t EventType, cb func(Event)) (unsubscribe func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	id := e.nextID
+	e.nextID++
+	if e.subscribers[t] == nil {
+		e.subscribers[t] = make(map[int]func(Event))
+	}
+	e.subscribers[t][id] = cb
+	return func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		delete(e.subscribers[t], id)
+	}
+}
+
+// Publish invokes all subscribers of the event's type.
+// Subscribers are invoked synchronously on the calling goroutine.
+func (e *EventBus) Publish(ev Event) {
+	e.mu.Lock()
+	subs := make([]func(Event), 0, len(e.subscribers[ev.Type]))
+	for _, cb := range e.subscribers[ev.Type] {
+		subs = append(subs, cb)
+	}
+	e.mu.Unlock()
+	for _, cb := range subs {
+		cb(ev)
+	}
+}
@@ -0,0 +1,158 @@
+package backend
+
+import (
+	"sync"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+// FavoritesDiff describes the change in favorited items between two
+// refreshes of the FavoritesCache, as IDs of added and removed items.
+type FavoritesDiff struct {
+	AddedAlbums    []string
+	RemovedAlbums  []string
+	AddedArtists   []string
+	RemovedArtists []string
+	AddedTracks    []string
+	RemovedTracks  []string
+}
+
+func (f *FavoritesDiff) IsEmpty() bool {
+	return len(f.AddedAlbums) == 0 && len(f.RemovedAlbums) == 0 &&
+		len(f.AddedArtists) == 0 && len(f.RemovedArtists) == 0 &&
+		len(f.AddedTracks) == 0 && len(f.RemovedTracks) == 0
+}
+
+// FavoritesCache keeps a local copy of the user's favorites, so that
+// views can display them immediately. Refresh fetches the latest favorites
+// from the server and publishes an EventFavoritesChanged if anything changed.
+type FavoritesCache struct {
+	sm  *ServerManager
+	bus *EventBus
+
+	mu   sync.Mutex
+	favs *mediaprovider.Favorites
+}
+
+func NewFavoritesCache(sm *ServerManager, bus *EventBus) *FavoritesCache {
+	f := &FavoritesCache{sm: sm, bus: bus}
+	sm.OnLogout(f.clear)
+	sm.OnServerConnected(f.clear)
+	return f
+}
+
+// Cached returns the cached favorites, or nil if they have not been fetched yet.
+func (f *FavoritesCache) Cached() *mediaprovider.Favorites {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.favs
+}
+
+// Get returns the cached favorites if present, otherwise fetches them from the server.
+// If the fetch partly failed, the partial favorites are returned (but not cached)
+// along with the error.
+func (f *FavoritesCache) Get() (*mediaprovider.Favorites, error) {
+	if favs := f.Cached(); favs != nil {
+		return favs, nil
+	}
+	if partial, err := f.refresh(); err != nil {
+		return partial, err
+	}
+	return f.Cached(), nil
+}
+
+// Refresh fetches the latest favorites from the server, diffs them against
+// the cached copy, and publishes an EventFavoritesChanged if they differ.
+// If the fetch fails, even partly, the cached copy is left unchanged, so that
+// favorites which failed to load aren't reported as removed.
+func (f *FavoritesCache) Refresh() error {
+	_, err := f.refresh()
+	return err
+}
+
+// refresh is like Refresh, but also returns the partial favorites if the fetch partly failed.
+func (f *FavoritesCache) refresh() (*mediaprovider.Favorites, error) {
+	if f.sm.Server == nil {
+		return nil, nil
+	}
+	latest, err := f.sm.Server.GetFavorites(mediaprovider.FavoritesSortDefault)
+	if err != nil {
+		return &latest, err
+	}
+
+	f.mu.Lock()
+	old := f.favs
+	f.favs = &latest
+	f.mu.Unlock()
+
+	if old == nil {
+		return nil, nil // first fetch; no open view can be showing stale data
+	}
+	diff := diffFavorites(old, &latest)
+	if !diff.IsEmpty() {
+		f.bus.Publish(Event{Type: EventFavoritesChanged, Data: diff})
+	}
+	return nil, nil
+}
+
+// applyLocalChange updates the cached favorites with a change made in this app
+// before the server has confirmed it, and publishes an EventFavoritesChanged.
+// Only removals are applied to the cache, since the models of added items
+// aren't known; they are added by the next Refresh.
+func (f *FavoritesCache) applyLocalChange(diff *FavoritesDiff) {
+	if diff.IsEmpty() {
+		return
+	}
+	f.mu.Lock()
+	if f.favs != nil {
+		// copy, so as not to modify favorites returned by Cached
+		favs := *f.favs
+		notIn := func(ids []string) func(string) bool {
+			set := sharedutil.ToSet(ids)
+			return func(id string) bool { _, ok := set[id]; return !ok }
+		}
+		notRemovedAlbum, notRemovedArtist, notRemovedTrack := notIn(diff.RemovedAlbums), notIn(diff.RemovedArtists), notIn(diff.RemovedTracks)
+		favs.Albums = sharedutil.FilterSlice(favs.Albums, func(a *mediaprovider.Album) bool { return notRemovedAlbum(a.ID) })
+		favs.Artists = sharedutil.FilterSlice(favs.Artists, func(a *mediaprovider.Artist) bool { return notRemovedArtist(a.ID) })
+		favs.Tracks = sharedutil.FilterSlice(favs.Tracks, func(t *mediaprovider.Track) bool { return notRemovedTrack(t.ID) })
+		f.favs = &favs
+	}
+	f.mu.Unlock()
+	f.bus.Publish(Event{Type: EventFavoritesChanged, Data: diff})
+}
+
+func (f *FavoritesCache) clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.favs = nil
+}
+
+func diffFavorites(old, latest *mediaprovider.Favorites) *FavoritesDiff {
+	albumID := func(a *mediaprovider.Album) string { return a.ID }
+	artistID := func(a *mediaprovider.Artist) string { return a.ID }
+	trackID := func(t *mediaprovider.Track) string { return t.ID }
+
+	diff := &FavoritesDiff{}
+	diff.AddedAlbums, diff.RemovedAlbums = diffIDs(
+		sharedutil.MapSlice(old.Albums, albumID), sharedutil.MapSlice(latest.Albums, albumID))
+	diff.AddedArtists, diff.RemovedArtists = diffIDs(
+		sharedutil.MapSlice(old.Artists, artistID), sharedutil.MapSlice(latest.Artists, artistID))
+	diff.AddedTracks, diff.RemovedTracks = diffIDs(
+		sharedutil.MapSlice(old.Tracks, trackID), sharedutil.MapSlice(latest.Tracks, trackID))
+	return diff
+}
+
+func diffIDs(old, latest []string) (added, removed []string) {
+	oldSet := sharedutil.ToSet(old)
+	latestSet := sharedutil.ToSet(latest)
+	added = sharedutil.FilterSlice(latest, func(id string) bool {
+		_, ok := oldSet[id]
+		return !ok
+	})
+	removed = sharedutil.FilterSlice(old, func(id string) bool {
+		_, ok := latestSet[id]
+		return !ok
+	})
+	return added, removed
+}
@@ -0,0 +1,212 @@
+package backend
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// GenreMapper merges near-duplicate genres of the library ("Hip-Hop", "hip hop")
+// and configured aliases ("Rap" -> "Hip-Hop") into a single genre, and adds
+// configured parent genres which include their subgenres. It is applied when
+// listing genres and when filtering albums by genre, so that browsing a genre
+// aggregates all of the server's genres it stands for.
+type GenreMapper struct {
+	sm  *ServerManager
+	cfg *GenreConfig
+
+	mu           sync.Mutex
+	serverGenres []*mediaprovider.Genre // as last fetched from the server
+}
+
+func NewGenreMapper(sm *ServerManager, cfg *GenreConfig) *GenreMapper {
+	g := &GenreMapper{sm: sm, cfg: cfg}
+	sm.OnServerConnected(g.clear)
+	sm.OnLogout(g.clear)
+	return g
+}
+
+// GetGenres returns the server's genres, with the genres which are merged
+// together combined into one with the summed album and track counts, and with
+// the configured parent genres whose counts include those of their subgenres.
+// Since an album may have several genres, the summed counts are approximate.
+func (g *GenreMapper) GetGenres() ([]*mediaprovider.Genre, error) {
+	serverGenres, err := g.fetchServerGenres()
+	if err != nil {
+		return nil, err
+	}
+
+	type nameRank struct {
+		isAlias    bool
+		albumCount int
+	}
+	byKey := make(map[string]*mediaprovider.Genre)
+	nameRanks := make(map[string]nameRank)
+	var keys []string
+	for _, sg := range serverGenres {
+		key := g.key(sg.Name)
+		merged, ok := byKey[key]
+		if !ok {
+			merged = &mediaprovider.Genre{}
+			byKey[key] = merged
+			keys = append(keys, key)
+		}
+		merged.AlbumCount += sg.AlbumCount
+		merged.TrackCount += sg.TrackCount
+		// name the merged genre after a server genre which isn't an alias,
+		// preferring the one with the most albums
+		rank := nameRank{isAlias: g.aliasTarget(sg.Name) != sg.Name, albumCount: sg.AlbumCount}
+		best := nameRanks[key]
+		if merged.Name == "" || (best.isAlias && !rank.isAlias) ||
+			(best.isAlias == rank.isAlias && rank.albumCount > best.albumCount) {
+			merged.Name = sg.Name
+			nameRanks[key] = rank
+		}
+	}
+
+	for parent, subgenres := range g.cfg.Subgenres {
+		key := g.key(parent)
+		merged, ok := byKey[key]
+		if !ok {
+			merged = &mediaprovider.Genre{Name: parent}
+			byKey[key] = merged
+			keys = append(keys, key)
+		}
+		for _, sub := range subgenres {
+			if s, ok := byKey[g.key(sub)]; ok && s != merged {
+				merged.AlbumCount += s.AlbumCount
+				merged.TrackCount += s.TrackCount
+			}
+		}
+	}
+
+	genres := make([]*mediaprovider.Genre, 0, len(keys))
+	for _, k := range keys {
+		genres = append(genres, byKey[k])
+	}
+	slices.SortFunc(genres, func(a, b *mediaprovider.Genre) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	return genres, nil
+}
+
+// ExpandGenre returns the server's genres which the given genre stands for:
+// those merged with it, and those of its subgenres, if it is a parent genre.
+// Returns just the given genre if the server's genres can't be fetched.
+func (g *GenreMapper) ExpandGenre(genre string) []string {
+	serverGenres, err := g.fetchServerGenres()
+	if err != nil {
+		return []string{genre}
+	}
+	var expanded []string
+	g.expand(g.key(genre), serverGenres, make(map[string]bool), &expanded)
+	if len(expanded) == 0 {
+		return []string{genre}
+	}
+	return expanded
+}
+
+func (g *GenreMapper) expand(key string, serverGenres []*mediaprovider.Genre, visited map[string]bool, expanded *[]string) {
+	if visited[key] {
+		return // guard against cycles in the configured hierarchy
+	}
+	visited[key] = true
+	for _, sg := range serverGenres {
+		if g.key(sg.Name) == key {
+			*expanded = append(*expanded, sg.Name)
+		}
+	}
+	for parent, subgenres := range g.cfg.Subgenres {
+		if g.key(parent) != key {
+			continue
+		}
+		for _, sub := range subgenres {
+			g.expand(g.key(sub), serverGenres, visited, expanded)
+		}
+	}
+}
+
+// MapAlbumFilter returns the filter with its genres expanded
+// to the server's genres they stand for, for passing to the server.
+func (g *GenreMapper) MapAlbumFilter(filter mediaprovider.AlbumFilter) mediaprovider.AlbumFilter {
+	opts := filter.Options()
+	if len(opts.Genres) == 0 {
+		return filter
+	}
+	opts = opts.Clone()
+	var genres []string
+	for _, genre := range opts.Genres {
+		for _, e := range g.ExpandGenre(genre) {
+			if !slices.Contains(genres, e) {
+				genres = append(genres, e)
+			}
+		}
+	}
+	opts.Genres = genres
+	return mediaprovider.NewAlbumFilter(opts)
+}
+
+func (g *GenreMapper) fetchServerGenres() ([]*mediaprovider.Genre, error) {
+	g.mu.Lock()
+	cached := g.serverGenres
+	g.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+	if g.sm.Server == nil {
+		return nil, ErrNoServers
+	}
+	genres, err := g.sm.Server.GetGenres()
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	g.serverGenres = genres
+	g.mu.Unlock()
+	return genres, nil
+}
+
+// Refresh discards the cached server genres, so they are re-fetched on next use.
+func (g *GenreMapper) Refresh() {
+	g.clear()
+}
+
+func (g *GenreMapper) clear() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.serverGenres = nil
+}
+
+// aliasTarget returns the genre the given genre is an alias of, or the genre itself.
+func (g *GenreMapper) aliasTarget(genre string) string {
+	norm := normalizeGenre(genre)
+	for alias, target := range g.cfg.Aliases {
+		if normalizeGenre(alias) == norm {
+			return target
+		}
+	}
+	return genre
+}
+
+// key returns the key identifying the merged genre the given genre belongs to.
+func (g *GenreMapper) key(genre string) string {
+	target := g.aliasTarget(genre)
+	if g.cfg.MergeSimilar {
+		return normalizeGenre(target)
+	}
+	return target
+}
+
+// normalizeGenre lowercases the genre and removes everything
+// but letters and digits, e.g. "Hip-Hop" and "hip hop" -> "hiphop".
+func normalizeGenre(genre string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, genre)
+}
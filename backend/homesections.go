@@ -0,0 +1,317 @@
+package backend
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+// Home page section kinds, as stored in HomeConfig.Sections.
+const (
+	HomeSectionRecentlyAdded   = "Recently Added"
+	HomeSectionRecentlyPlayed  = "Recently Played"
+	HomeSectionRandomAlbums    = "Random Albums"
+	HomeSectionFavoriteArtists = "Favorite Artists"
+	HomeSectionPinnedPlaylists = "Pinned Playlists"
+	HomeSectionOnThisDay       = "On This Day Last Year"
+	HomeSectionHeavyRotation   = "Heavy Rotation"
+	HomeSectionRecentTracks    = "Recently Played Tracks"
+)
+
+var AllHomeSections = []string{
+	HomeSectionRecentlyAdded,
+	HomeSectionRecentlyPlayed,
+	HomeSectionRandomAlbums,
+	HomeSectionFavoriteArtists,
+	HomeSectionPinnedPlaylists,
+	HomeSectionOnThisDay,
+	HomeSectionHeavyRotation,
+	HomeSectionRecentTracks,
+}
+
+// period of local listening history the Heavy Rotation section covers
+const heavyRotationDays = 30
+
+// album sort orders used by the album sections,
+// which both Subsonic and Jellyfin providers offer
+var homeSectionAlbumSorts = map[string]string{
+	HomeSectionRecentlyAdded:  "Recently Added",
+	HomeSectionRecentlyPlayed: "Recently Played",
+}
+
+var errHomeSectionUnsupported = errors.New("not supported by this server")
+
+// HomeSection is the fetched content of one section of the home page.
+// Only the field matching the section kind is set.
+type HomeSection struct {
+	Kind      string
+	Albums    []*mediaprovider.Album
+	Artists   []*mediaprovider.Artist
+	Playlists []*mediaprovider.Playlist
+	Tracks    []*mediaprovider.Track
+	Err       error
+}
+
+func (h *HomeSection) IsEmpty() bool {
+	return len(h.Albums) == 0 && len(h.Artists) == 0 &&
+		len(h.Playlists) == 0 && len(h.Tracks) == 0
+}
+
+// HomeSectionsManager assembles the user-configured home page sections.
+type HomeSectionsManager struct {
+	sm      *ServerManager
+	favs    *FavoritesCache
+	history *ListeningHistory
+	config  *HomeConfig
+
+	mu sync.Mutex // guards config.PinnedPlaylistIDs
+}
+
+func NewHomeSectionsManager(sm *ServerManager, favs *FavoritesCache, history *ListeningHistory, config *HomeConfig) *HomeSectionsManager {
+	return &HomeSectionsManager{sm: sm, favs: favs, history: history, config: config}
+}
+
+// Fetch fetches the content of all configured sections concurrently,
+// returning them in the configured order. A section which failed to load
+// has Err set; sections unsupported by the current server are omitted.
+func (h *HomeSectionsManager) Fetch() []HomeSection {
+	server := h.sm.Server
+	if server == nil {
+		return nil
+	}
+	kinds := h.sm.HomeSections()
+	limit := h.config.ItemsPerSection
+	if limit <= 0 {
+		limit = 20
+	}
+
+	sections := make([]HomeSection, len(kinds))
+	var wg sync.WaitGroup
+	for i, kind := range kinds {
+		wg.Add(1)
+		go func(s *HomeSection, kind string) {
+			defer wg.Done()
+			s.Kind = kind
+			s.Err = h.fetchSection(server, s, limit)
+			if s.Err != nil && !errors.Is(s.Err, errHomeSectionUnsupported) {
+				log.Printf("error fetching home section %q: %v", kind, s.Err)
+			}
+		}(&sections[i], kind)
+	}
+	wg.Wait()
+
+	return sharedutil.FilterSlice(sections, func(s HomeSection) bool {
+		return !errors.Is(s.Err, errHomeSectionUnsupported)
+	})
+}
+
+func (h *HomeSectionsManager) fetchSection(server mediaprovider.MediaProvider, s *HomeSection, limit int) error {
+	switch s.Kind {
+	case HomeSectionRandomAlbums:
+		albums, err := server.GetRandomAlbums(limit, mediaprovider.NewAlbumFilter(mediaprovider.AlbumFilterOptions{}))
+		s.Albums = albums
+		return err
+	case HomeSectionRecentlyAdded, HomeSectionRecentlyPlayed:
+		sort := homeSectionAlbumSorts[s.Kind]
+		if !slices.Contains(server.AlbumSortOrders(), sort) {
+			return errHomeSectionUnsupported
+		}
+		iter := server.IterateAlbums(sort, mediaprovider.NewAlbumFilter(mediaprovider.AlbumFilterOptions{}))
+		for al := iter.Next(); al != nil && len(s.Albums) < limit; al = iter.Next() {
+			s.Albums = append(s.Albums, al)
+		}
+		return nil
+	case HomeSectionFavoriteArtists:
+		favs, err := h.favs.Get()
+		if err != nil {
+			return err
+		}
+		if favs != nil {
+			s.Artists = favs.Artists[:min(limit, len(favs.Artists))]
+		}
+		return nil
+	case HomeSectionPinnedPlaylists:
+		pinned := h.PinnedPlaylistIDs()
+		if len(pinned) == 0 {
+			return nil
+		}
+		playlists, err := server.GetPlaylists()
+		if err != nil {
+			return err
+		}
+		// in the order they were pinned
+		for _, id := range pinned {
+			if idx := slices.IndexFunc(playlists, func(p *mediaprovider.Playlist) bool { return p.ID == id }); idx >= 0 {
+				s.Playlists = append(s.Playlists, playlists[idx])
+			}
+		}
+		return nil
+	case HomeSectionOnThisDay:
+		s.Tracks = h.onThisDayLastYear(server, limit)
+		return nil
+	case HomeSectionHeavyRotation:
+		s.Tracks = h.heavyRotation(server, limit)
+		return nil
+	case HomeSectionRecentTracks:
+		s.Tracks = h.recentlyPlayedTracks(server, limit)
+		return nil
+	default:
+		return errHomeSectionUnsupported
+	}
+}
+
+// onThisDayLastYear returns the tracks played on the current server
+// on today's date one year ago, most played first.
+func (h *HomeSectionsManager) onThisDayLastYear(server mediaprovider.MediaProvider, limit int) []*mediaprovider.Track {
+	now := time.Now()
+	from := time.Date(now.Year()-1, now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	serverID := h.sm.DataKey()
+
+	plays := make(map[string]int)
+	var ids []string
+	for _, r := range h.history.Records(from, from.AddDate(0, 0, 1)) {
+		if r.ServerID != serverID || !r.CountsAsPlay() {
+			continue
+		}
+		if plays[r.TrackID] == 0 {
+			ids = append(ids, r.TrackID)
+		}
+		plays[r.TrackID]++
+	}
+	slices.SortStableFunc(ids, func(a, b string) int { return plays[b] - plays[a] })
+	return h.getTracks(server, ids, limit)
+}
+
+// heavyRotation returns the tracks played most on the current server in
+// the last month of the local listening history, topped up with the
+// server's most played tracks (all time) if it supports listing them.
+func (h *HomeSectionsManager) heavyRotation(server mediaprovider.MediaProvider, limit int) []*mediaprovider.Track {
+	now := time.Now()
+	plays := make(map[string]int)
+	var ids []string
+	for _, r := range h.serverRecords(now.AddDate(0, 0, -heavyRotationDays), now) {
+		if !r.CountsAsPlay() {
+			continue
+		}
+		if plays[r.TrackID] == 0 {
+			ids = append(ids, r.TrackID)
+		}
+		plays[r.TrackID]++
+	}
+	slices.SortStableFunc(ids, func(a, b string) int { return plays[b] - plays[a] })
+	tracks := h.getTracks(server, ids, limit)
+
+	if pt, ok := mediaprovider.As[mediaprovider.SupportsPlayedTracks](server); ok && len(tracks) < limit {
+		mostPlayed, err := pt.GetMostPlayedTracks(limit)
+		if err != nil {
+			log.Printf("error fetching most played tracks: %v", err)
+		}
+		tracks = appendNewTracks(tracks, mostPlayed, limit)
+	}
+	return tracks
+}
+
+// recentlyPlayedTracks merges the tracks last played on the current
+// server according to the local listening history and the server.
+func (h *HomeSectionsManager) recentlyPlayedTracks(server mediaprovider.MediaProvider, limit int) []*mediaprovider.Track {
+	var serverRecent []*mediaprovider.Track
+	if pt, ok := mediaprovider.As[mediaprovider.SupportsPlayedTracks](server); ok {
+		var err error
+		if serverRecent, err = pt.GetRecentlyPlayedTracks(limit); err != nil {
+			log.Printf("error fetching recently played tracks: %v", err)
+		}
+	}
+
+	records := h.serverRecords(time.Time{}, time.Time{})
+	lastPlayed := make(map[string]time.Time)
+	var ids []string
+	for i := len(records) - 1; i >= 0 && len(ids) < limit; i-- {
+		if id := records[i].TrackID; lastPlayed[id].IsZero() {
+			lastPlayed[id] = records[i].Time
+			ids = append(ids, id)
+		}
+	}
+	local := h.getTracks(server, ids, limit)
+	for _, tr := range local {
+		if t := lastPlayed[tr.ID]; t.After(tr.LastPlayed) {
+			tr.LastPlayed = t
+		}
+	}
+
+	tracks := appendNewTracks(local, serverRecent, len(local)+len(serverRecent))
+	// tracks whose play time isn't known keep their relative order, last
+	slices.SortStableFunc(tracks, func(a, b *mediaprovider.Track) int { return b.LastPlayed.Compare(a.LastPlayed) })
+	return tracks[:min(limit, len(tracks))]
+}
+
+// serverRecords returns the listening history records of the current server
+// in the time range (unbounded if zero), oldest first.
+func (h *HomeSectionsManager) serverRecords(from, to time.Time) []ListenRecord {
+	serverID := h.sm.DataKey()
+	return sharedutil.FilterSlice(h.history.Records(from, to), func(r ListenRecord) bool {
+		return r.ServerID == serverID
+	})
+}
+
+// getTracks fetches up to limit of the tracks with the given IDs,
+// skipping those no longer on the server.
+func (h *HomeSectionsManager) getTracks(server mediaprovider.MediaProvider, ids []string, limit int) []*mediaprovider.Track {
+	var tracks []*mediaprovider.Track
+	for _, id := range ids {
+		if len(tracks) >= limit {
+			break
+		}
+		tr, err := server.GetTrack(id)
+		if err != nil {
+			continue // may have been removed from the server
+		}
+		tracks = append(tracks, tr)
+	}
+	return tracks
+}
+
+// appendNewTracks appends the tracks of more not already in tracks,
+// up to a total of limit.
+func appendNewTracks(tracks, more []*mediaprovider.Track, limit int) []*mediaprovider.Track {
+	for _, tr := range more {
+		if len(tracks) >= limit {
+			break
+		}
+		if sharedutil.FindTrackByID(tr.ID, tracks) == nil {
+			tracks = append(tracks, tr)
+		}
+	}
+	return tracks
+}
+
+// PinnedPlaylistIDs returns the IDs of the playlists pinned to the
+// home page for the current server.
+func (h *HomeSectionsManager) PinnedPlaylistIDs() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.config.PinnedPlaylistIDs[h.sm.DataKey()])
+}
+
+func (h *HomeSectionsManager) IsPlaylistPinned(id string) bool {
+	return slices.Contains(h.PinnedPlaylistIDs(), id)
+}
+
+// SetPlaylistPinned pins or unpins a playlist of the current server to the home page.
+func (h *HomeSectionsManager) SetPlaylistPinned(id string, pinned bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	serverID := h.sm.DataKey()
+	ids := slices.DeleteFunc(h.config.PinnedPlaylistIDs[serverID], func(p string) bool { return p == id })
+	if pinned {
+		ids = append(ids, id)
+	}
+	if h.config.PinnedPlaylistIDs == nil {
+		h.config.PinnedPlaylistIDs = make(map[string][]string)
+	}
+	h.config.PinnedPlaylistIDs[serverID] = ids
+}
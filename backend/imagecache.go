@@ -128,6 +128,13 @@ func (i *ImageCache) GetWithNewTTL(key string, newTtl time.Duration) (image.Imag
 	return nil, ErrNotFound
 }
 
+func (i *ImageCache) Delete(key string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.cache, key)
+}
+
 func (i *ImageCache) Clear() {
 	i.mu.Lock()
 	defer i.mu.Unlock()
@@ -14,6 +14,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -46,6 +47,11 @@ type ImageManager struct {
 	filesWrittenSinceLastPrune bool
 
 	serverFetchSema chan interface{}
+	metrics         *Metrics
+
+	// cover fallback state - see coverfallback.go
+	fallbackCoverIDs sync.Map // "album-{id}"/"artist-{id}" -> coverArtID
+	missingCovers    sync.Map // coverArtID -> time.Time of failed fetch
 }
 
 // NewImageManager returns a new ImageManager.
@@ -68,6 +74,7 @@ func NewImageManager(ctx context.Context, s *ServerManager, baseCacheDir string)
 	s.OnLogout(func() {
 		i.thumbnailCache.Clear()
 		i.clearFullSizeCover()
+		i.clearCoverFallbacks()
 	})
 	i.thumbnailCache.OnEvictTaskRan = func() {
 		i.clearFullSizeCoverIfExpired()
@@ -83,13 +90,28 @@ func (i *ImageManager) SetMaxOnDiskCacheSizeBytes(size int64) {
 	i.maxOnDiskCacheSizeBytes = size
 }
 
+// SetMaxInMemoryThumbnails sets the maximum number of cover thumbnails
+// kept in the in-memory cache.
+func (i *ImageManager) SetMaxInMemoryThumbnails(n int) {
+	i.thumbnailCache.mu.Lock()
+	defer i.thumbnailCache.mu.Unlock()
+	i.thumbnailCache.MaxSize = n
+}
+
+// SetMetrics sets the Metrics to record cache hits and misses to.
+func (i *ImageManager) SetMetrics(m *Metrics) {
+	i.metrics = m
+}
+
 // GetCoverThumbnailFromCache returns the cover thumbnail for the given ID if it exists
 // in the in-memory cache. Returns quickly, safe to call in UI threads.
 func (i *ImageManager) GetCoverThumbnailFromCache(coverID string) (image.Image, bool) {
 	img, err := i.thumbnailCache.GetExtendTTL(coverID, i.thumbnailCache.DefaultTTL)
 	if err == nil && img != nil {
+		i.metrics.RecordCacheHit(CacheNameThumbnailMemory)
 		return img, true
 	}
+	i.metrics.RecordCacheMiss(CacheNameThumbnailMemory)
 	return nil, false
 }
 
@@ -224,6 +246,7 @@ func (i *ImageManager) fetchAndCacheCoverFromDiskOrServer(ctx context.Context, c
 		if s, err := os.Stat(path); err == nil {
 			go i.checkRefreshLocalCover(s, coverID, ttl)
 			if img, ok := i.loadLocalImage(path); ok {
+				i.metrics.RecordCacheHit(CacheNameThumbnailDisk)
 				i.thumbnailCache.SetWithTTL(coverID, img, ttl)
 				if ctx.Err() == nil && cb != nil {
 					cb(img, nil)
@@ -234,6 +257,7 @@ func (i *ImageManager) fetchAndCacheCoverFromDiskOrServer(ctx context.Context, c
 	}
 
 	// fetch from server
+	i.metrics.RecordCacheMiss(CacheNameThumbnailDisk)
 	return i.fetchAndCacheCoverFromServer(ctx, coverID, ttl, cb)
 }
 
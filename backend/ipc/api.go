@@ -1,25 +1,55 @@
 package ipc
 
-import "fmt"
+import (
+	"fmt"
+	"net/url"
+)
+
+const (
+	PingPath       = "/ping"
+	PlayPath       = "/transport/play"
+	PlayPausePath  = "/transport/playpause"
+	PausePath      = "/transport/pause"
+	StopPath       = "/transport/stop"
+	PreviousPath   = "/transport/previous"
+	NextPath       = "/transport/next"
+	TimePosPath    = "/transport/timepos" // ?s=<seconds>
+	VolumePath     = "/volume"            // ?v=<vol>
+	NowPlayingPath = "/queue/nowplaying"
+	EnqueuePath    = "/queue/enqueue" // ?kind=<album|playlist>&q=<search>&mode=<play|next|append>
+	ShowPath       = "/window/show"
+	QuitPath       = "/window/quit"
+)
 
+// The kinds of items which can be enqueued by search.
 const (
-	PingPath      = "/ping"
-	PlayPath      = "/transport/play"
-	PlayPausePath = "/transport/playpause"
-	PausePath     = "/transport/pause"
-	StopPath      = "/transport/stop"
-	PreviousPath  = "/transport/previous"
-	NextPath      = "/transport/next"
-	TimePosPath   = "/transport/timepos" // ?s=<seconds>
-	VolumePath    = "/volume"            // ?v=<vol>
-	ShowPath      = "/window/show"
-	QuitPath      = "/window/quit"
+	EnqueueKindAlbum    = "album"
+	EnqueueKindPlaylist = "playlist"
+)
+
+// The modes for enqueueing items.
+const (
+	EnqueueModePlay   = "play"   // replace the queue and begin playback
+	EnqueueModeNext   = "next"   // insert after the now playing track
+	EnqueueModeAppend = "append" // add to the end of the queue
 )
 
 type Response struct {
 	Error string `json:"error"`
 }
 
+// NowPlayingResponse is the response to a NowPlayingPath request.
+type NowPlayingResponse struct {
+	Response
+	State    string   `json:"state"` // "playing", "paused", or "stopped"
+	Title    string   `json:"title"`
+	Artists  []string `json:"artists"`
+	Album    string   `json:"album"`
+	TimePos  float64  `json:"timePos"`
+	Duration float64  `json:"duration"`
+	Volume   int      `json:"volume"`
+}
+
 func SetVolumePath(vol int) string {
 	return fmt.Sprintf("%s?v=%d", VolumePath, vol)
 }
@@ -27,3 +57,11 @@ func SetVolumePath(vol int) string {
 func SeekToSecondsPath(secs float64) string {
 	return fmt.Sprintf("%s?s=%0.2f", TimePosPath, secs)
 }
+
+func EnqueueBySearchPath(kind, query, mode string) string {
+	v := url.Values{}
+	v.Set("kind", kind)
+	v.Set("q", query)
+	v.Set("mode", mode)
+	return fmt.Sprintf("%s?%s", EnqueuePath, v.Encode())
+}
@@ -64,6 +64,20 @@ func (c *Client) SetVolume(vol int) error {
 	return c.sendRequest(SetVolumePath(vol))
 }
 
+func (c *Client) NowPlaying() (*NowPlayingResponse, error) {
+	var r NowPlayingResponse
+	if err := c.getJSON(NowPlayingPath, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// EnqueueBySearch adds the best match for the search query of the given
+// kind (EnqueueKindAlbum, EnqueueKindPlaylist) to the play queue.
+func (c *Client) EnqueueBySearch(kind, query, mode string) error {
+	return c.sendRequest(EnqueueBySearchPath(kind, query, mode))
+}
+
 func (c *Client) Show() error {
 	return c.sendRequest(ShowPath)
 }
@@ -73,6 +87,11 @@ func (c *Client) Quit() error {
 }
 
 func (c *Client) sendRequest(path string) error {
+	return c.getJSON(path, nil)
+}
+
+// getJSON sends the request and decodes the response into v, if not nil.
+func (c *Client) getJSON(path string, v any) error {
 	resp, err := c.httpC.Get("http://supersonic/" + path)
 
 	if err != nil {
@@ -84,5 +103,8 @@ func (c *Client) sendRequest(path string) error {
 		json.NewDecoder(resp.Body).Decode(&r)
 		return errors.New(r.Error)
 	}
+	if v != nil {
+		return json.NewDecoder(resp.Body).Decode(v)
+	}
 	return nil
 }
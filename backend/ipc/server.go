@@ -20,6 +20,12 @@ type PlaybackHandler interface {
 	SetVolume(int) error
 }
 
+// QueueHandler handles the requests which query or add to the play queue.
+type QueueHandler interface {
+	NowPlaying() NowPlayingResponse
+	EnqueueBySearch(kind, query, mode string) error
+}
+
 type IPCServer interface {
 	Serve(net.Listener) error
 	Shutdown(context.Context) error
@@ -28,12 +34,13 @@ type IPCServer interface {
 type serverImpl struct {
 	server    *http.Server
 	pbHandler PlaybackHandler
+	qHandler  QueueHandler
 	showFn    func()
 	quitFn    func()
 }
 
-func NewServer(pbHandler PlaybackHandler, showFn, quitFn func()) IPCServer {
-	s := &serverImpl{pbHandler: pbHandler, showFn: showFn, quitFn: quitFn}
+func NewServer(pbHandler PlaybackHandler, qHandler QueueHandler, showFn, quitFn func()) IPCServer {
+	s := &serverImpl{pbHandler: pbHandler, qHandler: qHandler, showFn: showFn, quitFn: quitFn}
 	s.server = &http.Server{
 		Handler: s.createHandler(),
 	}
@@ -87,6 +94,18 @@ func (s *serverImpl) createHandler() http.Handler {
 			s.writeErr(w, err)
 		}
 	})
+	m.HandleFunc(NowPlayingPath, func(w http.ResponseWriter, r *http.Request) {
+		np := s.qHandler.NowPlaying()
+		if b, err := json.Marshal(&np); err == nil {
+			w.Write(b)
+		} else {
+			s.writeErr(w, err)
+		}
+	})
+	m.HandleFunc(EnqueuePath, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		s.writeSimpleResponse(w, s.qHandler.EnqueueBySearch(q.Get("kind"), q.Get("q"), q.Get("mode")))
+	})
 	return m
 }
 
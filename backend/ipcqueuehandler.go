@@ -0,0 +1,134 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/ipc"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+)
+
+const ipcEnqueueSearchResults = 20
+
+var _ ipc.QueueHandler = (*ipcQueueHandler)(nil)
+
+// ipcQueueHandler handles the IPC requests from the command line
+// which query the now playing track or add to the play queue.
+type ipcQueueHandler struct {
+	sm *ServerManager
+	pm *PlaybackManager
+}
+
+func (h *ipcQueueHandler) NowPlaying() ipc.NowPlayingResponse {
+	status := h.pm.PlayerStatus()
+	np := ipc.NowPlayingResponse{Volume: h.pm.Volume()}
+	switch status.State {
+	case player.Playing:
+		np.State = "playing"
+	case player.Paused:
+		np.State = "paused"
+	default:
+		np.State = "stopped"
+	}
+	if item := h.pm.NowPlaying(); item != nil {
+		meta := item.Metadata()
+		np.Title = meta.Name
+		np.Artists = meta.Artists
+		np.Album = meta.Album
+		np.TimePos = status.TimePos
+		np.Duration = status.Duration
+	}
+	return np
+}
+
+func (h *ipcQueueHandler) EnqueueBySearch(kind, query, mode string) error {
+	if h.sm.Server == nil {
+		return errors.New("not connected to a server")
+	}
+	if query == "" {
+		return errors.New("empty search query")
+	}
+	insertMode := Append
+	switch mode {
+	case ipc.EnqueueModePlay:
+		insertMode = Replace
+	case ipc.EnqueueModeNext:
+		insertMode = InsertNext
+	case ipc.EnqueueModeAppend, "":
+	default:
+		return fmt.Errorf("unknown enqueue mode %q", mode)
+	}
+
+	switch kind {
+	case ipc.EnqueueKindAlbum:
+		id, err := h.findAlbum(query)
+		if err != nil {
+			return err
+		}
+		if insertMode == Replace {
+			return h.pm.PlayAlbum(id, 0, false)
+		}
+		return h.pm.LoadAlbum(id, insertMode, false)
+	case ipc.EnqueueKindPlaylist:
+		id, err := h.findPlaylist(query)
+		if err != nil {
+			return err
+		}
+		if insertMode == Replace {
+			return h.pm.PlayPlaylist(id, 0, false)
+		}
+		return h.pm.LoadPlaylist(id, insertMode, false)
+	default:
+		return fmt.Errorf("unknown item kind %q", kind)
+	}
+}
+
+// findAlbum returns the ID of the album best matching the query,
+// preferring an exact name match over the server's search ranking.
+func (h *ipcQueueHandler) findAlbum(query string) (string, error) {
+	results, err := h.sm.Server.SearchAll(query, ipcEnqueueSearchResults)
+	if err != nil {
+		return "", err
+	}
+	var best *mediaprovider.SearchResult
+	for _, r := range results {
+		if r.Type != mediaprovider.ContentTypeAlbum {
+			continue
+		}
+		if strings.EqualFold(r.Name, query) {
+			return r.ID, nil
+		}
+		if best == nil {
+			best = r
+		}
+	}
+	if best == nil {
+		return "", fmt.Errorf("no album found matching %q", query)
+	}
+	return best.ID, nil
+}
+
+// findPlaylist returns the ID of the playlist whose name matches the query,
+// preferring an exact match over one containing the query.
+func (h *ipcQueueHandler) findPlaylist(query string) (string, error) {
+	playlists, err := h.sm.Server.GetPlaylists()
+	if err != nil {
+		return "", err
+	}
+	lowerQuery := strings.ToLower(query)
+	var best *mediaprovider.Playlist
+	for _, p := range playlists {
+		if strings.EqualFold(p.Name, query) {
+			return p.ID, nil
+		}
+		if best == nil && strings.Contains(strings.ToLower(p.Name), lowerQuery) {
+			best = p
+		}
+	}
+	if best == nil {
+		return "", fmt.Errorf("no playlist found matching %q", query)
+	}
+	return best.ID, nil
+}
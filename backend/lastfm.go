@@ -0,0 +1,165 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deluan/sanitize"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
+)
+
+// FetchLastFMTopTracks is a static function to fetch the titles of
+// an artist's most popular tracks on Last.fm, most popular first.
+func FetchLastFMTopTracks(apiKey, artist string, limit int) ([]string, error) {
+	var parsed struct {
+		TopTracks struct {
+			Track []struct {
+				Name string `json:"name"`
+			} `json:"track"`
+		} `json:"toptracks"`
+	}
+	err := lastFMRequest(apiKey, url.Values{
+		"method":      {"artist.gettoptracks"},
+		"artist":      {artist},
+		"autocorrect": {"1"},
+		"limit":       {strconv.Itoa(limit)},
+	}, &parsed)
+	if err != nil {
+		return nil, err
+	}
+	titles := make([]string, 0, len(parsed.TopTracks.Track))
+	for _, t := range parsed.TopTracks.Track {
+		titles = append(titles, t.Name)
+	}
+	return titles, nil
+}
+
+// lastFMRequest calls a method of the Last.fm API and decodes the JSON response into out.
+func lastFMRequest(apiKey string, params url.Values, out any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	params.Set("api_key", apiKey)
+	params.Set("format", "json")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://ws.audioscrobbler.com/2.0/?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("User-Agent", "Supersonic")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var lfmErr struct {
+		Error   int    `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(b, &lfmErr) == nil && lfmErr.Error != 0 {
+		return fmt.Errorf("error from Last.fm: %s", lfmErr.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error from Last.fm: status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("failed to decode Last.fm response: %w", err)
+	}
+	return nil
+}
+
+// GetTopTracks returns the artist's top tracks as supplied by the server.
+// If the server has none and a Last.fm API key is configured, the artist's
+// top tracks on Last.fm which can be found in the library are returned instead.
+func GetTopTracks(mp mediaprovider.MediaProvider, artist mediaprovider.Artist, count int, lastFMAPIKey string) ([]*mediaprovider.Track, error) {
+	tracks, err := mp.GetTopTracks(artist, count)
+	if len(tracks) > 0 || lastFMAPIKey == "" {
+		return tracks, err
+	}
+	if err != nil {
+		log.Printf("error getting top tracks from server: %v", err)
+	}
+
+	titles, lfmErr := FetchLastFMTopTracks(lastFMAPIKey, artist.Name, count)
+	if lfmErr != nil {
+		if err == nil {
+			err = lfmErr
+		}
+		return nil, err
+	}
+
+	// look up all the titles concurrently, keeping Last.fm's order
+	matches := make([]*mediaprovider.Track, len(titles))
+	var wg sync.WaitGroup
+	for i, title := range titles {
+		wg.Add(1)
+		go func(i int, title string) {
+			defer wg.Done()
+			matches[i] = findLibraryTrack(mp, artist.Name, title)
+		}(i, title)
+	}
+	wg.Wait()
+
+	tracks = make([]*mediaprovider.Track, 0, len(matches))
+	for _, tr := range matches {
+		if tr != nil {
+			tracks = append(tracks, tr)
+		}
+	}
+	return tracks, nil
+}
+
+// findLibraryTrack searches the library for a track by the given artist
+// with the given title, ignoring case, accents and a parenthesized suffix
+// such as "(Remastered)". Returns nil if none is found.
+func findLibraryTrack(mp mediaprovider.MediaProvider, artistName, title string) *mediaprovider.Track {
+	opts := mediaprovider.SearchOptions{Limits: map[mediaprovider.ContentType]int{mediaprovider.ContentTypeTrack: 10}}
+	results, err := helpers.SearchWithOptions(mp, artistName+" "+title, opts)
+	if err != nil || len(results) == 0 {
+		// many servers only match the query against the title
+		results, err = helpers.SearchWithOptions(mp, title, opts)
+	}
+	if err != nil {
+		log.Printf("error searching for top track: %v", err)
+		return nil
+	}
+	wantTitle := normalizeName(title)
+	wantArtist := normalizeName(artistName)
+	for _, r := range results {
+		if r.Type != mediaprovider.ContentTypeTrack ||
+			normalizeName(r.Name) != wantTitle ||
+			!strings.Contains(normalizeName(r.ArtistName), wantArtist) {
+			continue
+		}
+		tr, err := mp.GetTrack(r.ID)
+		if err != nil {
+			log.Printf("error getting top track: %v", err)
+			return nil
+		}
+		return tr
+	}
+	return nil
+}
+
+func normalizeName(title string) string {
+	title = strings.ToLower(sanitize.Accents(title))
+	if i := strings.Index(title, " ("); i > 0 {
+		title = title[:i]
+	}
+	return strings.TrimSpace(title)
+}
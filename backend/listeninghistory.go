@@ -0,0 +1,312 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+const listeningHistoryFile = "history.jsonl"
+
+// ListenRecord is a single completed or partial listen of a track.
+type ListenRecord struct {
+	Time         time.Time `json:"time"` // when playback of the track ended
+	ServerID     string    `json:"serverId"`
+	TrackID      string    `json:"trackId"`
+	Title        string    `json:"title"`
+	AlbumID      string    `json:"albumId,omitempty"`
+	Album        string    `json:"album,omitempty"`
+	ArtistIDs    []string  `json:"artistIds,omitempty"`
+	Artists      []string  `json:"artists,omitempty"`
+	DurationSecs int       `json:"duration"`
+	ListenedSecs float64   `json:"listened"`
+	Completed    bool      `json:"completed"`
+	Genres       []string  `json:"genres,omitempty"`
+}
+
+// CountsAsPlay returns whether the listen was long enough to count as a play
+// in statistics: played to the end, or for at least half the track or 4 minutes.
+func (r *ListenRecord) CountsAsPlay() bool {
+	return r.Completed || r.ListenedSecs >= 240 ||
+		(r.DurationSecs > 0 && r.ListenedSecs >= float64(r.DurationSecs)/2)
+}
+
+type StatsPeriod int
+
+const (
+	StatsPeriodWeek StatsPeriod = iota
+	StatsPeriodMonth
+	StatsPeriodYear
+	StatsPeriodAllTime
+)
+
+// Range returns the time range of the period ending at now.
+func (s StatsPeriod) Range(now time.Time) (from, to time.Time) {
+	switch s {
+	case StatsPeriodWeek:
+		return now.AddDate(0, 0, -7), now
+	case StatsPeriodMonth:
+		return now.AddDate(0, -1, 0), now
+	case StatsPeriodYear:
+		return now.AddDate(-1, 0, 0), now
+	default:
+		return time.Time{}, now
+	}
+}
+
+// StatsEntry is one row of a top tracks, albums or artists list.
+type StatsEntry struct {
+	ID           string
+	Name         string
+	Artist       string // for tracks and albums
+	Plays        int
+	ListenedSecs float64
+}
+
+// DailyListeningTime is the total time listened on a given day.
+type DailyListeningTime struct {
+	Date         time.Time // midnight, local time
+	ListenedSecs float64
+}
+
+// ListeningHistory records every listen of a track to a local
+// append-only store, and provides aggregated listening statistics.
+type ListeningHistory struct {
+	filePath string
+	sm       *ServerManager
+
+	mu      sync.RWMutex
+	records []ListenRecord
+}
+
+func NewListeningHistory(filePath string, sm *ServerManager) *ListeningHistory {
+	h := &ListeningHistory{filePath: filePath, sm: sm}
+	if err := h.load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("error loading listening history: %v", err)
+	}
+	return h
+}
+
+// SetupRecording registers with the PlaybackManager to record listens
+// while enabled returns true.
+func (h *ListeningHistory) SetupRecording(pm *PlaybackManager, enabled func() bool) {
+	pm.OnTrackPlayed(func(track *mediaprovider.Track, listenedSecs float64, completed bool) {
+		if !enabled() || listenedSecs < 1 {
+			return
+		}
+		h.Record(ListenRecord{
+			Time:         time.Now(),
+			ServerID:     h.sm.DataKey(),
+			TrackID:      track.ID,
+			Title:        track.Title,
+			AlbumID:      track.AlbumID,
+			Album:        track.Album,
+			ArtistIDs:    track.ArtistIDs,
+			Artists:      track.ArtistNames,
+			DurationSecs: track.Duration,
+			ListenedSecs: listenedSecs,
+			Completed:    completed,
+			Genres:       track.Genres,
+		})
+	})
+}
+
+// Record adds a listen to the history and persists it.
+func (h *ListeningHistory) Record(r ListenRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	if err := h.appendToFile(r); err != nil {
+		log.Printf("error saving listening history: %v", err)
+	}
+}
+
+// Records returns the listens in the given time range, oldest first.
+// A zero from or to leaves that end of the range open.
+func (h *ListeningHistory) Records(from, to time.Time) []ListenRecord {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var result []ListenRecord
+	for _, r := range h.records {
+		if inRange(r.Time, from, to) {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// TopTracks returns the most played tracks in the time range.
+func (h *ListeningHistory) TopTracks(from, to time.Time, limit int) []StatsEntry {
+	return h.top(from, to, limit, func(r *ListenRecord, add func(id, name, artist string)) {
+		add(r.TrackID, r.Title, strings.Join(r.Artists, ", "))
+	})
+}
+
+// TopAlbums returns the most played albums in the time range.
+func (h *ListeningHistory) TopAlbums(from, to time.Time, limit int) []StatsEntry {
+	return h.top(from, to, limit, func(r *ListenRecord, add func(id, name, artist string)) {
+		if r.AlbumID != "" {
+			add(r.AlbumID, r.Album, strings.Join(r.Artists, ", "))
+		}
+	})
+}
+
+// TopArtists returns the most played artists in the time range.
+// A listen of a track with several artists counts for each of them.
+func (h *ListeningHistory) TopArtists(from, to time.Time, limit int) []StatsEntry {
+	return h.top(from, to, limit, func(r *ListenRecord, add func(id, name, artist string)) {
+		for i, name := range r.Artists {
+			id := name
+			if i < len(r.ArtistIDs) {
+				id = r.ArtistIDs[i]
+			}
+			add(id, name, "")
+		}
+	})
+}
+
+// ListeningTimePerDay returns the total listening time for each day
+// in the time range which has any listens, oldest first.
+func (h *ListeningHistory) ListeningTimePerDay(from, to time.Time) []DailyListeningTime {
+	byDay := make(map[time.Time]float64)
+	for _, r := range h.Records(from, to) {
+		t := r.Time.Local()
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+		byDay[day] += r.ListenedSecs
+	}
+	result := make([]DailyListeningTime, 0, len(byDay))
+	for day, secs := range byDay {
+		result = append(result, DailyListeningTime{Date: day, ListenedSecs: secs})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date.Before(result[j].Date) })
+	return result
+}
+
+// keyFn calls add for each entity (track, album, artist) a listen counts towards.
+func (h *ListeningHistory) top(from, to time.Time, limit int, keyFn func(*ListenRecord, func(id, name, artist string))) []StatsEntry {
+	entries := make(map[string]*StatsEntry)
+	records := h.Records(from, to)
+	for i := range records {
+		r := &records[i]
+		keyFn(r, func(id, name, artist string) {
+			e, ok := entries[id]
+			if !ok {
+				e = &StatsEntry{ID: id, Name: name, Artist: artist}
+				entries[id] = e
+			}
+			e.ListenedSecs += r.ListenedSecs
+			if r.CountsAsPlay() {
+				e.Plays++
+			}
+		})
+	}
+	result := make([]StatsEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.Plays > 0 {
+			result = append(result, *e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Plays != result[j].Plays {
+			return result[i].Plays > result[j].Plays
+		}
+		return result[i].ListenedSecs > result[j].ListenedSecs
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+type HistoryExportFormat int
+
+const (
+	HistoryExportCSV HistoryExportFormat = iota
+	HistoryExportJSON
+)
+
+// Export writes the full listening history in the given format.
+func (h *ListeningHistory) Export(w io.Writer, format HistoryExportFormat) error {
+	records := h.Records(time.Time{}, time.Time{})
+	if format == HistoryExportJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if records == nil {
+			records = []ListenRecord{}
+		}
+		return enc.Encode(records)
+	}
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"Time", "Artist", "Title", "Album", "Duration", "Listened", "Completed", "Track ID", "Server ID"})
+	for _, r := range records {
+		_ = cw.Write([]string{
+			r.Time.Format(time.RFC3339),
+			strings.Join(r.Artists, ", "),
+			r.Title,
+			r.Album,
+			strconv.Itoa(r.DurationSecs),
+			strconv.FormatFloat(r.ListenedSecs, 'f', 0, 64),
+			strconv.FormatBool(r.Completed),
+			r.TrackID,
+			r.ServerID,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (h *ListeningHistory) load() error {
+	f, err := os.Open(h.filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var records []ListenRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r ListenRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue // skip a line truncated by a crash
+		}
+		records = append(records, r)
+	}
+	h.mu.Lock()
+	h.records = records
+	h.mu.Unlock()
+	return scanner.Err()
+}
+
+func (h *ListeningHistory) appendToFile(r ListenRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(f, "%s\n", b); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func inRange(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || !t.After(to))
+}
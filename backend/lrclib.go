@@ -14,6 +14,8 @@ import (
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
 )
 
+var errLrcLibNotFound = errors.New("lrclib lyrics not found")
+
 // FetchLrcLibLyrics is a static function to search and fetch lyrics from lrclib.net
 func FetchLrcLibLyrics(name, artist, album string, durationSecs int) (*mediaprovider.Lyrics, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -44,7 +46,7 @@ func FetchLrcLibLyrics(name, artist, album string, durationSecs int) (*mediaprov
 
 func parseLrcLibResponse(resp *http.Response) (*mediaprovider.Lyrics, error) {
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, errors.New("lrclib lyrics not found")
+		return nil, errLrcLibNotFound
 	} else if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("error from lrclib: status %d", resp.StatusCode)
 	}
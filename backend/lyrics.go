@@ -0,0 +1,178 @@
+package backend
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// LyricsSource is a source of lyrics for tracks. Sources are queried in
+// order by the LyricsFetcher until one returns lyrics.
+type LyricsSource interface {
+	// Name identifies the source in log messages.
+	Name() string
+
+	// FetchLyrics returns the lyrics of the track,
+	// or nil (and possibly an error) if not found.
+	FetchLyrics(track *mediaprovider.Track) (*mediaprovider.Lyrics, error)
+}
+
+// LyricsFetcher fetches the lyrics of tracks from a chain of sources:
+// the media server, if it supports lyrics, then sidecar .lrc files next to
+// local copies of the track, and finally LRCLIB if enabled.
+type LyricsFetcher struct {
+	mu      sync.Mutex
+	sources []LyricsSource
+}
+
+func NewLyricsFetcher(sm *ServerManager, tc *TrackCache, dm *DownloadManager, conf *AppConfig) *LyricsFetcher {
+	return &LyricsFetcher{
+		sources: []LyricsSource{
+			&serverLyricsSource{sm: sm},
+			&sidecarLyricsSource{localPaths: func(tr *mediaprovider.Track) []string {
+				var paths []string
+				if p, ok := dm.LocalPath(tr.ID); ok {
+					paths = append(paths, p)
+				}
+				if p, ok := tc.LocalPath(tr.ID); ok {
+					paths = append(paths, p)
+				}
+				// the server's own copy, if it is on this machine
+				if filepath.IsAbs(tr.FilePath) {
+					paths = append(paths, tr.FilePath)
+				}
+				return paths
+			}},
+			&lrcLibLyricsSource{enabled: func() bool { return conf.EnableLrcLib }},
+		},
+	}
+}
+
+// AddSource appends a source, to be queried after the existing ones.
+func (l *LyricsFetcher) AddSource(s LyricsSource) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sources = append(l.sources, s)
+}
+
+// FetchLyrics returns the lyrics of the track from the first source
+// which has them, or nil if none do. It blocks during network requests.
+func (l *LyricsFetcher) FetchLyrics(track *mediaprovider.Track) *mediaprovider.Lyrics {
+	if track == nil {
+		return nil
+	}
+	l.mu.Lock()
+	sources := l.sources
+	l.mu.Unlock()
+	for _, s := range sources {
+		lyrics, err := s.FetchLyrics(track)
+		if err != nil {
+			log.Printf("Error fetching lyrics from %s: %v", s.Name(), err)
+		}
+		if lyrics != nil && len(lyrics.Lines) > 0 {
+			return lyrics
+		}
+	}
+	return nil
+}
+
+type serverLyricsSource struct {
+	sm *ServerManager
+}
+
+func (s *serverLyricsSource) Name() string { return "server" }
+
+func (s *serverLyricsSource) FetchLyrics(track *mediaprovider.Track) (*mediaprovider.Lyrics, error) {
+	lp, ok := mediaprovider.As[mediaprovider.LyricsProvider](s.sm.Server)
+	if !ok {
+		return nil, nil
+	}
+	return lp.GetLyrics(track)
+}
+
+// sidecarLyricsSource reads lyrics from an .lrc (or .txt) file
+// with the same base name as a local copy of the track.
+type sidecarLyricsSource struct {
+	localPaths func(*mediaprovider.Track) []string
+}
+
+func (s *sidecarLyricsSource) Name() string { return "sidecar file" }
+
+func (s *sidecarLyricsSource) FetchLyrics(track *mediaprovider.Track) (*mediaprovider.Lyrics, error) {
+	for _, p := range s.localPaths(track) {
+		base := strings.TrimSuffix(p, filepath.Ext(p))
+		for _, ext := range []string{".lrc", ".txt"} {
+			b, err := os.ReadFile(base + ext)
+			if err != nil {
+				continue
+			}
+			lyrics := parseLrc(string(b))
+			if lyrics.Title == "" {
+				lyrics.Title = track.Title
+			}
+			if lyrics.Artist == "" && len(track.ArtistNames) > 0 {
+				lyrics.Artist = track.ArtistNames[0]
+			}
+			return lyrics, nil
+		}
+	}
+	return nil, nil
+}
+
+type lrcLibLyricsSource struct {
+	enabled func() bool
+}
+
+func (s *lrcLibLyricsSource) Name() string { return "LRCLIB" }
+
+func (s *lrcLibLyricsSource) FetchLyrics(track *mediaprovider.Track) (*mediaprovider.Lyrics, error) {
+	if !s.enabled() || len(track.ArtistNames) == 0 {
+		return nil, nil
+	}
+	lyrics, err := FetchLrcLibLyrics(track.Title, track.ArtistNames[0], track.Album, track.Duration)
+	if errors.Is(err, errLrcLibNotFound) {
+		err = nil
+	}
+	return lyrics, err
+}
+
+// parseLrc parses the contents of an LRC file. If it has no timestamped
+// lines it is treated as plain, unsynced lyrics.
+func parseLrc(contents string) *mediaprovider.Lyrics {
+	contents = strings.ReplaceAll(strings.TrimPrefix(contents, "\ufeff"), "\r\n", "\n")
+	lyrics := &mediaprovider.Lyrics{}
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if v, ok := lrcTag(line, "ti"); ok {
+			lyrics.Title = v
+		} else if v, ok := lrcTag(line, "ar"); ok {
+			lyrics.Artist = v
+		}
+	}
+	if lines, err := parseSyncedLyrics(contents); err == nil {
+		lyrics.Synced = true
+		lyrics.Lines = lines
+		return lyrics
+	}
+	for _, line := range strings.Split(strings.TrimSpace(contents), "\n") {
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			continue // ID tag
+		}
+		lyrics.Lines = append(lyrics.Lines, mediaprovider.LyricLine{Text: line})
+	}
+	return lyrics
+}
+
+// lrcTag returns the value of an LRC ID tag line such as "[ar:Artist]".
+func lrcTag(line, tag string) (string, bool) {
+	prefix := "[" + tag + ":"
+	if !strings.HasPrefix(line, prefix) || !strings.HasSuffix(line, "]") {
+		return "", false
+	}
+	return strings.TrimSpace(line[len(prefix) : len(line)-1]), true
+}
@@ -0,0 +1,483 @@
+package mediaprovider
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/deluan/sanitize"
+)
+
+// ErrContentFiltered is returned when getting an item hidden by the ContentFilter.
+var ErrContentFiltered = errors.New("item is hidden by the content filter")
+
+// Wrapper is implemented by MediaProviders which wrap
+// another provider to change the behavior of some methods.
+type Wrapper interface {
+	Unwrap() MediaProvider
+}
+
+// As returns the provider as the optional interface T (e.g. SupportsRating)
+// if it implements it. Unlike a type assertion, it sees through Wrappers:
+// a wrapper implements T if the provider it wraps does, using its own
+// implementation of T if it has one.
+func As[T any](mp MediaProvider) (T, bool) {
+	w, ok := mp.(Wrapper)
+	if !ok {
+		t, ok := mp.(T)
+		return t, ok
+	}
+	inner, ok := As[T](w.Unwrap())
+	if !ok {
+		return inner, false
+	}
+	if t, ok := mp.(T); ok {
+		return t, true
+	}
+	return inner, true
+}
+
+// ContentFilterOptions configures the content hidden by a ContentFilter.
+type ContentFilterOptions struct {
+	// hide tracks and albums with any of these genres
+	Genres []string
+	// hide items whose title, album or artist names contain any of these words
+	Keywords []string
+	// hide tracks and albums marked explicit. Not all servers report this.
+	HideExplicit bool
+}
+
+// IsNil returns true if the options hide nothing.
+func (o ContentFilterOptions) IsNil() bool {
+	return len(o.Genres) == 0 && len(o.Keywords) == 0 && !o.HideExplicit
+}
+
+// ContentFilter hides content from the results of a MediaProvider
+// wrapped by NewContentFilteredProvider, e.g. for a shared family computer.
+// The options can be changed at any time.
+type ContentFilter struct {
+	mu       sync.RWMutex
+	options  ContentFilterOptions
+	keywords []string // normalized
+}
+
+func NewContentFilter(options ContentFilterOptions) *ContentFilter {
+	f := &ContentFilter{}
+	f.SetOptions(options)
+	return f
+}
+
+func (f *ContentFilter) Options() ContentFilterOptions {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.options
+}
+
+func (f *ContentFilter) SetOptions(options ContentFilterOptions) {
+	var keywords []string
+	for _, k := range options.Keywords {
+		if k = normalizeKeyword(k); k != "" {
+			keywords = append(keywords, k)
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.options = options
+	f.keywords = keywords
+}
+
+func (f *ContentFilter) IsNil() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.options.IsNil()
+}
+
+func (f *ContentFilter) TrackAllowed(tr *Track) bool {
+	if tr == nil {
+		return false
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return !(f.options.HideExplicit && tr.Explicit) &&
+		!genresMatch(f.options.Genres, tr.Genres) &&
+		!f.containsKeyword(tr.Title, tr.Album) &&
+		!f.containsKeyword(tr.ArtistNames...)
+}
+
+func (f *ContentFilter) AlbumAllowed(al *Album) bool {
+	if al == nil {
+		return false
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return !(f.options.HideExplicit && al.Explicit) &&
+		!genresMatch(f.options.Genres, al.Genres) &&
+		!f.containsKeyword(al.Name) &&
+		!f.containsKeyword(al.ArtistNames...)
+}
+
+func (f *ContentFilter) ArtistAllowed(ar *Artist) bool {
+	if ar == nil {
+		return false
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return !f.containsKeyword(ar.Name)
+}
+
+func (f *ContentFilter) GenreAllowed(genre string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return !genresMatch(f.options.Genres, []string{genre})
+}
+
+// SearchResultAllowed checks the result's names and, for genres,
+// the genre. Albums and tracks are checked in full when fetched.
+func (f *ContentFilter) SearchResultAllowed(r *SearchResult) bool {
+	if r.Type == ContentTypeGenre && !f.GenreAllowed(r.Name) {
+		return false
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return !f.containsKeyword(r.Name, r.ArtistName)
+}
+
+func (f *ContentFilter) FilterTracks(tracks []*Track) []*Track {
+	return filterContent(f, tracks, f.TrackAllowed)
+}
+
+func (f *ContentFilter) FilterAlbums(albums []*Album) []*Album {
+	return filterContent(f, albums, f.AlbumAllowed)
+}
+
+func (f *ContentFilter) FilterArtists(artists []*Artist) []*Artist {
+	return filterContent(f, artists, f.ArtistAllowed)
+}
+
+// filterContent returns the allowed items, in a new slice
+// if any are hidden, so that cached slices are left unchanged.
+func filterContent[M any](f *ContentFilter, items []*M, allowed func(*M) bool) []*M {
+	if f.IsNil() || !slices.ContainsFunc(items, func(m *M) bool { return !allowed(m) }) {
+		return items
+	}
+	filtered := make([]*M, 0, len(items))
+	for _, m := range items {
+		if allowed(m) {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+// containsKeyword must be called with the read lock held.
+func (f *ContentFilter) containsKeyword(texts ...string) bool {
+	if len(f.keywords) == 0 {
+		return false
+	}
+	for _, t := range texts {
+		t = normalizeKeyword(t)
+		for _, k := range f.keywords {
+			if strings.Contains(t, k) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func normalizeKeyword(s string) string {
+	return sanitize.Accents(strings.ToLower(strings.TrimSpace(s)))
+}
+
+// filteredIterator checks the filter on each item rather than when
+// created, so that long-lived iterators follow changes to the options.
+type filteredIterator[M any] struct {
+	iter    MediaIterator[M]
+	f       *ContentFilter
+	allowed func(*M) bool
+}
+
+func (i *filteredIterator[M]) Next() *M {
+	for {
+		m := i.iter.Next()
+		if m == nil || i.f.IsNil() || i.allowed(m) {
+			return m
+		}
+	}
+}
+
+// contentFilteredProvider hides the content matching a ContentFilter
+// from the results of the MediaProvider it wraps.
+type contentFilteredProvider struct {
+	MediaProvider
+	f *ContentFilter
+}
+
+var (
+	_ Wrapper                       = (*contentFilteredProvider)(nil)
+	_ SupportsInstantMix            = (*contentFilteredProvider)(nil)
+	_ SupportsPlayedTracks          = (*contentFilteredProvider)(nil)
+	_ SupportsFavoriteTrackIterator = (*contentFilteredProvider)(nil)
+	_ SupportsGenreTracks           = (*contentFilteredProvider)(nil)
+	_ SupportsAlbumsByYear          = (*contentFilteredProvider)(nil)
+	_ SupportsArtistAppearsOn       = (*contentFilteredProvider)(nil)
+	_ SupportsGetArtistProgressive  = (*contentFilteredProvider)(nil)
+	_ SupportsSearchOptions         = (*contentFilteredProvider)(nil)
+	_ CanSavePlayQueue              = (*contentFilteredProvider)(nil)
+)
+
+// NewContentFilteredProvider wraps the provider so that the content
+// hidden by the filter is left out of browsing, search and playback.
+// Use As rather than type assertions to find its optional interfaces.
+func NewContentFilteredProvider(mp MediaProvider, f *ContentFilter) MediaProvider {
+	return &contentFilteredProvider{MediaProvider: mp, f: f}
+}
+
+func (c *contentFilteredProvider) Unwrap() MediaProvider {
+	return c.MediaProvider
+}
+
+func (c *contentFilteredProvider) GetTrack(trackID string) (*Track, error) {
+	tr, err := c.MediaProvider.GetTrack(trackID)
+	if err == nil && !c.f.IsNil() && !c.f.TrackAllowed(tr) {
+		return nil, ErrContentFiltered
+	}
+	return tr, err
+}
+
+func (c *contentFilteredProvider) GetAlbum(albumID string) (*AlbumWithTracks, error) {
+	al, err := c.MediaProvider.GetAlbum(albumID)
+	if err != nil || c.f.IsNil() {
+		return al, err
+	}
+	if !c.f.AlbumAllowed(&al.Album) {
+		return nil, ErrContentFiltered
+	}
+	filtered := *al
+	filtered.Tracks = c.f.FilterTracks(al.Tracks)
+	return &filtered, nil
+}
+
+func (c *contentFilteredProvider) GetArtist(artistID string) (*ArtistWithAlbums, error) {
+	return c.filterArtist(c.MediaProvider.GetArtist(artistID))
+}
+
+func (c *contentFilteredProvider) filterArtist(ar *ArtistWithAlbums, err error) (*ArtistWithAlbums, error) {
+	if err != nil || c.f.IsNil() {
+		return ar, err
+	}
+	if !c.f.ArtistAllowed(&ar.Artist) {
+		return nil, ErrContentFiltered
+	}
+	filtered := *ar
+	filtered.Albums = c.f.FilterAlbums(ar.Albums)
+	return &filtered, nil
+}
+
+func (c *contentFilteredProvider) GetArtistInfo(artistID string) (*ArtistInfo, error) {
+	info, err := c.MediaProvider.GetArtistInfo(artistID)
+	if err != nil || c.f.IsNil() {
+		return info, err
+	}
+	filtered := *info
+	filtered.SimilarArtists = c.f.FilterArtists(info.SimilarArtists)
+	return &filtered, nil
+}
+
+func (c *contentFilteredProvider) GetPlaylist(playlistID string) (*PlaylistWithTracks, error) {
+	pl, err := c.MediaProvider.GetPlaylist(playlistID)
+	if err != nil || c.f.IsNil() {
+		return pl, err
+	}
+	filtered := *pl
+	filtered.Tracks = c.f.FilterTracks(pl.Tracks)
+	return &filtered, nil
+}
+
+func (c *contentFilteredProvider) IterateAlbums(sortOrder string, filter AlbumFilter) AlbumIterator {
+	return c.albumIterator(c.MediaProvider.IterateAlbums(sortOrder, filter))
+}
+
+func (c *contentFilteredProvider) IterateTracks(searchQuery string) TrackIterator {
+	return c.trackIterator(c.MediaProvider.IterateTracks(searchQuery))
+}
+
+func (c *contentFilteredProvider) SearchAlbums(searchQuery string, filter AlbumFilter) AlbumIterator {
+	return c.albumIterator(c.MediaProvider.SearchAlbums(searchQuery, filter))
+}
+
+func (c *contentFilteredProvider) IterateArtists(sortOrder string, filter ArtistFilter) ArtistIterator {
+	return c.artistIterator(c.MediaProvider.IterateArtists(sortOrder, filter))
+}
+
+func (c *contentFilteredProvider) SearchArtists(searchQuery string, filter ArtistFilter) ArtistIterator {
+	return c.artistIterator(c.MediaProvider.SearchArtists(searchQuery, filter))
+}
+
+func (c *contentFilteredProvider) SearchAll(searchQuery string, maxResults int) ([]*SearchResult, error) {
+	return c.filterSearchResults(c.MediaProvider.SearchAll(searchQuery, maxResults))
+}
+
+func (c *contentFilteredProvider) GetRandomTracks(genre string, count int) ([]*Track, error) {
+	return c.filterTracks(c.MediaProvider.GetRandomTracks(genre, count))
+}
+
+func (c *contentFilteredProvider) GetRandomAlbums(limit int, filter AlbumFilter) ([]*Album, error) {
+	return c.filterAlbums(c.MediaProvider.GetRandomAlbums(limit, filter))
+}
+
+func (c *contentFilteredProvider) GetRandomArtists(limit int) ([]*Artist, error) {
+	artists, err := c.MediaProvider.GetRandomArtists(limit)
+	return c.f.FilterArtists(artists), err
+}
+
+func (c *contentFilteredProvider) GetSimilarTracks(artistID string, count int) ([]*Track, error) {
+	return c.filterTracks(c.MediaProvider.GetSimilarTracks(artistID, count))
+}
+
+func (c *contentFilteredProvider) GetSongRadio(trackID string, count int) ([]*Track, error) {
+	return c.filterTracks(c.MediaProvider.GetSongRadio(trackID, count))
+}
+
+func (c *contentFilteredProvider) GetSimilarAlbums(albumID string, limit int) ([]*Album, error) {
+	return c.filterAlbums(c.MediaProvider.GetSimilarAlbums(albumID, limit))
+}
+
+func (c *contentFilteredProvider) GetTopTracks(artist Artist, count int) ([]*Track, error) {
+	return c.filterTracks(c.MediaProvider.GetTopTracks(artist, count))
+}
+
+func (c *contentFilteredProvider) GetGenres() ([]*Genre, error) {
+	genres, err := c.MediaProvider.GetGenres()
+	return filterContent(c.f, genres, func(g *Genre) bool { return c.f.GenreAllowed(g.Name) }), err
+}
+
+func (c *contentFilteredProvider) GetFavorites(sort FavoritesSortOrder) (Favorites, error) {
+	fav, err := c.MediaProvider.GetFavorites(sort)
+	fav.Albums = c.f.FilterAlbums(fav.Albums)
+	fav.Artists = c.f.FilterArtists(fav.Artists)
+	fav.Tracks = c.f.FilterTracks(fav.Tracks)
+	return fav, err
+}
+
+func (c *contentFilteredProvider) GetInstantMix(itemID string, limit int) ([]*Track, error) {
+	im, _ := As[SupportsInstantMix](c.MediaProvider)
+	return c.filterTracks(im.GetInstantMix(itemID, limit))
+}
+
+func (c *contentFilteredProvider) GetGenreInstantMix(genre string, limit int) ([]*Track, error) {
+	if !c.f.GenreAllowed(genre) {
+		return nil, ErrContentFiltered
+	}
+	im, _ := As[SupportsInstantMix](c.MediaProvider)
+	return c.filterTracks(im.GetGenreInstantMix(genre, limit))
+}
+
+func (c *contentFilteredProvider) GetMostPlayedTracks(limit int) ([]*Track, error) {
+	pt, _ := As[SupportsPlayedTracks](c.MediaProvider)
+	return c.filterTracks(pt.GetMostPlayedTracks(limit))
+}
+
+func (c *contentFilteredProvider) GetRecentlyPlayedTracks(limit int) ([]*Track, error) {
+	pt, _ := As[SupportsPlayedTracks](c.MediaProvider)
+	return c.filterTracks(pt.GetRecentlyPlayedTracks(limit))
+}
+
+func (c *contentFilteredProvider) IterateFavoriteTracks(sort FavoritesSortOrder) TrackIterator {
+	fi, _ := As[SupportsFavoriteTrackIterator](c.MediaProvider)
+	return c.trackIterator(fi.IterateFavoriteTracks(sort))
+}
+
+func (c *contentFilteredProvider) IterateGenreTracks(genre string) TrackIterator {
+	gt, _ := As[SupportsGenreTracks](c.MediaProvider)
+	return c.trackIterator(gt.IterateGenreTracks(genre))
+}
+
+func (c *contentFilteredProvider) IterateAlbumsByYear(fromYear, toYear int, filter AlbumFilter) AlbumIterator {
+	ay, _ := As[SupportsAlbumsByYear](c.MediaProvider)
+	return c.albumIterator(ay.IterateAlbumsByYear(fromYear, toYear, filter))
+}
+
+func (c *contentFilteredProvider) GetArtistAppearsOn(artistID string) ([]*Album, error) {
+	ao, _ := As[SupportsArtistAppearsOn](c.MediaProvider)
+	return c.filterAlbums(ao.GetArtistAppearsOn(artistID))
+}
+
+func (c *contentFilteredProvider) GetArtistProgressive(artistID string, onPartial func(*ArtistWithAlbums)) (*ArtistWithAlbums, error) {
+	p, _ := As[SupportsGetArtistProgressive](c.MediaProvider)
+	return c.filterArtist(p.GetArtistProgressive(artistID, func(partial *ArtistWithAlbums) {
+		if partial, err := c.filterArtist(partial, nil); err == nil {
+			onPartial(partial)
+		}
+	}))
+}
+
+func (c *contentFilteredProvider) SearchWithOptions(searchQuery string, opts SearchOptions) ([]*SearchResult, error) {
+	so, _ := As[SupportsSearchOptions](c.MediaProvider)
+	return c.filterSearchResults(so.SearchWithOptions(searchQuery, opts))
+}
+
+func (c *contentFilteredProvider) SavePlayQueue(trackIDs []string, currentTrackPos int, timeSeconds int) error {
+	pq, _ := As[CanSavePlayQueue](c.MediaProvider)
+	return pq.SavePlayQueue(trackIDs, currentTrackPos, timeSeconds)
+}
+
+// GetPlayQueue leaves the hidden tracks out of the queue saved on the
+// server, e.g. by another client, moving the current position to the
+// next track left if the current one is hidden.
+func (c *contentFilteredProvider) GetPlayQueue() (*SavedPlayQueue, error) {
+	pq, _ := As[CanSavePlayQueue](c.MediaProvider)
+	q, err := pq.GetPlayQueue()
+	if err != nil || q == nil || c.f.IsNil() {
+		return q, err
+	}
+	filtered := &SavedPlayQueue{TimePos: q.TimePos}
+	for i, tr := range q.Tracks {
+		if !c.f.TrackAllowed(tr) {
+			if i == q.TrackPos {
+				filtered.TimePos = 0
+			}
+			continue
+		}
+		if i < q.TrackPos {
+			filtered.TrackPos++
+		}
+		filtered.Tracks = append(filtered.Tracks, tr)
+	}
+	filtered.TrackPos = min(filtered.TrackPos, max(len(filtered.Tracks)-1, 0))
+	return filtered, nil
+}
+
+func (c *contentFilteredProvider) albumIterator(it AlbumIterator) AlbumIterator {
+	if it == nil {
+		return nil
+	}
+	return &filteredIterator[Album]{iter: it, f: c.f, allowed: c.f.AlbumAllowed}
+}
+
+func (c *contentFilteredProvider) trackIterator(it TrackIterator) TrackIterator {
+	if it == nil {
+		return nil
+	}
+	return &filteredIterator[Track]{iter: it, f: c.f, allowed: c.f.TrackAllowed}
+}
+
+func (c *contentFilteredProvider) artistIterator(it ArtistIterator) ArtistIterator {
+	if it == nil {
+		return nil
+	}
+	return &filteredIterator[Artist]{iter: it, f: c.f, allowed: c.f.ArtistAllowed}
+}
+
+func (c *contentFilteredProvider) filterTracks(tracks []*Track, err error) ([]*Track, error) {
+	return c.f.FilterTracks(tracks), err
+}
+
+func (c *contentFilteredProvider) filterAlbums(albums []*Album, err error) ([]*Album, error) {
+	return c.f.FilterAlbums(albums), err
+}
+
+func (c *contentFilteredProvider) filterSearchResults(results []*SearchResult, err error) ([]*SearchResult, error) {
+	return filterContent(c.f, results, c.f.SearchResultAllowed), err
+}
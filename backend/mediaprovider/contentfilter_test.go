@@ -0,0 +1,120 @@
+package mediaprovider_test
+
+import (
+	"testing"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/demo"
+)
+
+func TestContentFilterAllowed(t *testing.T) {
+	track := &mediaprovider.Track{Title: "Café Song", Album: "Album", ArtistNames: []string{"Artist"}, Genres: []string{"Rock", "Pop"}}
+	explicitTrack := &mediaprovider.Track{Title: "Song", Explicit: true}
+	album := &mediaprovider.Album{Name: "Album", ArtistNames: []string{"Artist"}, Genres: []string{"Rock"}, Explicit: true}
+
+	for _, tt := range []struct {
+		name             string
+		options          mediaprovider.ContentFilterOptions
+		trackAllowed     bool
+		albumAllowed     bool
+		rockGenreAllowed bool
+		artistAllowed    bool
+		explicitAllowed  bool
+	}{
+		{
+			name:         "nil options",
+			trackAllowed: true, albumAllowed: true, rockGenreAllowed: true, artistAllowed: true, explicitAllowed: true,
+		},
+		{
+			name:         "genre",
+			options:      mediaprovider.ContentFilterOptions{Genres: []string{"rock"}},
+			trackAllowed: false, albumAllowed: false, rockGenreAllowed: false, artistAllowed: true, explicitAllowed: true,
+		},
+		{
+			name:         "other genre",
+			options:      mediaprovider.ContentFilterOptions{Genres: []string{"Jazz"}},
+			trackAllowed: true, albumAllowed: true, rockGenreAllowed: true, artistAllowed: true, explicitAllowed: true,
+		},
+		{
+			name:         "title keyword ignoring case and accents",
+			options:      mediaprovider.ContentFilterOptions{Keywords: []string{" CAFE "}},
+			trackAllowed: false, albumAllowed: true, rockGenreAllowed: true, artistAllowed: true, explicitAllowed: true,
+		},
+		{
+			name:         "artist keyword",
+			options:      mediaprovider.ContentFilterOptions{Keywords: []string{"", "artist"}},
+			trackAllowed: false, albumAllowed: false, rockGenreAllowed: true, artistAllowed: false, explicitAllowed: true,
+		},
+		{
+			name:         "hide explicit",
+			options:      mediaprovider.ContentFilterOptions{HideExplicit: true},
+			trackAllowed: true, albumAllowed: false, rockGenreAllowed: true, artistAllowed: true, explicitAllowed: false,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f := mediaprovider.NewContentFilter(tt.options)
+			if got := f.TrackAllowed(track); got != tt.trackAllowed {
+				t.Errorf("TrackAllowed = %v, want %v", got, tt.trackAllowed)
+			}
+			if got := f.AlbumAllowed(album); got != tt.albumAllowed {
+				t.Errorf("AlbumAllowed = %v, want %v", got, tt.albumAllowed)
+			}
+			if got := f.GenreAllowed("Rock"); got != tt.rockGenreAllowed {
+				t.Errorf("GenreAllowed = %v, want %v", got, tt.rockGenreAllowed)
+			}
+			if got := f.ArtistAllowed(&mediaprovider.Artist{Name: "Artist"}); got != tt.artistAllowed {
+				t.Errorf("ArtistAllowed = %v, want %v", got, tt.artistAllowed)
+			}
+			if got := f.TrackAllowed(explicitTrack); got != tt.explicitAllowed {
+				t.Errorf("TrackAllowed(explicit) = %v, want %v", got, tt.explicitAllowed)
+			}
+			if got := f.IsNil(); got != tt.options.IsNil() {
+				t.Errorf("IsNil = %v, want %v", got, tt.options.IsNil())
+			}
+		})
+	}
+}
+
+func TestContentFilteredProvider(t *testing.T) {
+	inner := demo.NewMediaProvider(demo.DefaultSeed, 10)
+	tr, _ := inner.GetTrack("tr-1")
+	f := mediaprovider.NewContentFilter(mediaprovider.ContentFilterOptions{})
+	mp := mediaprovider.NewContentFilteredProvider(inner, f)
+
+	if _, ok := mediaprovider.As[mediaprovider.SupportsScanStatus](mp); !ok {
+		t.Error("wrapped provider lost an optional interface")
+	}
+	if _, ok := mediaprovider.As[mediaprovider.JukeboxProvider](mp); ok {
+		t.Error("wrapped provider gained an optional interface")
+	}
+
+	for _, opts := range []mediaprovider.ContentFilterOptions{
+		{Keywords: []string{tr.Title}},
+		{Genres: tr.Genres[:1]},
+	} {
+		f.SetOptions(opts)
+		if _, err := mp.GetTrack(tr.ID); err != mediaprovider.ErrContentFiltered {
+			t.Errorf("%+v: GetTrack of hidden track: err = %v", opts, err)
+		}
+		if al, err := mp.GetAlbum(tr.AlbumID); err == nil {
+			for _, alTr := range al.Tracks {
+				if alTr.ID == tr.ID {
+					t.Errorf("%+v: hidden track listed in its album", opts)
+				}
+			}
+		} else if err != mediaprovider.ErrContentFiltered {
+			t.Fatal(err)
+		}
+		iter := mp.IterateTracks("")
+		for it := iter.Next(); it != nil; it = iter.Next() {
+			if it.ID == tr.ID {
+				t.Errorf("%+v: hidden track listed by IterateTracks", opts)
+			}
+		}
+	}
+
+	f.SetOptions(mediaprovider.ContentFilterOptions{})
+	if _, err := mp.GetTrack(tr.ID); err != nil {
+		t.Errorf("GetTrack after clearing filter: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package demo
+
+import (
+	"hash/fnv"
+	"image"
+	"image/color"
+)
+
+const defaultCoverSize = 300
+
+// GetCoverArt returns a generated placeholder image: a diagonal gradient
+// between two colors derived from the ID. For a library of real files,
+// it returns the cover image from the files if there is one.
+func (d *demoMediaProvider) GetCoverArt(coverArtID string, size int) (image.Image, error) {
+	if d.files != nil {
+		if img, err := d.files.CoverArt(coverArtID); err == nil && img != nil {
+			return img, nil
+		}
+	}
+	if size <= 0 {
+		size = defaultCoverSize
+	}
+	h := fnv.New32a()
+	h.Write([]byte(coverArtID))
+	sum := h.Sum32()
+	from := hueColor(float64(sum%360), 0.55, 0.75)
+	to := hueColor(float64((sum/360)%360), 0.65, 0.35)
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			t := float64(x+y) / float64(2*size)
+			img.SetRGBA(x, y, color.RGBA{
+				R: lerp(from.R, to.R, t),
+				G: lerp(from.G, to.G, t),
+				B: lerp(from.B, to.B, t),
+				A: 255,
+			})
+		}
+	}
+	return img, nil
+}
+
+func lerp(a, b uint8, t float64) uint8 {
+	return uint8(float64(a) + (float64(b)-float64(a))*t)
+}
+
+// hueColor converts an HSV color with the given hue (in degrees),
+// saturation and value to RGB.
+func hueColor(hue, sat, val float64) color.RGBA {
+	c := val * sat
+	hp := hue / 60
+	x := c * (1 - abs(mod2(hp)-1))
+	var r, g, b float64
+	switch int(hp) {
+	case 0:
+		r, g = c, x
+	case 1:
+		r, g = x, c
+	case 2:
+		g, b = c, x
+	case 3:
+		g, b = x, c
+	case 4:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := val - c
+	return color.RGBA{R: uint8((r + m) * 255), G: uint8((g + m) * 255), B: uint8((b + m) * 255), A: 255}
+}
+
+func mod2(f float64) float64 {
+	return f - 2*float64(int(f/2))
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
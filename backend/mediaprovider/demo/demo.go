@@ -0,0 +1,940 @@
+// Package demo implements an in-memory MediaProvider with a generated
+// library, for trying out the app without a server ("demo mode")
+// and for testing code which uses a MediaProvider. The same provider can
+// also serve a library of real files indexed by the app itself
+// (see NewLibraryProvider), for sources which run no music server.
+package demo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deluan/sanitize"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+const (
+	// DefaultSeed is the seed of the library of the demo server.
+	DefaultSeed = 1
+	// Username is the user which owns the demo playlists.
+	Username = "demo"
+
+	demoNumArtists = 40
+)
+
+const (
+	AlbumSortRecentlyAdded  string = "Recently Added"
+	AlbumSortRandom         string = "Random"
+	AlbumSortTitleAZ        string = "Title (A-Z)"
+	AlbumSortArtistAZ       string = "Artist (A-Z)"
+	AlbumSortYearAscending  string = "Year (ascending)"
+	AlbumSortYearDescending string = "Year (descending)"
+
+	ArtistSortNameAZ     string = "Name (A-Z)"
+	ArtistSortAlbumCount string = "Album Count"
+	ArtistSortRandom     string = "Random"
+)
+
+// Server is a demo server. Logging in always succeeds.
+type Server struct {
+	// Seed determines the generated library.
+	// The same seed always generates the same library.
+	Seed int64
+
+	once sync.Once
+	mp   *demoMediaProvider
+}
+
+var _ mediaprovider.Server = (*Server)(nil)
+
+func (s *Server) Login(username, password string) mediaprovider.LoginResponse {
+	return mediaprovider.LoginResponse{}
+}
+
+func (s *Server) MediaProvider() mediaprovider.MediaProvider {
+	s.once.Do(func() { s.mp = newDemoMediaProvider(s.Seed, demoNumArtists) })
+	return s.mp
+}
+
+// NewMediaProvider returns a new in-memory MediaProvider with a library
+// of the given number of artists, generated from the seed. Everything
+// it returns, including the "random" results, is determined by the seed
+// and the calls made to it, so it can be used in tests.
+func NewMediaProvider(seed int64, numArtists int) mediaprovider.MediaProvider {
+	return newDemoMediaProvider(seed, numArtists)
+}
+
+type demoMediaProvider struct {
+	mu         sync.RWMutex
+	lib        *library
+	rand       *rand.Rand
+	prefetchCB func(string)
+	nextPlID   int
+
+	files    LibraryFiles // nil for a generated library
+	scanMu   sync.Mutex
+	scanning bool
+}
+
+var (
+	_ mediaprovider.SupportsRating                = (*demoMediaProvider)(nil)
+	_ mediaprovider.SupportsPlaylistTrackMove     = (*demoMediaProvider)(nil)
+	_ mediaprovider.SupportsSearchOptions         = (*demoMediaProvider)(nil)
+	_ mediaprovider.SupportsGenreTracks           = (*demoMediaProvider)(nil)
+	_ mediaprovider.SupportsAlbumsByYear          = (*demoMediaProvider)(nil)
+	_ mediaprovider.SupportsScanStatus            = (*demoMediaProvider)(nil)
+	_ mediaprovider.SupportsPlayedTracks          = (*demoMediaProvider)(nil)
+	_ mediaprovider.SupportsFavoriteTrackIterator = (*demoMediaProvider)(nil)
+	_ mediaprovider.SupportsStreamHeaders         = (*demoMediaProvider)(nil)
+)
+
+var errNotFound = errors.New("not found")
+
+func newDemoMediaProvider(seed int64, numArtists int) *demoMediaProvider {
+	lib := generateLibrary(seed, numArtists)
+	return &demoMediaProvider{
+		lib:        lib,
+		rand:       rand.New(rand.NewSource(seed)),
+		prefetchCB: func(string) {},
+		nextPlID:   len(lib.playlists) + 1,
+	}
+}
+
+func (d *demoMediaProvider) SetPrefetchCoverCallback(cb func(coverArtID string)) {
+	if cb == nil {
+		cb = func(string) {}
+	}
+	d.prefetchCB = cb
+}
+
+func (d *demoMediaProvider) GetTrack(trackID string) (*mediaprovider.Track, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	tr, ok := d.lib.tracksByID[trackID]
+	if !ok {
+		return nil, fmt.Errorf("track %s %w", trackID, errNotFound)
+	}
+	return copyTrack(tr), nil
+}
+
+func (d *demoMediaProvider) GetAlbum(albumID string) (*mediaprovider.AlbumWithTracks, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	al, ok := d.lib.albumsByID[albumID]
+	if !ok {
+		return nil, fmt.Errorf("album %s %w", albumID, errNotFound)
+	}
+	return &mediaprovider.AlbumWithTracks{
+		Album:  *copyAlbum(al),
+		Tracks: d.tracksByIDs(d.lib.albumTracks[albumID]),
+	}, nil
+}
+
+func (d *demoMediaProvider) GetAlbumInfo(albumID string) (*mediaprovider.AlbumInfo, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	al, ok := d.lib.albumsByID[albumID]
+	if !ok {
+		return nil, fmt.Errorf("album %s %w", albumID, errNotFound)
+	}
+	if d.files != nil {
+		return &mediaprovider.AlbumInfo{}, nil
+	}
+	return &mediaprovider.AlbumInfo{
+		Notes: fmt.Sprintf("%s is a %d album by %s. This album is part of the demo library, and its tracks play a test tone.",
+			al.Name, al.Year, strings.Join(al.ArtistNames, ", ")),
+	}, nil
+}
+
+func (d *demoMediaProvider) GetArtist(artistID string) (*mediaprovider.ArtistWithAlbums, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	ar, ok := d.lib.artistsByID[artistID]
+	if !ok {
+		return nil, fmt.Errorf("artist %s %w", artistID, errNotFound)
+	}
+	artist := &mediaprovider.ArtistWithAlbums{Artist: *ar}
+	for _, id := range d.lib.artistAlbums[artistID] {
+		artist.Albums = append(artist.Albums, copyAlbum(d.lib.albumsByID[id]))
+	}
+	return artist, nil
+}
+
+func (d *demoMediaProvider) GetArtistInfo(artistID string) (*mediaprovider.ArtistInfo, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	ar, ok := d.lib.artistsByID[artistID]
+	if !ok {
+		return nil, fmt.Errorf("artist %s %w", artistID, errNotFound)
+	}
+	info := &mediaprovider.ArtistInfo{}
+	if d.files == nil {
+		info.Biography = fmt.Sprintf("%s is an artist in the demo library, with %d releases.", ar.Name, ar.AlbumCount)
+	}
+	// artists with the same genre are similar
+	genre := d.artistGenre(artistID)
+	for _, other := range d.lib.artists {
+		if other.ID != artistID && genre != "" && d.artistGenre(other.ID) == genre {
+			a := *other
+			info.SimilarArtists = append(info.SimilarArtists, &a)
+		}
+	}
+	return info, nil
+}
+
+func (d *demoMediaProvider) GetPlaylist(playlistID string) (*mediaprovider.PlaylistWithTracks, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	pl := d.findPlaylist(playlistID)
+	if pl == nil {
+		return nil, fmt.Errorf("playlist %s %w", playlistID, errNotFound)
+	}
+	p := &mediaprovider.PlaylistWithTracks{Playlist: pl.Playlist}
+	for _, tr := range pl.Tracks {
+		p.Tracks = append(p.Tracks, copyTrack(tr))
+	}
+	return p, nil
+}
+
+func (d *demoMediaProvider) AlbumSortOrders() []string {
+	return []string{
+		AlbumSortRecentlyAdded,
+		AlbumSortRandom,
+		AlbumSortTitleAZ,
+		AlbumSortArtistAZ,
+		AlbumSortYearAscending,
+		AlbumSortYearDescending,
+	}
+}
+
+func (d *demoMediaProvider) IterateAlbums(sortOrder string, filter mediaprovider.AlbumFilter) mediaprovider.AlbumIterator {
+	d.mu.Lock()
+	albums := slices.Clone(d.lib.albums)
+	switch sortOrder {
+	case AlbumSortRandom:
+		d.rand.Shuffle(len(albums), func(i, j int) { albums[i], albums[j] = albums[j], albums[i] })
+	case AlbumSortTitleAZ:
+		slices.SortStableFunc(albums, func(a, b *mediaprovider.Album) int { return compareNames(a.SortKey(), b.SortKey()) })
+	case AlbumSortArtistAZ:
+		slices.SortStableFunc(albums, func(a, b *mediaprovider.Album) int {
+			return compareNames(d.lib.artistsByID[a.ArtistIDs[0]].SortKey(), d.lib.artistsByID[b.ArtistIDs[0]].SortKey())
+		})
+	case AlbumSortYearAscending:
+		slices.SortStableFunc(albums, func(a, b *mediaprovider.Album) int { return a.Year - b.Year })
+	case AlbumSortYearDescending:
+		slices.SortStableFunc(albums, func(a, b *mediaprovider.Album) int { return b.Year - a.Year })
+	}
+	d.mu.Unlock()
+	return d.albumIterator(albums, filter)
+}
+
+func (d *demoMediaProvider) IterateAlbumsByYear(fromYear, toYear int, filter mediaprovider.AlbumFilter) mediaprovider.AlbumIterator {
+	d.mu.RLock()
+	var albums []*mediaprovider.Album
+	for _, al := range d.lib.albums {
+		if al.Year >= min(fromYear, toYear) && al.Year <= max(fromYear, toYear) {
+			albums = append(albums, al)
+		}
+	}
+	d.mu.RUnlock()
+	slices.SortStableFunc(albums, func(a, b *mediaprovider.Album) int {
+		if fromYear > toYear {
+			return b.Year - a.Year
+		}
+		return a.Year - b.Year
+	})
+	return d.albumIterator(albums, filter)
+}
+
+func (d *demoMediaProvider) IterateGenreTracks(genre string) mediaprovider.TrackIterator {
+	d.mu.RLock()
+	var tracks []*mediaprovider.Track
+	for _, tr := range d.lib.tracks {
+		if slices.Contains(tr.Genres, genre) {
+			tracks = append(tracks, tr)
+		}
+	}
+	d.mu.RUnlock()
+	return d.trackIterator(tracks)
+}
+
+func (d *demoMediaProvider) IterateFavoriteTracks(sort mediaprovider.FavoritesSortOrder) mediaprovider.TrackIterator {
+	d.mu.RLock()
+	var favs mediaprovider.Favorites
+	for _, tr := range d.lib.tracks {
+		if tr.Favorite {
+			favs.Tracks = append(favs.Tracks, tr)
+		}
+	}
+	favs.Sort(sort)
+	d.mu.RUnlock()
+	return d.trackIterator(favs.Tracks)
+}
+
+func (d *demoMediaProvider) IterateTracks(searchQuery string) mediaprovider.TrackIterator {
+	d.mu.RLock()
+	var tracks []*mediaprovider.Track
+	terms := searchTerms(searchQuery)
+	for _, tr := range d.lib.tracks {
+		if helpers.AllTermsMatch(sanitized(tr.Title+" "+tr.Album+" "+strings.Join(tr.ArtistNames, " ")), terms) {
+			tracks = append(tracks, tr)
+		}
+	}
+	d.mu.RUnlock()
+	return d.trackIterator(tracks)
+}
+
+func (d *demoMediaProvider) SearchAlbums(searchQuery string, filter mediaprovider.AlbumFilter) mediaprovider.AlbumIterator {
+	d.mu.RLock()
+	var albums []*mediaprovider.Album
+	terms := searchTerms(searchQuery)
+	for _, al := range d.lib.albums {
+		if helpers.AllTermsMatch(sanitized(al.Name+" "+strings.Join(al.ArtistNames, " ")), terms) {
+			albums = append(albums, al)
+		}
+	}
+	d.mu.RUnlock()
+	return d.albumIterator(albums, filter)
+}
+
+func (d *demoMediaProvider) SearchAll(searchQuery string, maxResults int) ([]*mediaprovider.SearchResult, error) {
+	results, _ := d.SearchWithOptions(searchQuery, mediaprovider.DefaultSearchOptions(maxResults))
+	if len(results) > maxResults {
+		results = results[:maxResults]
+	}
+	return results, nil
+}
+
+func (d *demoMediaProvider) SearchWithOptions(searchQuery string, opts mediaprovider.SearchOptions) ([]*mediaprovider.SearchResult, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	terms := searchTerms(searchQuery)
+	matches := func(name string) bool { return helpers.AllTermsMatch(sanitized(name), terms) }
+
+	var results []*mediaprovider.SearchResult
+	for _, ar := range d.lib.artists {
+		if matches(ar.Name) {
+			results = append(results, &mediaprovider.SearchResult{
+				Type: mediaprovider.ContentTypeArtist, ID: ar.ID, CoverID: ar.CoverArtID, Name: ar.Name, Size: ar.AlbumCount,
+			})
+		}
+	}
+	for _, al := range d.lib.albums {
+		if matches(al.Name) {
+			results = append(results, &mediaprovider.SearchResult{
+				Type: mediaprovider.ContentTypeAlbum, ID: al.ID, CoverID: al.CoverArtID, Name: al.Name,
+				ArtistName: strings.Join(al.ArtistNames, ", "), Size: al.TrackCount,
+			})
+		}
+	}
+	for _, tr := range d.lib.tracks {
+		if matches(tr.Title) {
+			results = append(results, &mediaprovider.SearchResult{
+				Type: mediaprovider.ContentTypeTrack, ID: tr.ID, CoverID: tr.CoverArtID, Name: tr.Title,
+				ArtistName: strings.Join(tr.ArtistNames, ", "), Size: tr.Duration,
+			})
+		}
+	}
+	for _, pl := range d.lib.playlists {
+		if matches(pl.Name) {
+			results = append(results, &mediaprovider.SearchResult{
+				Type: mediaprovider.ContentTypePlaylist, ID: pl.ID, CoverID: pl.CoverArtID, Name: pl.Name, Size: pl.TrackCount,
+			})
+		}
+	}
+	for _, g := range d.genres() {
+		if matches(g.Name) {
+			results = append(results, &mediaprovider.SearchResult{
+				Type: mediaprovider.ContentTypeGenre, ID: g.Name, Name: g.Name, Size: g.AlbumCount,
+			})
+		}
+	}
+
+	helpers.RankSearchResults(results, strings.Join(terms, " "), terms)
+	return helpers.LimitSearchResults(results, opts), nil
+}
+
+func (d *demoMediaProvider) GetRandomTracks(genre string, count int) ([]*mediaprovider.Track, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var tracks []*mediaprovider.Track
+	for _, idx := range d.rand.Perm(len(d.lib.tracks)) {
+		if len(tracks) == count {
+			break
+		}
+		if tr := d.lib.tracks[idx]; genre == "" || slices.Contains(tr.Genres, genre) {
+			tracks = append(tracks, copyTrack(tr))
+		}
+	}
+	return tracks, nil
+}
+
+func (d *demoMediaProvider) GetRandomAlbums(limit int, filter mediaprovider.AlbumFilter) ([]*mediaprovider.Album, error) {
+	var albums []*mediaprovider.Album
+	iter := d.IterateAlbums(AlbumSortRandom, filter)
+	for al := iter.Next(); al != nil && len(albums) < limit; al = iter.Next() {
+		albums = append(albums, al)
+	}
+	return albums, nil
+}
+
+func (d *demoMediaProvider) GetRandomArtists(limit int) ([]*mediaprovider.Artist, error) {
+	var artists []*mediaprovider.Artist
+	iter := d.IterateArtists(ArtistSortRandom, mediaprovider.NewArtistFilter(mediaprovider.ArtistFilterOptions{}))
+	for ar := iter.Next(); ar != nil && len(artists) < limit; ar = iter.Next() {
+		artists = append(artists, ar)
+	}
+	return artists, nil
+}
+
+func (d *demoMediaProvider) GetSimilarTracks(artistID string, count int) ([]*mediaprovider.Track, error) {
+	info, err := d.GetArtistInfo(artistID)
+	if err != nil {
+		return nil, err
+	}
+	var artistIDs []string
+	for _, ar := range info.SimilarArtists {
+		artistIDs = append(artistIDs, ar.ID)
+	}
+	return d.randomTracksByArtists(artistIDs, count), nil
+}
+
+func (d *demoMediaProvider) GetSongRadio(trackID string, count int) ([]*mediaprovider.Track, error) {
+	tr, err := d.GetTrack(trackID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tr.ArtistIDs) == 0 {
+		return []*mediaprovider.Track{tr}, nil
+	}
+	info, err := d.GetArtistInfo(tr.ArtistIDs[0])
+	if err != nil {
+		return nil, err
+	}
+	artistIDs := []string{tr.ArtistIDs[0]}
+	for _, ar := range info.SimilarArtists {
+		artistIDs = append(artistIDs, ar.ID)
+	}
+	tracks := d.randomTracksByArtists(artistIDs, count)
+	tracks = slices.DeleteFunc(tracks, func(t *mediaprovider.Track) bool { return t.ID == trackID })
+	return append([]*mediaprovider.Track{tr}, tracks...), nil
+}
+
+func (d *demoMediaProvider) GetSimilarAlbums(albumID string, limit int) ([]*mediaprovider.Album, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	al, ok := d.lib.albumsByID[albumID]
+	if !ok {
+		return nil, fmt.Errorf("album %s %w", albumID, errNotFound)
+	}
+	byGenre := sharedutil.FilterSlice(d.lib.albums, func(a *mediaprovider.Album) bool {
+		return firstGenre(a) == firstGenre(al)
+	})
+	similar := helpers.PickSimilarAlbums(al, nil, byGenre, limit)
+	return sharedutil.MapSlice(similar, copyAlbum), nil
+}
+
+func (d *demoMediaProvider) randomTracksByArtists(artistIDs []string, count int) []*mediaprovider.Track {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var tracks []*mediaprovider.Track
+	for _, idx := range d.rand.Perm(len(d.lib.tracks)) {
+		if len(tracks) == count {
+			break
+		}
+		if tr := d.lib.tracks[idx]; len(tr.ArtistIDs) > 0 && slices.Contains(artistIDs, tr.ArtistIDs[0]) {
+			tracks = append(tracks, copyTrack(tr))
+		}
+	}
+	return tracks
+}
+
+func (d *demoMediaProvider) ArtistSortOrders() []string {
+	return []string{
+		ArtistSortNameAZ,
+		ArtistSortAlbumCount,
+		ArtistSortRandom,
+	}
+}
+
+func (d *demoMediaProvider) IterateArtists(sortOrder string, filter mediaprovider.ArtistFilter) mediaprovider.ArtistIterator {
+	d.mu.Lock()
+	artists := slices.Clone(d.lib.artists)
+	switch sortOrder {
+	case ArtistSortAlbumCount:
+		sort.SliceStable(artists, func(i, j int) bool { return artists[i].AlbumCount > artists[j].AlbumCount })
+	case ArtistSortRandom:
+		d.rand.Shuffle(len(artists), func(i, j int) { artists[i], artists[j] = artists[j], artists[i] })
+	default:
+		sort.SliceStable(artists, func(i, j int) bool {
+			return compareNames(artists[i].SortKey(), artists[j].SortKey()) < 0
+		})
+	}
+	d.mu.Unlock()
+	return d.artistIterator(artists, filter)
+}
+
+func (d *demoMediaProvider) SearchArtists(searchQuery string, filter mediaprovider.ArtistFilter) mediaprovider.ArtistIterator {
+	d.mu.RLock()
+	var artists []*mediaprovider.Artist
+	terms := searchTerms(searchQuery)
+	for _, ar := range d.lib.artists {
+		if helpers.AllTermsMatch(sanitized(ar.Name), terms) {
+			artists = append(artists, ar)
+		}
+	}
+	d.mu.RUnlock()
+	return d.artistIterator(artists, filter)
+}
+
+func (d *demoMediaProvider) GetGenres() ([]*mediaprovider.Genre, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.genres(), nil
+}
+
+func (d *demoMediaProvider) genres() []*mediaprovider.Genre {
+	byName := make(map[string]*mediaprovider.Genre)
+	var genres []*mediaprovider.Genre
+	for _, al := range d.lib.albums {
+		for _, name := range al.Genres {
+			g, ok := byName[name]
+			if !ok {
+				g = &mediaprovider.Genre{Name: name}
+				byName[name] = g
+				genres = append(genres, g)
+			}
+			g.AlbumCount++
+			g.TrackCount += al.TrackCount
+		}
+	}
+	sort.Slice(genres, func(i, j int) bool { return genres[i].Name < genres[j].Name })
+	return genres
+}
+
+func (d *demoMediaProvider) GetFavorites(sort mediaprovider.FavoritesSortOrder) (mediaprovider.Favorites, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var favs mediaprovider.Favorites
+	for _, ar := range d.lib.artists {
+		if ar.Favorite {
+			a := *ar
+			favs.Artists = append(favs.Artists, &a)
+		}
+	}
+	for _, al := range d.lib.albums {
+		if al.Favorite {
+			favs.Albums = append(favs.Albums, copyAlbum(al))
+		}
+	}
+	for _, tr := range d.lib.tracks {
+		if tr.Favorite {
+			favs.Tracks = append(favs.Tracks, copyTrack(tr))
+		}
+	}
+	favs.Sort(sort)
+	return favs, nil
+}
+
+// GetStreamURL returns a URL of a test tone generated by mpv (through
+// libavfilter) as long as the track. Each track has its own pitch.
+// For a library of real files, it returns the URL of the track's file.
+func (d *demoMediaProvider) GetStreamURL(trackID string, forceRaw bool) (string, error) {
+	tr, err := d.GetTrack(trackID)
+	if err != nil {
+		return "", err
+	}
+	if d.files != nil {
+		return d.files.StreamURL(tr)
+	}
+	var n int
+	fmt.Sscanf(tr.ID, "tr-%d", &n)
+	// notes of the A minor pentatonic scale, from A3
+	freqs := []int{220, 262, 294, 330, 392, 440, 523, 587, 659, 784}
+	return fmt.Sprintf("av://lavfi:sine=frequency=%d:duration=%d", freqs[n%len(freqs)], tr.Duration), nil
+}
+
+// StreamHeaders returns the headers of a library of real files, if any.
+func (d *demoMediaProvider) StreamHeaders(trackID string) http.Header {
+	if d.files == nil {
+		return nil
+	}
+	tr, err := d.GetTrack(trackID)
+	if err != nil {
+		return nil
+	}
+	return d.files.StreamHeaders(tr)
+}
+
+func (d *demoMediaProvider) GetTopTracks(artist mediaprovider.Artist, count int) ([]*mediaprovider.Track, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var tracks []*mediaprovider.Track
+	for _, tr := range d.lib.tracks {
+		if slices.Contains(tr.ArtistIDs, artist.ID) {
+			tracks = append(tracks, tr)
+		}
+	}
+	sort.SliceStable(tracks, func(i, j int) bool { return tracks[i].PlayCount > tracks[j].PlayCount })
+	return d.tracksByIDs(trackIDs(tracks[:min(count, len(tracks))])), nil
+}
+
+func (d *demoMediaProvider) SetFavorite(params mediaprovider.RatingFavoriteParameters, favorite bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var favoritedAt time.Time
+	if favorite {
+		favoritedAt = time.Now()
+	}
+	for _, id := range params.AlbumIDs {
+		if al, ok := d.lib.albumsByID[id]; ok {
+			al.Favorite, al.FavoritedAt = favorite, favoritedAt
+		}
+	}
+	for _, id := range params.ArtistIDs {
+		if ar, ok := d.lib.artistsByID[id]; ok {
+			ar.Favorite, ar.FavoritedAt = favorite, favoritedAt
+		}
+	}
+	for _, id := range params.TrackIDs {
+		if tr, ok := d.lib.tracksByID[id]; ok {
+			tr.Favorite, tr.FavoritedAt = favorite, favoritedAt
+		}
+	}
+	return nil
+}
+
+func (d *demoMediaProvider) SetRating(params mediaprovider.RatingFavoriteParameters, rating int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, id := range params.TrackIDs {
+		if tr, ok := d.lib.tracksByID[id]; ok {
+			tr.Rating = rating
+		}
+	}
+	return nil
+}
+
+func (d *demoMediaProvider) GetPlaylists() ([]*mediaprovider.Playlist, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	playlists := make([]*mediaprovider.Playlist, 0, len(d.lib.playlists))
+	for _, pl := range d.lib.playlists {
+		p := pl.Playlist
+		playlists = append(playlists, &p)
+	}
+	return playlists, nil
+}
+
+func (d *demoMediaProvider) CreatePlaylist(name string, trackIDs []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	pl := &mediaprovider.PlaylistWithTracks{Playlist: mediaprovider.Playlist{
+		ID:    fmt.Sprintf("pl-%d", d.nextPlID),
+		Name:  name,
+		Owner: Username,
+	}}
+	d.nextPlID++
+	pl.Tracks = d.libraryTracks(trackIDs)
+	updatePlaylistStats(pl)
+	d.lib.playlists = append(d.lib.playlists, pl)
+	return nil
+}
+
+func (d *demoMediaProvider) CanMakePublicPlaylist() bool {
+	return true
+}
+
+func (d *demoMediaProvider) EditPlaylist(id, name, description string, public bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	pl := d.findPlaylist(id)
+	if pl == nil {
+		return fmt.Errorf("playlist %s %w", id, errNotFound)
+	}
+	pl.Name, pl.Description, pl.Public = name, description, public
+	return nil
+}
+
+func (d *demoMediaProvider) AddPlaylistTracks(id string, trackIDsToAdd []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	pl := d.findPlaylist(id)
+	if pl == nil {
+		return fmt.Errorf("playlist %s %w", id, errNotFound)
+	}
+	pl.Tracks = append(pl.Tracks, d.libraryTracks(trackIDsToAdd)...)
+	updatePlaylistStats(pl)
+	return nil
+}
+
+func (d *demoMediaProvider) RemovePlaylistTracks(id string, trackIdxsToRemove []int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	pl := d.findPlaylist(id)
+	if pl == nil {
+		return fmt.Errorf("playlist %s %w", id, errNotFound)
+	}
+	tracks := make([]*mediaprovider.Track, 0, len(pl.Tracks))
+	for i, tr := range pl.Tracks {
+		if !slices.Contains(trackIdxsToRemove, i) {
+			tracks = append(tracks, tr)
+		}
+	}
+	pl.Tracks = tracks
+	updatePlaylistStats(pl)
+	return nil
+}
+
+func (d *demoMediaProvider) ReplacePlaylistTracks(id string, trackIDs []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	pl := d.findPlaylist(id)
+	if pl == nil {
+		return fmt.Errorf("playlist %s %w", id, errNotFound)
+	}
+	pl.Tracks = d.libraryTracks(trackIDs)
+	updatePlaylistStats(pl)
+	return nil
+}
+
+func (d *demoMediaProvider) MovePlaylistTrack(playlistID string, fromIdx, toIdx int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	pl := d.findPlaylist(playlistID)
+	if pl == nil {
+		return fmt.Errorf("playlist %s %w", playlistID, errNotFound)
+	}
+	if fromIdx < 0 || fromIdx >= len(pl.Tracks) || toIdx < 0 || toIdx >= len(pl.Tracks) {
+		return errors.New("playlist track index out of range")
+	}
+	tr := pl.Tracks[fromIdx]
+	pl.Tracks = slices.Insert(slices.Delete(pl.Tracks, fromIdx, fromIdx+1), toIdx, tr)
+	updatePlaylistStats(pl)
+	return nil
+}
+
+func (d *demoMediaProvider) DeletePlaylist(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	idx := slices.IndexFunc(d.lib.playlists, func(pl *mediaprovider.PlaylistWithTracks) bool { return pl.ID == id })
+	if idx < 0 {
+		return fmt.Errorf("playlist %s %w", id, errNotFound)
+	}
+	d.lib.playlists = slices.Delete(d.lib.playlists, idx, idx+1)
+	return nil
+}
+
+func (d *demoMediaProvider) ClientDecidesScrobble() bool {
+	return true
+}
+
+func (d *demoMediaProvider) TrackBeganPlayback(trackID string) error {
+	return nil
+}
+
+// TrackEndedPlayback counts a play of the track if submission is true.
+func (d *demoMediaProvider) TrackEndedPlayback(trackID string, positionSecs int, submission bool) error {
+	if !submission {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	tr, ok := d.lib.tracksByID[trackID]
+	if !ok {
+		return fmt.Errorf("track %s %w", trackID, errNotFound)
+	}
+	tr.PlayCount++
+	tr.LastPlayed = time.Now()
+	return nil
+}
+
+func (d *demoMediaProvider) GetMostPlayedTracks(limit int) ([]*mediaprovider.Track, error) {
+	return d.playedTracks(limit, func(a, b *mediaprovider.Track) int { return b.PlayCount - a.PlayCount }), nil
+}
+
+func (d *demoMediaProvider) GetRecentlyPlayedTracks(limit int) ([]*mediaprovider.Track, error) {
+	return d.playedTracks(limit, func(a, b *mediaprovider.Track) int { return b.LastPlayed.Compare(a.LastPlayed) }), nil
+}
+
+func (d *demoMediaProvider) playedTracks(limit int, cmp func(a, b *mediaprovider.Track) int) []*mediaprovider.Track {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	played := sharedutil.FilterSlice(d.lib.tracks, func(tr *mediaprovider.Track) bool { return tr.PlayCount > 0 })
+	slices.SortStableFunc(played, cmp)
+	return sharedutil.MapSlice(played[:min(limit, len(played))], copyTrack)
+}
+
+func (d *demoMediaProvider) DownloadTrack(trackID string) (io.Reader, error) {
+	if d.files == nil {
+		return nil, errors.New("the demo library has no files to download")
+	}
+	tr, err := d.GetTrack(trackID)
+	if err != nil {
+		return nil, err
+	}
+	return d.files.Open(tr)
+}
+
+// RescanLibrary rescans the files of a library of real files in the
+// background, replacing the library when done. No-op for the demo library.
+func (d *demoMediaProvider) RescanLibrary() error {
+	if d.files == nil {
+		return nil
+	}
+	d.scanMu.Lock()
+	defer d.scanMu.Unlock()
+	if d.scanning {
+		return nil
+	}
+	d.scanning = true
+	go func() {
+		tracks, err := d.files.Scan()
+		if err == nil {
+			d.setLibrary(buildLibrary(tracks))
+		}
+		d.scanMu.Lock()
+		d.scanning = false
+		d.scanMu.Unlock()
+	}()
+	return nil
+}
+
+func (d *demoMediaProvider) GetScanStatus() (mediaprovider.ScanStatus, error) {
+	d.scanMu.Lock()
+	scanning := d.scanning
+	d.scanMu.Unlock()
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return mediaprovider.ScanStatus{Scanning: scanning, Count: int64(len(d.lib.tracks)), Progress: -1}, nil
+}
+
+func (d *demoMediaProvider) albumIterator(albums []*mediaprovider.Album, filter mediaprovider.AlbumFilter) mediaprovider.AlbumIterator {
+	return helpers.NewAlbumIterator(func(offset, limit int) ([]*mediaprovider.Album, error) {
+		d.mu.RLock()
+		defer d.mu.RUnlock()
+		var page []*mediaprovider.Album
+		for _, al := range pageOf(albums, offset, limit) {
+			page = append(page, copyAlbum(al))
+		}
+		return page, nil
+	}, filter, d.prefetchCB)
+}
+
+func (d *demoMediaProvider) trackIterator(tracks []*mediaprovider.Track) mediaprovider.TrackIterator {
+	return helpers.NewTrackIterator(func(offset, limit int) ([]*mediaprovider.Track, error) {
+		d.mu.RLock()
+		defer d.mu.RUnlock()
+		var page []*mediaprovider.Track
+		for _, tr := range pageOf(tracks, offset, limit) {
+			page = append(page, copyTrack(tr))
+		}
+		return page, nil
+	}, d.prefetchCB)
+}
+
+func (d *demoMediaProvider) artistIterator(artists []*mediaprovider.Artist, filter mediaprovider.ArtistFilter) mediaprovider.ArtistIterator {
+	return helpers.NewArtistIterator(func(offset, limit int) ([]*mediaprovider.Artist, error) {
+		d.mu.RLock()
+		defer d.mu.RUnlock()
+		var page []*mediaprovider.Artist
+		for _, ar := range pageOf(artists, offset, limit) {
+			a := *ar
+			page = append(page, &a)
+		}
+		return page, nil
+	}, filter, d.prefetchCB)
+}
+
+// artistGenre returns the genre of the artist's first album, or "" if none.
+// Must be called with d.mu held.
+func (d *demoMediaProvider) artistGenre(artistID string) string {
+	if ids := d.lib.artistAlbums[artistID]; len(ids) > 0 {
+		return firstGenre(d.lib.albumsByID[ids[0]])
+	}
+	return ""
+}
+
+// must be called with d.mu held
+func (d *demoMediaProvider) findPlaylist(id string) *mediaprovider.PlaylistWithTracks {
+	for _, pl := range d.lib.playlists {
+		if pl.ID == id {
+			return pl
+		}
+	}
+	return nil
+}
+
+// libraryTracks returns the library tracks with the given IDs,
+// skipping unknown IDs. Must be called with d.mu held.
+func (d *demoMediaProvider) libraryTracks(ids []string) []*mediaprovider.Track {
+	var tracks []*mediaprovider.Track
+	for _, id := range ids {
+		if tr, ok := d.lib.tracksByID[id]; ok {
+			tracks = append(tracks, tr)
+		}
+	}
+	return tracks
+}
+
+// tracksByIDs is like libraryTracks, but returns copies of the tracks.
+func (d *demoMediaProvider) tracksByIDs(ids []string) []*mediaprovider.Track {
+	tracks := d.libraryTracks(ids)
+	for i, tr := range tracks {
+		tracks[i] = copyTrack(tr)
+	}
+	return tracks
+}
+
+func trackIDs(tracks []*mediaprovider.Track) []string {
+	ids := make([]string, len(tracks))
+	for i, tr := range tracks {
+		ids[i] = tr.ID
+	}
+	return ids
+}
+
+func copyTrack(tr *mediaprovider.Track) *mediaprovider.Track {
+	return tr.Copy().(*mediaprovider.Track)
+}
+
+func firstGenre(al *mediaprovider.Album) string {
+	if len(al.Genres) == 0 {
+		return ""
+	}
+	return al.Genres[0]
+}
+
+func copyAlbum(al *mediaprovider.Album) *mediaprovider.Album {
+	a := *al
+	return &a
+}
+
+func pageOf[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	return items[offset:min(offset+limit, len(items))]
+}
+
+func compareNames(a, b string) int {
+	return strings.Compare(sanitized(a), sanitized(b))
+}
+
+func sanitized(s string) string {
+	return strings.ToLower(sanitize.Accents(s))
+}
+
+func searchTerms(query string) []string {
+	return strings.Fields(sanitized(query))
+}
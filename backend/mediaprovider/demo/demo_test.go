@@ -0,0 +1,100 @@
+package demo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+func TestLibraryIsDeterministic(t *testing.T) {
+	a, b := NewMediaProvider(42, 10), NewMediaProvider(42, 10)
+	iterA := a.IterateAlbums(AlbumSortRecentlyAdded, mediaprovider.NewAlbumFilter(mediaprovider.AlbumFilterOptions{}))
+	iterB := b.IterateAlbums(AlbumSortRecentlyAdded, mediaprovider.NewAlbumFilter(mediaprovider.AlbumFilterOptions{}))
+	n := 0
+	for alA, alB := iterA.Next(), iterB.Next(); alA != nil || alB != nil; alA, alB = iterA.Next(), iterB.Next() {
+		if alA == nil || alB == nil || alA.ID != alB.ID || alA.Name != alB.Name {
+			t.Fatalf("albums differ at %d: %v, %v", n, alA, alB)
+		}
+		n++
+	}
+	if n == 0 {
+		t.Fatal("no albums generated")
+	}
+
+	randA, _ := a.GetRandomTracks("", 5)
+	randB, _ := b.GetRandomTracks("", 5)
+	for i := range randA {
+		if randA[i].ID != randB[i].ID {
+			t.Errorf("random tracks differ at %d: %s, %s", i, randA[i].ID, randB[i].ID)
+		}
+	}
+}
+
+func TestSearchAll(t *testing.T) {
+	mp := NewMediaProvider(DefaultSeed, 10)
+	tr, err := mp.GetTrack("tr-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := mp.SearchAll(tr.Title, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Type == mediaprovider.ContentTypeTrack && r.ID == tr.ID {
+			return
+		}
+	}
+	t.Errorf("track %q not found in search results", tr.Title)
+}
+
+func TestScrobbleAndFavorite(t *testing.T) {
+	mp := NewMediaProvider(DefaultSeed, 10)
+	mp.TrackEndedPlayback("tr-1", 30, false)
+	mp.TrackEndedPlayback("tr-1", 200, true)
+	mp.SetFavorite(mediaprovider.RatingFavoriteParameters{TrackIDs: []string{"tr-1"}}, true)
+
+	tr, _ := mp.GetTrack("tr-1")
+	if tr.PlayCount != 1 {
+		t.Errorf("PlayCount = %d, want 1", tr.PlayCount)
+	}
+	if !tr.Favorite {
+		t.Error("track not favorited")
+	}
+	tr.PlayCount = 100 // returned tracks must be copies
+	if tr2, _ := mp.GetTrack("tr-1"); tr2.PlayCount != 1 {
+		t.Errorf("library track was modified through returned copy")
+	}
+}
+
+func TestFavoritesSort(t *testing.T) {
+	mp := NewMediaProvider(DefaultSeed, 10)
+	// favorited dates of the generated favorites aren't known
+	mp.SetFavorite(mediaprovider.RatingFavoriteParameters{TrackIDs: []string{"tr-2"}}, true)
+
+	fav, err := mp.GetFavorites(mediaprovider.FavoritesSortRecentlyFavorited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fav.Tracks) == 0 || fav.Tracks[0].ID != "tr-2" || fav.Tracks[0].FavoritedAt.IsZero() {
+		t.Errorf("newly favorited track not first with a favorited date")
+	}
+
+	fav, _ = mp.GetFavorites(mediaprovider.FavoritesSortAlphabetical)
+	for i := 1; i < len(fav.Artists); i++ {
+		if strings.ToLower(fav.Artists[i].SortKey()) < strings.ToLower(fav.Artists[i-1].SortKey()) {
+			t.Fatalf("artists not sorted: %q before %q", fav.Artists[i-1].SortKey(), fav.Artists[i].SortKey())
+		}
+	}
+
+	iter := mp.(mediaprovider.SupportsFavoriteTrackIterator).IterateFavoriteTracks(mediaprovider.FavoritesSortRecentlyFavorited)
+	if tr := iter.Next(); tr == nil || tr.ID != "tr-2" {
+		t.Errorf("iterator did not return newly favorited track first")
+	}
+
+	mp.SetFavorite(mediaprovider.RatingFavoriteParameters{TrackIDs: []string{"tr-2"}}, false)
+	if tr, _ := mp.GetTrack("tr-2"); !tr.FavoritedAt.IsZero() {
+		t.Error("favorited date not cleared")
+	}
+}
@@ -0,0 +1,225 @@
+package demo
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"io"
+	"math/rand"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+const unknownArtist = "Unknown Artist"
+
+// LibraryFiles gives access to the audio files of a library served by
+// a provider created with NewLibraryProvider.
+type LibraryFiles interface {
+	// Scan indexes the files, returning all the tracks of the library.
+	// It is called in the background by RescanLibrary.
+	Scan() ([]*LibraryTrack, error)
+
+	// StreamURL returns a URL of the track's file which mpv can play.
+	StreamURL(track *mediaprovider.Track) (string, error)
+
+	// StreamHeaders returns the HTTP headers, e.g. credentials,
+	// to request the track's StreamURL with, or nil.
+	StreamHeaders(track *mediaprovider.Track) http.Header
+
+	// Open returns a reader of the track's file, for downloading it.
+	Open(track *mediaprovider.Track) (io.ReadCloser, error)
+
+	// CoverArt returns the image with the ID set as the CoverArtID
+	// of a LibraryTrack, or an error if not found.
+	CoverArt(coverArtID string) (image.Image, error)
+}
+
+// LibraryTrack is a track of a library of real files. The provider
+// groups tracks into albums and artists by their tags, and sets the
+// track's album and artist IDs accordingly. The Track's ID must be
+// stable across scans, e.g. derived from the file path.
+type LibraryTrack struct {
+	*mediaprovider.Track
+	// the album artist tag, if different from the first track artist
+	AlbumArtist   string
+	IsCompilation bool
+}
+
+// NewLibraryProvider returns an in-memory MediaProvider serving the given
+// tracks, e.g. from a local index of the files. RescanLibrary replaces them
+// with the result of files.Scan. Playlists, favorites and ratings are kept
+// in memory only.
+func NewLibraryProvider(tracks []*LibraryTrack, files LibraryFiles) mediaprovider.MediaProvider {
+	return &demoMediaProvider{
+		lib:        buildLibrary(tracks),
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		prefetchCB: func(string) {},
+		nextPlID:   1,
+		files:      files,
+	}
+}
+
+// buildLibrary groups the tracks into albums, by album artist and album
+// name, and artists. Albums are ordered newest first by date added.
+func buildLibrary(tracks []*LibraryTrack) *library {
+	l := newLibrary()
+	artistID := func(name string) string {
+		id := itemID("ar-", strings.ToLower(name))
+		if _, ok := l.artistsByID[id]; !ok {
+			ar := &mediaprovider.Artist{ID: id, Name: name}
+			l.artistsByID[id] = ar
+			l.artists = append(l.artists, ar)
+		}
+		return id
+	}
+
+	for _, lt := range tracks {
+		tr := lt.Track
+		if len(tr.ArtistNames) == 0 {
+			tr.ArtistNames = []string{unknownArtist}
+		}
+		tr.ArtistIDs = make([]string, len(tr.ArtistNames))
+		for i, name := range tr.ArtistNames {
+			tr.ArtistIDs[i] = artistID(name)
+		}
+		albumArtist := lt.AlbumArtist
+		if albumArtist == "" {
+			albumArtist = tr.ArtistNames[0]
+		}
+
+		albumID := itemID("al-", strings.ToLower(albumArtist+"\x00"+tr.Album))
+		al, ok := l.albumsByID[albumID]
+		if !ok {
+			al = &mediaprovider.Album{
+				ID:            albumID,
+				CoverArtID:    tr.CoverArtID,
+				Name:          tr.Album,
+				ArtistIDs:     []string{artistID(albumArtist)},
+				ArtistNames:   []string{albumArtist},
+				Year:          tr.Year,
+				ReleaseDate:   mediaprovider.ItemDate{Year: tr.Year},
+				ReleaseTypes:  mediaprovider.ReleaseTypeAlbum,
+				IsCompilation: lt.IsCompilation,
+			}
+			if al.IsCompilation {
+				al.ReleaseTypes |= mediaprovider.ReleaseTypeCompilation
+			}
+			l.albumsByID[albumID] = al
+			l.albums = append(l.albums, al)
+		}
+		tr.AlbumID, tr.ParentID = albumID, albumID
+		if tr.CoverArtID == "" {
+			tr.CoverArtID = al.CoverArtID
+		} else if al.CoverArtID == "" {
+			al.CoverArtID = tr.CoverArtID
+		}
+		al.Duration += tr.Duration
+		al.TrackCount++
+		al.Explicit = al.Explicit || tr.Explicit
+		for _, g := range tr.Genres {
+			if !containsFold(al.Genres, g) {
+				al.Genres = append(al.Genres, g)
+			}
+		}
+		if tr.CreatedAt.After(al.CreatedAt) {
+			al.CreatedAt = tr.CreatedAt
+		}
+		l.tracks = append(l.tracks, tr)
+		l.tracksByID[tr.ID] = tr
+		l.albumTracks[albumID] = append(l.albumTracks[albumID], tr.ID)
+	}
+
+	for _, al := range l.albums {
+		ids := l.albumTracks[al.ID]
+		sort.SliceStable(ids, func(i, j int) bool {
+			a, b := l.tracksByID[ids[i]], l.tracksByID[ids[j]]
+			if a.DiscNumber != b.DiscNumber {
+				return a.DiscNumber < b.DiscNumber
+			}
+			return a.TrackNumber < b.TrackNumber
+		})
+		// an artist's albums are those they appear on at all,
+		// so that every artist has at least one album
+		artistIDs := make(map[string]bool)
+		for _, id := range append(slices.Clone(al.ArtistIDs), trackArtistIDs(l, ids)...) {
+			if !artistIDs[id] {
+				artistIDs[id] = true
+				l.artistAlbums[id] = append(l.artistAlbums[id], al.ID)
+				l.artistsByID[id].AlbumCount++
+			}
+		}
+	}
+	for _, ar := range l.artists {
+		if ids := l.artistAlbums[ar.ID]; len(ids) > 0 {
+			ar.CoverArtID = l.albumsByID[ids[0]].CoverArtID
+		}
+	}
+	sort.SliceStable(l.albums, func(i, j int) bool {
+		return l.albums[i].CreatedAt.After(l.albums[j].CreatedAt)
+	})
+	return l
+}
+
+// setLibrary replaces the library with a rescanned one, keeping the
+// playlists and the user data (favorites, ratings, play counts)
+// of the items which are still present.
+func (d *demoMediaProvider) setLibrary(lib *library) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	old := d.lib
+	for _, tr := range lib.tracks {
+		if o, ok := old.tracksByID[tr.ID]; ok {
+			tr.Favorite, tr.Rating, tr.PlayCount, tr.LastPlayed = o.Favorite, o.Rating, o.PlayCount, o.LastPlayed
+			tr.FavoritedAt = o.FavoritedAt
+		}
+	}
+	for _, al := range lib.albums {
+		if o, ok := old.albumsByID[al.ID]; ok {
+			al.Favorite, al.FavoritedAt = o.Favorite, o.FavoritedAt
+		}
+	}
+	for _, ar := range lib.artists {
+		if o, ok := old.artistsByID[ar.ID]; ok {
+			ar.Favorite, ar.FavoritedAt = o.Favorite, o.FavoritedAt
+		}
+	}
+	for _, pl := range old.playlists {
+		pl.Tracks = sharedutil.FilterMapSlice(pl.Tracks, func(tr *mediaprovider.Track) (*mediaprovider.Track, bool) {
+			t, ok := lib.tracksByID[tr.ID]
+			return t, ok
+		})
+		updatePlaylistStats(pl)
+	}
+	lib.playlists = old.playlists
+	d.lib = lib
+}
+
+func trackArtistIDs(l *library, trackIDs []string) []string {
+	var ids []string
+	for _, id := range trackIDs {
+		ids = append(ids, l.tracksByID[id].ArtistIDs...)
+	}
+	return ids
+}
+
+// itemID returns a short stable ID derived from the key.
+func itemID(prefix, key string) string {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return fmt.Sprintf("%s%x", prefix, h.Sum64())
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
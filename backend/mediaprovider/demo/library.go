@@ -0,0 +1,196 @@
+package demo
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+var (
+	nameAdjectives = []string{
+		"Amber", "Broken", "Crimson", "Distant", "Electric", "Fading", "Golden", "Hollow",
+		"Indigo", "Jagged", "Lunar", "Midnight", "Neon", "Paper", "Quiet", "Restless",
+		"Silver", "Tidal", "Velvet", "Wandering", "Wild", "Young",
+	}
+	nameNouns = []string{
+		"Anchors", "Beacons", "Cities", "Coast", "Echoes", "Embers", "Fields", "Harbor",
+		"Horizon", "Lanterns", "Machines", "Meadows", "Mirrors", "Oceans", "Rivers", "Satellites",
+		"Shadows", "Signals", "Skylines", "Streets", "Thunder", "Wolves",
+	}
+	titleWords = []string{
+		"After", "All", "Before", "Blue", "Burning", "Call", "Dance", "Dawn", "Dream", "Falling",
+		"Fire", "Ghost", "Gravity", "Heart", "Home", "Light", "Lost", "Morning", "Night", "Ocean",
+		"Rain", "Run", "Slow", "Stars", "Summer", "Tonight", "Under", "Waves", "Winter", "You",
+	}
+	genreNames = []string{
+		"Ambient", "Blues", "Electronic", "Folk", "Hip-Hop", "Jazz", "Metal", "Pop", "Rock", "Soul",
+	}
+)
+
+// library is the generated content of a demo server.
+type library struct {
+	artists   []*mediaprovider.Artist
+	albums    []*mediaprovider.Album // in order added to the library, newest first
+	tracks    []*mediaprovider.Track
+	playlists []*mediaprovider.PlaylistWithTracks
+
+	artistsByID map[string]*mediaprovider.Artist
+	albumsByID  map[string]*mediaprovider.Album
+	tracksByID  map[string]*mediaprovider.Track
+	// track IDs of each album, in album order
+	albumTracks map[string][]string
+	// album IDs of each artist
+	artistAlbums map[string][]string
+}
+
+func newLibrary() *library {
+	return &library{
+		artistsByID:  make(map[string]*mediaprovider.Artist),
+		albumsByID:   make(map[string]*mediaprovider.Album),
+		tracksByID:   make(map[string]*mediaprovider.Track),
+		albumTracks:  make(map[string][]string),
+		artistAlbums: make(map[string][]string),
+	}
+}
+
+// generateLibrary generates a library of the given number of artists.
+// The same seed always generates the same library.
+func generateLibrary(seed int64, numArtists int) *library {
+	r := rand.New(rand.NewSource(seed))
+	l := newLibrary()
+
+	usedNames := make(map[string]bool)
+	uniqueName := func(gen func() string) string {
+		for {
+			if name := gen(); !usedNames[name] {
+				usedNames[name] = true
+				return name
+			}
+		}
+	}
+
+	for i := 0; i < numArtists; i++ {
+		name := uniqueName(func() string {
+			if r.Intn(3) == 0 {
+				return pick(r, nameAdjectives) + " " + pick(r, nameNouns)
+			}
+			return "The " + pick(r, nameAdjectives) + " " + pick(r, nameNouns)
+		})
+		artist := &mediaprovider.Artist{
+			ID:       fmt.Sprintf("ar-%d", i+1),
+			Name:     name,
+			Favorite: r.Intn(8) == 0,
+		}
+		artist.CoverArtID = artist.ID
+		if strings.HasPrefix(name, "The ") {
+			artist.SortName = strings.TrimPrefix(name, "The ") + ", The"
+		}
+		genre := pick(r, genreNames)
+		firstYear := 1965 + r.Intn(50)
+
+		numAlbums := 1 + r.Intn(4)
+		for j := 0; j < numAlbums; j++ {
+			album := &mediaprovider.Album{
+				ID:          fmt.Sprintf("al-%d", len(l.albums)+1),
+				Name:        uniqueName(func() string { return randomTitle(r) }),
+				ArtistIDs:   []string{artist.ID},
+				ArtistNames: []string{artist.Name},
+				Year:        min(firstYear+j*(1+r.Intn(4)), 2024),
+				Genres:      []string{genre},
+				Favorite:    r.Intn(6) == 0,
+			}
+			album.CoverArtID = album.ID
+			album.ReleaseDate = mediaprovider.ItemDate{Year: album.Year, Month: 1 + r.Intn(12), Day: 1 + r.Intn(28)}
+			if r.Intn(4) == 0 {
+				album.ReleaseTypes = mediaprovider.ReleaseTypeEP
+			} else {
+				album.ReleaseTypes = mediaprovider.ReleaseTypeAlbum
+			}
+
+			numTracks := 4 + r.Intn(9)
+			if album.ReleaseTypes == mediaprovider.ReleaseTypeEP {
+				numTracks = 3 + r.Intn(3)
+			}
+			for k := 0; k < numTracks; k++ {
+				duration := 120 + r.Intn(300)
+				track := &mediaprovider.Track{
+					ID:          fmt.Sprintf("tr-%d", len(l.tracks)+1),
+					CoverArtID:  album.CoverArtID,
+					ParentID:    album.ID,
+					Title:       randomTitle(r),
+					Duration:    duration,
+					TrackNumber: k + 1,
+					DiscNumber:  1,
+					Genres:      album.Genres,
+					ArtistIDs:   album.ArtistIDs,
+					ArtistNames: album.ArtistNames,
+					Album:       album.Name,
+					AlbumID:     album.ID,
+					Year:        album.Year,
+					Favorite:    r.Intn(10) == 0,
+					BitRate:     320,
+					Size:        int64(duration) * 320 * 1000 / 8,
+				}
+				if r.Intn(3) == 0 {
+					track.Rating = 1 + r.Intn(5)
+				}
+				track.FilePath = fmt.Sprintf("%s/%s/%02d %s.mp3", artist.Name, album.Name, track.TrackNumber, track.Title)
+				album.Duration += duration
+				album.TrackCount++
+				l.tracks = append(l.tracks, track)
+				l.tracksByID[track.ID] = track
+				l.albumTracks[album.ID] = append(l.albumTracks[album.ID], track.ID)
+			}
+
+			artist.AlbumCount++
+			l.albums = append(l.albums, album)
+			l.albumsByID[album.ID] = album
+			l.artistAlbums[artist.ID] = append(l.artistAlbums[artist.ID], album.ID)
+		}
+		l.artists = append(l.artists, artist)
+		l.artistsByID[artist.ID] = artist
+	}
+	r.Shuffle(len(l.albums), func(i, j int) { l.albums[i], l.albums[j] = l.albums[j], l.albums[i] })
+
+	for i, name := range []string{"Favorites Mix", "Road Trip", "Late Night"} {
+		pl := &mediaprovider.PlaylistWithTracks{Playlist: mediaprovider.Playlist{
+			ID:     fmt.Sprintf("pl-%d", i+1),
+			Name:   name,
+			Owner:  Username,
+			Public: i == 0,
+		}}
+		for _, idx := range r.Perm(len(l.tracks))[:min(15+r.Intn(15), len(l.tracks))] {
+			pl.Tracks = append(pl.Tracks, l.tracks[idx])
+		}
+		updatePlaylistStats(pl)
+		l.playlists = append(l.playlists, pl)
+	}
+	return l
+}
+
+func pick(r *rand.Rand, words []string) string {
+	return words[r.Intn(len(words))]
+}
+
+func randomTitle(r *rand.Rand) string {
+	words := make([]string, 1+r.Intn(3))
+	for i := range words {
+		words[i] = pick(r, titleWords)
+	}
+	return strings.Join(words, " ")
+}
+
+func updatePlaylistStats(pl *mediaprovider.PlaylistWithTracks) {
+	pl.TrackCount = len(pl.Tracks)
+	pl.Duration = 0
+	for _, tr := range pl.Tracks {
+		pl.Duration += tr.Duration
+	}
+	if len(pl.Tracks) > 0 {
+		pl.CoverArtID = pl.Tracks[0].CoverArtID
+	} else {
+		pl.CoverArtID = ""
+	}
+}
@@ -0,0 +1,59 @@
+package helpers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// ForEachConcurrently calls op for each ID, with at most concurrency calls
+// in flight at once, for servers which don't support operating on many
+// items in one request. onProgress (if non-nil) is called as items are done,
+// serialized with other calls to it. Returns ctx.Err() if canceled,
+// or an *mediaprovider.ItemsError if some items failed.
+func ForEachConcurrently(ctx context.Context, ids []string, concurrency int, op func(id string) error, onProgress func(done, total int)) error {
+	idCh := make(chan string)
+	go func() {
+		defer close(idCh)
+		for _, id := range ids {
+			select {
+			case <-ctx.Done():
+				return
+			case idCh <- id:
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	failed := make(map[string]error)
+	done := 0
+	var wg sync.WaitGroup
+	for i := 0; i < min(concurrency, len(ids)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range idCh {
+				err := op(id)
+				mu.Lock()
+				if err != nil {
+					failed[id] = err
+				}
+				done++
+				if onProgress != nil {
+					onProgress(done, len(ids))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return &mediaprovider.ItemsError{Errors: failed}
+	}
+	return nil
+}
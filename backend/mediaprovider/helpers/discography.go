@@ -0,0 +1,45 @@
+package helpers
+
+import (
+	"slices"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// GetArtistDiscography returns the artist's albums grouped by kind of release.
+// Albums are "appears on" if the artist is not one of their album artists.
+func GetArtistDiscography(mp mediaprovider.MediaProvider, artistID string) (*mediaprovider.Discography, error) {
+	artist, err := mp.GetArtist(artistID)
+	if err != nil {
+		return nil, err
+	}
+	albums := artist.Albums
+	if ao, ok := mediaprovider.As[mediaprovider.SupportsArtistAppearsOn](mp); ok {
+		appearsOn, err := ao.GetArtistAppearsOn(artistID)
+		if err != nil {
+			return nil, err
+		}
+		albums = append(slices.Clip(albums), appearsOn...)
+	}
+
+	d := &mediaprovider.Discography{}
+	seen := make(map[string]bool, len(albums))
+	for _, al := range albums {
+		if seen[al.ID] {
+			continue
+		}
+		seen[al.ID] = true
+		switch {
+		case !slices.Contains(al.ArtistIDs, artistID):
+			d.AppearsOn = append(d.AppearsOn, al)
+		case al.IsCompilation || al.ReleaseTypes&mediaprovider.ReleaseTypeCompilation != 0:
+			d.Compilations = append(d.Compilations, al)
+		case al.ReleaseTypes&(mediaprovider.ReleaseTypeEP|mediaprovider.ReleaseTypeSingle) != 0 &&
+			al.ReleaseTypes&mediaprovider.ReleaseTypeAlbum == 0:
+			d.EPsAndSingles = append(d.EPsAndSingles, al)
+		default:
+			d.Albums = append(d.Albums, al)
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,112 @@
+package helpers
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/deluan/sanitize"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// max number of album search results to consider when finding editions
+const editionsSearchLimit = 100
+
+// matches trailing edition descriptors like " (Deluxe Edition)", " [2011 Remaster]", " - Expanded Version"
+var editionSuffixRegex = regexp.MustCompile(`(?i)(\s*[(\[][^)\]]*(edition|remaster|deluxe|expanded|anniversary|version|bonus|reissue|special|collector|limited|mono|stereo)[^)\]]*[)\]]|\s+-\s+[^-]*(edition|remaster|deluxe|expanded|anniversary|version)[^-]*)+$`)
+
+// EditionGroupKey returns the key identifying the group of editions
+// (e.g. the original release, a remaster, and a deluxe edition)
+// which the album belongs to: its MusicBrainz release group, if known,
+// or else its normalized first artist and title without edition descriptors.
+func EditionGroupKey(al *mediaprovider.Album) string {
+	if al.MusicBrainzReleaseGroupID != "" {
+		return "mbrg:" + al.MusicBrainzReleaseGroupID
+	}
+	var artist string
+	if len(al.ArtistNames) > 0 {
+		artist = strings.ToLower(sanitize.Accents(al.ArtistNames[0]))
+	}
+	return artist + "\x00" + normalizeAlbumTitle(al.Name)
+}
+
+// EditionName returns the edition descriptors of the album's title,
+// e.g. "Deluxe Edition" for "Abbey Road (Deluxe Edition)", or "" if none.
+// Multiple descriptors are joined with ", ".
+func EditionName(al *mediaprovider.Album) string {
+	suffix := editionSuffixRegex.FindString(al.Name)
+	var parts []string
+	for _, p := range strings.FieldsFunc(suffix, func(r rune) bool { return strings.ContainsRune("()[]", r) }) {
+		if p = strings.Trim(p, "- "); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+type groupEditionsIter struct {
+	iter mediaprovider.AlbumIterator
+	seen map[string]struct{}
+}
+
+// NewGroupEditionsIterator wraps an album iterator so that multiple editions
+// of the same album are presented as one entry: the first edition
+// encountered is returned, and subsequent ones are skipped.
+// Use FindEditions to list all the editions of a returned album.
+func NewGroupEditionsIterator(iter mediaprovider.AlbumIterator) mediaprovider.AlbumIterator {
+	return &groupEditionsIter{iter: iter, seen: make(map[string]struct{})}
+}
+
+func (g *groupEditionsIter) Next() *mediaprovider.Album {
+	for {
+		al := g.iter.Next()
+		if al == nil {
+			return nil
+		}
+		key := EditionGroupKey(al)
+		if _, ok := g.seen[key]; ok {
+			continue
+		}
+		g.seen[key] = struct{}{}
+		return al
+	}
+}
+
+// FindEditions searches the library for the editions of the given album,
+// including the album itself, sorted by release date.
+func FindEditions(mp mediaprovider.MediaProvider, album *mediaprovider.Album) ([]*mediaprovider.Album, error) {
+	key := EditionGroupKey(album)
+	title := strings.TrimSpace(editionSuffixRegex.ReplaceAllString(album.Name, ""))
+	if title == "" {
+		title = album.Name
+	}
+
+	iter := mp.SearchAlbums(title, mediaprovider.NewAlbumFilter(mediaprovider.AlbumFilterOptions{}))
+	editions := []*mediaprovider.Album{album}
+	for i := 0; i < editionsSearchLimit; i++ {
+		al := iter.Next()
+		if al == nil {
+			break
+		}
+		if al.ID != album.ID && EditionGroupKey(al) == key {
+			editions = append(editions, al)
+		}
+	}
+	slices.SortStableFunc(editions, func(a, b *mediaprovider.Album) int {
+		return editionReleaseDate(a).Compare(editionReleaseDate(b))
+	})
+	return editions, nil
+}
+
+// editionReleaseDate returns the date the edition itself was released
+func editionReleaseDate(al *mediaprovider.Album) mediaprovider.ItemDate {
+	if !al.ReleaseDate.IsZero() {
+		return al.ReleaseDate
+	}
+	return mediaprovider.ItemDate{Year: max(al.ReissueYear, al.Year)}
+}
+
+func normalizeAlbumTitle(title string) string {
+	title = strings.ToLower(sanitize.Accents(title))
+	return strings.TrimSpace(editionSuffixRegex.ReplaceAllString(title, ""))
+}
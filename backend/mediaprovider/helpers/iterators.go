@@ -7,6 +7,10 @@ import (
 	"github.com/dweymouth/supersonic/sharedutil"
 )
 
+// IteratorPageSize is the number of items requested from the server
+// at a time by the paginated iterators.
+var IteratorPageSize = 20
+
 type baseIter[M, F any] struct {
 	filter        mediaprovider.MediaFilter[M, F]
 	prefetchCB    func(*M)
@@ -58,7 +62,7 @@ func (r *baseIter[M, F]) Next() *M {
 	}
 	r.prefetched = nil
 	for { // keep fetching until we are done or have matching results
-		items, err := r.fetcher(r.serverPos, 20)
+		items, err := r.fetcher(r.serverPos, IteratorPageSize)
 		if err != nil {
 			log.Printf("error fetching items: %s", err.Error())
 			items = nil
@@ -0,0 +1,87 @@
+package helpers
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/deluan/sanitize"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+const (
+	// max number of track search results to consider
+	otherReleasesSearchLimit = 200
+	// max difference in track duration to be considered the same recording
+	otherReleasesMaxDurationDiffSecs = 5
+)
+
+// matches trailing version descriptors like " (Remastered 2011)", " [Live]", " - 2009 Remaster"
+var titleSuffixRegex = regexp.MustCompile(`(\s*[(\[][^)\]]*[)\]]|\s+-\s+[^-]*remaster[^-]*)+$`)
+
+// FindOtherReleases searches the library for other albums containing the same
+// recording as the given track, matching on MusicBrainz recording ID if known,
+// or else using a title + artist + duration heuristic.
+func FindOtherReleases(mp mediaprovider.MediaProvider, track *mediaprovider.Track) ([]*mediaprovider.Album, error) {
+	title := normalizeTrackTitle(track.Title)
+	if title == "" {
+		return nil, nil
+	}
+
+	iter := mp.IterateTracks(track.Title)
+	seenAlbums := map[string]struct{}{track.AlbumID: {}}
+	var albums []*mediaprovider.Album
+	for i := 0; i < otherReleasesSearchLimit; i++ {
+		tr := iter.Next()
+		if tr == nil {
+			break
+		}
+		if _, ok := seenAlbums[tr.AlbumID]; ok || tr.AlbumID == "" {
+			continue
+		}
+		if !IsSameRecording(track, tr) {
+			continue
+		}
+		seenAlbums[tr.AlbumID] = struct{}{}
+		albums = append(albums, &mediaprovider.Album{
+			ID:          tr.AlbumID,
+			CoverArtID:  tr.CoverArtID,
+			Name:        tr.Album,
+			ArtistIDs:   tr.ArtistIDs,
+			ArtistNames: tr.ArtistNames,
+			Year:        tr.Year,
+		})
+	}
+	return albums, nil
+}
+
+// IsSameRecording returns true if the two tracks are likely the same recording.
+// If both tracks have a MusicBrainz recording ID, they must match. Otherwise,
+// the tracks must have matching titles (ignoring version suffixes such as "(Remastered)"),
+// at least one artist in common, and approximately the same duration.
+func IsSameRecording(a, b *mediaprovider.Track) bool {
+	if a.MusicBrainzRecordingID != "" && b.MusicBrainzRecordingID != "" {
+		return strings.EqualFold(a.MusicBrainzRecordingID, b.MusicBrainzRecordingID)
+	}
+	if normalizeTrackTitle(a.Title) != normalizeTrackTitle(b.Title) {
+		return false
+	}
+	if a.Duration > 0 && b.Duration > 0 {
+		diff := a.Duration - b.Duration
+		if diff < -otherReleasesMaxDurationDiffSecs || diff > otherReleasesMaxDurationDiffSecs {
+			return false
+		}
+	}
+	for _, artistA := range a.ArtistNames {
+		for _, artistB := range b.ArtistNames {
+			if strings.EqualFold(sanitize.Accents(artistA), sanitize.Accents(artistB)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func normalizeTrackTitle(title string) string {
+	title = strings.ToLower(sanitize.Accents(title))
+	return strings.TrimSpace(titleSuffixRegex.ReplaceAllString(title, ""))
+}
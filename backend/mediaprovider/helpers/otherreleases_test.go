@@ -0,0 +1,37 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+func TestIsSameRecording(t *testing.T) {
+	const mbid = "5b11f4ce-a62d-471e-81fc-a69a8278c7da"
+	track := &mediaprovider.Track{Title: "Song", ArtistNames: []string{"Artist"}, Duration: 200}
+	withMBID := func(tr mediaprovider.Track, id string) *mediaprovider.Track {
+		tr.MusicBrainzRecordingID = id
+		return &tr
+	}
+
+	for _, tt := range []struct {
+		name string
+		a, b *mediaprovider.Track
+		want bool
+	}{
+		{"same MBID", withMBID(*track, mbid), &mediaprovider.Track{Title: "Song (Single Edit)", Duration: 180, MusicBrainzRecordingID: mbid}, true},
+		{"different MBID", withMBID(*track, mbid), withMBID(*track, "d8f4c2b0-5a2e-4b3c-9a77-1b0f0c1e2d3f"), false},
+		{"one MBID missing", withMBID(*track, mbid), track, true},
+		{"remaster suffix", track, &mediaprovider.Track{Title: "Song - 2011 Remaster", ArtistNames: []string{"ARTIST"}, Duration: 203}, true},
+		{"bracketed suffix", track, &mediaprovider.Track{Title: "Song [Mono]", ArtistNames: []string{"Artist"}}, true},
+		{"different duration", track, &mediaprovider.Track{Title: "Song", ArtistNames: []string{"Artist"}, Duration: 260}, false},
+		{"different artist", track, &mediaprovider.Track{Title: "Song", ArtistNames: []string{"Other"}, Duration: 200}, false},
+		{"different title", track, &mediaprovider.Track{Title: "Other Song", ArtistNames: []string{"Artist"}, Duration: 200}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsSameRecording(tt.a, tt.b); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// ReplacePlaylistTracks replaces the tracks of a playlist currently holding
+// oldTrackIDs with trackIDs, for servers which can only add and remove tracks.
+// The old tracks are removed before the new ones are added, since some servers
+// (e.g. Jellyfin 10.9+) skip adding tracks which are already in the playlist.
+// If adding fails, the old tracks are put back.
+func ReplacePlaylistTracks(mp mediaprovider.MediaProvider, playlistID string, oldTrackIDs, trackIDs []string) error {
+	oldIdxs := make([]int, len(oldTrackIDs))
+	for i := range oldIdxs {
+		oldIdxs[i] = i
+	}
+	if len(oldIdxs) > 0 {
+		if err := mp.RemovePlaylistTracks(playlistID, oldIdxs); err != nil {
+			return err
+		}
+	}
+	if len(trackIDs) == 0 {
+		return nil
+	}
+	if err := mp.AddPlaylistTracks(playlistID, trackIDs); err != nil {
+		if len(oldTrackIDs) > 0 {
+			if rerr := mp.AddPlaylistTracks(playlistID, oldTrackIDs); rerr != nil {
+				return fmt.Errorf("%w (restoring old tracks failed: %v)", err, rerr)
+			}
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package helpers
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// playlistProvider holds the track IDs of a single playlist.
+type playlistProvider struct {
+	mediaprovider.MediaProvider
+	tracks []string
+	// skip adding tracks which are already in the playlist, like Jellyfin 10.9+
+	skipsDuplicates bool
+	// number of AddPlaylistTracks calls which fail
+	failAdds int
+}
+
+func (p *playlistProvider) AddPlaylistTracks(_ string, trackIDs []string) error {
+	if p.failAdds > 0 {
+		p.failAdds--
+		return errors.New("add failed")
+	}
+	for _, id := range trackIDs {
+		if !p.skipsDuplicates || !slices.Contains(p.tracks, id) {
+			p.tracks = append(p.tracks, id)
+		}
+	}
+	return nil
+}
+
+func (p *playlistProvider) RemovePlaylistTracks(_ string, idxs []int) error {
+	var kept []string
+	for i, id := range p.tracks {
+		if !slices.Contains(idxs, i) {
+			kept = append(kept, id)
+		}
+	}
+	p.tracks = kept
+	return nil
+}
+
+func TestReplacePlaylistTracks(t *testing.T) {
+	old := []string{"tr-1", "tr-2", "tr-3"}
+	for _, tt := range []struct {
+		name     string
+		provider *playlistProvider
+		tracks   []string
+		want     []string
+		wantErr  string
+	}{
+		{
+			name:     "overlapping tracks",
+			provider: &playlistProvider{skipsDuplicates: true},
+			tracks:   []string{"tr-3", "tr-4", "tr-1"},
+			want:     []string{"tr-3", "tr-4", "tr-1"},
+		},
+		{
+			name:     "clear",
+			provider: &playlistProvider{},
+			want:     nil,
+		},
+		{
+			name:     "add fails",
+			provider: &playlistProvider{failAdds: 1},
+			tracks:   []string{"tr-4"},
+			want:     old,
+			wantErr:  "add failed",
+		},
+		{
+			name:     "restore fails",
+			provider: &playlistProvider{failAdds: 2},
+			tracks:   []string{"tr-4"},
+			want:     nil,
+			wantErr:  "add failed (restoring old tracks failed: add failed)",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.provider.tracks = slices.Clone(old)
+			err := ReplacePlaylistTracks(tt.provider, "pl-1", old, tt.tracks)
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+			if !slices.Equal(tt.provider.tracks, tt.want) {
+				t.Errorf("playlist tracks = %v, want %v", tt.provider.tracks, tt.want)
+			}
+		})
+	}
+}
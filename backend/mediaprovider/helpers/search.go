@@ -65,3 +65,33 @@ func RankSearchResults(results []*mediaprovider.SearchResult, fullQuery string,
 		return a.Type < b.Type
 	})
 }
+
+// LimitSearchResults returns the results of the content types included
+// in the options, up to the limit of each type, keeping their order.
+func LimitSearchResults(results []*mediaprovider.SearchResult, opts mediaprovider.SearchOptions) []*mediaprovider.SearchResult {
+	counts := make(map[mediaprovider.ContentType]int)
+	limited := make([]*mediaprovider.SearchResult, 0, len(results))
+	for _, r := range results {
+		if counts[r.Type] < opts.Limits[r.Type] {
+			counts[r.Type]++
+			limited = append(limited, r)
+		}
+	}
+	return limited
+}
+
+// SearchWithOptions searches the provider with the options. If the provider
+// doesn't implement mediaprovider.SupportsSearchOptions, the results of
+// SearchAll are filtered to the options instead.
+func SearchWithOptions(mp mediaprovider.MediaProvider, searchQuery string, opts mediaprovider.SearchOptions) ([]*mediaprovider.SearchResult, error) {
+	if so, ok := mediaprovider.As[mediaprovider.SupportsSearchOptions](mp); ok {
+		return so.SearchWithOptions(searchQuery, opts)
+	}
+	// SearchAll splits maxResults between artists, albums and tracks
+	maxLimit := 0
+	for _, limit := range opts.Limits {
+		maxLimit = max(maxLimit, limit)
+	}
+	results, err := mp.SearchAll(searchQuery, 3*maxLimit)
+	return LimitSearchResults(results, opts), err
+}
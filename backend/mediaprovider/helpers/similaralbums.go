@@ -0,0 +1,53 @@
+package helpers
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// max number of albums by any one artist picked by PickSimilarAlbums
+const similarAlbumsMaxPerArtist = 2
+
+// PickSimilarAlbums approximates the albums most similar to album for servers
+// with no similar albums API, from the albums by similar artists (in order of
+// artist similarity) and the albums in the same genre. Albums by similar artists
+// which share a genre rank first, then other albums in the genre, then the rest
+// of the albums by similar artists. Albums by the album's own artists are excluded.
+func PickSimilarAlbums(album *mediaprovider.Album, bySimilarArtists, byGenre []*mediaprovider.Album, limit int) []*mediaprovider.Album {
+	sharesGenre := func(al *mediaprovider.Album) bool {
+		return slices.ContainsFunc(al.Genres, func(g string) bool {
+			return slices.ContainsFunc(album.Genres, func(g2 string) bool { return strings.EqualFold(g, g2) })
+		})
+	}
+
+	picked := make([]*mediaprovider.Album, 0, limit)
+	seen := map[string]bool{album.ID: true}
+	perArtist := make(map[string]int)
+	pick := func(candidates []*mediaprovider.Album, include func(*mediaprovider.Album) bool) {
+		for _, al := range candidates {
+			if len(picked) == limit {
+				return
+			}
+			if seen[al.ID] || !include(al) ||
+				slices.ContainsFunc(al.ArtistIDs, func(id string) bool { return slices.Contains(album.ArtistIDs, id) }) {
+				continue
+			}
+			var artistKey string
+			if len(al.ArtistIDs) > 0 {
+				artistKey = al.ArtistIDs[0]
+			}
+			if perArtist[artistKey] == similarAlbumsMaxPerArtist {
+				continue
+			}
+			perArtist[artistKey]++
+			seen[al.ID] = true
+			picked = append(picked, al)
+		}
+	}
+	pick(bySimilarArtists, sharesGenre)
+	pick(byGenre, func(*mediaprovider.Album) bool { return true })
+	pick(bySimilarArtists, func(*mediaprovider.Album) bool { return true })
+	return picked
+}
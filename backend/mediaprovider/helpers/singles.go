@@ -0,0 +1,239 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+const singlesAlbumIDPrefix = "singles:"
+
+// SinglesAlbumName is the name of the virtual album that collects
+// all single-track albums (loose tracks) of an artist.
+const SinglesAlbumName = "Singles"
+
+type collapseSinglesIter struct {
+	iter       mediaprovider.AlbumIterator
+	seenArtist map[string]struct{}
+}
+
+// NewCollapseSinglesIterator wraps an album iterator so that albums containing
+// a single track are collapsed into one virtual "Singles" album per artist.
+// The virtual album is returned in place of the first single-track album
+// encountered for that artist; subsequent ones are skipped.
+func NewCollapseSinglesIterator(iter mediaprovider.AlbumIterator) mediaprovider.AlbumIterator {
+	return &collapseSinglesIter{iter: iter, seenArtist: make(map[string]struct{})}
+}
+
+func (c *collapseSinglesIter) Next() *mediaprovider.Album {
+	for {
+		al := c.iter.Next()
+		if al == nil || al.TrackCount != 1 || len(al.ArtistIDs) == 0 || al.ArtistIDs[0] == "" {
+			return al
+		}
+		artistID := al.ArtistIDs[0]
+		if _, ok := c.seenArtist[artistID]; ok {
+			continue
+		}
+		c.seenArtist[artistID] = struct{}{}
+		return &mediaprovider.Album{
+			ID:          singlesAlbumIDPrefix + artistID,
+			CoverArtID:  al.CoverArtID,
+			Name:        SinglesAlbumName,
+			ArtistIDs:   al.ArtistIDs[:1],
+			ArtistNames: al.ArtistNames[:min(1, len(al.ArtistNames))],
+		}
+	}
+}
+
+// IsSinglesAlbumID returns true if the ID is that of a virtual "Singles" album.
+func IsSinglesAlbumID(albumID string) bool {
+	return strings.HasPrefix(albumID, singlesAlbumIDPrefix)
+}
+
+// ErrSinglesAlbum is returned for operations which have no
+// meaning for a virtual "Singles" album, such as sharing it.
+var ErrSinglesAlbum = errors.New("not supported for the Singles album")
+
+// singlesProvider intercepts the IDs of virtual "Singles" albums before
+// they reach the server, which knows nothing of them.
+type singlesProvider struct {
+	mediaprovider.MediaProvider
+}
+
+var (
+	_ mediaprovider.Wrapper                     = (*singlesProvider)(nil)
+	_ mediaprovider.SupportsRating              = (*singlesProvider)(nil)
+	_ mediaprovider.SupportsSetFavoriteProgress = (*singlesProvider)(nil)
+	_ mediaprovider.SupportsSetRatingProgress   = (*singlesProvider)(nil)
+	_ mediaprovider.SupportsInstantMix          = (*singlesProvider)(nil)
+	_ mediaprovider.SupportsSharing             = (*singlesProvider)(nil)
+)
+
+// NewSinglesProvider wraps the provider so that the virtual "Singles"
+// albums returned by NewCollapseSinglesIterator can be used as album IDs:
+// GetAlbum builds the album from the artist's single-track albums, and
+// rating, favoriting and instant mixes apply to those albums instead.
+// Use mediaprovider.As rather than type assertions to find its optional interfaces.
+func NewSinglesProvider(mp mediaprovider.MediaProvider) mediaprovider.MediaProvider {
+	return &singlesProvider{MediaProvider: mp}
+}
+
+func (s *singlesProvider) Unwrap() mediaprovider.MediaProvider {
+	return s.MediaProvider
+}
+
+func (s *singlesProvider) GetAlbum(albumID string) (*mediaprovider.AlbumWithTracks, error) {
+	return GetAlbum(s.MediaProvider, albumID)
+}
+
+func (s *singlesProvider) GetAlbumInfo(albumID string) (*mediaprovider.AlbumInfo, error) {
+	if IsSinglesAlbumID(albumID) {
+		return &mediaprovider.AlbumInfo{}, nil
+	}
+	return s.MediaProvider.GetAlbumInfo(albumID)
+}
+
+func (s *singlesProvider) GetSimilarAlbums(albumID string, limit int) ([]*mediaprovider.Album, error) {
+	if IsSinglesAlbumID(albumID) {
+		return nil, nil
+	}
+	return s.MediaProvider.GetSimilarAlbums(albumID, limit)
+}
+
+func (s *singlesProvider) SetFavorite(params mediaprovider.RatingFavoriteParameters, favorite bool) error {
+	params, err := s.expandSingles(params)
+	if err != nil {
+		return err
+	}
+	return s.MediaProvider.SetFavorite(params, favorite)
+}
+
+func (s *singlesProvider) SetFavoriteWithProgress(ctx context.Context, params mediaprovider.RatingFavoriteParameters, favorite bool, onProgress func(done, total int)) error {
+	params, err := s.expandSingles(params)
+	if err != nil {
+		return err
+	}
+	fp, _ := mediaprovider.As[mediaprovider.SupportsSetFavoriteProgress](s.MediaProvider)
+	return fp.SetFavoriteWithProgress(ctx, params, favorite, onProgress)
+}
+
+func (s *singlesProvider) SetRating(params mediaprovider.RatingFavoriteParameters, rating int) error {
+	params, err := s.expandSingles(params)
+	if err != nil {
+		return err
+	}
+	r, _ := mediaprovider.As[mediaprovider.SupportsRating](s.MediaProvider)
+	return r.SetRating(params, rating)
+}
+
+func (s *singlesProvider) SetRatingWithProgress(ctx context.Context, params mediaprovider.RatingFavoriteParameters, rating int, onProgress func(done, total int)) error {
+	params, err := s.expandSingles(params)
+	if err != nil {
+		return err
+	}
+	rp, _ := mediaprovider.As[mediaprovider.SupportsSetRatingProgress](s.MediaProvider)
+	return rp.SetRatingWithProgress(ctx, params, rating, onProgress)
+}
+
+// GetInstantMix returns a mix of the artist for a "Singles" album.
+func (s *singlesProvider) GetInstantMix(itemID string, limit int) ([]*mediaprovider.Track, error) {
+	im, _ := mediaprovider.As[mediaprovider.SupportsInstantMix](s.MediaProvider)
+	return im.GetInstantMix(strings.TrimPrefix(itemID, singlesAlbumIDPrefix), limit)
+}
+
+func (s *singlesProvider) GetGenreInstantMix(genre string, limit int) ([]*mediaprovider.Track, error) {
+	im, _ := mediaprovider.As[mediaprovider.SupportsInstantMix](s.MediaProvider)
+	return im.GetGenreInstantMix(genre, limit)
+}
+
+func (s *singlesProvider) CreateShareURL(id string) (*url.URL, error) {
+	if IsSinglesAlbumID(id) {
+		return nil, ErrSinglesAlbum
+	}
+	sh, _ := mediaprovider.As[mediaprovider.SupportsSharing](s.MediaProvider)
+	return sh.CreateShareURL(id)
+}
+
+func (s *singlesProvider) CanShareArtists() bool {
+	sh, _ := mediaprovider.As[mediaprovider.SupportsSharing](s.MediaProvider)
+	return sh.CanShareArtists()
+}
+
+// expandSingles replaces the IDs of "Singles" albums in params
+// with the IDs of the single-track albums they collect.
+func (s *singlesProvider) expandSingles(params mediaprovider.RatingFavoriteParameters) (mediaprovider.RatingFavoriteParameters, error) {
+	if !slices.ContainsFunc(params.AlbumIDs, IsSinglesAlbumID) {
+		return params, nil
+	}
+	var albumIDs []string
+	for _, id := range params.AlbumIDs {
+		if !IsSinglesAlbumID(id) {
+			albumIDs = append(albumIDs, id)
+			continue
+		}
+		artist, err := s.MediaProvider.GetArtist(strings.TrimPrefix(id, singlesAlbumIDPrefix))
+		if err != nil {
+			return params, err
+		}
+		for _, al := range singleAlbums(artist) {
+			albumIDs = append(albumIDs, al.ID)
+		}
+	}
+	params.AlbumIDs = albumIDs
+	return params, nil
+}
+
+// singleAlbums returns the artist's albums containing a single track.
+func singleAlbums(artist *mediaprovider.ArtistWithAlbums) []*mediaprovider.Album {
+	var albums []*mediaprovider.Album
+	for _, al := range artist.Albums {
+		if al.TrackCount == 1 {
+			albums = append(albums, al)
+		}
+	}
+	return albums
+}
+
+// GetAlbum returns the album with the given ID from the media provider,
+// or builds the virtual "Singles" album if the ID is that of one.
+func GetAlbum(mp mediaprovider.MediaProvider, albumID string) (*mediaprovider.AlbumWithTracks, error) {
+	if !IsSinglesAlbumID(albumID) {
+		return mp.GetAlbum(albumID)
+	}
+
+	artistID := strings.TrimPrefix(albumID, singlesAlbumIDPrefix)
+	artist, err := mp.GetArtist(artistID)
+	if err != nil {
+		return nil, err
+	}
+	singles := &mediaprovider.AlbumWithTracks{
+		Album: mediaprovider.Album{
+			ID:          albumID,
+			Name:        SinglesAlbumName,
+			ArtistIDs:   []string{artist.ID},
+			ArtistNames: []string{artist.Name},
+		},
+	}
+	for _, al := range singleAlbums(artist) {
+		album, err := mp.GetAlbum(al.ID)
+		if err != nil {
+			return nil, err
+		}
+		if singles.CoverArtID == "" {
+			singles.CoverArtID = album.CoverArtID
+		}
+		singles.Duration += album.Duration
+		singles.Tracks = append(singles.Tracks, album.Tracks...)
+	}
+	for i, tr := range singles.Tracks {
+		tr.TrackNumber = i + 1
+		tr.DiscNumber = 1
+	}
+	singles.TrackCount = len(singles.Tracks)
+	return singles, nil
+}
@@ -0,0 +1,85 @@
+package jellyfin
+
+import (
+	"slices"
+	"time"
+
+	"github.com/dweymouth/go-jellyfin"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+const (
+	// how long to cache loaded artists, so navigating back
+	// to an artist page doesn't refetch a large discography
+	artistCacheTTL = 2 * time.Minute
+	// number of albums to fetch per request when loading an artist
+	artistAlbumsPageSize = 100
+)
+
+var _ mediaprovider.SupportsGetArtistProgressive = (*jellyfinMediaProvider)(nil)
+
+func (j *jellyfinMediaProvider) GetArtist(artistID string) (*mediaprovider.ArtistWithAlbums, error) {
+	return j.GetArtistProgressive(artistID, nil)
+}
+
+// GetArtistProgressive fetches the artist concurrently with the first page
+// of its albums, then fetches the remaining pages, reporting the albums
+// loaded so far after each page if onPartial is non-nil.
+func (j *jellyfinMediaProvider) GetArtistProgressive(artistID string, onPartial func(*mediaprovider.ArtistWithAlbums)) (*mediaprovider.ArtistWithAlbums, error) {
+	cacheKey := j.libraryID + "/" + artistID
+	if artist, ok := j.artistCache.Get(cacheKey); ok {
+		return cloneArtist(artist), nil
+	}
+
+	type artistResult struct {
+		artist *jellyfin.Artist
+		err    error
+	}
+	arCh := make(chan artistResult, 1)
+	go func() {
+		ar, err := j.client.GetArtist(artistID)
+		arCh <- artistResult{artist: ar, err: err}
+	}()
+
+	var opts jellyfin.QueryOpts
+	opts.Filter.ArtistID = artistID
+	opts.Paging.Limit = artistAlbumsPageSize
+	// in the order the discography is shown, so partial results fill it in from the start
+	opts.Sort = jellyfin.Sort{Field: jellyfin.SortByYear, Mode: jellyfin.SortAsc}
+	al, albumsErr := j.client.GetAlbums(j.scoped(opts))
+	arRes := <-arCh
+	if arRes.err != nil {
+		return nil, arRes.err
+	}
+	if albumsErr != nil {
+		return nil, albumsErr
+	}
+
+	artist := &mediaprovider.ArtistWithAlbums{
+		Albums: sharedutil.MapSlice(al, toAlbum),
+	}
+	fillArtist(arRes.artist, &artist.Artist)
+	for len(al) == artistAlbumsPageSize {
+		if onPartial != nil {
+			onPartial(cloneArtist(artist))
+		}
+		opts.Paging.StartIndex += artistAlbumsPageSize
+		var err error
+		if al, err = j.client.GetAlbums(j.scoped(opts)); err != nil {
+			return nil, err
+		}
+		artist.Albums = append(artist.Albums, sharedutil.MapSlice(al, toAlbum)...)
+	}
+	j.fillArtistMetadata(&artist.Artist, artist.Albums)
+	j.artistCache.Set(cacheKey, artist)
+	return cloneArtist(artist), nil
+}
+
+// cloneArtist returns a shallow copy of the artist with its own albums slice,
+// so callers may sort it without affecting the cached or in-progress artist.
+func cloneArtist(artist *mediaprovider.ArtistWithAlbums) *mediaprovider.ArtistWithAlbums {
+	a := *artist
+	a.Albums = slices.Clone(artist.Albums)
+	return &a
+}
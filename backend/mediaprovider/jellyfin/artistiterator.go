@@ -57,10 +57,10 @@ func (j *jellyfinMediaProvider) IterateArtists(sortOrder string, filter mediapro
 			if !disablePagination {
 				paging = jellyfin.Paging{StartIndex: offs, Limit: limit}
 			}
-			return j.client.GetAlbumArtists(jellyfin.QueryOpts{
+			return j.client.GetAlbumArtists(j.scoped(jellyfin.QueryOpts{
 				Sort:   jfSort,
 				Paging: paging,
-			})
+			}))
 		},
 		sortFn,
 	)
@@ -96,13 +96,13 @@ func (j *jellyfinMediaProvider) SearchArtists(searchQuery string, filter mediapr
 
 	fetcher := makeArtistFetchFn(
 		func(offs, limit int) ([]*jellyfin.Artist, error) {
-			return j.client.GetAlbumArtists(jellyfin.QueryOpts{
+			return j.client.GetAlbumArtists(j.scoped(jellyfin.QueryOpts{
 				Sort: jellyfin.Sort{
 					Field: jellyfin.SortByName,
 					Mode:  jellyfin.SortAsc,
 				},
 				Paging: jellyfin.Paging{StartIndex: offs, Limit: limit},
-			})
+			}))
 		},
 		nil,
 	)
@@ -0,0 +1,106 @@
+package jellyfin
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// how long to remember the container of a track, so the
+// repeated stream URL requests for the track being played
+// (prefetch, cache, waveform, ...) don't each fetch the item
+const containerCacheTTL = 30 * time.Minute
+
+// containers which can be played directly by the local player,
+// mapped to the audio codec to request (empty if the container
+// may hold several codecs and the server should keep the original)
+var directStreamContainers = map[string]string{
+	"mp3":  "mp3",
+	"flac": "flac",
+	"m4a":  "",
+	"mp4":  "",
+	"aac":  "aac",
+	"alac": "alac",
+	"ogg":  "",
+	"oga":  "",
+	"opus": "opus",
+	"wav":  "pcm_s16le",
+	"wv":   "wavpack",
+	"ape":  "ape",
+	"aiff": "",
+	"aif":  "",
+	"webm": "",
+	"mka":  "",
+}
+
+// directStreamURL constructs a static stream URL for the original file,
+// including the container and codec parameters, so that the server
+// serves the file as-is rather than deciding to transcode based on
+// its device profiles. Falls back to the default stream URL if
+// the container is unknown or not directly playable.
+func (j *jellyfinMediaProvider) directStreamURL(trackID string) (string, error) {
+	streamURL, err := j.clientStreamURL(trackID)
+	if err != nil {
+		return "", err
+	}
+	container, err := j.directStreamContainer(trackID)
+	if err != nil || container == "" {
+		return streamURL, nil
+	}
+	codec := directStreamContainers[container]
+
+	u, err := url.Parse(streamURL)
+	if err != nil {
+		return "", err
+	}
+	// reuse the auth and session params from the default stream URL
+	q := u.Query()
+	q.Set("static", "true")
+	q.Set("container", container)
+	if codec != "" {
+		q.Set("audioCodec", codec)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/stream") + "/stream." + container
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// directStreamContainer returns the track's container if it is
+// in directStreamContainers, or "" if it is unknown or not directly playable.
+func (j *jellyfinMediaProvider) directStreamContainer(trackID string) (string, error) {
+	if c, ok := j.containerCache.Get(trackID); ok {
+		return c, nil
+	}
+	song, err := j.client.GetSong(trackID)
+	if err != nil {
+		return "", err
+	}
+	var container string
+	if len(song.MediaSources) > 0 {
+		container = directStreamContainerOf(song.MediaSources[0].Container)
+	}
+	j.containerCache.Set(trackID, container)
+	return container, nil
+}
+
+// rememberContainer caches the container of a track loaded from the server,
+// so that streaming it directly doesn't need to fetch the item again.
+func (j *jellyfinMediaProvider) rememberContainer(tr *mediaprovider.Track) {
+	if tr.Codec != "" {
+		j.containerCache.Set(tr.ID, directStreamContainerOf(tr.Codec))
+	}
+}
+
+// directStreamContainerOf returns the first of the containers reported by
+// Jellyfin (a comma-separated list of matching containers, e.g. "mov,mp4,m4a")
+// that is in directStreamContainers, or "" if there is none.
+func directStreamContainerOf(containers string) string {
+	for _, c := range strings.Split(strings.ToLower(containers), ",") {
+		if _, ok := directStreamContainers[c]; ok {
+			return c
+		}
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package jellyfin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// max number of times an interrupted download is resumed
+const downloadMaxResumes = 3
+
+var _ mediaprovider.SupportsDownloadResume = (*jellyfinMediaProvider)(nil)
+
+// DownloadTrack downloads the original file of the track (not a transcode),
+// resuming with HTTP Range requests if the connection is interrupted.
+// Returns a *mediaprovider.TrackDownload.
+func (j *jellyfinMediaProvider) DownloadTrack(trackID string) (io.Reader, error) {
+	return j.DownloadTrackFrom(trackID, 0)
+}
+
+func (j *jellyfinMediaProvider) DownloadTrackFrom(trackID string, offset int64) (io.Reader, error) {
+	resp, err := j.requestDownload(trackID, offset)
+	if err != nil {
+		return nil, err
+	}
+	d := &mediaprovider.TrackDownload{Size: -1}
+	if offset == 0 {
+		d.Size = resp.ContentLength
+	} else if _, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok {
+		if size, err := strconv.ParseInt(total, 10, 64); err == nil {
+			d.Size = size
+		}
+	}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		d.FileName = params["filename"]
+	}
+	d.ReadCloser = &resumingReader{j: j, trackID: trackID, body: resp.Body, size: d.Size, read: offset}
+	return d, nil
+}
+
+// requestDownload requests the original file of the track from the given byte offset.
+func (j *jellyfinMediaProvider) requestDownload(trackID string, offset int64) (*http.Response, error) {
+	creds, err := j.credentials()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, j.client.BaseURL().JoinPath("Items", trackID, "Download").String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Emby-Token", creds.token)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	// the API client's timeout is too short to download large files
+	cli := &http.Client{Transport: j.client.HTTPClient.Transport}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	wantStatus := http.StatusOK
+	if offset > 0 {
+		wantStatus = http.StatusPartialContent
+	}
+	if resp.StatusCode != wantStatus {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download %s: %s", trackID, resp.Status)
+	}
+	if offset > 0 && !strings.HasPrefix(resp.Header.Get("Content-Range"), "bytes "+strconv.FormatInt(offset, 10)+"-") {
+		resp.Body.Close()
+		return nil, errors.New("server did not resume download at the requested offset")
+	}
+	return resp, nil
+}
+
+// resumingReader reads a download, requesting the remainder
+// of the file if the connection drops partway through.
+type resumingReader struct {
+	j       *jellyfinMediaProvider
+	trackID string
+	body    io.ReadCloser
+	size    int64 // -1 if unknown
+	read    int64
+	resumes int
+}
+
+func (r *resumingReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.read += int64(n)
+	if err == nil || err == io.EOF && (r.size < 0 || r.read >= r.size) {
+		return n, err
+	}
+	// unexpected error or premature EOF
+	if r.resumes >= downloadMaxResumes {
+		return n, err
+	}
+	r.resumes++
+	log.Printf("resuming interrupted download of %s at %d bytes: %v", r.trackID, r.read, err)
+	resp, rerr := r.j.requestDownload(r.trackID, r.read)
+	if rerr != nil {
+		return n, fmt.Errorf("%w (resume failed: %v)", err, rerr)
+	}
+	r.body.Close()
+	r.body = resp.Body
+	return n, nil
+}
+
+func (r *resumingReader) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,73 @@
+package jellyfin
+
+import (
+	"context"
+	"slices"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+var _ mediaprovider.SupportsMetadataEditing = (*jellyfinMediaProvider)(nil)
+
+// CanEditMetadata returns whether the user is an administrator,
+// which Jellyfin requires to edit item metadata.
+func (j *jellyfinMediaProvider) CanEditMetadata() bool {
+	j.isAdminOnce.Do(func() {
+		creds, err := j.credentials()
+		if err != nil {
+			return
+		}
+		var user struct {
+			Policy struct {
+				IsAdministrator bool `json:"IsAdministrator"`
+			} `json:"Policy"`
+		}
+		if err := j.getJSON("/Users/"+creds.userID, nil, &user); err == nil {
+			j.isAdmin = user.Policy.IsAdministrator
+		}
+	})
+	return j.isAdmin
+}
+
+func (j *jellyfinMediaProvider) UpdateTrackMetadata(trackID string, update mediaprovider.MetadataUpdate) error {
+	return j.updateItemMetadata(trackID, update)
+}
+
+func (j *jellyfinMediaProvider) UpdateAlbumMetadata(albumID string, update mediaprovider.MetadataUpdate) error {
+	return j.updateItemMetadata(albumID, update)
+}
+
+// updateItemMetadata updates the item with the full item as fetched from
+// the server, with the edited fields changed, since Jellyfin replaces
+// the item's metadata with the posted item, clearing omitted fields.
+func (j *jellyfinMediaProvider) updateItemMetadata(itemID string, update mediaprovider.MetadataUpdate) error {
+	creds, err := j.credentials()
+	if err != nil {
+		return err
+	}
+	var item map[string]any
+	if err := j.getJSON("/Users/"+creds.userID+"/Items/"+itemID, nil, &item); err != nil {
+		return err
+	}
+	item["Name"] = update.Title
+	if update.Year > 0 {
+		item["ProductionYear"] = update.Year
+	} else {
+		delete(item, "ProductionYear")
+		delete(item, "PremiereDate")
+	}
+	genres := update.Genres
+	if genres == nil {
+		genres = []string{}
+	}
+	item["Genres"] = genres
+	// keep the server from overwriting the edits on the next metadata refresh
+	locked, _ := item["LockedFields"].([]any)
+	for _, field := range []string{"Name", "Genres"} {
+		if !slices.Contains(locked, any(field)) {
+			locked = append(locked, field)
+		}
+	}
+	item["LockedFields"] = locked
+	return j.postJSON(context.Background(), "/Items/"+itemID, item)
+}
@@ -0,0 +1,104 @@
+package jellyfin
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dweymouth/go-jellyfin"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+// page size for fetching favorites, so that users with thousands
+// of favorites don't make one huge (and possibly timing out) request
+const favoritesPageSize = 500
+
+var _ mediaprovider.SupportsFavoriteTrackIterator = (*jellyfinMediaProvider)(nil)
+
+// GetFavorites returns the favorites in the given order. Jellyfin doesn't
+// record when items were favorited, so they can't be ordered by it.
+func (j *jellyfinMediaProvider) GetFavorites(sort mediaprovider.FavoritesSortOrder) (mediaprovider.Favorites, error) {
+	var wg sync.WaitGroup
+	var favorites mediaprovider.Favorites
+	var albumsErr, artistsErr, tracksErr error
+
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		al, err := fetchAllPages(sort, func(opts jellyfin.QueryOpts) ([]*jellyfin.Album, error) {
+			return j.client.GetAlbums(j.scoped(opts))
+		})
+		favorites.Albums = sharedutil.MapSlice(al, toAlbum)
+		if err != nil {
+			albumsErr = fmt.Errorf("favorite albums: %w", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		ar, err := fetchAllPages(sort, func(opts jellyfin.QueryOpts) ([]*jellyfin.Artist, error) {
+			return j.client.GetAlbumArtists(j.scoped(opts))
+		})
+		favorites.Artists = sharedutil.MapSlice(ar, toArtist)
+		if err != nil {
+			artistsErr = fmt.Errorf("favorite artists: %w", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		tr, err := fetchAllPages(sort, func(opts jellyfin.QueryOpts) ([]*jellyfin.Song, error) {
+			return j.client.GetSongs(j.scoped(opts))
+		})
+		favorites.Tracks = sharedutil.MapSlice(tr, toTrack)
+		if err != nil {
+			tracksErr = fmt.Errorf("favorite tracks: %w", err)
+		}
+	}()
+	wg.Wait()
+
+	return favorites, errors.Join(albumsErr, artistsErr, tracksErr)
+}
+
+func (j *jellyfinMediaProvider) IterateFavoriteTracks(sort mediaprovider.FavoritesSortOrder) mediaprovider.TrackIterator {
+	return helpers.NewTrackIterator(func(offs, limit int) ([]*mediaprovider.Track, error) {
+		var opts jellyfin.QueryOpts
+		opts.Filter.Favorite = true
+		opts.Sort = favoritesSort(sort)
+		opts.Paging = jellyfin.Paging{StartIndex: offs, Limit: limit}
+		tr, err := j.client.GetSongs(j.scoped(opts))
+		if err != nil {
+			return nil, err
+		}
+		return sharedutil.MapSlice(tr, toTrack), nil
+	}, j.prefetchCoverCB)
+}
+
+// fetchAllPages fetches favorite items page by page until a short page is
+// returned. If a page fails, the items fetched so far are returned with the error.
+func fetchAllPages[T any](sort mediaprovider.FavoritesSortOrder, fetch func(jellyfin.QueryOpts) ([]T, error)) ([]T, error) {
+	var all []T
+	for {
+		var opts jellyfin.QueryOpts
+		opts.Filter.Favorite = true
+		opts.Sort = favoritesSort(sort)
+		opts.Paging = jellyfin.Paging{StartIndex: len(all), Limit: favoritesPageSize}
+		page, err := fetch(opts)
+		if err != nil {
+			return all, err
+		}
+		all = append(all, page...)
+		if len(page) < favoritesPageSize {
+			return all, nil
+		}
+	}
+}
+
+// favoritesSort returns the server sort for the favorites sort order.
+// Recently favorited falls back to the server's default order.
+func favoritesSort(sort mediaprovider.FavoritesSortOrder) jellyfin.Sort {
+	if sort == mediaprovider.FavoritesSortAlphabetical {
+		return jellyfin.Sort{Field: jellyfin.SortByName, Mode: jellyfin.SortAsc}
+	}
+	return jellyfin.Sort{}
+}
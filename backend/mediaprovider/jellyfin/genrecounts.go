@@ -0,0 +1,120 @@
+package jellyfin
+
+import (
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/go-jellyfin"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+const (
+	// Jellyfin's genre listing doesn't include album and track counts, so they
+	// are looked up with count-only item queries per genre. This is relatively
+	// expensive, so the counts are cached for longer than the genre list.
+	genreCountsValidDuration = 10 * time.Minute
+	genreCountConcurrency    = 8
+)
+
+type genreCounts struct {
+	albums int
+	tracks int
+}
+
+type genreCountCache struct {
+	mu     sync.Mutex
+	counts map[string]genreCounts // by genre ID
+	at     time.Time
+}
+
+// toGenresWithCounts converts the genres, filling in album and track counts from
+// the cache or by querying the server. Counts that can't be fetched are set to -1.
+func (j *jellyfinMediaProvider) toGenresWithCounts(genres []jellyfin.NameID) []*mediaprovider.Genre {
+	c := &j.genreCounts
+	c.mu.Lock()
+	if c.counts == nil || time.Since(c.at) > genreCountsValidDuration {
+		c.counts = make(map[string]genreCounts)
+		c.at = time.Now()
+	}
+	var missing []string
+	for _, g := range genres {
+		if _, ok := c.counts[g.ID]; !ok {
+			missing = append(missing, g.ID)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(missing) > 0 {
+		j.fetchGenreCounts(missing)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := make([]*mediaprovider.Genre, len(genres))
+	for i, g := range genres {
+		counts, ok := c.counts[g.ID]
+		if !ok {
+			counts = genreCounts{albums: -1, tracks: -1}
+		}
+		result[i] = &mediaprovider.Genre{
+			Name:       g.Name,
+			AlbumCount: counts.albums,
+			TrackCount: counts.tracks,
+		}
+	}
+	return result
+}
+
+func (j *jellyfinMediaProvider) fetchGenreCounts(genreIDs []string) {
+	creds, err := j.credentials()
+	if err != nil {
+		log.Printf("error fetching genre counts: %v", err)
+		return
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, genreCountConcurrency)
+	for _, id := range genreIDs {
+		id := id
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			albums, err := j.countItems(creds.userID, id, "MusicAlbum")
+			if err != nil {
+				log.Printf("error fetching genre album count: %v", err)
+				return
+			}
+			tracks, err := j.countItems(creds.userID, id, "Audio")
+			if err != nil {
+				log.Printf("error fetching genre track count: %v", err)
+				return
+			}
+			j.genreCounts.mu.Lock()
+			j.genreCounts.counts[id] = genreCounts{albums: albums, tracks: tracks}
+			j.genreCounts.mu.Unlock()
+		}()
+	}
+	wg.Wait()
+}
+
+// countItems returns the number of items of the given type in the genre,
+// without fetching the items themselves.
+func (j *jellyfinMediaProvider) countItems(userID, genreID, itemType string) (int, error) {
+	params := url.Values{
+		"Recursive":        {"true"},
+		"IncludeItemTypes": {itemType},
+		"GenreIds":         {genreID},
+		"Limit":            {"0"},
+		"EnableImages":     {"false"},
+		"EnableUserData":   {"false"},
+	}
+	var resp struct {
+		TotalRecordCount int `json:"TotalRecordCount"`
+	}
+	if err := j.getJSON("/Users/"+userID+"/Items", params, &resp); err != nil {
+		return 0, err
+	}
+	return resp.TotalRecordCount, nil
+}
@@ -0,0 +1,39 @@
+package jellyfin
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/dweymouth/go-jellyfin"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+var _ mediaprovider.SupportsInstantMix = (*jellyfinMediaProvider)(nil)
+
+// GetInstantMix returns Jellyfin's Instant Mix for the item,
+// which the server resolves by ID regardless of the item's type.
+func (j *jellyfinMediaProvider) GetInstantMix(itemID string, limit int) ([]*mediaprovider.Track, error) {
+	tr, err := j.client.GetInstantMix(itemID, jellyfin.ItemType(""), limit)
+	if err != nil {
+		return nil, err
+	}
+	return sharedutil.MapSlice(tr, toTrack), nil
+}
+
+// GetGenreInstantMix returns the Instant Mix for the genre, looked up by name
+// since genres are identified by name throughout the app.
+func (j *jellyfinMediaProvider) GetGenreInstantMix(genre string, limit int) ([]*mediaprovider.Track, error) {
+	creds, err := j.credentials()
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{"UserId": {creds.userID}, "Limit": {strconv.Itoa(limit)}}
+	var resp struct {
+		Items []*jellyfin.Song `json:"Items"`
+	}
+	if err := j.getJSON("/MusicGenres/"+genre+"/InstantMix", params, &resp); err != nil {
+		return nil, err
+	}
+	return sharedutil.MapSlice(resp.Items, toTrack), nil
+}
@@ -0,0 +1,195 @@
+package jellyfin
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dweymouth/go-jellyfin"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// max number of item IDs per request, to keep the URL a reasonable length
+const metadataLookupBatchSize = 100
+
+// the fields requested by go-jellyfin, plus those decoded into itemMetadata
+const (
+	songMetadataFields  = "Genres,DateCreated,MediaSources,UserData,ParentId,ProviderIds,People,MediaStreams"
+	albumMetadataFields = "Genres,DateCreated,ChildCount,UserData,ParentId,Overview,ProviderIds,SortName,PremiereDate"
+)
+
+// itemMetadata holds item fields which go-jellyfin
+// doesn't decode for songs, albums and artists.
+type itemMetadata struct {
+	Id           string            `json:"Id"`
+	SortName     string            `json:"SortName"`
+	PremiereDate string            `json:"PremiereDate"`
+	Genres       []string          `json:"Genres"`
+	ProviderIds  map[string]string `json:"ProviderIds"`
+	People       []struct {
+		Name string `json:"Name"`
+		Type string `json:"Type"`
+	} `json:"People"`
+	MediaStreams []struct {
+		Type       string `json:"Type"`
+		Codec      string `json:"Codec"`
+		SampleRate int    `json:"SampleRate"`
+		BitDepth   int    `json:"BitDepth"`
+		Channels   int    `json:"Channels"`
+	} `json:"MediaStreams"`
+}
+
+// getItemMetadata returns the extra metadata of the given items by ID.
+func (j *jellyfinMediaProvider) getItemMetadata(ids []string) (map[string]*itemMetadata, error) {
+	creds, err := j.credentials()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*itemMetadata, len(ids))
+	for start := 0; start < len(ids); start += metadataLookupBatchSize {
+		batch := ids[start:min(start+metadataLookupBatchSize, len(ids))]
+		var resp struct {
+			Items []*itemMetadata `json:"Items"`
+		}
+		params := url.Values{"Ids": {strings.Join(batch, ",")}, "Fields": {"Genres,ProviderIds,People,SortName,PremiereDate,MediaStreams"}}
+		if err := j.getJSON("/Users/"+creds.userID+"/Items", params, &resp); err != nil {
+			return nil, err
+		}
+		for _, item := range resp.Items {
+			result[item.Id] = item
+		}
+	}
+	return result, nil
+}
+
+// getTracksWithMetadata gets the songs at path (e.g. /Users/{id}/Items),
+// requesting the fields of itemMetadata along with those go-jellyfin
+// decodes, so that no separate metadata lookup is needed.
+func (j *jellyfinMediaProvider) getTracksWithMetadata(path string, params url.Values) ([]*mediaprovider.Track, error) {
+	params.Set("Fields", songMetadataFields)
+	var resp struct {
+		Items []json.RawMessage `json:"Items"`
+	}
+	if err := j.getJSON(path, params, &resp); err != nil {
+		return nil, err
+	}
+	tracks := make([]*mediaprovider.Track, 0, len(resp.Items))
+	for _, raw := range resp.Items {
+		var song jellyfin.Song
+		var meta itemMetadata
+		if err := json.Unmarshal(raw, &song); err != nil {
+			return nil, fmt.Errorf("parse songs: %w", err)
+		}
+		tr := toTrack(&song)
+		if json.Unmarshal(raw, &meta) == nil {
+			applyTrackMetadata(tr, &meta)
+		}
+		j.rememberContainer(tr)
+		tracks = append(tracks, tr)
+	}
+	return tracks, nil
+}
+
+// getAlbumWithMetadata is like client.GetAlbum, but also
+// requests and returns the fields of itemMetadata.
+func (j *jellyfinMediaProvider) getAlbumWithMetadata(albumID string) (*jellyfin.Album, *itemMetadata, error) {
+	creds, err := j.credentials()
+	if err != nil {
+		return nil, nil, err
+	}
+	var raw json.RawMessage
+	if err := j.getJSON("/Users/"+creds.userID+"/Items/"+albumID, url.Values{"Fields": {albumMetadataFields}}, &raw); err != nil {
+		return nil, nil, err
+	}
+	var al jellyfin.Album
+	var meta itemMetadata
+	if err := json.Unmarshal(raw, &al); err != nil {
+		return nil, nil, fmt.Errorf("parse item: %w", err)
+	}
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, nil, fmt.Errorf("parse item: %w", err)
+	}
+	return &al, &meta, nil
+}
+
+// applyTrackMetadata sets the genres, composer, MusicBrainz IDs
+// and audio stream details of the track.
+func applyTrackMetadata(tr *mediaprovider.Track, m *itemMetadata) {
+	tr.Genres = m.Genres
+	tr.MusicBrainzRecordingID = m.ProviderIds["MusicBrainzTrack"]
+	tr.MusicBrainzReleaseID = m.ProviderIds["MusicBrainzAlbum"]
+	var composers []string
+	for _, p := range m.People {
+		if p.Type == "Composer" {
+			composers = append(composers, p.Name)
+		}
+	}
+	tr.Composer = strings.Join(composers, ", ")
+	for _, s := range m.MediaStreams {
+		if s.Type == "Audio" {
+			tr.Codec = s.Codec
+			tr.SampleRate = s.SampleRate
+			tr.BitDepth = s.BitDepth
+			tr.Channels = s.Channels
+			break
+		}
+	}
+}
+
+// applyAlbumMetadata sets the sort name, release
+// date and MusicBrainz release group ID of the album.
+func applyAlbumMetadata(album *mediaprovider.Album, m *itemMetadata) {
+	album.SortName = sortNameIfDifferent(m.SortName, album.Name)
+	album.ReleaseDate = parsePremiereDate(m.PremiereDate)
+	album.MusicBrainzReleaseGroupID = m.ProviderIds["MusicBrainzReleaseGroup"]
+}
+
+// fillArtistMetadata sets the sort names of the artist and its albums,
+// and the albums' release dates.
+func (j *jellyfinMediaProvider) fillArtistMetadata(artist *mediaprovider.Artist, albums []*mediaprovider.Album) {
+	ids := make([]string, 0, len(albums)+1)
+	ids = append(ids, artist.ID)
+	for _, al := range albums {
+		ids = append(ids, al.ID)
+	}
+	meta, err := j.getItemMetadata(ids)
+	if err != nil {
+		log.Printf("error getting item metadata: %v", err)
+		return
+	}
+	if m, ok := meta[artist.ID]; ok {
+		artist.SortName = sortNameIfDifferent(m.SortName, artist.Name)
+	}
+	for _, al := range albums {
+		if m, ok := meta[al.ID]; ok {
+			al.SortName = sortNameIfDifferent(m.SortName, al.Name)
+			al.ReleaseDate = parsePremiereDate(m.PremiereDate)
+		}
+	}
+}
+
+// Jellyfin always returns a SortName, often a lowercased copy of the name,
+// so only keep it if it changes the ordering (e.g. a stripped "The").
+func sortNameIfDifferent(sortName, name string) string {
+	if strings.EqualFold(sortName, name) {
+		return ""
+	}
+	return sortName
+}
+
+// parsePremiereDate parses the date part of a Jellyfin PremiereDate timestamp.
+// Jellyfin has no notion of an original release date, so this is
+// the date of the release itself.
+func parsePremiereDate(s string) mediaprovider.ItemDate {
+	if len(s) < len("2006-01-02") {
+		return mediaprovider.ItemDate{}
+	}
+	t, err := time.Parse("2006-01-02", s[:len("2006-01-02")])
+	if err != nil {
+		return mediaprovider.ItemDate{}
+	}
+	return mediaprovider.ItemDate{Year: t.Year(), Month: int(t.Month()), Day: t.Day()}
+}
@@ -10,31 +10,58 @@ import (
 )
 
 const (
-	AlbumSortRecentlyAdded  string = "Recently Added"
-	AlbumSortRandom         string = "Random"
-	AlbumSortTitleAZ        string = "Title (A-Z)"
-	AlbumSortArtistAZ       string = "Artist (A-Z)"
-	AlbumSortYearAscending  string = "Year (ascending)"
-	AlbumSortYearDescending string = "Year (descending)"
+	AlbumSortRecentlyAdded    string = "Recently Added"
+	AlbumSortRecentlyPlayed   string = "Recently Played"
+	AlbumSortFrequentlyPlayed string = "Frequently Played"
+	AlbumSortRandom           string = "Random"
+	AlbumSortTitleAZ          string = "Title (A-Z)"
+	AlbumSortArtistAZ         string = "Artist (A-Z)"
+	AlbumSortYearAscending    string = "Year (ascending)"
+	AlbumSortYearDescending   string = "Year (descending)"
+	AlbumSortSuggested        string = "Suggested for You"
+	AlbumSortLatest           string = "Latest Additions"
 )
 
 func (j *jellyfinMediaProvider) AlbumSortOrders() []string {
 	return []string{
 		AlbumSortRecentlyAdded,
+		AlbumSortRecentlyPlayed,
+		AlbumSortFrequentlyPlayed,
 		AlbumSortRandom,
 		AlbumSortTitleAZ,
 		AlbumSortArtistAZ,
 		AlbumSortYearAscending,
 		AlbumSortYearDescending,
+		AlbumSortSuggested,
+		AlbumSortLatest,
 	}
 }
 
 func (j *jellyfinMediaProvider) IterateAlbums(sortOrder string, filter mediaprovider.AlbumFilter) mediaprovider.AlbumIterator {
+	// server-side recommendation shelves, which don't support filtering
+	// so the filter is applied in full by the iterator
+	switch sortOrder {
+	case AlbumSortSuggested:
+		return helpers.NewAlbumIterator(j.getSuggestedAlbums, filter, j.prefetchCoverCB)
+	case AlbumSortLatest:
+		return helpers.NewAlbumIterator(j.getLatestAlbums, filter, j.prefetchCoverCB)
+	}
+
+	jfFilt, modifiedFilter := jfFilterFromFilter(filter)
 	var jfSort jellyfin.Sort
 	switch sortOrder {
 	case AlbumSortRecentlyAdded:
 		jfSort.Field = jellyfin.SortByDateCreated
 		jfSort.Mode = jellyfin.SortDesc
+	case AlbumSortRecentlyPlayed:
+		jfSort.Field = jellyfin.SortByDatePlayed
+		jfSort.Mode = jellyfin.SortDesc
+		// unplayed albums have no play date and would otherwise trail the list
+		jfFilt.FilterPlayed = jellyfin.FilterIsPlayed
+	case AlbumSortFrequentlyPlayed:
+		jfSort.Field = jellyfin.SortByPlayCount
+		jfSort.Mode = jellyfin.SortDesc
+		jfFilt.FilterPlayed = jellyfin.FilterIsPlayed
 	case AlbumSortRandom:
 		jfSort.Field = jellyfin.SortByRandom
 	case AlbumSortArtistAZ:
@@ -50,14 +77,13 @@ func (j *jellyfinMediaProvider) IterateAlbums(sortOrder string, filter mediaprov
 		jfSort.Field = jellyfin.SortByYear
 		jfSort.Mode = jellyfin.SortDesc
 	}
-	jfFilt, modifiedFilter := jfFilterFromFilter(filter)
 
 	fetcher := func(offs, limit int) ([]*mediaprovider.Album, error) {
-		al, err := j.client.GetAlbums(jellyfin.QueryOpts{
+		al, err := j.client.GetAlbums(j.scoped(jellyfin.QueryOpts{
 			Sort:   jfSort,
 			Filter: jfFilt,
 			Paging: jellyfin.Paging{StartIndex: offs, Limit: limit},
-		})
+		}))
 		if err != nil {
 			return nil, err
 		}
@@ -66,11 +92,11 @@ func (j *jellyfinMediaProvider) IterateAlbums(sortOrder string, filter mediaprov
 
 	if sortOrder == AlbumSortRandom {
 		determFetcher := func(offs, limit int) ([]*mediaprovider.Album, error) {
-			al, err := j.client.GetAlbums(jellyfin.QueryOpts{
+			al, err := j.client.GetAlbums(j.scoped(jellyfin.QueryOpts{
 				Sort:   jellyfin.Sort{Field: "SortName", Mode: jellyfin.SortAsc},
 				Filter: jfFilt,
 				Paging: jellyfin.Paging{StartIndex: offs, Limit: limit},
-			})
+			}))
 			if err != nil {
 				return nil, err
 			}
@@ -81,9 +107,34 @@ func (j *jellyfinMediaProvider) IterateAlbums(sortOrder string, filter mediaprov
 	return helpers.NewAlbumIterator(fetcher, modifiedFilter, j.prefetchCoverCB)
 }
 
+var _ mediaprovider.SupportsAlbumsByYear = (*jellyfinMediaProvider)(nil)
+
+func (j *jellyfinMediaProvider) IterateAlbumsByYear(fromYear, toYear int, filter mediaprovider.AlbumFilter) mediaprovider.AlbumIterator {
+	jfFilt, modifiedFilter := jfFilterFromFilter(filter)
+	jfSort := jellyfin.Sort{Field: jellyfin.SortByYear, Mode: jellyfin.SortAsc}
+	if fromYear > toYear {
+		fromYear, toYear = toYear, fromYear
+		jfSort.Mode = jellyfin.SortDesc
+	}
+	// the requested range takes precedence over the filter's
+	jfFilt.YearRange = [2]int{fromYear, toYear}
+	fetcher := func(offs, limit int) ([]*mediaprovider.Album, error) {
+		al, err := j.client.GetAlbums(j.scoped(jellyfin.QueryOpts{
+			Sort:   jfSort,
+			Filter: jfFilt,
+			Paging: jellyfin.Paging{StartIndex: offs, Limit: limit},
+		}))
+		if err != nil {
+			return nil, err
+		}
+		return sharedutil.MapSlice(al, toAlbum), nil
+	}
+	return helpers.NewAlbumIterator(fetcher, modifiedFilter, j.prefetchCoverCB)
+}
+
 func (j *jellyfinMediaProvider) SearchAlbums(searchQuery string, filter mediaprovider.AlbumFilter) mediaprovider.AlbumIterator {
 	fetcher := func(offs, limit int) ([]*mediaprovider.Album, error) {
-		sr, err := j.client.Search(searchQuery, jellyfin.TypeAlbum, jellyfin.Paging{StartIndex: offs, Limit: limit})
+		sr, err := j.search(searchQuery, jellyfin.TypeAlbum, jellyfin.Paging{StartIndex: offs, Limit: limit})
 		if err != nil {
 			return nil, err
 		}
@@ -98,7 +149,7 @@ func (j *jellyfinMediaProvider) IterateTracks(searchQuery string) mediaprovider.
 		fetcher = func(offs, limit int) ([]*mediaprovider.Track, error) {
 			var opts jellyfin.QueryOpts
 			opts.Paging = jellyfin.Paging{StartIndex: offs, Limit: limit}
-			s, err := j.client.GetSongs(opts)
+			s, err := j.client.GetSongs(j.scoped(opts))
 			if err != nil {
 				return nil, err
 			}
@@ -106,7 +157,7 @@ func (j *jellyfinMediaProvider) IterateTracks(searchQuery string) mediaprovider.
 		}
 	} else {
 		fetcher = func(offs, limit int) ([]*mediaprovider.Track, error) {
-			sr, err := j.client.Search(searchQuery, jellyfin.TypeSong, jellyfin.Paging{StartIndex: offs, Limit: limit})
+			sr, err := j.search(searchQuery, jellyfin.TypeSong, jellyfin.Paging{StartIndex: offs, Limit: limit})
 			if err != nil {
 				return nil, err
 			}
@@ -116,6 +167,23 @@ func (j *jellyfinMediaProvider) IterateTracks(searchQuery string) mediaprovider.
 	return helpers.NewTrackIterator(fetcher, j.prefetchCoverCB)
 }
 
+var _ mediaprovider.SupportsGenreTracks = (*jellyfinMediaProvider)(nil)
+
+func (j *jellyfinMediaProvider) IterateGenreTracks(genre string) mediaprovider.TrackIterator {
+	fetcher := func(offs, limit int) ([]*mediaprovider.Track, error) {
+		s, err := j.client.GetSongs(j.scoped(jellyfin.QueryOpts{
+			Filter: jellyfin.Filter{Genres: []string{genre}},
+			Sort:   jellyfin.Sort{Field: jellyfin.SortByArtist, Mode: jellyfin.SortAsc},
+			Paging: jellyfin.Paging{StartIndex: offs, Limit: limit},
+		}))
+		if err != nil {
+			return nil, err
+		}
+		return sharedutil.MapSlice(s, toTrack), nil
+	}
+	return helpers.NewTrackIterator(fetcher, j.prefetchCoverCB)
+}
+
 // Creates the Jellyfin filter to implement the given mediaprovider filter,
 // and returns a modified mediaprovider filter, with now-unneeded fields zeroed out.
 func jfFilterFromFilter(filter mediaprovider.AlbumFilter) (jellyfin.Filter, mediaprovider.AlbumFilter) {
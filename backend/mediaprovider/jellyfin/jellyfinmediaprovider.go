@@ -1,25 +1,39 @@
 package jellyfin
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"image"
 	"io"
-	"math"
+	"log"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/dweymouth/go-jellyfin"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
 	"github.com/dweymouth/supersonic/sharedutil"
 )
 
 const (
-	cacheValidDurationSeconds = 60
-	runTimeTicksPerSecond     = 10_000_000
+	genreCacheTTL         = time.Minute
+	runTimeTicksPerSecond = 10_000_000
+	streamPrefetchBytes   = 256 * 1024
+	streamPrefetchTimeout = 30 * time.Second
+	variousArtistsName    = "Various Artists"
 )
 
 type JellyfinServer struct {
 	jellyfin.Client
+
+	// ForceDirectStream makes stream URLs request the original file
+	// for supported codecs, bypassing the server's transcoding decision.
+	ForceDirectStream bool
 }
 
 func (j *JellyfinServer) Login(user, pass string) mediaprovider.LoginResponse {
@@ -27,6 +41,9 @@ func (j *JellyfinServer) Login(user, pass string) mediaprovider.LoginResponse {
 		return mediaprovider.LoginResponse{Error: err}
 	}
 	err := j.Client.Login(user, pass)
+	if err == nil {
+		j.enableReauth(user, pass)
+	}
 	return mediaprovider.LoginResponse{
 		Error:       err,
 		IsAuthError: err != nil,
@@ -34,23 +51,44 @@ func (j *JellyfinServer) Login(user, pass string) mediaprovider.LoginResponse {
 }
 
 func (j *JellyfinServer) MediaProvider() mediaprovider.MediaProvider {
-	return newJellyfinMediaProvider(&j.Client)
+	mp := newJellyfinMediaProvider(&j.Client).(*jellyfinMediaProvider)
+	mp.forceDirectStream = j.ForceDirectStream
+	return mp
 }
 
 var _ mediaprovider.MediaProvider = (*jellyfinMediaProvider)(nil)
+var _ mediaprovider.SupportsStreamPrefetch = (*jellyfinMediaProvider)(nil)
 
 type jellyfinMediaProvider struct {
-	client          *jellyfin.Client
-	prefetchCoverCB func(coverArtID string)
+	client            *jellyfin.Client
+	prefetchCoverCB   func(coverArtID string)
+	forceDirectStream bool
+	libraryID         string // "" for all libraries
+
+	genreCache  *sharedutil.TTLCache[string, []*mediaprovider.Genre] // keyed by library ID
+	genreCounts genreCountCache
+	artistCache *sharedutil.TTLCache[string, *mediaprovider.ArtistWithAlbums]
+	// the direct stream container of each track, keyed by track ID
+	containerCache *sharedutil.TTLCache[string, string]
+
+	playlistAccessOnce      sync.Once
+	playlistAccessSupported bool // server is 10.9+
 
-	genresCached   []*mediaprovider.Genre
-	genresCachedAt int64 // unix
+	isAdminOnce sync.Once
+	isAdmin     bool
+
+	prefetchMu     sync.Mutex
+	prefetchCancel context.CancelFunc
 }
 
+var streamPrefetchClient = &http.Client{Timeout: streamPrefetchTimeout}
+
 func newJellyfinMediaProvider(cli *jellyfin.Client) mediaprovider.MediaProvider {
 	return &jellyfinMediaProvider{
-		client:       cli,
-		genresCached: make([]*mediaprovider.Genre, 0),
+		client:         cli,
+		genreCache:     sharedutil.NewTTLCache[string, []*mediaprovider.Genre](genreCacheTTL),
+		artistCache:    sharedutil.NewTTLCache[string, *mediaprovider.ArtistWithAlbums](artistCacheTTL),
+		containerCache: sharedutil.NewTTLCache[string, string](containerCacheTTL),
 	}
 }
 
@@ -67,11 +105,17 @@ func (j *jellyfinMediaProvider) DeletePlaylist(id string) error {
 }
 
 func (j *jellyfinMediaProvider) CanMakePublicPlaylist() bool {
-	return false
+	return j.supportsPlaylistAccess()
 }
 
 func (j *jellyfinMediaProvider) EditPlaylist(id, name, description string, public bool) error {
-	return j.client.UpdatePlaylistMetadata(id, name, description)
+	if err := j.client.UpdatePlaylistMetadata(id, name, description); err != nil {
+		return err
+	}
+	if !j.supportsPlaylistAccess() {
+		return nil
+	}
+	return j.postJSON(context.Background(), "/Playlists/"+id, map[string]any{"IsPublic": public})
 }
 
 func (j *jellyfinMediaProvider) AddPlaylistTracks(id string, trackIDsToAdd []string) error {
@@ -83,38 +127,69 @@ func (j *jellyfinMediaProvider) RemovePlaylistTracks(playlistID string, removeId
 }
 
 func (j *jellyfinMediaProvider) ReplacePlaylistTracks(playlistID string, trackIDs []string) error {
-	pl, err := j.client.GetPlaylist(playlistID)
+	old, err := j.client.GetPlaylistSongs(playlistID)
 	if err != nil {
 		return err
 	}
-	allIndexes := make([]int, pl.SongCount)
-	for i := range allIndexes {
-		allIndexes[i] = i
-	}
-	if err = j.client.RemoveSongsFromPlaylist(playlistID, allIndexes); err != nil {
+	oldIDs := sharedutil.MapSlice(old, func(s *jellyfin.Song) string { return s.Id })
+	return helpers.ReplacePlaylistTracks(j, playlistID, oldIDs, trackIDs)
+}
+
+var _ mediaprovider.SupportsPlaylistTrackMove = (*jellyfinMediaProvider)(nil)
+
+func (j *jellyfinMediaProvider) MovePlaylistTrack(playlistID string, fromIdx, toIdx int) error {
+	songs, err := j.client.GetPlaylistSongs(playlistID)
+	if err != nil {
 		return err
 	}
-	return j.client.AddSongsToPlaylist(playlistID, trackIDs)
+	if fromIdx < 0 || fromIdx >= len(songs) || toIdx < 0 || toIdx >= len(songs) {
+		return errors.New("playlist track index out of range")
+	}
+	// the move endpoint takes the playlist entry ID, not the track ID
+	return j.client.MovePlaylistSong(playlistID, songs[fromIdx].PlaylistItemId, toIdx)
 }
 
 func (j *jellyfinMediaProvider) GetAlbum(albumID string) (*mediaprovider.AlbumWithTracks, error) {
-	al, err := j.client.GetAlbum(albumID)
+	creds, err := j.credentials()
 	if err != nil {
 		return nil, err
 	}
-	var opts jellyfin.QueryOpts
-	opts.Filter.ParentID = albumID
-	tr, err := j.client.GetSongs(opts)
+	al, meta, err := j.getAlbumWithMetadata(albumID)
+	if err != nil {
+		return nil, err
+	}
+	tracks, err := j.getTracksWithMetadata("/Users/"+creds.userID+"/Items", url.Values{
+		"ParentId":         {albumID},
+		"IncludeItemTypes": {"Audio"},
+		"Recursive":        {"true"},
+		"SortBy":           {"SortName"},
+		"SortOrder":        {"Ascending"},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	album := &mediaprovider.AlbumWithTracks{}
+	album := &mediaprovider.AlbumWithTracks{Tracks: tracks}
 	fillAlbum(al, &album.Album)
-	album.Tracks = sharedutil.MapSlice(tr, toTrack)
+	applyAlbumMetadata(&album.Album, meta)
+	normalizeDiscNumbers(album.Tracks)
 	return album, nil
 }
 
+// normalizeDiscNumbers assigns tracks with no disc number (ParentIndexNumber)
+// to disc 1 if other tracks of the album have one, so they are not
+// grouped into a separate "disc 0".
+func normalizeDiscNumbers(tracks []*mediaprovider.Track) {
+	if !slices.ContainsFunc(tracks, func(tr *mediaprovider.Track) bool { return tr.DiscNumber > 0 }) {
+		return
+	}
+	for _, tr := range tracks {
+		if tr.DiscNumber == 0 {
+			tr.DiscNumber = 1
+		}
+	}
+}
+
 func (j *jellyfinMediaProvider) GetAlbumInfo(albumID string) (*mediaprovider.AlbumInfo, error) {
 	al, err := j.client.GetAlbum(albumID)
 	if err != nil {
@@ -125,25 +200,6 @@ func (j *jellyfinMediaProvider) GetAlbumInfo(albumID string) (*mediaprovider.Alb
 	}, nil
 }
 
-func (j *jellyfinMediaProvider) GetArtist(artistID string) (*mediaprovider.ArtistWithAlbums, error) {
-	ar, err := j.client.GetArtist(artistID)
-	if err != nil {
-		return nil, err
-	}
-	var opts jellyfin.QueryOpts
-	opts.Filter.ArtistID = artistID
-	al, err := j.client.GetAlbums(opts)
-	if err != nil {
-		return nil, err
-	}
-
-	artist := &mediaprovider.ArtistWithAlbums{
-		Albums: sharedutil.MapSlice(al, toAlbum),
-	}
-	fillArtist(ar, &artist.Artist)
-	return artist, nil
-}
-
 func (j *jellyfinMediaProvider) GetArtistInfo(artistID string) (*mediaprovider.ArtistInfo, error) {
 	ar, err := j.client.GetArtist(artistID)
 	if err != nil {
@@ -160,20 +216,55 @@ func (j *jellyfinMediaProvider) GetArtistInfo(artistID string) (*mediaprovider.A
 }
 
 func (j *jellyfinMediaProvider) GetTrack(trackID string) (*mediaprovider.Track, error) {
-	tr, err := j.client.GetSong(trackID)
+	creds, err := j.credentials()
+	if err != nil {
+		return nil, err
+	}
+	tracks, err := j.getTracksWithMetadata("/Users/"+creds.userID+"/Items", url.Values{"Ids": {trackID}})
 	if err != nil {
 		return nil, err
 	}
-	return toTrack(tr), nil
+	if len(tracks) == 0 {
+		return nil, errors.New("track not found")
+	}
+	return tracks[0], nil
 }
 
 func (j *jellyfinMediaProvider) GetTopTracks(artist mediaprovider.Artist, limit int) ([]*mediaprovider.Track, error) {
 	var opts jellyfin.QueryOpts
 	opts.Paging.Limit = limit
 	opts.Filter.ArtistID = artist.ID
-	opts.Sort.Field = jellyfin.SortByCommunityRating
+	// Jellyfin has no notion of popularity, and community ratings
+	// are rarely populated, so use the user's most played tracks
+	opts.Sort.Field = jellyfin.SortByPlayCount
+	opts.Sort.Mode = jellyfin.SortDesc
+	tr, err := j.client.GetSongs(j.scoped(opts))
+	if err != nil {
+		return nil, err
+	}
+	tracks := sharedutil.MapSlice(tr, toTrack)
+	return slices.DeleteFunc(tracks, func(t *mediaprovider.Track) bool {
+		return t.PlayCount == 0
+	}), nil
+}
+
+var _ mediaprovider.SupportsPlayedTracks = (*jellyfinMediaProvider)(nil)
+
+func (j *jellyfinMediaProvider) GetMostPlayedTracks(limit int) ([]*mediaprovider.Track, error) {
+	return j.playedTracks(jellyfin.SortByPlayCount, limit)
+}
+
+func (j *jellyfinMediaProvider) GetRecentlyPlayedTracks(limit int) ([]*mediaprovider.Track, error) {
+	return j.playedTracks(jellyfin.SortByDatePlayed, limit)
+}
+
+func (j *jellyfinMediaProvider) playedTracks(sort jellyfin.SortField, limit int) ([]*mediaprovider.Track, error) {
+	var opts jellyfin.QueryOpts
+	opts.Paging.Limit = limit
+	opts.Filter.FilterPlayed = jellyfin.FilterIsPlayed
+	opts.Sort.Field = sort
 	opts.Sort.Mode = jellyfin.SortDesc
-	tr, err := j.client.GetSongs(opts)
+	tr, err := j.client.GetSongs(j.scoped(opts))
 	if err != nil {
 		return nil, err
 	}
@@ -185,13 +276,33 @@ func (j *jellyfinMediaProvider) GetRandomTracks(genreName string, limit int) ([]
 	opts.Paging.Limit = limit
 	opts.Filter.Genres = []string{genreName}
 	opts.Sort.Field = jellyfin.SortByRandom
-	tr, err := j.client.GetSongs(opts)
+	tr, err := j.client.GetSongs(j.scoped(opts))
 	if err != nil {
 		return nil, err
 	}
 	return sharedutil.MapSlice(tr, toTrack), nil
 }
 
+func (j *jellyfinMediaProvider) GetRandomAlbums(limit int, filter mediaprovider.AlbumFilter) ([]*mediaprovider.Album, error) {
+	var albums []*mediaprovider.Album
+	iter := j.IterateAlbums(AlbumSortRandom, filter)
+	for al := iter.Next(); al != nil && len(albums) < limit; al = iter.Next() {
+		albums = append(albums, al)
+	}
+	return albums, nil
+}
+
+func (j *jellyfinMediaProvider) GetRandomArtists(limit int) ([]*mediaprovider.Artist, error) {
+	var opts jellyfin.QueryOpts
+	opts.Paging.Limit = limit
+	opts.Sort.Field = jellyfin.SortByRandom
+	ar, err := j.client.GetAlbumArtists(j.scoped(opts))
+	if err != nil {
+		return nil, err
+	}
+	return sharedutil.MapSlice(ar, toArtist), nil
+}
+
 func (j *jellyfinMediaProvider) GetSimilarTracks(artistID string, limit int) ([]*mediaprovider.Track, error) {
 	tr, err := j.client.GetInstantMix(artistID, jellyfin.TypeArtist, limit)
 	if err != nil {
@@ -204,65 +315,18 @@ func (j *jellyfinMediaProvider) GetCoverArt(id string, size int) (image.Image, e
 	return j.client.GetItemImage(id, "Primary", size, 92)
 }
 
-func (s *jellyfinMediaProvider) GetFavorites() (mediaprovider.Favorites, error) {
-	var wg sync.WaitGroup
-	var favorites mediaprovider.Favorites
-
-	wg.Add(1)
-	go func() {
-		var opts jellyfin.QueryOpts
-		opts.Filter.Favorite = true
-		al, err := s.client.GetAlbums(opts)
-		if err == nil && len(al) > 0 {
-			favorites.Albums = sharedutil.MapSlice(al, toAlbum)
-		}
-		wg.Done()
-	}()
-
-	wg.Add(1)
-	go func() {
-		var opts jellyfin.QueryOpts
-		opts.Filter.Favorite = true
-		ar, err := s.client.GetAlbumArtists(opts)
-		if err == nil && len(ar) > 0 {
-			favorites.Artists = sharedutil.MapSlice(ar, toArtist)
-		}
-		wg.Done()
-	}()
-
-	wg.Add(1)
-	go func() {
-		var opts jellyfin.QueryOpts
-		opts.Filter.Favorite = true
-		tr, err := s.client.GetSongs(opts)
-		if err == nil && len(tr) > 0 {
-			favorites.Tracks = sharedutil.MapSlice(tr, toTrack)
-		}
-		wg.Done()
-	}()
-
-	wg.Wait()
-	return favorites, nil
-}
-
 func (j *jellyfinMediaProvider) GetGenres() ([]*mediaprovider.Genre, error) {
-	if j.genresCached != nil && time.Now().Unix()-j.genresCachedAt < cacheValidDurationSeconds {
-		return j.genresCached, nil
+	if genres, ok := j.genreCache.Get(j.libraryID); ok {
+		return genres, nil
 	}
 
 	g, err := j.client.GetGenres(jellyfin.Paging{})
 	if err != nil {
 		return nil, err
 	}
-	j.genresCached = sharedutil.MapSlice(g, func(g jellyfin.NameID) *mediaprovider.Genre {
-		return &mediaprovider.Genre{
-			Name:       g.Name,
-			AlbumCount: -1, // unsupported by Jellyfin
-			TrackCount: -1, // unsupported by Jellyfin
-		}
-	})
-	j.genresCachedAt = time.Now().Unix()
-	return j.genresCached, nil
+	genres := j.toGenresWithCounts(g)
+	j.genreCache.Set(j.libraryID, genres)
+	return genres, nil
 }
 
 func (j *jellyfinMediaProvider) GetPlaylists() ([]*mediaprovider.Playlist, error) {
@@ -274,7 +338,11 @@ func (j *jellyfinMediaProvider) GetPlaylists() ([]*mediaprovider.Playlist, error
 }
 
 func (j *jellyfinMediaProvider) GetPlaylist(playlistID string) (*mediaprovider.PlaylistWithTracks, error) {
-	tr, err := j.client.GetPlaylistSongs(playlistID)
+	creds, err := j.credentials()
+	if err != nil {
+		return nil, err
+	}
+	tracks, err := j.getTracksWithMetadata("/Playlists/"+playlistID+"/Items", url.Values{"UserId": {creds.userID}})
 	if err != nil {
 		return nil, err
 	}
@@ -283,61 +351,72 @@ func (j *jellyfinMediaProvider) GetPlaylist(playlistID string) (*mediaprovider.P
 		return nil, err
 	}
 
-	playlist := &mediaprovider.PlaylistWithTracks{
-		Tracks: sharedutil.MapSlice(tr, toTrack),
-	}
+	playlist := &mediaprovider.PlaylistWithTracks{Tracks: tracks}
 	j.fillPlaylist(pl, &playlist.Playlist)
+	j.fillPlaylistAccess(&playlist.Playlist)
 	return playlist, nil
 }
 
 func (j *jellyfinMediaProvider) SetFavorite(params mediaprovider.RatingFavoriteParameters, favorite bool) error {
-	var allIDs []string
-	allIDs = append(allIDs, params.AlbumIDs...)
-	allIDs = append(allIDs, params.ArtistIDs...)
-	allIDs = append(allIDs, params.TrackIDs...)
-
-	// Jellyfin doesn't allow bulk setting favorites.
-	// To not overwhelm the server with requests, set favorite for
-	// only 5 items at a time concurrently
-	batchSize := 5
-	var err error
-	batchSetFavorite := func(offs int, wg *sync.WaitGroup) {
-		for i := 0; i < batchSize && offs+i < len(allIDs); i++ {
-			wg.Add(1)
-			go func(idx int) {
-				newErr := j.client.SetFavorite(allIDs[idx], favorite)
-				if err == nil && newErr != nil {
-					err = newErr
-				}
-				wg.Done()
-			}(offs + i)
-		}
-	}
-
-	numBatches := int(math.Ceil(float64(len(allIDs)) / float64(batchSize)))
-	for i := 0; i < numBatches; i++ {
-		var wg sync.WaitGroup
-		batchSetFavorite(i*batchSize, &wg)
-		wg.Wait()
-	}
-
-	return err
+	return j.SetFavoriteWithProgress(context.Background(), params, favorite, nil)
 }
 
 func (j *jellyfinMediaProvider) GetStreamURL(trackID string, forceRaw bool) (string, error) {
-	return j.client.GetStreamURL(trackID)
+	if forceRaw || j.forceDirectStream {
+		return j.directStreamURL(trackID)
+	}
+	return j.clientStreamURL(trackID)
 }
 
-func (j *jellyfinMediaProvider) DownloadTrack(trackID string) (io.Reader, error) {
-	url, err := j.client.GetStreamURL(trackID)
+// clientStreamURL returns the client's stream URL for the track,
+// with the current access token.
+func (j *jellyfinMediaProvider) clientStreamURL(trackID string) (string, error) {
+	u, err := j.client.GetStreamURL(trackID)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	resp, err := http.Get(url)
+	return withCurrentToken(j.client, u), nil
+}
+
+func (j *jellyfinMediaProvider) PrefetchStreamURL(trackID string, forceRaw bool) (string, error) {
+	streamURL, err := j.GetStreamURL(trackID, forceRaw)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	// a static URL serves the original file, so there
+	// is no transcoding session to get ready
+	if u, err := url.Parse(streamURL); err != nil || u.Query().Get("static") == "true" {
+		return streamURL, nil
 	}
-	return resp.Body, nil
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j.prefetchMu.Lock()
+	if j.prefetchCancel != nil {
+		j.prefetchCancel() // the previous next track was skipped
+	}
+	j.prefetchCancel = cancel
+	j.prefetchMu.Unlock()
+
+	// Request the first chunk of the stream so that the server has the
+	// transcoding session ready by the time the player opens it.
+	go func() {
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+		if err != nil {
+			return
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", streamPrefetchBytes-1))
+		resp, err := streamPrefetchClient.Do(req)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("failed to prefetch stream: %v", err)
+			}
+			return
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+	return streamURL, nil
 }
 
 func (j *jellyfinMediaProvider) ClientDecidesScrobble() bool { return false }
@@ -354,6 +433,31 @@ func (j *jellyfinMediaProvider) RescanLibrary() error {
 	return j.client.RefreshLibrary()
 }
 
+var _ mediaprovider.SupportsScanStatus = (*jellyfinMediaProvider)(nil)
+
+// GetScanStatus returns the status of the library scan scheduled task.
+// Viewing scheduled tasks requires an administrator account.
+func (j *jellyfinMediaProvider) GetScanStatus() (mediaprovider.ScanStatus, error) {
+	var tasks []struct {
+		Key                       string  `json:"Key"`
+		State                     string  `json:"State"`
+		CurrentProgressPercentage float64 `json:"CurrentProgressPercentage"`
+	}
+	if err := j.getJSON("/ScheduledTasks", url.Values{"isHidden": {"false"}}, &tasks); err != nil {
+		return mediaprovider.ScanStatus{}, err
+	}
+	for _, t := range tasks {
+		if t.Key != "RefreshLibrary" {
+			continue
+		}
+		if t.State != "Running" {
+			return mediaprovider.ScanStatus{Progress: -1}, nil
+		}
+		return mediaprovider.ScanStatus{Scanning: true, Progress: t.CurrentProgressPercentage / 100}, nil
+	}
+	return mediaprovider.ScanStatus{}, errors.New("library scan task not found")
+}
+
 var _ mediaprovider.LyricsProvider = (*jellyfinMediaProvider)(nil)
 
 func (j *jellyfinMediaProvider) GetLyrics(tr *mediaprovider.Track) (*mediaprovider.Lyrics, error) {
@@ -399,7 +503,6 @@ func toTrack(ch *jellyfin.Song) *mediaprovider.Track {
 		Duration:    int(ch.RunTimeTicks / runTimeTicksPerSecond),
 		TrackNumber: ch.IndexNumber,
 		DiscNumber:  ch.DiscNumber,
-		//Genre:       ch.Genres,
 		ArtistIDs:   artistIDs,
 		ArtistNames: artistNames,
 		Album:       ch.Album,
@@ -408,11 +511,24 @@ func toTrack(ch *jellyfin.Song) *mediaprovider.Track {
 		Rating:      ch.UserData.Rating,
 		Favorite:    ch.UserData.IsFavorite,
 		PlayCount:   ch.UserData.PlayCount,
+		CreatedAt:   parseJellyfinTime(ch.DateCreated),
+		LastPlayed:  parseJellyfinTime(ch.UserData.LastPlayedDate),
 	}
 	if len(ch.MediaSources) > 0 {
 		t.FilePath = ch.MediaSources[0].Path
 		t.Size = int64(ch.MediaSources[0].Size)
 		t.BitRate = ch.MediaSources[0].Bitrate / 1000
+		t.Codec = ch.MediaSources[0].Container
+	}
+	return t
+}
+
+// parseJellyfinTime parses a Jellyfin timestamp such as DateCreated,
+// returning the zero time if empty or malformed.
+func parseJellyfinTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
 	}
 	return t
 }
@@ -454,7 +570,18 @@ func fillAlbum(a *jellyfin.Album, album *mediaprovider.Album) {
 	album.TrackCount = a.ChildCount
 	album.Genres = a.Genres
 	album.Favorite = a.UserData.IsFavorite
+	album.CreatedAt = parseJellyfinTime(a.DateCreated)
+	album.LastPlayed = parseJellyfinTime(a.UserData.LastPlayedDate)
 	album.ReleaseTypes = mediaprovider.ReleaseTypeAlbum
+	// Jellyfin doesn't store the compilation flag, but tags
+	// compilations with the "Various Artists" album artist
+	for _, name := range artistNames {
+		if strings.EqualFold(name, variousArtistsName) {
+			album.IsCompilation = true
+			album.ReleaseTypes = mediaprovider.ReleaseTypeCompilation
+			break
+		}
+	}
 }
 
 func (j *jellyfinMediaProvider) toPlaylist(p *jellyfin.Playlist) *mediaprovider.Playlist {
@@ -470,7 +597,9 @@ func (j *jellyfinMediaProvider) fillPlaylist(p *jellyfin.Playlist, pl *mediaprov
 	pl.Description = p.Overview
 	pl.TrackCount = p.SongCount
 	pl.Duration = int(p.RunTimeTicks / runTimeTicksPerSecond)
-	// Jellyfin does not have public playlists
+	// Jellyfin doesn't report playlist owners, and only the logged in
+	// user's playlists and those shared with them are listed.
+	// Public and Collaborative are filled in by GetPlaylist.
 	pl.Owner = j.client.LoggedInUser()
 	pl.Public = false
 }
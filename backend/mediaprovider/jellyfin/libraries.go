@@ -0,0 +1,111 @@
+package jellyfin
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/dweymouth/go-jellyfin"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+var _ mediaprovider.SupportsMusicLibraries = (*jellyfinMediaProvider)(nil)
+
+func (j *jellyfinMediaProvider) GetMusicLibraries() ([]*mediaprovider.MusicLibrary, error) {
+	creds, err := j.credentials()
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Items []struct {
+			Id             string `json:"Id"`
+			Name           string `json:"Name"`
+			CollectionType string `json:"CollectionType"`
+		} `json:"Items"`
+	}
+	if err := j.getJSON("/Users/"+creds.userID+"/Views", nil, &resp); err != nil {
+		return nil, err
+	}
+	var libraries []*mediaprovider.MusicLibrary
+	for _, v := range resp.Items {
+		if v.CollectionType == "music" {
+			libraries = append(libraries, &mediaprovider.MusicLibrary{ID: v.Id, Name: v.Name})
+		}
+	}
+	return libraries, nil
+}
+
+// SetMusicLibrary scopes browsing and search to one library. Jellyfin
+// item queries accept a single parent, so only one library can be selected.
+func (j *jellyfinMediaProvider) SetMusicLibrary(id string) {
+	j.libraryID = id
+}
+
+// scoped sets the parent of the query to the selected library,
+// unless the query is already scoped to a specific item.
+func (j *jellyfinMediaProvider) scoped(opts jellyfin.QueryOpts) jellyfin.QueryOpts {
+	if opts.Filter.ParentID == "" {
+		opts.Filter.ParentID = j.libraryID
+	}
+	return opts
+}
+
+// fields requested for search results, matching what go-jellyfin requests
+var searchIncludeFields = map[jellyfin.ItemType]string{
+	jellyfin.TypeAlbum:  "Genres,DateCreated,ChildCount,UserData,ParentId",
+	jellyfin.TypeArtist: "ChildCount,UserData",
+	jellyfin.TypeSong:   "Genres,DateCreated,MediaSources,UserData,ParentId",
+}
+
+// search is like client.Search, but scoped to the selected library.
+// The returned result is never nil.
+func (j *jellyfinMediaProvider) search(query string, itemType jellyfin.ItemType, paging jellyfin.Paging) (*jellyfin.SearchResult, error) {
+	if j.libraryID == "" {
+		sr, err := j.client.Search(query, itemType, paging)
+		if sr == nil {
+			sr = &jellyfin.SearchResult{}
+		}
+		return sr, err
+	}
+
+	creds, err := j.credentials()
+	if err != nil {
+		return &jellyfin.SearchResult{}, err
+	}
+	includeType := map[jellyfin.ItemType]string{
+		jellyfin.TypeAlbum:  "MusicAlbum",
+		jellyfin.TypeArtist: "MusicArtist",
+		jellyfin.TypeSong:   "Audio",
+	}[itemType]
+	params := url.Values{
+		"SearchTerm":       {query},
+		"ParentId":         {j.libraryID},
+		"Recursive":        {"true"},
+		"IncludeItemTypes": {includeType},
+		"Fields":           {searchIncludeFields[itemType]},
+		"StartIndex":       {strconv.Itoa(paging.StartIndex)},
+	}
+	if paging.Limit > 0 {
+		params.Set("Limit", strconv.Itoa(paging.Limit))
+	}
+	path := "/Users/" + creds.userID + "/Items"
+
+	var sr jellyfin.SearchResult
+	switch itemType {
+	case jellyfin.TypeAlbum:
+		var resp struct{ Items []*jellyfin.Album }
+		err = j.getJSON(path, params, &resp)
+		sr.Albums = resp.Items
+	case jellyfin.TypeArtist:
+		var resp struct{ Items []*jellyfin.Artist }
+		err = j.getJSON(path, params, &resp)
+		sr.Artists = resp.Items
+	case jellyfin.TypeSong:
+		var resp struct{ Items []*jellyfin.Song }
+		err = j.getJSON(path, params, &resp)
+		sr.Songs = resp.Items
+	default:
+		err = fmt.Errorf("unsupported search type: %s", itemType)
+	}
+	return &sr, err
+}
@@ -0,0 +1,23 @@
+package jellyfin
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+var _ mediaprovider.SupportsPlaylistCoverUpload = (*jellyfinMediaProvider)(nil)
+
+// SetPlaylistCover uploads the image as the playlist's primary image.
+// Jellyfin expects the image data base64 encoded in the request body.
+func (j *jellyfinMediaProvider) SetPlaylistCover(playlistID string, image []byte) error {
+	contentType := http.DetectContentType(image)
+	if contentType != "image/jpeg" && contentType != "image/png" {
+		return errors.New("unsupported image format")
+	}
+	body := []byte(base64.StdEncoding.EncodeToString(image))
+	return j.post(context.Background(), "/Items/"+playlistID+"/Images/Primary", contentType, body)
+}
@@ -0,0 +1,145 @@
+package jellyfin
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+var (
+	_ mediaprovider.SupportsPlaylistSharing        = (*jellyfinMediaProvider)(nil)
+	_ mediaprovider.SupportsCollaborativePlaylists = (*jellyfinMediaProvider)(nil)
+)
+
+type playlistUserShare struct {
+	UserId  string `json:"UserId"`
+	CanEdit bool   `json:"CanEdit"`
+}
+
+type playlistAccess struct {
+	OpenAccess bool                `json:"OpenAccess"`
+	Shares     []playlistUserShare `json:"Shares"`
+}
+
+type serverUser struct {
+	Id   string `json:"Id"`
+	Name string `json:"Name"`
+}
+
+func (j *jellyfinMediaProvider) CanSharePlaylists() bool {
+	return j.supportsPlaylistAccess()
+}
+
+func (j *jellyfinMediaProvider) GetServerUsers() ([]*mediaprovider.ServerUser, error) {
+	creds, err := j.credentials()
+	if err != nil {
+		return nil, err
+	}
+	var users []serverUser
+	if err := j.getJSON("/Users", nil, &users); err != nil {
+		// listing all users may be restricted to admins;
+		// fall back to the users shown on the login screen
+		users = nil
+		if err := j.getJSON("/Users/Public", nil, &users); err != nil {
+			return nil, err
+		}
+	}
+	result := make([]*mediaprovider.ServerUser, 0, len(users))
+	for _, u := range users {
+		if u.Id != creds.userID {
+			result = append(result, &mediaprovider.ServerUser{ID: u.Id, Name: u.Name})
+		}
+	}
+	return result, nil
+}
+
+func (j *jellyfinMediaProvider) GetPlaylistShares(playlistID string) ([]mediaprovider.PlaylistShare, error) {
+	access, err := j.getPlaylistAccess(playlistID)
+	if err != nil {
+		return nil, err
+	}
+	shares := make([]mediaprovider.PlaylistShare, 0, len(access.Shares))
+	for _, s := range access.Shares {
+		shares = append(shares, mediaprovider.PlaylistShare{UserID: s.UserId, CanEdit: s.CanEdit})
+	}
+	return shares, nil
+}
+
+func (j *jellyfinMediaProvider) SetPlaylistShares(playlistID string, shares []mediaprovider.PlaylistShare) error {
+	users := make([]playlistUserShare, 0, len(shares))
+	for _, s := range shares {
+		users = append(users, playlistUserShare{UserId: s.UserID, CanEdit: s.CanEdit})
+	}
+	return j.postJSON(context.Background(), "/Playlists/"+playlistID, map[string]any{"Users": users})
+}
+
+func (j *jellyfinMediaProvider) CanMakeCollaborativePlaylist() bool {
+	return j.supportsPlaylistAccess()
+}
+
+// SetPlaylistCollaborative grants or revokes edit access
+// for all the users the playlist is shared with.
+func (j *jellyfinMediaProvider) SetPlaylistCollaborative(playlistID string, collaborative bool) error {
+	shares, err := j.GetPlaylistShares(playlistID)
+	if err != nil {
+		return err
+	}
+	for i := range shares {
+		shares[i].CanEdit = collaborative
+	}
+	return j.SetPlaylistShares(playlistID, shares)
+}
+
+// fillPlaylistAccess fills in the Public and Collaborative flags of the
+// playlist, which aren't included in the playlist item.
+func (j *jellyfinMediaProvider) fillPlaylistAccess(pl *mediaprovider.Playlist) {
+	if !j.supportsPlaylistAccess() {
+		return
+	}
+	access, err := j.getPlaylistAccess(pl.ID)
+	if err != nil {
+		log.Printf("error getting playlist access: %v", err)
+		return
+	}
+	pl.Public = access.OpenAccess
+	pl.Collaborative = slices.ContainsFunc(access.Shares, func(s playlistUserShare) bool { return s.CanEdit })
+}
+
+func (j *jellyfinMediaProvider) getPlaylistAccess(playlistID string) (*playlistAccess, error) {
+	var access playlistAccess
+	if err := j.getJSON("/Playlists/"+playlistID, nil, &access); err != nil {
+		return nil, err
+	}
+	return &access, nil
+}
+
+// supportsPlaylistAccess returns whether the server supports public
+// and shared playlists, which were added in Jellyfin 10.9.
+func (j *jellyfinMediaProvider) supportsPlaylistAccess() bool {
+	j.playlistAccessOnce.Do(func() {
+		var info struct {
+			Version string `json:"Version"`
+		}
+		if err := j.getJSON("/System/Info/Public", nil, &info); err == nil {
+			j.playlistAccessSupported = versionAtLeast(info.Version, 10, 9)
+		}
+	})
+	return j.playlistAccessSupported
+}
+
+func versionAtLeast(version string, major, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	maj, err1 := strconv.Atoi(parts[0])
+	mnr, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return maj > major || (maj == major && mnr >= minor)
+}
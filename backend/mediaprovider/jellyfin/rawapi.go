@@ -0,0 +1,110 @@
+package jellyfin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/dweymouth/go-jellyfin"
+)
+
+// Helpers for calling Jellyfin API endpoints not (yet) wrapped by go-jellyfin.
+
+type clientCredentials struct {
+	token    string
+	deviceID string
+	userID   string
+}
+
+// credentials returns the auth details of the logged in client, with the
+// current token if it has been renewed. The jellyfin.Client doesn't expose
+// them directly, but includes them in stream URLs.
+func (j *jellyfinMediaProvider) credentials() (clientCredentials, error) {
+	if t, ok := j.client.HTTPClient.Transport.(*reauthTransport); ok {
+		creds, err := clientCredentialsOf(j.client)
+		if err == nil {
+			creds.token = t.currentToken()
+		}
+		return creds, err
+	}
+	return clientCredentialsOf(j.client)
+}
+
+func clientCredentialsOf(client *jellyfin.Client) (clientCredentials, error) {
+	streamURL, err := client.GetStreamURL("")
+	if err != nil {
+		return clientCredentials{}, err
+	}
+	u, err := url.Parse(streamURL)
+	if err != nil {
+		return clientCredentials{}, err
+	}
+	q := u.Query()
+	creds := clientCredentials{token: q.Get("api_key"), deviceID: q.Get("DeviceId"), userID: q.Get("UserId")}
+	if creds.token == "" {
+		return clientCredentials{}, errors.New("not logged in")
+	}
+	return creds, nil
+}
+
+// getJSON makes an authenticated GET request and decodes the JSON response into v.
+func (j *jellyfinMediaProvider) getJSON(path string, params url.Values, v any) error {
+	u := j.client.BaseURL().JoinPath(path)
+	u.RawQuery = params.Encode()
+	resp, err := j.doRequest(context.Background(), http.MethodGet, u.String(), "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+	if err := json.NewDecoder(resp).Decode(v); err != nil {
+		return fmt.Errorf("decode json: %w", err)
+	}
+	return nil
+}
+
+// postJSON makes an authenticated POST request with body encoded as JSON.
+func (j *jellyfinMediaProvider) postJSON(ctx context.Context, path string, body any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return j.post(ctx, path, "application/json", b)
+}
+
+// post makes an authenticated POST request with the given body.
+func (j *jellyfinMediaProvider) post(ctx context.Context, path, contentType string, body []byte) error {
+	resp, err := j.doRequest(ctx, http.MethodPost, j.client.BaseURL().JoinPath(path).String(), contentType, body)
+	if err != nil {
+		return err
+	}
+	return resp.Close()
+}
+
+func (j *jellyfinMediaProvider) doRequest(ctx context.Context, method, url, contentType string, body []byte) (io.ReadCloser, error) {
+	creds, err := j.credentials()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("X-Emby-Token", creds.token)
+	resp, err := j.client.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: %s", method, req.URL.Path, resp.Status)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,165 @@
+package jellyfin
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/dweymouth/go-jellyfin"
+)
+
+// reauthTransport logs in again when the server rejects the access token
+// (e.g. it was revoked from the server's dashboard, or expired), and
+// retries the request with the new token.
+//
+// jellyfin.Client reads its token without synchronization when building
+// each request, so the client is never logged in again once in use.
+// Instead, logins use a separate client, and the transport replaces
+// the client's token in each request with the current one.
+type reauthTransport struct {
+	base  http.RoundTripper
+	login func() (string, error) // returns the new token
+
+	mu sync.Mutex // serializes logins
+
+	tokenMu sync.RWMutex
+	token   string
+}
+
+// enableReauth makes the client log in again with the given
+// credentials when its access token is rejected.
+func (j *JellyfinServer) enableReauth(user, pass string) {
+	creds, _ := clientCredentialsOf(&j.Client)
+	if t, ok := j.Client.HTTPClient.Transport.(*reauthTransport); ok {
+		t.mu.Lock()
+		t.login = newReauthLogin(&j.Client, t.base, user, pass)
+		t.setToken(creds.token)
+		t.mu.Unlock()
+		return
+	}
+	base := j.Client.HTTPClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	j.Client.HTTPClient.Transport = &reauthTransport{
+		base:  base,
+		login: newReauthLogin(&j.Client, base, user, pass),
+		token: creds.token,
+	}
+}
+
+// newReauthLogin returns a func which logs in with a new client
+// configured like cli, and returns the new access token.
+func newReauthLogin(cli *jellyfin.Client, base http.RoundTripper, user, pass string) func() (string, error) {
+	return func() (string, error) {
+		httpClient := &http.Client{Transport: base, Timeout: cli.HTTPClient.Timeout}
+		loginCli, err := jellyfin.NewClient(cli.BaseURL().String(), cli.ClientName, cli.ClientVersion, jellyfin.WithHTTPClient(httpClient))
+		if err != nil {
+			return "", err
+		}
+		if err := loginCli.Login(user, pass); err != nil {
+			return "", err
+		}
+		creds, err := clientCredentialsOf(loginCli)
+		return creds.token, err
+	}
+}
+
+// currentToken returns the access token to make requests with.
+func (t *reauthTransport) currentToken() string {
+	t.tokenMu.RLock()
+	defer t.tokenMu.RUnlock()
+	return t.token
+}
+
+func (t *reauthTransport) setToken(token string) {
+	t.tokenMu.Lock()
+	defer t.tokenMu.Unlock()
+	t.token = token
+}
+
+func (t *reauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	oldToken := t.currentToken()
+	if token := requestToken(req); token != "" && token != oldToken {
+		// built with the client's token from before a login
+		req = req.Clone(req.Context())
+		setRequestToken(req, oldToken)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized ||
+		strings.HasSuffix(strings.ToLower(req.URL.Path), "/users/authenticatebyname") {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil // can't resend the body
+	}
+
+	t.mu.Lock()
+	token := t.currentToken()
+	if token == oldToken {
+		// not already renewed by a concurrent request
+		log.Println("Jellyfin access token rejected, logging in again")
+		newToken, err := t.login()
+		if err != nil {
+			t.mu.Unlock()
+			log.Printf("error logging in to Jellyfin: %v", err)
+			return resp, nil
+		}
+		t.setToken(newToken)
+		token = newToken
+	}
+	t.mu.Unlock()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	setRequestToken(retry, token)
+	resp.Body.Close()
+	return t.base.RoundTrip(retry)
+}
+
+// requestToken returns the access token the request is made with, if any.
+func requestToken(req *http.Request) string {
+	if token := req.Header.Get("X-Emby-Token"); token != "" {
+		return token
+	}
+	return req.URL.Query().Get("api_key")
+}
+
+// setRequestToken replaces the access token of a request which has one.
+func setRequestToken(req *http.Request, token string) {
+	if req.Header.Get("X-Emby-Token") != "" {
+		req.Header.Set("X-Emby-Token", token)
+	}
+	if q := req.URL.Query(); q.Has("api_key") {
+		q.Set("api_key", token)
+		req.URL.RawQuery = q.Encode()
+	}
+}
+
+// withCurrentToken replaces the access token in a URL built by the client,
+// such as a stream URL, with the current one if it has been renewed.
+func withCurrentToken(client *jellyfin.Client, rawURL string) string {
+	t, ok := client.HTTPClient.Transport.(*reauthTransport)
+	if !ok {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	if token := t.currentToken(); q.Has("api_key") && q.Get("api_key") != token {
+		q.Set("api_key", token)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return rawURL
+}
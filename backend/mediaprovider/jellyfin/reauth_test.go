@@ -0,0 +1,78 @@
+package jellyfin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/dweymouth/go-jellyfin"
+)
+
+func TestReauthConcurrentRequests(t *testing.T) {
+	var mu sync.Mutex
+	logins := 0
+	validToken := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/System/Info/Public":
+			fmt.Fprint(w, `{}`)
+		case "/Users/authenticatebyname":
+			logins++
+			validToken = fmt.Sprintf("token%d", logins)
+			fmt.Fprintf(w, `{"AccessToken": %q, "User": {"Id": "user"}}`, validToken)
+		default:
+			if r.Header.Get("X-Emby-Token") != validToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer srv.Close()
+
+	cli, err := jellyfin.NewClient(srv.URL, "test", "1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &JellyfinServer{Client: *cli}
+	if resp := server.Login("user", "pass"); resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	mp := server.MediaProvider().(*jellyfinMediaProvider)
+
+	mu.Lock()
+	validToken = "revoked"
+	mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := mp.client.GetLyrics("item"); err != nil {
+				t.Errorf("request after token revoked: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if logins != 2 {
+		t.Errorf("logged in %d times, want 2", logins)
+	}
+	streamURL, err := mp.GetStreamURL("track", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(streamURL)
+	if token := u.Query().Get("api_key"); token != "token2" {
+		t.Errorf("stream URL has token %q, want token2", token)
+	}
+	if creds, _ := mp.credentials(); creds.token != "token2" {
+		t.Errorf("credentials have token %q, want token2", creds.token)
+	}
+}
@@ -11,8 +11,13 @@ import (
 	"github.com/dweymouth/supersonic/sharedutil"
 )
 
+var _ mediaprovider.SupportsSearchOptions = (*jellyfinMediaProvider)(nil)
+
 func (s *jellyfinMediaProvider) SearchAll(searchQuery string, maxResults int) ([]*mediaprovider.SearchResult, error) {
-	limit := maxResults / 3
+	return s.SearchWithOptions(searchQuery, mediaprovider.DefaultSearchOptions(maxResults))
+}
+
+func (s *jellyfinMediaProvider) SearchWithOptions(searchQuery string, opts mediaprovider.SearchOptions) ([]*mediaprovider.SearchResult, error) {
 	var wg sync.WaitGroup
 	var albums []*jellyfin.Album
 	var artists []*jellyfin.Artist
@@ -20,53 +25,63 @@ func (s *jellyfinMediaProvider) SearchAll(searchQuery string, maxResults int) ([
 	var genres []jellyfin.NameID
 	var playlists []*jellyfin.Playlist
 
-	wg.Add(1)
-	go func() {
-		albumResult, _ := s.client.Search(searchQuery, jellyfin.TypeAlbum, jellyfin.Paging{Limit: limit})
-		albums = albumResult.Albums
-		wg.Done()
-	}()
-	wg.Add(1)
-	go func() {
-		artistResult, _ := s.client.Search(searchQuery, jellyfin.TypeArtist, jellyfin.Paging{Limit: limit})
-		artists = artistResult.Artists
-		wg.Done()
-	}()
-	wg.Add(1)
-	go func() {
-		songResult, _ := s.client.Search(searchQuery, jellyfin.TypeSong, jellyfin.Paging{Limit: limit})
-		songs = songResult.Songs
-		wg.Done()
-	}()
+	if opts.Searches(mediaprovider.ContentTypeAlbum) {
+		wg.Add(1)
+		go func() {
+			albumResult, _ := s.search(searchQuery, jellyfin.TypeAlbum, jellyfin.Paging{Limit: opts.Limits[mediaprovider.ContentTypeAlbum]})
+			albums = albumResult.Albums
+			wg.Done()
+		}()
+	}
+	if opts.Searches(mediaprovider.ContentTypeArtist) {
+		wg.Add(1)
+		go func() {
+			artistResult, _ := s.search(searchQuery, jellyfin.TypeArtist, jellyfin.Paging{Limit: opts.Limits[mediaprovider.ContentTypeArtist]})
+			artists = artistResult.Artists
+			wg.Done()
+		}()
+	}
+	if opts.Searches(mediaprovider.ContentTypeTrack) {
+		wg.Add(1)
+		go func() {
+			songResult, _ := s.search(searchQuery, jellyfin.TypeSong, jellyfin.Paging{Limit: opts.Limits[mediaprovider.ContentTypeTrack]})
+			songs = songResult.Songs
+			wg.Done()
+		}()
+	}
 
 	querySanitized := strings.ToLower(sanitize.Accents(searchQuery))
 	queryLowerWords := strings.Fields(querySanitized)
 
-	wg.Add(1)
-	go func() {
-		p, e := s.client.GetPlaylists()
-		if e == nil {
-			playlists = sharedutil.FilterSlice(p, func(p *jellyfin.Playlist) bool {
-				return helpers.AllTermsMatch(strings.ToLower(sanitize.Accents(p.Name)), queryLowerWords)
-			})
-		}
-		wg.Done()
-	}()
-
-	wg.Add(1)
-	go func() {
-		g, e := s.client.GetGenres(jellyfin.Paging{})
-		if e == nil {
-			genres = sharedutil.FilterSlice(g, func(g jellyfin.NameID) bool {
-				return helpers.AllTermsMatch(strings.ToLower(sanitize.Accents(g.Name)), queryLowerWords)
-			})
-		}
-		wg.Done()
-	}()
+	if opts.Searches(mediaprovider.ContentTypePlaylist) {
+		wg.Add(1)
+		go func() {
+			p, e := s.client.GetPlaylists()
+			if e == nil {
+				playlists = sharedutil.FilterSlice(p, func(p *jellyfin.Playlist) bool {
+					return helpers.AllTermsMatch(strings.ToLower(sanitize.Accents(p.Name)), queryLowerWords)
+				})
+			}
+			wg.Done()
+		}()
+	}
+
+	if opts.Searches(mediaprovider.ContentTypeGenre) {
+		wg.Add(1)
+		go func() {
+			g, e := s.client.GetGenres(jellyfin.Paging{})
+			if e == nil {
+				genres = sharedutil.FilterSlice(g, func(g jellyfin.NameID) bool {
+					return helpers.AllTermsMatch(strings.ToLower(sanitize.Accents(g.Name)), queryLowerWords)
+				})
+			}
+			wg.Done()
+		}()
+	}
 
 	wg.Wait()
 
-	results := mergeResults(albums, artists, songs, playlists, genres)
+	results := helpers.LimitSearchResults(mergeResults(albums, artists, songs, playlists, genres), opts)
 	helpers.RankSearchResults(results, searchQuery, queryLowerWords)
 
 	return results, nil
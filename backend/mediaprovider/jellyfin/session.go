@@ -0,0 +1,249 @@
+package jellyfin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"golang.org/x/net/websocket"
+)
+
+var (
+	_ mediaprovider.SupportsRemoteSession    = (*jellyfinMediaProvider)(nil)
+	_ mediaprovider.SupportsPlaybackProgress = (*jellyfinMediaProvider)(nil)
+)
+
+// commands advertised to the server as supported by this session
+var sessionSupportedCommands = []string{"SetVolume"}
+
+type sessionMessage struct {
+	MessageType string          `json:"MessageType"`
+	Data        json.RawMessage `json:"Data,omitempty"`
+}
+
+type sessionPlayRequest struct {
+	ItemIds     []string `json:"ItemIds"`
+	StartIndex  int      `json:"StartIndex"`
+	PlayCommand string   `json:"PlayCommand"`
+}
+
+type sessionPlaystateRequest struct {
+	Command           string `json:"Command"`
+	SeekPositionTicks int64  `json:"SeekPositionTicks"`
+}
+
+type sessionGeneralCommand struct {
+	Name      string            `json:"Name"`
+	Arguments map[string]string `json:"Arguments"`
+}
+
+type sessionQueueItem struct {
+	Id             string `json:"Id"`
+	PlaylistItemId string `json:"PlaylistItemId"`
+}
+
+type sessionProgress struct {
+	ItemId          string             `json:"ItemId"`
+	PositionTicks   int64              `json:"PositionTicks"`
+	IsPaused        bool               `json:"IsPaused"`
+	VolumeLevel     int                `json:"VolumeLevel"`
+	CanSeek         bool               `json:"CanSeek"`
+	PlayMethod      string             `json:"PlayMethod"`
+	EventName       string             `json:"EventName"`
+	NowPlayingQueue []sessionQueueItem `json:"NowPlayingQueue"`
+}
+
+// RunRemoteSession connects to the server's session WebSocket so that
+// other Jellyfin clients can "play on" this app and control its playback.
+func (j *jellyfinMediaProvider) RunRemoteSession(ctx context.Context, onCommand func(mediaprovider.RemoteCommand)) error {
+	creds, err := j.credentials()
+	if err != nil {
+		return err
+	}
+	if err := j.postJSON(ctx, "/Sessions/Capabilities/Full", map[string]any{
+		"PlayableMediaTypes":   []string{"Audio"},
+		"SupportedCommands":    sessionSupportedCommands,
+		"SupportsMediaControl": true,
+	}); err != nil {
+		return fmt.Errorf("failed to register session capabilities: %w", err)
+	}
+
+	base := j.client.BaseURL()
+	wsURL := base.JoinPath("socket")
+	wsURL.Scheme = "ws"
+	if base.Scheme == "https" {
+		wsURL.Scheme = "wss"
+	}
+	wsURL.RawQuery = url.Values{"api_key": {creds.token}, "deviceId": {creds.deviceID}}.Encode()
+	config, err := websocket.NewConfig(wsURL.String(), base.String())
+	if err != nil {
+		return err
+	}
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect session socket: %w", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		conn.Close() // unblock the read loop below
+	}()
+
+	// the server may resend ForceKeepAlive; a single keepalive
+	// loop is started on the first one and retimed on the rest
+	keepAliveIntervals := make(chan time.Duration, 1)
+	keepAliveStarted := false
+
+	for {
+		var msg sessionMessage
+		if err := websocket.JSON.Receive(conn, &msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		switch msg.MessageType {
+		case "ForceKeepAlive":
+			// the server drops the session unless a KeepAlive
+			// is sent at least every Data seconds
+			var timeoutSecs int
+			if json.Unmarshal(msg.Data, &timeoutSecs) == nil && timeoutSecs > 0 {
+				if !keepAliveStarted {
+					keepAliveStarted = true
+					go sessionKeepAlive(ctx, conn, keepAliveIntervals)
+				}
+				select {
+				case <-keepAliveIntervals: // drop an interval not yet picked up
+				default:
+				}
+				keepAliveIntervals <- time.Duration(timeoutSecs) * time.Second / 2
+			}
+		case "Play", "Playstate", "GeneralCommand":
+			if cmd, ok := parseSessionCommand(msg); ok {
+				onCommand(cmd)
+			}
+		}
+	}
+}
+
+func (j *jellyfinMediaProvider) ReportSessionState(state mediaprovider.RemoteSessionState) error {
+	return j.ReportPlaybackProgress(state)
+}
+
+// ReportPlaybackProgress updates the server's playback session, which Jellyfin
+// uses for resume positions, the play state shown on other devices, and
+// play time statistics. The start and end of playback of each track
+// are reported by TrackBeganPlayback and TrackEndedPlayback.
+func (j *jellyfinMediaProvider) ReportPlaybackProgress(state mediaprovider.RemoteSessionState) error {
+	if state.TrackID == "" {
+		return nil // stopped playback is reported by TrackEndedPlayback
+	}
+	eventName := "timeupdate"
+	if state.Paused {
+		eventName = "pause"
+	}
+	progress := sessionProgress{
+		ItemId:          state.TrackID,
+		PositionTicks:   int64(state.Position * runTimeTicksPerSecond),
+		IsPaused:        state.Paused,
+		VolumeLevel:     state.Volume,
+		CanSeek:         true,
+		PlayMethod:      "DirectPlay",
+		EventName:       eventName,
+		NowPlayingQueue: make([]sessionQueueItem, 0, len(state.QueueIDs)),
+	}
+	for i, id := range state.QueueIDs {
+		progress.NowPlayingQueue = append(progress.NowPlayingQueue,
+			sessionQueueItem{Id: id, PlaylistItemId: "playlistItem" + strconv.Itoa(i)})
+	}
+	return j.postJSON(context.Background(), "/Sessions/Playing/Progress", progress)
+}
+
+func sessionKeepAlive(ctx context.Context, conn *websocket.Conn, intervals <-chan time.Duration) {
+	var interval time.Duration
+	select {
+	case <-ctx.Done():
+		return
+	case interval = <-intervals:
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case interval = <-intervals:
+			t.Reset(interval)
+		case <-t.C:
+			if websocket.JSON.Send(conn, sessionMessage{MessageType: "KeepAlive"}) != nil {
+				return
+			}
+		}
+	}
+}
+
+func parseSessionCommand(msg sessionMessage) (mediaprovider.RemoteCommand, bool) {
+	var cmd mediaprovider.RemoteCommand
+	switch msg.MessageType {
+	case "Play":
+		var req sessionPlayRequest
+		if json.Unmarshal(msg.Data, &req) != nil || len(req.ItemIds) == 0 {
+			return cmd, false
+		}
+		cmd.ItemIDs = req.ItemIds
+		cmd.StartIndex = req.StartIndex
+		switch req.PlayCommand {
+		case "PlayNext":
+			cmd.Type = mediaprovider.RemoteCommandPlayNext
+		case "PlayLast":
+			cmd.Type = mediaprovider.RemoteCommandPlayLast
+		default: // PlayNow, PlayShuffle, PlayInstantMix
+			cmd.Type = mediaprovider.RemoteCommandPlayNow
+		}
+	case "Playstate":
+		var req sessionPlaystateRequest
+		if json.Unmarshal(msg.Data, &req) != nil {
+			return cmd, false
+		}
+		switch req.Command {
+		case "Pause":
+			cmd.Type = mediaprovider.RemoteCommandPause
+		case "Unpause":
+			cmd.Type = mediaprovider.RemoteCommandUnpause
+		case "PlayPause":
+			cmd.Type = mediaprovider.RemoteCommandPlayPause
+		case "Stop":
+			cmd.Type = mediaprovider.RemoteCommandStop
+		case "NextTrack":
+			cmd.Type = mediaprovider.RemoteCommandNext
+		case "PreviousTrack":
+			cmd.Type = mediaprovider.RemoteCommandPrevious
+		case "Seek":
+			cmd.Type = mediaprovider.RemoteCommandSeek
+			cmd.Position = float64(req.SeekPositionTicks) / runTimeTicksPerSecond
+		default:
+			return cmd, false
+		}
+	case "GeneralCommand":
+		var req sessionGeneralCommand
+		if json.Unmarshal(msg.Data, &req) != nil || req.Name != "SetVolume" {
+			return cmd, false
+		}
+		vol, err := strconv.Atoi(req.Arguments["Volume"])
+		if err != nil {
+			return cmd, false
+		}
+		cmd.Type = mediaprovider.RemoteCommandSetVolume
+		cmd.Volume = vol
+	default:
+		return cmd, false
+	}
+	return cmd, true
+}
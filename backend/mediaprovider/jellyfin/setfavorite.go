@@ -0,0 +1,27 @@
+package jellyfin
+
+import (
+	"context"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
+)
+
+// Jellyfin doesn't allow bulk setting favorites. To not overwhelm
+// the server with requests, set favorite for only this many items at a time.
+const setFavoriteConcurrency = 5
+
+var _ mediaprovider.SupportsSetFavoriteProgress = (*jellyfinMediaProvider)(nil)
+
+func (j *jellyfinMediaProvider) SetFavoriteWithProgress(ctx context.Context, params mediaprovider.RatingFavoriteParameters, favorite bool, onProgress func(done, total int)) error {
+	var allIDs []string
+	allIDs = append(allIDs, params.AlbumIDs...)
+	allIDs = append(allIDs, params.ArtistIDs...)
+	allIDs = append(allIDs, params.TrackIDs...)
+	// cached artists include the favorite state of the artist and albums
+	defer j.artistCache.Clear()
+
+	return helpers.ForEachConcurrently(ctx, allIDs, setFavoriteConcurrency, func(id string) error {
+		return j.client.SetFavorite(id, favorite)
+	}, onProgress)
+}
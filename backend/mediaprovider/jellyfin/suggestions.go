@@ -0,0 +1,90 @@
+package jellyfin
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/dweymouth/go-jellyfin"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+// the Latest endpoint isn't paged; this many albums are fetched at once
+const latestAlbumsLimit = 100
+
+// fields needed to build a mediaprovider.Album
+const suggestionAlbumFields = "Genres,DateCreated,ChildCount,ParentId,Overview"
+
+// getSuggestedAlbums returns albums recommended for the user
+// by the server, based on their listening history.
+func (j *jellyfinMediaProvider) getSuggestedAlbums(offset, limit int) ([]*mediaprovider.Album, error) {
+	creds, err := j.credentials()
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{
+		"userId":                 {creds.userID},
+		"mediaType":              {"Audio"},
+		"type":                   {"MusicAlbum"},
+		"startIndex":             {strconv.Itoa(offset)},
+		"limit":                  {strconv.Itoa(limit)},
+		"enableTotalRecordCount": {"false"},
+		"fields":                 {suggestionAlbumFields},
+	}
+	var resp struct {
+		Items []*jellyfin.Album `json:"Items"`
+	}
+	if err := j.getJSON("/Items/Suggestions", params, &resp); err != nil {
+		return nil, err
+	}
+	return sharedutil.MapSlice(resp.Items, toAlbum), nil
+}
+
+// getLatestAlbums returns the albums most recently added to the library,
+// grouped by the server as shown on the Jellyfin home screen.
+func (j *jellyfinMediaProvider) getLatestAlbums(offset, limit int) ([]*mediaprovider.Album, error) {
+	if offset >= latestAlbumsLimit {
+		return nil, nil
+	}
+	creds, err := j.credentials()
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{
+		"IncludeItemTypes": {"MusicAlbum"},
+		"Limit":            {strconv.Itoa(latestAlbumsLimit)},
+		"GroupItems":       {"true"},
+		"Fields":           {suggestionAlbumFields},
+	}
+	if j.libraryID != "" {
+		params.Set("ParentId", j.libraryID)
+	}
+	var items []*jellyfin.Album
+	if err := j.getJSON("/Users/"+creds.userID+"/Items/Latest", params, &items); err != nil {
+		return nil, err
+	}
+	if offset >= len(items) {
+		return nil, nil
+	}
+	items = items[offset:min(offset+limit, len(items))]
+	return sharedutil.MapSlice(items, toAlbum), nil
+}
+
+func (j *jellyfinMediaProvider) GetSimilarAlbums(albumID string, limit int) ([]*mediaprovider.Album, error) {
+	creds, err := j.credentials()
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{
+		"userId": {creds.userID},
+		"limit":  {strconv.Itoa(limit)},
+		"fields": {suggestionAlbumFields},
+	}
+	var resp struct {
+		Items []*jellyfin.Album `json:"Items"`
+	}
+	if err := j.getJSON("/Items/"+albumID+"/Similar", params, &resp); err != nil {
+		return nil, err
+	}
+	return sharedutil.MapSlice(resp.Items, toAlbum), nil
+}
@@ -1,10 +1,16 @@
 package mediaprovider
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"image"
 	"io"
+	"net/http"
 	"net/url"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/deluan/sanitize"
 )
@@ -160,6 +166,97 @@ type Favorites struct {
 	Tracks  []*Track
 }
 
+// FavoritesSortOrder is the order favorites are returned in.
+type FavoritesSortOrder int
+
+const (
+	// the order returned by the server
+	FavoritesSortDefault FavoritesSortOrder = iota
+	// most recently favorited first. Items whose favorited date
+	// is not known (not all servers report it) come last.
+	FavoritesSortRecentlyFavorited
+	// by name, or title for tracks
+	FavoritesSortAlphabetical
+)
+
+var FavoritesSortOrders = []FavoritesSortOrder{
+	FavoritesSortDefault,
+	FavoritesSortRecentlyFavorited,
+	FavoritesSortAlphabetical,
+}
+
+func (s FavoritesSortOrder) String() string {
+	switch s {
+	case FavoritesSortRecentlyFavorited:
+		return "Recently favorited"
+	case FavoritesSortAlphabetical:
+		return "Alphabetical"
+	default:
+		return "Default"
+	}
+}
+
+// Sort sorts the favorites in place. Items which compare equal
+// keep their relative order.
+func (f *Favorites) Sort(order FavoritesSortOrder) {
+	switch order {
+	case FavoritesSortRecentlyFavorited:
+		sortByFavoritedAt(f.Albums, func(a *Album) time.Time { return a.FavoritedAt })
+		sortByFavoritedAt(f.Artists, func(a *Artist) time.Time { return a.FavoritedAt })
+		sortByFavoritedAt(f.Tracks, func(t *Track) time.Time { return t.FavoritedAt })
+	case FavoritesSortAlphabetical:
+		sort.SliceStable(f.Albums, func(i, j int) bool {
+			return alphabeticalLess(f.Albums[i].Name, f.Albums[j].Name)
+		})
+		sort.SliceStable(f.Artists, func(i, j int) bool {
+			return alphabeticalLess(f.Artists[i].SortKey(), f.Artists[j].SortKey())
+		})
+		sort.SliceStable(f.Tracks, func(i, j int) bool {
+			return alphabeticalLess(f.Tracks[i].Title, f.Tracks[j].Title)
+		})
+	}
+}
+
+// sortByFavoritedAt sorts items most recently favorited first,
+// followed by those whose favorited date is not known.
+func sortByFavoritedAt[T any](items []T, favoritedAt func(T) time.Time) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return favoritedAt(items[i]).After(favoritedAt(items[j]))
+	})
+}
+
+// alphabeticalLess compares the strings ignoring case and accents.
+func alphabeticalLess(a, b string) bool {
+	return sanitize.Accents(strings.ToLower(a)) < sanitize.Accents(strings.ToLower(b))
+}
+
+// TrackDownload is a track's file being downloaded.
+type TrackDownload struct {
+	io.ReadCloser
+	FileName string // as suggested by the server; may be empty
+	Size     int64  // size of the whole file, or -1 if unknown
+}
+
+// ItemsError reports the items which failed in an operation on several items.
+type ItemsError struct {
+	Errors map[string]error // by item ID
+}
+
+func (e *ItemsError) Error() string {
+	for id, err := range e.Errors {
+		if len(e.Errors) == 1 {
+			return fmt.Sprintf("item %s: %v", id, err)
+		}
+		return fmt.Sprintf("%d items failed, including %s: %v", len(e.Errors), id, err)
+	}
+	return "no items failed"
+}
+
+// ErrTokenAuthNotSupported is returned from Login when the server rejects
+// token authentication (e.g. Subsonic servers with LDAP users) and falling
+// back to legacy password authentication has not been allowed.
+var ErrTokenAuthNotSupported = errors.New("server does not support token authentication for this user")
+
 type LoginResponse struct {
 	Error       error
 	IsAuthError bool
@@ -199,10 +296,18 @@ type MediaProvider interface {
 
 	GetRandomTracks(genre string, count int) ([]*Track, error)
 
+	// GetRandomAlbums returns up to limit albums matching the filter, in random order.
+	GetRandomAlbums(limit int, filter AlbumFilter) ([]*Album, error)
+
+	// GetRandomArtists returns up to limit artists in random order.
+	GetRandomArtists(limit int) ([]*Artist, error)
+
 	GetSimilarTracks(artistID string, count int) ([]*Track, error)
 
 	GetSongRadio(trackID string, count int) ([]*Track, error)
 
+	GetSimilarAlbums(albumID string, limit int) ([]*Album, error)
+
 	ArtistSortOrders() []string
 
 	IterateArtists(sortOrder string, filter ArtistFilter) ArtistIterator
@@ -211,7 +316,10 @@ type MediaProvider interface {
 
 	GetGenres() ([]*Genre, error)
 
-	GetFavorites() (Favorites, error)
+	// GetFavorites returns the user's favorite albums, artists and tracks
+	// in the given order. If some could not be fetched, the ones that were
+	// are returned along with the error.
+	GetFavorites(sort FavoritesSortOrder) (Favorites, error)
 
 	GetStreamURL(trackID string, forceRaw bool) (string, error)
 
@@ -244,6 +352,8 @@ type MediaProvider interface {
 
 	TrackEndedPlayback(trackID string, positionSecs int, submission bool) error
 
+	// DownloadTrack returns a reader of the track's file. It may return
+	// a *TrackDownload, which also gives the file's name and size.
 	DownloadTrack(trackID string) (io.Reader, error)
 
 	RescanLibrary() error
@@ -253,6 +363,179 @@ type SupportsRating interface {
 	SetRating(params RatingFavoriteParameters, rating int) error
 }
 
+// StreamOptions are the transcoding options requested for a stream.
+type StreamOptions struct {
+	// ForceRaw requests the original file, ignoring the other options
+	ForceRaw bool
+	// Format to transcode to, e.g. "mp3" or "opus", "" for the server's default
+	Format string
+	// MaxBitRate in kbps, 0 for the server's default
+	MaxBitRate int
+}
+
+// SupportsStreamOptions is implemented by providers which can request
+// a transcoding format and max bit rate for a stream.
+type SupportsStreamOptions interface {
+	GetStreamURLWithOptions(trackID string, opts StreamOptions) (string, error)
+}
+
+// SupportsStreamHeaders is implemented by providers whose stream URLs
+// must be requested with extra HTTP headers, e.g. credentials
+// which should not be embedded in the URL.
+type SupportsStreamHeaders interface {
+	// StreamHeaders returns the headers to request the track's stream with, or nil.
+	StreamHeaders(trackID string) http.Header
+}
+
+// SupportsStreamPrefetch is implemented by providers that can prepare
+// a track's stream ahead of time (e.g. by starting a server-side transcode session)
+// so the player can transition into it without a gap.
+type SupportsStreamPrefetch interface {
+	// PrefetchStreamURL returns the stream URL for the track, like GetStreamURL,
+	// and starts warming up the stream on the server in the background.
+	PrefetchStreamURL(trackID string, forceRaw bool) (string, error)
+}
+
+// SupportsPlaylistTrackMove is implemented by providers that can move
+// a single playlist track without rewriting the whole playlist.
+type SupportsPlaylistTrackMove interface {
+	MovePlaylistTrack(playlistID string, fromIdx, toIdx int) error
+}
+
+// SupportsCollaborativePlaylists is implemented by providers
+// which can let users other than the owner edit a playlist.
+type SupportsCollaborativePlaylists interface {
+	// CanMakeCollaborativePlaylist returns false if the connected
+	// server version doesn't support collaborative playlists.
+	CanMakeCollaborativePlaylist() bool
+	// SetPlaylistCollaborative sets whether the users the playlist
+	// is shared with may edit it.
+	SetPlaylistCollaborative(playlistID string, collaborative bool) error
+}
+
+// SupportsPlaylistCoverUpload is implemented by providers
+// which can set a custom cover image for a playlist.
+type SupportsPlaylistCoverUpload interface {
+	// SetPlaylistCover uploads the image (JPEG or PNG) as the playlist's cover.
+	SetPlaylistCover(playlistID string, image []byte) error
+}
+
+// SupportsPlaylistSharing is implemented by providers that can share
+// a playlist with specific users of the server.
+type SupportsPlaylistSharing interface {
+	// CanSharePlaylists returns false if the connected server version
+	// doesn't support sharing playlists.
+	CanSharePlaylists() bool
+	// GetServerUsers returns the other users a playlist can be shared with.
+	GetServerUsers() ([]*ServerUser, error)
+	GetPlaylistShares(playlistID string) ([]PlaylistShare, error)
+	// SetPlaylistShares replaces the users the playlist is shared with.
+	SetPlaylistShares(playlistID string, shares []PlaylistShare) error
+}
+
+// SupportsArtistAppearsOn is implemented by providers whose GetArtist
+// returns only the albums by the artist, but which can also find
+// the albums by other artists that the artist appears on.
+type SupportsArtistAppearsOn interface {
+	GetArtistAppearsOn(artistID string) ([]*Album, error)
+}
+
+// SupportsGetArtistProgressive is implemented by providers which load
+// an artist's albums in batches, so a large discography can be shown
+// before it has fully loaded.
+type SupportsGetArtistProgressive interface {
+	// GetArtistProgressive is like GetArtist, but also calls onPartial
+	// with the artist and the albums loaded so far, after each batch
+	// except the last. Albums in partial results may lack some metadata.
+	GetArtistProgressive(artistID string, onPartial func(*ArtistWithAlbums)) (*ArtistWithAlbums, error)
+}
+
+// SupportsMusicLibraries is implemented by providers for servers which
+// can have several music libraries (or folders), to scope browsing to one.
+type SupportsMusicLibraries interface {
+	GetMusicLibraries() ([]*MusicLibrary, error)
+	// SetMusicLibrary scopes browsing and search to the library
+	// with the given ID, or to all libraries if "".
+	SetMusicLibrary(id string)
+}
+
+// SupportsInstantMix is implemented by providers that can generate
+// a mix of similar tracks seeded from any track, album, artist or genre.
+type SupportsInstantMix interface {
+	// GetInstantMix returns up to limit tracks similar to the
+	// track, album or artist with the given ID.
+	GetInstantMix(itemID string, limit int) ([]*Track, error)
+	// GetGenreInstantMix returns up to limit tracks for a mix of the genre.
+	GetGenreInstantMix(genre string, limit int) ([]*Track, error)
+}
+
+// SupportsFavoriteTrackIterator is implemented by providers which can
+// page through the favorite tracks, rather than fetching them all at once.
+// Subsonic's getStarred2 can't be paged, so it is not implemented there.
+type SupportsFavoriteTrackIterator interface {
+	IterateFavoriteTracks(sort FavoritesSortOrder) TrackIterator
+}
+
+// SupportsSetFavoriteProgress is implemented by providers which set the
+// favorite status of each item with a separate request, which can take a
+// long time for large selections.
+type SupportsSetFavoriteProgress interface {
+	// SetFavoriteWithProgress is like SetFavorite, but can be canceled by ctx,
+	// and calls onProgress (if non-nil) as items are done. If some items fail,
+	// the error is an *ItemsError.
+	SetFavoriteWithProgress(ctx context.Context, params RatingFavoriteParameters, favorite bool, onProgress func(done, total int)) error
+}
+
+// SupportsSetRatingProgress is implemented by providers which set the
+// rating of each track with a separate request.
+type SupportsSetRatingProgress interface {
+	// SetRatingWithProgress is like SetRating, but can be canceled by ctx,
+	// and calls onProgress (if non-nil) as tracks are done. If some tracks
+	// fail, the error is an *ItemsError.
+	SetRatingWithProgress(ctx context.Context, params RatingFavoriteParameters, rating int, onProgress func(done, total int)) error
+}
+
+// SupportsGenreTracks is implemented by providers which can page through
+// all the tracks of a genre, without fetching the genre's albums.
+type SupportsGenreTracks interface {
+	IterateGenreTracks(genre string) TrackIterator
+}
+
+// SupportsAlbumsByYear is implemented by providers which can page through
+// the albums released in a range of years, filtered on the server.
+type SupportsAlbumsByYear interface {
+	// IterateAlbumsByYear iterates the albums released from fromYear through
+	// toYear, oldest first, or newest first if fromYear > toYear.
+	IterateAlbumsByYear(fromYear, toYear int, filter AlbumFilter) AlbumIterator
+}
+
+// SupportsPlayedTracks is implemented by providers which can list the
+// tracks the user has played most, and most recently. Tracks which
+// haven't been played are not included.
+type SupportsPlayedTracks interface {
+	// GetMostPlayedTracks returns up to limit tracks, most played first.
+	GetMostPlayedTracks(limit int) ([]*Track, error)
+
+	// GetRecentlyPlayedTracks returns up to limit tracks,
+	// most recently played first.
+	GetRecentlyPlayedTracks(limit int) ([]*Track, error)
+}
+
+// SupportsSearchOptions is implemented by providers which can restrict
+// a search to some content types, with a limit for each.
+type SupportsSearchOptions interface {
+	// SearchWithOptions is like SearchAll, but returns only the content
+	// types included in the options, up to the limit of each.
+	SearchWithOptions(searchQuery string, opts SearchOptions) ([]*SearchResult, error)
+}
+
+// SupportsDownloadResume is implemented by providers which can resume
+// downloading a track's file partway through.
+type SupportsDownloadResume interface {
+	// DownloadTrackFrom is like DownloadTrack, but starts at the given byte offset.
+	DownloadTrackFrom(trackID string, offset int64) (io.Reader, error)
+}
+
 type SupportsSharing interface {
 	CreateShareURL(id string) (*url.URL, error)
 	CanShareArtists() bool
@@ -290,6 +573,91 @@ type JukeboxStatus struct {
 	PositionSeconds float64
 }
 
+// SupportsRemoteSession is implemented by providers whose server can relay
+// playback commands from other clients, so that the app appears as
+// a controllable "play on" target to the server's other apps.
+type SupportsRemoteSession interface {
+	// RunRemoteSession registers the app as a controllable session and
+	// delivers received commands to onCommand until ctx is cancelled
+	// or the connection is lost.
+	RunRemoteSession(ctx context.Context, onCommand func(RemoteCommand)) error
+
+	// ReportSessionState updates the playback state shown to other clients.
+	ReportSessionState(RemoteSessionState) error
+}
+
+type RemoteCommandType int
+
+const (
+	RemoteCommandPlayNow  RemoteCommandType = iota // replace the queue with ItemIDs, starting at StartIndex
+	RemoteCommandPlayNext                          // insert ItemIDs after the current track
+	RemoteCommandPlayLast                          // append ItemIDs to the queue
+	RemoteCommandPause
+	RemoteCommandUnpause
+	RemoteCommandPlayPause
+	RemoteCommandStop
+	RemoteCommandNext
+	RemoteCommandPrevious
+	RemoteCommandSeek      // seek to Position
+	RemoteCommandSetVolume // set Volume
+)
+
+type RemoteCommand struct {
+	Type       RemoteCommandType
+	ItemIDs    []string
+	StartIndex int
+	Position   float64 // seconds
+	Volume     int
+}
+
+type RemoteSessionState struct {
+	// ID of the playing track, or empty if stopped
+	TrackID  string
+	Position float64 // seconds
+	Paused   bool
+	Volume   int
+	QueueIDs []string
+}
+
+// SupportsPlaybackProgress is implemented by providers whose server keeps
+// track of the playback position of its clients, e.g. for resuming playback,
+// showing the play state on other devices, and play time statistics.
+type SupportsPlaybackProgress interface {
+	// ReportPlaybackProgress reports the state of the now playing track,
+	// between TrackBeganPlayback and TrackEndedPlayback.
+	ReportPlaybackProgress(RemoteSessionState) error
+}
+
+// ScanStatus is the status of a library scan on the server.
+type ScanStatus struct {
+	Scanning bool
+	Count    int64   // number of items scanned, or 0 if not known
+	Progress float64 // fraction (0-1) of the scan completed, or -1 if not known
+}
+
+// SupportsScanStatus is implemented by providers which can
+// report the progress of a library scan started by RescanLibrary.
+type SupportsScanStatus interface {
+	GetScanStatus() (ScanStatus, error)
+}
+
+// MetadataUpdate holds the new values of the editable metadata of a track or album.
+type MetadataUpdate struct {
+	Title  string
+	Year   int // 0 to clear
+	Genres []string
+}
+
+// SupportsMetadataEditing is implemented by providers whose server
+// can edit the metadata of library items.
+type SupportsMetadataEditing interface {
+	// CanEditMetadata returns false if the logged in
+	// user doesn't have permission to edit metadata.
+	CanEditMetadata() bool
+	UpdateTrackMetadata(trackID string, update MetadataUpdate) error
+	UpdateAlbumMetadata(albumID string, update MetadataUpdate) error
+}
+
 func genresMatch(filterGenres, albumGenres []string) bool {
 	for _, g1 := range filterGenres {
 		for _, g2 := range albumGenres {
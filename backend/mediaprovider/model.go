@@ -1,5 +1,13 @@
 package mediaprovider
 
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // Bit field flag for the ReleaseTypes property
 type ReleaseType = int32
 
@@ -34,34 +42,162 @@ type Album struct {
 	ID           string
 	CoverArtID   string
 	Name         string
+	SortName     string // set by the server, if different from Name
 	Duration     int
 	ArtistIDs    []string
 	ArtistNames  []string
-	Year         int
-	ReissueYear  int
+	Year         int // original release year, if known
+	ReissueYear  int // set if this is a later release of the album
 	Genres       []string
 	TrackCount   int
 	Favorite     bool
 	ReleaseTypes ReleaseTypes
+	// set if the album is flagged as a compilation in its tags,
+	// e.g. a "Various Artists" album
+	IsCompilation bool
+	// set if the album is marked explicit; not all servers report this
+	Explicit bool
+
+	MusicBrainzReleaseGroupID string
+
+	// Full dates, to the precision known by the server.
+	// OriginalReleaseDate is only set for reissues.
+	ReleaseDate         ItemDate
+	OriginalReleaseDate ItemDate
+
+	// when the album was added to the library and last played,
+	// or zero if not known (or never played)
+	CreatedAt  time.Time
+	LastPlayed time.Time
+	// when the album was favorited; zero if not a favorite or not known
+	FavoritedAt time.Time
+}
+
+// OriginalDate returns the date the album was first released, to the
+// best precision known, for sorting reissues alongside the originals.
+func (a *Album) OriginalDate() ItemDate {
+	if a.OriginalReleaseDate.Year > 0 {
+		return a.OriginalReleaseDate
+	}
+	if a.ReleaseDate.Year > 0 && (a.Year == 0 || a.ReleaseDate.Year == a.Year) {
+		return a.ReleaseDate
+	}
+	return ItemDate{Year: a.Year}
+}
+
+// ItemDate is a date which may be known only to the year or month.
+// Unknown components are zero.
+type ItemDate struct {
+	Year  int
+	Month int
+	Day   int
+}
+
+func (d ItemDate) IsZero() bool {
+	return d.Year == 0
+}
+
+// Compare returns -1, 0 or 1 if d is before, the same as, or after other.
+// Unknown components sort before known ones.
+func (d ItemDate) Compare(other ItemDate) int {
+	for _, c := range [][2]int{{d.Year, other.Year}, {d.Month, other.Month}, {d.Day, other.Day}} {
+		if c[0] < c[1] {
+			return -1
+		} else if c[0] > c[1] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// String formats the date as YYYY, YYYY-MM or YYYY-MM-DD, depending on precision.
+func (d ItemDate) String() string {
+	switch {
+	case d.Year == 0:
+		return ""
+	case d.Month == 0:
+		return fmt.Sprint(d.Year)
+	case d.Day == 0:
+		return fmt.Sprintf("%d-%02d", d.Year, d.Month)
+	default:
+		return fmt.Sprintf("%d-%02d-%02d", d.Year, d.Month, d.Day)
+	}
+}
+
+// SortKey returns the name the album should be sorted by.
+func (a *Album) SortKey() string {
+	if a.SortName != "" {
+		return a.SortName
+	}
+	return a.Name
 }
 
 type AlbumWithTracks struct {
 	Album
 	Tracks []*Track
+
+	// Disc subtitles by disc number, if any (e.g. the
+	// named discs of a box set). Not every disc need have one.
+	DiscTitles map[int]string
+}
+
+type Disc struct {
+	Number int
+	Title  string // may be empty
+	Tracks []*Track
+}
+
+// Discs groups the album's tracks by disc number, in ascending disc order.
+func (a *AlbumWithTracks) Discs() []Disc {
+	var discs []Disc
+	idxByNumber := make(map[int]int)
+	for _, tr := range a.Tracks {
+		i, ok := idxByNumber[tr.DiscNumber]
+		if !ok {
+			i = len(discs)
+			idxByNumber[tr.DiscNumber] = i
+			discs = append(discs, Disc{Number: tr.DiscNumber, Title: a.DiscTitles[tr.DiscNumber]})
+		}
+		discs[i].Tracks = append(discs[i].Tracks, tr)
+	}
+	sort.SliceStable(discs, func(i, j int) bool { return discs[i].Number < discs[j].Number })
+	return discs
+}
+
+// DiscCount returns the number of distinct discs among the album's tracks.
+func (a *AlbumWithTracks) DiscCount() int {
+	discs := make(map[int]bool)
+	for _, tr := range a.Tracks {
+		discs[tr.DiscNumber] = true
+	}
+	return len(discs)
 }
 
 type AlbumInfo struct {
 	Notes         string
 	LastFmUrl     string
 	MusicBrainzID string
+	// external services the notes were fetched from, to be credited
+	Sources []string
 }
 
 type Artist struct {
 	ID         string
 	CoverArtID string
 	Name       string
+	SortName   string // set by the server, e.g. "Beatles, The"
 	Favorite   bool
 	AlbumCount int
+	// when the artist was favorited; zero if not a favorite or not known
+	FavoritedAt time.Time
+}
+
+// SortKey returns the name the artist should be sorted by.
+func (a *Artist) SortKey() string {
+	if a.SortName != "" {
+		return a.SortName
+	}
+	return a.Name
 }
 
 type ArtistWithAlbums struct {
@@ -69,11 +205,28 @@ type ArtistWithAlbums struct {
 	Albums []*Album
 }
 
+// Discography is an artist's albums, grouped by kind of release.
+type Discography struct {
+	Albums        []*Album
+	EPsAndSingles []*Album
+	Compilations  []*Album
+	// albums by other artists which include tracks by the artist
+	AppearsOn []*Album
+}
+
 type ArtistInfo struct {
 	Biography      string
 	LastFMUrl      string
 	ImageURL       string
 	SimilarArtists []*Artist
+	Tags           []string
+	// external services the info was supplemented from, to be credited
+	Sources []string
+}
+
+type MusicLibrary struct {
+	ID   string
+	Name string
 }
 
 type Genre struct {
@@ -90,7 +243,7 @@ type Track struct {
 	Duration    int
 	TrackNumber int
 	DiscNumber  int
-	Genre       string
+	Genres      []string
 	ArtistIDs   []string
 	ArtistNames []string
 	Album       string
@@ -102,7 +255,49 @@ type Track struct {
 	PlayCount   int
 	FilePath    string
 	BitRate     int
+	Codec       string // e.g. "flac", "mp3"; may be the container format
+	SampleRate  int    // Hz, 0 if unknown
+	BitDepth    int    // 0 if unknown or not applicable (lossy formats)
+	Channels    int
 	Comment     string
+	Composer    string
+	BPM         int
+	ReplayGain  *ReplayGainInfo // nil if not reported by the server
+	CreatedAt   time.Time       // when added to the library; zero if not known
+	LastPlayed  time.Time       // zero if not known or never played
+	FavoritedAt time.Time       // zero if not a favorite or not known
+	Explicit    bool            // marked explicit; not all servers report this
+
+	MusicBrainzRecordingID string
+	MusicBrainzReleaseID   string
+}
+
+// AudioFormat returns a short description of the track's audio format,
+// e.g. "FLAC 24/96" or "MP3 320 kbps", or "" if the codec is unknown.
+func (t *Track) AudioFormat() string {
+	if t.Codec == "" {
+		return ""
+	}
+	codec := t.Codec
+	if len(codec) <= 4 && !strings.EqualFold(codec, "opus") {
+		codec = strings.ToUpper(codec)
+	}
+	switch {
+	case t.BitDepth > 0 && t.SampleRate > 0:
+		return fmt.Sprintf("%s %d/%s", codec, t.BitDepth, strconv.FormatFloat(float64(t.SampleRate)/1000, 'f', -1, 64))
+	case t.BitRate > 0:
+		return fmt.Sprintf("%s %d kbps", codec, t.BitRate)
+	}
+	return codec
+}
+
+// ReplayGain metadata of a track. Gains are in dB,
+// peaks are linear sample values where 1.0 is full scale.
+type ReplayGainInfo struct {
+	TrackGain float64
+	AlbumGain float64
+	TrackPeak float64
+	AlbumPeak float64
 }
 
 type Playlist struct {
@@ -111,9 +306,17 @@ type Playlist struct {
 	Name        string
 	Description string
 	Public      bool
-	Owner       string
-	Duration    int
-	TrackCount  int
+	// Users the playlist is shared with, or all users
+	// if it is public, may edit it
+	Collaborative bool
+	Owner         string
+	Duration      int
+	TrackCount    int
+}
+
+// CanEdit returns whether the user may edit the playlist's tracks.
+func (p *Playlist) CanEdit(user string) bool {
+	return p.Owner == user || p.Collaborative
 }
 
 type PlaylistWithTracks struct {
@@ -121,6 +324,16 @@ type PlaylistWithTracks struct {
 	Tracks []*Track
 }
 
+type ServerUser struct {
+	ID   string
+	Name string
+}
+
+type PlaylistShare struct {
+	UserID  string
+	CanEdit bool
+}
+
 type Lyrics struct {
 	Title  string
 	Artist string
@@ -248,3 +461,29 @@ type SearchResult struct {
 	// Unset for ContentTypes Artist, Playlist, and Genre
 	ArtistName string
 }
+
+// SearchOptions restricts a search to some content types,
+// with the max number of results of each.
+type SearchOptions struct {
+	// Limits is the max number of results by content type.
+	// Content types without a positive limit are not searched.
+	Limits map[ContentType]int
+}
+
+// DefaultSearchOptions returns the options used by SearchAll: maxResults
+// split evenly between artists, albums and tracks, plus up to
+// maxResults matching playlists and genres.
+func DefaultSearchOptions(maxResults int) SearchOptions {
+	return SearchOptions{Limits: map[ContentType]int{
+		ContentTypeArtist:   maxResults / 3,
+		ContentTypeAlbum:    maxResults / 3,
+		ContentTypeTrack:    maxResults / 3,
+		ContentTypePlaylist: maxResults,
+		ContentTypeGenre:    maxResults,
+	}}
+}
+
+// Searches returns true if the options include the content type.
+func (o SearchOptions) Searches(t ContentType) bool {
+	return o.Limits[t] > 0
+}
@@ -0,0 +1,346 @@
+package netshare
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fileInfo describes a file of the share. Path is relative
+// to the root of the share, with forward slashes.
+type fileInfo struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// fileSystem is a share holding music files.
+type fileSystem interface {
+	// ping checks that the share is reachable and the credentials valid.
+	ping(ctx context.Context) error
+
+	// walk lists all the files of the share, recursively.
+	walk(ctx context.Context) ([]fileInfo, error)
+
+	// readAt reads n bytes of the file at offset off,
+	// returning fewer bytes only at the end of the file.
+	readAt(path string, off, n int64) ([]byte, error)
+
+	// open opens the file for reading.
+	open(path string) (io.ReadCloser, error)
+
+	// streamURL returns a URL of the file which mpv can play.
+	streamURL(path string) string
+
+	// streamHeaders returns the HTTP headers to request the streamURL with, or nil.
+	streamHeaders() http.Header
+}
+
+var errAuth = errors.New("share rejected the credentials")
+
+// dirFS is a folder of the local file system, e.g. where
+// an SMB or NFS share is mounted by the operating system.
+type dirFS struct {
+	root string
+}
+
+func (d *dirFS) ping(context.Context) error {
+	st, err := os.Stat(d.root)
+	if err != nil {
+		return err
+	}
+	if !st.IsDir() {
+		return fmt.Errorf("%s is not a folder", d.root)
+	}
+	return nil
+}
+
+func (d *dirFS) walk(ctx context.Context) ([]fileInfo, error) {
+	var files []fileInfo
+	err := filepath.WalkDir(d.root, func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // skip unreadable folders
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if e.IsDir() {
+			if strings.HasPrefix(e.Name(), ".") && p != d.root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(d.root, p)
+		files = append(files, fileInfo{Path: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+func (d *dirFS) readAt(p string, off, n int64) ([]byte, error) {
+	f, err := os.Open(d.fullPath(p))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	buf := make([]byte, n)
+	read, err := f.ReadAt(buf, off)
+	if err == io.EOF {
+		err = nil
+	}
+	return buf[:read], err
+}
+
+func (d *dirFS) open(p string) (io.ReadCloser, error) {
+	return os.Open(d.fullPath(p))
+}
+
+func (d *dirFS) streamURL(p string) string {
+	return d.fullPath(p)
+}
+
+func (d *dirFS) streamHeaders() http.Header {
+	return nil
+}
+
+func (d *dirFS) fullPath(p string) string {
+	return filepath.Join(d.root, filepath.FromSlash(p))
+}
+
+// webdavFS is a folder on a WebDAV server.
+type webdavFS struct {
+	base     *url.URL // with a trailing slash
+	username string
+	password string
+	// client for listing and tag reads, with a timeout
+	client *http.Client
+	// client for downloads, without a timeout as files may be large
+	downloadClient *http.Client
+}
+
+func newWebDAVFS(rawURL, username, password string, transport http.RoundTripper) (*webdavFS, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "webdav", "dav":
+		u.Scheme = "http"
+	case "webdavs", "davs":
+		u.Scheme = "https"
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	u.User = nil
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return &webdavFS{
+		base:           u,
+		username:       username,
+		password:       password,
+		client:         &http.Client{Timeout: 30 * time.Second, Transport: transport},
+		downloadClient: &http.Client{Transport: transport},
+	}, nil
+}
+
+func (w *webdavFS) ping(ctx context.Context) error {
+	_, err := w.propfind(ctx, w.base.Path)
+	return err
+}
+
+func (w *webdavFS) walk(ctx context.Context) ([]fileInfo, error) {
+	var files []fileInfo
+	dirs := []string{w.base.Path}
+	for len(dirs) > 0 {
+		dir := dirs[0]
+		dirs = dirs[1:]
+		entries, err := w.propfind(ctx, dir)
+		if err != nil {
+			if ctx.Err() != nil || dir == w.base.Path {
+				return nil, err
+			}
+			continue // skip unreadable folders
+		}
+		for _, e := range entries {
+			if e.dir {
+				if e.path != dir && !strings.HasPrefix(path.Base(e.path), ".") {
+					dirs = append(dirs, e.path)
+				}
+				continue
+			}
+			files = append(files, fileInfo{
+				Path:    strings.TrimPrefix(e.path, w.base.Path),
+				Size:    e.size,
+				ModTime: e.modTime,
+			})
+		}
+	}
+	return files, nil
+}
+
+type davEntry struct {
+	path    string // unescaped URL path
+	dir     bool
+	size    int64
+	modTime time.Time
+}
+
+type davMultistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				ContentLength int64  `xml:"getcontentlength"`
+				LastModified  string `xml:"getlastmodified"`
+				ResourceType  struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/><d:getcontentlength/><d:getlastmodified/></d:prop></d:propfind>`
+
+// propfind lists the folder at the URL path dir.
+func (w *webdavFS) propfind(ctx context.Context, dir string) ([]davEntry, error) {
+	u := *w.base
+	u.Path = dir
+	req, err := http.NewRequestWithContext(ctx, "PROPFIND", u.String(), strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", "1")
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	w.authorize(req)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, errAuth
+	case resp.StatusCode != http.StatusMultiStatus:
+		return nil, fmt.Errorf("WebDAV listing failed: %s", resp.Status)
+	}
+	var ms davMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("failed to decode WebDAV listing: %w", err)
+	}
+	var entries []davEntry
+	for _, r := range ms.Responses {
+		href, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			e := davEntry{
+				path: href.Path,
+				dir:  ps.Prop.ResourceType.Collection != nil,
+				size: ps.Prop.ContentLength,
+			}
+			e.modTime, _ = http.ParseTime(ps.Prop.LastModified)
+			if e.dir && !strings.HasSuffix(e.path, "/") {
+				e.path += "/"
+			}
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (w *webdavFS) readAt(p string, off, n int64) ([]byte, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, w.fileURL(p).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+n-1))
+	w.authorize(req)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK: // server ignored the range
+		if _, err := io.CopyN(io.Discard, resp.Body, off); err != nil {
+			return nil, err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		return nil, nil // past the end of the file
+	default:
+		return nil, fmt.Errorf("WebDAV read failed: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, n))
+}
+
+func (w *webdavFS) open(p string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, w.fileURL(p).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	w.authorize(req)
+	resp, err := w.downloadClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("WebDAV download failed: %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (w *webdavFS) streamURL(p string) string {
+	return w.fileURL(p).String()
+}
+
+// streamHeaders returns the credentials as an Authorization header,
+// rather than embedding them in the stream URL, where they would be
+// logged and shown wherever the URL is.
+func (w *webdavFS) streamHeaders() http.Header {
+	if w.username == "" {
+		return nil
+	}
+	req := &http.Request{Header: http.Header{}}
+	w.authorize(req)
+	return req.Header
+}
+
+func (w *webdavFS) fileURL(p string) *url.URL {
+	u := *w.base
+	u.Path = w.base.Path + p
+	return &u
+}
+
+func (w *webdavFS) authorize(req *http.Request) {
+	if w.username != "" {
+		req.SetBasicAuth(w.username, w.password)
+	}
+}
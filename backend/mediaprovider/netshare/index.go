@@ -0,0 +1,140 @@
+package netshare
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/demo"
+)
+
+// bump to reindex all files after changing how tags are read
+const indexVersion = 2
+
+// index is the on-disk index of the audio files of a share.
+type index struct {
+	Version int
+	Entries []*indexEntry
+
+	byPath map[string]*indexEntry
+}
+
+type indexEntry struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Cover   string    `json:",omitempty"` // path of the folder's cover image
+	Tags    *fileTags `json:",omitempty"` // nil if the file has no supported tags
+}
+
+// loadIndex reads the index from the file, returning
+// an empty index if it doesn't exist or is outdated.
+func loadIndex(file string) *index {
+	idx := &index{Version: indexVersion}
+	if b, err := os.ReadFile(file); err == nil {
+		var saved index
+		if json.Unmarshal(b, &saved) == nil && saved.Version == indexVersion {
+			idx = &saved
+		}
+	}
+	return idx
+}
+
+func (i *index) save(file string) error {
+	b, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// entry returns the indexed entry of the file, or nil
+// if it isn't indexed or has changed since.
+func (i *index) entry(f fileInfo) *indexEntry {
+	if i.byPath == nil {
+		i.byPath = make(map[string]*indexEntry, len(i.Entries))
+		for _, e := range i.Entries {
+			i.byPath[e.Path] = e
+		}
+	}
+	if e, ok := i.byPath[f.Path]; ok && e.Size == f.Size && e.ModTime.Equal(f.ModTime) {
+		return e
+	}
+	return nil
+}
+
+// tracks returns the tracks of the indexed files.
+func (i *index) tracks() []*demo.LibraryTrack {
+	tracks := make([]*demo.LibraryTrack, len(i.Entries))
+	for n, e := range i.Entries {
+		tracks[n] = e.track()
+	}
+	return tracks
+}
+
+// track returns the track of the file, falling back to metadata from the
+// path ("Artist/Album/01 Title.ext") for anything missing from the tags.
+func (e *indexEntry) track() *demo.LibraryTrack {
+	ext := path.Ext(e.Path)
+	dir := path.Dir(e.Path)
+	tr := &mediaprovider.Track{
+		ID:         trackID(e.Path),
+		CoverArtID: e.Cover,
+		FilePath:   e.Path,
+		Size:       e.Size,
+		Codec:      strings.ToLower(strings.TrimPrefix(ext, ".")),
+		CreatedAt:  e.ModTime,
+		DiscNumber: 1,
+	}
+	lt := &demo.LibraryTrack{Track: tr}
+	if t := e.Tags; t != nil {
+		tr.Title = t.Title
+		tr.ArtistNames = t.Artists
+		tr.Album = t.Album
+		tr.Genres = t.Genres
+		tr.Composer = t.Composer
+		tr.TrackNumber = t.Track
+		tr.Year = t.Year
+		tr.Duration = int(t.Duration + 0.5)
+		tr.BitRate = t.BitRate
+		tr.SampleRate = t.SampleRate
+		tr.BitDepth = t.BitDepth
+		tr.Channels = t.Channels
+		if t.Disc > 0 {
+			tr.DiscNumber = t.Disc
+		}
+		lt.AlbumArtist = t.AlbumArtist
+		lt.IsCompilation = t.Compilation
+		tr.Explicit = t.Explicit
+	}
+	if tr.Title == "" {
+		tr.Title = strings.TrimSuffix(path.Base(e.Path), ext)
+		if n := leadingInt(tr.Title); n > 0 {
+			tr.TrackNumber = n
+			if t := strings.TrimLeft(tr.Title, "0123456789 .-_"); t != "" {
+				tr.Title = t
+			}
+		}
+	}
+	if tr.Album == "" && dir != "." {
+		tr.Album = path.Base(dir)
+	}
+	if len(tr.ArtistNames) == 0 && path.Dir(dir) != "." {
+		tr.ArtistNames = []string{path.Base(path.Dir(dir))}
+	}
+	if tr.BitRate == 0 && tr.Duration > 0 {
+		tr.BitRate = int(e.Size * 8 / 1000 / int64(tr.Duration))
+	}
+	return lt
+}
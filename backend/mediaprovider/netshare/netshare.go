@@ -0,0 +1,217 @@
+// Package netshare implements a MediaProvider which reads music files
+// directly from a network share, for NAS users who run no music server:
+// a WebDAV folder, or an SMB/NFS share mounted by the operating system.
+// The app indexes the files' tags itself, keeping the index on disk so
+// that only new and changed files are read when rescanning.
+package netshare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/demo"
+)
+
+// number of files whose tags are read at once, to hide network latency
+const scanWorkers = 8
+
+var audioExtensions = map[string]bool{
+	".mp3": true, ".flac": true, ".ogg": true, ".oga": true, ".opus": true, ".m4a": true,
+	".aac": true, ".wav": true, ".aiff": true, ".aif": true, ".wma": true, ".ape": true,
+	".wv": true, ".mpc": true, ".dsf": true,
+}
+
+// cover image file names, in order of preference
+var coverNames = []string{"cover", "folder", "front", "album"}
+
+// Server is a network share of music files.
+type Server struct {
+	// URL of a WebDAV folder (http(s):// or webdav(s)://), or the path
+	// of a local folder where the share is mounted.
+	URL string
+	// File to keep the index of the share's tracks in.
+	IndexPath string
+	// Transport for requests to a WebDAV server, or nil for the default.
+	Transport http.RoundTripper
+
+	mu sync.Mutex
+	fs fileSystem
+	mp mediaprovider.MediaProvider
+}
+
+var _ mediaprovider.Server = (*Server)(nil)
+
+func (s *Server) Login(username, password string) mediaprovider.LoginResponse {
+	var fsys fileSystem
+	if strings.Contains(s.URL, "://") {
+		w, err := newWebDAVFS(s.URL, username, password, s.Transport)
+		if err != nil {
+			return mediaprovider.LoginResponse{Error: err}
+		}
+		fsys = w
+	} else {
+		fsys = &dirFS{root: s.URL}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := fsys.ping(ctx); err != nil {
+		return mediaprovider.LoginResponse{Error: err, IsAuthError: errors.Is(err, errAuth)}
+	}
+	s.mu.Lock()
+	s.fs = fsys
+	s.mu.Unlock()
+	return mediaprovider.LoginResponse{}
+}
+
+// MediaProvider returns the provider serving the share's tracks from
+// the index, and starts a rescan of the share in the background.
+func (s *Server) MediaProvider() mediaprovider.MediaProvider {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mp == nil {
+		sh := &share{fs: s.fs, indexPath: s.IndexPath}
+		sh.idx = loadIndex(s.IndexPath)
+		s.mp = demo.NewLibraryProvider(sh.idx.tracks(), sh)
+		s.mp.RescanLibrary()
+	}
+	return s.mp
+}
+
+// share implements demo.LibraryFiles for the files of a fileSystem.
+type share struct {
+	fs        fileSystem
+	indexPath string
+
+	mu  sync.Mutex
+	idx *index
+}
+
+var _ demo.LibraryFiles = (*share)(nil)
+
+// Scan lists the share's files, reading the tags of those
+// not already indexed or changed since, and saves the index.
+func (s *share) Scan() ([]*demo.LibraryTrack, error) {
+	files, err := s.fs.walk(context.Background())
+	if err != nil {
+		log.Printf("error scanning share: %v", err)
+		return nil, err
+	}
+	s.mu.Lock()
+	old := s.idx
+	s.mu.Unlock()
+
+	covers := findCovers(files)
+	newIdx := &index{Version: indexVersion}
+	var toRead []*indexEntry
+	for _, f := range files {
+		if !audioExtensions[strings.ToLower(path.Ext(f.Path))] {
+			continue
+		}
+		e := old.entry(f)
+		if e == nil {
+			e = &indexEntry{Path: f.Path, Size: f.Size, ModTime: f.ModTime}
+			toRead = append(toRead, e)
+		}
+		e.Cover = covers[path.Dir(f.Path)]
+		newIdx.Entries = append(newIdx.Entries, e)
+	}
+	s.readTags(toRead)
+
+	s.mu.Lock()
+	s.idx = newIdx
+	s.mu.Unlock()
+	if err := newIdx.save(s.indexPath); err != nil {
+		log.Printf("error saving share index: %v", err)
+	}
+	return newIdx.tracks(), nil
+}
+
+func (s *share) readTags(entries []*indexEntry) {
+	work := make(chan *indexEntry)
+	var wg sync.WaitGroup
+	for i := 0; i < scanWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range work {
+				readAt := func(off, n int64) ([]byte, error) { return s.fs.readAt(e.Path, off, n) }
+				tags, err := readTags(readAt, e.Size)
+				if err != nil && !errors.Is(err, errNoTags) {
+					log.Printf("error reading tags of %s: %v", e.Path, err)
+				}
+				e.Tags = tags
+			}
+		}()
+	}
+	for _, e := range entries {
+		work <- e
+	}
+	close(work)
+	wg.Wait()
+}
+
+func (s *share) StreamURL(track *mediaprovider.Track) (string, error) {
+	return s.fs.streamURL(track.FilePath), nil
+}
+
+func (s *share) StreamHeaders(*mediaprovider.Track) http.Header {
+	return s.fs.streamHeaders()
+}
+
+func (s *share) Open(track *mediaprovider.Track) (io.ReadCloser, error) {
+	return s.fs.open(track.FilePath)
+}
+
+// CoverArt returns the image of the cover file whose path is the ID.
+func (s *share) CoverArt(coverArtID string) (image.Image, error) {
+	if coverArtID == "" {
+		return nil, errors.New("no cover")
+	}
+	r, err := s.fs.open(coverArtID)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// findCovers returns the path of the cover image file of each folder.
+func findCovers(files []fileInfo) map[string]string {
+	covers := make(map[string]string)
+	rank := make(map[string]int)
+	for _, f := range files {
+		ext := strings.ToLower(path.Ext(f.Path))
+		if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSuffix(path.Base(f.Path), path.Ext(f.Path)))
+		for i, c := range coverNames {
+			dir := path.Dir(f.Path)
+			if name == c && (covers[dir] == "" || i < rank[dir]) {
+				covers[dir], rank[dir] = f.Path, i
+			}
+		}
+	}
+	return covers
+}
+
+// trackID returns a stable ID of the file at the path in the share.
+func trackID(p string) string {
+	h := fnv.New64a()
+	h.Write([]byte(p))
+	return fmt.Sprintf("tr-%x", h.Sum64())
+}
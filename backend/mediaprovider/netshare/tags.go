@@ -0,0 +1,334 @@
+package netshare
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// fileTags is the metadata read from an audio file. Only ID3v2 (MP3)
+// and FLAC tags are read; other formats are described by their path.
+type fileTags struct {
+	Title       string   `json:",omitempty"`
+	Artists     []string `json:",omitempty"`
+	AlbumArtist string   `json:",omitempty"`
+	Album       string   `json:",omitempty"`
+	Genres      []string `json:",omitempty"`
+	Composer    string   `json:",omitempty"`
+	Track       int      `json:",omitempty"`
+	Disc        int      `json:",omitempty"`
+	Year        int      `json:",omitempty"`
+	Compilation bool     `json:",omitempty"`
+	Explicit    bool     `json:",omitempty"` // iTunes parental advisory tag
+
+	Duration   float64 `json:",omitempty"` // seconds
+	BitRate    int     `json:",omitempty"` // kbps
+	SampleRate int     `json:",omitempty"`
+	BitDepth   int     `json:",omitempty"`
+	Channels   int     `json:",omitempty"`
+}
+
+// readAtFunc reads n bytes at offset off of a file,
+// returning fewer bytes only at the end of the file.
+type readAtFunc func(off, n int64) ([]byte, error)
+
+const maxID3Read = 1 << 20
+
+var errNoTags = errors.New("no supported tags")
+
+// readTags reads the tags of the audio file of the given size.
+func readTags(readAt readAtFunc, size int64) (*fileTags, error) {
+	head, err := readAt(0, 10)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return readFlacTags(readAt)
+	case bytes.HasPrefix(head, []byte("ID3")) && len(head) == 10:
+		return readID3Tags(readAt, head, size)
+	}
+	return nil, errNoTags
+}
+
+// readFlacTags reads the STREAMINFO and VORBIS_COMMENT metadata blocks.
+func readFlacTags(readAt readAtFunc) (*fileTags, error) {
+	t := &fileTags{}
+	off := int64(4)
+	for {
+		hdr, err := readAt(off, 4)
+		if err != nil || len(hdr) < 4 {
+			return t, err
+		}
+		last := hdr[0]&0x80 != 0
+		blockType := hdr[0] & 0x7f
+		length := int64(hdr[1])<<16 | int64(hdr[2])<<8 | int64(hdr[3])
+		off += 4
+		switch blockType {
+		case 0: // STREAMINFO
+			b, err := readAt(off, length)
+			if err != nil {
+				return t, err
+			}
+			if len(b) >= 18 {
+				t.SampleRate = int(b[10])<<12 | int(b[11])<<4 | int(b[12])>>4
+				t.Channels = int(b[12]>>1&0x07) + 1
+				t.BitDepth = int(b[12]&0x01)<<4 | int(b[13]>>4) + 1
+				samples := int64(b[13]&0x0f)<<32 | int64(binary.BigEndian.Uint32(b[14:18]))
+				if t.SampleRate > 0 {
+					t.Duration = float64(samples) / float64(t.SampleRate)
+				}
+			}
+		case 4: // VORBIS_COMMENT
+			b, err := readAt(off, length)
+			if err != nil {
+				return t, err
+			}
+			parseVorbisComments(t, b)
+		}
+		if last {
+			return t, nil
+		}
+		off += length
+	}
+}
+
+func parseVorbisComments(t *fileTags, b []byte) {
+	next := func() (string, bool) {
+		if len(b) < 4 {
+			return "", false
+		}
+		n := int(binary.LittleEndian.Uint32(b))
+		if n > len(b)-4 {
+			return "", false
+		}
+		s := string(b[4 : 4+n])
+		b = b[4+n:]
+		return s, true
+	}
+	if _, ok := next(); !ok { // vendor string
+		return
+	}
+	if len(b) < 4 {
+		return
+	}
+	count := int(binary.LittleEndian.Uint32(b))
+	b = b[4:]
+	for i := 0; i < count; i++ {
+		c, ok := next()
+		if !ok {
+			return
+		}
+		key, val, ok := strings.Cut(c, "=")
+		if !ok || val == "" {
+			continue
+		}
+		switch strings.ToUpper(key) {
+		case "TITLE":
+			t.Title = val
+		case "ARTIST":
+			t.Artists = append(t.Artists, val)
+		case "ALBUMARTIST", "ALBUM ARTIST":
+			t.AlbumArtist = val
+		case "ALBUM":
+			t.Album = val
+		case "GENRE":
+			t.Genres = append(t.Genres, val)
+		case "COMPOSER":
+			t.Composer = val
+		case "TRACKNUMBER":
+			t.Track = leadingInt(val)
+		case "DISCNUMBER":
+			t.Disc = leadingInt(val)
+		case "DATE", "YEAR":
+			t.Year = leadingInt(val)
+		case "COMPILATION":
+			t.Compilation = val == "1"
+		case "ITUNESADVISORY":
+			t.Explicit = val == "1"
+		}
+	}
+}
+
+// readID3Tags reads the ID3v2.3/2.4 tag at the start of the file, and
+// estimates the duration from the first MPEG frame if it has no TLEN.
+func readID3Tags(readAt readAtFunc, head []byte, size int64) (*fileTags, error) {
+	version := head[3]
+	tagSize := int64(syncsafe(head[6:10]))
+	t := &fileTags{}
+	var body []byte
+	// ID3v2.2 and unsynchronized tags aren't supported,
+	// but the audio info can still be read
+	if version >= 3 && version <= 4 && head[5]&0x80 == 0 {
+		// frames after the first MiB, which could only fit after
+		// large embedded images, are not worth downloading
+		var err error
+		if body, err = readAt(10, min(tagSize, maxID3Read)); err != nil {
+			return nil, err
+		}
+	}
+	if head[5]&0x40 != 0 && len(body) >= 4 { // extended header
+		n := int(binary.BigEndian.Uint32(body))
+		if version == 4 {
+			n = syncsafe(body[:4])
+		} else {
+			n += 4
+		}
+		body = body[min(n, len(body)):]
+	}
+	var lengthMs int
+	for len(body) >= 10 && body[0] != 0 {
+		id := string(body[:4])
+		n := int(binary.BigEndian.Uint32(body[4:8]))
+		if version == 4 {
+			n = syncsafe(body[4:8])
+		}
+		if n > len(body)-10 {
+			break
+		}
+		frame := body[10 : 10+n]
+		body = body[10+n:]
+		if id[0] != 'T' || len(frame) == 0 {
+			continue
+		}
+		vals := decodeID3Text(frame)
+		if len(vals) == 0 {
+			continue
+		}
+		switch id {
+		case "TIT2":
+			t.Title = vals[0]
+		case "TPE1":
+			t.Artists = vals
+		case "TPE2":
+			t.AlbumArtist = vals[0]
+		case "TALB":
+			t.Album = vals[0]
+		case "TCON":
+			t.Genres = vals
+		case "TCOM":
+			t.Composer = vals[0]
+		case "TRCK":
+			t.Track = leadingInt(vals[0])
+		case "TPOS":
+			t.Disc = leadingInt(vals[0])
+		case "TYER", "TDRC", "TDOR":
+			if t.Year == 0 {
+				t.Year = leadingInt(vals[0])
+			}
+		case "TCMP":
+			t.Compilation = vals[0] == "1"
+		case "TXXX": // user text: description, value
+			if len(vals) > 1 && strings.EqualFold(vals[0], "ITUNESADVISORY") {
+				t.Explicit = vals[1] == "1"
+			}
+		case "TLEN":
+			lengthMs = leadingInt(vals[0])
+		}
+	}
+
+	audioStart := 10 + tagSize
+	if frame, err := readAt(audioStart, 4); err == nil && len(frame) == 4 {
+		bitRate, sampleRate, channels := parseMPEGFrameHeader(frame)
+		t.BitRate, t.SampleRate, t.Channels = bitRate, sampleRate, channels
+		if lengthMs == 0 && bitRate > 0 {
+			// assumes constant bit rate
+			t.Duration = float64(size-audioStart) * 8 / float64(bitRate*1000)
+		}
+	}
+	if lengthMs > 0 {
+		t.Duration = float64(lengthMs) / 1000
+	}
+	return t, nil
+}
+
+var (
+	mpeg1L3BitRates = []int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320}
+	mpeg2L3BitRates = []int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160}
+	mpegSampleRates = []int{44100, 48000, 32000}
+)
+
+// parseMPEGFrameHeader returns the bit rate (kbps), sample rate
+// and channel count of an MPEG layer III frame header, or zeros.
+func parseMPEGFrameHeader(h []byte) (bitRate, sampleRate, channels int) {
+	if h[0] != 0xff || h[1]&0xe0 != 0xe0 || h[1]>>1&0x03 != 0x01 /*layer III*/ {
+		return 0, 0, 0
+	}
+	versionBits := h[1] >> 3 & 0x03
+	brIdx, srIdx := int(h[2]>>4), int(h[2]>>2&0x03)
+	if versionBits == 0x01 || brIdx == 0x0f || srIdx == 0x03 {
+		return 0, 0, 0
+	}
+	sampleRate = mpegSampleRates[srIdx]
+	if versionBits == 0x03 { // MPEG 1
+		bitRate = mpeg1L3BitRates[brIdx]
+	} else {
+		bitRate = mpeg2L3BitRates[brIdx]
+		sampleRate /= 2
+		if versionBits == 0x00 { // MPEG 2.5
+			sampleRate /= 2
+		}
+	}
+	channels = 2
+	if h[3]>>6 == 0x03 {
+		channels = 1
+	}
+	return bitRate, sampleRate, channels
+}
+
+// decodeID3Text decodes the value(s) of an ID3 text frame.
+func decodeID3Text(frame []byte) []string {
+	enc, data := frame[0], frame[1:]
+	var s string
+	switch enc {
+	case 0: // ISO-8859-1
+		r := make([]rune, len(data))
+		for i, c := range data {
+			r[i] = rune(c)
+		}
+		s = string(r)
+	case 1, 2: // UTF-16 with BOM, UTF-16BE
+		order := binary.ByteOrder(binary.BigEndian)
+		if enc == 1 && len(data) >= 2 {
+			if data[0] == 0xff && data[1] == 0xfe {
+				order = binary.LittleEndian
+			}
+			data = data[2:]
+		}
+		u := make([]uint16, len(data)/2)
+		for i := range u {
+			u[i] = order.Uint16(data[2*i:])
+		}
+		s = string(utf16.Decode(u))
+		// each value of a UTF-16 (with BOM) list has its own BOM
+		s = strings.ReplaceAll(s, "\x00\ufeff", "\x00")
+	default: // UTF-8
+		s = string(data)
+	}
+	var vals []string
+	for _, v := range strings.Split(strings.TrimRight(s, "\x00"), "\x00") {
+		if v = strings.TrimSpace(v); v != "" {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
+
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+// leadingInt parses the integer at the start of s, e.g. 3 from "3/12"
+// or 1999 from "1999-05-01", returning 0 if there is none.
+func leadingInt(s string) int {
+	s = strings.TrimSpace(s)
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(s[:end])
+	return n
+}
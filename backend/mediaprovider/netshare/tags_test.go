@@ -0,0 +1,251 @@
+package netshare
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// readAtBytes reads from b like a file of len(b) bytes.
+func readAtBytes(b []byte) readAtFunc {
+	return func(off, n int64) ([]byte, error) {
+		if off >= int64(len(b)) {
+			return nil, nil
+		}
+		return b[off:min(off+n, int64(len(b)))], nil
+	}
+}
+
+func syncsafeBytes(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+}
+
+// id3Frame builds a frame of the given ID3v2 major version.
+func id3Frame(version byte, id string, data []byte) []byte {
+	size := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	if version == 4 {
+		size = syncsafeBytes(len(data))
+	}
+	return append(append(append([]byte(id), size...), 0, 0), data...)
+}
+
+// id3Tag builds a tag of the given version and flags holding the frames,
+// whose declared size is len(frames) + extraSize.
+func id3Tag(version, flags byte, extraSize int, frames ...[]byte) []byte {
+	body := bytes.Join(frames, nil)
+	tag := append([]byte{'I', 'D', '3', version, 0, flags}, syncsafeBytes(len(body)+extraSize)...)
+	return append(tag, body...)
+}
+
+func latin1(s string) []byte { return append([]byte{0}, s...) }
+
+// MPEG 1 layer III, 128 kbps, 44.1 kHz, stereo
+var mp3Frame = []byte{0xff, 0xfb, 0x90, 0x00}
+
+func TestReadID3Tags(t *testing.T) {
+	utf16Artists := []byte{1, 0xff, 0xfe, 'A', 0, 0, 0, 0xff, 0xfe, 'B', 0}
+	audio := append(mp3Frame, make([]byte, 15996)...)
+
+	for _, tt := range []struct {
+		name string
+		file []byte
+		want *fileTags
+	}{
+		{
+			name: "v2.3 with TLEN",
+			file: id3Tag(3, 0, 0,
+				id3Frame(3, "TIT2", latin1("Title")),
+				id3Frame(3, "TRCK", latin1("3/12")),
+				id3Frame(3, "TYER", latin1("1999")),
+				id3Frame(3, "TLEN", latin1("61000")),
+			),
+			want: &fileTags{Title: "Title", Track: 3, Year: 1999, Duration: 61},
+		},
+		{
+			name: "v2.4 with UTF-16 list and audio info",
+			file: append(id3Tag(4, 0, 0,
+				id3Frame(4, "TPE1", utf16Artists),
+				id3Frame(4, "TDRC", []byte("\x032001-05-01")),
+				id3Frame(4, "TXXX", []byte("\x03ITUNESADVISORY\x001")),
+			), audio...),
+			want: &fileTags{Artists: []string{"A", "B"}, Year: 2001, Explicit: true,
+				BitRate: 128, SampleRate: 44100, Channels: 2, Duration: 1},
+		},
+		{
+			name: "v2.4 extended header",
+			file: id3Tag(4, 0x40, 0,
+				append(syncsafeBytes(6), 1, 0),
+				id3Frame(4, "TALB", latin1("Album")),
+			),
+			want: &fileTags{Album: "Album"},
+		},
+		{
+			name: "extended header larger than the tag",
+			file: id3Tag(3, 0x40, 0, []byte{0, 0, 0xff, 0xff, 0, 0}),
+			want: &fileTags{},
+		},
+		{
+			name: "truncated tag",
+			file: id3Tag(3, 0, 1000,
+				id3Frame(3, "TIT2", latin1("Title")),
+				id3Frame(3, "TALB", latin1("Alb")),
+			)[:35],
+			want: &fileTags{Title: "Title"},
+		},
+		{
+			name: "frame larger than the tag",
+			file: id3Tag(3, 0, 0,
+				id3Frame(3, "TIT2", latin1("Title")),
+				append(append([]byte("TALB"), 0x7f, 0xff, 0xff, 0xff, 0, 0), latin1("Album")...),
+			),
+			want: &fileTags{Title: "Title"},
+		},
+		{
+			name: "empty and non-text frames",
+			file: id3Tag(3, 0, 0,
+				id3Frame(3, "TIT2", nil),
+				id3Frame(3, "APIC", []byte{0, 1, 2, 3}),
+				id3Frame(3, "TCON", latin1("Rock")),
+			),
+			want: &fileTags{Genres: []string{"Rock"}},
+		},
+		{
+			name: "unsynchronized tag",
+			file: append(id3Tag(3, 0x80, 0, id3Frame(3, "TIT2", latin1("Title"))), mp3Frame...),
+			want: &fileTags{BitRate: 128, SampleRate: 44100, Channels: 2, Duration: 0.00025},
+		},
+		{
+			name: "v2.2 tag",
+			file: id3Tag(2, 0, 0, []byte("TT2\x00\x00\x06\x00Title")),
+			want: &fileTags{},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readTags(readAtBytes(tt.file), int64(len(tt.file)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// flacBlock builds a metadata block header and data.
+func flacBlock(last bool, blockType byte, length int, data []byte) []byte {
+	if last {
+		blockType |= 0x80
+	}
+	return append([]byte{blockType, byte(length >> 16), byte(length >> 8), byte(length)}, data...)
+}
+
+func vorbisComments(comments ...string) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, 6)
+	b = append(b, "vendor"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(comments)))
+	for _, c := range comments {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(c)))
+		b = append(b, c...)
+	}
+	return b
+}
+
+func TestReadFlacTags(t *testing.T) {
+	// 44.1 kHz, stereo, 16 bits, 441000 samples
+	streamInfo := make([]byte, 34)
+	copy(streamInfo[10:], []byte{0x0a, 0xc4, 0x42, 0xf0, 0x00, 0x06, 0xba, 0xa8})
+	comments := vorbisComments("TITLE=Title", "artist=A", "ARTIST=B", "TRACKNUMBER=2/10", "EMPTY=", "NOVALUE")
+
+	for _, tt := range []struct {
+		name string
+		file []byte
+		want *fileTags
+	}{
+		{
+			name: "stream info and comments",
+			file: bytes.Join([][]byte{[]byte("fLaC"),
+				flacBlock(false, 0, len(streamInfo), streamInfo),
+				flacBlock(false, 1, 4, make([]byte, 4)), // PADDING
+				flacBlock(true, 4, len(comments), comments),
+			}, nil),
+			want: &fileTags{Title: "Title", Artists: []string{"A", "B"}, Track: 2,
+				SampleRate: 44100, Channels: 2, BitDepth: 16, Duration: 10},
+		},
+		{
+			name: "truncated stream info",
+			file: append([]byte("fLaC"), flacBlock(true, 0, len(streamInfo), streamInfo[:10])...),
+			want: &fileTags{},
+		},
+		{
+			name: "no last block",
+			file: append([]byte("fLaC"), flacBlock(false, 4, len(comments), comments)...),
+			want: &fileTags{Title: "Title", Artists: []string{"A", "B"}, Track: 2},
+		},
+		{
+			name: "truncated block header",
+			file: append([]byte("fLaC"), 0x84, 0),
+			want: &fileTags{},
+		},
+		{
+			name: "comment count past the end",
+			file: append([]byte("fLaC"), flacBlock(true, 4, len(comments), append(vorbisComments("TITLE=Title")[:10:10], 0xff, 0, 0, 0))...),
+			want: &fileTags{},
+		},
+		{
+			name: "comment length past the end",
+			file: append([]byte("fLaC"), flacBlock(true, 4, len(comments), append(vorbisComments("TITLE=Title")[:14], 0xff, 0xff, 0, 0))...),
+			want: &fileTags{},
+		},
+		{
+			name: "truncated vendor",
+			file: append([]byte("fLaC"), flacBlock(true, 4, 6, []byte{0xff, 0, 0, 0, 'v', 'e'})...),
+			want: &fileTags{},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readTags(readAtBytes(tt.file), int64(len(tt.file)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTagsUnsupported(t *testing.T) {
+	for _, file := range [][]byte{nil, []byte("ID3"), []byte("OggS\x00\x02\x00\x00\x00\x00"), mp3Frame} {
+		if _, err := readTags(readAtBytes(file), int64(len(file))); err != errNoTags {
+			t.Errorf("readTags(%q): got err %v, want errNoTags", file, err)
+		}
+	}
+}
+
+func TestParseMPEGFrameHeader(t *testing.T) {
+	for _, tt := range []struct {
+		name                          string
+		header                        []byte
+		bitRate, sampleRate, channels int
+	}{
+		{"MPEG 1 stereo", mp3Frame, 128, 44100, 2},
+		{"MPEG 1 mono 320k 48 kHz", []byte{0xff, 0xfb, 0xe4, 0xc0}, 320, 48000, 1},
+		{"MPEG 2", []byte{0xff, 0xf3, 0x80, 0xc0}, 64, 22050, 1},
+		{"MPEG 2.5", []byte{0xff, 0xe3, 0x88, 0x00}, 64, 8000, 2},
+		{"no sync", []byte{0x00, 0xfb, 0x90, 0x00}, 0, 0, 0},
+		{"layer II", []byte{0xff, 0xfd, 0x90, 0x00}, 0, 0, 0},
+		{"reserved version", []byte{0xff, 0xeb, 0x90, 0x00}, 0, 0, 0},
+		{"bad bit rate", []byte{0xff, 0xfb, 0xf0, 0x00}, 0, 0, 0},
+		{"reserved sample rate", []byte{0xff, 0xfb, 0x9c, 0x00}, 0, 0, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			br, sr, ch := parseMPEGFrameHeader(tt.header)
+			if br != tt.bitRate || sr != tt.sampleRate || ch != tt.channels {
+				t.Errorf("got %d kbps, %d Hz, %d channels, want %d kbps, %d Hz, %d channels",
+					br, sr, ch, tt.bitRate, tt.sampleRate, tt.channels)
+			}
+		})
+	}
+}
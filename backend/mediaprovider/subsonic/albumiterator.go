@@ -46,7 +46,7 @@ func filterAlbumMatches(f mediaprovider.AlbumFilter, album *subsonic.AlbumID3, i
 	if filterOptions.ExcludeUnfavorited && album.Starred.IsZero() {
 		return false
 	}
-	if y := album.Year; y < filterOptions.MinYear || (filterOptions.MaxYear > 0 && y > filterOptions.MaxYear) {
+	if y := originalYear(album); y < filterOptions.MinYear || (filterOptions.MaxYear > 0 && y > filterOptions.MaxYear) {
 		return false
 	}
 	if ignoreGenre || len(filterOptions.Genres) == 0 {
@@ -56,6 +56,12 @@ func filterAlbumMatches(f mediaprovider.AlbumFilter, album *subsonic.AlbumID3, i
 		if strings.EqualFold(g, album.Genre) {
 			return true
 		}
+		// OpenSubsonic extension
+		for _, ag := range album.Genres {
+			if strings.EqualFold(g, ag.Name) {
+				return true
+			}
+		}
 	}
 	return false
 }
@@ -74,7 +80,7 @@ func (s *subsonicMediaProvider) IterateAlbums(sortOrder string, filter mediaprov
 		modifiedFilter.SetOptions(modifiedOptions)
 		fetchFn := func(offset, limit int) ([]*subsonic.AlbumID3, error) {
 			return s.client.GetAlbumList2("byGenre",
-				map[string]string{"genre": genre, "offset": strconv.Itoa(offset), "limit": strconv.Itoa(limit)})
+				s.withMusicFolder(map[string]string{"genre": genre, "offset": strconv.Itoa(offset), "limit": strconv.Itoa(limit)}))
 		}
 		return helpers.NewAlbumIterator(makeFetchFn(fetchFn), modifiedFilter, s.prefetchCoverCB)
 	}
@@ -101,24 +107,39 @@ func (s *subsonicMediaProvider) IterateAlbums(sortOrder string, filter mediaprov
 		return s.baseIterFromSimpleSortOrder("alphabeticalByName", filter)
 	case AlbumSortArtistAZ:
 		return s.baseIterFromSimpleSortOrder("alphabeticalByArtist", filter)
-	case AlbumSortYearAscending:
-		fetchFn := func(offset, limit int) ([]*subsonic.AlbumID3, error) {
-			return s.client.GetAlbumList2("byYear",
-				map[string]string{"fromYear": "0", "toYear": "3000", "offset": strconv.Itoa(offset), "limit": strconv.Itoa(limit)})
+	case AlbumSortYearAscending, AlbumSortYearDescending:
+		// restrict the year range on the server to the filter's
+		fromYear, toYear := 0, 3000
+		if filterOptions.MinYear > 0 {
+			fromYear = filterOptions.MinYear
 		}
-		return helpers.NewAlbumIterator(makeFetchFn(fetchFn), filter, s.prefetchCoverCB)
-	case AlbumSortYearDescending:
-		fetchFn := func(offset, limit int) ([]*subsonic.AlbumID3, error) {
-			return s.client.GetAlbumList2("byYear",
-				map[string]string{"fromYear": "3000", "toYear": "0", "offset": strconv.Itoa(offset), "limit": strconv.Itoa(limit)})
+		if filterOptions.MaxYear > 0 {
+			toYear = filterOptions.MaxYear
+		}
+		if sortOrder == AlbumSortYearDescending {
+			fromYear, toYear = toYear, fromYear
 		}
-		return helpers.NewAlbumIterator(makeFetchFn(fetchFn), filter, s.prefetchCoverCB)
+		return s.IterateAlbumsByYear(fromYear, toYear, filter)
 	default:
 		log.Printf("Undefined album sort order: %s", sortOrder)
 		return nil
 	}
 }
 
+var _ mediaprovider.SupportsAlbumsByYear = (*subsonicMediaProvider)(nil)
+
+func (s *subsonicMediaProvider) IterateAlbumsByYear(fromYear, toYear int, filter mediaprovider.AlbumFilter) mediaprovider.AlbumIterator {
+	fetchFn := func(offset, limit int) ([]*subsonic.AlbumID3, error) {
+		return s.client.GetAlbumList2("byYear", s.withMusicFolder(map[string]string{
+			"fromYear": strconv.Itoa(fromYear),
+			"toYear":   strconv.Itoa(toYear),
+			"offset":   strconv.Itoa(offset),
+			"size":     strconv.Itoa(limit),
+		}))
+	}
+	return helpers.NewAlbumIterator(makeFetchFn(fetchFn), filter, s.prefetchCoverCB)
+}
+
 func (s *subsonicMediaProvider) SearchAlbums(searchQuery string, filter mediaprovider.AlbumFilter) mediaprovider.AlbumIterator {
 	return s.newSearchAlbumIter(searchQuery, filter, s.prefetchCoverCB)
 }
@@ -137,8 +158,9 @@ type searchAlbumIter struct {
 func (s *subsonicMediaProvider) newSearchAlbumIter(query string, filter mediaprovider.AlbumFilter, cb func(string)) *searchAlbumIter {
 	return &searchAlbumIter{
 		searchIterBase: searchIterBase{
-			query: query,
-			s:     s.client,
+			query:         query,
+			s:             s.client,
+			musicFolderID: s.musicFolderID,
 		},
 		prefetchCB: cb,
 		filter:     filter,
@@ -229,7 +251,7 @@ func (s *subsonicMediaProvider) newRandomIter(filter mediaprovider.AlbumFilter,
 				"size":   strconv.Itoa(limit),
 				"offset": strconv.Itoa(offset),
 			}
-			return s.client.GetAlbumList2("random", args)
+			return s.client.GetAlbumList2("random", s.withMusicFolder(args))
 		}),
 		filter, s.prefetchCoverCB)
 }
@@ -240,7 +262,7 @@ func (s *subsonicMediaProvider) baseIterFromSimpleSortOrder(sort string, filter
 
 func (s *subsonicMediaProvider) fetchFnFromStandardSort(sort string) helpers.AlbumFetchFn {
 	return makeFetchFn(func(offset, limit int) ([]*subsonic.AlbumID3, error) {
-		return s.client.GetAlbumList2(sort, map[string]string{"size": strconv.Itoa(limit), "offset": strconv.Itoa(offset)})
+		return s.client.GetAlbumList2(sort, s.withMusicFolder(map[string]string{"size": strconv.Itoa(limit), "offset": strconv.Itoa(offset)}))
 	})
 }
 
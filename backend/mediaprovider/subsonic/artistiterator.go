@@ -5,9 +5,6 @@ import (
 	"math/rand"
 	"slices"
 
-	"golang.org/x/text/collate"
-	"golang.org/x/text/language"
-
 	"github.com/dweymouth/go-subsonic/subsonic"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
 	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
@@ -42,8 +39,8 @@ func (s *subsonicMediaProvider) IterateArtists(sortOrder string, filter mediapro
 	switch sortOrder {
 	case ArtistSortAlbumCount:
 		return s.baseArtistIterFromSimpleSortOrder(
-			func(artists []*subsonic.ArtistID3) []*subsonic.ArtistID3 {
-				slices.SortStableFunc(artists, func(a, b *subsonic.ArtistID3) int {
+			func(artists []*mediaprovider.Artist) []*mediaprovider.Artist {
+				slices.SortStableFunc(artists, func(a, b *mediaprovider.Artist) int {
 					return b.AlbumCount - a.AlbumCount
 				})
 				return artists
@@ -52,10 +49,9 @@ func (s *subsonicMediaProvider) IterateArtists(sortOrder string, filter mediapro
 		)
 	case ArtistSortNameAZ:
 		return s.baseArtistIterFromSimpleSortOrder(
-			func(artists []*subsonic.ArtistID3) []*subsonic.ArtistID3 {
-				c := collate.New(language.English, collate.Loose)
-				slices.SortFunc(artists, func(a, b *subsonic.ArtistID3) int {
-					return c.CompareString(a.Name, b.Name)
+			func(artists []*mediaprovider.Artist) []*mediaprovider.Artist {
+				slices.SortFunc(artists, func(a, b *mediaprovider.Artist) int {
+					return sharedutil.CompareStrings(a.SortKey(), b.SortKey())
 				})
 				return artists
 			},
@@ -63,8 +59,8 @@ func (s *subsonicMediaProvider) IterateArtists(sortOrder string, filter mediapro
 		)
 	case ArtistSortRandom:
 		return s.baseArtistIterFromSimpleSortOrder(
-			func(artists []*subsonic.ArtistID3) []*subsonic.ArtistID3 {
-				newArtists := make([]*subsonic.ArtistID3, len(artists))
+			func(artists []*mediaprovider.Artist) []*mediaprovider.Artist {
+				newArtists := make([]*mediaprovider.Artist, len(artists))
 				copy(newArtists, artists)
 				rand.Shuffle(len(newArtists), func(i, j int) { newArtists[i], newArtists[j] = newArtists[j], newArtists[i] })
 				return newArtists
@@ -95,8 +91,9 @@ type searchArtistIter struct {
 func (s *subsonicMediaProvider) newSearchArtistIter(query string, filter mediaprovider.ArtistFilter, cb func(string)) *searchArtistIter {
 	return &searchArtistIter{
 		searchIterBase: searchIterBase{
-			query: query,
-			s:     s.client,
+			query:         query,
+			s:             s.client,
+			musicFolderID: s.musicFolderID,
 		},
 		prefetchCB:  cb,
 		filter:      filter,
@@ -154,38 +151,30 @@ func (s *searchArtistIter) addNewArtists(artists []*subsonic.ArtistID3) {
 	}
 }
 
-func (s *subsonicMediaProvider) baseArtistIterFromSimpleSortOrder(sortFn func([]*subsonic.ArtistID3) []*subsonic.ArtistID3, filter mediaprovider.ArtistFilter) mediaprovider.ArtistIterator {
+func (s *subsonicMediaProvider) baseArtistIterFromSimpleSortOrder(sortFn func([]*mediaprovider.Artist) []*mediaprovider.Artist, filter mediaprovider.ArtistFilter) mediaprovider.ArtistIterator {
 	return helpers.NewArtistIterator(s.artistFetchFnFromStandardSort(sortFn), filter, s.prefetchCoverCB)
 }
 
-func (s *subsonicMediaProvider) artistFetchFnFromStandardSort(sortFn func([]*subsonic.ArtistID3) []*subsonic.ArtistID3) helpers.ArtistFetchFn {
-	return makeArtistFetchFn(func(offset, limit int) ([]*subsonic.ArtistID3, error) {
+func (s *subsonicMediaProvider) artistFetchFnFromStandardSort(sortFn func([]*mediaprovider.Artist) []*mediaprovider.Artist) helpers.ArtistFetchFn {
+	return func(offset, limit int) ([]*mediaprovider.Artist, error) {
 		// When the iterator asks for a second page of results, return nil, as Subsonic does not support pagination for artists.
 		if offset > 0 {
 			return nil, nil
 		}
 
-		idxs, err := s.client.GetArtists(map[string]string{})
+		resp, ext, err := s.getWithExtensions("getArtists", s.withMusicFolder(map[string]string{}))
 		if err != nil {
 			return nil, err
 		}
-		var artists []*subsonic.ArtistID3
-		for _, idx := range idxs.Index {
+		if resp.Artists == nil {
+			return nil, nil
+		}
+		var artists []*mediaprovider.Artist
+		for _, idx := range resp.Artists.Index {
 			for _, ar := range idx.Artist {
-				artists = append(artists, ar)
+				artists = append(artists, ext.toArtist(ar))
 			}
 		}
-		artists = sortFn(artists)
-		return artists, nil
-	})
-}
-
-func makeArtistFetchFn(subsonicFetchFn func(offset, limit int) ([]*subsonic.ArtistID3, error)) helpers.ArtistFetchFn {
-	return func(offset, limit int) ([]*mediaprovider.Artist, error) {
-		ar, err := subsonicFetchFn(offset, limit)
-		if err != nil {
-			return nil, err
-		}
-		return sharedutil.MapSlice(ar, toArtistFromID3), nil
+		return sortFn(artists), nil
 	}
 }
@@ -0,0 +1,32 @@
+package subsonic
+
+import (
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+var _ mediaprovider.SupportsMusicLibraries = (*subsonicMediaProvider)(nil)
+
+func (s *subsonicMediaProvider) GetMusicLibraries() ([]*mediaprovider.MusicLibrary, error) {
+	folders, err := s.client.GetMusicFolders()
+	if err != nil {
+		return nil, err
+	}
+	libraries := make([]*mediaprovider.MusicLibrary, 0, len(folders))
+	for _, f := range folders {
+		libraries = append(libraries, &mediaprovider.MusicLibrary{ID: f.ID, Name: f.Name})
+	}
+	return libraries, nil
+}
+
+// SetMusicLibrary scopes browsing and search to the music folder with the given ID.
+func (s *subsonicMediaProvider) SetMusicLibrary(id string) {
+	s.musicFolderID = id
+}
+
+// withMusicFolder adds the selected music folder, if any, to the request params.
+func (s *subsonicMediaProvider) withMusicFolder(params map[string]string) map[string]string {
+	if s.musicFolderID != "" {
+		params["musicFolderId"] = s.musicFolderID
+	}
+	return params
+}
@@ -0,0 +1,273 @@
+package subsonic
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dweymouth/go-subsonic/subsonic"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// osChild holds the OpenSubsonic extension fields of a song
+// which are not (yet) decoded by the go-subsonic library.
+type osChild struct {
+	ID              string          `xml:"id,attr"`
+	ReplayGain      *osReplayGain   `xml:"replayGain"`
+	BPM             int             `xml:"bpm,attr"`
+	MusicBrainzID   string          `xml:"musicBrainzId,attr"`
+	DisplayComposer string          `xml:"displayComposer,attr"`
+	Contributors    []osContributor `xml:"contributors"`
+	SamplingRate    int             `xml:"samplingRate,attr"`
+	BitDepth        int             `xml:"bitDepth,attr"`
+	ChannelCount    int             `xml:"channelCount,attr"`
+	Played          string          `xml:"played,attr"`
+}
+
+type osContributor struct {
+	Role   string `xml:"role,attr"`
+	Artist struct {
+		Name string `xml:"name,attr"`
+	} `xml:"artist"`
+}
+
+// osAlbum holds the OpenSubsonic extension fields of an album.
+type osAlbum struct {
+	ID            string `xml:"id,attr"`
+	SortName      string `xml:"sortName,attr"`
+	MusicBrainzID string `xml:"musicBrainzId,attr"`
+	Played        string `xml:"played,attr"`
+	DiscTitles    map[int]string
+}
+
+// osArtist holds the OpenSubsonic extension fields of an artist.
+type osArtist struct {
+	ID       string `xml:"id,attr"`
+	SortName string `xml:"sortName,attr"`
+}
+
+type osReplayGain struct {
+	TrackGain *float64 `xml:"trackGain,attr"`
+	AlbumGain *float64 `xml:"albumGain,attr"`
+	TrackPeak *float64 `xml:"trackPeak,attr"`
+	AlbumPeak *float64 `xml:"albumPeak,attr"`
+}
+
+// osExtensions indexes the OpenSubsonic extension fields
+// of the items in an API response by item ID.
+type osExtensions struct {
+	songs   map[string]*osChild
+	albums  map[string]*osAlbum
+	artists map[string]*osArtist
+}
+
+// getWithExtensions performs a GET request against the Subsonic API and decodes the
+// response both into the go-subsonic response and the OpenSubsonic extension fields.
+func (s *subsonicMediaProvider) getWithExtensions(endpoint string, params map[string]string) (*subsonic.Response, *osExtensions, error) {
+	vals := url.Values{}
+	for k, v := range params {
+		vals.Add(k, v)
+	}
+	resp, err := s.client.Request("GET", endpoint, vals)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parsed := &subsonic.Response{}
+	if err := xml.Unmarshal(body, parsed); err != nil {
+		return nil, nil, err
+	}
+	if parsed.Error != nil {
+		return nil, nil, fmt.Errorf("Error #%d: %s", parsed.Error.Code, parsed.Error.Message)
+	}
+	ext, err := parseExtensions(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parsed, ext, nil
+}
+
+func parseExtensions(body []byte) (*osExtensions, error) {
+	ext := &osExtensions{
+		songs:   make(map[string]*osChild),
+		albums:  make(map[string]*osAlbum),
+		artists: make(map[string]*osArtist),
+	}
+	d := xml.NewDecoder(bytes.NewReader(body))
+	var curAlbum *osAlbum // the album element we are within, if any
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if ee, ok := tok.(xml.EndElement); ok && ee.Name.Local == "album" {
+			curAlbum = nil
+			continue
+		}
+		se, ok := tok.(xml.StartElement)
+		if ok && se.Name.Local == "discTitles" && curAlbum != nil {
+			var disc int
+			var title string
+			for _, attr := range se.Attr {
+				switch attr.Name.Local {
+				case "disc":
+					disc, _ = strconv.Atoi(attr.Value)
+				case "title":
+					title = attr.Value
+				}
+			}
+			if title != "" {
+				if curAlbum.DiscTitles == nil {
+					curAlbum.DiscTitles = make(map[int]string)
+				}
+				curAlbum.DiscTitles[disc] = title
+			}
+			continue
+		}
+		if ok && se.Name.Local == "album" {
+			// don't decode the whole element, so the songs within it are visited next
+			al := osAlbum{}
+			for _, attr := range se.Attr {
+				switch attr.Name.Local {
+				case "id":
+					al.ID = attr.Value
+				case "sortName":
+					al.SortName = attr.Value
+				case "musicBrainzId":
+					al.MusicBrainzID = attr.Value
+				case "played":
+					al.Played = attr.Value
+				}
+			}
+			if al.ID != "" {
+				ext.albums[al.ID] = &al
+			}
+			curAlbum = &al
+			continue
+		}
+		if ok && se.Name.Local == "artist" {
+			// the artist element of getArtist contains its albums
+			ar := osArtist{}
+			for _, attr := range se.Attr {
+				switch attr.Name.Local {
+				case "id":
+					ar.ID = attr.Value
+				case "sortName":
+					ar.SortName = attr.Value
+				}
+			}
+			if ar.ID != "" {
+				ext.artists[ar.ID] = &ar
+			}
+			continue
+		}
+		if !ok || (se.Name.Local != "song" && se.Name.Local != "entry") {
+			continue
+		}
+		var ch osChild
+		if err := d.DecodeElement(&ch, &se); err != nil {
+			return nil, err
+		}
+		if ch.ID != "" {
+			ext.songs[ch.ID] = &ch
+		}
+	}
+	return ext, nil
+}
+
+// toTrack converts the go-subsonic Child to a Track,
+// filling in any OpenSubsonic extension fields present in the response.
+func (e *osExtensions) toTrack(ch *subsonic.Child) *mediaprovider.Track {
+	tr := toTrack(ch)
+	if tr == nil || e == nil {
+		return tr
+	}
+	if al, ok := e.albums[tr.AlbumID]; ok {
+		tr.MusicBrainzReleaseID = al.MusicBrainzID
+	}
+	ext, ok := e.songs[tr.ID]
+	if !ok {
+		return tr
+	}
+	tr.BPM = ext.BPM
+	tr.SampleRate = ext.SamplingRate
+	tr.BitDepth = ext.BitDepth
+	tr.Channels = ext.ChannelCount
+	tr.LastPlayed = parsePlayedTime(ext.Played)
+	tr.MusicBrainzRecordingID = ext.MusicBrainzID
+	tr.Composer = ext.DisplayComposer
+	if tr.Composer == "" {
+		var composers []string
+		for _, c := range ext.Contributors {
+			if c.Role == "composer" && c.Artist.Name != "" {
+				composers = append(composers, c.Artist.Name)
+			}
+		}
+		tr.Composer = strings.Join(composers, ", ")
+	}
+	if rg := ext.ReplayGain; rg != nil && (rg.TrackGain != nil || rg.AlbumGain != nil) {
+		tr.ReplayGain = &mediaprovider.ReplayGainInfo{
+			TrackGain: derefOrZero(rg.TrackGain),
+			AlbumGain: derefOrZero(rg.AlbumGain),
+			TrackPeak: derefOrZero(rg.TrackPeak),
+			AlbumPeak: derefOrZero(rg.AlbumPeak),
+		}
+	}
+	return tr
+}
+
+// toAlbum converts the go-subsonic AlbumID3 to an Album,
+// filling in any OpenSubsonic extension fields present in the response.
+func (e *osExtensions) toAlbum(al *subsonic.AlbumID3) *mediaprovider.Album {
+	album := toAlbum(al)
+	if album != nil && e != nil {
+		if ext, ok := e.albums[album.ID]; ok {
+			album.SortName = ext.SortName
+			album.LastPlayed = parsePlayedTime(ext.Played)
+		}
+	}
+	return album
+}
+
+// parsePlayedTime parses the OpenSubsonic played timestamp,
+// returning the zero time if absent or malformed.
+func parsePlayedTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// toArtist converts the go-subsonic ArtistID3 to an Artist,
+// filling in any OpenSubsonic extension fields present in the response.
+func (e *osExtensions) toArtist(ar *subsonic.ArtistID3) *mediaprovider.Artist {
+	artist := toArtistFromID3(ar)
+	if artist != nil && e != nil {
+		if ext, ok := e.artists[artist.ID]; ok {
+			artist.SortName = ext.SortName
+		}
+	}
+	return artist
+}
+
+func derefOrZero[T any](t *T) T {
+	var zero T
+	if t == nil {
+		return zero
+	}
+	return *t
+}
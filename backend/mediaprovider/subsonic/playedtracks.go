@@ -0,0 +1,77 @@
+package subsonic
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
+)
+
+// Subsonic has no endpoint listing tracks by play statistics,
+// so the most and recently played tracks are gathered from the tracks
+// of the most and recently played albums.
+
+// number of albums whose tracks are gathered
+const playedTracksAlbumCount = 15
+
+var _ mediaprovider.SupportsPlayedTracks = (*subsonicMediaProvider)(nil)
+
+func (s *subsonicMediaProvider) GetMostPlayedTracks(limit int) ([]*mediaprovider.Track, error) {
+	tracks, err := s.playedAlbumTracks("frequent")
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(tracks, func(i, j int) bool { return tracks[i].PlayCount > tracks[j].PlayCount })
+	return tracks[:min(limit, len(tracks))], nil
+}
+
+// GetRecentlyPlayedTracks orders the tracks by when they were last played
+// if the server reports it (OpenSubsonic), else by their albums' order.
+func (s *subsonicMediaProvider) GetRecentlyPlayedTracks(limit int) ([]*mediaprovider.Track, error) {
+	tracks, err := s.playedAlbumTracks("recent")
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(tracks, func(i, j int) bool { return tracks[i].LastPlayed.After(tracks[j].LastPlayed) })
+	return tracks[:min(limit, len(tracks))], nil
+}
+
+// playedAlbumTracks returns the played tracks of the first albums of
+// the album list type, in album order. Albums which fail to load are skipped.
+func (s *subsonicMediaProvider) playedAlbumTracks(listType string) ([]*mediaprovider.Track, error) {
+	albums, err := s.client.GetAlbumList2(listType, s.withMusicFolder(map[string]string{
+		"size": strconv.Itoa(playedTracksAlbumCount),
+	}))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(albums))
+	for i, al := range albums {
+		ids[i] = al.ID
+	}
+	albumTracks := make(map[string][]*mediaprovider.Track, len(ids))
+	var mu sync.Mutex
+	_ = helpers.ForEachConcurrently(context.Background(), ids, 4, func(id string) error {
+		al, err := s.GetAlbum(id)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		albumTracks[id] = al.Tracks
+		mu.Unlock()
+		return nil
+	}, nil)
+
+	var tracks []*mediaprovider.Track
+	for _, id := range ids {
+		for _, tr := range albumTracks[id] {
+			if tr.PlayCount > 0 {
+				tracks = append(tracks, tr)
+			}
+		}
+	}
+	return tracks, nil
+}
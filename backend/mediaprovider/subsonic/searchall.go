@@ -12,64 +12,81 @@ import (
 	"github.com/dweymouth/supersonic/sharedutil"
 )
 
+var _ mediaprovider.SupportsSearchOptions = (*subsonicMediaProvider)(nil)
+
 func (s *subsonicMediaProvider) SearchAll(searchQuery string, maxResults int) ([]*mediaprovider.SearchResult, error) {
+	results, err := s.SearchWithOptions(searchQuery, mediaprovider.DefaultSearchOptions(maxResults))
+	if len(results) > maxResults {
+		results = results[:maxResults]
+	}
+	return results, err
+}
+
+func (s *subsonicMediaProvider) SearchWithOptions(searchQuery string, opts mediaprovider.SearchOptions) ([]*mediaprovider.SearchResult, error) {
 	var wg sync.WaitGroup
 	var err error // only set by Search3
-	var result *subsonic.SearchResult3
+	result := &subsonic.SearchResult3{}
 	var playlists []*subsonic.Playlist
 	var genres []*subsonic.Genre
 
-	wg.Add(1)
-	go func() {
-		count := strconv.Itoa(maxResults / 3)
-		res, e := s.client.Search3(searchQuery, map[string]string{
-			"artistCount": count,
-			"albumCount":  count,
-			"songCount":   count,
-		})
-		if e != nil {
-			err = e
-		} else {
-			result = res
-		}
-		wg.Done()
-	}()
+	if opts.Searches(mediaprovider.ContentTypeArtist) || opts.Searches(mediaprovider.ContentTypeAlbum) ||
+		opts.Searches(mediaprovider.ContentTypeTrack) {
+		wg.Add(1)
+		go func() {
+			count := func(t mediaprovider.ContentType) string {
+				return strconv.Itoa(max(opts.Limits[t], 0))
+			}
+			res, e := s.client.Search3(searchQuery, s.withMusicFolder(map[string]string{
+				"artistCount": count(mediaprovider.ContentTypeArtist),
+				"albumCount":  count(mediaprovider.ContentTypeAlbum),
+				"songCount":   count(mediaprovider.ContentTypeTrack),
+			}))
+			if e != nil {
+				err = e
+			} else {
+				result = res
+			}
+			wg.Done()
+		}()
+	}
 
 	querySanitized := strings.ToLower(sanitize.Accents(searchQuery))
 	queryLowerWords := strings.Fields(querySanitized)
 
-	wg.Add(1)
-	go func() {
-		p, e := s.client.GetPlaylists(nil)
-		if e == nil {
-			playlists = sharedutil.FilterSlice(p, func(p *subsonic.Playlist) bool {
-				return helpers.AllTermsMatch(strings.ToLower(sanitize.Accents(p.Name)), queryLowerWords)
-			})
-		}
-		wg.Done()
-	}()
-
-	wg.Add(1)
-	go func() {
-		g, e := s.client.GetGenres()
-		if e == nil {
-			genres = sharedutil.FilterSlice(g, func(g *subsonic.Genre) bool {
-				return helpers.AllTermsMatch(strings.ToLower(sanitize.Accents(g.Name)), queryLowerWords)
-			})
-		}
-		wg.Done()
-	}()
+	if opts.Searches(mediaprovider.ContentTypePlaylist) {
+		wg.Add(1)
+		go func() {
+			p, e := s.client.GetPlaylists(nil)
+			if e == nil {
+				playlists = sharedutil.FilterSlice(p, func(p *subsonic.Playlist) bool {
+					return helpers.AllTermsMatch(strings.ToLower(sanitize.Accents(p.Name)), queryLowerWords)
+				})
+			}
+			wg.Done()
+		}()
+	}
+
+	if opts.Searches(mediaprovider.ContentTypeGenre) {
+		wg.Add(1)
+		go func() {
+			g, e := s.client.GetGenres()
+			if e == nil {
+				genres = sharedutil.FilterSlice(g, func(g *subsonic.Genre) bool {
+					return helpers.AllTermsMatch(strings.ToLower(sanitize.Accents(g.Name)), queryLowerWords)
+				})
+			}
+			wg.Done()
+		}()
+	}
 
 	wg.Wait()
 	if err != nil {
 		return nil, err
 	}
 
-	results := mergeResults(result, playlists, genres)
+	// the server may not respect a count of 0 for content types not searched
+	results := helpers.LimitSearchResults(mergeResults(result, playlists, genres), opts)
 	helpers.RankSearchResults(results, querySanitized, queryLowerWords)
-	if len(results) > maxResults {
-		results = results[:maxResults]
-	}
 	return results, nil
 }
 
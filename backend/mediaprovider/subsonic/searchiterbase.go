@@ -13,6 +13,8 @@ type searchIterBase struct {
 	albumOffset  int
 	songOffset   int
 	s            *subsonic.Client
+
+	musicFolderID string // "" for all folders
 }
 
 func (s *searchIterBase) fetchResults() *subsonic.SearchResult3 {
@@ -21,6 +23,9 @@ func (s *searchIterBase) fetchResults() *subsonic.SearchResult3 {
 		"albumOffset":  strconv.Itoa(s.albumOffset),
 		"songOffset":   strconv.Itoa(s.songOffset),
 	}
+	if s.musicFolderID != "" {
+		searchOpts["musicFolderId"] = s.musicFolderID
+	}
 	results, err := s.s.Search3(s.query, searchOpts)
 	if err != nil {
 		log.Println(err)
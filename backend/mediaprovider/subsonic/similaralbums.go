@@ -0,0 +1,65 @@
+package subsonic
+
+import (
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+// number of similar artists whose albums are considered for similar albums
+const similarAlbumsArtistCount = 6
+
+// GetSimilarAlbums approximates similar albums, as Subsonic has no API for them,
+// from the albums of the album artist's similar artists and albums in the same genre.
+func (s *subsonicMediaProvider) GetSimilarAlbums(albumID string, limit int) ([]*mediaprovider.Album, error) {
+	al, err := s.client.GetAlbum(albumID)
+	if err != nil {
+		return nil, err
+	}
+	album := toAlbum(al)
+
+	var bySimilarArtists []*mediaprovider.Album
+	if len(album.ArtistIDs) > 0 {
+		info, err := s.client.GetArtistInfo2(album.ArtistIDs[0], map[string]string{
+			"count": strconv.Itoa(similarAlbumsArtistCount),
+		})
+		if err != nil {
+			log.Printf("error getting similar artists: %v", err)
+		} else if info != nil {
+			// fetch the similar artists' albums concurrently, keeping similarity order
+			albums := make([][]*mediaprovider.Album, len(info.SimilarArtist))
+			var wg sync.WaitGroup
+			for i, ar := range info.SimilarArtist {
+				wg.Add(1)
+				go func(i int, artistID string) {
+					defer wg.Done()
+					if ar, err := s.client.GetArtist(artistID); err == nil {
+						albums[i] = sharedutil.MapSlice(ar.Album, toAlbum)
+					}
+				}(i, ar.ID)
+			}
+			wg.Wait()
+			for _, a := range albums {
+				bySimilarArtists = append(bySimilarArtists, a...)
+			}
+		}
+	}
+
+	var byGenre []*mediaprovider.Album
+	if len(album.Genres) > 0 {
+		al, err := s.client.GetAlbumList2("byGenre", s.withMusicFolder(map[string]string{
+			"genre": album.Genres[0],
+			"size":  strconv.Itoa(limit * 3),
+		}))
+		if err != nil {
+			log.Printf("error getting albums by genre: %v", err)
+		}
+		byGenre = sharedutil.MapSlice(al, toAlbum)
+	}
+
+	return helpers.PickSimilarAlbums(album, bySimilarArtists, byGenre, limit), nil
+}
@@ -1,19 +1,20 @@
 package subsonic
 
 import (
+	"context"
 	"errors"
 	"image"
 	"io"
-	"math"
+	"math/rand"
 	"net/url"
 	"slices"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/dweymouth/go-subsonic/subsonic"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
 	"github.com/dweymouth/supersonic/sharedutil"
 )
 
@@ -22,6 +23,7 @@ const cacheValidDurationSeconds = 60
 type subsonicMediaProvider struct {
 	client          *subsonic.Client
 	prefetchCoverCB func(coverArtID string)
+	musicFolderID   string // "" for all folders
 
 	genresCached   []*mediaprovider.Genre
 	genresCachedAt int64 // unix
@@ -71,23 +73,67 @@ func (s *subsonicMediaProvider) RemovePlaylistTracks(id string, removeIdxs []int
 	return s.client.UpdatePlaylistTracks(id, nil, removeIdxs)
 }
 
+var _ mediaprovider.SupportsPlaylistTrackMove = (*subsonicMediaProvider)(nil)
+
+// MovePlaylistTrack moves a track with a single updatePlaylist call, which
+// removes the tracks from the first affected index on and re-adds them in the new order.
+func (s *subsonicMediaProvider) MovePlaylistTrack(playlistID string, fromIdx, toIdx int) error {
+	pl, err := s.client.GetPlaylist(playlistID)
+	if err != nil {
+		return err
+	}
+	n := len(pl.Entry)
+	if fromIdx < 0 || fromIdx >= n || toIdx < 0 || toIdx >= n {
+		return errors.New("playlist track index out of range")
+	}
+	if fromIdx == toIdx {
+		return nil
+	}
+	start := min(fromIdx, toIdx)
+	tail := make([]string, 0, n-start)
+	for _, e := range pl.Entry[start:] {
+		tail = append(tail, e.ID)
+	}
+	moved := tail[fromIdx-start]
+	tail = append(tail[:fromIdx-start], tail[fromIdx-start+1:]...)
+	tail = append(tail[:toIdx-start], append([]string{moved}, tail[toIdx-start:]...)...)
+
+	removeIdxs := make([]int, 0, n-start)
+	for i := start; i < n; i++ {
+		removeIdxs = append(removeIdxs, i)
+	}
+	s.playlistsCached = nil
+	return s.client.UpdatePlaylistTracks(playlistID, tail, removeIdxs)
+}
+
 func (s *subsonicMediaProvider) GetTrack(trackID string) (*mediaprovider.Track, error) {
-	tr, err := s.client.GetSong(trackID)
+	resp, ext, err := s.getWithExtensions("getSong", map[string]string{"id": trackID})
 	if err != nil {
 		return nil, err
 	}
-	return toTrack(tr), nil
+	if resp.Song == nil {
+		return nil, errors.New("track not found")
+	}
+	return ext.toTrack(resp.Song), nil
 }
 
 func (s *subsonicMediaProvider) GetAlbum(albumID string) (*mediaprovider.AlbumWithTracks, error) {
-	al, err := s.client.GetAlbum(albumID)
+	resp, ext, err := s.getWithExtensions("getAlbum", map[string]string{"id": albumID})
 	if err != nil {
 		return nil, err
 	}
+	if resp.Album == nil {
+		return nil, errors.New("album not found")
+	}
+	al := resp.Album
 	album := &mediaprovider.AlbumWithTracks{
-		Tracks: sharedutil.MapSlice(al.Song, toTrack),
+		Tracks: sharedutil.MapSlice(al.Song, ext.toTrack),
 	}
 	fillAlbum(al, &album.Album)
+	if osAl, ok := ext.albums[al.ID]; ok {
+		album.SortName = osAl.SortName
+		album.DiscTitles = osAl.DiscTitles
+	}
 	return album, nil
 }
 
@@ -105,19 +151,28 @@ func (s *subsonicMediaProvider) GetAlbumInfo(albumID string) (*mediaprovider.Alb
 }
 
 func (s *subsonicMediaProvider) GetArtist(artistID string) (*mediaprovider.ArtistWithAlbums, error) {
-	ar, err := s.client.GetArtist(artistID)
+	resp, ext, err := s.getWithExtensions("getArtist", map[string]string{"id": artistID})
 	if err != nil {
 		return nil, err
 	}
-	return &mediaprovider.ArtistWithAlbums{
+	if resp.Artist == nil {
+		return nil, errors.New("artist not found")
+	}
+	ar := resp.Artist
+	artist := &mediaprovider.ArtistWithAlbums{
 		Artist: mediaprovider.Artist{
-			ID:         ar.ID,
-			Name:       ar.Name,
-			Favorite:   !ar.Starred.IsZero(),
-			AlbumCount: ar.AlbumCount,
+			ID:          ar.ID,
+			Name:        ar.Name,
+			Favorite:    !ar.Starred.IsZero(),
+			FavoritedAt: ar.Starred,
+			AlbumCount:  ar.AlbumCount,
 		},
-		Albums: sharedutil.MapSlice(ar.Album, toAlbum),
-	}, nil
+		Albums: sharedutil.MapSlice(ar.Album, ext.toAlbum),
+	}
+	if osAr, ok := ext.artists[ar.ID]; ok {
+		artist.SortName = osAr.SortName
+	}
+	return artist, nil
 }
 
 func (s *subsonicMediaProvider) GetArtistInfo(artistID string) (*mediaprovider.ArtistInfo, error) {
@@ -144,16 +199,19 @@ func (s *subsonicMediaProvider) GetCoverArt(id string, size int) (image.Image, e
 	return s.client.GetCoverArt(id, params)
 }
 
-func (s *subsonicMediaProvider) GetFavorites() (mediaprovider.Favorites, error) {
-	fav, err := s.client.GetStarred2(map[string]string{})
+func (s *subsonicMediaProvider) GetFavorites(sort mediaprovider.FavoritesSortOrder) (mediaprovider.Favorites, error) {
+	fav, err := s.client.GetStarred2(s.withMusicFolder(map[string]string{}))
 	if err != nil {
 		return mediaprovider.Favorites{}, err
 	}
-	return mediaprovider.Favorites{
+	favorites := mediaprovider.Favorites{
 		Albums:  sharedutil.MapSlice(fav.Album, toAlbum),
 		Artists: sharedutil.MapSlice(fav.Artist, toArtistFromID3),
 		Tracks:  sharedutil.MapSlice(fav.Song, toTrack),
-	}, nil
+	}
+	// getStarred2 has no sort options
+	favorites.Sort(sort)
+	return favorites, nil
 }
 
 func (s *subsonicMediaProvider) GetGenres() ([]*mediaprovider.Genre, error) {
@@ -177,12 +235,16 @@ func (s *subsonicMediaProvider) GetGenres() ([]*mediaprovider.Genre, error) {
 }
 
 func (s *subsonicMediaProvider) GetPlaylist(playlistID string) (*mediaprovider.PlaylistWithTracks, error) {
-	pl, err := s.client.GetPlaylist(playlistID)
+	resp, ext, err := s.getWithExtensions("getPlaylist", map[string]string{"id": playlistID})
 	if err != nil {
 		return nil, err
 	}
+	if resp.Playlist == nil {
+		return nil, errors.New("playlist not found")
+	}
+	pl := resp.Playlist
 	playlist := &mediaprovider.PlaylistWithTracks{
-		Tracks: sharedutil.MapSlice(pl.Entry, toTrack),
+		Tracks: sharedutil.MapSlice(pl.Entry, ext.toTrack),
 	}
 	fillPlaylist(pl, &playlist.Playlist)
 	return playlist, nil
@@ -207,13 +269,41 @@ func (s *subsonicMediaProvider) GetRandomTracks(genreName string, count int) ([]
 	if genreName != "" {
 		opts["genre"] = genreName
 	}
-	tr, err := s.client.GetRandomSongs(opts)
+	tr, err := s.client.GetRandomSongs(s.withMusicFolder(opts))
 	if err != nil {
 		return nil, err
 	}
 	return sharedutil.MapSlice(tr, toTrack), nil
 }
 
+func (s *subsonicMediaProvider) GetRandomAlbums(limit int, filter mediaprovider.AlbumFilter) ([]*mediaprovider.Album, error) {
+	if filter.IsNil() {
+		al, err := s.client.GetAlbumList2("random", s.withMusicFolder(map[string]string{"size": strconv.Itoa(limit)}))
+		if err != nil {
+			return nil, err
+		}
+		return sharedutil.MapSlice(al, toAlbum), nil
+	}
+	// the filter is applied client-side, so may need several pages of random albums
+	var albums []*mediaprovider.Album
+	iter := s.newRandomIter(filter, nil)
+	for al := iter.Next(); al != nil && len(albums) < limit; al = iter.Next() {
+		albums = append(albums, al)
+	}
+	return albums, nil
+}
+
+// GetRandomArtists picks random artists from the full artist list,
+// since Subsonic has no endpoint for random artists.
+func (s *subsonicMediaProvider) GetRandomArtists(limit int) ([]*mediaprovider.Artist, error) {
+	artists, err := s.artistFetchFnFromStandardSort(func(a []*mediaprovider.Artist) []*mediaprovider.Artist { return a })(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	rand.Shuffle(len(artists), func(i, j int) { artists[i], artists[j] = artists[j], artists[i] })
+	return artists[:min(limit, len(artists))], nil
+}
+
 func (s *subsonicMediaProvider) GetSimilarTracks(artistID string, count int) ([]*mediaprovider.Track, error) {
 	tr, err := s.client.GetSimilarSongs2(artistID, map[string]string{"count": strconv.Itoa(count)})
 	if err != nil {
@@ -223,9 +313,24 @@ func (s *subsonicMediaProvider) GetSimilarTracks(artistID string, count int) ([]
 }
 
 func (s *subsonicMediaProvider) GetStreamURL(trackID string, forceRaw bool) (string, error) {
+	return s.GetStreamURLWithOptions(trackID, mediaprovider.StreamOptions{ForceRaw: forceRaw})
+}
+
+var _ mediaprovider.SupportsStreamOptions = (*subsonicMediaProvider)(nil)
+
+// GetStreamURLWithOptions requests transcoding with the format and maxBitRate
+// parameters of the stream endpoint, leaving unset options to the server's defaults.
+func (s *subsonicMediaProvider) GetStreamURLWithOptions(trackID string, opts mediaprovider.StreamOptions) (string, error) {
 	m := make(map[string]string)
-	if forceRaw {
+	if opts.ForceRaw {
 		m["format"] = "raw"
+	} else {
+		if opts.Format != "" {
+			m["format"] = opts.Format
+		}
+		if opts.MaxBitRate > 0 {
+			m["maxBitRate"] = strconv.Itoa(opts.MaxBitRate)
+		}
 	}
 	u, err := s.client.GetStreamURL(trackID, m)
 	if err != nil {
@@ -280,33 +385,20 @@ func (s *subsonicMediaProvider) SetFavorite(params mediaprovider.RatingFavoriteP
 	return s.client.Unstar(subParams)
 }
 
+// Subsonic doesn't allow bulk setting ratings. To not overwhelm
+// the server with requests, set rating for only this many tracks at a time.
+const setRatingConcurrency = 5
+
 func (s *subsonicMediaProvider) SetRating(params mediaprovider.RatingFavoriteParameters, rating int) error {
-	// Subsonic doesn't allow bulk setting ratings.
-	// To not overwhelm the server with requests, set rating for
-	// only 5 tracks at a time concurrently
-	batchSize := 5
-	var err error
-	batchSetRating := func(offs int, wg *sync.WaitGroup) {
-		for i := 0; i < batchSize && offs+i < len(params.TrackIDs); i++ {
-			wg.Add(1)
-			go func(idx int) {
-				newErr := s.client.SetRating(params.TrackIDs[idx], rating)
-				if err == nil && newErr != nil {
-					err = newErr
-				}
-				wg.Done()
-			}(offs + i)
-		}
-	}
+	return s.SetRatingWithProgress(context.Background(), params, rating, nil)
+}
 
-	numBatches := int(math.Ceil(float64(len(params.TrackIDs)) / float64(batchSize)))
-	for i := 0; i < numBatches; i++ {
-		var wg sync.WaitGroup
-		batchSetRating(i*batchSize, &wg)
-		wg.Wait()
-	}
+var _ mediaprovider.SupportsSetRatingProgress = (*subsonicMediaProvider)(nil)
 
-	return err
+func (s *subsonicMediaProvider) SetRatingWithProgress(ctx context.Context, params mediaprovider.RatingFavoriteParameters, rating int, onProgress func(done, total int)) error {
+	return helpers.ForEachConcurrently(ctx, params.TrackIDs, setRatingConcurrency, func(id string) error {
+		return s.client.SetRating(id, rating)
+	}, onProgress)
 }
 
 func (s *subsonicMediaProvider) CreateShareURL(id string) (*url.URL, error) {
@@ -338,6 +430,20 @@ func (s *subsonicMediaProvider) RescanLibrary() error {
 	return err
 }
 
+var _ mediaprovider.SupportsScanStatus = (*subsonicMediaProvider)(nil)
+
+func (s *subsonicMediaProvider) GetScanStatus() (mediaprovider.ScanStatus, error) {
+	status, err := s.client.GetScanStatus()
+	if err != nil {
+		return mediaprovider.ScanStatus{}, err
+	}
+	if status == nil {
+		return mediaprovider.ScanStatus{}, errors.New("no scan status returned")
+	}
+	// the Subsonic API only reports the number of items scanned so far
+	return mediaprovider.ScanStatus{Scanning: status.Scanning, Count: status.Count, Progress: -1}, nil
+}
+
 // LyricsProvider interface
 var _ mediaprovider.LyricsProvider = (*subsonicMediaProvider)(nil)
 
@@ -455,6 +561,16 @@ func toTrack(ch *subsonic.Child) *mediaprovider.Track {
 		artistIDs = append(artistIDs, ch.ArtistID)
 	}
 
+	var genres []string
+	if len(ch.Genres) > 0 {
+		// OpenSubsonic extension
+		for _, g := range ch.Genres {
+			genres = append(genres, g.Name)
+		}
+	} else if ch.Genre != "" {
+		genres = append(genres, ch.Genre)
+	}
+
 	return &mediaprovider.Track{
 		ID:          ch.ID,
 		CoverArtID:  ch.CoverArt,
@@ -463,7 +579,7 @@ func toTrack(ch *subsonic.Child) *mediaprovider.Track {
 		Duration:    ch.Duration,
 		TrackNumber: ch.Track,
 		DiscNumber:  ch.DiscNumber,
-		Genre:       ch.Genre,
+		Genres:      genres,
 		ArtistIDs:   artistIDs,
 		ArtistNames: artistNames,
 		Album:       ch.Album,
@@ -471,10 +587,13 @@ func toTrack(ch *subsonic.Child) *mediaprovider.Track {
 		Year:        ch.Year,
 		Rating:      ch.UserRating,
 		Favorite:    !ch.Starred.IsZero(),
+		FavoritedAt: ch.Starred,
 		PlayCount:   int(ch.PlayCount),
 		FilePath:    ch.Path,
 		Size:        ch.Size,
 		BitRate:     ch.BitRate,
+		Codec:       ch.Suffix,
+		CreatedAt:   ch.Created,
 		Comment:     ch.Comment,
 	}
 }
@@ -511,14 +630,18 @@ func fillAlbum(subAlbum *subsonic.AlbumID3, album *mediaprovider.Album) {
 		genres = append(genres, subAlbum.Genre)
 	}
 
-	album.Year = subAlbum.Year
-	if subAlbum.OriginalReleaseDate != nil &&
-		subAlbum.OriginalReleaseDate.Year != nil &&
-		*subAlbum.OriginalReleaseDate.Year < subAlbum.Year {
-		album.Year = *subAlbum.OriginalReleaseDate.Year
+	album.ReleaseDate = toItemDate(subAlbum.ReleaseDate)
+	album.OriginalReleaseDate = toItemDate(subAlbum.OriginalReleaseDate)
+	album.Year = originalYear(subAlbum)
+	releaseYear := album.ReleaseDate.Year
+	if releaseYear == 0 {
+		releaseYear = subAlbum.Year
 	}
-	if subAlbum.ReleaseDate != nil && subAlbum.ReleaseDate.Year != nil {
-		album.ReissueYear = *subAlbum.ReleaseDate.Year
+	if releaseYear > album.Year {
+		album.ReissueYear = releaseYear
+	} else {
+		// not a reissue; the release date is the original
+		album.OriginalReleaseDate = mediaprovider.ItemDate{}
 	}
 
 	album.ID = subAlbum.ID
@@ -530,12 +653,40 @@ func fillAlbum(subAlbum *subsonic.AlbumID3, album *mediaprovider.Album) {
 	album.TrackCount = subAlbum.SongCount
 	album.Genres = genres
 	album.Favorite = !subAlbum.Starred.IsZero()
+	album.FavoritedAt = subAlbum.Starred
+	album.CreatedAt = subAlbum.Created
 	album.ReleaseTypes = normalizeReleaseTypes(subAlbum.ReleaseTypes)
+	album.IsCompilation = subAlbum.IsCompilation
 	if subAlbum.IsCompilation {
 		album.ReleaseTypes |= mediaprovider.ReleaseTypeCompilation
 	}
 }
 
+// originalYear returns the year the album was first released,
+// which for reissues is earlier than the year tag.
+func originalYear(al *subsonic.AlbumID3) int {
+	year := al.Year
+	if d := al.OriginalReleaseDate; d != nil && d.Year != nil && *d.Year > 0 && (year == 0 || *d.Year < year) {
+		year = *d.Year
+	}
+	return year
+}
+
+func toItemDate(d *subsonic.ItemDate) mediaprovider.ItemDate {
+	var date mediaprovider.ItemDate
+	if d == nil || d.Year == nil {
+		return date
+	}
+	date.Year = *d.Year
+	if d.Month != nil {
+		date.Month = *d.Month
+		if d.Date != nil {
+			date.Day = *d.Date
+		}
+	}
+	return date
+}
+
 func normalizeReleaseTypes(releaseTypes []string) mediaprovider.ReleaseTypes {
 	var mpReleaseTypes mediaprovider.ReleaseTypes
 	for _, t := range releaseTypes {
@@ -585,11 +736,12 @@ func toArtistFromID3(ar *subsonic.ArtistID3) *mediaprovider.Artist {
 		return nil
 	}
 	return &mediaprovider.Artist{
-		ID:         ar.ID,
-		CoverArtID: ar.CoverArt,
-		Name:       ar.Name,
-		Favorite:   !ar.Starred.IsZero(),
-		AlbumCount: ar.AlbumCount,
+		ID:          ar.ID,
+		CoverArtID:  ar.CoverArt,
+		Name:        ar.Name,
+		Favorite:    !ar.Starred.IsZero(),
+		FavoritedAt: ar.Starred,
+		AlbumCount:  ar.AlbumCount,
 	}
 }
 
@@ -620,3 +772,56 @@ func (s *subsonicMediaProvider) GetSongRadio(trackID string, count int) ([]*medi
 	}
 	return sharedutil.MapSlice(tr, toTrack), nil
 }
+
+var _ mediaprovider.SupportsInstantMix = (*subsonicMediaProvider)(nil)
+
+// getSimilarSongs accepts any of a track, album or artist ID as the seed.
+func (s *subsonicMediaProvider) GetInstantMix(itemID string, limit int) ([]*mediaprovider.Track, error) {
+	return s.GetSongRadio(itemID, limit)
+}
+
+// Subsonic has no notion of genre similarity, so a genre mix is random tracks of the genre.
+func (s *subsonicMediaProvider) GetGenreInstantMix(genre string, limit int) ([]*mediaprovider.Track, error) {
+	return s.GetRandomTracks(genre, limit)
+}
+
+var _ mediaprovider.SupportsArtistAppearsOn = (*subsonicMediaProvider)(nil)
+
+// max number of the artist's tracks searched to find the albums they appear on
+const appearsOnSearchLimit = 500
+
+func (s *subsonicMediaProvider) GetArtistAppearsOn(artistID string) ([]*mediaprovider.Album, error) {
+	ar, err := s.client.GetArtist(artistID)
+	if err != nil {
+		return nil, err
+	}
+	ownAlbums := make(map[string]bool, len(ar.Album))
+	for _, al := range ar.Album {
+		ownAlbums[al.ID] = true
+	}
+
+	// getArtist only returns the albums the artist is an album artist of,
+	// so search for their tracks on other albums
+	res, err := s.client.Search3(ar.Name, map[string]string{
+		"artistCount": "0",
+		"albumCount":  "0",
+		"songCount":   strconv.Itoa(appearsOnSearchLimit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var albums []*mediaprovider.Album
+	for _, song := range res.Song {
+		tr := toTrack(song)
+		if tr.AlbumID == "" || ownAlbums[tr.AlbumID] || !slices.Contains(tr.ArtistIDs, artistID) {
+			continue
+		}
+		ownAlbums[tr.AlbumID] = true // don't fetch again
+		al, err := s.client.GetAlbum(tr.AlbumID)
+		if err != nil {
+			return nil, err
+		}
+		albums = append(albums, toAlbum(al))
+	}
+	return albums, nil
+}
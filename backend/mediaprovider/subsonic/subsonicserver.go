@@ -1,23 +1,70 @@
 package subsonic
 
 import (
+	"encoding/xml"
+	"io"
+	"log"
+
 	subsonicCli "github.com/dweymouth/go-subsonic/subsonic"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
 )
 
+// Subsonic API error codes returned when the server cannot accept
+// token (salted hash) authentication, e.g. for LDAP-backed users.
+const (
+	errCodeTokenAuthNotSupportedLDAP = 41
+	errCodeAuthMechanismNotSupported = 42
+)
+
 type SubsonicServer struct {
 	subsonicCli.Client
+
+	// If true, fall back to legacy (plaintext) password authentication
+	// when the server rejects token authentication for this user.
+	AllowPasswordAuthFallback bool
 }
 
 func (s *SubsonicServer) Login(username, password string) mediaprovider.LoginResponse {
 	s.User = username
 	err := s.Client.Authenticate(password)
+	if err == subsonicCli.ErrAuthenticationFailure && !s.PasswordAuth && s.tokenAuthRejected() {
+		if !s.AllowPasswordAuthFallback {
+			return mediaprovider.LoginResponse{
+				Error:       mediaprovider.ErrTokenAuthNotSupported,
+				IsAuthError: true,
+			}
+		}
+		log.Printf("server %s does not support token authentication for user %s; falling back to legacy password authentication", s.BaseUrl, username)
+		s.PasswordAuth = true
+		err = s.Client.Authenticate(password)
+	}
 	return mediaprovider.LoginResponse{
 		Error:       err,
 		IsAuthError: err == subsonicCli.ErrAuthenticationFailure,
 	}
 }
 
+// tokenAuthRejected re-pings the server with the current credentials and
+// reports whether it failed because token authentication is not supported,
+// since Authenticate does not expose the Subsonic error code.
+func (s *SubsonicServer) tokenAuthRejected() bool {
+	resp, err := s.Client.Request("GET", "ping", nil)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false
+	}
+	var parsed subsonicCli.Response
+	if err := xml.Unmarshal(body, &parsed); err != nil || parsed.Error == nil {
+		return false
+	}
+	return parsed.Error.Code == errCodeTokenAuthNotSupportedLDAP ||
+		parsed.Error.Code == errCodeAuthMechanismNotSupported
+}
+
 func (s *SubsonicServer) MediaProvider() mediaprovider.MediaProvider {
 	return SubsonicMediaProvider(&s.Client)
 }
@@ -2,9 +2,12 @@ package subsonic
 
 import (
 	"log"
+	"strconv"
 
 	"github.com/dweymouth/go-subsonic/subsonic"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
+	"github.com/dweymouth/supersonic/sharedutil"
 )
 
 func (s *subsonicMediaProvider) IterateTracks(searchQuery string) mediaprovider.TrackIterator {
@@ -19,13 +22,30 @@ func (s *subsonicMediaProvider) IterateTracks(searchQuery string) mediaprovider.
 	}
 	return &searchTracksIterator{
 		searchIterBase: searchIterBase{
-			s:     s.client,
-			query: searchQuery,
+			s:             s.client,
+			query:         searchQuery,
+			musicFolderID: s.musicFolderID,
 		},
 		trackIDset: make(map[string]bool),
 	}
 }
 
+var _ mediaprovider.SupportsGenreTracks = (*subsonicMediaProvider)(nil)
+
+func (s *subsonicMediaProvider) IterateGenreTracks(genre string) mediaprovider.TrackIterator {
+	fetchFn := func(offset, limit int) ([]*mediaprovider.Track, error) {
+		tracks, err := s.client.GetSongsByGenre(genre, s.withMusicFolder(map[string]string{
+			"offset": strconv.Itoa(offset),
+			"count":  strconv.Itoa(limit),
+		}))
+		if err != nil {
+			return nil, err
+		}
+		return sharedutil.MapSlice(tracks, toTrack), nil
+	}
+	return helpers.NewTrackIterator(fetchFn, s.prefetchCoverCB)
+}
+
 type allTracksIterator struct {
 	s           *subsonicMediaProvider
 	albumIter   mediaprovider.AlbumIterator
@@ -0,0 +1,299 @@
+package backend
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Names of the caches reported in the Metrics
+const (
+	CacheNameThumbnailMemory = "Thumbnails (memory)"
+	CacheNameThumbnailDisk   = "Thumbnails (disk)"
+)
+
+// Metrics collects runtime performance counters (API requests, cache hit rates)
+// that are shown in the performance overlay to help diagnose slowness.
+type Metrics struct {
+	inFlightRequests  atomic.Int64
+	totalRequests     atomic.Int64
+	totalRequestNanos atomic.Int64
+	slowThreshold     atomic.Int64 // nanoseconds, 0 if disabled
+
+	mu        sync.Mutex
+	caches    map[string]*cacheCounters
+	endpoints map[string]*EndpointStats
+	onRequest []func(RequestInfo)
+}
+
+// RequestInfo describes a completed API request.
+type RequestInfo struct {
+	Endpoint   string // the request path, with item IDs replaced by "{id}"
+	Method     string
+	Duration   time.Duration // until the response headers were received
+	Bytes      int64         // size of the response body read
+	StatusCode int           // 0 if Err is set
+	Err        error         // transport error, if any
+}
+
+// Failed returns true if the request failed or the server returned an error status.
+func (r RequestInfo) Failed() bool {
+	return r.Err != nil || r.StatusCode >= 400
+}
+
+// EndpointStats are the aggregated stats of the requests to one endpoint.
+type EndpointStats struct {
+	Endpoint  string
+	Requests  int64
+	Errors    int64
+	TotalTime time.Duration
+	MaxTime   time.Duration
+	Bytes     int64
+}
+
+func (e EndpointStats) AverageTime() time.Duration {
+	if e.Requests == 0 {
+		return 0
+	}
+	return e.TotalTime / time.Duration(e.Requests)
+}
+
+// ErrorRate returns the fraction (0-1) of requests that failed.
+func (e EndpointStats) ErrorRate() float64 {
+	if e.Requests == 0 {
+		return 0
+	}
+	return float64(e.Errors) / float64(e.Requests)
+}
+
+type cacheCounters struct {
+	hits   int64
+	misses int64
+}
+
+// MetricsSnapshot is a point-in-time copy of the values in the Metrics.
+type MetricsSnapshot struct {
+	InFlightRequests   int64
+	TotalRequests      int64
+	AverageRequestTime time.Duration
+	Caches             []CacheStats
+	// sorted by total time spent in requests, descending
+	Endpoints []EndpointStats
+}
+
+type CacheStats struct {
+	Name   string
+	Hits   int64
+	Misses int64
+}
+
+// HitRate returns the fraction (0-1) of cache lookups that were hits.
+func (c CacheStats) HitRate() float64 {
+	if c.Hits+c.Misses == 0 {
+		return 0
+	}
+	return float64(c.Hits) / float64(c.Hits+c.Misses)
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		caches:    make(map[string]*cacheCounters),
+		endpoints: make(map[string]*EndpointStats),
+	}
+}
+
+// OnRequest registers a callback to be invoked after each API request.
+// It is invoked on the goroutine which made the request, so must not block.
+func (m *Metrics) OnRequest(cb func(RequestInfo)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onRequest = append(m.onRequest, cb)
+}
+
+// SetSlowRequestThreshold sets the duration above which requests
+// are logged as slow. Zero disables logging slow requests.
+func (m *Metrics) SetSlowRequestThreshold(d time.Duration) {
+	m.slowThreshold.Store(int64(d))
+}
+
+func (m *Metrics) recordRequest(info RequestInfo) {
+	if t := time.Duration(m.slowThreshold.Load()); t > 0 && info.Duration > t {
+		log.Printf("slow request: %s %s took %s (status %d, %d bytes)",
+			info.Method, info.Endpoint, info.Duration.Round(time.Millisecond), info.StatusCode, info.Bytes)
+	}
+
+	m.mu.Lock()
+	e, ok := m.endpoints[info.Endpoint]
+	if !ok {
+		e = &EndpointStats{Endpoint: info.Endpoint}
+		m.endpoints[info.Endpoint] = e
+	}
+	e.Requests++
+	if info.Failed() {
+		e.Errors++
+	}
+	e.TotalTime += info.Duration
+	e.MaxTime = max(e.MaxTime, info.Duration)
+	e.Bytes += info.Bytes
+	callbacks := m.onRequest
+	m.mu.Unlock()
+
+	for _, cb := range callbacks {
+		cb(info)
+	}
+}
+
+func (m *Metrics) RecordCacheHit(cacheName string) {
+	m.recordCacheLookup(cacheName, true)
+}
+
+func (m *Metrics) RecordCacheMiss(cacheName string) {
+	m.recordCacheLookup(cacheName, false)
+}
+
+func (m *Metrics) recordCacheLookup(cacheName string, hit bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.caches[cacheName]
+	if !ok {
+		c = &cacheCounters{}
+		m.caches[cacheName] = c
+	}
+	if hit {
+		c.hits++
+	} else {
+		c.misses++
+	}
+}
+
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	s := MetricsSnapshot{
+		InFlightRequests: m.inFlightRequests.Load(),
+		TotalRequests:    m.totalRequests.Load(),
+	}
+	if s.TotalRequests > 0 {
+		s.AverageRequestTime = time.Duration(m.totalRequestNanos.Load() / s.TotalRequests)
+	}
+	m.mu.Lock()
+	for name, c := range m.caches {
+		s.Caches = append(s.Caches, CacheStats{Name: name, Hits: c.hits, Misses: c.misses})
+	}
+	for _, e := range m.endpoints {
+		s.Endpoints = append(s.Endpoints, *e)
+	}
+	m.mu.Unlock()
+	sort.Slice(s.Caches, func(i, j int) bool { return s.Caches[i].Name < s.Caches[j].Name })
+	sort.Slice(s.Endpoints, func(i, j int) bool { return s.Endpoints[i].TotalTime > s.Endpoints[j].TotalTime })
+	return s
+}
+
+// Transport wraps the given RoundTripper (or http.DefaultTransport if nil)
+// so that requests made through it are recorded in the Metrics.
+func (m *Metrics) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if m == nil {
+		return base
+	}
+	return &metricsTransport{base: base, m: m}
+}
+
+type metricsTransport struct {
+	base http.RoundTripper
+	m    *Metrics
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.m.inFlightRequests.Add(1)
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	dur := time.Since(start)
+	t.m.inFlightRequests.Add(-1)
+	t.m.totalRequests.Add(1)
+	t.m.totalRequestNanos.Add(dur.Nanoseconds())
+
+	info := RequestInfo{
+		Endpoint: endpointName(req.URL),
+		Method:   req.Method,
+		Duration: dur,
+		Err:      err,
+	}
+	if err != nil {
+		t.m.recordRequest(info)
+		return resp, err
+	}
+	info.StatusCode = resp.StatusCode
+	// the request is recorded once the body has been read, to know its size
+	resp.Body = &countingBody{ReadCloser: resp.Body, onDone: func(n int64) {
+		info.Bytes = n
+		t.m.recordRequest(info)
+	}}
+	return resp, nil
+}
+
+// countingBody counts the bytes read from a response body,
+// calling onDone once when it is read to the end or closed.
+type countingBody struct {
+	io.ReadCloser
+	n      int64
+	once   sync.Once
+	onDone func(n int64)
+}
+
+func (c *countingBody) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	if err != nil {
+		c.once.Do(func() { c.onDone(c.n) })
+	}
+	return n, err
+}
+
+func (c *countingBody) Close() error {
+	c.once.Do(func() { c.onDone(c.n) })
+	return c.ReadCloser.Close()
+}
+
+// endpointName returns the endpoint of the request URL, for aggregating
+// stats: the method name for Subsonic API requests, and otherwise the
+// path with the segments which look like item IDs replaced by "{id}".
+func endpointName(u *url.URL) string {
+	path := strings.TrimSuffix(u.Path, "/")
+	if i := strings.LastIndex(path, "/rest/"); i >= 0 {
+		return strings.TrimSuffix(path[i+len("/rest/"):], ".view")
+	}
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if looksLikeID(seg) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// looksLikeID returns true for numbers and long hex strings (e.g. GUIDs).
+func looksLikeID(s string) bool {
+	if s == "" {
+		return false
+	}
+	digits := true
+	for _, r := range s {
+		isDigit := r >= '0' && r <= '9'
+		isHex := isDigit || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F') || r == '-'
+		if !isHex {
+			return false
+		}
+		digits = digits && isDigit
+	}
+	return digits || len(s) >= 16
+}
@@ -19,8 +19,6 @@ import (
 	"log"
 	"strings"
 	"unsafe"
-
-	"github.com/dweymouth/supersonic/backend/mediaprovider"
 )
 
 // os_remote_command_callback is called by Objective-C when incoming OS media commands are received.
@@ -50,7 +48,6 @@ func os_remote_command_callback(command C.Command, value C.double) {
 // MPMediaHandler is the handler for MacOS media controls and system events.
 type MPMediaHandler struct {
 	playbackManager *PlaybackManager
-	artURLLookup    func(string) (string, error)
 }
 
 // global recipient for Object-C callbacks from command center.
@@ -59,24 +56,19 @@ var mpMediaEventRecipient *MPMediaHandler
 
 // NewMPMediaHandler creates a new MPMediaHandler instances and sets it as the current recipient
 // for incoming system events.
-func InitMPMediaHandler(playbackManager *PlaybackManager, artURLLookup func(trackID string) (string, error)) error {
+func InitMPMediaHandler(playbackManager *PlaybackManager, nowPlaying *NowPlayingBroadcaster) error {
 	mp := &MPMediaHandler{
 		playbackManager: playbackManager,
-		artURLLookup:    artURLLookup,
 	}
 
 	// register remote commands and set callback target
 	mpMediaEventRecipient = mp
 	C.register_os_remote_commands()
 
-	mp.playbackManager.OnSongChange(func(track mediaprovider.MediaItem, _ *mediaprovider.Track) {
-		// Asynchronously because artwork fetching can take time
-		var meta *mediaprovider.MediaItemMetadata
-		if track != nil {
-			m := track.Metadata()
-			meta = &m
+	nowPlaying.OnChange(func(info *NowPlayingInfo) {
+		if !info.LyricsLoaded {
+			mp.updateMetadata(info)
 		}
-		go mp.updateMetadata(meta)
 	})
 
 	mp.playbackManager.OnStopped(func() {
@@ -100,18 +92,14 @@ func InitMPMediaHandler(playbackManager *PlaybackManager, artURLLookup func(trac
 	return nil
 }
 
-func (mp *MPMediaHandler) updateMetadata(meta *mediaprovider.MediaItemMetadata) {
-	var title, artist, artURL string
+func (mp *MPMediaHandler) updateMetadata(info *NowPlayingInfo) {
+	var title, artist, album, artURL string
 	var duration int
-	if meta != nil && meta.ID != "" {
+	if meta := info.Metadata; info.Item != nil && meta.ID != "" {
 		title = meta.Name
-		var err error
-		if artURL, err = mp.artURLLookup(meta.CoverArtID); err != nil {
-			if meta.CoverArtID != "" {
-				log.Printf("error fetching art url: %s", err.Error())
-			}
-		}
+		artURL = info.ArtFileURL
 		artist = strings.Join(meta.Artists, ", ")
+		album = meta.Album
 		duration = meta.Duration
 	}
 
@@ -121,12 +109,15 @@ func (mp *MPMediaHandler) updateMetadata(meta *mediaprovider.MediaItemMetadata)
 	cArtist := C.CString(artist)
 	defer C.free(unsafe.Pointer(cArtist))
 
+	cAlbum := C.CString(album)
+	defer C.free(unsafe.Pointer(cAlbum))
+
 	cArtURL := C.CString(artURL)
 	defer C.free(unsafe.Pointer(cArtURL))
 
 	cTrackDuration := C.double(duration)
 
-	C.set_os_now_playing_info(cTitle, cArtist, cArtURL, cTrackDuration)
+	C.set_os_now_playing_info(cTitle, cArtist, cAlbum, cArtURL, cTrackDuration)
 }
 
 /**
@@ -1,4 +1,4 @@
-//go:build !darwin
+//go:build !darwin && !windows
 
 package backend
 
@@ -6,7 +6,7 @@ import (
 	"errors"
 )
 
-func InitMPMediaHandler(playbackManager *PlaybackManager, artURLLookup func(trackID string) (string, error)) error {
-	// MPMediaHandler only supports macOS.
+func InitMPMediaHandler(playbackManager *PlaybackManager, nowPlaying *NowPlayingBroadcaster) error {
+	// MPMediaHandler only supports macOS and Windows.
 	return errors.New("unsupported platform")
 }
@@ -0,0 +1,59 @@
+//go:build windows
+
+package backend
+
+/**
+* This file handles implementation of Windows native controls via the System Media Transport Controls
+**/
+
+import (
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/smtc"
+)
+
+// InitMPMediaHandler connects the playback manager to the Windows System Media Transport Controls,
+// which drive the media keys and the OS media overlay.
+func InitMPMediaHandler(playbackManager *PlaybackManager, nowPlaying *NowPlayingBroadcaster) error {
+	pm := playbackManager
+	controls, err := smtc.New(smtc.Commands{
+		Play:     func() { _ = pm.Continue() },
+		Pause:    func() { _ = pm.Pause() },
+		Stop:     func() { _ = pm.Stop() },
+		Previous: func() { _ = pm.SeekBackOrPrevious() },
+		Next:     func() { _ = pm.SeekNext() },
+		Seek:     func(secs float64) { _ = pm.SeekSeconds(secs) },
+	})
+	if err != nil {
+		return err
+	}
+
+	nowPlaying.OnChange(func(info *NowPlayingInfo) {
+		if info.LyricsLoaded {
+			return // nothing changed that the OS displays
+		}
+		var meta smtc.Metadata
+		if info.Item != nil {
+			meta.Title = info.Metadata.Name
+			meta.Artist = strings.Join(info.Metadata.Artists, ", ")
+			meta.Album = info.Metadata.Album
+			meta.Duration = float64(info.Metadata.Duration)
+			// the OS can't read file URLs for the artwork
+			meta.ArtURL = info.ArtURL
+		}
+		controls.SetMetadata(meta)
+	})
+	pm.OnPlaying(func() { controls.SetState(smtc.Playing) })
+	pm.OnPaused(func() { controls.SetState(smtc.Paused) })
+	pm.OnStopped(func() { controls.SetState(smtc.Stopped) })
+
+	lastPos := -1
+	pm.OnPlayTimeUpdate(func(cur, _ float64, seeked bool) {
+		// the timeline only shows whole seconds
+		if pos := int(cur); pos != lastPos || seeked {
+			lastPos = pos
+			controls.SetPosition(cur)
+		}
+	})
+	return nil
+}
@@ -47,25 +47,28 @@ void register_os_remote_commands() {
 /**
  * C bridge setting "Now Playing" information on macOS for media playback using the native APIs.
  */
-void set_os_now_playing_info(const char *title, const char *artist, const char *coverArtFileURL, double trackDuration) {
-    NSString *coverArtLocationString = [NSString stringWithUTF8String:coverArtFileURL];
-    NSURL *coverArtURL = [NSURL URLWithString:coverArtLocationString];
-    NSImage *coverArtImage = [[NSImage alloc] initWithContentsOfURL:coverArtURL];
-
-    MPMediaItemArtwork *coverArt = [[MPMediaItemArtwork alloc] initWithBoundsSize:coverArtImage.size requestHandler:^NSImage * _Nonnull(CGSize size) {
-        return coverArtImage;
-    }];
-
-    MPNowPlayingInfoCenter *infoCenter = [MPNowPlayingInfoCenter defaultCenter];
-    NSDictionary *nowPlayingInfo = @{
+void set_os_now_playing_info(const char *title, const char *artist, const char *album, const char *coverArtFileURL, double trackDuration) {
+    NSMutableDictionary *nowPlayingInfo = [@{
         MPMediaItemPropertyTitle: [NSString stringWithUTF8String:title],
         MPMediaItemPropertyArtist: [NSString stringWithUTF8String:artist],
-        MPMediaItemPropertyArtwork: coverArt,
+        MPMediaItemPropertyAlbumTitle: [NSString stringWithUTF8String:album],
         MPNowPlayingInfoPropertyElapsedPlaybackTime: @(0),
+        MPNowPlayingInfoPropertyPlaybackRate: @(1),
         MPMediaItemPropertyPlaybackDuration: @(trackDuration) // Expects 'NSNumber'
-    };
-    
-    infoCenter.nowPlayingInfo = nowPlayingInfo;
+    } mutableCopy];
+
+    // the cover may not be available, and the artwork must have an image
+    NSString *coverArtLocationString = [NSString stringWithUTF8String:coverArtFileURL];
+    NSURL *coverArtURL = [NSURL URLWithString:coverArtLocationString];
+    NSImage *coverArtImage = coverArtURL ? [[NSImage alloc] initWithContentsOfURL:coverArtURL] : nil;
+    if (coverArtImage) {
+        nowPlayingInfo[MPMediaItemPropertyArtwork] = [[MPMediaItemArtwork alloc] initWithBoundsSize:coverArtImage.size requestHandler:^NSImage * _Nonnull(CGSize size) {
+            return coverArtImage;
+        }];
+    }
+
+    MPNowPlayingInfoCenter *infoCenter = [MPNowPlayingInfoCenter defaultCenter];
+    infoCenter.nowPlayingInfo = [nowPlayingInfo copy];
 }
 
 /**
@@ -76,6 +79,8 @@ void update_os_now_playing_info_position(double positionSeconds) {
     MPNowPlayingInfoCenter *infoCenter = [MPNowPlayingInfoCenter defaultCenter];
     NSMutableDictionary *updatedInfo = [infoCenter.nowPlayingInfo mutableCopy];
     updatedInfo[MPNowPlayingInfoPropertyElapsedPlaybackTime] = @(positionSeconds);
+    // the OS extrapolates the elapsed time while the rate is non-zero
+    updatedInfo[MPNowPlayingInfoPropertyPlaybackRate] = @(infoCenter.playbackState == MPNowPlayingPlaybackStatePlaying ? 1 : 0);
     infoCenter.nowPlayingInfo = [updatedInfo copy];
 }
 
@@ -36,19 +36,26 @@ type MPRISHandler struct {
 	// Function called if the player is requested to bring its UI to the front.
 	OnRaise func() error
 
-	// Function to look up the artwork URL for a given track ID
-	ArtURLLookup func(trackID string) (string, error)
+	// Function to look up the artwork URL for a given cover ID,
+	// for tracks in the queue other than the now playing one
+	ArtURLLookup func(coverID string) (string, error)
+
+	// Function to look up the server's playlists, exposed through
+	// the MPRIS Playlists interface
+	PlaylistsLookup func() ([]*mediaprovider.Playlist, error)
 
 	connErr      error
 	playerName   string
 	curTrackPath string // empty for no track
 	pm           *PlaybackManager
+	np           *NowPlayingBroadcaster
 	s            *server.Server
 	evt          *events.EventHandler
+	stop         chan struct{}
 }
 
-func NewMPRISHandler(playerName string, pm *PlaybackManager) *MPRISHandler {
-	m := &MPRISHandler{playerName: playerName, pm: pm, connErr: errors.New("not started")}
+func NewMPRISHandler(playerName string, pm *PlaybackManager, np *NowPlayingBroadcaster) *MPRISHandler {
+	m := &MPRISHandler{playerName: playerName, pm: pm, np: np, connErr: errors.New("not started")}
 	m.s = server.NewServer(playerName, m, m)
 	m.evt = events.NewEventHandler(m.s)
 
@@ -62,12 +69,20 @@ func NewMPRISHandler(playerName string, pm *PlaybackManager) *MPRISHandler {
 		if tr == nil {
 			m.curTrackPath = ""
 		} else {
-			m.curTrackPath = dbusTrackIDPrefix + encodeTrackId(tr.Metadata().ID)
+			m.curTrackPath = string(trackObjectPath(tr, pm.NowPlayingIndex()))
 		}
 		if m.connErr == nil {
 			m.evt.Player.OnTitle()
 		}
+		m.emitTrackListReplaced()
+	})
+	np.OnChange(func(info *NowPlayingInfo) {
+		// artwork of the new track is ready
+		if m.connErr == nil && !info.LyricsLoaded {
+			m.evt.Player.OnTitle()
+		}
 	})
+	pm.OnQueueChange(m.emitTrackListReplaced)
 	pm.OnVolumeChange(func(vol int) {
 		if m.connErr == nil {
 			m.evt.Player.OnVolume()
@@ -93,9 +108,10 @@ func NewMPRISHandler(playerName string, pm *PlaybackManager) *MPRISHandler {
 // Starts listening for MPRIS events.
 func (m *MPRISHandler) Start() {
 	m.connErr = nil
+	m.stop = make(chan struct{})
 	go func() {
 		// exits early with err if unable to establish D-Bus connection
-		m.connErr = m.s.Listen()
+		m.connErr = m.listen()
 	}()
 }
 
@@ -103,6 +119,7 @@ func (m *MPRISHandler) Start() {
 func (m *MPRISHandler) Shutdown() {
 	if m.connErr == nil {
 		m.s.Stop()
+		close(m.stop)
 		m.connErr = errors.New("stopped")
 	}
 }
@@ -136,7 +153,7 @@ func (m *MPRISHandler) Raise() error {
 }
 
 func (m *MPRISHandler) HasTrackList() (bool, error) {
-	return false, nil
+	return true, nil
 }
 
 func (m *MPRISHandler) SupportedUriSchemes() ([]string, error) {
@@ -251,32 +268,52 @@ func (m *MPRISHandler) Metadata() (types.Metadata, error) {
 		trackObjPath = m.curTrackPath
 	}
 	status := m.pm.PlayerStatus()
+	var item mediaprovider.MediaItem
+	if np := m.pm.NowPlaying(); np != nil && status.State != player.Stopped {
+		item = np
+	}
+	return m.itemMetadata(item, trackObjPath, status.Duration), nil
+}
 
+// itemMetadata returns the MPRIS metadata of the queue item (which may be nil),
+// with the given track object path and length in seconds, if known.
+func (m *MPRISHandler) itemMetadata(item mediaprovider.MediaItem, trackObjPath string, length float64) types.Metadata {
 	var meta mediaprovider.MediaItemMetadata
 	// metadata that can come only from tracks
-	var discNumber, trackNumber, userRating, playCount, year int
-	var genre string
+	var discNumber, trackNumber, userRating, playCount, year, bpm int
+	var genres []string
+	var composer, comment string
 
-	if np := m.pm.NowPlaying(); np != nil && status.State != player.Stopped {
-		meta = np.Metadata()
-		if track, ok := np.(*mediaprovider.Track); ok {
+	if item != nil {
+		meta = item.Metadata()
+		if track, ok := item.(*mediaprovider.Track); ok {
 			discNumber = track.DiscNumber
 			trackNumber = track.TrackNumber
 			userRating = track.Rating
 			playCount = track.PlayCount
 			year = track.Year
-			genre = track.Genre
+			genres = track.Genres
+			bpm = track.BPM
+			composer = track.Composer
+			comment = track.Comment
 		}
 	}
 	var artURL string
-	if meta.ID != "" && m.ArtURLLookup != nil {
+	if info := m.np.Current(); item != nil && info.Item == item {
+		artURL = info.ArtURL
+	} else if meta.ID != "" && m.ArtURLLookup != nil {
 		if u, err := m.ArtURLLookup(meta.CoverArtID); err == nil {
 			artURL = u
 		}
 	}
+	if length <= 0 {
+		// player may not know the duration yet right after a track change,
+		// and AVRCP targets (car head units) don't re-read it once known
+		length = float64(meta.Duration)
+	}
 	mprisMeta := types.Metadata{
 		TrackId:     dbus.ObjectPath(trackObjPath),
-		Length:      secondsToMicroseconds(status.Duration),
+		Length:      secondsToMicroseconds(length),
 		Title:       meta.Name,
 		Album:       meta.Album,
 		Artist:      meta.Artists,
@@ -286,13 +323,20 @@ func (m *MPRISHandler) Metadata() (types.Metadata, error) {
 		UseCount:    playCount,
 		ArtUrl:      artURL,
 	}
-	if genre != "" {
-		mprisMeta.Genre = []string{genre}
+	if len(genres) > 0 {
+		mprisMeta.Genre = genres
+	}
+	if composer != "" {
+		mprisMeta.Composer = []string{composer}
 	}
+	if comment != "" {
+		mprisMeta.Comment = []string{comment}
+	}
+	mprisMeta.AudioBPM = bpm
 	if year != 0 {
 		mprisMeta.ContentCreated = strconv.Itoa(year)
 	}
-	return mprisMeta, nil
+	return mprisMeta
 }
 
 func (m *MPRISHandler) Volume() (float64, error) {
@@ -351,3 +395,8 @@ func encodeTrackId(id string) string {
 	data := []byte(id)
 	return base32.StdEncoding.WithPadding('0').EncodeToString(data)
 }
+
+func decodeTrackId(encoded string) (string, error) {
+	data, err := base32.StdEncoding.WithPadding('0').DecodeString(encoded)
+	return string(data), err
+}
@@ -0,0 +1,466 @@
+package backend
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+	"github.com/quarckster/go-mpris-server/pkg/types"
+)
+
+// go-mpris-server only implements the org.mpris.MediaPlayer2 and .Player
+// interfaces, and its Properties handler can't be extended, so the bus name
+// is claimed and all the interfaces exported here instead of by its server.
+// Its event handler is still used to emit property changes.
+
+const (
+	mprisObjectPath     = "/org/mpris/MediaPlayer2"
+	mprisRootIface      = "org.mpris.MediaPlayer2"
+	mprisPlayerIface    = "org.mpris.MediaPlayer2.Player"
+	mprisTrackListIface = "org.mpris.MediaPlayer2.TrackList"
+	mprisPlaylistsIface = "org.mpris.MediaPlayer2.Playlists"
+
+	dbusPlaylistIDPrefix = "/Supersonic/Playlist/"
+
+	// max number of queue items exposed in the TrackList,
+	// starting shortly before the now playing item
+	mprisMaxTracks        = 200
+	mprisTracksBeforeCurr = 20
+)
+
+var mprisPlaylistOrderings = []string{"Alphabetical", "UserDefined"}
+
+// mprisPlaylist is the D-Bus (oss) struct describing a playlist.
+type mprisPlaylist struct {
+	ID   dbus.ObjectPath
+	Name string
+	Icon string
+}
+
+// mprisMaybePlaylist is the D-Bus (b(oss)) ActivePlaylist struct.
+type mprisMaybePlaylist struct {
+	Valid    bool
+	Playlist mprisPlaylist
+}
+
+type mprisPropGetter = func() (any, error)
+
+func mprisProp[T any](f func() (T, error)) mprisPropGetter {
+	return func() (any, error) { return f() }
+}
+
+// listen claims the MPRIS bus name and exports the MPRIS interfaces.
+// It blocks until the handler is shut down.
+func (m *MPRISHandler) listen() error {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return err
+	}
+	m.s.Conn = conn // used by the event handler
+	serviceName := "org.mpris.MediaPlayer2." + m.playerName
+	reply, err := conn.RequestName(serviceName, dbus.NameFlagReplaceExisting)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		conn.Close()
+		return errors.New("unable to claim " + serviceName)
+	}
+	if err := m.exportInterfaces(conn); err != nil {
+		conn.ReleaseName(serviceName)
+		conn.Close()
+		return err
+	}
+	<-m.stop
+	return nil
+}
+
+func (m *MPRISHandler) exportInterfaces(conn *dbus.Conn) error {
+	tables := map[string]map[string]any{
+		mprisRootIface: {
+			"Raise": func() *dbus.Error { return makeDBusError(m.Raise()) },
+			"Quit":  func() *dbus.Error { return makeDBusError(m.Quit()) },
+		},
+		mprisPlayerIface: {
+			"Next":      func() *dbus.Error { return makeDBusError(m.Next()) },
+			"Previous":  func() *dbus.Error { return makeDBusError(m.Previous()) },
+			"Pause":     func() *dbus.Error { return makeDBusError(m.Pause()) },
+			"PlayPause": func() *dbus.Error { return makeDBusError(m.PlayPause()) },
+			"Stop":      func() *dbus.Error { return makeDBusError(m.Stop()) },
+			"Play":      func() *dbus.Error { return makeDBusError(m.Play()) },
+			"Seek": func(offset int64) *dbus.Error {
+				return makeDBusError(m.Seek(types.Microseconds(offset)))
+			},
+			"SetPosition": func(trackID dbus.ObjectPath, pos int64) *dbus.Error {
+				return makeDBusError(m.SetPosition(string(trackID), types.Microseconds(pos)))
+			},
+			"OpenUri": func(uri string) *dbus.Error { return makeDBusError(m.OpenUri(uri)) },
+		},
+		mprisTrackListIface: {
+			"GetTracksMetadata": func(ids []dbus.ObjectPath) ([]map[string]dbus.Variant, *dbus.Error) {
+				return m.GetTracksMetadata(ids), nil
+			},
+			"AddTrack": func(string, dbus.ObjectPath, bool) *dbus.Error {
+				return makeDBusError(errNotSupported)
+			},
+			"RemoveTrack": func(dbus.ObjectPath) *dbus.Error { return makeDBusError(errNotSupported) },
+			"GoTo":        func(id dbus.ObjectPath) *dbus.Error { return makeDBusError(m.GoTo(id)) },
+		},
+		mprisPlaylistsIface: {
+			"ActivatePlaylist": func(id dbus.ObjectPath) *dbus.Error {
+				return makeDBusError(m.ActivatePlaylist(id))
+			},
+			"GetPlaylists": func(index, maxCount uint32, order string, reverse bool) ([]mprisPlaylist, *dbus.Error) {
+				pl, err := m.GetPlaylists(index, maxCount, order, reverse)
+				return pl, makeDBusError(err)
+			},
+		},
+		"org.freedesktop.DBus.Properties": {
+			"Get":    m.getProperty,
+			"GetAll": m.getAllProperties,
+			"Set":    m.setProperty,
+		},
+		"org.freedesktop.DBus.Introspectable": {
+			"Introspect": introspect.Introspectable(mprisIntrospectXML).Introspect,
+		},
+	}
+	for iface, methods := range tables {
+		if err := conn.ExportMethodTable(methods, mprisObjectPath, iface); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MPRISHandler) propertyGetters(iface string) map[string]mprisPropGetter {
+	switch iface {
+	case mprisRootIface:
+		return map[string]mprisPropGetter{
+			"CanQuit":             mprisProp(m.CanQuit),
+			"CanRaise":            mprisProp(m.CanRaise),
+			"HasTrackList":        mprisProp(m.HasTrackList),
+			"Identity":            mprisProp(m.Identity),
+			"SupportedUriSchemes": mprisProp(m.SupportedUriSchemes),
+			"SupportedMimeTypes":  mprisProp(m.SupportedMimeTypes),
+		}
+	case mprisPlayerIface:
+		return map[string]mprisPropGetter{
+			"PlaybackStatus": mprisProp(m.PlaybackStatus),
+			"LoopStatus":     mprisProp(m.LoopStatus),
+			"Rate":           mprisProp(m.Rate),
+			"Metadata": func() (any, error) {
+				meta, err := m.Metadata()
+				return meta.MakeMap(), err
+			},
+			"Volume":        mprisProp(m.Volume),
+			"Position":      mprisProp(m.Position),
+			"MinimumRate":   mprisProp(m.MinimumRate),
+			"MaximumRate":   mprisProp(m.MaximumRate),
+			"CanGoNext":     mprisProp(m.CanGoNext),
+			"CanGoPrevious": mprisProp(m.CanGoPrevious),
+			"CanPlay":       mprisProp(m.CanPlay),
+			"CanPause":      mprisProp(m.CanPause),
+			"CanSeek":       mprisProp(m.CanSeek),
+			"CanControl":    mprisProp(m.CanControl),
+		}
+	case mprisTrackListIface:
+		return map[string]mprisPropGetter{
+			"Tracks":        func() (any, error) { return m.Tracks(), nil },
+			"CanEditTracks": func() (any, error) { return false, nil },
+		}
+	case mprisPlaylistsIface:
+		return map[string]mprisPropGetter{
+			"PlaylistCount": mprisProp(m.PlaylistCount),
+			"Orderings":     func() (any, error) { return mprisPlaylistOrderings, nil },
+			"ActivePlaylist": func() (any, error) {
+				// the queue isn't tracked as being a playlist
+				return mprisMaybePlaylist{Playlist: mprisPlaylist{ID: "/"}}, nil
+			},
+		}
+	}
+	return nil
+}
+
+func (m *MPRISHandler) getProperty(iface, name string) (dbus.Variant, *dbus.Error) {
+	getters := m.propertyGetters(iface)
+	if getters == nil {
+		return dbus.Variant{}, prop.ErrIfaceNotFound
+	}
+	get, ok := getters[name]
+	if !ok {
+		return dbus.Variant{}, prop.ErrPropNotFound
+	}
+	v, err := get()
+	if err != nil {
+		return dbus.Variant{}, dbus.MakeFailedError(err)
+	}
+	return dbus.MakeVariant(v), nil
+}
+
+func (m *MPRISHandler) getAllProperties(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	getters := m.propertyGetters(iface)
+	if getters == nil {
+		return nil, prop.ErrIfaceNotFound
+	}
+	props := make(map[string]dbus.Variant, len(getters))
+	for name, get := range getters {
+		v, err := get()
+		if err != nil {
+			return nil, dbus.MakeFailedError(err)
+		}
+		props[name] = dbus.MakeVariant(v)
+	}
+	return props, nil
+}
+
+func (m *MPRISHandler) setProperty(iface, name string, value dbus.Variant) *dbus.Error {
+	if iface != mprisPlayerIface {
+		return prop.ErrReadOnly
+	}
+	var err error
+	switch name {
+	case "Volume":
+		v, ok := value.Value().(float64)
+		if !ok {
+			return prop.ErrInvalidArg
+		}
+		err = m.SetVolume(v)
+	case "Rate":
+		err = m.SetRate(1)
+	case "LoopStatus":
+		s, ok := value.Value().(string)
+		if !ok {
+			return prop.ErrInvalidArg
+		}
+		err = m.SetLoopStatus(types.LoopStatus(s))
+	default:
+		return prop.ErrReadOnly
+	}
+	if err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	m.s.Conn.Emit(mprisObjectPath, "org.freedesktop.DBus.Properties.PropertiesChanged",
+		iface, map[string]dbus.Variant{name: value}, []string{})
+	return nil
+}
+
+// TrackList implementation
+
+// Tracks returns the object paths of the queue items exposed in the TrackList.
+func (m *MPRISHandler) Tracks() []dbus.ObjectPath {
+	queue := m.pm.GetPlayQueue()
+	start, end := mprisTrackWindow(len(queue), m.pm.NowPlayingIndex())
+	tracks := make([]dbus.ObjectPath, 0, end-start)
+	for i := start; i < end; i++ {
+		tracks = append(tracks, trackObjectPath(queue[i], i))
+	}
+	return tracks
+}
+
+func (m *MPRISHandler) GetTracksMetadata(ids []dbus.ObjectPath) []map[string]dbus.Variant {
+	queue := m.pm.GetPlayQueue()
+	metas := make([]map[string]dbus.Variant, 0, len(ids))
+	for _, id := range ids {
+		if idx, ok := queueIndexFromTrackPath(id, queue); ok {
+			item := queue[idx]
+			meta := m.itemMetadata(item, string(id), float64(item.Metadata().Duration))
+			metas = append(metas, meta.MakeMap())
+		}
+	}
+	return metas
+}
+
+func (m *MPRISHandler) GoTo(id dbus.ObjectPath) error {
+	idx, ok := queueIndexFromTrackPath(id, m.pm.GetPlayQueue())
+	if !ok {
+		return errors.New("track not in queue")
+	}
+	return m.pm.PlayTrackAt(idx)
+}
+
+// emitTrackListReplaced notifies clients that the tracks exposed in the TrackList changed.
+func (m *MPRISHandler) emitTrackListReplaced() {
+	if m.connErr != nil || m.s.Conn == nil {
+		return
+	}
+	current := dbus.ObjectPath(noTrackObjectPath)
+	if m.curTrackPath != "" {
+		current = dbus.ObjectPath(m.curTrackPath)
+	}
+	m.s.Conn.Emit(mprisObjectPath, mprisTrackListIface+".TrackListReplaced", m.Tracks(), current)
+	m.s.Conn.Emit(mprisObjectPath, "org.freedesktop.DBus.Properties.PropertiesChanged",
+		mprisTrackListIface, map[string]dbus.Variant{}, []string{"Tracks"})
+}
+
+// mprisTrackWindow returns the range of queue indexes exposed in the TrackList.
+func mprisTrackWindow(queueLen, nowPlaying int) (start, end int) {
+	start = clamp(nowPlaying-mprisTracksBeforeCurr, 0, max(queueLen-mprisMaxTracks, 0))
+	end = min(start+mprisMaxTracks, queueLen)
+	return start, end
+}
+
+// trackObjectPath returns the TrackList ID of the queue item at the given index.
+// The index is included since the same track may be in the queue more than once.
+func trackObjectPath(item mediaprovider.MediaItem, idx int) dbus.ObjectPath {
+	return dbus.ObjectPath(dbusTrackIDPrefix + encodeTrackId(item.Metadata().ID) + "/" + strconv.Itoa(idx))
+}
+
+func queueIndexFromTrackPath(path dbus.ObjectPath, queue []mediaprovider.MediaItem) (int, bool) {
+	s := string(path)
+	idx, err := strconv.Atoi(s[strings.LastIndex(s, "/")+1:])
+	if err != nil || idx < 0 || idx >= len(queue) || trackObjectPath(queue[idx], idx) != path {
+		return 0, false
+	}
+	return idx, true
+}
+
+// Playlists implementation
+
+func (m *MPRISHandler) PlaylistCount() (uint32, error) {
+	if m.PlaylistsLookup == nil {
+		return 0, nil
+	}
+	playlists, err := m.PlaylistsLookup()
+	return uint32(len(playlists)), err
+}
+
+func (m *MPRISHandler) GetPlaylists(index, maxCount uint32, order string, reverse bool) ([]mprisPlaylist, error) {
+	if m.PlaylistsLookup == nil {
+		return []mprisPlaylist{}, nil
+	}
+	playlists, err := m.PlaylistsLookup()
+	if err != nil {
+		return nil, err
+	}
+	playlists = slices.Clone(playlists)
+	if order == "Alphabetical" {
+		slices.SortStableFunc(playlists, func(a, b *mediaprovider.Playlist) int {
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+	}
+	if reverse {
+		slices.Reverse(playlists)
+	}
+	start := min(int(index), len(playlists))
+	end := min(start+int(maxCount), len(playlists))
+	result := make([]mprisPlaylist, 0, end-start)
+	for _, p := range playlists[start:end] {
+		var icon string
+		if p.CoverArtID != "" && m.ArtURLLookup != nil {
+			icon, _ = m.ArtURLLookup(p.CoverArtID)
+		}
+		result = append(result, mprisPlaylist{
+			ID:   dbus.ObjectPath(dbusPlaylistIDPrefix + encodeTrackId(p.ID)),
+			Name: p.Name,
+			Icon: icon,
+		})
+	}
+	return result, nil
+}
+
+func (m *MPRISHandler) ActivatePlaylist(id dbus.ObjectPath) error {
+	encoded, ok := strings.CutPrefix(string(id), dbusPlaylistIDPrefix)
+	if !ok {
+		return errors.New("invalid playlist ID")
+	}
+	playlistID, err := decodeTrackId(encoded)
+	if err != nil {
+		return err
+	}
+	return m.pm.PlayPlaylist(playlistID, 0, false)
+}
+
+func makeDBusError(err error) *dbus.Error {
+	if err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+const mprisIntrospectXML = `<node name="/org/mpris/MediaPlayer2">
+  <interface name="org.mpris.MediaPlayer2">
+    <method name="Raise"/>
+    <method name="Quit"/>
+    <property name="CanQuit" type="b" access="read"/>
+    <property name="CanRaise" type="b" access="read"/>
+    <property name="HasTrackList" type="b" access="read"/>
+    <property name="Identity" type="s" access="read"/>
+    <property name="SupportedUriSchemes" type="as" access="read"/>
+    <property name="SupportedMimeTypes" type="as" access="read"/>
+  </interface>
+  <interface name="org.mpris.MediaPlayer2.Player">
+    <method name="Next"/>
+    <method name="Previous"/>
+    <method name="Pause"/>
+    <method name="PlayPause"/>
+    <method name="Stop"/>
+    <method name="Play"/>
+    <method name="Seek">
+      <arg direction="in" type="x" name="Offset"/>
+    </method>
+    <method name="SetPosition">
+      <arg direction="in" type="o" name="TrackId"/>
+      <arg direction="in" type="x" name="Position"/>
+    </method>
+    <method name="OpenUri">
+      <arg direction="in" type="s" name="Uri"/>
+    </method>
+    <property name="PlaybackStatus" type="s" access="read"/>
+    <property name="LoopStatus" type="s" access="readwrite"/>
+    <property name="Rate" type="d" access="readwrite"/>
+    <property name="Metadata" type="a{sv}" access="read"/>
+    <property name="Volume" type="d" access="readwrite"/>
+    <property name="Position" type="x" access="read"/>
+    <property name="MinimumRate" type="d" access="read"/>
+    <property name="MaximumRate" type="d" access="read"/>
+    <property name="CanGoNext" type="b" access="read"/>
+    <property name="CanGoPrevious" type="b" access="read"/>
+    <property name="CanPlay" type="b" access="read"/>
+    <property name="CanPause" type="b" access="read"/>
+    <property name="CanSeek" type="b" access="read"/>
+    <property name="CanControl" type="b" access="read"/>
+    <signal name="Seeked">
+      <arg name="Position" type="x"/>
+    </signal>
+  </interface>
+  <interface name="org.mpris.MediaPlayer2.TrackList">
+    <method name="GetTracksMetadata">
+      <arg direction="in" name="TrackIds" type="ao"/>
+      <arg direction="out" name="Metadata" type="aa{sv}"/>
+    </method>
+    <method name="AddTrack">
+      <arg direction="in" name="Uri" type="s"/>
+      <arg direction="in" name="AfterTrack" type="o"/>
+      <arg direction="in" name="SetAsCurrent" type="b"/>
+    </method>
+    <method name="RemoveTrack">
+      <arg direction="in" name="TrackId" type="o"/>
+    </method>
+    <method name="GoTo">
+      <arg direction="in" name="TrackId" type="o"/>
+    </method>
+    <property name="Tracks" type="ao" access="read"/>
+    <property name="CanEditTracks" type="b" access="read"/>
+    <signal name="TrackListReplaced">
+      <arg name="Tracks" type="ao"/>
+      <arg name="CurrentTrack" type="o"/>
+    </signal>
+  </interface>
+  <interface name="org.mpris.MediaPlayer2.Playlists">
+    <method name="ActivatePlaylist">
+      <arg direction="in" name="PlaylistId" type="o"/>
+    </method>
+    <method name="GetPlaylists">
+      <arg direction="in" name="Index" type="u"/>
+      <arg direction="in" name="MaxCount" type="u"/>
+      <arg direction="in" name="Order" type="s"/>
+      <arg direction="in" name="ReverseOrder" type="b"/>
+      <arg direction="out" name="Playlists" type="a(oss)"/>
+    </method>
+    <property name="PlaylistCount" type="u" access="read"/>
+    <property name="Orderings" type="as" access="read"/>
+    <property name="ActivePlaylist" type="(b(oss))" access="read"/>
+  </interface>` + introspect.IntrospectDataString + prop.IntrospectDataString + `
+</node>`
@@ -0,0 +1,39 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// musicBrainzRequest queries an entity of the MusicBrainz web service
+// and decodes the JSON response into out.
+func musicBrainzRequest(entity string, params url.Values, out any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	params.Set("fmt", "json")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://musicbrainz.org/ws/2/"+entity+"/?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	// MusicBrainz requires an identifying user agent
+	req.Header.Add("User-Agent", "Supersonic ( https://github.com/dweymouth/supersonic )")
+	req.Header.Add("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error from MusicBrainz: status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode MusicBrainz response: %w", err)
+	}
+	return nil
+}
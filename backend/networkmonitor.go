@@ -0,0 +1,151 @@
+package backend
+
+import (
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// the transcode bit rates (kbps) that adaptive streaming steps between
+var adaptiveBitRates = []int{64, 96, 128, 192, 256, 320}
+
+const (
+	// fraction of the measured throughput which the stream bit rate may use,
+	// leaving headroom for fluctuations and other traffic
+	adaptiveThroughputHeadroom = 0.5
+	// don't raise the bit rate again until this long after a buffer underrun.
+	// Without throughput measurements (pre-caching is off by default), the
+	// bit rate is also raised one step per holdoff without underruns.
+	adaptiveRaiseHoldoff = 2 * time.Minute
+	// smaller downloads are dominated by latency rather than throughput
+	minThroughputSampleBytes = 256 * 1024
+)
+
+// NetworkMonitor measures the network throughput of stream downloads and
+// the buffer underruns of the local player, and from them picks the max
+// bit rate to request transcoding at when adaptive bit rate is enabled.
+// The bit rate is chosen when each track's stream URL is requested,
+// so changes take effect from the next track.
+type NetworkMonitor struct {
+	cfg *TranscodingConfig
+
+	mu             sync.Mutex
+	throughputKbps float64 // moving average, 0 if unmeasured
+	bitRate        int     // 0 if no measurements yet
+	lastUnderrun   time.Time
+	lastSample     time.Time // of throughput
+	lastRaise      time.Time
+}
+
+func NewNetworkMonitor(cfg *TranscodingConfig) *NetworkMonitor {
+	return &NetworkMonitor{cfg: cfg}
+}
+
+// ReportThroughput records the download of a stream of the given size.
+func (n *NetworkMonitor) ReportThroughput(bytes int64, dur time.Duration) {
+	if bytes < minThroughputSampleBytes || dur <= 0 {
+		return
+	}
+	kbps := float64(bytes) * 8 / 1000 / dur.Seconds()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.lastSample = time.Now()
+	if n.throughputKbps == 0 {
+		n.throughputKbps = kbps
+	} else {
+		n.throughputKbps = 0.7*n.throughputKbps + 0.3*kbps
+	}
+	target := n.levelFor(n.throughputKbps * adaptiveThroughputHeadroom)
+	switch {
+	case n.bitRate == 0 || target < n.bitRate:
+		n.bitRate = target
+	case target > n.bitRate && time.Since(n.lastUnderrun) > adaptiveRaiseHoldoff:
+		// raise one step at a time
+		n.bitRate = n.stepFrom(n.bitRate, 1)
+		n.lastRaise = time.Now()
+	}
+}
+
+// ReportUnderrun records that playback stalled waiting for the stream to buffer.
+func (n *NetworkMonitor) ReportUnderrun() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.lastUnderrun = time.Now()
+	if n.bitRate == 0 {
+		n.bitRate = n.clamp(adaptiveBitRates[len(adaptiveBitRates)-1])
+	}
+	n.bitRate = n.stepFrom(n.bitRate, -1)
+	log.Printf("buffer underrun, lowering stream bit rate to %d kbps", n.bitRate)
+}
+
+// MaxBitRate returns the max bit rate in kbps to request transcoding at,
+// or 0 if adaptive bit rate is disabled or nothing has been measured yet.
+func (n *NetworkMonitor) MaxBitRate() int {
+	if !n.cfg.AdaptiveBitRate {
+		return 0
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.bitRate == 0 {
+		return 0
+	}
+	n.recover(time.Now())
+	return n.clamp(n.bitRate)
+}
+
+// recover raises the bit rate a step if it has been lowered and there
+// has been neither an underrun nor a throughput measurement to go by
+// for the holdoff, since otherwise it would never be raised again.
+func (n *NetworkMonitor) recover(now time.Time) {
+	if now.Sub(n.lastUnderrun) <= adaptiveRaiseHoldoff ||
+		now.Sub(n.lastSample) <= adaptiveRaiseHoldoff ||
+		now.Sub(n.lastRaise) <= adaptiveRaiseHoldoff {
+		return
+	}
+	if raised := n.stepFrom(n.bitRate, 1); raised != n.bitRate {
+		n.bitRate = raised
+		n.lastRaise = now
+		log.Printf("no buffer underruns, raising stream bit rate to %d kbps", n.bitRate)
+	}
+}
+
+// ThroughputKbps returns the moving average of the measured throughput.
+func (n *NetworkMonitor) ThroughputKbps() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return int(n.throughputKbps)
+}
+
+// levelFor returns the highest bit rate step not above kbps, within the configured bounds.
+func (n *NetworkMonitor) levelFor(kbps float64) int {
+	level := adaptiveBitRates[0]
+	for _, r := range adaptiveBitRates {
+		if float64(r) <= kbps {
+			level = r
+		}
+	}
+	return n.clamp(level)
+}
+
+// stepFrom returns the bit rate step dir steps from bitRate, within the configured bounds.
+func (n *NetworkMonitor) stepFrom(bitRate, dir int) int {
+	i, found := slices.BinarySearch(adaptiveBitRates, bitRate)
+	if found || dir < 0 {
+		i += dir
+	}
+	i = clamp(i, 0, len(adaptiveBitRates)-1)
+	return n.clamp(adaptiveBitRates[i])
+}
+
+func (n *NetworkMonitor) clamp(bitRate int) int {
+	lo, hi := n.cfg.AdaptiveMinBitRate, n.cfg.AdaptiveMaxBitRate
+	if hi > 0 && bitRate > hi {
+		bitRate = hi
+	}
+	if lo > 0 && bitRate < lo {
+		bitRate = lo
+	}
+	return bitRate
+}
@@ -0,0 +1,103 @@
+package backend
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// max number of recently added albums to check each poll
+const newMusicMaxAlbumsChecked = 50
+
+// NewMusicWatcher periodically polls the active server for newly added
+// albums, publishing an EventNewAlbums when any are found. The polling
+// interval is set per server by ServerConfig.NewMusicCheckMinutes.
+type NewMusicWatcher struct {
+	sm  *ServerManager
+	bus *EventBus
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func NewNewMusicWatcher(ctx context.Context, sm *ServerManager, bus *EventBus) *NewMusicWatcher {
+	w := &NewMusicWatcher{sm: sm, bus: bus}
+	sm.OnServerConnected(func() { w.start(ctx) })
+	sm.OnLogout(w.stop)
+	return w
+}
+
+func (w *NewMusicWatcher) start(ctx context.Context) {
+	w.stop()
+	server := w.sm.CurrentServerConfig()
+	if server == nil || server.NewMusicCheckMinutes <= 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	w.mu.Lock()
+	w.cancel = cancel
+	w.mu.Unlock()
+
+	go func() {
+		w.check(server) // check once at connection
+		t := time.NewTicker(time.Duration(server.NewMusicCheckMinutes) * time.Minute)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				w.check(server)
+			}
+		}
+	}()
+}
+
+func (w *NewMusicWatcher) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+}
+
+// check fetches the recently added albums and publishes those added
+// since the last check. The first check for a server only records
+// the newest album, so the existing library isn't announced as new.
+func (w *NewMusicWatcher) check(server *ServerConfig) {
+	mp := w.sm.Server
+	if mp == nil || !slices.Contains(mp.AlbumSortOrders(), "Recently Added") {
+		return
+	}
+	var newAlbums []*mediaprovider.Album
+	foundLastSeen := false
+	iter := mp.IterateAlbums("Recently Added", mediaprovider.NewAlbumFilter(mediaprovider.AlbumFilterOptions{}))
+	for al := iter.Next(); al != nil && len(newAlbums) < newMusicMaxAlbumsChecked; al = iter.Next() {
+		if al.ID == server.NewMusicLastSeenAlbumID {
+			foundLastSeen = true
+			break
+		}
+		newAlbums = append(newAlbums, al)
+	}
+	if len(newAlbums) == 0 {
+		return
+	}
+	// check the server is still the active one before updating its state
+	if w.sm.ServerID != server.ID {
+		return
+	}
+	lastSeen := server.NewMusicLastSeenAlbumID
+	server.NewMusicLastSeenAlbumID = newAlbums[0].ID
+	if lastSeen == "" || !foundLastSeen {
+		// first check, or the last seen album was removed or is
+		// too far down the list to tell what's new
+		return
+	}
+	log.Printf("found %d newly added albums", len(newAlbums))
+	w.bus.Publish(Event{Type: EventNewAlbums, Data: newAlbums})
+}
@@ -0,0 +1,207 @@
+package backend
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// NowPlayingInfo is the metadata of the currently playing item,
+// assembled once and shared by all the now playing integrations
+// (OS media controls, Discord, the remote control API).
+type NowPlayingInfo struct {
+	Item       mediaprovider.MediaItem // nil if nothing is playing
+	Track      *mediaprovider.Track    // nil unless Item is a track
+	Metadata   mediaprovider.MediaItemMetadata
+	ReplayGain *mediaprovider.ReplayGainInfo
+
+	// URL of the cover, served over HTTP on the loopback interface,
+	// falling back to a file:// URL. Empty if there is no cover.
+	ArtURL string
+	// file:// URL of the locally cached cover thumbnail, or empty
+	ArtFileURL string
+	// colors of the cover, for adaptive theming. Nil if there is no cover.
+	Palette *CoverPalette
+
+	// Only fetched if an integration has called RequestLyrics.
+	// Lyrics are delivered in a second update after the initial one,
+	// with LyricsLoaded set; Lyrics may still be nil if not found.
+	Lyrics       *mediaprovider.Lyrics
+	LyricsLoaded bool
+}
+
+// NowPlayingBroadcaster assembles the NowPlayingInfo whenever the playing
+// item changes and fans it out to subscribers, so that integrations
+// don't each need to look up artwork and lyrics themselves.
+//
+// Scrobbling is not a subscriber: the playback engine submits now playing
+// and scrobbles synchronously on each track change, since it updates the
+// play counts of the queue and must do so before the song change callbacks,
+// whereas subscribers are notified later, after artwork lookup, and not at
+// all for items skipped past in the meantime.
+type NowPlayingBroadcaster struct {
+	sm        *ServerManager
+	im        *ImageManager
+	artServer *coverArtServer
+	lyrics    *LyricsFetcher
+	palettes  *CoverPalettes
+
+	publishMu     sync.Mutex // serializes notifying the subscribers
+	mu            sync.Mutex
+	current       *NowPlayingInfo
+	generation    int
+	wantLyrics    bool
+	subscribers   []func(*NowPlayingInfo)
+	externalArt   map[string]string // album key -> public art URL, or "" if not found
+	externalArtMu sync.Mutex
+}
+
+func NewNowPlayingBroadcaster(pm *PlaybackManager, sm *ServerManager, im *ImageManager, artServer *coverArtServer, lyrics *LyricsFetcher, palettes *CoverPalettes) *NowPlayingBroadcaster {
+	n := &NowPlayingBroadcaster{
+		sm:          sm,
+		im:          im,
+		artServer:   artServer,
+		lyrics:      lyrics,
+		palettes:    palettes,
+		current:     &NowPlayingInfo{},
+		externalArt: make(map[string]string),
+	}
+	pm.OnSongChange(func(item mediaprovider.MediaItem, _ *mediaprovider.Track) {
+		n.update(item)
+	})
+	return n
+}
+
+// OnChange registers a callback to be invoked with the new info when
+// the playing item changes, and again when its lyrics are loaded.
+// Callbacks are invoked on a background goroutine.
+func (n *NowPlayingBroadcaster) OnChange(cb func(*NowPlayingInfo)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.subscribers = append(n.subscribers, cb)
+}
+
+// RequestLyrics enables fetching the lyrics of each playing track,
+// for integrations which display them.
+func (n *NowPlayingBroadcaster) RequestLyrics() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.wantLyrics = true
+}
+
+// Current returns the info of the currently playing item.
+// Its Item is nil if nothing is playing. The returned struct must not be modified.
+func (n *NowPlayingBroadcaster) Current() *NowPlayingInfo {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.current
+}
+
+func (n *NowPlayingBroadcaster) update(item mediaprovider.MediaItem) {
+	info := &NowPlayingInfo{Item: item}
+	if item != nil {
+		info.Metadata = item.Metadata()
+		if tr, ok := item.(*mediaprovider.Track); ok {
+			info.Track = tr
+			info.ReplayGain = tr.ReplayGain
+		}
+	}
+	n.mu.Lock()
+	n.generation++
+	gen := n.generation
+	wantLyrics := n.wantLyrics && info.Track != nil
+	n.mu.Unlock()
+
+	// asynchronously because artwork and lyrics fetching can take time
+	go func() {
+		if id := info.Metadata.CoverArtID; id != "" {
+			if u, err := n.artServer.ArtURL(id); err == nil {
+				info.ArtURL = u
+			} else {
+				log.Printf("error fetching art url: %s", err.Error())
+			}
+			// ArtURL may not have fetched the cover if the art server is running
+			n.im.GetCoverThumbnail(id)
+			info.ArtFileURL, _ = n.im.GetCoverArtUrl(id)
+			info.Palette = n.palettes.Palette(id)
+		}
+		if !n.publish(gen, info) || !wantLyrics {
+			return
+		}
+		withLyrics := *info
+		withLyrics.Lyrics = n.lyrics.FetchLyrics(info.Track)
+		withLyrics.LyricsLoaded = true
+		n.publish(gen, &withLyrics)
+	}()
+}
+
+// publish makes info current and notifies the subscribers,
+// unless the playing item has changed since gen. Returns false if so.
+func (n *NowPlayingBroadcaster) publish(gen int, info *NowPlayingInfo) bool {
+	n.publishMu.Lock()
+	defer n.publishMu.Unlock()
+	n.mu.Lock()
+	if gen != n.generation {
+		n.mu.Unlock()
+		return false
+	}
+	n.current = info
+	subscribers := n.subscribers
+	n.mu.Unlock()
+	for _, cb := range subscribers {
+		cb(info)
+	}
+	return true
+}
+
+// ExternalArtURL returns a publicly accessible cover image URL for the
+// playing item, for integrations such as Discord which can't load images
+// from the media server: the Cover Art Archive image of the track's
+// MusicBrainz release, if any, else the iTunes artwork of the album.
+// It blocks while looking up the artwork, and returns "" if not found.
+func (n *NowPlayingBroadcaster) ExternalArtURL(info *NowPlayingInfo) string {
+	meta := info.Metadata
+	if meta.Album == "" || len(meta.Artists) == 0 {
+		return ""
+	}
+	key := strings.ToLower(meta.Artists[0] + "\x00" + meta.Album)
+	n.externalArtMu.Lock()
+	defer n.externalArtMu.Unlock()
+	if u, ok := n.externalArt[key]; ok {
+		return u
+	}
+	if info.Track != nil && info.Track.MusicBrainzReleaseID != "" {
+		u := "https://coverartarchive.org/release/" + info.Track.MusicBrainzReleaseID + "/front-500"
+		if coverArtArchiveHasImage(u) {
+			n.externalArt[key] = u
+			return u
+		}
+	}
+	u, err := lookUpITunesArtURL(meta.Artists[0], meta.Album)
+	if err != nil {
+		log.Printf("error looking up album art: %v", err)
+		return "" // don't cache, so it can be retried
+	}
+	n.externalArt[key] = u
+	return u
+}
+
+// coverArtArchiveHasImage checks that the Cover Art Archive has an image
+// at the URL, since many MusicBrainz releases have no artwork uploaded.
+func coverArtArchiveHasImage(u string) bool {
+	cli := http.Client{
+		Timeout: albumArtLookupTimeout,
+		// the archive redirects to the image on archive.org; the redirect itself is enough
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := cli.Head(u)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusTemporaryRedirect || resp.StatusCode == http.StatusFound ||
+		resp.StatusCode == http.StatusOK
+}
@@ -0,0 +1,173 @@
+package backend
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"log"
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+
+	"fyne.io/fyne/v2/theme"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+var (
+	placeholderFontOnce sync.Once
+	placeholderFont     *opentype.Font
+)
+
+// placeholderCover returns the generated placeholder art for the item.
+// Placeholders are cached in memory and on disk like real covers.
+func (i *ImageManager) placeholderCover(fb CoverFallback) image.Image {
+	colorKey := placeholderColorKey(fb)
+	h := fnv.New64a()
+	h.Write([]byte(fb.Name + "\x00" + colorKey))
+	key := fmt.Sprintf("placeholder-%x", h.Sum64())
+
+	if img, err := i.thumbnailCache.GetExtendTTL(key, i.thumbnailCache.DefaultTTL); err == nil && img != nil {
+		return img
+	}
+	var path string
+	if dir := i.ensureCoverCacheDir(); dir != "" {
+		path = filepath.Join(dir, key+".jpg")
+		if img, ok := i.loadLocalImage(path); ok {
+			i.thumbnailCache.Set(key, img)
+			return img
+		}
+	}
+	img := generatePlaceholderCover(fb.Name, colorKey, coverArtThumbnailSize)
+	if path != "" {
+		_ = i.writeJpeg(img, path)
+	}
+	i.thumbnailCache.Set(key, img)
+	return img
+}
+
+// placeholderColorKey returns the string to derive the placeholder hue from,
+// so that items of the same genre (or else, artist) share a color.
+func placeholderColorKey(fb CoverFallback) string {
+	switch {
+	case fb.Genre != "":
+		return fb.Genre
+	case fb.Artist != "":
+		return fb.Artist
+	default:
+		return fb.Name
+	}
+}
+
+// generatePlaceholderCover returns a square image of the given size showing
+// the initials of name on a diagonal gradient with a hue derived from colorKey.
+func generatePlaceholderCover(name, colorKey string, size int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	hue := placeholderHue(colorKey)
+	from := hslToRGB(hue, 0.55, 0.5)
+	to := hslToRGB(math.Mod(hue+30, 360), 0.5, 0.28)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			t := float64(x+y) / float64(2*(size-1))
+			img.SetRGBA(x, y, lerpRGB(from, to, t))
+		}
+	}
+
+	initials := nameInitials(name)
+	placeholderFontOnce.Do(func() {
+		f, err := opentype.Parse(theme.DefaultTextBoldFont().Content())
+		if err != nil {
+			log.Printf("error parsing placeholder font: %v", err)
+		}
+		placeholderFont = f
+	})
+	if initials == "" || placeholderFont == nil {
+		return img
+	}
+	face, err := opentype.NewFace(placeholderFont, &opentype.FaceOptions{
+		Size:    float64(size) * 0.4,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return img
+	}
+	defer face.Close()
+
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.RGBA{R: 255, G: 255, B: 255, A: 220}),
+		Face: face,
+	}
+	m := face.Metrics()
+	d.Dot = fixed.Point26_6{
+		X: (fixed.I(size) - d.MeasureString(initials)) / 2,
+		Y: (fixed.I(size) + m.Ascent - m.Descent) / 2,
+	}
+	d.DrawString(initials)
+	return img
+}
+
+// placeholderHue returns a stable hue in degrees derived from the key.
+func placeholderHue(key string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(strings.TrimSpace(key))))
+	return float64(h.Sum32() % 360)
+}
+
+// nameInitials returns up to two uppercase initials from the words of name.
+func nameInitials(name string) string {
+	var initials []rune
+	for _, word := range strings.Fields(name) {
+		for _, r := range word {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				initials = append(initials, unicode.ToUpper(r))
+				break
+			}
+		}
+		if len(initials) == 2 {
+			break
+		}
+	}
+	return string(initials)
+}
+
+func lerpRGB(a, b color.RGBA, t float64) color.RGBA {
+	lerp := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*t)
+	}
+	return color.RGBA{R: lerp(a.R, b.R), G: lerp(a.G, b.G), B: lerp(a.B, b.B), A: 255}
+}
+
+// hslToRGB converts a hue in degrees and saturation, lightness in [0, 1] to RGB.
+func hslToRGB(h, s, l float64) color.RGBA {
+	c := (1 - math.Abs(2*l-1)) * s
+	hp := h / 60
+	x := c * (1 - math.Abs(math.Mod(hp, 2)-1))
+	var r, g, b float64
+	switch {
+	case hp < 1:
+		r, g = c, x
+	case hp < 2:
+		r, g = x, c
+	case hp < 3:
+		g, b = c, x
+	case hp < 4:
+		g, b = x, c
+	case hp < 5:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := l - c/2
+	return color.RGBA{
+		R: uint8((r + m) * 255),
+		G: uint8((g + m) * 255),
+		B: uint8((b + m) * 255),
+		A: 255,
+	}
+}
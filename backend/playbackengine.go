@@ -5,6 +5,9 @@ import (
 	"errors"
 	"log"
 	"math/rand"
+	"net/http"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
@@ -20,12 +23,18 @@ var (
 	ReplayGainAuto  = "Auto"
 )
 
+// The longest a track change event may be deferred after the player reports
+// the change, waiting for the logical end of the previous track.
+const maxTrackChangeDelay = 2 * time.Second
+
 type InsertQueueMode int
 
 const (
 	Replace InsertQueueMode = iota
 	InsertNext
 	Append
+	// Insert after the remaining tracks of the currently playing album
+	InsertAfterCurrentAlbum
 )
 
 // The playback loop mode (LoopNone, LoopAll, LoopOne).
@@ -44,21 +53,53 @@ type playbackEngine struct {
 	player        player.BasePlayer
 
 	playTimeStopwatch   util.Stopwatch
+	mutedStopwatch      util.Stopwatch // play time while the volume was 0
+	muted               bool
 	curTrackDuration    float64
 	latestTrackPosition float64 // cleared by checkScrobble
 	callbacksDisabled   bool
 
 	playQueue     []mediaprovider.MediaItem
 	nowPlayingIdx int
+	queueUndo     queueUndoStack
 	isRadio       bool
 	wasStopped    bool // true iff player was stopped before handleOnTrackChange invocation
 	loopMode      LoopMode
 
 	// to pass to onSongChange listeners; clear once listeners have been called
 	lastScrobbled *mediaprovider.Track
-	scrobbleCfg   *ScrobbleConfig
+	scrobbleRules *scrobbleRules
 	transcodeCfg  *TranscodingConfig
 	replayGainCfg ReplayGainConfig
+	// the effective ReplayGain mode (resolved from Auto)
+	replayGainMode player.ReplayGainMode
+	trackFadeCfg   *TrackFadeConfig
+	trackFader     *trackFader
+	// playback stops at the end of this item, if set (sleep timer)
+	stopAfterItem mediaprovider.MediaItem
+	trackCache    *TrackCache // may be nil
+
+	// the player the engine was created with, whose callbacks stay
+	// registered while playing to other players (e.g. a network renderer)
+	localPlayer player.BasePlayer
+	// position to seek to once the next track begins playing
+	pendingSeek float64
+
+	// the last polled player status, to estimate the logical end of a track
+	lastPollPos float64
+	lastPollDur float64
+	lastPollAt  time.Time
+	// true if the next track change was requested by us, rather than
+	// the player advancing on its own at the end of a track
+	explicitTrackChange bool
+	// track change events deferred until the previous track's logical end
+	trackChangeMu      sync.Mutex
+	pendingTrackChange *time.Timer
+	pendingTrackEvents func()
+	pendingTrackGen    int // identifies the latest deferred track change
+
+	// runs player event handling and deferred work one at a time
+	events *eventQueue
 
 	// registered callbacks
 	onSongChange     []func(nowPlaying mediaprovider.MediaItem, justScrobbledIfAny *mediaprovider.Track)
@@ -71,6 +112,7 @@ type playbackEngine struct {
 	onPlaying        []func()
 	onPlayerChange   []func()
 	onQueueChange    []func()
+	onTrackPlayed    []func(track *mediaprovider.Track, listenedSecs float64, completed bool)
 }
 
 func NewPlaybackEngine(
@@ -79,6 +121,7 @@ func NewPlaybackEngine(
 	p player.BasePlayer,
 	scrobbleCfg *ScrobbleConfig,
 	transcodeCfg *TranscodingConfig,
+	trackFadeCfg *TrackFadeConfig,
 ) *playbackEngine {
 	// clamp to 99% to avoid any possible rounding issues
 	scrobbleCfg.ThresholdPercent = clamp(scrobbleCfg.ThresholdPercent, 0, 99)
@@ -86,39 +129,126 @@ func NewPlaybackEngine(
 		ctx:           ctx,
 		sm:            s,
 		player:        p,
-		scrobbleCfg:   scrobbleCfg,
+		scrobbleRules: newScrobbleRules(s),
 		transcodeCfg:  transcodeCfg,
+		trackFadeCfg:  trackFadeCfg,
+		trackFader:    newTrackFader(trackFadeCfg, p),
+		localPlayer:   p,
 		nowPlayingIdx: -1,
 		wasStopped:    true,
+		muted:         p.GetVolume() == 0,
+		events:        &eventQueue{},
 	}
-	p.OnTrackChange(pm.handleOnTrackChange)
-	p.OnSeek(func() {
-		pm.doUpdateTimePos(true)
-		pm.invokeNoArgCallbacks(pm.onSeek)
-	})
-	p.OnStopped(pm.handleOnStopped)
-	p.OnPaused(func() {
-		pm.playTimeStopwatch.Stop()
-		pm.stopPollTimePos()
-		pm.invokeNoArgCallbacks(pm.onPaused)
-	})
-	p.OnPlaying(func() {
-		pm.playTimeStopwatch.Start()
-		pm.startPollTimePos()
-		pm.invokeNoArgCallbacks(pm.onPlaying)
-	})
+	pm.registerPlayerCallbacks(p)
 
 	s.OnLogout(func() {
 		pm.StopAndClearPlayQueue()
+		pm.queueUndo.clear()
 	})
 
 	return pm
 }
 
+// registers the engine's callbacks on the player. Callbacks are ignored
+// if the player is not the current one, since they cannot be unregistered.
+// Players invoke callbacks from various goroutines (and some synchronously),
+// so they are handled in order on the engine's event queue.
+func (p *playbackEngine) registerPlayerCallbacks(pl player.BasePlayer) {
+	ifCurrent := func(f func()) func() {
+		return func() {
+			p.events.post(func() {
+				if p.player == pl {
+					f()
+				}
+			})
+		}
+	}
+	pl.OnTrackChange(ifCurrent(p.handleOnTrackChange))
+	pl.OnSeek(ifCurrent(func() {
+		p.doUpdateTimePos(true)
+		p.invokeNoArgCallbacks(p.onSeek)
+	}))
+	pl.OnStopped(ifCurrent(p.handleOnStopped))
+	pl.OnPaused(ifCurrent(func() {
+		p.stopPlayTime()
+		p.stopPollTimePos()
+		p.invokeNoArgCallbacks(p.onPaused)
+	}))
+	pl.OnPlaying(ifCurrent(func() {
+		p.startPlayTime()
+		p.startPollTimePos()
+		p.invokeNoArgCallbacks(p.onPlaying)
+	}))
+}
+
+// SetPlayer switches playback to a different player (e.g. a network renderer),
+// resuming the currently playing track on the new player at the same position.
+// Players other than the local one must not be set again once switched away
+// from, since their callbacks would be registered twice; create a new one instead.
+func (p *playbackEngine) SetPlayer(pl player.BasePlayer) error {
+	if pl == p.player {
+		return nil
+	}
+	status := p.player.GetStatus()
+	idx := p.nowPlayingIdx
+
+	old := p.player
+	p.trackFader.Cancel()
+	p.player = pl // before stopping, so the old player's callbacks are ignored
+	old.Stop()
+	if status.State != player.Stopped {
+		p.handleOnStopped()
+	}
+	p.trackFader = newTrackFader(p.trackFadeCfg, pl)
+	if pl != p.localPlayer {
+		p.registerPlayerCallbacks(pl)
+	}
+	p.invokeNoArgCallbacks(p.onPlayerChange)
+	p.setMuted(pl.GetVolume() == 0)
+	for _, cb := range p.onVolumeChange {
+		cb(pl.GetVolume())
+	}
+
+	if status.State == player.Stopped || idx < 0 {
+		return nil
+	}
+	p.pendingSeek = status.TimePos
+	if err := p.PlayTrackAt(idx); err != nil {
+		p.pendingSeek = 0
+		return err
+	}
+	if status.State == player.Paused {
+		return p.player.Pause()
+	}
+	return nil
+}
+
 func (p *playbackEngine) PlayTrackAt(idx int) error {
 	if idx < 0 || idx >= len(p.playQueue) {
 		return errors.New("track index out of range")
 	}
+	if p.trackFader.Enabled() && p.trackFadeCfg.ApplyOnManualSkip &&
+		!p.isRadio && p.player.GetStatus().State == player.Playing {
+		// the fade out after a skip doesn't count as listening time for scrobbling
+		p.stopPlayTime()
+		item := p.playQueue[idx]
+		p.trackFader.FadeOut(p.trackFader.Duration(), func() {
+			p.events.post(func() {
+				// the queue may have changed during the fade
+				idx := slices.Index(p.playQueue, item)
+				if idx < 0 {
+					p.trackFader.Cancel()
+					return
+				}
+				p.nowPlayingIdx = idx - 1
+				if err := p.setTrack(idx, false); err != nil {
+					log.Printf("failed to play track after fade: %v", err)
+				}
+			})
+		})
+		return nil
+	}
+	p.trackFader.Cancel()
 	p.nowPlayingIdx = idx - 1
 	return p.setTrack(idx, false)
 }
@@ -156,15 +286,43 @@ func (p *playbackEngine) PlayerStatus() player.Status {
 
 func (p *playbackEngine) SetVolume(vol int) error {
 	vol = clamp(vol, 0, 100)
+	p.trackFader.Abort()
 	if err := p.player.SetVolume(vol); err != nil {
 		return err
 	}
+	p.setMuted(vol == 0)
 	for _, cb := range p.onVolumeChange {
 		cb(vol)
 	}
 	return nil
 }
 
+func (p *playbackEngine) setMuted(muted bool) {
+	p.muted = muted
+	if muted && p.player.GetStatus().State == player.Playing {
+		p.mutedStopwatch.Start()
+	} else {
+		p.mutedStopwatch.Stop()
+	}
+}
+
+func (p *playbackEngine) startPlayTime() {
+	p.playTimeStopwatch.Start()
+	if p.muted {
+		p.mutedStopwatch.Start()
+	}
+}
+
+func (p *playbackEngine) stopPlayTime() {
+	p.playTimeStopwatch.Stop()
+	p.mutedStopwatch.Stop()
+}
+
+func (p *playbackEngine) resetPlayTime() {
+	p.playTimeStopwatch.Reset()
+	p.mutedStopwatch.Reset()
+}
+
 func (p *playbackEngine) CurrentPlayer() player.BasePlayer {
 	return p.player
 }
@@ -225,21 +383,19 @@ func (p *playbackEngine) LoadTracks(tracks []*mediaprovider.Track, insertQueueMo
 }
 
 func (p *playbackEngine) doLoaditems(items []mediaprovider.MediaItem, insertQueueMode InsertQueueMode, shuffle bool) error {
+	p.saveQueueUndo()
 	if insertQueueMode == Replace {
 		p.player.Stop()
 		p.nowPlayingIdx = -1
 		p.playQueue = nil
 	}
-	needToSetNext := len(items) > 0 && (insertQueueMode == InsertNext || (insertQueueMode == Append && p.nowPlayingIdx == len(p.playQueue)-1))
+	insertIdx := p.insertIndex(insertQueueMode)
+	needToSetNext := len(items) > 0 && insertQueueMode != Replace && insertIdx == p.nowPlayingIdx+1
 
 	if shuffle {
 		rand.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
 	}
 
-	insertIdx := len(p.playQueue)
-	if insertQueueMode == InsertNext {
-		insertIdx = p.nowPlayingIdx + 1
-	}
 	p.playQueue = append(p.playQueue[:insertIdx], append(items, p.playQueue[insertIdx:]...)...)
 
 	if needToSetNext {
@@ -251,16 +407,14 @@ func (p *playbackEngine) doLoaditems(items []mediaprovider.MediaItem, insertQueu
 }
 
 func (p *playbackEngine) LoadRadioStation(radio *mediaprovider.RadioStation, insertMode InsertQueueMode) {
+	p.saveQueueUndo()
 	if insertMode == Replace {
 		p.player.Stop()
 		p.nowPlayingIdx = -1
 		p.playQueue = nil
 	}
-	needToSetNext := insertMode == InsertNext || (insertMode == Append && p.nowPlayingIdx == len(p.playQueue)-1)
-	insertIdx := len(p.playQueue)
-	if insertMode == InsertNext {
-		insertIdx = p.nowPlayingIdx + 1
-	}
+	insertIdx := p.insertIndex(insertMode)
+	needToSetNext := insertMode != Replace && insertIdx == p.nowPlayingIdx+1
 	new := make([]mediaprovider.MediaItem, len(p.playQueue)+1)
 	firstHalf := p.playQueue[:insertIdx]
 	copy(new, firstHalf)
@@ -275,9 +429,40 @@ func (p *playbackEngine) LoadRadioStation(radio *mediaprovider.RadioStation, ins
 	p.invokeNoArgCallbacks(p.onQueueChange)
 }
 
+// insertIndex returns the play queue index at which to insert new items
+// for the given (non-Replace) insert mode.
+func (p *playbackEngine) insertIndex(mode InsertQueueMode) int {
+	switch mode {
+	case InsertNext:
+		return p.nowPlayingIdx + 1
+	case InsertAfterCurrentAlbum:
+		idx := p.nowPlayingIdx + 1
+		if p.nowPlayingIdx < 0 || p.nowPlayingIdx >= len(p.playQueue) {
+			return idx
+		}
+		tr, ok := p.playQueue[p.nowPlayingIdx].(*mediaprovider.Track)
+		if !ok || tr.AlbumID == "" {
+			return idx
+		}
+		for idx < len(p.playQueue) {
+			next, ok := p.playQueue[idx].(*mediaprovider.Track)
+			if !ok || next.AlbumID != tr.AlbumID {
+				break
+			}
+			idx++
+		}
+		return idx
+	default:
+		return len(p.playQueue)
+	}
+}
+
 // Stop playback and clear the play queue.
 func (p *playbackEngine) StopAndClearPlayQueue() {
 	changed := len(p.playQueue) > 0
+	if changed {
+		p.saveQueueUndo()
+	}
 	p.player.Stop()
 	p.doUpdateTimePos(false)
 	p.playQueue = nil
@@ -315,15 +500,69 @@ func (p *playbackEngine) OnTrackRatingChanged(id string, rating int) {
 // Does not stop playback if the currently playing track is in the new queue,
 // but updates the now playing index to point to the first instance of the track in the new queue.
 func (p *playbackEngine) UpdatePlayQueue(items []mediaprovider.MediaItem) error {
-	newQueue := p.deepCopyMediaItemSlice(items)
+	p.saveQueueUndo()
+	return p.setPlayQueue(p.deepCopyMediaItemSlice(items), -1)
+}
+
+// MoveQueueRange moves the items [from, to) of the play queue so that
+// the first of them is at index dest of the resulting queue.
+func (p *playbackEngine) MoveQueueRange(from, to, dest int) error {
+	if from < 0 || to > len(p.playQueue) || from >= to || dest < 0 || dest > len(p.playQueue)-(to-from) {
+		return errors.New("queue range out of bounds")
+	}
+	moved := slices.Clone(p.playQueue[from:to])
+	newQueue := slices.Delete(slices.Clone(p.playQueue), from, to)
+	newQueue = slices.Insert(newQueue, dest, moved...)
+	p.saveQueueUndo()
+	return p.setPlayQueue(newQueue, -1)
+}
+
+// RemoveQueueRange removes the items [from, to) from the play queue.
+func (p *playbackEngine) RemoveQueueRange(from, to int) error {
+	if from < 0 || to > len(p.playQueue) || from >= to {
+		return errors.New("queue range out of bounds")
+	}
+	p.removeQueueItems(func(i int, _ mediaprovider.MediaItem) bool {
+		return i >= from && i < to
+	})
+	return nil
+}
+
+// CanUndoQueueChange returns whether there is a play queue change to undo.
+func (p *playbackEngine) CanUndoQueueChange() bool {
+	return len(p.queueUndo.snapshots) > 0
+}
+
+// UndoQueueChange restores the play queue to how it was before the
+// last change. Playback continues if the playing item is in the restored queue.
+func (p *playbackEngine) UndoQueueChange() error {
+	snap, ok := p.queueUndo.pop()
+	if !ok {
+		return nil
+	}
+	return p.setPlayQueue(snap.items, snap.nowPlayingIdx)
+}
+
+func (p *playbackEngine) saveQueueUndo() {
+	p.queueUndo.push(queueSnapshot{items: slices.Clone(p.playQueue), nowPlayingIdx: p.nowPlayingIdx})
+}
+
+// setPlayQueue replaces the play queue, keeping the playing item playing if
+// it is in the new queue: at nowPlayingHint if it is there, else at its first
+// instance. Playback is stopped if the playing item is not in the new queue.
+func (p *playbackEngine) setPlayQueue(newQueue []mediaprovider.MediaItem, nowPlayingHint int) error {
 	newNowPlayingIdx := -1
 	if p.nowPlayingIdx >= 0 {
-		nowPlayingID := p.playQueue[p.nowPlayingIdx].Metadata().ID
-		for i, tr := range newQueue {
-			if tr.Metadata().ID == nowPlayingID {
-				newNowPlayingIdx = i
-				break
-			}
+		nowPlaying := p.playQueue[p.nowPlayingIdx]
+		if nowPlayingHint >= 0 && nowPlayingHint < len(newQueue) &&
+			newQueue[nowPlayingHint].Metadata().ID == nowPlaying.Metadata().ID {
+			newNowPlayingIdx = nowPlayingHint
+		} else if idx := slices.Index(newQueue, nowPlaying); idx >= 0 {
+			newNowPlayingIdx = idx // the same item, which may be a duplicate
+		} else {
+			newNowPlayingIdx = slices.IndexFunc(newQueue, func(item mediaprovider.MediaItem) bool {
+				return item.Metadata().ID == nowPlaying.Metadata().ID
+			})
 		}
 	}
 
@@ -342,14 +581,22 @@ func (p *playbackEngine) UpdatePlayQueue(items []mediaprovider.MediaItem) error
 }
 
 func (p *playbackEngine) RemoveTracksFromQueue(trackIDs []string) {
-	newQueue := make([]mediaprovider.MediaItem, 0, len(p.playQueue)-len(trackIDs))
 	idSet := sharedutil.ToSet(trackIDs)
+	p.removeQueueItems(func(_ int, item mediaprovider.MediaItem) bool {
+		_, ok := idSet[item.Metadata().ID]
+		return ok
+	})
+}
+
+func (p *playbackEngine) removeQueueItems(remove func(i int, item mediaprovider.MediaItem) bool) {
+	p.saveQueueUndo()
+	newQueue := make([]mediaprovider.MediaItem, 0, len(p.playQueue))
 	isPlayingTrackRemoved := false
 	isNextPlayingTrackremoved := false
 	nowPlaying := p.NowPlayingIndex()
 	newNowPlaying := nowPlaying
 	for i, tr := range p.playQueue {
-		if _, ok := idSet[tr.Metadata().ID]; ok {
+		if remove(i, tr) {
 			if i < nowPlaying {
 				// if removing a track earlier than the currently playing one (if any),
 				// decrement new now playing index by one to account for new position in queue
@@ -408,11 +655,29 @@ func (p *playbackEngine) SetReplayGainOptions(config ReplayGainConfig) {
 		mode = player.ReplayGainAlbum
 	}
 
+	p.replayGainMode = mode
 	rGainPlayer.SetReplayGainOptions(player.ReplayGainOptions{
 		Mode:            mode,
 		PreventClipping: config.PreventClipping,
 		PreampGain:      config.PreampGainDB,
 	})
+	p.updateClientReplayGain()
+	p.reloadNextTrackReplayGain()
+}
+
+func (p *playbackEngine) SetSkipSilenceOptions(config SkipSilenceConfig) {
+	ssPlayer, ok := p.player.(player.SkipSilencePlayer)
+	if !ok {
+		log.Println("Error: player doesn't support skipping silence")
+		return
+	}
+	if err := ssPlayer.SetSkipSilenceOptions(player.SkipSilenceOptions{
+		Enabled:       config.Enabled,
+		ThresholdDB:   config.ThresholdDB,
+		MaxGapSeconds: config.MaxGapSeconds,
+	}); err != nil {
+		log.Printf("error setting skip silence options: %v", err)
+	}
 }
 
 func (p *playbackEngine) SetReplayGainMode(mode player.ReplayGainMode) {
@@ -421,18 +686,76 @@ func (p *playbackEngine) SetReplayGainMode(mode player.ReplayGainMode) {
 		log.Println("Error: player doesn't support ReplayGain")
 		return
 	}
+	p.replayGainMode = mode
 	rGainPlayer.SetReplayGainOptions(player.ReplayGainOptions{
 		PreventClipping: p.replayGainCfg.PreventClipping,
 		PreampGain:      p.replayGainCfg.PreampGainDB,
 		Mode:            mode,
 	})
+	p.updateClientReplayGain()
+	p.reloadNextTrackReplayGain()
+}
+
+// Applies ReplayGain to the now playing track from the ReplayGain
+// metadata reported by the server, if the player supports it.
+// If the server did not report ReplayGain info for the track,
+// falls back to loudness normalization if enabled, or else
+// to the player's own ReplayGain handling (e.g. from file tags).
+func (p *playbackEngine) updateClientReplayGain() {
+	rgPlayer, ok := p.player.(player.ClientReplayGainPlayer)
+	if !ok {
+		return
+	}
+	if err := rgPlayer.SetClientReplayGain(p.clientReplayGain(p.NowPlaying())); err != nil {
+		log.Printf("failed to set ReplayGain: %v", err)
+	}
+}
+
+// reloadNextTrackReplayGain sets the next track again after the ReplayGain
+// settings change, since its gain is applied when it is loaded.
+func (p *playbackEngine) reloadNextTrackReplayGain() {
+	if _, ok := p.player.(player.ClientReplayGainPlayer); ok && p.nowPlayingIdx >= 0 {
+		p.setNextTrackAfterQueueUpdate()
+	}
+}
+
+// clientReplayGain returns the client ReplayGain adjustment for the item.
+func (p *playbackEngine) clientReplayGain(item mediaprovider.MediaItem) player.ClientReplayGain {
+	var rg player.ClientReplayGain
+	if tr, ok := item.(*mediaprovider.Track); ok && p.replayGainMode != player.ReplayGainNone {
+		opts := player.ReplayGainOptions{
+			Mode:            p.replayGainMode,
+			PreampGain:      p.replayGainCfg.PreampGainDB,
+			PreventClipping: p.replayGainCfg.PreventClipping,
+		}
+		if gain, ok := player.ComputeReplayGain(tr.ReplayGain, opts); ok {
+			rg = player.ClientReplayGain{Enabled: true, GainDB: gain}
+		} else if p.replayGainCfg.LoudnessFallback {
+			rg = player.ClientReplayGain{Enabled: true, Normalize: true}
+		}
+	}
+	return rg
 }
 
 func (p *playbackEngine) handleOnTrackChange() {
-	p.checkScrobble() // scrobble the previous song if needed
-	if p.player.GetStatus().State == player.Playing {
-		p.playTimeStopwatch.Start()
+	p.flushPendingTrackEvents()
+
+	// If the player advanced on its own at the end of a track, it reports the change
+	// as soon as it begins decoding the next track, while the end of the previous one
+	// may still be playing out of the audio buffer (gapless) or fading out (track fade).
+	// Defer the track change events (scrobbling, MPRIS, notifications, etc)
+	// to the previous track's logical end.
+	natural := !p.explicitTrackChange && !p.wasStopped
+	p.explicitTrackChange = false
+	var delay time.Duration
+	if natural {
+		delay = p.remainingPlayTime()
+	}
+	var prev mediaprovider.MediaItem
+	if p.nowPlayingIdx >= 0 && p.nowPlayingIdx < len(p.playQueue) {
+		prev = p.playQueue[p.nowPlayingIdx]
 	}
+
 	if p.wasStopped || p.loopMode != LoopOne {
 		p.nowPlayingIdx++
 		if p.loopMode == LoopAll && p.nowPlayingIdx == len(p.playQueue) {
@@ -443,15 +766,83 @@ func (p *playbackEngine) handleOnTrackChange() {
 	_, isRadio := nowPlaying.(*mediaprovider.RadioStation)
 	p.isRadio = isRadio
 	p.wasStopped = false
-	p.curTrackDuration = float64(nowPlaying.Metadata().Duration)
-	p.sendNowPlayingScrobble() // Must come before invokeOnChangeCallbacks b/c track may immediately be scrobbled
-	p.invokeOnSongChangeCallbacks()
-	p.doUpdateTimePos(false)
+	p.updateClientReplayGain()
 	p.setNextTrackBasedOnLoopMode(false)
+	p.trackFader.FadeIn()
+	if p.pendingSeek > 0 {
+		if err := p.player.SeekSeconds(p.pendingSeek); err != nil {
+			log.Printf("failed to resume playback position: %v", err)
+		}
+		p.pendingSeek = 0
+	}
+
+	events := func() {
+		if prev != nil {
+			p.checkScrobbleItem(prev, natural) // scrobble the previous song if needed
+		}
+		if p.player.GetStatus().State == player.Playing {
+			p.startPlayTime()
+		}
+		p.curTrackDuration = float64(nowPlaying.Metadata().Duration)
+		p.sendNowPlayingScrobble() // Must come before invokeOnChangeCallbacks b/c track may immediately be scrobbled
+		p.invokeOnSongChangeCallbacks()
+		p.doUpdateTimePos(false)
+	}
+	if delay <= 0 {
+		events()
+		return
+	}
+	p.trackChangeMu.Lock()
+	p.pendingTrackGen++
+	gen := p.pendingTrackGen
+	p.pendingTrackEvents = events
+	p.pendingTrackChange = time.AfterFunc(delay, func() {
+		p.events.post(func() {
+			p.trackChangeMu.Lock()
+			stale := gen != p.pendingTrackGen
+			p.trackChangeMu.Unlock()
+			if !stale {
+				p.flushPendingTrackEvents()
+			}
+		})
+	})
+	p.trackChangeMu.Unlock()
+}
+
+// flushPendingTrackEvents invokes the deferred track change events, if any.
+func (p *playbackEngine) flushPendingTrackEvents() {
+	p.trackChangeMu.Lock()
+	defer p.trackChangeMu.Unlock()
+	if p.pendingTrackChange != nil {
+		p.pendingTrackChange.Stop()
+		p.pendingTrackChange = nil
+	}
+	if events := p.pendingTrackEvents; events != nil {
+		p.pendingTrackEvents = nil
+		events()
+	}
+}
+
+// remainingPlayTime estimates the time until the current track finishes playing,
+// from the last polled position. Returns 0 if it cannot be reliably estimated.
+func (p *playbackEngine) remainingPlayTime() time.Duration {
+	if p.lastPollAt.IsZero() || p.lastPollDur <= 0 {
+		return 0
+	}
+	pos := p.lastPollPos + time.Since(p.lastPollAt).Seconds()
+	remaining := time.Duration((p.lastPollDur - pos) * float64(time.Second))
+	if remaining > maxTrackChangeDelay {
+		return 0 // stale position info, don't hold back events
+	}
+	return remaining
 }
 
 func (p *playbackEngine) handleOnStopped() {
-	p.playTimeStopwatch.Stop()
+	p.flushPendingTrackEvents()
+	p.lastPollAt = time.Time{}
+	p.trackFader.Cancel()
+	p.stopAfterItem = nil
+	p.stopPlayTime()
 	p.checkScrobble()
 	p.stopPollTimePos()
 	p.doUpdateTimePos(false)
@@ -461,7 +852,36 @@ func (p *playbackEngine) handleOnStopped() {
 	p.nowPlayingIdx = -1
 }
 
+// Returns true if the current track is about to end naturally
+// and should begin fading out into the next track.
+func (p *playbackEngine) shouldBeginTrackFade(s player.Status) bool {
+	if !p.trackFader.Enabled() || p.isRadio || s.State != player.Playing || p.trackFader.IsActive() {
+		return false
+	}
+	if p.loopMode == LoopNone && p.nowPlayingIdx >= len(p.playQueue)-1 ||
+		p.stopAfterItem != nil && p.playQueue[p.nowPlayingIdx] == p.stopAfterItem {
+		return false // no next track to fade into
+	}
+	fadeSecs := p.trackFadeCfg.DurationSeconds
+	remaining := s.Duration - s.TimePos
+	// don't fade tracks that are shorter than twice the fade duration
+	return s.Duration > 2*fadeSecs && remaining > 0 && remaining <= fadeSecs
+}
+
+// setStopAfterItem sets the queue item after which playback should stop,
+// or clears it if nil, by (un)setting the player's next track.
+func (p *playbackEngine) setStopAfterItem(item mediaprovider.MediaItem) {
+	p.stopAfterItem = item
+	if p.nowPlayingIdx >= 0 && p.nowPlayingIdx < len(p.playQueue) {
+		p.setNextTrackBasedOnLoopMode(false)
+	}
+}
+
 func (p *playbackEngine) setNextTrackBasedOnLoopMode(onLoopModeChange bool) {
+	if p.stopAfterItem != nil && p.nowPlayingIdx >= 0 && p.playQueue[p.nowPlayingIdx] == p.stopAfterItem {
+		p.setNextTrack(-1)
+		return
+	}
 	switch p.loopMode {
 	case LoopNone:
 		if p.nowPlayingIdx < len(p.playQueue)-1 {
@@ -503,19 +923,30 @@ func (p *playbackEngine) setNextTrackAfterQueueUpdate() {
 }
 
 func (p *playbackEngine) setTrack(idx int, next bool) error {
+	if !next {
+		p.explicitTrackChange = true
+	}
 	if urlP, ok := p.player.(player.URLPlayer); ok {
 		url := ""
+		var header http.Header
 		if idx >= 0 {
 			var err error
 			item := p.playQueue[idx]
 			if tr, ok := item.(*mediaprovider.Track); ok {
-				url, err = p.sm.Server.GetStreamURL(tr.ID, p.transcodeCfg.ForceRawFile)
+				url, header, err = p.getStreamURL(tr.ID, next)
 			} else {
 				url = item.(*mediaprovider.RadioStation).StreamURL
 			}
 			if err != nil {
 				return err
 			}
+			if rgPlayer, ok := p.player.(player.ClientReplayGainPlayer); ok {
+				// so that the gain changes exactly at the (gapless) transition
+				rgPlayer.SetNextFileClientReplayGain(p.clientReplayGain(p.playQueue[idx]))
+			}
+		}
+		if hPlayer, ok := p.player.(player.HTTPHeaderPlayer); ok {
+			hPlayer.SetNextFileHTTPHeaders(header)
 		}
 		if next {
 			return urlP.SetNextFile(url)
@@ -537,51 +968,106 @@ func (p *playbackEngine) setTrack(idx int, next bool) error {
 	panic("Unsupported player type")
 }
 
+// getStreamURL returns the stream URL for the given track and the headers to
+// request it with, or the path of its local copy if it has been pre-cached.
+// If the track is to be played next, the provider is asked to prefetch
+// the stream if supported.
+func (p *playbackEngine) getStreamURL(trackID string, next bool) (string, http.Header, error) {
+	if p.trackCache != nil {
+		if path, ok := p.trackCache.LocalPath(trackID); ok {
+			return path, nil, nil
+		}
+	}
+	var url string
+	var err error
+	if pf, ok := mediaprovider.As[mediaprovider.SupportsStreamPrefetch](p.sm.Server); ok && next {
+		url, err = pf.PrefetchStreamURL(trackID, p.sm.StreamOptions(trackID).ForceRaw)
+	} else {
+		url, err = p.sm.GetStreamURL(trackID)
+	}
+	return url, p.sm.StreamHeaders(trackID), err
+}
+
 func (p *playbackEngine) setNextTrack(idx int) error {
 	return p.setTrack(idx, true)
 }
 
 // call BEFORE updating p.nowPlayingIdx
 func (p *playbackEngine) checkScrobble() {
-	if !p.scrobbleCfg.Enabled || len(p.playQueue) == 0 || p.nowPlayingIdx < 0 {
+	if len(p.playQueue) == 0 || p.nowPlayingIdx < 0 {
 		return
 	}
-	track, ok := p.playQueue[p.nowPlayingIdx].(*mediaprovider.Track)
+	p.checkScrobbleItem(p.playQueue[p.nowPlayingIdx], false)
+}
+
+// checkScrobbleItem scrobbles the item that just finished playing, if needed.
+// completed is true if it played through to its end, even if the last
+// seconds were faded out by a track fade and so not reported by the player.
+func (p *playbackEngine) checkScrobbleItem(item mediaprovider.MediaItem, completed bool) {
+	track, ok := item.(*mediaprovider.Track)
 	if !ok {
 		return // radio stations are not scrobbled
 	}
-
 	playDur := p.playTimeStopwatch.Elapsed()
+	if playDur.Seconds() >= 0.1 {
+		for _, cb := range p.onTrackPlayed {
+			cb(track, playDur.Seconds(), completed)
+		}
+	}
+	scrobbleCfg := p.sm.ScrobbleConfig()
+	if !scrobbleCfg.Enabled {
+		p.latestTrackPosition = 0
+		p.resetPlayTime()
+		return
+	}
+	if completed && p.latestTrackPosition < p.curTrackDuration {
+		p.latestTrackPosition = p.curTrackDuration
+	}
+
 	if playDur.Seconds() < 0.1 || p.curTrackDuration < 0.1 {
 		return
 	}
-	pcnt := playDur.Seconds() / p.curTrackDuration * 100
-	timeThresholdMet := p.scrobbleCfg.ThresholdTimeSeconds >= 0 &&
-		playDur.Seconds() >= float64(p.scrobbleCfg.ThresholdTimeSeconds)
+	listenDur := playDur
+	if scrobbleCfg.SkipWhenMuted {
+		listenDur -= p.mutedStopwatch.Elapsed()
+	}
+	pcnt := listenDur.Seconds() / p.curTrackDuration * 100
+	timeThresholdMet := scrobbleCfg.ThresholdTimeSeconds >= 0 &&
+		listenDur.Seconds() >= float64(scrobbleCfg.ThresholdTimeSeconds)
+	rulesMet := listenDur.Seconds() >= float64(scrobbleCfg.MinListenSeconds) &&
+		p.scrobbleRules.allows(scrobbleCfg, track)
 
 	var submission bool
 	server := p.sm.Server
-	if server.ClientDecidesScrobble() && (timeThresholdMet || pcnt >= float64(p.scrobbleCfg.ThresholdPercent)) {
+	if server.ClientDecidesScrobble() && rulesMet && (timeThresholdMet || pcnt >= float64(scrobbleCfg.ThresholdPercent)) {
 		track.PlayCount += 1
 		p.lastScrobbled = track
 		submission = true
 	}
 	go server.TrackEndedPlayback(track.ID, int(p.latestTrackPosition), submission)
 	p.latestTrackPosition = 0
-	p.playTimeStopwatch.Reset()
+	p.resetPlayTime()
 }
 
 func (p *playbackEngine) sendNowPlayingScrobble() {
-	if !p.scrobbleCfg.Enabled || len(p.playQueue) == 0 || p.nowPlayingIdx < 0 {
+	scrobbleCfg := p.sm.ScrobbleConfig()
+	if !scrobbleCfg.Enabled || len(p.playQueue) == 0 || p.nowPlayingIdx < 0 {
 		return
 	}
 	track, ok := p.playQueue[p.nowPlayingIdx].(*mediaprovider.Track)
 	if !ok {
 		return // radio stations are not scrobbled
 	}
+	// load the ignored playlists ahead of the scrobble check
+	p.scrobbleRules.refresh()
 
 	server := p.sm.Server
 	if !server.ClientDecidesScrobble() {
+		// the server counts the play immediately, so the listening
+		// time rules can't be applied, but the others can
+		if !p.scrobbleRules.allows(scrobbleCfg, track) || (scrobbleCfg.SkipWhenMuted && p.muted) {
+			return
+		}
 		// server will count track as scrobbled as soon as it starts playing
 		p.lastScrobbled = track
 		track.PlayCount += 1
@@ -638,7 +1124,7 @@ func (p *playbackEngine) startPollTimePos() {
 				pollingTick.Stop()
 				return
 			case <-pollingTick.C:
-				p.doUpdateTimePos(false)
+				p.events.post(func() { p.doUpdateTimePos(false) })
 			}
 		}
 	}()
@@ -656,9 +1142,14 @@ func (p *playbackEngine) doUpdateTimePos(seeked bool) {
 		return
 	}
 	s := p.player.GetStatus()
+	p.lastPollPos, p.lastPollDur, p.lastPollAt = s.TimePos, s.Duration, time.Now()
 	if s.TimePos > p.latestTrackPosition {
 		p.latestTrackPosition = s.TimePos
 	}
+	if p.shouldBeginTrackFade(s) {
+		remaining := time.Duration((s.Duration - s.TimePos) * float64(time.Second))
+		p.trackFader.FadeOut(remaining, nil)
+	}
 	duration := s.Duration
 	if p.isRadio {
 		// MPV reports buffered duration - we don't want to show this
@@ -668,3 +1159,34 @@ func (p *playbackEngine) doUpdateTimePos(seeked bool) {
 		cb(s.TimePos, duration, seeked)
 	}
 }
+
+// eventQueue runs functions one at a time, in the order they were posted.
+// A function posted while none is running runs right away on the posting
+// goroutine, which then runs any posted meanwhile; otherwise it is left for
+// the running goroutine. So posting never blocks, functions may post more
+// (e.g. through player callbacks, which some players invoke synchronously),
+// and callbacks invoked synchronously outside the queue still run in place.
+type eventQueue struct {
+	mu      sync.Mutex
+	queue   []func()
+	running bool
+}
+
+func (q *eventQueue) post(f func()) {
+	q.mu.Lock()
+	q.queue = append(q.queue, f)
+	if q.running {
+		q.mu.Unlock()
+		return
+	}
+	q.running = true
+	for len(q.queue) > 0 {
+		f := q.queue[0]
+		q.queue = q.queue[1:]
+		q.mu.Unlock()
+		f()
+		q.mu.Lock()
+	}
+	q.running = false
+	q.mu.Unlock()
+}
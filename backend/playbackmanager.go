@@ -3,7 +3,10 @@ package backend
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
+	"math/rand"
+	"slices"
 
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
 	"github.com/dweymouth/supersonic/backend/player"
@@ -12,7 +15,8 @@ import (
 // A high-level MediaProvider-aware playback engine, serves as an
 // intermediary between the frontend and various Player backends.
 type PlaybackManager struct {
-	engine *playbackEngine
+	engine     *playbackEngine
+	radioSeeds *RadioSeedCache
 }
 
 func NewPlaybackManager(
@@ -21,9 +25,10 @@ func NewPlaybackManager(
 	p player.BasePlayer,
 	scrobbleCfg *ScrobbleConfig,
 	transcodeCfg *TranscodingConfig,
+	trackFadeCfg *TrackFadeConfig,
 ) *PlaybackManager {
 	return &PlaybackManager{
-		engine: NewPlaybackEngine(ctx, s, p, scrobbleCfg, transcodeCfg),
+		engine: NewPlaybackEngine(ctx, s, p, scrobbleCfg, transcodeCfg, trackFadeCfg),
 	}
 }
 
@@ -31,6 +36,11 @@ func (p *PlaybackManager) CurrentPlayer() player.BasePlayer {
 	return p.engine.CurrentPlayer()
 }
 
+// SetPlayer switches playback to the given player, resuming the current track.
+func (p *PlaybackManager) SetPlayer(pl player.BasePlayer) error {
+	return p.engine.SetPlayer(pl)
+}
+
 func (p *PlaybackManager) OnPlayerChange(cb func()) {
 	p.engine.onPlayerChange = append(p.engine.onPlayerChange, cb)
 }
@@ -59,6 +69,12 @@ func (p *PlaybackManager) OnSongChange(cb func(nowPlaying mediaprovider.MediaIte
 	p.engine.onSongChange = append(p.engine.onSongChange, cb)
 }
 
+// Registers a callback that is notified when a track stops playing, whether
+// it played to the end or not, with the number of seconds it was listened to.
+func (p *PlaybackManager) OnTrackPlayed(cb func(track *mediaprovider.Track, listenedSecs float64, completed bool)) {
+	p.engine.onTrackPlayed = append(p.engine.onTrackPlayed, cb)
+}
+
 // Registers a callback that is notified whenever the play time should be updated.
 func (p *PlaybackManager) OnPlayTimeUpdate(cb func(curTime float64, totalTime float64, seeked bool)) {
 	p.engine.onPlayTimeUpdate = append(p.engine.onPlayTimeUpdate, cb)
@@ -146,6 +162,52 @@ func (p *PlaybackManager) PlayAlbum(albumID string, firstTrack int, shuffle bool
 	return p.PlayTrackAt(firstTrack)
 }
 
+// LoadAlbumDisc loads the tracks of one disc of a multi-disc album into the play queue.
+func (p *PlaybackManager) LoadAlbumDisc(albumID string, discNumber int, insertQueueMode InsertQueueMode, shuffle bool) error {
+	album, err := p.engine.sm.Server.GetAlbum(albumID)
+	if err != nil {
+		return err
+	}
+	for _, disc := range album.Discs() {
+		if disc.Number == discNumber {
+			return p.LoadTracks(disc.Tracks, insertQueueMode, shuffle)
+		}
+	}
+	return fmt.Errorf("album has no disc %d", discNumber)
+}
+
+func (p *PlaybackManager) PlayAlbumDisc(albumID string, discNumber int, shuffle bool) error {
+	if err := p.LoadAlbumDisc(albumID, discNumber, Replace, shuffle); err != nil {
+		return err
+	}
+	if p.engine.replayGainCfg.Mode == ReplayGainAuto {
+		p.SetReplayGainMode(player.ReplayGainAlbum)
+	}
+	return p.PlayTrackAt(0)
+}
+
+// PlayAlbumShuffledWithinDiscs plays the album disc by disc,
+// shuffling the order of the tracks within each disc.
+func (p *PlaybackManager) PlayAlbumShuffledWithinDiscs(albumID string) error {
+	album, err := p.engine.sm.Server.GetAlbum(albumID)
+	if err != nil {
+		return err
+	}
+	var tracks []*mediaprovider.Track
+	for _, disc := range album.Discs() {
+		discTracks := slices.Clone(disc.Tracks)
+		rand.Shuffle(len(discTracks), func(i, j int) { discTracks[i], discTracks[j] = discTracks[j], discTracks[i] })
+		tracks = append(tracks, discTracks...)
+	}
+	if err := p.LoadTracks(tracks, Replace, false); err != nil {
+		return err
+	}
+	if p.engine.replayGainCfg.Mode == ReplayGainAuto {
+		p.SetReplayGainMode(player.ReplayGainAlbum)
+	}
+	return p.PlayTrackAt(0)
+}
+
 func (p *PlaybackManager) PlayPlaylist(playlistID string, firstTrack int, shuffle bool) error {
 	if err := p.LoadPlaylist(playlistID, Replace, shuffle); err != nil {
 		return err
@@ -178,13 +240,45 @@ func (p *PlaybackManager) PlayTrackAt(idx int) error {
 
 func (p *PlaybackManager) PlayRandomSongs(genreName string) {
 	p.fetchAndPlayTracks(func() ([]*mediaprovider.Track, error) {
-		return p.engine.sm.Server.GetRandomTracks(genreName, 100)
+		return p.radioSeeds.FetchMix(RadioSeedGenre, genreName, 100, func(count int) ([]*mediaprovider.Track, error) {
+			return p.engine.sm.Server.GetRandomTracks(genreName, count)
+		})
 	})
 }
 
 func (p *PlaybackManager) PlaySimilarSongs(id string) {
 	p.fetchAndPlayTracks(func() ([]*mediaprovider.Track, error) {
-		return p.engine.sm.Server.GetSimilarTracks(id, 100)
+		return p.radioSeeds.FetchMix(RadioSeedArtist, id, 100, func(count int) ([]*mediaprovider.Track, error) {
+			return p.engine.sm.Server.GetSimilarTracks(id, count)
+		})
+	})
+}
+
+// PlayAlbumMix plays a mix of tracks similar to the album.
+// Requires the server to support mediaprovider.SupportsInstantMix.
+func (p *PlaybackManager) PlayAlbumMix(albumID string) {
+	p.playInstantMix(RadioSeedAlbum, albumID, func(im mediaprovider.SupportsInstantMix, count int) ([]*mediaprovider.Track, error) {
+		return im.GetInstantMix(albumID, count)
+	})
+}
+
+// PlayGenreMix plays a mix of tracks from the genre.
+// Requires the server to support mediaprovider.SupportsInstantMix.
+func (p *PlaybackManager) PlayGenreMix(genre string) {
+	p.playInstantMix(RadioSeedGenre, genre, func(im mediaprovider.SupportsInstantMix, count int) ([]*mediaprovider.Track, error) {
+		return im.GetGenreInstantMix(genre, count)
+	})
+}
+
+func (p *PlaybackManager) playInstantMix(seedKind, seedID string, fetch func(mediaprovider.SupportsInstantMix, int) ([]*mediaprovider.Track, error)) {
+	p.fetchAndPlayTracks(func() ([]*mediaprovider.Track, error) {
+		im, ok := mediaprovider.As[mediaprovider.SupportsInstantMix](p.engine.sm.Server)
+		if !ok {
+			return nil, errors.New("server does not support instant mixes")
+		}
+		return p.radioSeeds.FetchMix(seedKind, seedID, 100, func(count int) ([]*mediaprovider.Track, error) {
+			return fetch(im, count)
+		})
 	})
 }
 
@@ -224,6 +318,34 @@ func (p *PlaybackManager) RemoveTracksFromQueue(trackIDs []string) {
 	p.engine.RemoveTracksFromQueue(trackIDs)
 }
 
+// InsertNext inserts the items into the play queue after the playing item.
+func (p *PlaybackManager) InsertNext(items []mediaprovider.MediaItem) error {
+	return p.engine.LoadItems(items, InsertNext, false)
+}
+
+// MoveQueueRange moves the items [from, to) of the play queue so that
+// the first of them is at index dest of the resulting queue.
+func (p *PlaybackManager) MoveQueueRange(from, to, dest int) error {
+	return p.engine.MoveQueueRange(from, to, dest)
+}
+
+// RemoveQueueRange removes the items [from, to) from the play queue.
+func (p *PlaybackManager) RemoveQueueRange(from, to int) error {
+	return p.engine.RemoveQueueRange(from, to)
+}
+
+// CanUndoQueueChange returns whether there is a play queue change to undo.
+func (p *PlaybackManager) CanUndoQueueChange() bool {
+	return p.engine.CanUndoQueueChange()
+}
+
+// UndoQueueChange restores the play queue to how it was before the last
+// change (e.g. loading, reordering, removing, or clearing), up to the
+// last 20 changes. Playback continues if the playing item is in the restored queue.
+func (p *PlaybackManager) UndoQueueChange() error {
+	return p.engine.UndoQueueChange()
+}
+
 // Stop playback and clear the play queue.
 func (p *PlaybackManager) StopAndClearPlayQueue() {
 	p.engine.StopAndClearPlayQueue()
@@ -233,6 +355,12 @@ func (p *PlaybackManager) SetReplayGainOptions(config ReplayGainConfig) {
 	p.engine.SetReplayGainOptions(config)
 }
 
+// SetSkipSilenceOptions sets whether and how long silences in tracks are trimmed.
+// Requires the player to implement player.SkipSilencePlayer.
+func (p *PlaybackManager) SetSkipSilenceOptions(config SkipSilenceConfig) {
+	p.engine.SetSkipSilenceOptions(config)
+}
+
 func (p *PlaybackManager) SetReplayGainMode(mode player.ReplayGainMode) {
 	p.engine.SetReplayGainMode(mode)
 }
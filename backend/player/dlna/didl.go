@@ -0,0 +1,87 @@
+package dlna
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// didlLite builds the DIDL-Lite metadata document describing
+// the given track, to send along with its stream URL.
+func didlLite(tr *mediaprovider.Track, streamURL string) string {
+	var b bytes.Buffer
+	esc := func(s string) string {
+		var e bytes.Buffer
+		xml.EscapeText(&e, []byte(s))
+		return e.String()
+	}
+	b.WriteString(`<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" ` +
+		`xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">`)
+	fmt.Fprintf(&b, `<item id="%s" parentID="0" restricted="1">`, esc(tr.ID))
+	fmt.Fprintf(&b, "<dc:title>%s</dc:title>", esc(tr.Title))
+	if len(tr.ArtistNames) > 0 {
+		artists := esc(strings.Join(tr.ArtistNames, ", "))
+		fmt.Fprintf(&b, "<dc:creator>%s</dc:creator>", artists)
+		fmt.Fprintf(&b, "<upnp:artist>%s</upnp:artist>", artists)
+	}
+	if tr.Album != "" {
+		fmt.Fprintf(&b, "<upnp:album>%s</upnp:album>", esc(tr.Album))
+	}
+	if tr.TrackNumber > 0 {
+		fmt.Fprintf(&b, "<upnp:originalTrackNumber>%d</upnp:originalTrackNumber>", tr.TrackNumber)
+	}
+	b.WriteString("<upnp:class>object.item.audioItem.musicTrack</upnp:class>")
+	fmt.Fprintf(&b, `<res protocolInfo="http-get:*:%s:*" duration="%s">%s</res>`,
+		mimeTypeForTrack(tr), formatTime(float64(tr.Duration)), esc(streamURL))
+	b.WriteString("</item></DIDL-Lite>")
+	return b.String()
+}
+
+func mimeTypeForTrack(tr *mediaprovider.Track) string {
+	ext := tr.FilePath
+	if i := strings.LastIndex(ext, "."); i >= 0 {
+		ext = ext[i+1:]
+	}
+	switch strings.ToLower(ext) {
+	case "flac":
+		return "audio/flac"
+	case "m4a", "mp4", "aac", "alac":
+		return "audio/mp4"
+	case "ogg", "oga", "opus":
+		return "audio/ogg"
+	case "wav":
+		return "audio/wav"
+	case "mp3":
+		return "audio/mpeg"
+	default:
+		return "*"
+	}
+}
+
+// formatTime formats seconds as the H:MM:SS format used by AVTransport.
+func formatTime(secs float64) string {
+	s := int(secs)
+	return fmt.Sprintf("%d:%02d:%02d", s/3600, (s/60)%60, s%60)
+}
+
+// parseTime parses the H+:MM:SS[.F+] format used by AVTransport into seconds.
+func parseTime(t string) float64 {
+	parts := strings.Split(strings.TrimSpace(t), ":")
+	if len(parts) != 3 {
+		return 0
+	}
+	var secs float64
+	for _, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return 0
+		}
+		secs = secs*60 + v
+	}
+	return secs
+}
@@ -0,0 +1,162 @@
+package dlna
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ssdpAddr          = "239.255.255.250:1900"
+	mediaRendererType = "urn:schemas-upnp-org:device:MediaRenderer:1"
+
+	avTransportService      = "urn:schemas-upnp-org:service:AVTransport:1"
+	renderingControlService = "urn:schemas-upnp-org:service:RenderingControl:1"
+)
+
+// A UPnP AV media renderer found on the local network.
+type Device struct {
+	// Unique device name, stable across discoveries
+	UDN string
+
+	// The friendly name to display in UIs
+	Name string
+
+	avTransportURL      string
+	renderingControlURL string
+}
+
+type deviceDescription struct {
+	Device struct {
+		UDN          string `xml:"UDN"`
+		FriendlyName string `xml:"friendlyName"`
+		Services     []struct {
+			ServiceType string `xml:"serviceType"`
+			ControlURL  string `xml:"controlURL"`
+		} `xml:"serviceList>service"`
+	} `xml:"device"`
+}
+
+// Discover searches the local network for UPnP AV media renderers
+// for the given duration, and returns those that support AVTransport.
+func Discover(ctx context.Context, timeout time.Duration) ([]Device, error) {
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	addr, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return nil, err
+	}
+	search := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddr + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n" +
+		"ST: " + mediaRendererType + "\r\n\r\n"
+	// UDP is unreliable; send the search a few times
+	for i := 0; i < 3; i++ {
+		if _, err := conn.WriteTo([]byte(search), addr); err != nil {
+			return nil, err
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+
+	locations := make(map[string]struct{})
+	buf := make([]byte, 2048)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			break // deadline reached
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil {
+			continue
+		}
+		if loc := resp.Header.Get("Location"); loc != "" {
+			locations[loc] = struct{}{}
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	devices := make(map[string]Device)
+	for loc := range locations {
+		wg.Add(1)
+		go func(loc string) {
+			defer wg.Done()
+			dev, err := fetchDevice(ctx, loc)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			devices[dev.UDN] = dev
+			mu.Unlock()
+		}(loc)
+	}
+	wg.Wait()
+
+	result := make([]Device, 0, len(devices))
+	for _, d := range devices {
+		result = append(result, d)
+	}
+	return result, nil
+}
+
+func fetchDevice(ctx context.Context, location string) (Device, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return Device{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Device{}, err
+	}
+	defer resp.Body.Close()
+
+	var desc deviceDescription
+	if err := xml.NewDecoder(resp.Body).Decode(&desc); err != nil {
+		return Device{}, err
+	}
+	base, err := url.Parse(location)
+	if err != nil {
+		return Device{}, err
+	}
+
+	dev := Device{UDN: desc.Device.UDN, Name: desc.Device.FriendlyName}
+	for _, svc := range desc.Device.Services {
+		ctrl, err := base.Parse(strings.TrimSpace(svc.ControlURL))
+		if err != nil {
+			continue
+		}
+		switch svc.ServiceType {
+		case avTransportService:
+			dev.avTransportURL = ctrl.String()
+		case renderingControlService:
+			dev.renderingControlURL = ctrl.String()
+		}
+	}
+	if dev.avTransportURL == "" {
+		return Device{}, fmt.Errorf("device %q does not support AVTransport", dev.Name)
+	}
+	if dev.UDN == "" {
+		dev.UDN = location
+	}
+	return dev, nil
+}
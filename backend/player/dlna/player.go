@@ -0,0 +1,334 @@
+package dlna
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+)
+
+const (
+	pollInterval = 1 * time.Second
+	// renderers may briefly report STOPPED while loading a new track
+	playStartGracePeriod = 3 * time.Second
+)
+
+var ErrNoRenderingControl = errors.New("device does not support volume control")
+
+// StreamURLFunc returns the URL the renderer should stream the track from.
+type StreamURLFunc func(track *mediaprovider.Track) (string, error)
+
+var _ player.TrackPlayer = (*Player)(nil)
+
+// Player plays tracks on a UPnP AV media renderer by pushing
+// stream URLs to it, and polls the renderer for its status.
+type Player struct {
+	dev       Device
+	streamURL StreamURLFunc
+	cancel    context.CancelFunc
+	ctx       context.Context
+
+	mu            sync.Mutex
+	status        player.Status
+	vol           int
+	cur           *mediaprovider.Track
+	curURI        string
+	next          *mediaprovider.Track
+	nextURI       string
+	playStartedAt time.Time
+
+	// callbacks
+	onPaused      []func()
+	onStopped     []func()
+	onPlaying     []func()
+	onSeek        []func()
+	onTrackChange []func()
+}
+
+// NewPlayer returns a Player for the given renderer device.
+// Destroy must be called when the player is no longer used.
+func NewPlayer(dev Device, streamURL StreamURLFunc) *Player {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Player{dev: dev, streamURL: streamURL, ctx: ctx, cancel: cancel, vol: 100}
+	if vol, err := p.fetchVolume(); err == nil {
+		p.vol = vol
+	}
+	go p.pollStatus()
+	return p
+}
+
+// Returns the renderer device this player plays to.
+func (p *Player) Device() Device {
+	return p.dev
+}
+
+// Destroy stops playback and stops polling the renderer.
+func (p *Player) Destroy() {
+	p.Stop()
+	p.cancel()
+}
+
+func (p *Player) PlayTrack(track *mediaprovider.Track) error {
+	if track == nil {
+		return p.Stop()
+	}
+	url, err := p.streamURL(track)
+	if err != nil {
+		return err
+	}
+	if err := p.avTransport("SetAVTransportURI",
+		soapArg{"CurrentURI", url}, soapArg{"CurrentURIMetaData", didlLite(track, url)}); err != nil {
+		return err
+	}
+	if err := p.avTransport("Play", soapArg{"Speed", "1"}); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.cur, p.curURI = track, url
+	p.next, p.nextURI = nil, ""
+	p.playStartedAt = time.Now()
+	p.status.TimePos = 0
+	p.status.Duration = float64(track.Duration)
+	p.mu.Unlock()
+
+	p.setState(player.Playing)
+	invokeCallbacks(p.onTrackChange)
+	return nil
+}
+
+func (p *Player) SetNextTrack(track *mediaprovider.Track) error {
+	if track == nil {
+		p.mu.Lock()
+		p.next, p.nextURI = nil, ""
+		p.mu.Unlock()
+		return nil
+	}
+	url, err := p.streamURL(track)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.next, p.nextURI = track, url
+	p.mu.Unlock()
+
+	// Optional action - if the renderer doesn't support it, the next track
+	// will be started when the renderer stops at the end of the current one.
+	if err := p.avTransport("SetNextAVTransportURI",
+		soapArg{"NextURI", url}, soapArg{"NextURIMetaData", didlLite(track, url)}); err != nil {
+		log.Printf("renderer %q does not support gapless: %v", p.dev.Name, err)
+	}
+	return nil
+}
+
+func (p *Player) Continue() error {
+	if err := p.avTransport("Play", soapArg{"Speed", "1"}); err != nil {
+		return err
+	}
+	p.setState(player.Playing)
+	return nil
+}
+
+func (p *Player) Pause() error {
+	if err := p.avTransport("Pause"); err != nil {
+		return err
+	}
+	p.setState(player.Paused)
+	return nil
+}
+
+func (p *Player) Stop() error {
+	p.mu.Lock()
+	p.cur, p.curURI = nil, ""
+	p.next, p.nextURI = nil, ""
+	p.mu.Unlock()
+	err := p.avTransport("Stop")
+	p.setState(player.Stopped)
+	return err
+}
+
+func (p *Player) SeekSeconds(secs float64) error {
+	if err := p.avTransport("Seek",
+		soapArg{"Unit", "REL_TIME"}, soapArg{"Target", formatTime(secs)}); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.status.TimePos = secs
+	p.mu.Unlock()
+	invokeCallbacks(p.onSeek)
+	return nil
+}
+
+func (p *Player) IsSeeking() bool {
+	return false
+}
+
+func (p *Player) SetVolume(vol int) error {
+	if p.dev.renderingControlURL == "" {
+		return ErrNoRenderingControl
+	}
+	_, err := soapCall(p.ctx, p.dev.renderingControlURL, renderingControlService, "SetVolume", []soapArg{
+		{"InstanceID", "0"}, {"Channel", "Master"}, {"DesiredVolume", strconv.Itoa(vol)},
+	})
+	if err == nil {
+		p.mu.Lock()
+		p.vol = vol
+		p.mu.Unlock()
+	}
+	return err
+}
+
+func (p *Player) GetVolume() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.vol
+}
+
+func (p *Player) GetStatus() player.Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+func (p *Player) OnPaused(cb func()) {
+	p.onPaused = append(p.onPaused, cb)
+}
+
+func (p *Player) OnStopped(cb func()) {
+	p.onStopped = append(p.onStopped, cb)
+}
+
+func (p *Player) OnPlaying(cb func()) {
+	p.onPlaying = append(p.onPlaying, cb)
+}
+
+func (p *Player) OnSeek(cb func()) {
+	p.onSeek = append(p.onSeek, cb)
+}
+
+func (p *Player) OnTrackChange(cb func()) {
+	p.onTrackChange = append(p.onTrackChange, cb)
+}
+
+func (p *Player) avTransport(action string, args ...soapArg) error {
+	args = append([]soapArg{{"InstanceID", "0"}}, args...)
+	_, err := soapCall(p.ctx, p.dev.avTransportURL, avTransportService, action, args)
+	return err
+}
+
+func (p *Player) fetchVolume() (int, error) {
+	if p.dev.renderingControlURL == "" {
+		return 0, ErrNoRenderingControl
+	}
+	res, err := soapCall(p.ctx, p.dev.renderingControlURL, renderingControlService, "GetVolume",
+		[]soapArg{{"InstanceID", "0"}, {"Channel", "Master"}})
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(res["CurrentVolume"])
+}
+
+// sets the state and invokes callbacks, if it changed
+func (p *Player) setState(s player.State) {
+	p.mu.Lock()
+	prev := p.status.State
+	p.status.State = s
+	if s == player.Stopped {
+		p.status.TimePos = 0
+		p.status.Duration = 0
+	}
+	p.mu.Unlock()
+	if s == prev {
+		return
+	}
+	switch s {
+	case player.Playing:
+		invokeCallbacks(p.onPlaying)
+	case player.Paused:
+		invokeCallbacks(p.onPaused)
+	case player.Stopped:
+		invokeCallbacks(p.onStopped)
+	}
+}
+
+func (p *Player) pollStatus() {
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-t.C:
+			if err := p.updateStatus(); err != nil && p.ctx.Err() == nil {
+				log.Printf("error polling renderer %q: %v", p.dev.Name, err)
+			}
+		}
+	}
+}
+
+func (p *Player) updateStatus() error {
+	args := []soapArg{{"InstanceID", "0"}}
+	transport, err := soapCall(p.ctx, p.dev.avTransportURL, avTransportService, "GetTransportInfo", args)
+	if err != nil {
+		return err
+	}
+	pos, err := soapCall(p.ctx, p.dev.avTransportURL, avTransportService, "GetPositionInfo", args)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	if p.cur == nil {
+		p.mu.Unlock()
+		return nil // stopped by us
+	}
+	trackChanged := false
+	if p.nextURI != "" && pos["TrackURI"] == p.nextURI {
+		// the renderer advanced to the next track by itself (gapless)
+		p.cur, p.curURI = p.next, p.nextURI
+		p.next, p.nextURI = nil, ""
+		p.status.Duration = float64(p.cur.Duration)
+		trackChanged = true
+	}
+	if d := parseTime(pos["TrackDuration"]); d > 0 {
+		p.status.Duration = d
+	}
+	p.status.TimePos = parseTime(pos["RelTime"])
+	inGracePeriod := time.Since(p.playStartedAt) < playStartGracePeriod
+	next := p.next
+	p.mu.Unlock()
+
+	if trackChanged {
+		invokeCallbacks(p.onTrackChange)
+	}
+	switch transport["CurrentTransportState"] {
+	case "PLAYING":
+		p.setState(player.Playing)
+	case "PAUSED_PLAYBACK":
+		p.setState(player.Paused)
+	case "STOPPED", "NO_MEDIA_PRESENT":
+		if inGracePeriod {
+			return nil
+		}
+		// the current track ended
+		if next != nil {
+			return p.PlayTrack(next)
+		}
+		p.mu.Lock()
+		p.cur, p.curURI = nil, ""
+		p.mu.Unlock()
+		p.setState(player.Stopped)
+	}
+	return nil
+}
+
+func invokeCallbacks(cbs []func()) {
+	for _, cb := range cbs {
+		cb()
+	}
+}
@@ -0,0 +1,103 @@
+package dlna
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const soapTimeout = 5 * time.Second
+
+type soapArg struct {
+	Name  string
+	Value string
+}
+
+// soapCall invokes a UPnP action and returns the output arguments by name.
+func soapCall(ctx context.Context, controlURL, serviceType, action string, args []soapArg) (map[string]string, error) {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	body.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&body, `<u:%s xmlns:u="%s">`, action, serviceType)
+	for _, a := range args {
+		fmt.Fprintf(&body, "<%s>", a.Name)
+		xml.EscapeText(&body, []byte(a.Value))
+		fmt.Fprintf(&body, "</%s>", a.Name)
+	}
+	fmt.Fprintf(&body, `</u:%s></s:Body></s:Envelope>`, action)
+
+	ctx, cancel := context.WithTimeout(ctx, soapTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, controlURL, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, serviceType, action))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s failed: %s %s", action, resp.Status, upnpErrorDescription(respBody))
+	}
+	return parseSOAPResponse(respBody, action+"Response")
+}
+
+// parseSOAPResponse returns the child elements of the
+// given response element as a map of name to text value.
+func parseSOAPResponse(body []byte, responseElem string) (map[string]string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	out := make(map[string]string)
+	inResponse := false
+	var curElem string
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == responseElem {
+				inResponse = true
+			} else if inResponse {
+				curElem = t.Name.Local
+				text.Reset()
+			}
+		case xml.CharData:
+			if curElem != "" {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if t.Name.Local == responseElem {
+				return out, nil
+			} else if curElem == t.Name.Local {
+				out[curElem] = text.String()
+				curElem = ""
+			}
+		}
+	}
+	return out, nil
+}
+
+func upnpErrorDescription(body []byte) string {
+	if res, err := parseSOAPResponse(body, "UPnPError"); err == nil {
+		return fmt.Sprintf("(%s: %s)", res["errorCode"], res["errorDescription"])
+	}
+	return ""
+}
@@ -0,0 +1,27 @@
+package dlna
+
+import "testing"
+
+func TestParseSOAPResponse(t *testing.T) {
+	body := []byte(`<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>
+<u:GetPositionInfoResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1">
+<Track>1</Track><TrackDuration>0:03:25</TrackDuration><TrackURI>http://host/stream?id=1&amp;x=2</TrackURI><RelTime>0:01:02.500</RelTime>
+</u:GetPositionInfoResponse></s:Body></s:Envelope>`)
+	res, err := parseSOAPResponse(body, "GetPositionInfoResponse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res["TrackURI"] != "http://host/stream?id=1&x=2" {
+		t.Errorf("unexpected TrackURI %q", res["TrackURI"])
+	}
+	if d := parseTime(res["TrackDuration"]); d != 205 {
+		t.Errorf("expected duration 205, got %v", d)
+	}
+	if p := parseTime(res["RelTime"]); p != 62.5 {
+		t.Errorf("expected position 62.5, got %v", p)
+	}
+	if f := formatTime(3725); f != "1:02:05" {
+		t.Errorf("expected 1:02:05, got %s", f)
+	}
+}
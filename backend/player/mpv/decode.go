@@ -0,0 +1,67 @@
+package mpv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/dweymouth/go-mpv"
+)
+
+// DecodeToWAV decodes the audio from url (a stream URL or local file path)
+// into a mono 16-bit WAV file at outPath with the given sample rate,
+// using a separate mpv instance that decodes as fast as the input allows.
+// The file is written progressively and can be read while decoding.
+// header holds the HTTP headers to request the stream with, or is nil.
+func DecodeToWAV(ctx context.Context, url string, header http.Header, outPath string, sampleRate int) error {
+	m := mpv.Create()
+	defer m.TerminateDestroy()
+
+	for _, opt := range [][2]string{
+		{"config", "no"},
+		{"terminal", "no"},
+		{"video", "no"},
+		{"idle", "yes"},
+		{"replaygain", "no"},
+		{"ao", "pcm"},
+		{"ao-pcm-file", outPath},
+		{"ao-pcm-waveheader", "yes"},
+		{"untimed", "yes"},
+		{"audio-channels", "mono"},
+		{"audio-format", "s16"},
+		{"audio-samplerate", fmt.Sprint(sampleRate)},
+	} {
+		if err := m.SetOptionString(opt[0], opt[1]); err != nil {
+			return fmt.Errorf("error setting mpv option %s: %s", opt[0], err.Error())
+		}
+	}
+	if len(header) > 0 {
+		if err := m.SetOption("http-header-fields", mpv.FORMAT_NODE, headerFieldsNode(header)); err != nil {
+			return fmt.Errorf("error setting mpv option http-header-fields: %s", err.Error())
+		}
+	}
+	if err := m.Initialize(); err != nil {
+		return fmt.Errorf("error initializing mpv: %s", err.Error())
+	}
+	if err := m.Command([]string{"loadfile", url}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		switch m.WaitEvent(0.5 /*timeout seconds*/).Event_Id {
+		case mpv.EVENT_END_FILE, mpv.EVENT_SHUTDOWN:
+			// the end file reason isn't exposed, so check that some audio was decoded
+			if fi, err := os.Stat(outPath); err != nil || fi.Size() <= 44 /*wav header*/ {
+				return errors.New("no audio decoded")
+			}
+			return nil
+		}
+	}
+}
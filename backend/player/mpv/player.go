@@ -5,7 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/dweymouth/go-mpv"
 	"github.com/dweymouth/supersonic/backend/player"
@@ -45,7 +48,24 @@ type MediaInfo struct {
 	Bitrate int
 }
 
+// OutputInfo describes the audio as it is being sent to the output device.
+type OutputInfo struct {
+	// The name of the audio device in use.
+	Device string
+
+	// The sample format as string, as sent to the audio output.
+	Format string
+
+	// Output samplerate.
+	Samplerate int
+
+	// The number of output channels.
+	ChannelCount int
+}
+
 var _ player.URLPlayer = (*Player)(nil)
+var _ player.SkipSilencePlayer = (*Player)(nil)
+var _ player.HTTPHeaderPlayer = (*Player)(nil)
 
 // Player encapsulates the mpv instance and provides functions
 // to control it and to check its status.
@@ -54,8 +74,15 @@ type Player struct {
 	initialized    bool
 	vol            int
 	replayGainOpts player.ReplayGainOptions
+	clientRGain    player.ClientReplayGain
+	nextFileRGain  player.ClientReplayGain // for the next loadfile
+	nextFileHeader http.Header             // for the next loadfile
+	skipSilence    player.SkipSilenceOptions
+	outputLimiter  bool
+	monoDownmix    bool
 	haveRGainOpts  bool
 	audioExclusive bool
+	bitPerfect     bool
 	status         player.Status
 	seeking        bool
 	curPlaylistPos int64
@@ -63,6 +90,9 @@ type Player struct {
 	prePausedState player.State
 	clientName     string
 	equalizer      Equalizer
+	pcmOutput      string
+	ducked         bool
+	duckFraction   float64 // of the volume to output while ducked
 
 	bgCancel context.CancelFunc
 
@@ -72,8 +102,22 @@ type Player struct {
 	onPlaying     []func()
 	onSeek        []func()
 	onTrackChange []func()
+
+	onAudioDeviceListChanged []func()
+	onBufferUnderrun         []func()
+
+	// also accessed from the event handler
+	filesMu    sync.Mutex
+	fileRGains map[string]player.ClientReplayGain // by URL of the loaded files
+	nextURL    string
 }
 
+// reply userdata values for observed mpv properties
+const (
+	observeAudioDeviceList uint64 = iota + 1
+	observePausedForCache
+)
+
 // Returns a new player.
 // Must call Init on the player before it is ready for playback.
 func New() *Player {
@@ -109,7 +153,7 @@ func (p *Player) Init(maxCacheMB int) error {
 		if p.vol < 0 {
 			p.vol = 100
 		}
-		m.SetOption("volume", mpv.FORMAT_INT64, p.vol)
+		m.SetOption("volume", mpv.FORMAT_INT64, p.outputVolume())
 
 		p.SetAudioExclusive(p.audioExclusive)
 		if p.haveRGainOpts {
@@ -123,6 +167,8 @@ func (p *Player) Init(maxCacheMB int) error {
 		if err := m.Initialize(); err != nil {
 			return fmt.Errorf("error initializing mpv: %s", err.Error())
 		}
+		m.ObserveProperty(observeAudioDeviceList, "audio-device-list", mpv.FORMAT_NONE)
+		m.ObserveProperty(observePausedForCache, "paused-for-cache", mpv.FORMAT_FLAG)
 		p.mpv = m
 	}
 	ctx, cancel := context.WithCancel(context.Background())
@@ -137,7 +183,11 @@ func (p *Player) PlayFile(url string) error {
 	if !p.initialized {
 		return ErrUnitialized
 	}
-	err := p.mpv.Command([]string{"loadfile", url, "replace"})
+	p.filesMu.Lock()
+	p.fileRGains = map[string]player.ClientReplayGain{}
+	p.nextURL = ""
+	p.filesMu.Unlock()
+	err := p.loadFile(url, "replace")
 	if err == nil {
 		p.lenPlaylist = 1
 		if p.status.State == player.Paused {
@@ -176,17 +226,62 @@ func (p *Player) SetNextFile(url string) error {
 		}
 		p.lenPlaylist--
 	}
+	p.filesMu.Lock()
+	p.nextURL = url
+	p.filesMu.Unlock()
 	if url == "" {
 		return nil
 	}
 
-	err := p.mpv.Command([]string{"loadfile", url, "append"})
+	err := p.loadFile(url, "append")
 	if err == nil {
 		p.lenPlaylist++
 	}
 	return err
 }
 
+// loadFile loads the file with the client ReplayGain set by
+// SetNextFileClientReplayGain, as per-file options so that
+// it takes effect exactly when the file starts playing,
+// and the HTTP headers set by SetNextFileHTTPHeaders.
+func (p *Player) loadFile(url, flags string) error {
+	rg := p.nextFileRGain
+	p.filesMu.Lock()
+	if p.fileRGains == nil {
+		p.fileRGains = map[string]player.ClientReplayGain{}
+	}
+	p.fileRGains[url] = rg
+	p.filesMu.Unlock()
+	options := map[string]*mpv.Node{
+		"af":         {Format: mpv.FORMAT_STRING, Data: p.audioFilters(rg)},
+		"replaygain": {Format: mpv.FORMAT_STRING, Data: p.replayGainMode(rg)},
+	}
+	if len(p.nextFileHeader) > 0 {
+		options["http-header-fields"] = headerFieldsNode(p.nextFileHeader)
+	}
+	// named arguments, since the position of the options
+	// argument differs between mpv versions
+	cmd := mpv.Node{Format: mpv.FORMAT_NODE_MAP, Data: map[string]*mpv.Node{
+		"name":    {Format: mpv.FORMAT_STRING, Data: "loadfile"},
+		"url":     {Format: mpv.FORMAT_STRING, Data: url},
+		"flags":   {Format: mpv.FORMAT_STRING, Data: flags},
+		"options": {Format: mpv.FORMAT_NODE_MAP, Data: options},
+	}}
+	return p.mpv.CommandNode(cmd, &mpv.Node{Format: mpv.FORMAT_NONE})
+}
+
+// headerFieldsNode returns the value of the http-header-fields option
+// for the headers, as a list so that values with commas need no escaping.
+func headerFieldsNode(h http.Header) *mpv.Node {
+	var fields []*mpv.Node
+	for name, vals := range h {
+		for _, v := range vals {
+			fields = append(fields, &mpv.Node{Format: mpv.FORMAT_STRING, Data: name + ": " + v})
+		}
+	}
+	return &mpv.Node{Format: mpv.FORMAT_NODE_ARRAY, Data: fields}
+}
+
 // Seeks within the currently playing track.
 // See MPV seek command documentation for more details.
 func (p *Player) SeekSeconds(secs float64) error {
@@ -208,10 +303,12 @@ func (p *Player) SetVolume(vol int) error {
 	} else if vol < 0 {
 		vol = 0
 	}
-	if p.initialized {
-		err := p.mpv.SetProperty("volume", mpv.FORMAT_INT64, vol)
-		if err == nil {
-			p.vol = vol
+	if p.initialized && !p.bitPerfect {
+		old := p.vol
+		p.vol = vol
+		err := p.mpv.SetProperty("volume", mpv.FORMAT_INT64, p.outputVolume())
+		if err != nil {
+			p.vol = old
 		}
 		return err
 	}
@@ -219,22 +316,38 @@ func (p *Player) SetVolume(vol int) error {
 	return nil
 }
 
+// SetDucked lowers the output volume to the fraction of the volume set
+// by SetVolume, e.g. while a call is in progress, without changing
+// the volume returned by GetVolume. A fraction of 1 or more unducks.
+// Ducking has no effect in bit-perfect mode.
+func (p *Player) SetDucked(fraction float64) error {
+	p.ducked = fraction < 1
+	p.duckFraction = max(fraction, 0)
+	if !p.initialized {
+		return nil
+	}
+	return p.mpv.SetProperty("volume", mpv.FORMAT_INT64, p.outputVolume())
+}
+
+// the volume to set on mpv, which must not scale the samples in bit-perfect mode
+func (p *Player) outputVolume() int {
+	if p.bitPerfect {
+		return 100
+	}
+	if p.ducked {
+		return int(float64(p.vol) * p.duckFraction)
+	}
+	return p.vol
+}
+
 // Sets the ReplayGain options of the player.
 // Unlike most Player functions, SetReplayGainOptions can be called
 // before Init, to set the initial replaygain options of the player on startup.
 func (p *Player) SetReplayGainOptions(options player.ReplayGainOptions) error {
 	p.replayGainOpts = options
 	p.haveRGainOpts = true
-	mode := "no"
-	switch options.Mode {
-	case player.ReplayGainAlbum:
-		mode = "album"
-	case player.ReplayGainTrack:
-		mode = "track"
-	}
-
 	if p.initialized {
-		if err := p.mpv.SetPropertyString("replaygain", mode); err != nil {
+		if err := p.mpv.SetPropertyString("replaygain", p.replayGainMode(p.clientRGain)); err != nil {
 			return err
 		}
 		if err := p.mpv.SetProperty("replaygain-preamp", mpv.FORMAT_DOUBLE, options.PreampGain); err != nil {
@@ -251,6 +364,21 @@ func (p *Player) SetReplayGainOptions(options player.ReplayGainOptions) error {
 	return nil
 }
 
+// replayGainMode returns the value of mpv's replaygain
+// option for a file with the given client ReplayGain.
+func (p *Player) replayGainMode(rg player.ClientReplayGain) string {
+	if rg.Enabled || p.bitPerfect {
+		return "no" // ReplayGain is being applied by the client, or disabled
+	}
+	switch p.replayGainOpts.Mode {
+	case player.ReplayGainAlbum:
+		return "album"
+	case player.ReplayGainTrack:
+		return "track"
+	}
+	return "no"
+}
+
 // Sets the audio exclusive option of the player.
 // Unlike most Player functions, SetAudioExclusive can be called
 // before Init, to set the initial option of the player on startup.
@@ -258,13 +386,45 @@ func (p *Player) SetAudioExclusive(tf bool) {
 	p.audioExclusive = tf
 	if p.initialized {
 		val := "no"
-		if tf {
+		if p.isExclusive() {
 			val = "yes"
 		}
 		p.mpv.SetOptionString("audio-exclusive", val)
 	}
 }
 
+// Sets whether the player outputs the decoded audio unaltered: the audio device
+// is opened in exclusive mode (WASAPI exclusive on Windows, hog mode on macOS),
+// bypassing the OS mixer, and the volume, ReplayGain and all audio filters are
+// disabled. Since gapless-audio is "weak", the output is reinitialized when the
+// sample format or rate changes, so the device always matches the source.
+// While enabled, SetVolume only records the volume to restore afterwards.
+// Unlike most Player functions, SetBitPerfect can be called
+// before Init, to set the initial option of the player on startup.
+func (p *Player) SetBitPerfect(tf bool) error {
+	if tf == p.bitPerfect {
+		return nil
+	}
+	p.bitPerfect = tf
+	if !p.initialized {
+		return nil
+	}
+	p.SetAudioExclusive(p.audioExclusive)
+	if err := p.mpv.SetProperty("volume", mpv.FORMAT_INT64, p.outputVolume()); err != nil {
+		return err
+	}
+	if p.haveRGainOpts {
+		if err := p.SetReplayGainOptions(p.replayGainOpts); err != nil {
+			return err
+		}
+	}
+	return p.updateAudioFilters()
+}
+
+func (p *Player) isExclusive() bool {
+	return p.audioExclusive || p.bitPerfect
+}
+
 // Gets the current volume of the player.
 func (p *Player) GetVolume() int {
 	return p.vol
@@ -273,13 +433,13 @@ func (p *Player) GetVolume() int {
 // sets paused status and ensures that audio exlusive is false while paused
 // (releases audio device to other players)
 func (p *Player) setPaused(paused bool) error {
-	if !paused && p.audioExclusive {
+	if !paused && p.isExclusive() {
 		if err := p.mpv.SetOptionString("audio-exclusive", "yes"); err != nil {
 			return err
 		}
 	}
 	err := p.mpv.SetProperty("pause", mpv.FORMAT_FLAG, paused)
-	if err == nil && paused && p.audioExclusive {
+	if err == nil && paused && p.isExclusive() {
 		err = p.mpv.SetOptionString("audio-exclusive", "no")
 	}
 	return err
@@ -350,22 +510,186 @@ func (p *Player) SetAudioDevice(deviceName string) error {
 	return p.mpv.SetPropertyString("audio-device", deviceName)
 }
 
+// Returns the name of the audio device currently set on the player.
+func (p *Player) AudioDevice() (string, error) {
+	dev, err := p.mpv.GetProperty("audio-device", mpv.FORMAT_STRING)
+	if err != nil {
+		return "", err
+	}
+	return dev.(string), nil
+}
+
+// The format of the audio written by SetPCMOutput: 16-bit signed
+// little endian samples, the default format of Snapcast streams.
+const (
+	PCMSampleRate = 48000
+	PCMChannels   = 2
+)
+
+// SetPCMOutput sets the player to write the decoded audio as raw PCM to the
+// file, e.g. a named pipe read by another program, rather than playing it on
+// the audio device. mpv writes only as fast as the file is read, so the reader
+// sets the pace of playback. An empty file restores the audio device output.
+// Opening a named pipe blocks until it has a reader, which would hang the player,
+// so the caller must ensure it has one.
+func (p *Player) SetPCMOutput(file string) error {
+	if !p.initialized {
+		return ErrUnitialized
+	}
+	if file == p.pcmOutput {
+		return nil
+	}
+	p.pcmOutput = file
+	opts := [][2]string{
+		{"ao", ""},
+		{"audio-format", "no"},
+		{"audio-samplerate", "0"},
+		{"audio-channels", "auto-safe"},
+	}
+	if file != "" {
+		opts = [][2]string{
+			{"ao-pcm-file", file},
+			{"ao-pcm-waveheader", "no"},
+			{"ao", "pcm"},
+			{"audio-format", "s16"},
+			{"audio-samplerate", strconv.Itoa(PCMSampleRate)},
+			{"audio-channels", "stereo"},
+		}
+	}
+	for _, o := range opts {
+		if err := p.mpv.SetOptionString(o[0], o[1]); err != nil {
+			return fmt.Errorf("error setting %s: %w", o[0], err)
+		}
+	}
+	return p.mpv.Command([]string{"ao-reload"})
+}
+
 func (p *Player) SetEqualizer(eq Equalizer) error {
 	p.equalizer = eq
-	if eq == nil || !eq.IsEnabled() {
-		return p.mpv.SetPropertyString("af", "")
+	return p.updateAudioFilters()
+}
+
+// SetClientReplayGain applies a ReplayGain adjustment computed by the client
+// to the current file. While enabled, mpv's own tag-based ReplayGain is disabled.
+func (p *Player) SetClientReplayGain(rg player.ClientReplayGain) error {
+	if !p.initialized {
+		return ErrUnitialized
+	}
+	if rg == p.clientRGain {
+		return nil // e.g. already applied when the file was loaded
 	}
-	af := ""
-	if math.Abs(eq.Preamp()) > 0.01 {
-		af = fmt.Sprintf("volume=volume=%0.1fdB", eq.Preamp())
+	p.clientRGain = rg
+	if err := p.mpv.SetPropertyString("replaygain", p.replayGainMode(rg)); err != nil {
+		return err
+	}
+	return p.updateAudioFilters()
+}
+
+func (p *Player) SetNextFileClientReplayGain(rg player.ClientReplayGain) {
+	p.nextFileRGain = rg
+}
+
+func (p *Player) SetNextFileHTTPHeaders(h http.Header) {
+	p.nextFileHeader = h
+}
+
+// SetSkipSilenceOptions sets whether and how long silences are trimmed from the audio.
+func (p *Player) SetSkipSilenceOptions(opts player.SkipSilenceOptions) error {
+	if !p.initialized {
+		return ErrUnitialized
+	}
+	if opts == p.skipSilence {
+		return nil
+	}
+	p.skipSilence = opts
+	return p.updateAudioFilters()
+}
+
+// SetOutputLimiter sets whether a peak limiter is applied at the end of the
+// audio filter chain, to prevent clipping when the EQ or ReplayGain preamp boosts the signal.
+func (p *Player) SetOutputLimiter(tf bool) error {
+	if !p.initialized {
+		return ErrUnitialized
+	}
+	if tf == p.outputLimiter {
+		return nil
 	}
-	eqAF := eq.Curve().String()
-	if af == "" {
-		af = eqAF
-	} else if eqAF != "" {
-		af = fmt.Sprintf("%s,%s", af, eqAF)
+	p.outputLimiter = tf
+	return p.updateAudioFilters()
+}
+
+// SetMonoDownmix sets whether the audio is downmixed to mono,
+// which is then played on all output channels.
+func (p *Player) SetMonoDownmix(tf bool) error {
+	if !p.initialized {
+		return ErrUnitialized
+	}
+	if tf == p.monoDownmix {
+		return nil
 	}
-	return p.mpv.SetPropertyString("af", af)
+	p.monoDownmix = tf
+	return p.updateAudioFilters()
+}
+
+// updateAudioFilters sets the audio filter chain of the current file,
+// and reloads the next file, if any, so that its chain is updated too.
+func (p *Player) updateAudioFilters() error {
+	if err := p.mpv.SetPropertyString("af", p.audioFilters(p.clientRGain)); err != nil {
+		return err
+	}
+	p.filesMu.Lock()
+	nextURL := p.nextURL
+	p.nextFileRGain = p.fileRGains[nextURL]
+	p.filesMu.Unlock()
+	if nextURL != "" && p.lenPlaylist > p.curPlaylistPos+1 {
+		return p.SetNextFile(nextURL)
+	}
+	return nil
+}
+
+// audioFilters builds the mpv audio filter chain for a file:
+// skip silence -> client ReplayGain (or loudness normalization) -> EQ preamp -> EQ
+// -> mono downmix -> limiter
+// In bit-perfect mode, the chain is empty.
+func (p *Player) audioFilters(rg player.ClientReplayGain) string {
+	if p.bitPerfect {
+		return ""
+	}
+	var filters []string
+	if s := p.skipSilence; s.Enabled {
+		// trims leading silence, and silences anywhere else (i.e. trailing
+		// silence and gaps before hidden tracks) longer than MaxGapSeconds,
+		// in both cases leaving at most MaxGapSeconds of silence
+		filters = append(filters, fmt.Sprintf(
+			"lavfi=[silenceremove=start_periods=1:start_threshold=%0.1fdB:start_silence=%0.2f"+
+				":stop_periods=-1:stop_threshold=%0.1fdB:stop_duration=%0.2f:stop_silence=%0.2f]",
+			s.ThresholdDB, s.MaxGapSeconds, s.ThresholdDB, s.MaxGapSeconds, s.MaxGapSeconds))
+	}
+	if rg.Enabled {
+		if rg.Normalize {
+			// EBU R128 normalization to the ReplayGain 2.0 reference level
+			filters = append(filters, "lavfi=[loudnorm=I=-18:TP=-1]")
+		} else if math.Abs(rg.GainDB) > 0.01 {
+			filters = append(filters, fmt.Sprintf("volume=volume=%0.2fdB", rg.GainDB))
+		}
+	}
+	if eq := p.equalizer; eq != nil && eq.IsEnabled() {
+		if math.Abs(eq.Preamp()) > 0.01 {
+			filters = append(filters, fmt.Sprintf("volume=volume=%0.1fdB", eq.Preamp()))
+		}
+		if eqAF := eq.Curve().String(); eqAF != "" {
+			filters = append(filters, eqAF)
+		}
+	}
+	if p.monoDownmix {
+		// convert to mono and back to stereo, so the downmix plays on both speakers
+		filters = append(filters, "lavfi=[aformat=channel_layouts=mono,aformat=channel_layouts=stereo]")
+	}
+	if p.outputLimiter {
+		// limit peaks to -0.1 dBFS without normalizing the output level
+		filters = append(filters, "lavfi=[alimiter=limit=0.989:level=disabled]")
+	}
+	return strings.Join(filters, ",")
 }
 
 func (p *Player) Equalizer() Equalizer {
@@ -395,6 +719,55 @@ func (p *Player) GetMediaInfo() (MediaInfo, error) {
 	return info, nil
 }
 
+// GetOutputInfo returns information about the audio after
+// all filters have been applied, as it is sent to the output device.
+func (p *Player) GetOutputInfo() (OutputInfo, error) {
+	var info OutputInfo
+	n, err := p.mpv.GetProperty("audio-out-params", mpv.FORMAT_NODE)
+	if err != nil {
+		return info, err
+	}
+	nodeMap := n.(*mpv.Node).Data.(map[string]*mpv.Node)
+	info.Format = nodeMap["format"].Data.(string)
+	info.Samplerate = int(nodeMap["samplerate"].Data.(int64))
+	info.ChannelCount = int(nodeMap["channel-count"].Data.(int64))
+
+	if dev, err := p.mpv.GetProperty("audio-device", mpv.FORMAT_STRING); err == nil {
+		info.Device = dev.(string)
+	}
+	return info, nil
+}
+
+// ReplayGainOptions returns the ReplayGain options currently in effect.
+func (p *Player) ReplayGainOptions() player.ReplayGainOptions {
+	return p.replayGainOpts
+}
+
+// ClientReplayGain returns the client ReplayGain adjustment currently in effect.
+func (p *Player) ClientReplayGain() player.ClientReplayGain {
+	return p.clientRGain
+}
+
+// SkipSilenceOptions returns the current skip silence options.
+func (p *Player) SkipSilenceOptions() player.SkipSilenceOptions {
+	return p.skipSilence
+}
+
+// OutputLimiter returns whether the output peak limiter is enabled.
+func (p *Player) OutputLimiter() bool {
+	return p.outputLimiter
+}
+
+// MonoDownmix returns whether the output is downmixed to mono.
+func (p *Player) MonoDownmix() bool {
+	return p.monoDownmix
+}
+
+// BitPerfect returns whether bit-perfect output is enabled.
+func (p *Player) BitPerfect() bool {
+	return p.bitPerfect
+}
+
 func (p *Player) getInt64Property(propName string) (int64, error) {
 	playpos, err := p.mpv.GetProperty(propName, mpv.FORMAT_INT64)
 	if err != nil {
@@ -438,6 +811,20 @@ func (p *Player) OnTrackChange(cb func()) {
 	p.onTrackChange = append(p.onTrackChange, cb)
 }
 
+// Registers a callback which is invoked when the list of available
+// audio devices changes (e.g. a USB DAC is plugged in or removed).
+// The callback is invoked on the player's event handling goroutine.
+func (p *Player) OnAudioDeviceListChanged(cb func()) {
+	p.onAudioDeviceListChanged = append(p.onAudioDeviceListChanged, cb)
+}
+
+// Registers a callback which is invoked when playback stalls
+// waiting for the stream to buffer. The callback is invoked
+// on the player's event handling goroutine.
+func (p *Player) OnBufferUnderrun(cb func()) {
+	p.onBufferUnderrun = append(p.onBufferUnderrun, cb)
+}
+
 // Destroy the player.
 func (p *Player) Destroy() {
 	if p.bgCancel != nil {
@@ -496,6 +883,18 @@ func (p *Player) eventHandler(ctx context.Context) {
 				}
 			case mpv.EVENT_FILE_LOADED:
 				p.curPlaylistPos, _ = p.getInt64Property("playlist-pos")
+				// the file's client ReplayGain was applied by loadFile
+				path := p.mpv.GetPropertyString("path")
+				p.filesMu.Lock()
+				if rg, ok := p.fileRGains[path]; ok {
+					p.clientRGain = rg
+				}
+				for url := range p.fileRGains {
+					if url != path && url != p.nextURL {
+						delete(p.fileRGains, url)
+					}
+				}
+				p.filesMu.Unlock()
 				if p.status.State == player.Paused {
 					// seek while paused switches to a new file
 					// mpv does not fire seek event in this case
@@ -510,6 +909,19 @@ func (p *Player) eventHandler(ctx context.Context) {
 				p.status.Duration = 0
 				p.status.TimePos = 0
 				p.setState(player.Stopped)
+			case mpv.EVENT_PROPERTY_CHANGE:
+				switch e.Reply_Userdata {
+				case observeAudioDeviceList:
+					for _, cb := range p.onAudioDeviceListChanged {
+						cb()
+					}
+				case observePausedForCache:
+					if paused, err := p.mpv.GetProperty("paused-for-cache", mpv.FORMAT_FLAG); err == nil && paused.(bool) && !p.seeking {
+						for _, cb := range p.onBufferUnderrun {
+							cb()
+						}
+					}
+				}
 			}
 		}
 	}
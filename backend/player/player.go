@@ -1,6 +1,10 @@
 package player
 
-import "github.com/dweymouth/supersonic/backend/mediaprovider"
+import (
+	"net/http"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
 
 type URLPlayer interface {
 	BasePlayer
@@ -39,6 +43,34 @@ type ReplayGainPlayer interface {
 	SetReplayGainOptions(ReplayGainOptions) error
 }
 
+// ClientReplayGainPlayer is a ReplayGainPlayer which can also apply
+// a gain computed by the client, e.g. from ReplayGain metadata reported by the server.
+type ClientReplayGainPlayer interface {
+	ReplayGainPlayer
+	SetClientReplayGain(ClientReplayGain) error
+
+	// SetNextFileClientReplayGain sets the adjustment for the file passed to
+	// the next PlayFile or SetNextFile call. It is applied from the start of
+	// the file, rather than after a gapless transition has been reported.
+	SetNextFileClientReplayGain(ClientReplayGain)
+}
+
+// HTTPHeaderPlayer is a URLPlayer which can request streams with
+// extra HTTP headers, e.g. credentials for a WebDAV share.
+type HTTPHeaderPlayer interface {
+	URLPlayer
+
+	// SetNextFileHTTPHeaders sets the headers to request the file passed
+	// to the next PlayFile or SetNextFile call with, or nil for none.
+	SetNextFileHTTPHeaders(http.Header)
+}
+
+// SkipSilencePlayer is a player which can trim long silences
+// from the audio, such as the gaps before hidden tracks.
+type SkipSilencePlayer interface {
+	SetSkipSilenceOptions(SkipSilenceOptions) error
+}
+
 // The playback state (Stopped, Paused, or Playing).
 type State int
 
@@ -72,6 +104,32 @@ type ReplayGainOptions struct {
 	// Fallback gain intentionally omitted
 }
 
+// A ReplayGain adjustment computed by the client (argument to SetClientReplayGain).
+type ClientReplayGain struct {
+	// If false, the player applies ReplayGain itself
+	// according to its ReplayGainOptions (e.g. from file tags).
+	Enabled bool
+
+	// The gain to apply to the current track, in dB.
+	GainDB float64
+
+	// If true, GainDB is ignored and the player instead normalizes
+	// the loudness based on a measurement of the audio itself.
+	Normalize bool
+}
+
+// Skip silence options (argument to SetSkipSilenceOptions).
+type SkipSilenceOptions struct {
+	Enabled bool
+
+	// Audio below this level, in dB, is considered silent.
+	ThresholdDB float64
+
+	// Silences longer than this, in seconds, are shortened to this length,
+	// including leading silence at the start of a track.
+	MaxGapSeconds float64
+}
+
 func (r ReplayGainMode) String() string {
 	switch r {
 	case ReplayGainTrack:
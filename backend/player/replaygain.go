@@ -0,0 +1,28 @@
+package player
+
+import (
+	"math"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// ComputeReplayGain returns the gain in dB to apply to a track with the given
+// ReplayGain info, according to the mode, preamp, and clipping prevention options.
+// Album mode falls back to the track gain if the album gain is missing.
+// Returns false if no gain can be computed (no info, or ReplayGainNone mode).
+func ComputeReplayGain(info *mediaprovider.ReplayGainInfo, opts ReplayGainOptions) (float64, bool) {
+	if info == nil || opts.Mode == ReplayGainNone {
+		return 0, false
+	}
+	gain, peak := info.TrackGain, info.TrackPeak
+	if opts.Mode == ReplayGainAlbum && (info.AlbumGain != 0 || info.AlbumPeak != 0) {
+		gain, peak = info.AlbumGain, info.AlbumPeak
+	}
+	gain += opts.PreampGain
+	if opts.PreventClipping && peak > 0 {
+		// the gain at which the peak sample reaches full scale
+		maxGain := -20 * math.Log10(peak)
+		gain = math.Min(gain, maxGain)
+	}
+	return gain, true
+}
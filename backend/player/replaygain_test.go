@@ -0,0 +1,42 @@
+package player
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+func Test_ComputeReplayGain(t *testing.T) {
+	info := &mediaprovider.ReplayGainInfo{
+		TrackGain: -6,
+		TrackPeak: 0.5,
+		AlbumGain: -8,
+		AlbumPeak: 0.9,
+	}
+
+	if _, ok := ComputeReplayGain(nil, ReplayGainOptions{Mode: ReplayGainTrack}); ok {
+		t.Error("expected no gain for nil info")
+	}
+	if _, ok := ComputeReplayGain(info, ReplayGainOptions{Mode: ReplayGainNone}); ok {
+		t.Error("expected no gain for ReplayGainNone")
+	}
+	if g, _ := ComputeReplayGain(info, ReplayGainOptions{Mode: ReplayGainTrack, PreampGain: 2}); g != -4 {
+		t.Errorf("track gain: got %v, want -4", g)
+	}
+	if g, _ := ComputeReplayGain(info, ReplayGainOptions{Mode: ReplayGainAlbum}); g != -8 {
+		t.Errorf("album gain: got %v, want -8", g)
+	}
+
+	// peak of 0.5 allows at most ~6.02 dB of gain
+	g, _ := ComputeReplayGain(info, ReplayGainOptions{Mode: ReplayGainTrack, PreampGain: 15, PreventClipping: true})
+	if math.Abs(g-6.0206) > 0.001 {
+		t.Errorf("clipping prevention: got %v, want 6.02", g)
+	}
+
+	// album mode falls back to track gain when album info is missing
+	trackOnly := &mediaprovider.ReplayGainInfo{TrackGain: -3}
+	if g, _ := ComputeReplayGain(trackOnly, ReplayGainOptions{Mode: ReplayGainAlbum}); g != -3 {
+		t.Errorf("album fallback: got %v, want -3", g)
+	}
+}
@@ -0,0 +1,111 @@
+package backend
+
+import (
+	"context"
+	"image"
+	"log"
+	"os"
+
+	"golang.org/x/image/draw"
+)
+
+// SetPlaylistCoverAlbum sets the album whose cover is shown for
+// the playlist on the connected server. "" resets it to the playlist's own cover.
+func (s *ServerManager) SetPlaylistCoverAlbum(playlistID, albumID string) {
+	conf := s.CurrentServerConfig()
+	if conf == nil {
+		return
+	}
+	if albumID == "" {
+		delete(conf.PlaylistCoverAlbums, playlistID)
+		return
+	}
+	if conf.PlaylistCoverAlbums == nil {
+		conf.PlaylistCoverAlbums = make(map[string]string)
+	}
+	conf.PlaylistCoverAlbums[playlistID] = albumID
+}
+
+// PlaylistCoverAlbum returns the album whose cover is shown
+// for the playlist on the connected server, if one was chosen.
+func (s *ServerManager) PlaylistCoverAlbum(playlistID string) string {
+	if conf := s.CurrentServerConfig(); conf != nil {
+		return conf.PlaylistCoverAlbums[playlistID]
+	}
+	return ""
+}
+
+// InvalidatePlaylistCover clears the cached cover images of the playlist,
+// so that a newly set cover is shown.
+func (i *ImageManager) InvalidatePlaylistCover(playlistID, coverArtID string) {
+	i.thumbnailCache.Delete(playlistMosaicKey(playlistID))
+	for _, id := range []string{playlistID, coverArtID} {
+		if id == "" {
+			continue
+		}
+		i.thumbnailCache.Delete(id)
+		i.missingCovers.Delete(id)
+		if i.ensureCoverCacheDir() != "" {
+			os.Remove(i.filePathForCover(id))
+		}
+	}
+}
+
+func playlistMosaicKey(playlistID string) string {
+	return "mosaic-" + playlistID
+}
+
+// playlistMosaic composites a 2x2 mosaic from the covers of the first four
+// albums in the playlist, or returns the cover of the only album if the
+// playlist has fewer than four. Returns nil if there are no album covers.
+func (i *ImageManager) playlistMosaic(ctx context.Context, playlistID string, getCover func(coverID string) image.Image) image.Image {
+	key := playlistMosaicKey(playlistID)
+	if img, err := i.thumbnailCache.GetExtendTTL(key, i.thumbnailCache.DefaultTTL); err == nil && img != nil {
+		return img
+	}
+	server := i.s.Server
+	if server == nil {
+		return nil
+	}
+	pl, err := server.GetPlaylist(playlistID)
+	if err != nil {
+		log.Printf("error fetching playlist for cover mosaic: %v", err)
+		return nil
+	}
+
+	var covers []image.Image
+	seen := make(map[string]bool)
+	for _, tr := range pl.Tracks {
+		if len(covers) == 4 || ctx.Err() != nil {
+			break
+		}
+		albumKey := tr.AlbumID
+		if albumKey == "" {
+			albumKey = tr.CoverArtID
+		}
+		if seen[albumKey] {
+			continue
+		}
+		seen[albumKey] = true
+		if img := getCover(tr.CoverArtID); img != nil {
+			covers = append(covers, img)
+		}
+	}
+	var img image.Image
+	switch {
+	case len(covers) == 0:
+		return nil
+	case len(covers) < 4:
+		img = covers[0]
+	default:
+		const half = coverArtThumbnailSize / 2
+		mosaic := image.NewRGBA(image.Rect(0, 0, coverArtThumbnailSize, coverArtThumbnailSize))
+		for n, cover := range covers {
+			x, y := (n%2)*half, (n/2)*half
+			draw.CatmullRom.Scale(mosaic, image.Rect(x, y, x+half, y+half), cover, cover.Bounds(), draw.Src, nil)
+		}
+		img = mosaic
+	}
+	i.thumbnailCache.Set(key, img)
+	return img
+}
@@ -0,0 +1,63 @@
+package backend
+
+import (
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// max difference in seconds for tracks to be considered fuzzy duplicates
+const duplicateDurationTolerance = 2
+
+// FindDuplicateTracks returns the indexes of tracks which duplicate an
+// earlier track in the list. Tracks are duplicates if they have the same ID,
+// or if fuzzy is true, the same artist and title (ignoring case, punctuation
+// and bracketed suffixes) and nearly the same duration, such as the same
+// song from an album and a compilation.
+func FindDuplicateTracks(tracks []*mediaprovider.Track, fuzzy bool) []int {
+	seenIDs := make(map[string]bool, len(tracks))
+	seenKeys := make(map[string][]*mediaprovider.Track)
+	var dupIdxs []int
+	for i, tr := range tracks {
+		if seenIDs[tr.ID] {
+			dupIdxs = append(dupIdxs, i)
+			continue
+		}
+		seenIDs[tr.ID] = true
+		if !fuzzy {
+			continue
+		}
+		key := normalizeForMatch(strings.Join(tr.ArtistNames, " ")) + "\x00" + normalizeForMatch(tr.Title)
+		isDup := false
+		for _, other := range seenKeys[key] {
+			if d := tr.Duration - other.Duration; d >= -duplicateDurationTolerance && d <= duplicateDurationTolerance {
+				isDup = true
+				break
+			}
+		}
+		if isDup {
+			dupIdxs = append(dupIdxs, i)
+		} else {
+			seenKeys[key] = append(seenKeys[key], tr)
+		}
+	}
+	return dupIdxs
+}
+
+// RemovePlaylistDuplicates removes all duplicate tracks from the playlist
+// in one request, keeping the first occurrence of each.
+// Returns the number of tracks removed.
+func RemovePlaylistDuplicates(server mediaprovider.MediaProvider, playlistID string, fuzzy bool) (int, error) {
+	pl, err := server.GetPlaylist(playlistID)
+	if err != nil {
+		return 0, err
+	}
+	dupIdxs := FindDuplicateTracks(pl.Tracks, fuzzy)
+	if len(dupIdxs) == 0 {
+		return 0, nil
+	}
+	if err := server.RemovePlaylistTracks(playlistID, dupIdxs); err != nil {
+		return 0, err
+	}
+	return len(dupIdxs), nil
+}
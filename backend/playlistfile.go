@@ -0,0 +1,340 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+type PlaylistFileFormat int
+
+const (
+	PlaylistFileM3U PlaylistFileFormat = iota // extended M3U, UTF-8 (.m3u8)
+	PlaylistFileXSPF
+)
+
+// PlaylistFileFormatForFile returns the playlist format for the given file name,
+// based on its extension. Unknown extensions are treated as M3U.
+func PlaylistFileFormatForFile(name string) PlaylistFileFormat {
+	if strings.EqualFold(filepath.Ext(name), ".xspf") {
+		return PlaylistFileXSPF
+	}
+	return PlaylistFileM3U
+}
+
+type PlaylistEntryLocation int
+
+const (
+	// Server file paths; useful for players with access to the same music files
+	PlaylistLocationFilePath PlaylistEntryLocation = iota
+	// Stream URLs; playable anywhere the server is reachable, but contain credentials
+	PlaylistLocationStreamURL
+)
+
+// PlaylistFileEntry is one entry of an imported playlist file.
+type PlaylistFileEntry struct {
+	Location string
+	Title    string
+	Artist   string
+	Album    string
+	Duration int // seconds, 0 if unknown
+}
+
+// ExportPlaylistFile writes the tracks as a playlist file with the given name.
+// For PlaylistLocationStreamURL, server is used to get each track's stream URL.
+func ExportPlaylistFile(w io.Writer, name string, tracks []*mediaprovider.Track, format PlaylistFileFormat, location PlaylistEntryLocation, server mediaprovider.MediaProvider) error {
+	locations := make([]string, len(tracks))
+	for i, tr := range tracks {
+		locations[i] = tr.FilePath
+		if location == PlaylistLocationStreamURL {
+			u, err := server.GetStreamURL(tr.ID, false)
+			if err != nil {
+				return err
+			}
+			locations[i] = u
+		}
+	}
+	if format == PlaylistFileXSPF {
+		return exportXSPF(w, name, tracks, locations)
+	}
+	return exportM3U(w, name, tracks, locations)
+}
+
+func exportM3U(w io.Writer, name string, tracks []*mediaprovider.Track, locations []string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "#EXTM3U\n#PLAYLIST:%s\n", name)
+	for i, tr := range tracks {
+		fmt.Fprintf(bw, "#EXTINF:%d,%s - %s\n%s\n",
+			tr.Duration, strings.Join(tr.ArtistNames, ", "), tr.Title, locations[i])
+	}
+	return bw.Flush()
+}
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"http://xspf.org/ns/0/ playlist"`
+	Version string      `xml:"version,attr"`
+	Title   string      `xml:"title,omitempty"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location,omitempty"`
+	Title    string `xml:"title,omitempty"`
+	Creator  string `xml:"creator,omitempty"`
+	Album    string `xml:"album,omitempty"`
+	Duration int    `xml:"duration,omitempty"` // milliseconds
+}
+
+func exportXSPF(w io.Writer, name string, tracks []*mediaprovider.Track, locations []string) error {
+	pl := xspfPlaylist{Version: "1", Title: name}
+	for i, tr := range tracks {
+		loc := locations[i]
+		if !strings.Contains(loc, "://") {
+			// XSPF locations are URIs; relative paths become relative references
+			u := &url.URL{Path: slashPath(loc)}
+			if path.IsAbs(u.Path) {
+				u.Scheme = "file"
+			}
+			loc = u.String()
+		}
+		pl.Tracks = append(pl.Tracks, xspfTrack{
+			Location: loc,
+			Title:    tr.Title,
+			Creator:  strings.Join(tr.ArtistNames, ", "),
+			Album:    tr.Album,
+			Duration: tr.Duration * 1000,
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(pl); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ParsePlaylistFile reads the entries of an M3U/M3U8 or XSPF playlist file.
+func ParsePlaylistFile(r io.Reader, format PlaylistFileFormat) ([]PlaylistFileEntry, error) {
+	if format == PlaylistFileXSPF {
+		var pl xspfPlaylist
+		if err := xml.NewDecoder(r).Decode(&pl); err != nil {
+			return nil, err
+		}
+		entries := make([]PlaylistFileEntry, 0, len(pl.Tracks))
+		for _, t := range pl.Tracks {
+			e := PlaylistFileEntry{
+				Location: t.Location,
+				Title:    t.Title,
+				Artist:   t.Creator,
+				Album:    t.Album,
+				Duration: t.Duration / 1000,
+			}
+			if u, err := url.Parse(t.Location); err == nil && (u.Scheme == "file" || u.Scheme == "") {
+				e.Location = u.Path
+			}
+			if e.Title == "" {
+				e.Artist, e.Title = entryInfoFromLocation(e.Location)
+			}
+			entries = append(entries, e)
+		}
+		return entries, nil
+	}
+
+	var entries []PlaylistFileEntry
+	var pending PlaylistFileEntry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// #EXTINF:<duration>[ attributes],<artist> - <title>
+			info, display, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			if f := strings.Fields(info); len(f) > 0 {
+				pending.Duration, _ = strconv.Atoi(f[0])
+			}
+			if artist, title, ok := strings.Cut(display, " - "); ok {
+				pending.Artist, pending.Title = artist, title
+			} else {
+				pending.Title = display
+			}
+		case strings.HasPrefix(line, "#EXTALB:"):
+			pending.Album = strings.TrimPrefix(line, "#EXTALB:")
+		case strings.HasPrefix(line, "#"):
+			continue // other directives and comments
+		default:
+			pending.Location = line
+			if pending.Title == "" {
+				pending.Artist, pending.Title = entryInfoFromLocation(line)
+			}
+			entries = append(entries, pending)
+			pending = PlaylistFileEntry{}
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// entryInfoFromLocation guesses artist and title from a file name
+// like "01 - Artist - Title.mp3" or "Title.flac".
+func entryInfoFromLocation(location string) (artist, title string) {
+	if u, err := url.Parse(location); err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		location = u.Path
+	}
+	name := path.Base(slashPath(location))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	parts := strings.Split(name, " - ")
+	if len(parts) > 1 && strings.IndexFunc(parts[0], func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+		parts = parts[1:] // leading track number
+	}
+	if len(parts) > 1 {
+		return parts[len(parts)-2], parts[len(parts)-1]
+	}
+	return "", strings.TrimLeft(parts[0], "0123456789. -")
+}
+
+// slashPath converts both Windows and Unix separators to forward slashes,
+// since playlist files are often made on a different OS than they're read on.
+func slashPath(p string) string {
+	return strings.ReplaceAll(p, `\`, "/")
+}
+
+// PlaylistImportResult is the result of matching playlist file entries to the library.
+type PlaylistImportResult struct {
+	Tracks    []*mediaprovider.Track
+	Unmatched []PlaylistFileEntry
+}
+
+const (
+	playlistMatchSearchResults = 10
+	playlistMatchMinScore      = 0.6
+)
+
+// MatchPlaylistEntries finds the library track for each entry by searching
+// the server, tolerating small differences in title and artist
+// (case, punctuation, "(Remastered)" style suffixes).
+func MatchPlaylistEntries(server mediaprovider.MediaProvider, entries []PlaylistFileEntry) PlaylistImportResult {
+	var result PlaylistImportResult
+	for _, e := range entries {
+		if tr := matchPlaylistEntry(server, e); tr != nil {
+			result.Tracks = append(result.Tracks, tr)
+		} else {
+			result.Unmatched = append(result.Unmatched, e)
+		}
+	}
+	return result
+}
+
+func matchPlaylistEntry(server mediaprovider.MediaProvider, e PlaylistFileEntry) *mediaprovider.Track {
+	title := normalizeForMatch(e.Title)
+	if title == "" {
+		return nil
+	}
+	// try the more specific query first
+	queries := []string{title}
+	if artist := normalizeForMatch(e.Artist); artist != "" {
+		queries = []string{artist + " " + title, title}
+	}
+	for _, q := range queries {
+		var best *mediaprovider.Track
+		bestScore := playlistMatchMinScore
+		iter := server.IterateTracks(q)
+		for i := 0; i < playlistMatchSearchResults; i++ {
+			tr := iter.Next()
+			if tr == nil {
+				break
+			}
+			if e.Location != "" && tr.FilePath != "" && strings.HasSuffix(slashPath(e.Location), slashPath(tr.FilePath)) {
+				return tr
+			}
+			if s := playlistMatchScore(e, tr); s > bestScore {
+				best, bestScore = tr, s
+			}
+		}
+		if best != nil {
+			return best
+		}
+	}
+	return nil
+}
+
+func playlistMatchScore(e PlaylistFileEntry, tr *mediaprovider.Track) float64 {
+	titleSim := tokenSimilarity(normalizeForMatch(e.Title), normalizeForMatch(tr.Title))
+	if titleSim < 0.5 {
+		return 0
+	}
+	artistSim := 0.5 // unknown
+	if e.Artist != "" {
+		artistSim = tokenSimilarity(normalizeForMatch(e.Artist), normalizeForMatch(strings.Join(tr.ArtistNames, " ")))
+	}
+	durationSim := 0.5
+	if e.Duration > 0 && tr.Duration > 0 {
+		durationSim = 0
+		if d := e.Duration - tr.Duration; d >= -3 && d <= 3 {
+			durationSim = 1
+		}
+	}
+	return 0.6*titleSim + 0.3*artistSim + 0.1*durationSim
+}
+
+// normalizeForMatch lowercases s and removes bracketed parts and punctuation.
+func normalizeForMatch(s string) string {
+	var sb strings.Builder
+	depth := 0
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r == '(' || r == '[':
+			depth++
+		case r == ')' || r == ']':
+			if depth > 0 {
+				depth--
+			}
+		case depth > 0:
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// tokenSimilarity returns the Jaccard similarity of the words in a and b.
+func tokenSimilarity(a, b string) float64 {
+	aw, bw := strings.Fields(a), strings.Fields(b)
+	if len(aw) == 0 || len(bw) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(aw))
+	for _, w := range aw {
+		set[w] = true
+	}
+	var common int
+	union := len(set)
+	seen := make(map[string]bool, len(bw))
+	for _, w := range bw {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		if set[w] {
+			common++
+		} else {
+			union++
+		}
+	}
+	return float64(common) / float64(union)
+}
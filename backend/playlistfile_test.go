@@ -0,0 +1,259 @@
+package backend
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// searchProvider returns its tracks, in order, for any track search.
+type searchProvider struct {
+	mediaprovider.MediaProvider
+	tracks []*mediaprovider.Track
+}
+
+func (p *searchProvider) IterateTracks(string) mediaprovider.TrackIterator {
+	return &sliceTrackIterator{tracks: p.tracks}
+}
+
+func (p *searchProvider) GetStreamURL(trackID string, _ bool) (string, error) {
+	return "https://music.example/stream?id=" + trackID, nil
+}
+
+type sliceTrackIterator struct {
+	tracks []*mediaprovider.Track
+}
+
+func (s *sliceTrackIterator) Next() *mediaprovider.Track {
+	if len(s.tracks) == 0 {
+		return nil
+	}
+	tr := s.tracks[0]
+	s.tracks = s.tracks[1:]
+	return tr
+}
+
+var playlistFileTracks = []*mediaprovider.Track{
+	{ID: "1", Title: "Song One", ArtistNames: []string{"Artist A"}, Album: "Album", Duration: 200, FilePath: "Artist A/Album/01 Song One.flac"},
+	{ID: "2", Title: "Duet", ArtistNames: []string{"Artist A", "Artist B"}, Album: "Album", Duration: 185, FilePath: "Artist A/Album/02 Duet.flac"},
+}
+
+func TestPlaylistFileRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		format   PlaylistFileFormat
+		location PlaylistEntryLocation
+		want     []PlaylistFileEntry
+	}{
+		{
+			name:     "M3U file paths",
+			format:   PlaylistFileM3U,
+			location: PlaylistLocationFilePath,
+			want: []PlaylistFileEntry{
+				{Location: "Artist A/Album/01 Song One.flac", Title: "Song One", Artist: "Artist A", Duration: 200},
+				{Location: "Artist A/Album/02 Duet.flac", Title: "Duet", Artist: "Artist A, Artist B", Duration: 185},
+			},
+		},
+		{
+			name:     "M3U stream URLs",
+			format:   PlaylistFileM3U,
+			location: PlaylistLocationStreamURL,
+			want: []PlaylistFileEntry{
+				{Location: "https://music.example/stream?id=1", Title: "Song One", Artist: "Artist A", Duration: 200},
+				{Location: "https://music.example/stream?id=2", Title: "Duet", Artist: "Artist A, Artist B", Duration: 185},
+			},
+		},
+		{
+			name:     "XSPF file paths",
+			format:   PlaylistFileXSPF,
+			location: PlaylistLocationFilePath,
+			want: []PlaylistFileEntry{
+				{Location: "Artist A/Album/01 Song One.flac", Title: "Song One", Artist: "Artist A", Album: "Album", Duration: 200},
+				{Location: "Artist A/Album/02 Duet.flac", Title: "Duet", Artist: "Artist A, Artist B", Album: "Album", Duration: 185},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			sp := &searchProvider{}
+			if err := ExportPlaylistFile(&buf, "Mix", playlistFileTracks, tt.format, tt.location, sp); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParsePlaylistFile(&buf, tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseM3U(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		file string
+		want []PlaylistFileEntry
+	}{
+		{
+			name: "extended with BOM and CRLF",
+			file: "\ufeff#EXTM3U\r\n#EXTINF:123,Artist - Title\r\n/music/a.mp3\r\n",
+			want: []PlaylistFileEntry{{Location: "/music/a.mp3", Title: "Title", Artist: "Artist", Duration: 123}},
+		},
+		{
+			name: "EXTINF attributes and album",
+			file: "#EXTM3U\n#EXTINF:-1 tvg-id=\"x\",No Artist\n#EXTALB:Album\n\n# comment\nhttp://host/x.ogg\n",
+			want: []PlaylistFileEntry{{Location: "http://host/x.ogg", Title: "No Artist", Album: "Album", Duration: -1}},
+		},
+		{
+			name: "plain M3U",
+			file: "01 - Artist - Title.mp3\nC:\\Music\\05. Other.flac\nfile:///music/Some%20Song.ogg\n",
+			want: []PlaylistFileEntry{
+				{Location: "01 - Artist - Title.mp3", Title: "Title", Artist: "Artist"},
+				{Location: "C:\\Music\\05. Other.flac", Title: "Other"},
+				{Location: "file:///music/Some%20Song.ogg", Title: "Some Song"},
+			},
+		},
+		{
+			name: "EXTINF without location",
+			file: "#EXTM3U\n#EXTINF:10,Artist - Title\n",
+			want: nil,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePlaylistFile(strings.NewReader(tt.file), PlaylistFileM3U)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportXSPFLocations(t *testing.T) {
+	for _, tt := range []struct {
+		filePath, want string
+	}{
+		{"Artist/01 Song #1.flac", "<location>Artist/01%20Song%20%231.flac</location>"},
+		{"/music/Song.flac", "<location>file:///music/Song.flac</location>"},
+		{`Artist\Song.flac`, "<location>Artist/Song.flac</location>"},
+	} {
+		var buf bytes.Buffer
+		tracks := []*mediaprovider.Track{{ID: "1", Title: "Song", FilePath: tt.filePath}}
+		if err := ExportPlaylistFile(&buf, "Mix", tracks, PlaylistFileXSPF, PlaylistLocationFilePath, nil); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("exporting %q: %s does not contain %s", tt.filePath, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestParseXSPF(t *testing.T) {
+	file := `<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+  <trackList>
+    <track><location>file:///C:/Music/Artist%20-%20Song.mp3</location></track>
+    <track><location>https://host/stream?id=1&amp;f=mp3</location><title>Radio</title><duration>61500</duration></track>
+  </trackList>
+</playlist>`
+	want := []PlaylistFileEntry{
+		{Location: "/C:/Music/Artist - Song.mp3", Title: "Song", Artist: "Artist"},
+		{Location: "https://host/stream?id=1&f=mp3", Title: "Radio", Duration: 61},
+	}
+	got, err := ParsePlaylistFile(strings.NewReader(file), PlaylistFileXSPF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseXSPFMalformed(t *testing.T) {
+	for _, file := range []string{"", "<playlist", "<playlist xmlns=\"http://example.com/\"></playlist>"} {
+		if _, err := ParsePlaylistFile(strings.NewReader(file), PlaylistFileXSPF); err == nil {
+			t.Errorf("ParsePlaylistFile(%q): expected an error", file)
+		}
+	}
+}
+
+func TestNormalizeForMatch(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"Song One", "song one"},
+		{"Song One (Remastered 2011)", "song one"},
+		{"Song [Live] One", "song one"},
+		{"Don't Stop—Believin'!", "don t stop believin"},
+		{"Unclosed (paren", "unclosed"},
+		{"Stray) bracket", "stray bracket"},
+		{"Beyoncé", "beyoncé"},
+		{"", ""},
+	} {
+		if got := normalizeForMatch(tt.in); got != tt.want {
+			t.Errorf("normalizeForMatch(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTokenSimilarity(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want float64
+	}{
+		{"song one", "song one", 1},
+		{"song one", "one song", 1},
+		{"song one", "song two", 1.0 / 3},
+		{"song song one", "song one", 1},
+		{"song", "", 0},
+		{"", "", 0},
+	} {
+		if got := tokenSimilarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("tokenSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMatchPlaylistEntries(t *testing.T) {
+	library := []*mediaprovider.Track{
+		{ID: "live", Title: "Song One (Live)", ArtistNames: []string{"Artist A"}, Duration: 260, FilePath: "live/Song One.flac"},
+		{ID: "studio", Title: "Song One", ArtistNames: []string{"Artist A"}, Duration: 200, FilePath: "studio/Song One.flac"},
+		{ID: "cover", Title: "Song One", ArtistNames: []string{"Someone Else"}, Duration: 201},
+		{ID: "other", Title: "Different Song", ArtistNames: []string{"Artist A"}, Duration: 180},
+	}
+	for _, tt := range []struct {
+		name  string
+		entry PlaylistFileEntry
+		want  string // track ID, "" if unmatched
+	}{
+		{"file path suffix", PlaylistFileEntry{Location: "/mnt/music/live/Song One.flac", Title: "x"}, "live"},
+		{"Windows file path suffix", PlaylistFileEntry{Location: `D:\music\studio\Song One.flac`, Title: "Song One"}, "studio"},
+		{"first best score", PlaylistFileEntry{Title: "song one!", Artist: "ARTIST A"}, "live"},
+		{"duration", PlaylistFileEntry{Title: "Song One", Artist: "Artist A", Duration: 201}, "studio"},
+		{"artist", PlaylistFileEntry{Title: "Song One", Artist: "Someone Else"}, "cover"},
+		{"remaster suffix", PlaylistFileEntry{Title: "Different Song (2011 Remaster)", Artist: "Artist A"}, "other"},
+		{"no title", PlaylistFileEntry{Artist: "Artist A"}, ""},
+		{"no match", PlaylistFileEntry{Title: "Unknown Track", Artist: "Nobody"}, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			res := MatchPlaylistEntries(&searchProvider{tracks: library}, []PlaylistFileEntry{tt.entry})
+			var got string
+			if len(res.Tracks) > 0 {
+				got = res.Tracks[0].ID
+			}
+			if got != tt.want {
+				t.Errorf("matched %q, want %q", got, tt.want)
+			}
+			if tt.want == "" && len(res.Unmatched) != 1 {
+				t.Errorf("entry not reported as unmatched")
+			}
+		})
+	}
+}
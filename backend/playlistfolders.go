@@ -0,0 +1,370 @@
+package backend
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/google/uuid"
+)
+
+// PlaylistFolder is a client-side folder for organizing a server's playlists.
+type PlaylistFolder struct {
+	ID       string
+	ServerID string
+	ParentID string // "" for a top-level folder
+	Name     string
+	Position int // custom order within the parent, 0 if unordered
+}
+
+// PlaylistPlacement is the client-side organization of one server playlist.
+type PlaylistPlacement struct {
+	ServerID   string
+	PlaylistID string
+	FolderID   string // "" for the top level
+	Pinned     bool
+	Position   int // custom order within the folder, 0 if unordered
+}
+
+// PlaylistTreeNode is a folder or a playlist in the playlist tree.
+// Exactly one of Folder and Playlist is set.
+type PlaylistTreeNode struct {
+	Folder   *PlaylistFolder
+	Playlist *mediaprovider.Playlist
+	Pinned   bool
+	Children []*PlaylistTreeNode // for folders
+}
+
+// Name returns the name of the folder or playlist.
+func (n *PlaylistTreeNode) Name() string {
+	if n.Folder != nil {
+		return n.Folder.Name
+	}
+	return n.Playlist.Name
+}
+
+// ID returns the ID of the folder or playlist.
+func (n *PlaylistTreeNode) ID() string {
+	if n.Folder != nil {
+		return n.Folder.ID
+	}
+	return n.Playlist.ID
+}
+
+// PlaylistOrganizer organizes the current server's playlists into folders,
+// with pinning and custom ordering. The organization is persisted in the
+// config, keyed by server and playlist ID, and doesn't change the playlists
+// on the server.
+type PlaylistOrganizer struct {
+	sm     *ServerManager
+	config *Config
+
+	mu sync.Mutex // guards config.PlaylistFolders and config.PlaylistPlacements
+}
+
+func NewPlaylistOrganizer(sm *ServerManager, config *Config) *PlaylistOrganizer {
+	return &PlaylistOrganizer{sm: sm, config: config}
+}
+
+// Folders returns the current server's playlist folders.
+func (o *PlaylistOrganizer) Folders() []*PlaylistFolder {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.folders()
+}
+
+// CreateFolder creates a folder in the parent folder, or at the top level if parentID is "".
+func (o *PlaylistOrganizer) CreateFolder(name, parentID string) *PlaylistFolder {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	f := &PlaylistFolder{ID: uuid.NewString(), ServerID: o.serverID(), ParentID: parentID, Name: name}
+	o.config.PlaylistFolders = append(o.config.PlaylistFolders, f)
+	return f
+}
+
+func (o *PlaylistOrganizer) RenameFolder(folderID, name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if f := o.folder(folderID); f != nil {
+		f.Name = name
+	}
+}
+
+// DeleteFolder deletes the folder, moving its contents to its parent.
+func (o *PlaylistOrganizer) DeleteFolder(folderID string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	f := o.folder(folderID)
+	if f == nil {
+		return
+	}
+	for _, child := range o.folders() {
+		if child.ParentID == folderID {
+			child.ParentID = f.ParentID
+		}
+	}
+	for _, p := range o.placements() {
+		if p.FolderID == folderID {
+			p.FolderID = f.ParentID
+		}
+	}
+	o.config.PlaylistFolders = slices.DeleteFunc(o.config.PlaylistFolders, func(x *PlaylistFolder) bool {
+		return x == f
+	})
+}
+
+// MoveFolder moves the folder into the parent folder, or to the top level if parentID is "".
+func (o *PlaylistOrganizer) MoveFolder(folderID, parentID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	f := o.folder(folderID)
+	if f == nil {
+		return errors.New("folder not found")
+	}
+	for id := parentID; id != ""; {
+		if id == folderID {
+			return errors.New("can't move a folder into itself")
+		}
+		p := o.folder(id)
+		if p == nil {
+			return errors.New("folder not found")
+		}
+		id = p.ParentID
+	}
+	f.ParentID = parentID
+	f.Position = 0
+	return nil
+}
+
+// MovePlaylist moves the playlist into the folder, or to the top level if folderID is "".
+func (o *PlaylistOrganizer) MovePlaylist(playlistID, folderID string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	p := o.placement(playlistID, true)
+	p.FolderID = folderID
+	p.Position = 0
+	o.prune()
+}
+
+// SetPinned sets whether the playlist is pinned. Pinned playlists are
+// listed first at the top level of the tree, as well as in their folder.
+func (o *PlaylistOrganizer) SetPinned(playlistID string, pinned bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.placement(playlistID, true).Pinned = pinned
+	o.prune()
+}
+
+func (o *PlaylistOrganizer) IsPinned(playlistID string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	p := o.placement(playlistID, false)
+	return p != nil && p.Pinned
+}
+
+// FolderOf returns the ID of the playlist's folder, "" if at the top level.
+func (o *PlaylistOrganizer) FolderOf(playlistID string) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if p := o.placement(playlistID, false); p != nil {
+		return p.FolderID
+	}
+	return ""
+}
+
+// SetOrder sets the custom order of the folders and playlists in
+// the folder (or the top level if folderID is ""), by their IDs.
+// Items not included are listed after, by name.
+func (o *PlaylistOrganizer) SetOrder(folderID string, ids []string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, f := range o.folders() {
+		if f.ParentID == folderID {
+			f.Position = slices.Index(ids, f.ID) + 1
+		}
+	}
+	for _, p := range o.placements() {
+		if p.FolderID == folderID {
+			p.Position = 0
+		}
+	}
+	for i, id := range ids {
+		if o.folder(id) == nil {
+			p := o.placement(id, true)
+			p.FolderID = folderID
+			p.Position = i + 1
+		}
+	}
+	o.prune()
+}
+
+// Tree returns the playlists organized into the folder tree.
+// Each level lists pinned playlists first (top level only), then
+// the items in custom order, then the unordered items by name,
+// folders before playlists.
+func (o *PlaylistOrganizer) Tree(playlists []*mediaprovider.Playlist) []*PlaylistTreeNode {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	folders := o.folders()
+	nodes := make(map[string]*PlaylistTreeNode, len(folders))
+	for _, f := range folders {
+		nodes[f.ID] = &PlaylistTreeNode{Folder: f}
+	}
+	var root, pinned []*PlaylistTreeNode
+	addTo := func(parentID string, n *PlaylistTreeNode) {
+		if parent, ok := nodes[parentID]; ok {
+			parent.Children = append(parent.Children, n)
+		} else {
+			root = append(root, n)
+		}
+	}
+	for _, f := range folders {
+		addTo(f.ParentID, nodes[f.ID])
+	}
+	positions := make(map[*PlaylistTreeNode]int)
+	for _, f := range folders {
+		positions[nodes[f.ID]] = f.Position
+	}
+	for _, pl := range playlists {
+		n := &PlaylistTreeNode{Playlist: pl}
+		p := o.placement(pl.ID, false)
+		if p == nil {
+			root = append(root, n)
+			continue
+		}
+		positions[n] = p.Position
+		if p.Pinned {
+			n.Pinned = true
+			pinned = append(pinned, n)
+			if _, ok := nodes[p.FolderID]; !ok {
+				continue // else also listed in its folder
+			}
+		}
+		addTo(p.FolderID, n)
+	}
+
+	var sortLevel func([]*PlaylistTreeNode)
+	sortLevel = func(level []*PlaylistTreeNode) {
+		sort.SliceStable(level, func(i, j int) bool {
+			a, b := level[i], level[j]
+			pa, pb := positions[a], positions[b]
+			switch {
+			case pa > 0 && pb > 0:
+				return pa < pb
+			case pa > 0 || pb > 0:
+				return pa > 0
+			case (a.Folder != nil) != (b.Folder != nil):
+				return a.Folder != nil
+			}
+			return strings.ToLower(a.Name()) < strings.ToLower(b.Name())
+		})
+		for _, n := range level {
+			sortLevel(n.Children)
+		}
+	}
+	sortLevel(pinned)
+	sortLevel(root)
+	return append(pinned, root...)
+}
+
+// FolderPath returns the folder's name, prefixed by the names of its parents.
+func (o *PlaylistOrganizer) FolderPath(folderID string) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var names []string
+	for f := o.folder(folderID); f != nil && len(names) <= len(o.config.PlaylistFolders); f = o.folder(f.ParentID) {
+		names = append(names, f.Name)
+	}
+	slices.Reverse(names)
+	return strings.Join(names, " / ")
+}
+
+// FlattenPlaylistTree returns the playlists in the tree in depth-first order.
+// Playlists in the folder folderID and its subfolders are returned,
+// or all of them if folderID is "".
+func FlattenPlaylistTree(tree []*PlaylistTreeNode, folderID string) []*mediaprovider.Playlist {
+	var playlists []*mediaprovider.Playlist
+	seen := make(map[string]bool) // pinned playlists are in the tree twice
+	var walk func([]*PlaylistTreeNode, bool)
+	walk = func(level []*PlaylistTreeNode, include bool) {
+		for _, n := range level {
+			if n.Playlist != nil {
+				if include && !seen[n.Playlist.ID] {
+					seen[n.Playlist.ID] = true
+					playlists = append(playlists, n.Playlist)
+				}
+				continue
+			}
+			walk(n.Children, include || n.Folder.ID == folderID)
+		}
+	}
+	walk(tree, folderID == "")
+	return playlists
+}
+
+func (o *PlaylistOrganizer) serverID() string {
+	return o.sm.DataKey()
+}
+
+// must be called with o.mu held
+func (o *PlaylistOrganizer) folders() []*PlaylistFolder {
+	serverID := o.serverID()
+	var folders []*PlaylistFolder
+	for _, f := range o.config.PlaylistFolders {
+		if f.ServerID == serverID {
+			folders = append(folders, f)
+		}
+	}
+	return folders
+}
+
+// must be called with o.mu held
+func (o *PlaylistOrganizer) folder(id string) *PlaylistFolder {
+	for _, f := range o.folders() {
+		if f.ID == id {
+			return f
+		}
+	}
+	return nil
+}
+
+// must be called with o.mu held
+func (o *PlaylistOrganizer) placements() []*PlaylistPlacement {
+	serverID := o.serverID()
+	var placements []*PlaylistPlacement
+	for _, p := range o.config.PlaylistPlacements {
+		if p.ServerID == serverID {
+			placements = append(placements, p)
+		}
+	}
+	return placements
+}
+
+// placement returns the playlist's placement, adding one if create is true.
+// must be called with o.mu held
+func (o *PlaylistOrganizer) placement(playlistID string, create bool) *PlaylistPlacement {
+	serverID := o.serverID()
+	for _, p := range o.config.PlaylistPlacements {
+		if p.ServerID == serverID && p.PlaylistID == playlistID {
+			return p
+		}
+	}
+	if !create {
+		return nil
+	}
+	p := &PlaylistPlacement{ServerID: serverID, PlaylistID: playlistID}
+	o.config.PlaylistPlacements = append(o.config.PlaylistPlacements, p)
+	return p
+}
+
+// prune removes placements which are the same as the default.
+// must be called with o.mu held
+func (o *PlaylistOrganizer) prune() {
+	o.config.PlaylistPlacements = slices.DeleteFunc(o.config.PlaylistPlacements, func(p *PlaylistPlacement) bool {
+		return p.FolderID == "" && !p.Pinned && p.Position == 0
+	})
+}
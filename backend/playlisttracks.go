@@ -0,0 +1,80 @@
+package backend
+
+import (
+	"sync"
+
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+// PlaylistTracksCache caches the track IDs of playlists, so that tracks
+// being added can be checked for duplicates without refetching the playlist
+// each time. A playlist's snapshot is dropped when an EventPlaylistChanged
+// is published for it, whether changed by us or detected on the server.
+type PlaylistTracksCache struct {
+	sm *ServerManager
+
+	mu        sync.Mutex
+	snapshots map[string]map[string]struct{} // playlist ID -> set of track IDs
+	// incremented when snapshots are dropped, so that a
+	// snapshot fetched concurrently isn't stored
+	generation int
+}
+
+func NewPlaylistTracksCache(sm *ServerManager, bus *EventBus) *PlaylistTracksCache {
+	c := &PlaylistTracksCache{sm: sm, snapshots: make(map[string]map[string]struct{})}
+	bus.Subscribe(EventPlaylistChanged, func(e Event) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.generation++
+		if id, _ := e.Data.(string); id != "" {
+			delete(c.snapshots, id)
+		} else {
+			clear(c.snapshots)
+		}
+	})
+	sm.OnLogout(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.generation++
+		clear(c.snapshots)
+	})
+	return c
+}
+
+// Duplicates returns those of the tracks which are already in the playlist,
+// fetching the playlist only if it isn't cached.
+func (c *PlaylistTracksCache) Duplicates(playlistID string, trackIDs []string) ([]string, error) {
+	tracks, err := c.trackIDs(playlistID)
+	if err != nil {
+		return nil, err
+	}
+	return sharedutil.FilterSlice(trackIDs, func(id string) bool {
+		_, ok := tracks[id]
+		return ok
+	}), nil
+}
+
+func (c *PlaylistTracksCache) trackIDs(playlistID string) (map[string]struct{}, error) {
+	c.mu.Lock()
+	tracks, ok := c.snapshots[playlistID]
+	gen := c.generation
+	c.mu.Unlock()
+	if ok {
+		return tracks, nil
+	}
+
+	pl, err := c.sm.Server.GetPlaylist(playlistID)
+	if err != nil {
+		return nil, err
+	}
+	tracks = make(map[string]struct{}, len(pl.Tracks))
+	for _, tr := range pl.Tracks {
+		tracks[tr.ID] = struct{}{}
+	}
+	c.mu.Lock()
+	if c.generation == gen {
+		c.snapshots[playlistID] = tracks
+	}
+	c.mu.Unlock()
+	return tracks, nil
+}
@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+)
+
+const (
+	// how often the playback position is saved while playing
+	queueAutosaveInterval = 10 * time.Second
+	// delay to coalesce bursts of queue changes into one write
+	queueAutosaveDebounce = 1 * time.Second
+)
+
+// queueAutosaver writes the play queue to the local saved queue file
+// whenever it changes, and periodically while playing, so that the
+// queue survives a crash or an unclean shutdown.
+// It never saves to the server; that is done only on a clean exit.
+type queueAutosaver struct {
+	pm       *PlaybackManager
+	sm       *ServerManager
+	filePath string
+	enabled  func() bool
+
+	// set once the saved queue has been restored (or restoring was skipped),
+	// so that the empty startup queue doesn't overwrite the saved one
+	restored atomic.Bool
+
+	changeCh chan struct{}
+}
+
+func newQueueAutosaver(ctx context.Context, pm *PlaybackManager, sm *ServerManager, filePath string, enabled func() bool) *queueAutosaver {
+	q := &queueAutosaver{
+		pm:       pm,
+		sm:       sm,
+		filePath: filePath,
+		enabled:  enabled,
+		changeCh: make(chan struct{}, 1),
+	}
+	changed := func() { q.queueChanged() }
+	pm.OnQueueChange(changed)
+	pm.OnSongChange(func(mediaprovider.MediaItem, *mediaprovider.Track) { changed() })
+	pm.OnLoopModeChange(func(LoopMode) { changed() })
+	pm.OnPaused(changed)
+	pm.OnPlaying(changed)
+	pm.OnStopped(changed)
+	pm.OnSeek(changed)
+	go q.run(ctx)
+	return q
+}
+
+// SetRestored enables autosaving after the saved queue has been loaded.
+func (q *queueAutosaver) SetRestored() {
+	q.restored.Store(true)
+}
+
+func (q *queueAutosaver) queueChanged() {
+	select {
+	case q.changeCh <- struct{}{}:
+	default: // a save is already pending
+	}
+}
+
+func (q *queueAutosaver) run(ctx context.Context) {
+	tick := time.NewTicker(queueAutosaveInterval)
+	defer tick.Stop()
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.changeCh:
+			if debounce == nil {
+				debounce = time.After(queueAutosaveDebounce)
+			}
+		case <-debounce:
+			debounce = nil
+			q.save()
+		case <-tick.C:
+			if q.pm.PlayerStatus().State == player.Playing {
+				q.save()
+			}
+		}
+	}
+}
+
+func (q *queueAutosaver) save() {
+	if !q.enabled() || q.sm.Server == nil {
+		return
+	}
+	if !q.restored.Load() && len(q.pm.GetPlayQueue()) == 0 {
+		return
+	}
+	if err := SavePlayQueue(q.sm.DataKey(), q.pm, q.filePath, nil); err != nil {
+		log.Printf("error autosaving play queue: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+type QueueExportFormat int
+
+const (
+	// Comma-separated values, one row per track with a totals row
+	QueueExportCSV QueueExportFormat = iota
+	// Plain text setlist: "1. Artist – Title – 3:45"
+	QueueExportText
+	// Print-friendly HTML setlist, suitable for opening in a browser and printing
+	QueueExportHTML
+)
+
+// QueueExportFormatForFile returns the export format to use for the given file name,
+// based on its extension. Unknown extensions are exported as plain text.
+func QueueExportFormatForFile(name string) QueueExportFormat {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return QueueExportCSV
+	case ".html", ".htm":
+		return QueueExportHTML
+	default:
+		return QueueExportText
+	}
+}
+
+// ExportQueue writes the given play queue items as a setlist in the given format.
+func ExportQueue(w io.Writer, items []mediaprovider.MediaItem, format QueueExportFormat) error {
+	switch format {
+	case QueueExportCSV:
+		return exportQueueCSV(w, items)
+	case QueueExportHTML:
+		return exportQueueHTML(w, items)
+	default:
+		return exportQueueText(w, items)
+	}
+}
+
+func exportQueueCSV(w io.Writer, items []mediaprovider.MediaItem) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"#", "Artist", "Title", "Album", "Duration"})
+	total := 0
+	for i, item := range items {
+		meta := item.Metadata()
+		total += meta.Duration
+		_ = cw.Write([]string{
+			strconv.Itoa(i + 1),
+			strings.Join(meta.Artists, ", "),
+			meta.Name,
+			meta.Album,
+			formatSetlistDuration(meta.Duration),
+		})
+	}
+	_ = cw.Write([]string{"", "", fmt.Sprintf("%d tracks", len(items)), "", formatSetlistDuration(total)})
+	cw.Flush()
+	return cw.Error()
+}
+
+func exportQueueText(w io.Writer, items []mediaprovider.MediaItem) error {
+	total := 0
+	for i, item := range items {
+		total += item.Metadata().Duration
+		if _, err := fmt.Fprintf(w, "%d. %s\n", i+1, setlistLine(item)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%s\n", setlistTotals(len(items), total))
+	return err
+}
+
+func exportQueueHTML(w io.Writer, items []mediaprovider.MediaItem) error {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Setlist</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #000; background: #fff; }
+h1 { font-size: 1.6em; margin-bottom: 0.5em; }
+table { border-collapse: collapse; width: 100%; }
+td, th { text-align: left; padding: 0.3em 0.6em; border-bottom: 1px solid #ccc; }
+td.num, td.dur, th.dur { text-align: right; white-space: nowrap; }
+tfoot td { font-weight: bold; border-bottom: none; }
+@media print { body { margin: 0; } tr { page-break-inside: avoid; } }
+</style></head><body>
+<h1>Setlist</h1>
+<table><thead><tr><th></th><th>Artist</th><th>Title</th><th>Album</th><th class="dur">Duration</th></tr></thead><tbody>
+`)
+	total := 0
+	for i, item := range items {
+		meta := item.Metadata()
+		total += meta.Duration
+		fmt.Fprintf(&b, `<tr><td class="num">%d</td><td>%s</td><td>%s</td><td>%s</td><td class="dur">%s</td></tr>`+"\n",
+			i+1,
+			html.EscapeString(strings.Join(meta.Artists, ", ")),
+			html.EscapeString(meta.Name),
+			html.EscapeString(meta.Album),
+			formatSetlistDuration(meta.Duration))
+	}
+	fmt.Fprintf(&b, `</tbody><tfoot><tr><td></td><td colspan="3">%d tracks</td><td class="dur">%s</td></tr></tfoot></table>`+"\n",
+		len(items), formatSetlistDuration(total))
+	b.WriteString("</body></html>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func setlistLine(item mediaprovider.MediaItem) string {
+	meta := item.Metadata()
+	parts := make([]string, 0, 3)
+	if len(meta.Artists) > 0 {
+		parts = append(parts, strings.Join(meta.Artists, ", "))
+	}
+	parts = append(parts, meta.Name)
+	if meta.Duration > 0 {
+		parts = append(parts, formatSetlistDuration(meta.Duration))
+	}
+	return strings.Join(parts, " – ")
+}
+
+func setlistTotals(numTracks, totalSecs int) string {
+	tracks := "tracks"
+	if numTracks == 1 {
+		tracks = "track"
+	}
+	return fmt.Sprintf("%d %s, total %s", numTracks, tracks, formatSetlistDuration(totalSecs))
+}
+
+// formats seconds as M:SS, or H:MM:SS if an hour or longer
+func formatSetlistDuration(secs int) string {
+	if secs >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", secs/3600, (secs/60)%60, secs%60)
+	}
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
@@ -0,0 +1,38 @@
+package backend
+
+import "github.com/dweymouth/supersonic/backend/mediaprovider"
+
+// max number of play queue changes which can be undone
+const queueUndoLimit = 20
+
+// queueSnapshot is the play queue as it was before a change.
+type queueSnapshot struct {
+	items         []mediaprovider.MediaItem
+	nowPlayingIdx int
+}
+
+// queueUndoStack holds the snapshots of the last queueUndoLimit
+// play queue changes, most recent last.
+type queueUndoStack struct {
+	snapshots []queueSnapshot
+}
+
+func (s *queueUndoStack) push(snap queueSnapshot) {
+	if len(s.snapshots) == queueUndoLimit {
+		s.snapshots = s.snapshots[1:]
+	}
+	s.snapshots = append(s.snapshots, snap)
+}
+
+func (s *queueUndoStack) pop() (queueSnapshot, bool) {
+	if len(s.snapshots) == 0 {
+		return queueSnapshot{}, false
+	}
+	snap := s.snapshots[len(s.snapshots)-1]
+	s.snapshots = s.snapshots[:len(s.snapshots)-1]
+	return snap, true
+}
+
+func (s *queueUndoStack) clear() {
+	s.snapshots = nil
+}
@@ -0,0 +1,187 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+const radioSeedsFile = "radioseeds.json"
+
+// The kinds of items a radio mix can be seeded from.
+const (
+	RadioSeedArtist = "artist"
+	RadioSeedAlbum  = "album"
+	RadioSeedTrack  = "track"
+	RadioSeedGenre  = "genre"
+)
+
+// RadioMix records a radio mix that was played: the seed it was
+// generated from, and the tracks it contained.
+type RadioMix struct {
+	ServerID string    `json:"serverId"`
+	SeedKind string    `json:"seedKind"`
+	SeedID   string    `json:"seedId"`
+	Time     time.Time `json:"time"`
+	TrackIDs []string  `json:"trackIds"`
+}
+
+// RadioSeedCache remembers recently played radio mixes, so that new mixes
+// can be biased away from recently used seeds and recently heard tracks,
+// rather than producing nearly identical results each time.
+type RadioSeedCache struct {
+	filePath string
+	sm       *ServerManager
+	config   *RadioConfig
+
+	mu    sync.Mutex
+	mixes []RadioMix // oldest first
+}
+
+func NewRadioSeedCache(filePath string, sm *ServerManager, config *RadioConfig) *RadioSeedCache {
+	r := &RadioSeedCache{filePath: filePath, sm: sm, config: config}
+	if b, err := os.ReadFile(filePath); err == nil {
+		if err := json.Unmarshal(b, &r.mixes); err != nil {
+			log.Printf("error loading radio seeds: %v", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("error loading radio seeds: %v", err)
+	}
+	return r
+}
+
+// FetchMix fetches count tracks for a radio mix from the seed with fetch,
+// steering the mix away from tracks heard in recent mixes, and records it.
+// If the seed was recently used, more tracks are fetched to choose from.
+func (r *RadioSeedCache) FetchMix(seedKind, seedID string, count int, fetch func(count int) ([]*mediaprovider.Track, error)) ([]*mediaprovider.Track, error) {
+	if r == nil || r.config.RecentMixesToRemember <= 0 {
+		return fetch(count)
+	}
+	fetchCount := count
+	if r.WasRecentlyUsed(seedKind, seedID) {
+		fetchCount *= 2
+	}
+	tracks, err := fetch(fetchCount)
+	if err != nil {
+		return nil, err
+	}
+	tracks = r.Diversify(tracks, count)
+	r.Record(seedKind, seedID, tracks)
+	return tracks, nil
+}
+
+// Record remembers a radio mix generated from the given seed.
+func (r *RadioSeedCache) Record(seedKind, seedID string, tracks []*mediaprovider.Track) {
+	if r.config.RecentMixesToRemember <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mixes = append(r.mixes, RadioMix{
+		ServerID: r.sm.DataKey(),
+		SeedKind: seedKind,
+		SeedID:   seedID,
+		Time:     time.Now(),
+		TrackIDs: sharedutil.TracksToIDs(tracks),
+	})
+	r.prune()
+	if err := r.save(); err != nil {
+		log.Printf("error saving radio seeds: %v", err)
+	}
+}
+
+// WasRecentlyUsed returns whether a mix was recently generated from the seed.
+func (r *RadioSeedCache) WasRecentlyUsed(seedKind, seedID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.ContainsFunc(r.recentMixes(), func(m RadioMix) bool {
+		return m.SeedKind == seedKind && m.SeedID == seedID
+	})
+}
+
+// PreferFreshSeeds returns the candidate seed IDs reordered so that
+// seeds which have not recently been used for a mix come first.
+func (r *RadioSeedCache) PreferFreshSeeds(seedKind string, seedIDs []string) []string {
+	fresh := make([]string, 0, len(seedIDs))
+	var stale []string
+	for _, id := range seedIDs {
+		if r.WasRecentlyUsed(seedKind, id) {
+			stale = append(stale, id)
+		} else {
+			fresh = append(fresh, id)
+		}
+	}
+	return append(fresh, stale...)
+}
+
+// Diversify reorders the tracks of a new mix so that tracks which were
+// part of recent mixes come last, and truncates the result to limit
+// (if > 0). The relative order of the tracks is otherwise preserved.
+func (r *RadioSeedCache) Diversify(tracks []*mediaprovider.Track, limit int) []*mediaprovider.Track {
+	r.mu.Lock()
+	recent := make(map[string]bool)
+	for _, m := range r.recentMixes() {
+		for _, id := range m.TrackIDs {
+			recent[id] = true
+		}
+	}
+	r.mu.Unlock()
+
+	result := make([]*mediaprovider.Track, 0, len(tracks))
+	var heard []*mediaprovider.Track
+	for _, tr := range tracks {
+		if recent[tr.ID] {
+			heard = append(heard, tr)
+		} else {
+			result = append(result, tr)
+		}
+	}
+	result = append(result, heard...)
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// recentMixes returns the remembered mixes for the current server.
+// Must be called with the lock held.
+func (r *RadioSeedCache) recentMixes() []RadioMix {
+	if r.config.RecentMixesToRemember <= 0 {
+		return nil
+	}
+	serverID := r.sm.DataKey()
+	cutoff := time.Now().Add(-time.Duration(r.config.MixMemoryHours) * time.Hour)
+	var result []RadioMix
+	for i := len(r.mixes) - 1; i >= 0 && len(result) < r.config.RecentMixesToRemember; i-- {
+		if m := r.mixes[i]; m.ServerID == serverID && m.Time.After(cutoff) {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// prune drops mixes that are too old or too many to be remembered.
+// Must be called with the lock held.
+func (r *RadioSeedCache) prune() {
+	cutoff := time.Now().Add(-time.Duration(r.config.MixMemoryHours) * time.Hour)
+	r.mixes = slices.DeleteFunc(r.mixes, func(m RadioMix) bool { return m.Time.Before(cutoff) })
+	// mixes are per server, so allow some slack for having several servers
+	if limit := r.config.RecentMixesToRemember * 4; len(r.mixes) > limit {
+		r.mixes = r.mixes[len(r.mixes)-limit:]
+	}
+}
+
+func (r *RadioSeedCache) save() error {
+	b, err := json.Marshal(r.mixes)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.filePath, b, 0644)
+}
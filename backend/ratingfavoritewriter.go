@@ -0,0 +1,209 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+// RollbackInfo is the Data of an EventLocalChangeRolledBack.
+type RollbackInfo struct {
+	Err error
+
+	// The favorites change that was reverted, if any.
+	Favorites *FavoritesDiff
+
+	// The track ratings that were restored, if any. Tracks whose
+	// previous rating isn't known (i.e. not in the play queue) are
+	// not included, and views showing them should reload them.
+	Ratings []*RatingChange
+}
+
+// RatingFavoriteWriter sets favorites and ratings optimistically: the local
+// state (the play queue's track models, the favorites cache, and open views
+// via the event bus) is updated immediately, while the server calls are queued
+// and made in order in the background. If a server call fails, the local
+// change is rolled back and an EventLocalChangeRolledBack is published.
+type RatingFavoriteWriter struct {
+	sm   *ServerManager
+	pm   *PlaybackManager
+	favs *FavoritesCache
+	bus  *EventBus
+
+	// the queue is unbounded so that queuing a write
+	// never blocks the (usually UI) calling goroutine
+	mu      sync.Mutex
+	queue   []func()
+	wake    chan struct{}
+	refresh bool // refresh the favorites cache once the queue is drained
+}
+
+func NewRatingFavoriteWriter(ctx context.Context, sm *ServerManager, pm *PlaybackManager, favs *FavoritesCache, bus *EventBus) *RatingFavoriteWriter {
+	w := &RatingFavoriteWriter{
+		sm:   sm,
+		pm:   pm,
+		favs: favs,
+		bus:  bus,
+		wake: make(chan struct{}, 1),
+	}
+	go w.run(ctx)
+	return w
+}
+
+func (w *RatingFavoriteWriter) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+		}
+		for {
+			w.mu.Lock()
+			if len(w.queue) == 0 {
+				refresh := w.refresh
+				w.refresh = false
+				w.mu.Unlock()
+				// reconcile with the server, which also fills in the models of
+				// newly added favorites, once per burst of favorite changes
+				if refresh && w.favs.Cached() != nil {
+					w.favs.Refresh()
+				}
+				break
+			}
+			write := w.queue[0]
+			w.queue = w.queue[1:]
+			w.mu.Unlock()
+			if ctx.Err() != nil {
+				return
+			}
+			write()
+		}
+	}
+}
+
+func (w *RatingFavoriteWriter) enqueue(write func()) {
+	w.mu.Lock()
+	w.queue = append(w.queue, write)
+	w.mu.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default: // already woken
+	}
+}
+
+// SetFavorite sets the favorite status of the items locally,
+// and queues setting it on the server.
+func (w *RatingFavoriteWriter) SetFavorite(params mediaprovider.RatingFavoriteParameters, favorite bool) {
+	server := w.sm.Server
+	if server == nil {
+		return
+	}
+	w.applyFavorite(params, favorite)
+	w.enqueue(func() {
+		err := server.SetFavorite(params, favorite)
+		if err != nil {
+			log.Printf("error setting favorite: %v", err)
+			failed := params
+			var itemsErr *mediaprovider.ItemsError
+			if errors.As(err, &itemsErr) {
+				// only roll back the items which failed
+				didFail := func(id string) bool { _, ok := itemsErr.Errors[id]; return ok }
+				failed.AlbumIDs = sharedutil.FilterSlice(params.AlbumIDs, didFail)
+				failed.ArtistIDs = sharedutil.FilterSlice(params.ArtistIDs, didFail)
+				failed.TrackIDs = sharedutil.FilterSlice(params.TrackIDs, didFail)
+			}
+			diff := w.applyFavorite(failed, !favorite)
+			w.bus.Publish(Event{Type: EventLocalChangeRolledBack, Data: &RollbackInfo{Err: err, Favorites: diff}})
+		}
+		if favorite || err != nil {
+			// removals are fully applied locally, but the models of
+			// added (or rolled back removed) favorites have to be fetched
+			w.mu.Lock()
+			w.refresh = true
+			w.mu.Unlock()
+		}
+	})
+}
+
+// SetRating sets the rating of the tracks locally, and queues setting it
+// on the server. Does nothing if the server doesn't support ratings.
+func (w *RatingFavoriteWriter) SetRating(trackIDs []string, rating int) {
+	r, ok := mediaprovider.As[mediaprovider.SupportsRating](w.sm.Server)
+	if !ok {
+		return
+	}
+	prevRatings := w.queuedTrackRatings(trackIDs)
+	w.applyRating(&RatingChange{TrackIDs: trackIDs, Rating: rating})
+	w.enqueue(func() {
+		err := r.SetRating(mediaprovider.RatingFavoriteParameters{TrackIDs: trackIDs}, rating)
+		if err == nil {
+			return
+		}
+		log.Printf("error setting rating: %v", err)
+		var itemsErr *mediaprovider.ItemsError
+		didFail := func(string) bool { return true }
+		if errors.As(err, &itemsErr) {
+			// only roll back the tracks which failed
+			didFail = func(id string) bool { _, ok := itemsErr.Errors[id]; return ok }
+		}
+		byRating := make(map[int]*RatingChange)
+		var restored []*RatingChange
+		for _, id := range trackIDs {
+			prev, ok := prevRatings[id]
+			if !ok || !didFail(id) {
+				continue
+			}
+			if byRating[prev] == nil {
+				byRating[prev] = &RatingChange{Rating: prev}
+				restored = append(restored, byRating[prev])
+			}
+			byRating[prev].TrackIDs = append(byRating[prev].TrackIDs, id)
+		}
+		for _, change := range restored {
+			w.applyRating(change)
+		}
+		w.bus.Publish(Event{Type: EventLocalChangeRolledBack, Data: &RollbackInfo{Err: err, Ratings: restored}})
+	})
+}
+
+// applyFavorite updates the local state with the favorite status
+// of the items, and returns the resulting favorites change.
+func (w *RatingFavoriteWriter) applyFavorite(params mediaprovider.RatingFavoriteParameters, favorite bool) *FavoritesDiff {
+	for _, id := range params.TrackIDs {
+		w.pm.OnTrackFavoriteStatusChanged(id, favorite)
+	}
+	diff := &FavoritesDiff{}
+	if favorite {
+		diff.AddedAlbums, diff.AddedArtists, diff.AddedTracks = params.AlbumIDs, params.ArtistIDs, params.TrackIDs
+	} else {
+		diff.RemovedAlbums, diff.RemovedArtists, diff.RemovedTracks = params.AlbumIDs, params.ArtistIDs, params.TrackIDs
+	}
+	w.favs.applyLocalChange(diff)
+	return diff
+}
+
+func (w *RatingFavoriteWriter) applyRating(change *RatingChange) {
+	for _, id := range change.TrackIDs {
+		w.pm.OnTrackRatingChanged(id, change.Rating)
+	}
+	w.bus.Publish(Event{Type: EventRatingChanged, Data: change})
+}
+
+// queuedTrackRatings returns the current ratings of
+// those of the given tracks which are in the play queue.
+func (w *RatingFavoriteWriter) queuedTrackRatings(trackIDs []string) map[string]int {
+	ids := sharedutil.ToSet(trackIDs)
+	ratings := make(map[string]int)
+	for _, item := range w.pm.GetPlayQueue() {
+		if tr, ok := item.(*mediaprovider.Track); ok {
+			if _, wanted := ids[tr.ID]; wanted {
+				ratings[tr.ID] = tr.Rating
+			}
+		}
+	}
+	return ratings
+}
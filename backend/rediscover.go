@@ -0,0 +1,305 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
+)
+
+const (
+	// plays of a track for it to count as having been in heavy rotation
+	rediscoverMinPlays = 3
+	// max number of abandoned albums whose tracks are fetched
+	rediscoverMaxAlbums = 40
+	// max number of favorite tracks scanned
+	rediscoverMaxFavorites = 10000
+)
+
+// RediscoverKind is a kind of playlist made by the RediscoverGenerator.
+type RediscoverKind int
+
+const (
+	// favorite tracks not played within the period
+	RediscoverForgottenFavorites RediscoverKind = iota
+	// tracks which were played often, but not within the period
+	RediscoverFormerHeavyRotation
+	// the unplayed tracks of albums which were started but abandoned
+	// before half of their tracks were played, and not returned to within the period
+	RediscoverAbandonedAlbums
+)
+
+var RediscoverKinds = []RediscoverKind{
+	RediscoverForgottenFavorites,
+	RediscoverFormerHeavyRotation,
+	RediscoverAbandonedAlbums,
+}
+
+func (k RediscoverKind) String() string {
+	switch k {
+	case RediscoverFormerHeavyRotation:
+		return "Former heavy rotation"
+	case RediscoverAbandonedAlbums:
+		return "Abandoned albums"
+	default:
+		return "Forgotten favorites"
+	}
+}
+
+// PlaylistName returns the default name of a playlist of the kind.
+func (k RediscoverKind) PlaylistName(months int) string {
+	period := fmt.Sprintf("%d months", months)
+	if months == 1 {
+		period = "a month"
+	} else if months%12 == 0 {
+		period = fmt.Sprintf("%d years", months/12)
+		if months == 12 {
+			period = "a year"
+		}
+	}
+	switch k {
+	case RediscoverFormerHeavyRotation:
+		return "Old favorites not played in " + period
+	case RediscoverAbandonedAlbums:
+		return "Albums left unfinished"
+	default:
+		return "Favorites not played in " + period
+	}
+}
+
+// RediscoverGenerator makes playlists of music the user has drifted away
+// from, by joining the local listening history with the favorites, play
+// counts and last played times reported by the server.
+type RediscoverGenerator struct {
+	sm      *ServerManager
+	history *ListeningHistory
+}
+
+func NewRediscoverGenerator(sm *ServerManager, history *ListeningHistory) *RediscoverGenerator {
+	return &RediscoverGenerator{sm: sm, history: history}
+}
+
+// the listens of a track in the local history
+type trackListens struct {
+	plays      int
+	lastPlayed time.Time
+}
+
+// Generate returns up to limit tracks of the kind, for the period
+// of the given number of months before now.
+func (g *RediscoverGenerator) Generate(kind RediscoverKind, months, limit int) ([]*mediaprovider.Track, error) {
+	server := g.sm.Server
+	if server == nil {
+		return nil, errors.New("not connected to a server")
+	}
+	cutoff := time.Now().AddDate(0, -months, 0)
+	var tracks []*mediaprovider.Track
+	var err error
+	switch kind {
+	case RediscoverFormerHeavyRotation:
+		tracks, err = g.formerHeavyRotation(server, cutoff, limit)
+	case RediscoverAbandonedAlbums:
+		tracks, err = g.abandonedAlbums(server, cutoff, limit)
+	default:
+		tracks, err = g.forgottenFavorites(server, cutoff)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tracks[:min(limit, len(tracks))], nil
+}
+
+// forgottenFavorites returns the favorite tracks last played before
+// cutoff, or never, least recently played first.
+func (g *RediscoverGenerator) forgottenFavorites(server mediaprovider.MediaProvider, cutoff time.Time) ([]*mediaprovider.Track, error) {
+	listens := g.trackListens()
+	lastPlayed := make(map[string]time.Time)
+	var tracks []*mediaprovider.Track
+	add := func(tr *mediaprovider.Track) {
+		if t := latest(tr.LastPlayed, listens[tr.ID].lastPlayed); t.Before(cutoff) {
+			lastPlayed[tr.ID] = t
+			tracks = append(tracks, tr)
+		}
+	}
+	if fi, ok := mediaprovider.As[mediaprovider.SupportsFavoriteTrackIterator](server); ok {
+		iter := fi.IterateFavoriteTracks(mediaprovider.FavoritesSortDefault)
+		for tr, n := iter.Next(), 0; tr != nil && n < rediscoverMaxFavorites; tr, n = iter.Next(), n+1 {
+			add(tr)
+		}
+	} else {
+		fav, err := server.GetFavorites(mediaprovider.FavoritesSortDefault)
+		if err != nil {
+			return nil, err
+		}
+		for _, tr := range fav.Tracks {
+			add(tr)
+		}
+	}
+	sort.SliceStable(tracks, func(i, j int) bool {
+		return lastPlayed[tracks[i].ID].Before(lastPlayed[tracks[j].ID])
+	})
+	return tracks, nil
+}
+
+// formerHeavyRotation returns the tracks played at least rediscoverMinPlays
+// times, but last played before cutoff, most played first.
+// Tracks the server reports as most played are included as well.
+func (g *RediscoverGenerator) formerHeavyRotation(server mediaprovider.MediaProvider, cutoff time.Time, limit int) ([]*mediaprovider.Track, error) {
+	listens := g.trackListens()
+	var ids []string
+	for id, l := range listens {
+		if l.plays >= rediscoverMinPlays && l.lastPlayed.Before(cutoff) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return listens[ids[i]].plays > listens[ids[j]].plays })
+	ids = ids[:min(limit, len(ids))]
+
+	byID := make(map[string]*mediaprovider.Track, len(ids))
+	var mu sync.Mutex
+	// tracks which fail to load may have been removed from the library
+	_ = helpers.ForEachConcurrently(context.Background(), ids, 4, func(id string) error {
+		tr, err := server.GetTrack(id)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		byID[id] = tr
+		mu.Unlock()
+		return nil
+	}, nil)
+
+	if pt, ok := mediaprovider.As[mediaprovider.SupportsPlayedTracks](server); ok {
+		mostPlayed, err := pt.GetMostPlayedTracks(limit * 4)
+		if err != nil {
+			return nil, err
+		}
+		for _, tr := range mostPlayed {
+			// a zero LastPlayed is unknown rather than never played, and is
+			// only trusted if the local history shows an old listen
+			last := latest(tr.LastPlayed, listens[tr.ID].lastPlayed)
+			if tr.PlayCount >= rediscoverMinPlays && !last.IsZero() && last.Before(cutoff) {
+				byID[tr.ID] = tr
+			}
+		}
+	}
+
+	plays := func(tr *mediaprovider.Track) int { return max(tr.PlayCount, listens[tr.ID].plays) }
+	tracks := make([]*mediaprovider.Track, 0, len(byID))
+	for _, tr := range byID {
+		tracks = append(tracks, tr)
+	}
+	sort.Slice(tracks, func(i, j int) bool {
+		if pi, pj := plays(tracks[i]), plays(tracks[j]); pi != pj {
+			return pi > pj
+		}
+		return tracks[i].ID < tracks[j].ID
+	})
+	return tracks, nil
+}
+
+// abandonedAlbums returns the unplayed tracks, in album order, of the albums
+// with at least two tracks played but less than half, all before cutoff.
+// The most recently abandoned albums come first.
+func (g *RediscoverGenerator) abandonedAlbums(server mediaprovider.MediaProvider, cutoff time.Time, limit int) ([]*mediaprovider.Track, error) {
+	type albumListens struct {
+		played     map[string]bool // IDs of the tracks played
+		lastPlayed time.Time
+	}
+	albums := make(map[string]*albumListens)
+	serverID := g.sm.DataKey()
+	for _, r := range g.history.Records(time.Time{}, time.Time{}) {
+		if r.ServerID != serverID || r.AlbumID == "" {
+			continue
+		}
+		a, ok := albums[r.AlbumID]
+		if !ok {
+			a = &albumListens{played: make(map[string]bool)}
+			albums[r.AlbumID] = a
+		}
+		a.lastPlayed = latest(a.lastPlayed, r.Time)
+		if r.CountsAsPlay() {
+			a.played[r.TrackID] = true
+		}
+	}
+	var ids []string
+	for id, a := range albums {
+		if len(a.played) >= 2 && a.lastPlayed.Before(cutoff) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return albums[ids[i]].lastPlayed.After(albums[ids[j]].lastPlayed) })
+	ids = ids[:min(rediscoverMaxAlbums, len(ids))]
+
+	fetched := make(map[string]*mediaprovider.AlbumWithTracks, len(ids))
+	var mu sync.Mutex
+	_ = helpers.ForEachConcurrently(context.Background(), ids, 4, func(id string) error {
+		al, err := helpers.GetAlbum(server, id)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		fetched[id] = al
+		mu.Unlock()
+		return nil
+	}, nil)
+
+	var tracks []*mediaprovider.Track
+	for _, id := range ids {
+		al := fetched[id]
+		if al == nil {
+			continue
+		}
+		var unplayed []*mediaprovider.Track
+		returnedTo := false
+		for _, tr := range al.Tracks {
+			// the server knows of plays from other clients
+			if tr.LastPlayed.After(cutoff) {
+				returnedTo = true
+				break
+			}
+			if !albums[id].played[tr.ID] && tr.PlayCount == 0 {
+				unplayed = append(unplayed, tr)
+			}
+		}
+		if returnedTo || len(unplayed)*2 <= len(al.Tracks) {
+			continue
+		}
+		tracks = append(tracks, unplayed...)
+		if len(tracks) >= limit {
+			break
+		}
+	}
+	return tracks, nil
+}
+
+// trackListens returns the listens of each track of the current
+// server in the local history, by track ID.
+func (g *RediscoverGenerator) trackListens() map[string]trackListens {
+	listens := make(map[string]trackListens)
+	serverID := g.sm.DataKey()
+	for _, r := range g.history.Records(time.Time{}, time.Time{}) {
+		if r.ServerID != serverID {
+			continue
+		}
+		l := listens[r.TrackID]
+		if r.CountsAsPlay() {
+			l.plays++
+		}
+		l.lastPlayed = latest(l.lastPlayed, r.Time)
+		listens[r.TrackID] = l
+	}
+	return listens
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
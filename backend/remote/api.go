@@ -0,0 +1,103 @@
+// Package remote implements an optional HTTP and WebSocket API
+// for controlling playback from other devices on the network.
+package remote
+
+const (
+	StatusPath  = "/api/v1/status"
+	QueuePath   = "/api/v1/queue"
+	CommandPath = "/api/v1/command" // POST a Command as JSON
+	EventsPath  = "/api/v1/ws"      // WebSocket: Events out, Commands in
+
+	// The queue as an extended M3U playlist of stream URLs,
+	// for mirroring the queue in other players
+	QueueM3UPath = "/api/v1/queue.m3u8"
+)
+
+// Query params accepted by QueuePath and QueueM3UPath.
+const (
+	// "true" to include stream URLs in the JSON queue (always included in M3U)
+	ParamStreamURLs = "streamUrls"
+	// "current" to return only the queue from the current track onward,
+	// e.g. to continue listening on another device
+	ParamFrom = "from"
+)
+
+// Commands accepted by CommandPath and over the WebSocket.
+const (
+	CommandPlay      = "play"
+	CommandPause     = "pause"
+	CommandPlayPause = "playpause"
+	CommandStop      = "stop"
+	CommandNext      = "next"
+	CommandPrevious  = "previous"
+	CommandSeek      = "seek"      // Value: position in seconds
+	CommandVolume    = "volume"    // Value: volume 0-100
+	CommandPlayIndex = "playindex" // Value: index in the queue
+	CommandPlayNext  = "playnext"  // IDs: track IDs to insert after the current track
+	CommandAppend    = "append"    // IDs: track IDs to append to the queue
+	CommandRemove    = "remove"    // IDs: track IDs to remove from the queue
+	CommandClear     = "clear"
+)
+
+// Player states reported in Status.
+const (
+	StatePlaying = "playing"
+	StatePaused  = "paused"
+	StateStopped = "stopped"
+)
+
+type Command struct {
+	Command string   `json:"command"`
+	Value   float64  `json:"value,omitempty"`
+	IDs     []string `json:"ids,omitempty"`
+}
+
+type Status struct {
+	State      string     `json:"state"`
+	Position   float64    `json:"position"`
+	Duration   float64    `json:"duration"`
+	Volume     int        `json:"volume"`
+	LoopMode   string     `json:"loopMode"`
+	QueueIndex int        `json:"queueIndex"`
+	NowPlaying *QueueItem `json:"nowPlaying"`
+	// omitted until loaded, or if the track has none
+	Lyrics *Lyrics `json:"lyrics,omitempty"`
+}
+
+type Lyrics struct {
+	Synced bool        `json:"synced"`
+	Lines  []LyricLine `json:"lines"`
+}
+
+type LyricLine struct {
+	Text  string  `json:"text"`
+	Start float64 `json:"start,omitempty"` // seconds, if synced
+}
+
+type QueueItem struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Artists    []string `json:"artists"`
+	Album      string   `json:"album"`
+	Duration   int      `json:"duration"`
+	CoverArtID string   `json:"coverArtId,omitempty"`
+	// only included if requested, since it contains media server credentials
+	StreamURL string `json:"streamUrl,omitempty"`
+}
+
+type Queue struct {
+	Items      []QueueItem `json:"items"`
+	QueueIndex int         `json:"queueIndex"`
+}
+
+// Event is sent to WebSocket clients when the playback state or queue changes.
+type Event struct {
+	Type   string  `json:"type"` // "status", "queue" or "error"
+	Status *Status `json:"status,omitempty"`
+	Queue  *Queue  `json:"queue,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+type Response struct {
+	Error string `json:"error"`
+}
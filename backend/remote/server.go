@@ -0,0 +1,321 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// how often to push the status to WebSocket clients while playing,
+// so that they can keep their position display current
+const statusPushInterval = 1 * time.Second
+
+var ErrUnknownCommand = errors.New("unknown command")
+
+type PlaybackHandler interface {
+	PlayPause() error
+	Stop() error
+	Pause() error
+	Continue() error
+	SeekBackOrPrevious() error
+	SeekNext() error
+	SeekSeconds(float64) error
+	SetVolume(int) error
+	PlayTrackAt(int) error
+
+	InsertTracks(ids []string, playNext bool) error
+	RemoveTracks(ids []string) error
+	ClearQueue() error
+
+	Status() Status
+	Queue() Queue
+	// StreamURL returns a URL from which the queue item can be played.
+	StreamURL(id string) (string, error)
+
+	// Registers a callback to be invoked when the playback
+	// state changes, and whether the queue changed as well.
+	OnChange(func(queueChanged bool))
+}
+
+type Server struct {
+	handler PlaybackHandler
+	token   string
+	server  *http.Server
+
+	mu      sync.Mutex
+	clients map[*wsClient]struct{}
+}
+
+type wsClient struct {
+	conn   *websocket.Conn
+	events chan Event
+}
+
+// NewServer returns a new remote control server. All requests
+// must be authenticated with the given (non-empty) token, either as
+// a bearer token in the Authorization header, or the token query param.
+func NewServer(handler PlaybackHandler, token string) *Server {
+	s := &Server{
+		handler: handler,
+		token:   token,
+		clients: make(map[*wsClient]struct{}),
+	}
+	s.server = &http.Server{
+		Handler:           s.createHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	handler.OnChange(s.broadcastChange)
+	return s
+}
+
+func (s *Server) Serve(listener net.Listener) error {
+	return s.server.Serve(listener)
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	for c := range s.clients {
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+	return s.server.Shutdown(ctx)
+}
+
+func (s *Server) createHandler() http.Handler {
+	m := http.NewServeMux()
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("The given path is not valid"))
+	})
+	m.HandleFunc(StatusPath, s.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		s.writeJSON(w, s.handler.Status())
+	}))
+	m.HandleFunc(QueuePath, s.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		q, err := s.requestedQueue(r, r.URL.Query().Get(ParamStreamURLs) == "true")
+		if err != nil {
+			s.writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		s.writeJSON(w, q)
+	}))
+	m.HandleFunc(QueueM3UPath, s.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		q, err := s.requestedQueue(r, true)
+		if err != nil {
+			s.writeErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "audio/x-mpegurl; charset=utf-8")
+		writeM3U(w, q)
+	}))
+	m.HandleFunc(CommandPath, s.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var cmd Command
+		if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+			s.writeErr(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.execute(cmd); err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, ErrUnknownCommand) {
+				code = http.StatusBadRequest
+			}
+			s.writeErr(w, code, err)
+			return
+		}
+		s.writeJSON(w, Response{})
+	}))
+	// the default websocket.Handler rejects requests without a matching Origin,
+	// which companion apps won't send; requests are authenticated by token instead
+	ws := websocket.Server{Handler: s.serveWebSocket}
+	m.HandleFunc(EventsPath, s.authenticated(ws.ServeHTTP))
+	return m
+}
+
+func (s *Server) authenticated(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			s.writeErr(w, http.StatusUnauthorized, errors.New("invalid token"))
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *Server) execute(cmd Command) error {
+	switch cmd.Command {
+	case CommandPlay:
+		return s.handler.Continue()
+	case CommandPause:
+		return s.handler.Pause()
+	case CommandPlayPause:
+		return s.handler.PlayPause()
+	case CommandStop:
+		return s.handler.Stop()
+	case CommandNext:
+		return s.handler.SeekNext()
+	case CommandPrevious:
+		return s.handler.SeekBackOrPrevious()
+	case CommandSeek:
+		return s.handler.SeekSeconds(cmd.Value)
+	case CommandVolume:
+		return s.handler.SetVolume(int(cmd.Value))
+	case CommandPlayIndex:
+		return s.handler.PlayTrackAt(int(cmd.Value))
+	case CommandPlayNext, CommandAppend:
+		if len(cmd.IDs) == 0 {
+			return errors.New("no track IDs given")
+		}
+		return s.handler.InsertTracks(cmd.IDs, cmd.Command == CommandPlayNext)
+	case CommandRemove:
+		return s.handler.RemoveTracks(cmd.IDs)
+	case CommandClear:
+		return s.handler.ClearQueue()
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownCommand, cmd.Command)
+	}
+}
+
+// requestedQueue returns the queue, filtered and with stream URLs
+// added according to the request's query params.
+func (s *Server) requestedQueue(r *http.Request, withStreamURLs bool) (Queue, error) {
+	q := s.handler.Queue()
+	if r.URL.Query().Get(ParamFrom) == "current" && q.QueueIndex > 0 && q.QueueIndex < len(q.Items) {
+		q.Items = q.Items[q.QueueIndex:]
+		q.QueueIndex = 0
+	}
+	if withStreamURLs {
+		for i := range q.Items {
+			u, err := s.handler.StreamURL(q.Items[i].ID)
+			if err != nil {
+				return Queue{}, err
+			}
+			q.Items[i].StreamURL = u
+		}
+	}
+	return q, nil
+}
+
+func writeM3U(w io.Writer, q Queue) {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("#EXTM3U\n#PLAYLIST:Play Queue\n")
+	for _, item := range q.Items {
+		fmt.Fprintf(bw, "#EXTINF:%d,%s - %s\n%s\n",
+			item.Duration, strings.Join(item.Artists, ", "), item.Title, item.StreamURL)
+	}
+	bw.Flush()
+}
+
+func (s *Server) serveWebSocket(conn *websocket.Conn) {
+	c := &wsClient{conn: conn, events: make(chan Event, 16)}
+	s.mu.Lock()
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, c)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	// read loop - receive commands until the client disconnects
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var cmd Command
+			if err := websocket.JSON.Receive(conn, &cmd); err != nil {
+				return
+			}
+			if err := s.execute(cmd); err != nil {
+				c.send(Event{Type: "error", Error: err.Error()})
+			}
+		}
+	}()
+
+	status := s.handler.Status()
+	queue := s.handler.Queue()
+	if websocket.JSON.Send(conn, Event{Type: "queue", Queue: &queue}) != nil ||
+		websocket.JSON.Send(conn, Event{Type: "status", Status: &status}) != nil {
+		return
+	}
+
+	t := time.NewTicker(statusPushInterval)
+	defer t.Stop()
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case e := <-c.events:
+			err = websocket.JSON.Send(conn, e)
+		case <-t.C:
+			if st := s.handler.Status(); st.State == StatePlaying {
+				err = websocket.JSON.Send(conn, Event{Type: "status", Status: &st})
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) broadcastChange(queueChanged bool) {
+	s.mu.Lock()
+	numClients := len(s.clients)
+	s.mu.Unlock()
+	if numClients == 0 {
+		return
+	}
+
+	var queue *Queue
+	if queueChanged {
+		q := s.handler.Queue()
+		queue = &q
+	}
+	status := s.handler.Status()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		if queue != nil {
+			c.send(Event{Type: "queue", Queue: queue})
+		}
+		c.send(Event{Type: "status", Status: &status})
+	}
+}
+
+// send queues an event to the client, dropping it if the client is not keeping up.
+func (c *wsClient) send(e Event) {
+	select {
+	case c.events <- e:
+	default:
+	}
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) writeErr(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(Response{Error: err.Error()})
+}
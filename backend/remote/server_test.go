@@ -0,0 +1,87 @@
+package remote
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeHandler struct {
+	PlaybackHandler // unimplemented methods panic
+	seekedTo        float64
+	inserted        []string
+}
+
+func (f *fakeHandler) SeekSeconds(s float64) error { f.seekedTo = s; return nil }
+
+func (f *fakeHandler) InsertTracks(ids []string, _ bool) error {
+	f.inserted = ids
+	return nil
+}
+
+func (f *fakeHandler) Status() Status { return Status{State: StatePaused} }
+
+func (f *fakeHandler) Queue() Queue {
+	return Queue{
+		Items: []QueueItem{
+			{ID: "a", Title: "First", Artists: []string{"X"}, Duration: 60},
+			{ID: "b", Title: "Second", Artists: []string{"Y"}, Duration: 90},
+		},
+		QueueIndex: 1,
+	}
+}
+
+func (f *fakeHandler) StreamURL(id string) (string, error) { return "http://music/" + id, nil }
+
+func (f *fakeHandler) OnChange(func(bool)) {}
+
+func TestServer(t *testing.T) {
+	h := &fakeHandler{}
+	srv := httptest.NewServer(NewServer(h, "secret").server.Handler)
+	defer srv.Close()
+
+	post := func(token, body string) int {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+CommandPath, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("wrong", `{"command":"seek","value":5}`); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for bad token, got %d", code)
+	}
+	if code := post("secret", `{"command":"seek","value":12.5}`); code != http.StatusOK || h.seekedTo != 12.5 {
+		t.Errorf("seek failed: code %d, pos %v", code, h.seekedTo)
+	}
+	if code := post("secret", `{"command":"append","ids":["a","b"]}`); code != http.StatusOK || len(h.inserted) != 2 {
+		t.Errorf("append failed: code %d, inserted %v", code, h.inserted)
+	}
+	if code := post("secret", `{"command":"bogus"}`); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown command, got %d", code)
+	}
+
+	resp, err := http.Get(srv.URL + StatusPath + "?token=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 for status with query token, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + QueueM3UPath + "?token=secret&from=current")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m3u, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if want := "#EXTM3U\n#PLAYLIST:Play Queue\n#EXTINF:90,Y - Second\nhttp://music/b\n"; string(m3u) != want {
+		t.Errorf("unexpected M3U queue:\n%s", m3u)
+	}
+}
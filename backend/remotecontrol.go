@@ -0,0 +1,160 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/dweymouth/supersonic/backend/remote"
+)
+
+var _ remote.PlaybackHandler = (*remoteControlHandler)(nil)
+
+// remoteControlHandler adapts the PlaybackManager to the remote control API.
+type remoteControlHandler struct {
+	*PlaybackManager
+	sm *ServerManager
+	np *NowPlayingBroadcaster
+}
+
+func (a *App) startRemoteControlServer() {
+	cfg := &a.Config.RemoteControl
+	if !cfg.Enabled {
+		return
+	}
+	host := "127.0.0.1"
+	if cfg.ListenOnAllInterfaces {
+		host = ""
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, fmt.Sprint(cfg.Port)))
+	if err != nil {
+		log.Printf("error starting remote control server: %v", err)
+		return
+	}
+	a.NowPlaying.RequestLyrics()
+	handler := &remoteControlHandler{PlaybackManager: a.PlaybackManager, sm: a.ServerManager, np: a.NowPlaying}
+	a.remoteServer = remote.NewServer(handler, cfg.EnsureToken())
+	go a.remoteServer.Serve(listener)
+}
+
+func (r *remoteControlHandler) InsertTracks(ids []string, playNext bool) error {
+	if r.sm.Server == nil {
+		return errors.New("not connected to a server")
+	}
+	tracks := make([]*mediaprovider.Track, 0, len(ids))
+	for _, id := range ids {
+		tr, err := r.sm.Server.GetTrack(id)
+		if err != nil {
+			return fmt.Errorf("error fetching track %s: %w", id, err)
+		}
+		tracks = append(tracks, tr)
+	}
+	mode := Append
+	if playNext {
+		mode = InsertNext
+	}
+	return r.LoadTracks(tracks, mode, false)
+}
+
+func (r *remoteControlHandler) RemoveTracks(ids []string) error {
+	r.RemoveTracksFromQueue(ids)
+	return nil
+}
+
+func (r *remoteControlHandler) ClearQueue() error {
+	r.StopAndClearPlayQueue()
+	return nil
+}
+
+func (r *remoteControlHandler) Status() remote.Status {
+	status := r.PlayerStatus()
+	s := remote.Status{
+		Position:   status.TimePos,
+		Duration:   status.Duration,
+		Volume:     r.Volume(),
+		QueueIndex: r.NowPlayingIndex(),
+	}
+	switch status.State {
+	case player.Playing:
+		s.State = remote.StatePlaying
+	case player.Paused:
+		s.State = remote.StatePaused
+	default:
+		s.State = remote.StateStopped
+	}
+	switch r.GetLoopMode() {
+	case LoopAll:
+		s.LoopMode = "all"
+	case LoopOne:
+		s.LoopMode = "one"
+	default:
+		s.LoopMode = "none"
+	}
+	if np := r.NowPlaying(); np != nil {
+		item := toRemoteQueueItem(np)
+		s.NowPlaying = &item
+		if info := r.np.Current(); info.Item == np && info.Lyrics != nil {
+			s.Lyrics = toRemoteLyrics(info.Lyrics)
+		}
+	}
+	return s
+}
+
+func (r *remoteControlHandler) Queue() remote.Queue {
+	queue := r.GetPlayQueue()
+	q := remote.Queue{
+		Items:      make([]remote.QueueItem, 0, len(queue)),
+		QueueIndex: r.NowPlayingIndex(),
+	}
+	for _, item := range queue {
+		q.Items = append(q.Items, toRemoteQueueItem(item))
+	}
+	return q
+}
+
+func (r *remoteControlHandler) StreamURL(id string) (string, error) {
+	for _, item := range r.GetPlayQueue() {
+		if rs, ok := item.(*mediaprovider.RadioStation); ok && rs.ID == id {
+			return rs.StreamURL, nil
+		}
+	}
+	if r.sm.Server == nil {
+		return "", errors.New("not connected to a server")
+	}
+	return r.sm.Server.GetStreamURL(id, false)
+}
+
+func (r *remoteControlHandler) OnChange(cb func(queueChanged bool)) {
+	statusChanged := func() { cb(false) }
+	r.np.OnChange(func(*NowPlayingInfo) { cb(false) })
+	r.OnPlaying(statusChanged)
+	r.OnPaused(statusChanged)
+	r.OnStopped(statusChanged)
+	r.OnSeek(statusChanged)
+	r.OnVolumeChange(func(int) { cb(false) })
+	r.OnLoopModeChange(func(LoopMode) { cb(false) })
+	r.OnQueueChange(func() { cb(true) })
+}
+
+func toRemoteLyrics(lyrics *mediaprovider.Lyrics) *remote.Lyrics {
+	l := &remote.Lyrics{Synced: lyrics.Synced, Lines: make([]remote.LyricLine, 0, len(lyrics.Lines))}
+	for _, line := range lyrics.Lines {
+		l.Lines = append(l.Lines, remote.LyricLine{Text: line.Text, Start: line.Start})
+	}
+	return l
+}
+
+func toRemoteQueueItem(item mediaprovider.MediaItem) remote.QueueItem {
+	meta := item.Metadata()
+	return remote.QueueItem{
+		ID:         meta.ID,
+		Title:      meta.Name,
+		Artists:    meta.Artists,
+		Album:      meta.Album,
+		Duration:   meta.Duration,
+		CoverArtID: meta.CoverArtID,
+	}
+}
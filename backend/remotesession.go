@@ -0,0 +1,208 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+)
+
+const (
+	// how often to report the playback position to the server while playing
+	remoteSessionReportInterval = 10 * time.Second
+	// delay before reconnecting a dropped remote session
+	remoteSessionRetryInterval = 30 * time.Second
+)
+
+// remoteSession connects the PlaybackManager to a server-side
+// remote control session, for providers that support it, so that
+// other clients of the server can control playback in this app.
+// When not connected, the playback progress is still reported
+// to servers which keep track of it, if scrobbling is enabled.
+type remoteSession struct {
+	pm *PlaybackManager
+	sm *ServerManager
+
+	mu       sync.Mutex
+	provider mediaprovider.SupportsRemoteSession // nil if not connected
+	cancel   context.CancelFunc
+}
+
+func (a *App) setupRemoteSession() {
+	r := &remoteSession{pm: a.PlaybackManager, sm: a.ServerManager}
+	a.ServerManager.OnServerConnected(func() {
+		if !a.Config.RemoteControl.AllowServerSessionControl {
+			return
+		}
+		if rs, ok := mediaprovider.As[mediaprovider.SupportsRemoteSession](a.ServerManager.Server); ok {
+			r.start(a.bgrndCtx, rs)
+		}
+	})
+	a.ServerManager.OnLogout(r.stop)
+
+	report := func() { go r.reportState() }
+	a.PlaybackManager.OnPlaying(report)
+	a.PlaybackManager.OnPaused(report)
+	a.PlaybackManager.OnSeek(report)
+	a.PlaybackManager.OnQueueChange(report)
+	a.PlaybackManager.OnVolumeChange(func(int) { report() })
+	go r.reportWhilePlaying(a.bgrndCtx)
+}
+
+func (r *remoteSession) start(ctx context.Context, provider mediaprovider.SupportsRemoteSession) {
+	r.stop()
+	ctx, cancel := context.WithCancel(ctx)
+	r.mu.Lock()
+	r.provider = provider
+	r.cancel = cancel
+	r.mu.Unlock()
+
+	go func() {
+		for {
+			err := provider.RunRemoteSession(ctx, r.handleCommand)
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("remote session disconnected: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(remoteSessionRetryInterval):
+			}
+		}
+	}()
+}
+
+func (r *remoteSession) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.provider = nil
+	r.cancel = nil
+}
+
+func (r *remoteSession) handleCommand(cmd mediaprovider.RemoteCommand) {
+	var err error
+	switch cmd.Type {
+	case mediaprovider.RemoteCommandPlayNow,
+		mediaprovider.RemoteCommandPlayNext,
+		mediaprovider.RemoteCommandPlayLast:
+		err = r.playItems(cmd)
+	case mediaprovider.RemoteCommandPause:
+		err = r.pm.Pause()
+	case mediaprovider.RemoteCommandUnpause:
+		err = r.pm.Continue()
+	case mediaprovider.RemoteCommandPlayPause:
+		err = r.pm.PlayPause()
+	case mediaprovider.RemoteCommandStop:
+		err = r.pm.Stop()
+	case mediaprovider.RemoteCommandNext:
+		err = r.pm.SeekNext()
+	case mediaprovider.RemoteCommandPrevious:
+		err = r.pm.SeekBackOrPrevious()
+	case mediaprovider.RemoteCommandSeek:
+		err = r.pm.SeekSeconds(cmd.Position)
+	case mediaprovider.RemoteCommandSetVolume:
+		err = r.pm.SetVolume(cmd.Volume)
+	}
+	if err != nil {
+		log.Printf("error handling remote session command: %v", err)
+	}
+}
+
+func (r *remoteSession) playItems(cmd mediaprovider.RemoteCommand) error {
+	tracks, err := r.resolveTracks(cmd.ItemIDs)
+	if err != nil {
+		return err
+	}
+	switch cmd.Type {
+	case mediaprovider.RemoteCommandPlayNext:
+		return r.pm.LoadTracks(tracks, InsertNext, false)
+	case mediaprovider.RemoteCommandPlayLast:
+		return r.pm.LoadTracks(tracks, Append, false)
+	}
+	if err := r.pm.LoadTracks(tracks, Replace, false); err != nil {
+		return err
+	}
+	if cmd.StartIndex < 0 || cmd.StartIndex >= len(tracks) {
+		cmd.StartIndex = 0
+	}
+	return r.pm.PlayTrackAt(cmd.StartIndex)
+}
+
+// resolveTracks returns the tracks for the given IDs. Other clients may
+// send the IDs of albums or playlists, which are expanded to their tracks.
+func (r *remoteSession) resolveTracks(ids []string) ([]*mediaprovider.Track, error) {
+	server := r.sm.Server
+	if server == nil {
+		return nil, ErrNoServers
+	}
+	var tracks []*mediaprovider.Track
+	for _, id := range ids {
+		if tr, err := server.GetTrack(id); err == nil {
+			tracks = append(tracks, tr)
+		} else if al, err := server.GetAlbum(id); err == nil {
+			tracks = append(tracks, al.Tracks...)
+		} else if pl, err := server.GetPlaylist(id); err == nil {
+			tracks = append(tracks, pl.Tracks...)
+		} else {
+			return nil, fmt.Errorf("unable to resolve item %s", id)
+		}
+	}
+	return tracks, nil
+}
+
+func (r *remoteSession) reportWhilePlaying(ctx context.Context) {
+	t := time.NewTicker(remoteSessionReportInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if r.pm.PlayerStatus().State == player.Playing {
+				r.reportState()
+			}
+		}
+	}
+}
+
+func (r *remoteSession) reportState() {
+	r.mu.Lock()
+	var report func(mediaprovider.RemoteSessionState) error
+	if r.provider != nil {
+		report = r.provider.ReportSessionState
+	} else if pp, ok := mediaprovider.As[mediaprovider.SupportsPlaybackProgress](r.sm.Server); ok && r.sm.ScrobbleConfig().Enabled {
+		report = pp.ReportPlaybackProgress
+	}
+	r.mu.Unlock()
+	if report == nil {
+		return
+	}
+	np := r.pm.NowPlaying()
+	status := r.pm.PlayerStatus()
+	if np == nil || np.Metadata().Type != mediaprovider.MediaItemTypeTrack || status.State == player.Stopped {
+		return
+	}
+	queue := r.pm.GetPlayQueue()
+	ids := make([]string, 0, len(queue))
+	for _, item := range queue {
+		ids = append(ids, item.Metadata().ID)
+	}
+	err := report(mediaprovider.RemoteSessionState{
+		TrackID:  np.Metadata().ID,
+		Position: status.TimePos,
+		Paused:   status.State == player.Paused,
+		Volume:   r.pm.Volume(),
+		QueueIDs: ids,
+	})
+	if err != nil {
+		log.Printf("error reporting remote session state: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package backend
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/dweymouth/supersonic/backend/player/dlna"
+)
+
+const rendererDiscoveryTimeout = 3 * time.Second
+
+// RendererManager discovers UPnP/DLNA media renderers on the local network
+// and switches playback between the local player and a renderer.
+type RendererManager struct {
+	pm    *PlaybackManager
+	sm    *ServerManager
+	local player.BasePlayer
+
+	mu      sync.Mutex
+	devices []dlna.Device
+	active  *dlna.Player
+}
+
+func NewRendererManager(pm *PlaybackManager, sm *ServerManager, local player.BasePlayer) *RendererManager {
+	r := &RendererManager{pm: pm, sm: sm, local: local}
+	sm.OnLogout(func() { _ = r.PlayTo(nil) })
+	return r
+}
+
+// Discover searches the network for renderers. It blocks for a few seconds.
+func (r *RendererManager) Discover(ctx context.Context) ([]dlna.Device, error) {
+	devs, err := dlna.Discover(ctx, rendererDiscoveryTimeout)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(devs, func(i, j int) bool { return devs[i].Name < devs[j].Name })
+	r.mu.Lock()
+	r.devices = devs
+	r.mu.Unlock()
+	return devs, nil
+}
+
+// Devices returns the renderers found by the last Discover.
+func (r *RendererManager) Devices() []dlna.Device {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.devices
+}
+
+// ActiveDevice returns the renderer currently played to, or nil if playing locally.
+func (r *RendererManager) ActiveDevice() *dlna.Device {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active == nil {
+		return nil
+	}
+	dev := r.active.Device()
+	return &dev
+}
+
+// PlayTo switches playback to the given renderer, or back to the local player if nil.
+func (r *RendererManager) PlayTo(dev *dlna.Device) error {
+	r.mu.Lock()
+	prev := r.active
+	if dev != nil && prev != nil && prev.Device().UDN == dev.UDN {
+		r.mu.Unlock()
+		return nil
+	}
+	var next player.BasePlayer = r.local
+	r.active = nil
+	if dev != nil {
+		r.active = dlna.NewPlayer(*dev, r.streamURL)
+		next = r.active
+	}
+	r.mu.Unlock()
+
+	err := r.pm.SetPlayer(next)
+	if prev != nil {
+		prev.Destroy()
+	}
+	return err
+}
+
+// Shutdown stops polling the active renderer, if any.
+func (r *RendererManager) Shutdown() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active != nil {
+		r.active.Destroy()
+		r.active = nil
+	}
+}
+
+func (r *RendererManager) streamURL(track *mediaprovider.Track) (string, error) {
+	return r.sm.GetStreamURL(track.ID)
+}
@@ -0,0 +1,256 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// number of requests kept in the RequestLog
+	requestLogCapacity = 200
+	// bytes of each request and response body kept
+	requestLogBodyLimit = 4096
+)
+
+// query parameters whose values are never logged, compared case-insensitively
+var redactedParams = map[string]bool{
+	"p": true, "t": true, "s": true, "password": true, "pw": true, "token": true,
+	"apikey": true, "api_key": true, "x-emby-token": true, "accesstoken": true,
+}
+
+// matches the JSON fields named like redactedParams, with their values
+var secretFieldRegex = regexp.MustCompile(`(?i)"(p|t|s|password|pw|token|apikey|api_key|x-emby-token|accesstoken)"\s*:\s*"[^"]*"`)
+
+// RequestLog records the most recent API requests to the server while
+// enabled, for diagnosing problems. Secrets are redacted from the recorded
+// requests, so the log can be shared in bug reports.
+type RequestLog struct {
+	enabled atomic.Bool
+
+	mu      sync.Mutex
+	entries []RequestLogEntry // ring buffer
+	next    int               // index of the next entry to write
+}
+
+// RequestLogEntry is a request recorded in the RequestLog.
+type RequestLogEntry struct {
+	Time       time.Time
+	Method     string
+	URL        string // with secret parameters redacted
+	Endpoint   string
+	Body       string // request body, truncated and redacted
+	StatusCode int    // 0 if Err is set
+	Err        string
+	Duration   time.Duration // until the response headers were received
+	Bytes      int64         // size of the response body read
+	Response   string        // start of the response body, if textual
+	Truncated  bool          // whether Response is cut short
+}
+
+func NewRequestLog() *RequestLog {
+	return &RequestLog{entries: make([]RequestLogEntry, 0, requestLogCapacity)}
+}
+
+func (l *RequestLog) SetEnabled(enabled bool) {
+	l.enabled.Store(enabled)
+}
+
+func (l *RequestLog) Enabled() bool {
+	return l.enabled.Load()
+}
+
+// Entries returns the recorded requests, oldest first.
+func (l *RequestLog) Entries() []RequestLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) < requestLogCapacity {
+		return append([]RequestLogEntry(nil), l.entries...)
+	}
+	return append(append([]RequestLogEntry(nil), l.entries[l.next:]...), l.entries[:l.next]...)
+}
+
+// Clear removes all recorded requests.
+func (l *RequestLog) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = l.entries[:0]
+	l.next = 0
+}
+
+// Dump writes the recorded requests as text, oldest first.
+func (l *RequestLog) Dump(w io.Writer) error {
+	for _, e := range l.Entries() {
+		if _, err := io.WriteString(w, e.String()+"\n\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *RequestLog) add(e RequestLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) < requestLogCapacity {
+		l.entries = append(l.entries, e)
+		return
+	}
+	l.entries[l.next] = e
+	l.next = (l.next + 1) % requestLogCapacity
+}
+
+// Summary returns a one-line summary of the request.
+func (e RequestLogEntry) Summary() string {
+	status := e.Err
+	if status == "" {
+		status = fmt.Sprint(e.StatusCode)
+	}
+	return fmt.Sprintf("%s  %s %s  %s  %s", e.Time.Format(time.TimeOnly),
+		e.Method, e.Endpoint, status, e.Duration.Round(time.Millisecond))
+}
+
+// String returns the full details of the request.
+func (e RequestLogEntry) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s %s\n", e.Time.Format(time.RFC3339Nano), e.Method, e.URL)
+	if e.Body != "" {
+		fmt.Fprintf(&sb, "Request body: %s\n", e.Body)
+	}
+	if e.Err != "" {
+		fmt.Fprintf(&sb, "Error: %s after %s\n", e.Err, e.Duration.Round(time.Millisecond))
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "Status: %d in %s, %d bytes\n", e.StatusCode, e.Duration.Round(time.Millisecond), e.Bytes)
+	if e.Response != "" {
+		sb.WriteString(e.Response)
+		if e.Truncated {
+			sb.WriteString("…")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Transport wraps the given RoundTripper (or http.DefaultTransport if nil)
+// so that requests made through it are recorded while the log is enabled.
+func (l *RequestLog) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if l == nil {
+		return base
+	}
+	return &requestLogTransport{base: base, l: l}
+}
+
+type requestLogTransport struct {
+	base http.RoundTripper
+	l    *RequestLog
+}
+
+func (t *requestLogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.l.Enabled() {
+		return t.base.RoundTrip(req)
+	}
+	e := RequestLogEntry{
+		Time:     time.Now(),
+		Method:   req.Method,
+		URL:      redactURL(req.URL),
+		Endpoint: endpointName(req.URL),
+		Body:     requestBody(req),
+	}
+	resp, err := t.base.RoundTrip(req)
+	e.Duration = time.Since(e.Time)
+	if err != nil {
+		e.Err = err.Error()
+		t.l.add(e)
+		return resp, err
+	}
+	e.StatusCode = resp.StatusCode
+	if !isTextContent(resp.Header.Get("Content-Type")) {
+		// don't keep the start of images or audio files
+		if ct := resp.Header.Get("Content-Type"); ct != "" {
+			e.Response = fmt.Sprintf("[%s]", ct)
+		}
+		resp.Body = &countingBody{ReadCloser: resp.Body, onDone: func(n int64) {
+			e.Bytes = n
+			t.l.add(e)
+		}}
+		return resp, nil
+	}
+	var head bytes.Buffer
+	body := struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(resp.Body, &limitedWriter{w: &head, n: requestLogBodyLimit}), resp.Body}
+	resp.Body = &countingBody{ReadCloser: body, onDone: func(n int64) {
+		e.Bytes = n
+		e.Response = redactSecrets(head.String())
+		e.Truncated = n > requestLogBodyLimit
+		t.l.add(e)
+	}}
+	return resp, nil
+}
+
+// requestBody returns the start of the request body, redacted,
+// leaving the body of the request to be read in full.
+func requestBody(req *http.Request) string {
+	if req.GetBody == nil || req.ContentLength == 0 || !isTextContent(req.Header.Get("Content-Type")) {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	b, _ := io.ReadAll(io.LimitReader(body, requestLogBodyLimit))
+	return redactSecrets(string(b))
+}
+
+// redactURL returns the URL with the values of secret query parameters replaced.
+func redactURL(u *url.URL) string {
+	r := *u
+	r.User = nil
+	q := r.Query()
+	for k := range q {
+		if redactedParams[strings.ToLower(k)] {
+			q.Set(k, "REDACTED")
+		}
+	}
+	r.RawQuery = q.Encode()
+	return r.String()
+}
+
+// redactSecrets replaces the values of secret fields of JSON text,
+// which may be truncated so can't be parsed.
+func redactSecrets(text string) string {
+	return secretFieldRegex.ReplaceAllString(text, `"$1":"REDACTED"`)
+}
+
+func isTextContent(contentType string) bool {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	return strings.HasPrefix(mt, "text/") || strings.HasSuffix(mt, "json") || strings.HasSuffix(mt, "xml")
+}
+
+// limitedWriter writes up to n bytes to w, discarding the rest.
+type limitedWriter struct {
+	w io.Writer
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.n > 0 {
+		k := min(len(p), l.n)
+		l.w.Write(p[:k])
+		l.n -= k
+	}
+	return len(p), nil
+}
@@ -7,12 +7,17 @@ import (
 	"os"
 
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
 )
 
 type SavedPlayQueue struct {
 	Tracks     []*mediaprovider.Track
 	TrackIndex int
 	TimePos    float64
+	LoopMode   LoopMode
+	// Whether playback was in progress when the queue was saved,
+	// i.e. the app crashed or was killed while playing
+	WasPlaying bool
 }
 
 type serializedSavedPlayQueue struct {
@@ -20,11 +25,23 @@ type serializedSavedPlayQueue struct {
 	TrackIDs   []string `json:"trackIDs"`
 	TrackIndex int      `json:"trackIndex"`
 	TimePos    float64  `json:"timePos"`
+	LoopMode   LoopMode `json:"loopMode"`
+	WasPlaying bool     `json:"wasPlaying"`
 }
 
 // SavePlayQueue saves the current play queue and playback position to a JSON file.
 // If the provided CanSavePlayQueue server is non-nil, it will also save to the server.
 func SavePlayQueue(serverID string, pm *PlaybackManager, filepath string, server mediaprovider.CanSavePlayQueue) error {
+	saved := serializePlayQueue(serverID, pm)
+	err := writeSavedPlayQueue(saved, filepath)
+	if server != nil {
+		// save to server
+		err = server.SavePlayQueue(saved.TrackIDs, saved.TrackIndex, int(saved.TimePos))
+	}
+	return err
+}
+
+func serializePlayQueue(serverID string, pm *PlaybackManager) serializedSavedPlayQueue {
 	queue := pm.GetPlayQueue()
 	stats := pm.PlayerStatus()
 	trackIdx := pm.NowPlayingIndex()
@@ -44,20 +61,22 @@ func SavePlayQueue(serverID string, pm *PlaybackManager, filepath string, server
 		}
 	}
 
-	saved := serializedSavedPlayQueue{
+	return serializedSavedPlayQueue{
 		ServerID:   serverID,
 		TrackIDs:   trackIDs,
 		TrackIndex: trackIdx,
 		TimePos:    stats.TimePos,
+		LoopMode:   pm.GetLoopMode(),
+		WasPlaying: stats.State == player.Playing,
 	}
-	b, _ := json.Marshal(saved)
-	err := os.WriteFile(filepath, b, 0644)
+}
 
-	if server != nil {
-		// save to server
-		err = server.SavePlayQueue(trackIDs, trackIdx, int(stats.TimePos))
+func writeSavedPlayQueue(saved serializedSavedPlayQueue, filepath string) error {
+	b, err := json.Marshal(saved)
+	if err != nil {
+		return err
 	}
-	return err
+	return writeFileAtomic(filepath, b)
 }
 
 // Loads the saved play queue from the given filepath using the current server.
@@ -66,7 +85,7 @@ func SavePlayQueue(serverID string, pm *PlaybackManager, filepath string, server
 // Returns an error if the queue could not be loaded for any reason, including the
 // currently logged in server being different than the server from which the queue was saved.
 func LoadPlayQueue(filepath string, sm *ServerManager, loadFromServer bool) (*SavedPlayQueue, error) {
-	if pq, ok := sm.Server.(mediaprovider.CanSavePlayQueue); loadFromServer && ok && pq != nil {
+	if pq, ok := mediaprovider.As[mediaprovider.CanSavePlayQueue](sm.Server); loadFromServer && ok && pq != nil {
 		// load queue from server
 		queue, err := pq.GetPlayQueue()
 		if err == nil {
@@ -91,7 +110,7 @@ func LoadPlayQueue(filepath string, sm *ServerManager, loadFromServer bool) (*Sa
 		return nil, err
 	}
 
-	if sm.ServerID.String() != savedData.ServerID {
+	if sm.DataKey() != savedData.ServerID {
 		return nil, errors.New("saved play queue was from a different server")
 	}
 
@@ -112,6 +131,8 @@ func LoadPlayQueue(filepath string, sm *ServerManager, loadFromServer bool) (*Sa
 		Tracks:     tracks,
 		TrackIndex: savedData.TrackIndex,
 		TimePos:    savedData.TimePos,
+		LoopMode:   savedData.LoopMode,
+		WasPlaying: savedData.WasPlaying,
 	}
 	return savedQueue, nil
 }
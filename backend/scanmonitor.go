@@ -0,0 +1,127 @@
+package backend
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+const (
+	scanStatusPollInterval = 2 * time.Second
+	// how long to wait for a requested scan to be reported as started
+	scanStartTimeout = 15 * time.Second
+	// how long to assume a scan takes, if the server can't report its status
+	scanAssumedDuration = time.Minute
+)
+
+// ScanMonitor follows a library scan started by the user (EventLibraryRescanned),
+// publishing EventLibraryScanProgress while the scan runs and
+// EventLibraryScanCompleted when it finishes. For servers which can't
+// report the scan status, EventLibraryScanCompleted is published after
+// a fixed delay, since the scan progress can't be known.
+type ScanMonitor struct {
+	ctx context.Context
+	sm  *ServerManager
+	bus *EventBus
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	status *mediaprovider.ScanStatus // nil if not monitoring a scan
+}
+
+func NewScanMonitor(ctx context.Context, sm *ServerManager, bus *EventBus) *ScanMonitor {
+	s := &ScanMonitor{ctx: ctx, sm: sm, bus: bus}
+	bus.Subscribe(EventLibraryRescanned, func(Event) { s.start() })
+	sm.OnLogout(s.stop)
+	return s
+}
+
+// Status returns the status of the scan being monitored,
+// or nil if no scan is in progress.
+func (s *ScanMonitor) Status() *mediaprovider.ScanStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+func (s *ScanMonitor) start() {
+	s.stop()
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.mu.Lock()
+	s.cancel = cancel
+	s.status = &mediaprovider.ScanStatus{Scanning: true, Progress: -1}
+	s.mu.Unlock()
+
+	if st, ok := mediaprovider.As[mediaprovider.SupportsScanStatus](s.sm.Server); ok {
+		go s.poll(ctx, st)
+	} else {
+		go func() {
+			select {
+			case <-ctx.Done():
+			case <-time.After(scanAssumedDuration):
+				s.finish(ctx)
+			}
+		}()
+	}
+}
+
+func (s *ScanMonitor) poll(ctx context.Context, st mediaprovider.SupportsScanStatus) {
+	started := time.Now()
+	seenScanning := false
+	t := time.NewTicker(scanStatusPollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		status, err := st.GetScanStatus()
+		if err != nil {
+			// e.g. the user isn't permitted to see the scan status
+			log.Printf("error getting scan status: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(scanAssumedDuration - time.Since(started)):
+				s.finish(ctx)
+			}
+			return
+		}
+		if status.Scanning {
+			seenScanning = true
+			s.mu.Lock()
+			if ctx.Err() == nil {
+				s.status = &status
+			}
+			s.mu.Unlock()
+			s.bus.Publish(Event{Type: EventLibraryScanProgress, Data: status})
+		} else if seenScanning || time.Since(started) > scanStartTimeout {
+			s.finish(ctx)
+			return
+		}
+	}
+}
+
+func (s *ScanMonitor) finish(ctx context.Context) {
+	s.mu.Lock()
+	if ctx.Err() != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.status = nil
+	s.mu.Unlock()
+	s.bus.Publish(Event{Type: EventLibraryScanCompleted})
+}
+
+func (s *ScanMonitor) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+	s.status = nil
+}
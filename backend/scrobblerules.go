@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// how long the track IDs of the ignored playlists are cached
+const scrobbleRulesPlaylistTTL = 5 * time.Minute
+
+// scrobbleRules applies the genre rules of the ScrobbleConfig
+// and the playlist rules of the ServerConfig.
+// The tracks of the ignored playlists are loaded in the background
+// by refresh, so that checking a track never blocks on the server.
+type scrobbleRules struct {
+	sm *ServerManager
+
+	mu             sync.Mutex
+	loading        bool
+	loadedAt       time.Time
+	playlistsKey   string              // the ignored playlist IDs playlistTracks was loaded for
+	playlistTracks map[string]struct{} // IDs of the tracks in the ignored playlists
+}
+
+func newScrobbleRules(sm *ServerManager) *scrobbleRules {
+	r := &scrobbleRules{sm: sm}
+	sm.OnLogout(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.playlistsKey = ""
+		r.playlistTracks = nil
+	})
+	return r
+}
+
+// allows returns false if the track must not be scrobbled
+// because of its genre or because it is in an ignored playlist.
+func (r *scrobbleRules) allows(cfg ScrobbleConfig, track *mediaprovider.Track) bool {
+	for _, g := range track.Genres {
+		if slices.ContainsFunc(cfg.IgnoredGenres, func(ig string) bool { return strings.EqualFold(g, ig) }) {
+			return false
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ignored := r.playlistTracks[track.ID]
+	return !ignored
+}
+
+// refresh reloads the tracks of the ignored playlists in the background,
+// if they have changed or the cached tracks are stale.
+func (r *scrobbleRules) refresh() {
+	conf := r.sm.CurrentServerConfig()
+	if conf == nil {
+		return
+	}
+	key := strings.Join(conf.NoScrobblePlaylistIDs, ",")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loading || (key == r.playlistsKey && time.Since(r.loadedAt) < scrobbleRulesPlaylistTTL) {
+		return
+	}
+	if key == "" {
+		r.playlistsKey = ""
+		r.playlistTracks = nil
+		return
+	}
+	server := r.sm.Server
+	if server == nil {
+		return
+	}
+	r.loading = true
+	ids := slices.Clone(conf.NoScrobblePlaylistIDs)
+	go func() {
+		tracks := make(map[string]struct{})
+		for _, id := range ids {
+			pl, err := server.GetPlaylist(id)
+			if err != nil {
+				// e.g. the playlist was deleted
+				log.Printf("error loading ignored playlist for scrobbling: %v", err)
+				continue
+			}
+			for _, tr := range pl.Tracks {
+				tracks[tr.ID] = struct{}{}
+			}
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.loading = false
+		if r.sm.Server != server {
+			return // logged out while loading
+		}
+		r.playlistsKey = key
+		r.playlistTracks = tracks
+		r.loadedAt = time.Now()
+	}()
+}
@@ -0,0 +1,237 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deluan/sanitize"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
+)
+
+const (
+	searchHistoryFile = "searchhistory.json"
+
+	// max number of queries and opened results remembered per server
+	searchHistoryMaxQueries = 50
+	searchHistoryMaxOpened  = 200
+)
+
+// SearchHistory remembers the user's recent search queries and the search
+// results they opened, per server, to suggest completions as a query is typed
+// before the server has been searched.
+type SearchHistory struct {
+	filePath string
+	sm       *ServerManager
+	favs     *FavoritesCache
+
+	mu      sync.Mutex
+	servers map[string]*serverSearchHistory // by ServerManager.DataKey
+}
+
+type serverSearchHistory struct {
+	Queries []searchHistoryQuery  `json:"queries"` // most recent first
+	Opened  []searchHistoryOpened `json:"opened"`
+}
+
+type searchHistoryQuery struct {
+	Query string    `json:"query"`
+	Time  time.Time `json:"time"`
+}
+
+type searchHistoryOpened struct {
+	Result     mediaprovider.SearchResult `json:"result"`
+	Count      int                        `json:"count"`
+	LastOpened time.Time                  `json:"lastOpened"`
+}
+
+// SearchSuggestions are completions for a partly typed search query.
+type SearchSuggestions struct {
+	// Recent queries which contain the typed text, most recent first
+	Queries []string
+	// Opened results and favorites which match the typed text,
+	// most often opened first
+	Results []*mediaprovider.SearchResult
+}
+
+func NewSearchHistory(filePath string, sm *ServerManager, favs *FavoritesCache) *SearchHistory {
+	h := &SearchHistory{filePath: filePath, sm: sm, favs: favs}
+	if err := h.load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("error loading search history: %v", err)
+	}
+	if h.servers == nil {
+		h.servers = make(map[string]*serverSearchHistory)
+	}
+	return h
+}
+
+// RecordQuery adds a query to the recent queries of the current server.
+func (h *SearchHistory) RecordQuery(query string) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return
+	}
+	h.update(func(s *serverSearchHistory) {
+		s.Queries = slices.DeleteFunc(s.Queries, func(q searchHistoryQuery) bool {
+			return strings.EqualFold(q.Query, query)
+		})
+		s.Queries = slices.Insert(s.Queries, 0, searchHistoryQuery{Query: query, Time: time.Now()})
+		if len(s.Queries) > searchHistoryMaxQueries {
+			s.Queries = s.Queries[:searchHistoryMaxQueries]
+		}
+	})
+}
+
+// RecordOpened records that the user opened a search result.
+func (h *SearchHistory) RecordOpened(result *mediaprovider.SearchResult) {
+	h.update(func(s *serverSearchHistory) {
+		idx := slices.IndexFunc(s.Opened, func(o searchHistoryOpened) bool {
+			return o.Result.Type == result.Type && o.Result.ID == result.ID
+		})
+		if idx < 0 {
+			s.Opened = append(s.Opened, searchHistoryOpened{})
+			idx = len(s.Opened) - 1
+		}
+		s.Opened[idx].Result = *result
+		s.Opened[idx].Count++
+		s.Opened[idx].LastOpened = time.Now()
+		if len(s.Opened) > searchHistoryMaxOpened {
+			// forget the least recently opened
+			sort.Slice(s.Opened, func(i, j int) bool { return s.Opened[i].LastOpened.After(s.Opened[j].LastOpened) })
+			s.Opened = s.Opened[:searchHistoryMaxOpened]
+		}
+	})
+}
+
+// RecentQueries returns up to limit of the most recent queries on the current server.
+func (h *SearchHistory) RecentQueries(limit int) []string {
+	return h.Suggestions("", limit).Queries
+}
+
+// Suggestions returns up to limit recent queries and up to limit items
+// from the opened results and cached favorites which match the typed query.
+// If query is empty, the most often opened results are returned.
+func (h *SearchHistory) Suggestions(query string, limit int) SearchSuggestions {
+	terms := strings.Fields(strings.ToLower(sanitize.Accents(query)))
+	matches := func(name string) bool {
+		return helpers.AllTermsMatch(strings.ToLower(sanitize.Accents(name)), terms)
+	}
+
+	var sugg SearchSuggestions
+	h.mu.Lock()
+	if s := h.servers[h.sm.DataKey()]; s != nil {
+		for _, q := range s.Queries {
+			if len(sugg.Queries) == limit {
+				break
+			}
+			if matches(q.Query) && !strings.EqualFold(q.Query, strings.TrimSpace(query)) {
+				sugg.Queries = append(sugg.Queries, q.Query)
+			}
+		}
+		opened := slices.Clone(s.Opened)
+		sort.SliceStable(opened, func(i, j int) bool {
+			if opened[i].Count != opened[j].Count {
+				return opened[i].Count > opened[j].Count
+			}
+			return opened[i].LastOpened.After(opened[j].LastOpened)
+		})
+		for _, o := range opened {
+			if len(sugg.Results) == limit {
+				break
+			}
+			if matches(o.Result.Name) {
+				r := o.Result
+				sugg.Results = append(sugg.Results, &r)
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	if query == "" || len(sugg.Results) >= limit {
+		return sugg
+	}
+	// fill in with matching favorites, if they have been loaded
+	favs := h.favs.Cached()
+	if favs == nil {
+		return sugg
+	}
+	add := func(r *mediaprovider.SearchResult) bool {
+		if len(sugg.Results) == limit {
+			return false
+		}
+		if matches(r.Name) && !slices.ContainsFunc(sugg.Results, func(s *mediaprovider.SearchResult) bool {
+			return s.Type == r.Type && s.ID == r.ID
+		}) {
+			sugg.Results = append(sugg.Results, r)
+		}
+		return true
+	}
+	for _, ar := range favs.Artists {
+		if !add(&mediaprovider.SearchResult{Type: mediaprovider.ContentTypeArtist, ID: ar.ID, CoverID: ar.CoverArtID, Name: ar.Name, Size: ar.AlbumCount}) {
+			return sugg
+		}
+	}
+	for _, al := range favs.Albums {
+		if !add(&mediaprovider.SearchResult{Type: mediaprovider.ContentTypeAlbum, ID: al.ID, CoverID: al.CoverArtID, Name: al.Name,
+			ArtistName: strings.Join(al.ArtistNames, ", "), Size: al.TrackCount}) {
+			return sugg
+		}
+	}
+	for _, tr := range favs.Tracks {
+		if !add(&mediaprovider.SearchResult{Type: mediaprovider.ContentTypeTrack, ID: tr.ID, CoverID: tr.CoverArtID, Name: tr.Title,
+			ArtistName: strings.Join(tr.ArtistNames, ", "), Size: tr.Duration}) {
+			return sugg
+		}
+	}
+	return sugg
+}
+
+// Clear forgets the search history of the current server.
+func (h *SearchHistory) Clear() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.servers, h.sm.DataKey())
+	h.save()
+}
+
+func (h *SearchHistory) update(fn func(*serverSearchHistory)) {
+	if h.sm.Server == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	id := h.sm.DataKey()
+	s, ok := h.servers[id]
+	if !ok {
+		s = &serverSearchHistory{}
+		h.servers[id] = s
+	}
+	fn(s)
+	h.save()
+}
+
+func (h *SearchHistory) load() error {
+	b, err := os.ReadFile(h.filePath)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &h.servers)
+}
+
+// must be called with h.mu held
+func (h *SearchHistory) save() {
+	b, err := json.Marshal(h.servers)
+	if err == nil {
+		err = os.WriteFile(h.filePath, b, 0644)
+	}
+	if err != nil {
+		log.Printf("error saving search history: %v", err)
+	}
+}
@@ -3,18 +3,24 @@ package backend
 import (
 	"context"
 	"errors"
+	"fmt"
+	"hash/fnv"
 	"log"
 	"net/http"
+	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/dweymouth/go-jellyfin"
 	"github.com/dweymouth/go-subsonic/subsonic"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/demo"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
 	jellyfinMP "github.com/dweymouth/supersonic/backend/mediaprovider/jellyfin"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/netshare"
 	subsonicMP "github.com/dweymouth/supersonic/backend/mediaprovider/subsonic"
 	"github.com/dweymouth/supersonic/res"
 	"github.com/google/uuid"
-	"github.com/zalando/go-keyring"
 )
 
 type ServerManager struct {
@@ -22,18 +28,25 @@ type ServerManager struct {
 	ServerID     uuid.UUID
 	Server       mediaprovider.MediaProvider
 
-	useKeyring        bool
+	creds             CredentialStore
 	prefetchCoverCB   func(string)
+	metrics           *Metrics
+	requestLog        *RequestLog
+	contentFilter     *mediaprovider.ContentFilter
+	network           *NetworkMonitor
 	appName           string
+	cacheDir          string
 	config            *Config
 	onServerConnected []func()
 	onLogout          []func()
+	accountID         uuid.UUID // keyring key of the logged in account
+	switchingAccount  bool
 }
 
 var ErrUnreachable = errors.New("server is unreachable")
 
-func NewServerManager(appName string, config *Config, useKeyring bool) *ServerManager {
-	return &ServerManager{appName: appName, config: config, useKeyring: useKeyring}
+func NewServerManager(appName string, config *Config, creds CredentialStore) *ServerManager {
+	return &ServerManager{appName: appName, config: config, creds: creds}
 }
 
 func (s *ServerManager) SetPrefetchAlbumCoverCallback(cb func(string)) {
@@ -43,15 +56,54 @@ func (s *ServerManager) SetPrefetchAlbumCoverCallback(cb func(string)) {
 	}
 }
 
+// SetMetrics sets the Metrics to record API requests to.
+// Takes effect on the next server connection.
+func (s *ServerManager) SetMetrics(m *Metrics) {
+	s.metrics = m
+}
+
+// SetRequestLog sets the RequestLog to record API requests to.
+// Takes effect on the next server connection.
+func (s *ServerManager) SetRequestLog(l *RequestLog) {
+	s.requestLog = l
+}
+
+// SetContentFilter sets the filter hiding content from the
+// results of the server. Takes effect on the next server connection.
+func (s *ServerManager) SetContentFilter(f *mediaprovider.ContentFilter) {
+	s.contentFilter = f
+}
+
+// SetCacheDir sets the directory where providers which index
+// the library themselves (network shares) keep their index.
+func (s *ServerManager) SetCacheDir(dir string) {
+	s.cacheDir = dir
+}
+
+// SetNetworkMonitor sets the NetworkMonitor which
+// adapts the stream bit rate to network conditions.
+func (s *ServerManager) SetNetworkMonitor(n *NetworkMonitor) {
+	s.network = n
+}
+
 func (s *ServerManager) ConnectToServer(conf *ServerConfig, password string) error {
-	cli, err := s.connect(conf.ServerConnection, password)
+	account := conf.ActiveAccount()
+	cli, err := s.connect(conf.ActiveConnection(), password)
 	if err != nil {
 		return err
 	}
 	s.Server = cli.MediaProvider()
+	if s.contentFilter != nil {
+		s.Server = mediaprovider.NewContentFilteredProvider(s.Server, s.contentFilter)
+	}
+	s.Server = helpers.NewSinglesProvider(s.Server)
 	s.Server.SetPrefetchCoverCallback(s.prefetchCoverCB)
-	s.LoggedInUser = conf.Username
+	if ml, ok := mediaprovider.As[mediaprovider.SupportsMusicLibraries](s.Server); ok && conf.MusicLibraryID != "" {
+		ml.SetMusicLibrary(conf.MusicLibraryID)
+	}
+	s.LoggedInUser = account.Username
 	s.ServerID = conf.ID
+	s.accountID = account.ID
 	s.SetDefaultServer(s.ServerID)
 	for _, cb := range s.onServerConnected {
 		cb()
@@ -88,6 +140,126 @@ func (s *ServerManager) GetDefaultServer() *ServerConfig {
 	return nil
 }
 
+// CurrentServerConfig returns the config of the connected server, or nil.
+func (s *ServerManager) CurrentServerConfig() *ServerConfig {
+	if s.Server == nil {
+		return nil
+	}
+	for _, conf := range s.config.Servers {
+		if conf.ID == s.ServerID {
+			return conf
+		}
+	}
+	return nil
+}
+
+// StreamOptions returns the transcoding options to stream the track with,
+// from the connected server's settings and the transcoding config.
+func (s *ServerManager) StreamOptions(trackID string) mediaprovider.StreamOptions {
+	opts := mediaprovider.StreamOptions{ForceRaw: s.config.Transcoding.ForceRawFile}
+	conf := s.CurrentServerConfig()
+	if conf == nil {
+		return opts
+	}
+	opts.ForceRaw = opts.ForceRaw || slices.Contains(conf.ForceRawTrackIDs, trackID)
+	opts.Format, opts.MaxBitRate = conf.StreamFormat, conf.StreamMaxBitRate
+	if s.config.Transcoding.LowBandwidthMode {
+		if conf.LowBandwidthStreamFormat != "" {
+			opts.Format = conf.LowBandwidthStreamFormat
+		}
+		if conf.LowBandwidthStreamMaxBitRate > 0 {
+			opts.MaxBitRate = conf.LowBandwidthStreamMaxBitRate
+		}
+	}
+	if s.network != nil {
+		if r := s.network.MaxBitRate(); r > 0 && (opts.MaxBitRate == 0 || r < opts.MaxBitRate) {
+			opts.MaxBitRate = r
+		}
+	}
+	return opts
+}
+
+// GetStreamURL returns the URL to stream the track from the
+// connected server, with the options from StreamOptions.
+func (s *ServerManager) GetStreamURL(trackID string) (string, error) {
+	return s.streamURL(trackID, s.StreamOptions(trackID))
+}
+
+func (s *ServerManager) streamURL(trackID string, opts mediaprovider.StreamOptions) (string, error) {
+	if s.Server == nil {
+		return "", errors.New("not connected to a server")
+	}
+	if so, ok := mediaprovider.As[mediaprovider.SupportsStreamOptions](s.Server); ok {
+		return so.GetStreamURLWithOptions(trackID, opts)
+	}
+	return s.Server.GetStreamURL(trackID, opts.ForceRaw)
+}
+
+// StreamHeaders returns the HTTP headers to request the
+// track's stream URL with, or nil if none are needed.
+func (s *ServerManager) StreamHeaders(trackID string) http.Header {
+	if sh, ok := mediaprovider.As[mediaprovider.SupportsStreamHeaders](s.Server); ok {
+		return sh.StreamHeaders(trackID)
+	}
+	return nil
+}
+
+// SetTracksForceRaw sets whether the tracks are always streamed
+// from the connected server as the original file.
+func (s *ServerManager) SetTracksForceRaw(trackIDs []string, forceRaw bool) {
+	conf := s.CurrentServerConfig()
+	if conf == nil {
+		return
+	}
+	conf.ForceRawTrackIDs = slices.DeleteFunc(conf.ForceRawTrackIDs, func(id string) bool {
+		return slices.Contains(trackIDs, id)
+	})
+	if forceRaw {
+		conf.ForceRawTrackIDs = append(conf.ForceRawTrackIDs, trackIDs...)
+	}
+}
+
+// IsTrackForceRaw returns whether the track is always streamed
+// from the connected server as the original file.
+func (s *ServerManager) IsTrackForceRaw(trackID string) bool {
+	conf := s.CurrentServerConfig()
+	return conf != nil && slices.Contains(conf.ForceRawTrackIDs, trackID)
+}
+
+// SetPlaylistScrobbled sets whether tracks played from the
+// playlist on the connected server are scrobbled.
+func (s *ServerManager) SetPlaylistScrobbled(playlistID string, scrobbled bool) {
+	conf := s.CurrentServerConfig()
+	if conf == nil {
+		return
+	}
+	conf.NoScrobblePlaylistIDs = slices.DeleteFunc(conf.NoScrobblePlaylistIDs, func(id string) bool {
+		return id == playlistID
+	})
+	if !scrobbled {
+		conf.NoScrobblePlaylistIDs = append(conf.NoScrobblePlaylistIDs, playlistID)
+	}
+}
+
+// IsPlaylistScrobbled returns whether tracks played from the
+// playlist on the connected server are scrobbled.
+func (s *ServerManager) IsPlaylistScrobbled(playlistID string) bool {
+	conf := s.CurrentServerConfig()
+	return conf == nil || !slices.Contains(conf.NoScrobblePlaylistIDs, playlistID)
+}
+
+// SetMusicLibrary scopes the connected server to the given music library
+// ("" for all) and remembers the choice for the server. The server must
+// implement mediaprovider.SupportsMusicLibraries.
+func (s *ServerManager) SetMusicLibrary(id string) {
+	if ml, ok := mediaprovider.As[mediaprovider.SupportsMusicLibraries](s.Server); ok {
+		ml.SetMusicLibrary(id)
+		if conf := s.CurrentServerConfig(); conf != nil {
+			conf.MusicLibraryID = id
+		}
+	}
+}
+
 func (s *ServerManager) SetDefaultServer(serverID uuid.UUID) {
 	var found bool
 	for _, s := range s.config.Servers {
@@ -115,9 +287,13 @@ func (s *ServerManager) AddServer(nickname string, connection ServerConnection)
 func (s *ServerManager) DeleteServer(serverID uuid.UUID) {
 	s.deleteServerPassword(serverID)
 	newServers := make([]*ServerConfig, 0, len(s.config.Servers)-1)
-	for _, s := range s.config.Servers {
-		if s.ID != serverID {
-			newServers = append(newServers, s)
+	for _, conf := range s.config.Servers {
+		if conf.ID != serverID {
+			newServers = append(newServers, conf)
+		} else {
+			for _, a := range conf.Accounts {
+				s.creds.Delete(a.ID.String())
+			}
 		}
 	}
 	s.config.Servers = newServers
@@ -126,7 +302,9 @@ func (s *ServerManager) DeleteServer(serverID uuid.UUID) {
 func (s *ServerManager) Logout(deletePassword bool) {
 	if s.Server != nil {
 		if deletePassword {
-			s.deleteServerPassword(s.ServerID)
+			// the active account's password, keyed by
+			// the server ID for the primary account
+			s.deleteServerPassword(s.accountID)
 		}
 		for _, cb := range s.onLogout {
 			cb()
@@ -134,13 +312,80 @@ func (s *ServerManager) Logout(deletePassword bool) {
 		s.Server = nil
 		s.LoggedInUser = ""
 		s.ServerID = uuid.UUID{}
+		s.accountID = uuid.UUID{}
 	}
 }
 
-func (s *ServerManager) deleteServerPassword(serverID uuid.UUID) {
-	if s.useKeyring {
-		keyring.Delete(s.appName, s.ServerID.String())
+// DataKey returns the key to store local data of the logged in user
+// under, e.g. search history and pinned playlists: the server ID for the
+// server's primary account, or the server and account IDs for another.
+func (s *ServerManager) DataKey() string {
+	if s.accountID == s.ServerID {
+		return s.ServerID.String()
+	}
+	return s.ServerID.String() + "/" + s.accountID.String()
+}
+
+// AddAccount adds an additional user account to the server config,
+// saving its password to the credential store. The account is added even
+// if saving the password fails, in which case an error is also returned.
+func (s *ServerManager) AddAccount(conf *ServerConfig, username, password string) (ServerAccount, error) {
+	account := ServerAccount{ID: uuid.New(), Username: username}
+	conf.Accounts = append(conf.Accounts, account)
+	return account, s.creds.Set(account.ID.String(), password)
+}
+
+// DeleteAccount removes an additional user account from the server config.
+// If it was the active account, the primary account becomes active.
+func (s *ServerManager) DeleteAccount(conf *ServerConfig, accountID uuid.UUID) {
+	s.creds.Delete(accountID.String())
+	conf.Accounts = slices.DeleteFunc(conf.Accounts, func(a ServerAccount) bool { return a.ID == accountID })
+	if conf.ActiveAccountID == accountID {
+		conf.ActiveAccountID = uuid.UUID{}
+	}
+}
+
+// SwitchAccount logs out of the connected server and logs back in
+// as the given account of it (the server ID for the primary account),
+// with the saved password if password is "". The logout
+// callbacks are invoked as usual, with SwitchingAccount returning true.
+// If logging in as the account fails, the current login is kept.
+func (s *ServerManager) SwitchAccount(ctx context.Context, accountID uuid.UUID, password string) error {
+	conf := s.CurrentServerConfig()
+	if conf == nil {
+		return ErrNoServers
 	}
+	prevAccountID := conf.ActiveAccountID
+	conf.ActiveAccountID = accountID
+	if accountID == conf.ID {
+		conf.ActiveAccountID = uuid.UUID{}
+	}
+	if password == "" {
+		var err error
+		if password, err = s.GetServerPassword(conf.ActiveAccount().ID); err != nil {
+			conf.ActiveAccountID = prevAccountID
+			return err
+		}
+	}
+	if err := s.TestConnectionAndAuth(ctx, conf.ActiveConnection(), password); err != nil {
+		conf.ActiveAccountID = prevAccountID
+		return err
+	}
+
+	s.switchingAccount = true
+	s.Logout(false)
+	s.switchingAccount = false
+	return s.ConnectToServer(conf, password)
+}
+
+// SwitchingAccount returns true while the logout
+// callbacks are invoked for switching accounts.
+func (s *ServerManager) SwitchingAccount() bool {
+	return s.switchingAccount
+}
+
+func (s *ServerManager) deleteServerPassword(serverID uuid.UUID) {
+	s.creds.Delete(serverID.String())
 }
 
 // Sets a callback that is invoked when a server is connected to.
@@ -154,60 +399,89 @@ func (s *ServerManager) OnLogout(cb func()) {
 }
 
 func (s *ServerManager) GetServerPassword(serverID uuid.UUID) (string, error) {
-	if s.useKeyring {
-		return keyring.Get(s.appName, serverID.String())
-	}
-	return "", errors.New("keyring not enabled")
+	return s.creds.Get(serverID.String())
 }
 
 func (s *ServerManager) SetServerPassword(server *ServerConfig, password string) error {
-	if s.useKeyring {
-		return keyring.Set(s.appName, server.ID.String(), password)
+	return s.creds.Set(server.ID.String(), password)
+}
+
+func (s *ServerManager) newHTTPClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Second, Transport: s.transport()}
+}
+
+// transport returns the transport for API requests, recording them
+// in the Metrics and the RequestLog.
+func (s *ServerManager) transport() http.RoundTripper {
+	return s.requestLog.Transport(s.metrics.Transport(nil))
+}
+
+// connectNetworkShare checks that the share is reachable.
+// Shares have no alternate hostname to race against.
+func (s *ServerManager) connectNetworkShare(connection ServerConnection, password string) (mediaprovider.Server, error) {
+	h := fnv.New64a()
+	h.Write([]byte(connection.Hostname + "\x00" + connection.Username))
+	share := &netshare.Server{
+		URL:       connection.Hostname,
+		IndexPath: filepath.Join(s.cacheDir, "shares", fmt.Sprintf("%x.json", h.Sum64())),
+		Transport: s.transport(),
 	}
-	return errors.New("keyring not available")
+	resp := share.Login(connection.Username, password)
+	return share, resp.Error
 }
 
 func (s *ServerManager) connect(connection ServerConnection, password string) (mediaprovider.Server, error) {
+	if connection.ServerType == ServerTypeDemo {
+		return &demo.Server{Seed: demo.DefaultSeed}, nil
+	}
+	if connection.ServerType == ServerTypeNetworkShare {
+		return s.connectNetworkShare(connection, password)
+	}
+
 	var cli, altCli mediaprovider.Server
 
 	if connection.ServerType == ServerTypeJellyfin {
-		client, err := jellyfin.NewClient(connection.Hostname, res.AppName, res.AppVersion, jellyfin.WithTimeout(10*time.Second))
+		client, err := jellyfin.NewClient(connection.Hostname, res.AppName, res.AppVersion, jellyfin.WithHTTPClient(s.newHTTPClient()))
 		if err != nil {
 			log.Printf("error creating Jellyfin client: %s", err.Error())
 			return nil, err
 		}
 		cli = &jellyfinMP.JellyfinServer{
-			Client: *client,
+			Client:            *client,
+			ForceDirectStream: connection.ForceDirectStream,
 		}
 
 		if connection.AltHostname != "" {
-			altClient, err := jellyfin.NewClient(connection.AltHostname, res.AppName, res.AppVersion, jellyfin.WithTimeout(10*time.Second))
+			altClient, err := jellyfin.NewClient(connection.AltHostname, res.AppName, res.AppVersion, jellyfin.WithHTTPClient(s.newHTTPClient()))
 			if err != nil {
 				log.Printf("error creating Jellyfin alternative client: %s", err.Error())
 				return nil, err
 			}
 			altCli = &jellyfinMP.JellyfinServer{
-				Client: *altClient,
+				Client:            *altClient,
+				ForceDirectStream: connection.ForceDirectStream,
 			}
 		}
 	} else {
 		cli = &subsonicMP.SubsonicServer{
 			Client: subsonic.Client{
-				Client:       &http.Client{Timeout: 10 * time.Second},
+				Client:       s.newHTTPClient(),
 				BaseUrl:      connection.Hostname,
 				User:         connection.Username,
 				PasswordAuth: connection.LegacyAuth,
 				ClientName:   res.AppName,
 			},
+			AllowPasswordAuthFallback: connection.AllowLegacyAuthFallback,
 		}
 		altCli = &subsonicMP.SubsonicServer{
 			Client: subsonic.Client{
-				Client:       &http.Client{Timeout: 10 * time.Second},
+				Client:       s.newHTTPClient(),
 				BaseUrl:      connection.AltHostname,
 				User:         connection.Username,
 				PasswordAuth: connection.LegacyAuth,
 				ClientName:   res.AppName,
 			},
+			AllowPasswordAuthFallback: connection.AllowLegacyAuthFallback,
 		}
 	}
 	var authError error
@@ -0,0 +1,43 @@
+package backend
+
+import "slices"
+
+// The effective settings of the connected server, which are the
+// global settings with the server's ServerSettingsOverrides applied.
+// They are resolved on each call, so changes to either take effect immediately.
+
+// ScrobbleConfig returns the effective scrobble settings.
+func (s *ServerManager) ScrobbleConfig() ScrobbleConfig {
+	cfg := s.config.Scrobbling
+	conf := s.CurrentServerConfig()
+	if conf == nil {
+		return cfg
+	}
+	o := conf.Overrides
+	if o.ScrobbleEnabled != nil {
+		cfg.Enabled = *o.ScrobbleEnabled
+	}
+	if o.ScrobbleThresholdPercent != nil {
+		cfg.ThresholdPercent = clamp(*o.ScrobbleThresholdPercent, 0, 99)
+	}
+	if o.ScrobbleThresholdSeconds != nil {
+		cfg.ThresholdTimeSeconds = *o.ScrobbleThresholdSeconds
+	}
+	return cfg
+}
+
+// MaxTrackCacheSizeMB returns the effective size limit of the track cache.
+func (s *ServerManager) MaxTrackCacheSizeMB() int {
+	if conf := s.CurrentServerConfig(); conf != nil && conf.Overrides.MaxTrackCacheSizeMB != nil {
+		return *conf.Overrides.MaxTrackCacheSizeMB
+	}
+	return s.config.LocalPlayback.MaxTrackCacheSizeMB
+}
+
+// HomeSections returns the effective sections of the home page.
+func (s *ServerManager) HomeSections() []string {
+	if conf := s.CurrentServerConfig(); conf != nil && conf.Overrides.HomeSections != nil {
+		return slices.Clone(conf.Overrides.HomeSections)
+	}
+	return slices.Clone(s.config.Home.Sections)
+}
@@ -0,0 +1,148 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/dweymouth/supersonic/backend/player/mpv"
+)
+
+// SignalPathStage is a single stage in the signal path of the current playback.
+type SignalPathStage struct {
+	// Name of the stage, e.g. "Source", "Transcode", "ReplayGain"
+	Name string
+
+	// Human-readable description of what happens at this stage
+	Description string
+
+	// Whether this stage alters the audio signal
+	// (i.e. the playback is not bit-perfect if true)
+	Lossy bool
+}
+
+// SignalPath returns the stages the audio of the currently playing track
+// passes through, from the source file on the server to the output device.
+// Returns nil if nothing is playing.
+func (p *PlaybackManager) SignalPath() []SignalPathStage {
+	tr, ok := p.NowPlaying().(*mediaprovider.Track)
+	if !ok || p.PlayerStatus().State == player.Stopped {
+		return nil
+	}
+
+	source := SignalPathStage{Name: "Source"}
+	srcFormat := strings.TrimPrefix(strings.ToLower(filepath.Ext(tr.FilePath)), ".")
+	source.Description = formatCodecAndBitrate(srcFormat, tr.BitRate)
+	stages := []SignalPathStage{source}
+
+	mpvP, isMPV := p.CurrentPlayer().(*mpv.Player)
+	if !isMPV {
+		// remote players (e.g. Jukebox) handle the signal path on the server
+		return append(stages, SignalPathStage{Name: "Output", Description: "Remote player"})
+	}
+
+	if info, err := mpvP.GetMediaInfo(); err == nil {
+		decoded := SignalPathStage{Name: "Stream"}
+		transcoded := !p.engine.sm.StreamOptions(tr.ID).ForceRaw && srcFormat != "" &&
+			!strings.EqualFold(info.Codec, srcFormat) && !codecMatchesContainer(info.Codec, srcFormat)
+		if transcoded {
+			decoded.Name = "Transcode"
+			decoded.Lossy = true
+		}
+		decoded.Description = fmt.Sprintf("%s, %g kHz, %d ch",
+			formatCodecAndBitrate(info.Codec, info.Bitrate/1000),
+			float64(info.Samplerate)/1000, info.ChannelCount)
+		stages = append(stages, decoded)
+	}
+
+	if mpvP.BitPerfect() {
+		// volume, ReplayGain and all filters are bypassed
+		return append(stages, outputStage(mpvP, "exclusive, bit-perfect")...)
+	}
+
+	if ss := mpvP.SkipSilenceOptions(); ss.Enabled {
+		stages = append(stages, SignalPathStage{
+			Name:        "Skip silence",
+			Description: fmt.Sprintf("Below %0.0f dB, longer than %g s", ss.ThresholdDB, ss.MaxGapSeconds),
+			Lossy:       true,
+		})
+	}
+
+	if crg := mpvP.ClientReplayGain(); crg.Enabled {
+		desc := fmt.Sprintf("%0.2f dB from server metadata", crg.GainDB)
+		if crg.Normalize {
+			desc = "Loudness normalization (no ReplayGain metadata)"
+		}
+		stages = append(stages, SignalPathStage{Name: "ReplayGain", Description: desc, Lossy: true})
+	} else if rg := mpvP.ReplayGainOptions(); rg.Mode != player.ReplayGainNone {
+		desc := fmt.Sprintf("%s gain, preamp %0.1f dB", rg.Mode.String(), rg.PreampGain)
+		if rg.PreventClipping {
+			desc += ", clipping prevention"
+		}
+		stages = append(stages, SignalPathStage{Name: "ReplayGain", Description: desc, Lossy: true})
+	}
+
+	if eq := mpvP.Equalizer(); eq != nil && eq.IsEnabled() {
+		stages = append(stages, SignalPathStage{
+			Name:        "Equalizer",
+			Description: fmt.Sprintf("%s, preamp %0.1f dB", eq.Type(), eq.Preamp()),
+			Lossy:       true,
+		})
+	}
+
+	if mpvP.MonoDownmix() {
+		stages = append(stages, SignalPathStage{Name: "Mono downmix", Description: "All channels mixed to mono", Lossy: true})
+	}
+	if mpvP.OutputLimiter() {
+		stages = append(stages, SignalPathStage{Name: "Limiter", Description: "Peak limiter at -0.1 dBFS", Lossy: true})
+	}
+
+	return append(stages, outputStage(mpvP, "")...)
+}
+
+// outputStage returns the Output stage, with the note appended to its description
+// if non-empty, or nil if the output info isn't available.
+func outputStage(mpvP *mpv.Player, note string) []SignalPathStage {
+	out, err := mpvP.GetOutputInfo()
+	if err != nil {
+		return nil
+	}
+	desc := fmt.Sprintf("%s: %s, %g kHz, %d ch",
+		out.Device, out.Format, float64(out.Samplerate)/1000, out.ChannelCount)
+	if note != "" {
+		desc += " (" + note + ")"
+	}
+	return []SignalPathStage{{Name: "Output", Description: desc}}
+}
+
+func formatCodecAndBitrate(codec string, kbps int) string {
+	if codec == "" {
+		codec = "Unknown"
+	} else if len(codec) <= 4 && !strings.EqualFold(codec, "opus") {
+		codec = strings.ToUpper(codec) // FLAC, MP3, AAC, etc
+	}
+	if kbps <= 0 {
+		return codec
+	}
+	return fmt.Sprintf("%s %d kbps", codec, kbps)
+}
+
+// codecMatchesContainer returns true if the decoded codec name
+// as reported by the player is the expected codec for the given file extension.
+func codecMatchesContainer(codec, ext string) bool {
+	switch ext {
+	case "m4a", "mp4", "m4b":
+		return strings.EqualFold(codec, "aac") || strings.EqualFold(codec, "alac")
+	case "ogg", "oga":
+		return strings.EqualFold(codec, "vorbis") || strings.EqualFold(codec, "opus") || strings.EqualFold(codec, "flac")
+	case "wav", "aiff", "aif":
+		return strings.HasPrefix(strings.ToLower(codec), "pcm")
+	case "wv":
+		return strings.EqualFold(codec, "wavpack")
+	case "ape":
+		return strings.EqualFold(codec, "ape")
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package backend
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+)
+
+type SleepTimerMode int
+
+const (
+	SleepTimerOff SleepTimerMode = iota
+	// stop once the timer's duration has elapsed
+	SleepTimerDuration
+	// stop at the end of the track playing when the timer was set
+	SleepTimerEndOfTrack
+	// stop at the end of the album playing when the timer was set
+	SleepTimerEndOfAlbum
+)
+
+// SleepTimerState is the state of the sleep timer, for display in the UI.
+type SleepTimerState struct {
+	Mode SleepTimerMode
+	// Estimated time until playback stops. For the end of track and album
+	// modes, this is the remaining play time, which doesn't elapse while paused.
+	Remaining time.Duration
+	// Whether the volume is currently fading out before stopping
+	FadingOut bool
+}
+
+// SleepTimer stops playback after a duration, or at the end of the
+// current track or album, fading out the volume over the last seconds.
+type SleepTimer struct {
+	pm  *PlaybackManager
+	cfg *SleepTimerConfig
+
+	mu        sync.Mutex
+	mode      SleepTimerMode
+	deadline  time.Time               // for SleepTimerDuration
+	stopAfter mediaprovider.MediaItem // for SleepTimerEndOfTrack/Album
+	timers    []*time.Timer           // fade and stop timers for SleepTimerDuration
+	fadingOut bool
+	onChange  []func(SleepTimerState)
+}
+
+func NewSleepTimer(pm *PlaybackManager, cfg *SleepTimerConfig) *SleepTimer {
+	s := &SleepTimer{pm: pm, cfg: cfg}
+	pm.OnPlayTimeUpdate(func(cur, total float64, _ bool) {
+		s.checkFadeOut(total - cur)
+	})
+	pm.OnStopped(func() {
+		s.mu.Lock()
+		active := s.mode != SleepTimerOff
+		s.resetLocked()
+		s.mu.Unlock()
+		if active {
+			s.invokeOnChange()
+		}
+	})
+	return s
+}
+
+// OnChange registers a callback invoked when the sleep timer is set, cancelled, or expires.
+func (s *SleepTimer) OnChange(cb func(SleepTimerState)) {
+	s.onChange = append(s.onChange, cb)
+}
+
+// StopAfter sets the timer to stop playback once the duration has elapsed.
+func (s *SleepTimer) StopAfter(dur time.Duration) {
+	s.mu.Lock()
+	hadStopAfter := s.cancelLocked()
+	s.mode = SleepTimerDuration
+	s.deadline = time.Now().Add(dur)
+	fadeStart := max(dur-s.fadeDuration(), 0)
+	s.timers = []*time.Timer{
+		time.AfterFunc(fadeStart, func() { s.fadeOut(dur - fadeStart) }),
+		time.AfterFunc(dur, func() { _ = s.pm.Stop() }),
+	}
+	s.mu.Unlock()
+	if hadStopAfter {
+		s.pm.engine.setStopAfterItem(nil)
+	}
+	s.invokeOnChange()
+}
+
+// StopAtEndOfTrack sets the timer to stop playback at the end of the current track.
+func (s *SleepTimer) StopAtEndOfTrack() {
+	queue := s.pm.GetPlayQueue()
+	idx := s.pm.NowPlayingIndex()
+	if idx < 0 || idx >= len(queue) {
+		return
+	}
+	s.stopAfterItem(SleepTimerEndOfTrack, queue[idx])
+}
+
+// StopAtEndOfAlbum sets the timer to stop playback after the last track in the queue,
+// following the current one, that is from the same album.
+func (s *SleepTimer) StopAtEndOfAlbum() {
+	queue := s.pm.GetPlayQueue()
+	idx := s.pm.NowPlayingIndex()
+	if idx < 0 || idx >= len(queue) {
+		return
+	}
+	last := albumEndIndex(queue, idx)
+	s.stopAfterItem(SleepTimerEndOfAlbum, queue[last])
+}
+
+func (s *SleepTimer) stopAfterItem(mode SleepTimerMode, item mediaprovider.MediaItem) {
+	s.mu.Lock()
+	s.cancelLocked()
+	s.mode = mode
+	s.stopAfter = item
+	s.mu.Unlock()
+	s.pm.engine.setStopAfterItem(item)
+	s.invokeOnChange()
+}
+
+// Cancel cancels the sleep timer, restoring the volume if it was fading out.
+func (s *SleepTimer) Cancel() {
+	s.mu.Lock()
+	if s.mode == SleepTimerOff {
+		s.mu.Unlock()
+		return
+	}
+	hadStopAfter := s.cancelLocked()
+	s.mu.Unlock()
+	if hadStopAfter {
+		s.pm.engine.setStopAfterItem(nil)
+	}
+	s.invokeOnChange()
+}
+
+// State returns the current state of the sleep timer.
+func (s *SleepTimer) State() SleepTimerState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := SleepTimerState{Mode: s.mode, FadingOut: s.fadingOut}
+	switch s.mode {
+	case SleepTimerDuration:
+		state.Remaining = max(time.Until(s.deadline), 0)
+	case SleepTimerEndOfTrack, SleepTimerEndOfAlbum:
+		state.Remaining = s.remainingPlayTimeLocked()
+	}
+	return state
+}
+
+// remainingPlayTimeLocked returns the play time until the end of the stopAfter item.
+// must be called with s.mu held
+func (s *SleepTimer) remainingPlayTimeLocked() time.Duration {
+	status := s.pm.PlayerStatus()
+	queue := s.pm.GetPlayQueue()
+	idx := s.pm.NowPlayingIndex()
+	if status.State == player.Stopped || idx < 0 || idx >= len(queue) {
+		return 0
+	}
+	secs := max(status.Duration-status.TimePos, 0)
+	for i := idx; i < len(queue) && queue[i] != s.stopAfter; i++ {
+		if i+1 < len(queue) {
+			secs += float64(queue[i+1].Metadata().Duration)
+		}
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// checkFadeOut begins fading out once the last track is within
+// the fade duration of its end.
+func (s *SleepTimer) checkFadeOut(trackRemainingSecs float64) {
+	s.mu.Lock()
+	if s.stopAfter == nil || s.fadingOut || s.pm.NowPlaying() != s.stopAfter {
+		s.mu.Unlock()
+		return
+	}
+	remaining := time.Duration(trackRemainingSecs * float64(time.Second))
+	if remaining <= 0 || remaining > s.fadeDuration() {
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	s.fadeOut(remaining)
+}
+
+func (s *SleepTimer) fadeOut(dur time.Duration) {
+	cf := s.pm.engine.trackFader
+	if dur <= 0 || !cf.supported || s.pm.PlayerStatus().State != player.Playing {
+		return
+	}
+	s.mu.Lock()
+	s.fadingOut = true
+	s.mu.Unlock()
+	// the volume is restored by the trackFader when playback stops
+	cf.FadeOut(dur, nil)
+	s.invokeOnChange()
+}
+
+func (s *SleepTimer) fadeDuration() time.Duration {
+	return time.Duration(s.cfg.FadeOutSeconds * float64(time.Second))
+}
+
+// cancelLocked cancels the timer, and returns true if it was stopping after
+// a queue item, which the caller must clear from the playback engine.
+// must be called with s.mu held
+func (s *SleepTimer) cancelLocked() bool {
+	if s.fadingOut {
+		s.pm.engine.trackFader.Cancel()
+	}
+	hadStopAfter := s.stopAfter != nil
+	s.resetLocked()
+	return hadStopAfter
+}
+
+// must be called with s.mu held
+func (s *SleepTimer) resetLocked() {
+	for _, t := range s.timers {
+		t.Stop()
+	}
+	s.timers = nil
+	s.mode = SleepTimerOff
+	s.stopAfter = nil
+	s.fadingOut = false
+}
+
+func (s *SleepTimer) invokeOnChange() {
+	state := s.State()
+	for _, cb := range s.onChange {
+		cb(state)
+	}
+}
+
+// albumEndIndex returns the index of the last item of the run of
+// queue items from the same album as the item at idx.
+func albumEndIndex(queue []mediaprovider.MediaItem, idx int) int {
+	albumID := queue[idx].Metadata().AlbumID
+	if albumID == "" {
+		return idx
+	}
+	last := idx
+	for last+1 < len(queue) && queue[last+1].Metadata().AlbumID == albumID {
+		last++
+	}
+	return last
+}
@@ -0,0 +1,283 @@
+package backend
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
+	"github.com/dweymouth/supersonic/sharedutil"
+	"github.com/google/uuid"
+)
+
+const (
+	// max number of tracks scanned when evaluating a smart playlist,
+	// to bound the time and server load for huge libraries
+	smartPlaylistMaxScan      = 20000
+	smartPlaylistDefaultLimit = 500
+)
+
+// SmartPlaylist is a saved set of rules which select tracks from the library.
+// It is re-evaluated every time it's played, and can be materialized
+// into (and kept in sync with) a regular playlist on the server.
+// All rules must match; zero-valued rules match any track.
+type SmartPlaylist struct {
+	ID       string
+	ServerID string // ServerManager.DataKey of the server and account it belongs to
+	Name     string
+
+	Genres          []string // track genre is any of
+	MinYear         int
+	MaxYear         int
+	MinRating       int
+	FavoritesOnly   bool
+	NotPlayedInDays int // based on the local listening history and the server's last played time
+	AddedInDays     int // added to the library within this many days
+
+	Limit   int  // max number of tracks, 0 for the default
+	Shuffle bool // pick tracks randomly rather than in library order
+
+	// ID of the server playlist this was last materialized to, if any
+	MaterializedPlaylistID string
+}
+
+func (s *SmartPlaylist) limit() int {
+	if s.Limit > 0 {
+		return s.Limit
+	}
+	return smartPlaylistDefaultLimit
+}
+
+// Matches returns whether the track matches the playlist's rules.
+// NotPlayedInDays is checked only against the track's LastPlayed time;
+// Evaluate also checks the local listening history.
+func (s *SmartPlaylist) Matches(tr *mediaprovider.Track) bool {
+	if len(s.Genres) > 0 && !slices.ContainsFunc(s.Genres, func(g string) bool {
+		return slices.ContainsFunc(tr.Genres, func(tg string) bool { return strings.EqualFold(g, tg) })
+	}) {
+		return false
+	}
+	if (s.MinYear > 0 && tr.Year < s.MinYear) || (s.MaxYear > 0 && tr.Year > s.MaxYear) {
+		return false
+	}
+	if s.MinRating > 0 && tr.Rating < s.MinRating {
+		return false
+	}
+	if s.NotPlayedInDays > 0 && tr.LastPlayed.After(time.Now().AddDate(0, 0, -s.NotPlayedInDays)) {
+		return false
+	}
+	if s.AddedInDays > 0 && (tr.CreatedAt.IsZero() || tr.CreatedAt.Before(time.Now().AddDate(0, 0, -s.AddedInDays))) {
+		return false
+	}
+	return !s.FavoritesOnly || tr.Favorite
+}
+
+// SmartPlaylistManager stores smart playlists in the config
+// and evaluates them against the current server.
+type SmartPlaylistManager struct {
+	sm      *ServerManager
+	history *ListeningHistory
+	config  *Config
+
+	mu sync.Mutex // guards config.SmartPlaylists
+}
+
+func NewSmartPlaylistManager(sm *ServerManager, history *ListeningHistory, config *Config) *SmartPlaylistManager {
+	return &SmartPlaylistManager{sm: sm, history: history, config: config}
+}
+
+// SmartPlaylists returns the smart playlists for the current server.
+func (m *SmartPlaylistManager) SmartPlaylists() []*SmartPlaylist {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	serverID := m.sm.DataKey()
+	return sharedutil.FilterSlice(m.config.SmartPlaylists, func(s *SmartPlaylist) bool {
+		return s.ServerID == serverID
+	})
+}
+
+// Save adds a new smart playlist for the current server, or updates an existing one.
+func (m *SmartPlaylistManager) Save(sp *SmartPlaylist) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if sp.ID == "" {
+		sp.ID = uuid.NewString()
+		sp.ServerID = m.sm.DataKey()
+	}
+	for i, existing := range m.config.SmartPlaylists {
+		if existing.ID == sp.ID {
+			m.config.SmartPlaylists[i] = sp
+			return
+		}
+	}
+	m.config.SmartPlaylists = append(m.config.SmartPlaylists, sp)
+}
+
+// Delete removes the smart playlist. A materialized server playlist is kept.
+func (m *SmartPlaylistManager) Delete(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config.SmartPlaylists = slices.DeleteFunc(m.config.SmartPlaylists, func(s *SmartPlaylist) bool {
+		return s.ID == id
+	})
+}
+
+// Evaluate returns the tracks currently matching the smart playlist.
+func (m *SmartPlaylistManager) Evaluate(sp *SmartPlaylist) ([]*mediaprovider.Track, error) {
+	server := m.sm.Server
+	if server == nil {
+		return nil, errors.New("not connected to a server")
+	}
+	var recentlyPlayed map[string]bool
+	if sp.NotPlayedInDays > 0 {
+		recentlyPlayed = make(map[string]bool)
+		since := time.Now().AddDate(0, 0, -sp.NotPlayedInDays)
+		for _, r := range m.history.Records(since, time.Time{}) {
+			recentlyPlayed[r.TrackID] = true
+		}
+	}
+
+	var matches []*mediaprovider.Track
+	limit := sp.limit()
+	add := func(tr *mediaprovider.Track) bool {
+		if sp.Matches(tr) && !recentlyPlayed[tr.ID] {
+			matches = append(matches, tr)
+		}
+		// without shuffling, the first matches are the result
+		return sp.Shuffle || len(matches) < limit
+	}
+	if err := m.scanCandidates(server, sp, add); err != nil {
+		return nil, err
+	}
+
+	if sp.Shuffle {
+		rand.Shuffle(len(matches), func(i, j int) { matches[i], matches[j] = matches[j], matches[i] })
+	}
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// scanCandidates narrows the tracks to scan using the server's filtering
+// where possible, calling add for each until it returns false.
+func (m *SmartPlaylistManager) scanCandidates(server mediaprovider.MediaProvider, sp *SmartPlaylist, add func(*mediaprovider.Track) bool) error {
+	if sp.FavoritesOnly {
+		if fi, ok := mediaprovider.As[mediaprovider.SupportsFavoriteTrackIterator](server); ok {
+			iter := fi.IterateFavoriteTracks(mediaprovider.FavoritesSortDefault)
+			for tr := iter.Next(); tr != nil; tr = iter.Next() {
+				if !add(tr) {
+					break
+				}
+			}
+			return nil
+		}
+		fav, err := server.GetFavorites(mediaprovider.FavoritesSortDefault)
+		if err != nil {
+			return err
+		}
+		for _, tr := range fav.Tracks {
+			if !add(tr) {
+				break
+			}
+		}
+		return nil
+	}
+
+	scanned := 0
+	if len(sp.Genres) > 0 || sp.MinYear > 0 || sp.MaxYear > 0 {
+		// album genre and year usually match their tracks',
+		// so let the server filter by album
+		filter := mediaprovider.NewAlbumFilter(mediaprovider.AlbumFilterOptions{
+			Genres:  sp.Genres,
+			MinYear: sp.MinYear,
+			MaxYear: sp.MaxYear,
+		})
+		iter := server.IterateAlbums(server.AlbumSortOrders()[0], filter)
+		for al := iter.Next(); al != nil && scanned < smartPlaylistMaxScan; al = iter.Next() {
+			album, err := helpers.GetAlbum(server, al.ID)
+			if err != nil {
+				log.Printf("smart playlist: error getting album: %v", err)
+				continue
+			}
+			for _, tr := range album.Tracks {
+				scanned++
+				if !add(tr) {
+					return nil
+				}
+			}
+		}
+		return nil
+	}
+
+	iter := server.IterateTracks("")
+	for tr := iter.Next(); tr != nil && scanned < smartPlaylistMaxScan; tr = iter.Next() {
+		scanned++
+		if !add(tr) {
+			break
+		}
+	}
+	return nil
+}
+
+// Materialize evaluates the smart playlist and writes the result to
+// a regular server playlist with the same name, creating it the first time.
+func (m *SmartPlaylistManager) Materialize(sp *SmartPlaylist) error {
+	server := m.sm.Server
+	if server == nil {
+		return errors.New("not connected to a server")
+	}
+	tracks, err := m.Evaluate(sp)
+	if err != nil {
+		return err
+	}
+	ids := sharedutil.TracksToIDs(tracks)
+
+	existing, err := server.GetPlaylists()
+	if err != nil {
+		return err
+	}
+	existingIDs := sharedutil.ToSet(sharedutil.MapSlice(existing, func(pl *mediaprovider.Playlist) string { return pl.ID }))
+	if sp.MaterializedPlaylistID != "" {
+		if _, ok := existingIDs[sp.MaterializedPlaylistID]; ok {
+			return server.ReplacePlaylistTracks(sp.MaterializedPlaylistID, ids)
+		}
+		// deleted on the server; create it again
+	}
+	if err := server.CreatePlaylist(sp.Name, ids); err != nil {
+		return err
+	}
+	// CreatePlaylist doesn't return the new ID, so find the playlist
+	// which wasn't there before, rather than one which has the same name
+	playlists, err := server.GetPlaylists()
+	if err != nil {
+		return err
+	}
+	for _, pl := range playlists {
+		if _, ok := existingIDs[pl.ID]; !ok && pl.Name == sp.Name {
+			m.mu.Lock()
+			sp.MaterializedPlaylistID = pl.ID
+			m.mu.Unlock()
+			return nil
+		}
+	}
+	return errors.New("created playlist not found on the server")
+}
+
+// RefreshMaterialized re-evaluates all materialized smart playlists
+// for the current server and updates their server playlists.
+func (m *SmartPlaylistManager) RefreshMaterialized() {
+	for _, sp := range m.SmartPlaylists() {
+		if sp.MaterializedPlaylistID == "" {
+			continue
+		}
+		if err := m.Materialize(sp); err != nil {
+			log.Printf("error refreshing smart playlist %q: %v", sp.Name, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Package smtc integrates playback with the Windows System Media Transport
+// Controls, which drive the media keys and the OS media overlay.
+package smtc
+
+// PlaybackState is the playback state shown in the OS media overlay.
+type PlaybackState int
+
+const (
+	Stopped PlaybackState = iota
+	Playing
+	Paused
+)
+
+// Metadata is the now playing information shown in the OS media overlay.
+type Metadata struct {
+	Title    string
+	Artist   string
+	Album    string
+	ArtURL   string  // http(s) URL of the cover art, or empty
+	Duration float64 // seconds
+}
+
+// Commands are the callbacks invoked when the user presses a
+// media key or one of the controls in the OS media overlay.
+type Commands struct {
+	Play     func()
+	Pause    func()
+	Stop     func()
+	Previous func()
+	Next     func()
+	Seek     func(secs float64)
+}
+
+// Controls is the System Media Transport Controls integration.
+// Methods may be called from any goroutine.
+type Controls interface {
+	SetMetadata(Metadata)
+	SetState(PlaybackState)
+	// SetPosition sets the position in seconds in the current track.
+	SetPosition(secs float64)
+}
+
+// New creates the System Media Transport Controls integration,
+// or returns an error if the OS is not supported. The controls are
+// attached to the app's main window once it has been shown.
+func New(cmds Commands) (Controls, error) {
+	return newControls(cmds)
+}
+
+func (c Commands) invoke(f func()) {
+	if f != nil {
+		go f()
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package smtc
+
+import "errors"
+
+func newControls(Commands) (Controls, error) {
+	// macOS uses MPNowPlayingInfoCenter and Linux uses MPRIS instead.
+	return nil, errors.New("unsupported platform")
+}
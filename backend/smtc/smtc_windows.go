@@ -0,0 +1,506 @@
+//go:build windows
+
+package smtc
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	combase = syscall.NewLazyDLL("combase.dll")
+	user32  = syscall.NewLazyDLL("user32.dll")
+
+	procRoInitialize             = combase.NewProc("RoInitialize")
+	procRoGetActivationFactory   = combase.NewProc("RoGetActivationFactory")
+	procRoActivateInstance       = combase.NewProc("RoActivateInstance")
+	procWindowsCreateString      = combase.NewProc("WindowsCreateString")
+	procWindowsDeleteString      = combase.NewProc("WindowsDeleteString")
+	procEnumWindows              = user32.NewProc("EnumWindows")
+	procGetWindowThreadProcessId = user32.NewProc("GetWindowThreadProcessId")
+	procIsWindowVisible          = user32.NewProc("IsWindowVisible")
+	procGetWindow                = user32.NewProc("GetWindow")
+)
+
+var (
+	iidUnknown               = mustGUID("00000000-0000-0000-c000-000000000046")
+	iidAgileObject           = mustGUID("94ea2b94-e9cc-49e0-c0ff-ee64ca8f5b90")
+	iidSMTCInterop           = mustGUID("ddb0472d-c911-4a1f-86d9-dc3d71a95f5a")
+	iidSMTC                  = mustGUID("99fa3ff4-1742-42a6-902e-087d41f965ec")
+	iidSMTC2                 = mustGUID("ea98d2f6-7f3c-4af2-a586-72889808efb1")
+	iidMusicProperties2      = mustGUID("00368462-97d3-44b9-b00f-008afcefaf18")
+	iidTimelineProperties    = mustGUID("5125316a-c3a2-475b-8507-93534dc88f15")
+	iidUriFactory            = mustGUID("44a9796f-723e-4fdf-a218-033e75b0c084")
+	iidStreamRefStatics      = mustGUID("857309dc-3fbf-4e7d-986f-ef3f1a4bdc6d")
+	iidButtonPressedHandler  = mustGUID("0557e996-7b23-5bae-aa81-ea0d671143a4") // TypedEventHandler<SMTC, ButtonPressedEventArgs>
+	iidPositionChangeHandler = mustGUID("44e34f15-bdc0-50a7-ace4-39e91fb753f1") // TypedEventHandler<SMTC, PlaybackPositionChangeRequestedEventArgs>
+)
+
+// vtable indices. Methods of WinRT interfaces follow the
+// 3 IUnknown and 3 IInspectable methods.
+const (
+	vtQueryInterface = 0
+	vtRelease        = 2
+
+	// ISystemMediaTransportControlsInterop (IUnknown)
+	vtGetForWindow = 3
+
+	// ISystemMediaTransportControls
+	vtPutPlaybackStatus = 7
+	vtGetDisplayUpdater = 8
+	vtPutIsEnabled      = 11
+	vtPutIsPlayEnabled  = 13
+	vtPutIsStopEnabled  = 15
+	vtPutIsPauseEnabled = 17
+	vtPutIsPrevEnabled  = 25
+	vtPutIsNextEnabled  = 27
+	vtAddButtonPressed  = 32
+
+	// ISystemMediaTransportControls2
+	vtUpdateTimelineProperties           = 12
+	vtAddPlaybackPositionChangeRequested = 13
+
+	// ISystemMediaTransportControlsDisplayUpdater
+	vtPutType            = 7
+	vtPutThumbnail       = 11
+	vtGetMusicProperties = 12
+	vtUpdate             = 17
+
+	// IMusicDisplayProperties
+	vtPutTitle  = 7
+	vtPutArtist = 11
+	// IMusicDisplayProperties2
+	vtPutAlbumTitle = 7
+
+	// ISystemMediaTransportControlsTimelineProperties
+	vtPutStartTime   = 7
+	vtPutEndTime     = 9
+	vtPutMinSeekTime = 11
+	vtPutMaxSeekTime = 13
+	vtPutPosition    = 15
+
+	// IUriRuntimeClassFactory
+	vtCreateUri = 6
+	// IRandomAccessStreamReferenceStatics
+	vtCreateFromUri = 7
+
+	// ISystemMediaTransportControlsButtonPressedEventArgs
+	vtGetButton = 6
+	// IPlaybackPositionChangeRequestedEventArgs
+	vtGetRequestedPlaybackPosition = 6
+)
+
+const (
+	roInitMultithreaded = 1
+	eNoInterface        = 0x80004002
+	gwOwner             = 4
+
+	mediaPlaybackTypeMusic = 1
+
+	// MediaPlaybackStatus
+	statusStopped = 2
+	statusPlaying = 3
+	statusPaused  = 4
+
+	// SystemMediaTransportControlsButton
+	buttonPlay     = 0
+	buttonPause    = 1
+	buttonStop     = 2
+	buttonNext     = 6
+	buttonPrevious = 7
+
+	// TimeSpan units per second
+	ticksPerSecond = 10_000_000
+)
+
+type windowsControls struct {
+	cmds Commands
+	reqs chan func()
+
+	// accessed only from the controls thread
+	controls  unsafe.Pointer // *ISystemMediaTransportControls
+	controls2 unsafe.Pointer // *ISystemMediaTransportControls2
+	updater   unsafe.Pointer // *ISystemMediaTransportControlsDisplayUpdater
+	failed    bool
+	meta      Metadata
+	state     PlaybackState
+	position  float64
+}
+
+// comHandler is a statically allocated COM event handler object.
+// COM only sees the vtable pointer at the start of the struct.
+type comHandler struct {
+	vtbl *comHandlerVtbl
+	iid  *syscall.GUID
+}
+
+type comHandlerVtbl struct {
+	QueryInterface uintptr
+	AddRef         uintptr
+	Release        uintptr
+	Invoke         uintptr
+}
+
+// event handler callbacks can only be package-level,
+// and there is only one set of controls per app
+var (
+	activeControls *windowsControls
+
+	buttonPressedHandler = &comHandler{
+		vtbl: &comHandlerVtbl{
+			QueryInterface: syscall.NewCallback(handlerQueryInterface),
+			AddRef:         syscall.NewCallback(handlerAddRefRelease),
+			Release:        syscall.NewCallback(handlerAddRefRelease),
+			Invoke:         syscall.NewCallback(buttonPressedInvoke),
+		},
+		iid: iidButtonPressedHandler,
+	}
+	positionChangeHandler = &comHandler{
+		vtbl: &comHandlerVtbl{
+			QueryInterface: syscall.NewCallback(handlerQueryInterface),
+			AddRef:         syscall.NewCallback(handlerAddRefRelease),
+			Release:        syscall.NewCallback(handlerAddRefRelease),
+			Invoke:         syscall.NewCallback(positionChangeInvoke),
+		},
+		iid: iidPositionChangeHandler,
+	}
+
+	enumWindowsCallback = syscall.NewCallback(enumWindowsProc)
+	enumWindowsResult   uintptr
+)
+
+func newControls(cmds Commands) (Controls, error) {
+	c := &windowsControls{cmds: cmds, reqs: make(chan func(), 8)}
+	errCh := make(chan error)
+	go c.run(errCh)
+	if err := <-errCh; err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// run owns the WinRT objects; all calls into them happen on this locked OS thread.
+func (c *windowsControls) run(errCh chan<- error) {
+	runtime.LockOSThread()
+	if err := procRoInitialize.Find(); err != nil {
+		errCh <- err // Windows 7 or earlier
+		return
+	}
+	procRoInitialize.Call(roInitMultithreaded)
+	activeControls = c
+	errCh <- nil
+
+	for f := range c.reqs {
+		f()
+	}
+}
+
+func (c *windowsControls) SetMetadata(meta Metadata) {
+	c.reqs <- func() {
+		c.meta = meta
+		c.position = 0
+		if c.ensureControls() {
+			c.updateDisplay()
+			c.updateTimeline()
+		}
+	}
+}
+
+func (c *windowsControls) SetState(state PlaybackState) {
+	c.reqs <- func() {
+		c.state = state
+		if c.ensureControls() {
+			c.updateState()
+		}
+	}
+}
+
+func (c *windowsControls) SetPosition(secs float64) {
+	select {
+	case c.reqs <- func() {
+		c.position = secs
+		if c.ensureControls() {
+			c.updateTimeline()
+		}
+	}:
+	default:
+		// the next position update will catch up
+	}
+}
+
+// ensureControls gets the controls for the main window, once it has been shown,
+// and registers for button presses and seek requests.
+func (c *windowsControls) ensureControls() bool {
+	if c.controls != nil {
+		return true
+	}
+	if c.failed {
+		return false
+	}
+	enumWindowsResult = 0
+	procEnumWindows.Call(enumWindowsCallback, 0)
+	if enumWindowsResult == 0 {
+		return false
+	}
+	if err := c.initControls(enumWindowsResult); err != nil {
+		log.Printf("error initializing media transport controls: %v", err)
+		c.failed = true
+		return false
+	}
+	c.updateDisplay()
+	c.updateState()
+	c.updateTimeline()
+	return true
+}
+
+func (c *windowsControls) initControls(hwnd uintptr) error {
+	interop, err := activationFactory("Windows.Media.SystemMediaTransportControls", iidSMTCInterop)
+	if err != nil {
+		return err
+	}
+	hr := comCall(interop, vtGetForWindow, hwnd, uintptr(unsafe.Pointer(iidSMTC)), uintptr(unsafe.Pointer(&c.controls)))
+	comRelease(interop)
+	if hr != 0 {
+		c.controls = nil
+		return fmt.Errorf("GetForWindow failed: HRESULT %#x", hr)
+	}
+	if hr := comCall(c.controls, vtGetDisplayUpdater, uintptr(unsafe.Pointer(&c.updater))); hr != 0 {
+		comRelease(c.controls)
+		c.controls, c.updater = nil, nil
+		return fmt.Errorf("get_DisplayUpdater failed: HRESULT %#x", hr)
+	}
+	for _, method := range []int{vtPutIsEnabled, vtPutIsPlayEnabled, vtPutIsPauseEnabled,
+		vtPutIsStopEnabled, vtPutIsPrevEnabled, vtPutIsNextEnabled} {
+		comCall(c.controls, method, 1)
+	}
+	var token int64
+	comCall(c.controls, vtAddButtonPressed, uintptr(unsafe.Pointer(buttonPressedHandler)), uintptr(unsafe.Pointer(&token)))
+
+	// seeking from the overlay is only supported on Windows 10 1607 and later
+	c.controls2 = queryInterface(c.controls, iidSMTC2)
+	if c.controls2 != nil {
+		comCall(c.controls2, vtAddPlaybackPositionChangeRequested,
+			uintptr(unsafe.Pointer(positionChangeHandler)), uintptr(unsafe.Pointer(&token)))
+	}
+	return nil
+}
+
+func (c *windowsControls) updateState() {
+	status := statusStopped
+	switch c.state {
+	case Playing:
+		status = statusPlaying
+	case Paused:
+		status = statusPaused
+	}
+	comCall(c.controls, vtPutPlaybackStatus, uintptr(status))
+}
+
+func (c *windowsControls) updateDisplay() {
+	comCall(c.updater, vtPutType, mediaPlaybackTypeMusic)
+	var props unsafe.Pointer // *IMusicDisplayProperties
+	if hr := comCall(c.updater, vtGetMusicProperties, uintptr(unsafe.Pointer(&props))); hr == 0 {
+		withHString(c.meta.Title, func(h uintptr) { comCall(props, vtPutTitle, h) })
+		withHString(c.meta.Artist, func(h uintptr) { comCall(props, vtPutArtist, h) })
+		if props2 := queryInterface(props, iidMusicProperties2); props2 != nil {
+			withHString(c.meta.Album, func(h uintptr) { comCall(props2, vtPutAlbumTitle, h) })
+			comRelease(props2)
+		}
+		comRelease(props)
+	}
+	thumbnail := streamReferenceFromURI(c.meta.ArtURL)
+	comCall(c.updater, vtPutThumbnail, uintptr(thumbnail))
+	if thumbnail != nil {
+		comRelease(thumbnail)
+	}
+	comCall(c.updater, vtUpdate)
+}
+
+func (c *windowsControls) updateTimeline() {
+	if c.controls2 == nil {
+		return
+	}
+	var insp unsafe.Pointer
+	if err := activateInstance("Windows.Media.SystemMediaTransportControlsTimelineProperties", &insp); err != nil {
+		return
+	}
+	defer comRelease(insp)
+	timeline := queryInterface(insp, iidTimelineProperties)
+	if timeline == nil {
+		return
+	}
+	defer comRelease(timeline)
+	end := uintptr(c.meta.Duration * ticksPerSecond)
+	comCall(timeline, vtPutStartTime, 0)
+	comCall(timeline, vtPutEndTime, end)
+	comCall(timeline, vtPutMinSeekTime, 0)
+	comCall(timeline, vtPutMaxSeekTime, end)
+	comCall(timeline, vtPutPosition, uintptr(c.position*ticksPerSecond))
+	comCall(c.controls2, vtUpdateTimelineProperties, uintptr(timeline))
+}
+
+// streamReferenceFromURI returns a RandomAccessStreamReference for the URI, or nil.
+func streamReferenceFromURI(uri string) unsafe.Pointer {
+	if uri == "" {
+		return nil
+	}
+	uriFactory, err := activationFactory("Windows.Foundation.Uri", iidUriFactory)
+	if err != nil {
+		return nil
+	}
+	defer comRelease(uriFactory)
+	var u unsafe.Pointer
+	withHString(uri, func(h uintptr) { comCall(uriFactory, vtCreateUri, h, uintptr(unsafe.Pointer(&u))) })
+	if u == nil {
+		return nil
+	}
+	defer comRelease(u)
+	statics, err := activationFactory("Windows.Storage.Streams.RandomAccessStreamReference", iidStreamRefStatics)
+	if err != nil {
+		return nil
+	}
+	defer comRelease(statics)
+	var ref unsafe.Pointer
+	comCall(statics, vtCreateFromUri, uintptr(u), uintptr(unsafe.Pointer(&ref)))
+	return ref
+}
+
+func handlerQueryInterface(this *comHandler, riid *syscall.GUID, ppv *unsafe.Pointer) uintptr {
+	if *riid == *this.iid || *riid == *iidUnknown || *riid == *iidAgileObject {
+		*ppv = unsafe.Pointer(this)
+		return 0
+	}
+	*ppv = nil
+	return eNoInterface
+}
+
+// the handlers are statically allocated, so reference counting is a no-op
+func handlerAddRefRelease(*comHandler) uintptr {
+	return 1
+}
+
+func buttonPressedInvoke(_ *comHandler, _, args unsafe.Pointer) uintptr {
+	c := activeControls
+	var button int32
+	if comCall(args, vtGetButton, uintptr(unsafe.Pointer(&button))) != 0 {
+		return 0
+	}
+	switch button {
+	case buttonPlay:
+		c.cmds.invoke(c.cmds.Play)
+	case buttonPause:
+		c.cmds.invoke(c.cmds.Pause)
+	case buttonStop:
+		c.cmds.invoke(c.cmds.Stop)
+	case buttonPrevious:
+		c.cmds.invoke(c.cmds.Previous)
+	case buttonNext:
+		c.cmds.invoke(c.cmds.Next)
+	}
+	return 0
+}
+
+func positionChangeInvoke(_ *comHandler, _, args unsafe.Pointer) uintptr {
+	c := activeControls
+	var ticks int64
+	if comCall(args, vtGetRequestedPlaybackPosition, uintptr(unsafe.Pointer(&ticks))) != 0 {
+		return 0
+	}
+	if seek := c.cmds.Seek; seek != nil {
+		go seek(float64(ticks) / ticksPerSecond)
+	}
+	return 0
+}
+
+// enumWindowsProc finds the visible top-level window of this process.
+func enumWindowsProc(hwnd, _ uintptr) uintptr {
+	var pid uint32
+	procGetWindowThreadProcessId.Call(hwnd, uintptr(unsafe.Pointer(&pid)))
+	if int(pid) != os.Getpid() {
+		return 1 // continue
+	}
+	if visible, _, _ := procIsWindowVisible.Call(hwnd); visible == 0 {
+		return 1
+	}
+	if owner, _, _ := procGetWindow.Call(hwnd, gwOwner); owner != 0 {
+		return 1
+	}
+	enumWindowsResult = hwnd
+	return 0 // stop
+}
+
+// comCall calls the method at the vtable index of the COM object.
+func comCall(obj unsafe.Pointer, method int, args ...uintptr) uintptr {
+	vtbl := *(**[64]uintptr)(obj)
+	hr, _, _ := syscall.SyscallN(vtbl[method], append([]uintptr{uintptr(obj)}, args...)...)
+	return hr
+}
+
+func comRelease(obj unsafe.Pointer) {
+	comCall(obj, vtRelease)
+}
+
+// queryInterface returns the object's implementation of the interface, or nil.
+func queryInterface(obj unsafe.Pointer, iid *syscall.GUID) unsafe.Pointer {
+	var out unsafe.Pointer
+	if comCall(obj, vtQueryInterface, uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(&out))) != 0 {
+		return nil
+	}
+	return out
+}
+
+func activationFactory(class string, iid *syscall.GUID) (unsafe.Pointer, error) {
+	var factory unsafe.Pointer
+	var hr uintptr
+	withHString(class, func(h uintptr) {
+		hr, _, _ = procRoGetActivationFactory.Call(h, uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(&factory)))
+	})
+	if hr != 0 {
+		return nil, fmt.Errorf("RoGetActivationFactory(%s) failed: HRESULT %#x", class, hr)
+	}
+	return factory, nil
+}
+
+func activateInstance(class string, out *unsafe.Pointer) error {
+	var hr uintptr
+	withHString(class, func(h uintptr) {
+		hr, _, _ = procRoActivateInstance.Call(h, uintptr(unsafe.Pointer(out)))
+	})
+	if hr != 0 {
+		return fmt.Errorf("RoActivateInstance(%s) failed: HRESULT %#x", class, hr)
+	}
+	return nil
+}
+
+// withHString calls f with a WinRT HSTRING of s, which is valid only during the call.
+func withHString(s string, f func(h uintptr)) {
+	u16, err := syscall.UTF16FromString(s)
+	if err != nil {
+		u16 = []uint16{0} // s contains a NUL
+	}
+	var h uintptr
+	procWindowsCreateString.Call(uintptr(unsafe.Pointer(&u16[0])), uintptr(len(u16)-1), uintptr(unsafe.Pointer(&h)))
+	f(h)
+	procWindowsDeleteString.Call(h)
+}
+
+// mustGUID parses a GUID in the 8-4-4-4-12 hex digit format.
+func mustGUID(s string) *syscall.GUID {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(b) != 16 {
+		panic("invalid GUID: " + s)
+	}
+	g := &syscall.GUID{
+		Data1: uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3]),
+		Data2: uint16(b[4])<<8 | uint16(b[5]),
+		Data3: uint16(b[6])<<8 | uint16(b[7]),
+	}
+	copy(g.Data4[:], b[8:])
+	return g
+}
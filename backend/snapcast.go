@@ -0,0 +1,193 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/player/mpv"
+)
+
+const (
+	// audio is relayed to the server in chunks of this duration
+	snapcastChunkDuration = 20 * time.Millisecond
+	// wait between attempts to connect to the server
+	snapcastRedialInterval = 5 * time.Second
+	// while disconnected, audio is discarded in real time, but is allowed
+	// to fall this far behind (e.g. while paused) before the pace is reset
+	snapcastMaxLag = time.Second
+)
+
+// SnapcastOutput streams the audio of the local player to a Snapcast server
+// for multi-room playback. The player writes raw PCM (48000:16:2, the
+// default sample format of Snapcast sources) to a named pipe, which is
+// either read directly by a Snapcast "pipe" source, or relayed by us to
+// a Snapcast "tcp" source listening on the configured address.
+//
+// Track metadata reaches Snapcast clients through the control script
+// (see RunSnapcastControl), which the Snapcast source must be configured with.
+type SnapcastOutput struct {
+	cfg    *SnapcastConfig
+	player *mpv.Player
+
+	mu     sync.Mutex
+	cancel context.CancelFunc // stops the TCP relay, if running
+	fifo   string             // temporary pipe of the TCP relay
+}
+
+func NewSnapcastOutput(cfg *SnapcastConfig, p *mpv.Player) *SnapcastOutput {
+	return &SnapcastOutput{cfg: cfg, player: p}
+}
+
+// Apply starts, restarts or stops streaming according to the config.
+// If streaming can't be started, the player is left on the audio device.
+func (s *SnapcastOutput) Apply() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	oldCancel, oldFifo := s.cancel, s.fifo
+	s.cancel, s.fifo = nil, ""
+	// tear down the old relay only after the player has
+	// switched away from its pipe, so its writes never block
+	defer func() {
+		if oldCancel != nil {
+			oldCancel()
+			os.Remove(oldFifo)
+		}
+	}()
+
+	if !s.cfg.Enabled {
+		return s.player.SetPCMOutput("")
+	}
+
+	var pipe string
+	switch s.cfg.Mode {
+	case SnapcastModeTCP:
+		fifo, cancel, err := startSnapcastRelay(s.cfg.TCPAddress)
+		if err != nil {
+			s.player.SetPCMOutput("")
+			return err
+		}
+		s.cancel, s.fifo = cancel, fifo
+		pipe = fifo
+	default:
+		if err := checkPipeReader(s.cfg.PipePath); err != nil {
+			s.player.SetPCMOutput("")
+			return fmt.Errorf("Snapcast pipe %s is not being read (is snapserver running?): %w", s.cfg.PipePath, err)
+		}
+		pipe = s.cfg.PipePath
+	}
+
+	if err := s.player.SetPCMOutput(pipe); err != nil {
+		if s.cancel != nil {
+			s.cancel()
+			os.Remove(s.fifo)
+			s.cancel, s.fifo = nil, ""
+		}
+		s.player.SetPCMOutput("")
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the TCP relay, if running. The player must
+// be destroyed or switched away from the pipe before the relay stops.
+func (s *SnapcastOutput) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+		os.Remove(s.fifo)
+		s.cancel, s.fifo = nil, ""
+	}
+}
+
+// startSnapcastRelay creates a temporary named pipe and starts relaying
+// what is written to it to the Snapcast TCP source at addr.
+func startSnapcastRelay(addr string) (string, context.CancelFunc, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return "", nil, fmt.Errorf("invalid Snapcast address %q: %w", addr, err)
+	}
+	fifo := filepath.Join(os.TempDir(), fmt.Sprintf("supersonic-snapcast-%d", os.Getpid()))
+	os.Remove(fifo)
+	r, err := openPipeReader(fifo)
+	if err != nil {
+		return "", nil, fmt.Errorf("error creating Snapcast pipe: %w", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-ctx.Done()
+		r.Close() // unblocks the relay's read
+	}()
+	go relaySnapcast(ctx, r, addr)
+	return fifo, cancel, nil
+}
+
+func relaySnapcast(ctx context.Context, r io.Reader, addr string) {
+	bytesPerSec := mpv.PCMSampleRate * mpv.PCMChannels * 2
+	chunk := make([]byte, bytesPerSec*int(snapcastChunkDuration)/int(time.Second))
+
+	var conn net.Conn
+	var lastDial, paceStart time.Time
+	var discarded int
+	dialFailed := false // whether the last failure to connect was logged
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			if ctx.Err() == nil && !errors.Is(err, os.ErrClosed) {
+				log.Printf("Snapcast relay stopped: %v", err)
+			}
+			return
+		}
+		if conn == nil && time.Since(lastDial) >= snapcastRedialInterval {
+			lastDial = time.Now()
+			var err error
+			d := net.Dialer{Timeout: 2 * time.Second}
+			if conn, err = d.DialContext(ctx, "tcp", addr); err != nil {
+				conn = nil
+				if !dialFailed {
+					log.Printf("error connecting to Snapcast server %s: %v", addr, err)
+				}
+				dialFailed = true
+			} else {
+				log.Printf("connected to Snapcast server %s", addr)
+				dialFailed = false
+			}
+		}
+		if conn != nil {
+			conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+			_, err := conn.Write(chunk)
+			if err == nil {
+				continue
+			}
+			log.Printf("error writing to Snapcast server %s: %v", addr, err)
+			conn.Close()
+			conn = nil
+			paceStart = time.Time{}
+		}
+		// No server to set the pace of playback, so
+		// discard audio in real time instead.
+		if paceStart.IsZero() {
+			paceStart, discarded = time.Now(), 0
+		}
+		discarded += len(chunk)
+		due := time.Duration(discarded) * time.Second / time.Duration(bytesPerSec)
+		if ahead := due - time.Since(paceStart); ahead > 0 {
+			time.Sleep(ahead)
+		} else if ahead < -snapcastMaxLag {
+			paceStart, discarded = time.Now(), 0
+		}
+	}
+}
@@ -0,0 +1,61 @@
+//go:build !windows
+
+package backend
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// checkPipeReader returns an error if the file isn't a named pipe
+// which is currently open for reading by another process.
+func checkPipeReader(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeNamedPipe == 0 {
+		return errors.New("not a named pipe")
+	}
+	// opening for writing without blocking fails with ENXIO if there is no reader
+	f, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// openPipeReader creates a named pipe at path and opens it for reading.
+// Reads block until data is written rather than returning EOF
+// while the pipe has no writer.
+func openPipeReader(path string) (io.ReadCloser, error) {
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		return nil, err
+	}
+	r, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	// hold the pipe open for writing ourselves, so it never sees EOF
+	// when the player closes it (e.g. when switching audio output)
+	w, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		r.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return &pipeReader{File: r, w: w}, nil
+}
+
+type pipeReader struct {
+	*os.File
+	w *os.File
+}
+
+func (p *pipeReader) Close() error {
+	p.w.Close()
+	return p.File.Close()
+}
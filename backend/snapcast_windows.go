@@ -0,0 +1,16 @@
+package backend
+
+import (
+	"errors"
+	"io"
+)
+
+var errSnapcastUnsupported = errors.New("Snapcast output is not supported on Windows")
+
+func checkPipeReader(path string) error {
+	return errSnapcastUnsupported
+}
+
+func openPipeReader(path string) (io.ReadCloser, error) {
+	return nil, errSnapcastUnsupported
+}
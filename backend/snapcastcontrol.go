@@ -0,0 +1,261 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/ipc"
+)
+
+// how often the running instance is polled for the playback state
+const snapcastControlPollInterval = time.Second
+
+var errSupersonicNotRunning = errors.New("Supersonic is not running")
+
+// RunSnapcastControl runs as the control script of a Snapcast stream, e.g.
+//
+//	source = pipe:///tmp/snapfifo?name=Supersonic&controlscript=/usr/bin/supersonic&controlscriptparams=-snapcast-control
+//
+// It speaks the Snapcast stream plugin JSON-RPC protocol over in and out,
+// forwarding the now playing metadata of the running instance (via IPC)
+// to Snapcast, and Snapcast's playback control requests to the instance.
+// It returns when in is closed, i.e. when snapserver stops the stream.
+func RunSnapcastControl(in io.Reader, out io.Writer) error {
+	c := &snapcastControl{enc: json.NewEncoder(out)}
+	requests := make(chan snapcastRequest)
+	readErr := make(chan error, 1)
+	go func() {
+		sc := bufio.NewScanner(in)
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			var req snapcastRequest
+			if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+				log.Printf("invalid Snapcast request: %v", err)
+				continue
+			}
+			requests <- req
+		}
+		readErr <- sc.Err()
+	}()
+
+	if err := c.notify("Plugin.Stream.Ready", nil); err != nil {
+		return err
+	}
+	c.poll()
+	t := time.NewTicker(snapcastControlPollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case req := <-requests:
+			if err := c.handle(req); err != nil {
+				return err
+			}
+		case <-t.C:
+			if err := c.poll(); err != nil {
+				return err
+			}
+		case err := <-readErr:
+			return err
+		}
+	}
+}
+
+type snapcastRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type snapcastMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  any             `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *snapcastError  `json:"error,omitempty"`
+}
+
+type snapcastError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// the Snapcast stream properties, see
+// https://github.com/badaix/snapcast/blob/master/doc/json_rpc_api/stream_plugin.md
+type snapcastProperties struct {
+	PlaybackStatus string            `json:"playbackStatus"`
+	Position       float64           `json:"position"`
+	Volume         int               `json:"volume"`
+	CanGoNext      bool              `json:"canGoNext"`
+	CanGoPrevious  bool              `json:"canGoPrevious"`
+	CanPlay        bool              `json:"canPlay"`
+	CanPause       bool              `json:"canPause"`
+	CanSeek        bool              `json:"canSeek"`
+	CanControl     bool              `json:"canControl"`
+	Metadata       *snapcastMetadata `json:"metadata,omitempty"`
+}
+
+type snapcastMetadata struct {
+	Title    string   `json:"title"`
+	Artist   []string `json:"artist,omitempty"`
+	Album    string   `json:"album,omitempty"`
+	Duration float64  `json:"duration,omitempty"`
+}
+
+type snapcastControl struct {
+	enc *json.Encoder
+	cli *ipc.Client // nil while not connected to a running instance
+
+	props    snapcastProperties
+	polledAt time.Time
+}
+
+// poll updates the properties from the running instance,
+// notifying Snapcast if they changed other than by playing on.
+func (c *snapcastControl) poll() error {
+	old, oldPolledAt := c.props, c.polledAt
+	c.props = c.fetchProperties()
+	c.polledAt = time.Now()
+
+	expectedPos := old.Position
+	if old.PlaybackStatus == "playing" {
+		expectedPos += c.polledAt.Sub(oldPolledAt).Seconds()
+	}
+	seeked := math.Abs(c.props.Position-expectedPos) > 2
+	if !seeked && c.props.equalExceptPosition(old) {
+		return nil
+	}
+	return c.notify("Plugin.Stream.Player.Properties", c.props)
+}
+
+func (c *snapcastControl) fetchProperties() snapcastProperties {
+	if c.cli == nil {
+		c.cli, _ = ipc.Connect()
+	}
+	if c.cli == nil {
+		return snapcastProperties{PlaybackStatus: "stopped"}
+	}
+	np, err := c.cli.NowPlaying()
+	if err != nil {
+		// the instance has quit
+		c.cli = nil
+		return snapcastProperties{PlaybackStatus: "stopped"}
+	}
+	p := snapcastProperties{
+		PlaybackStatus: np.State,
+		Position:       np.TimePos,
+		Volume:         np.Volume,
+		CanControl:     true,
+	}
+	if np.Title != "" {
+		p.Metadata = &snapcastMetadata{
+			Title:    np.Title,
+			Artist:   np.Artists,
+			Album:    np.Album,
+			Duration: np.Duration,
+		}
+		p.CanGoNext, p.CanGoPrevious = true, true
+		p.CanPlay, p.CanPause, p.CanSeek = true, true, true
+	}
+	return p
+}
+
+func (p snapcastProperties) equalExceptPosition(o snapcastProperties) bool {
+	p.Position, o.Position = 0, 0
+	pm, om := p.Metadata, o.Metadata
+	p.Metadata, o.Metadata = nil, nil
+	if p != o || (pm == nil) != (om == nil) {
+		return false
+	}
+	return pm == nil || (pm.Title == om.Title && pm.Album == om.Album &&
+		pm.Duration == om.Duration && slices.Equal(pm.Artist, om.Artist))
+}
+
+func (c *snapcastControl) handle(req snapcastRequest) error {
+	var err error
+	switch req.Method {
+	case "Plugin.Stream.Player.GetProperties":
+		c.props, c.polledAt = c.fetchProperties(), time.Now()
+		return c.respond(req.ID, c.props, nil)
+	case "Plugin.Stream.Player.Control":
+		err = c.control(req.Params)
+	case "Plugin.Stream.Player.SetProperty":
+		err = c.setProperty(req.Params)
+	default:
+		if req.ID == nil {
+			return nil // ignore unknown notifications
+		}
+		return c.respond(req.ID, nil, &snapcastError{Code: -32601, Message: "Method not found"})
+	}
+	if err != nil {
+		return c.respond(req.ID, nil, &snapcastError{Code: -32603, Message: err.Error()})
+	}
+	if err := c.respond(req.ID, "ok", nil); err != nil {
+		return err
+	}
+	// let Snapcast see the effect of the request right away
+	return c.poll()
+}
+
+func (c *snapcastControl) control(params json.RawMessage) error {
+	var p struct {
+		Command string `json:"command"`
+		Params  struct {
+			Offset   float64 `json:"offset"`
+			Position float64 `json:"position"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return err
+	}
+	if c.cli == nil {
+		return errSupersonicNotRunning
+	}
+	switch p.Command {
+	case "play":
+		return c.cli.Play()
+	case "pause", "stop":
+		return c.cli.Pause()
+	case "playPause":
+		return c.cli.PlayPause()
+	case "next":
+		return c.cli.SeekNext()
+	case "previous":
+		return c.cli.SeekBackOrPrevious()
+	case "seek":
+		return c.cli.SeekSeconds(max(0, c.props.Position+p.Params.Offset))
+	case "setPosition":
+		return c.cli.SeekSeconds(p.Params.Position)
+	}
+	return nil
+}
+
+func (c *snapcastControl) setProperty(params json.RawMessage) error {
+	var p struct {
+		Volume *int `json:"volume"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return err
+	}
+	if c.cli == nil {
+		return errSupersonicNotRunning
+	}
+	if p.Volume != nil {
+		return c.cli.SetVolume(clamp(*p.Volume, 0, 100))
+	}
+	return nil
+}
+
+func (c *snapcastControl) notify(method string, params any) error {
+	return c.enc.Encode(snapcastMessage{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (c *snapcastControl) respond(id json.RawMessage, result any, err *snapcastError) error {
+	return c.enc.Encode(snapcastMessage{JSONRPC: "2.0", ID: id, Result: result, Error: err})
+}
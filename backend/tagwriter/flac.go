@@ -0,0 +1,216 @@
+package tagwriter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	flacBlockVorbisComment = 4
+	flacBlockPicture       = 6
+	flacLastBlockFlag      = 0x80
+	flacMaxBlockLen        = 1<<24 - 1
+)
+
+type flacBlock struct {
+	typ  byte
+	data []byte
+}
+
+// copyFLAC rewrites the metadata blocks of a FLAC file, merging the tags
+// into its Vorbis comment block, then copies the audio frames unchanged.
+func copyFLAC(w io.Writer, r *bufio.Reader, tags *Tags) error {
+	if _, err := io.CopyN(io.Discard, r, 4); err != nil { // "fLaC"
+		return err
+	}
+	var blocks []flacBlock
+	for last := false; !last; {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return err
+		}
+		last = header[0]&flacLastBlockFlag != 0
+		length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return err
+		}
+		blocks = append(blocks, flacBlock{typ: header[0] &^ flacLastBlockFlag, data: data})
+	}
+	if len(blocks) == 0 {
+		return errors.New("FLAC file has no STREAMINFO block")
+	}
+
+	var comments []string
+	vendor := "supersonic"
+	newBlocks := blocks[:1:1] // STREAMINFO must come first
+	for _, b := range blocks[1:] {
+		switch {
+		case b.typ == flacBlockVorbisComment:
+			vendor, comments = parseVorbisComment(b.data)
+		case b.typ == flacBlockPicture && len(tags.CoverJPEG) > 0:
+			// replaced by the new cover
+		default:
+			newBlocks = append(newBlocks, b)
+		}
+	}
+	comments = mergeVorbisComments(comments, vorbisComments(tags))
+	newBlocks = append(newBlocks, flacBlock{typ: flacBlockVorbisComment, data: buildVorbisComment(vendor, comments)})
+	if len(tags.CoverJPEG) > 0 {
+		newBlocks = append(newBlocks, flacBlock{typ: flacBlockPicture, data: buildFLACPicture(tags.CoverJPEG)})
+	}
+
+	if _, err := w.Write([]byte("fLaC")); err != nil {
+		return err
+	}
+	for i, b := range newBlocks {
+		if len(b.data) > flacMaxBlockLen {
+			return errors.New("FLAC metadata block too large")
+		}
+		header := [4]byte{b.typ, byte(len(b.data) >> 16), byte(len(b.data) >> 8), byte(len(b.data))}
+		if i == len(newBlocks)-1 {
+			header[0] |= flacLastBlockFlag
+		}
+		if _, err := w.Write(header[:]); err != nil {
+			return err
+		}
+		if _, err := w.Write(b.data); err != nil {
+			return err
+		}
+	}
+	_, err := io.Copy(w, r)
+	return err
+}
+
+func vorbisComments(tags *Tags) []string {
+	var c []string
+	add := func(key, value string) {
+		if value != "" {
+			c = append(c, key+"="+value)
+		}
+	}
+	number := func(key string, n int) {
+		if n > 0 {
+			add(key, strconv.Itoa(n))
+		}
+	}
+	add("TITLE", tags.Title)
+	for _, a := range tags.Artists {
+		add("ARTIST", a)
+	}
+	for _, a := range tags.AlbumArtists {
+		add("ALBUMARTIST", a)
+	}
+	add("ALBUM", tags.Album)
+	for _, g := range tags.Genres {
+		add("GENRE", g)
+	}
+	if tags.ReleaseDate != "" {
+		add("DATE", tags.ReleaseDate)
+	} else {
+		number("DATE", tags.Year)
+	}
+	number("TRACKNUMBER", tags.TrackNumber)
+	number("DISCNUMBER", tags.DiscNumber)
+	add("COMPOSER", tags.Composer)
+	number("BPM", tags.BPM)
+	add("MUSICBRAINZ_TRACKID", tags.MusicBrainzRecordingID)
+	add("MUSICBRAINZ_ALBUMID", tags.MusicBrainzReleaseID)
+	add("LYRICS", tags.Lyrics)
+	keys := make([]string, 0, len(tags.Extra))
+	for k := range tags.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		add(strings.ToUpper(k), tags.Extra[k])
+	}
+	return c
+}
+
+// mergeVorbisComments returns the existing comments with any fields
+// present in updated replaced by their new values.
+func mergeVorbisComments(existing, updated []string) []string {
+	replaced := make(map[string]bool)
+	for _, c := range updated {
+		replaced[vorbisCommentKey(c)] = true
+	}
+	var merged []string
+	for _, c := range existing {
+		if !replaced[vorbisCommentKey(c)] {
+			merged = append(merged, c)
+		}
+	}
+	return append(merged, updated...)
+}
+
+func vorbisCommentKey(comment string) string {
+	key, _, _ := strings.Cut(comment, "=")
+	return strings.ToUpper(key)
+}
+
+func parseVorbisComment(data []byte) (vendor string, comments []string) {
+	next := func() (string, bool) {
+		if len(data) < 4 {
+			return "", false
+		}
+		n := binary.LittleEndian.Uint32(data)
+		if uint64(len(data)-4) < uint64(n) {
+			return "", false
+		}
+		s := string(data[4 : 4+n])
+		data = data[4+n:]
+		return s, true
+	}
+	vendor, _ = next()
+	if len(data) < 4 {
+		return vendor, nil
+	}
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+	for i := uint32(0); i < count; i++ {
+		c, ok := next()
+		if !ok {
+			break
+		}
+		comments = append(comments, c)
+	}
+	return vendor, comments
+}
+
+func buildVorbisComment(vendor string, comments []string) []byte {
+	var buf bytes.Buffer
+	writeString := func(s string) {
+		binary.Write(&buf, binary.LittleEndian, uint32(len(s)))
+		buf.WriteString(s)
+	}
+	writeString(vendor)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(comments)))
+	for _, c := range comments {
+		writeString(c)
+	}
+	return buf.Bytes()
+}
+
+func buildFLACPicture(jpeg []byte) []byte {
+	var buf bytes.Buffer
+	be := func(n int) { binary.Write(&buf, binary.BigEndian, uint32(n)) }
+	be(3) // front cover
+	be(len("image/jpeg"))
+	buf.WriteString("image/jpeg")
+	be(0) // description
+	// width, height, color depth and palette size are optional
+	be(0)
+	be(0)
+	be(0)
+	be(0)
+	be(len(jpeg))
+	buf.Write(jpeg)
+	return buf.Bytes()
+}
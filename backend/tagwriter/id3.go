@@ -0,0 +1,167 @@
+package tagwriter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+)
+
+const (
+	id3HeaderLen      = 10
+	id3FlagFooter     = 0x10
+	id3EncodingUTF8   = 0x03
+	id3PicFrontCover  = 0x03
+	id3MaxSyncsafeLen = 1<<28 - 1
+)
+
+// copyMP3 writes an ID3v2.4 tag followed by the audio from r,
+// skipping any ID3v2 tag already at the start of the file.
+func copyMP3(w io.Writer, r *bufio.Reader, tags *Tags) error {
+	if err := skipID3v2(r); err != nil {
+		return err
+	}
+	tag, err := buildID3v2(tags)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(tag); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func skipID3v2(r *bufio.Reader) error {
+	header, err := r.Peek(id3HeaderLen)
+	if err != nil || !bytes.Equal(header[:3], []byte("ID3")) {
+		return nil // no tag, or a file too short to have one
+	}
+	size := int64(decodeSyncsafe(header[6:10])) + id3HeaderLen
+	if header[5]&id3FlagFooter != 0 {
+		size += id3HeaderLen
+	}
+	_, err = io.CopyN(io.Discard, r, size)
+	return err
+}
+
+func buildID3v2(tags *Tags) ([]byte, error) {
+	var frames bytes.Buffer
+	text := func(id, value string) {
+		if value != "" {
+			writeID3Frame(&frames, id, append([]byte{id3EncodingUTF8}, value...))
+		}
+	}
+	number := func(id string, n int) {
+		if n > 0 {
+			text(id, strconv.Itoa(n))
+		}
+	}
+
+	text("TIT2", tags.Title)
+	// ID3v2.4 separates multiple values with a null byte
+	text("TPE1", joinNonEmpty(tags.Artists, "\x00"))
+	text("TPE2", joinNonEmpty(tags.AlbumArtists, "\x00"))
+	text("TALB", tags.Album)
+	text("TCON", joinNonEmpty(tags.Genres, "\x00"))
+	if tags.ReleaseDate != "" {
+		text("TDRC", tags.ReleaseDate)
+	} else {
+		number("TDRC", tags.Year)
+	}
+	number("TRCK", tags.TrackNumber)
+	number("TPOS", tags.DiscNumber)
+	text("TCOM", tags.Composer)
+	number("TBPM", tags.BPM)
+
+	// MusicBrainz IDs, written the same way as MusicBrainz Picard
+	if tags.MusicBrainzRecordingID != "" {
+		data := append([]byte("http://musicbrainz.org\x00"), tags.MusicBrainzRecordingID...)
+		writeID3Frame(&frames, "UFID", data)
+	}
+	extra := tags.Extra
+	if tags.MusicBrainzReleaseID != "" {
+		extra = make(map[string]string, len(tags.Extra)+1)
+		for k, v := range tags.Extra {
+			extra[k] = v
+		}
+		extra["MusicBrainz Album Id"] = tags.MusicBrainzReleaseID
+	}
+
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		data := []byte{id3EncodingUTF8}
+		data = append(data, k...)
+		data = append(data, 0)
+		data = append(data, extra[k]...)
+		writeID3Frame(&frames, "TXXX", data)
+	}
+
+	if tags.Lyrics != "" {
+		data := []byte{id3EncodingUTF8}
+		data = append(data, "eng"...)
+		data = append(data, 0) // empty content descriptor
+		data = append(data, tags.Lyrics...)
+		writeID3Frame(&frames, "USLT", data)
+	}
+
+	if len(tags.CoverJPEG) > 0 {
+		data := []byte{id3EncodingUTF8}
+		data = append(data, "image/jpeg"...)
+		data = append(data, 0, id3PicFrontCover, 0) // empty description
+		data = append(data, tags.CoverJPEG...)
+		writeID3Frame(&frames, "APIC", data)
+	}
+
+	if frames.Len() > id3MaxSyncsafeLen {
+		return nil, errors.New("ID3 tag too large")
+	}
+	tag := make([]byte, id3HeaderLen, id3HeaderLen+frames.Len())
+	copy(tag, "ID3")
+	tag[3] = 4 // version 2.4.0
+	encodeSyncsafe(tag[6:10], frames.Len())
+	return append(tag, frames.Bytes()...), nil
+}
+
+func writeID3Frame(buf *bytes.Buffer, id string, data []byte) {
+	var header [id3HeaderLen]byte
+	copy(header[:4], id)
+	encodeSyncsafe(header[4:8], len(data))
+	buf.Write(header[:])
+	buf.Write(data)
+}
+
+func encodeSyncsafe(b []byte, n int) {
+	for i := 3; i >= 0; i-- {
+		b[i] = byte(n & 0x7F)
+		n >>= 7
+	}
+}
+
+func decodeSyncsafe(b []byte) int {
+	var n int
+	for _, c := range b[:4] {
+		n = n<<7 | int(c&0x7F)
+	}
+	return n
+}
+
+func joinNonEmpty(values []string, sep string) string {
+	var b bytes.Buffer
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(v)
+	}
+	return b.String()
+}
@@ -0,0 +1,63 @@
+// Package tagwriter embeds metadata tags, lyrics and cover art
+// into audio files as they are copied, without decoding the audio.
+// MP3 (ID3v2.4) and FLAC (Vorbis comments) are supported.
+package tagwriter
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Tags to embed into a file. Zero-valued fields are not written.
+type Tags struct {
+	Title        string
+	Artists      []string
+	AlbumArtists []string
+	Album        string
+	Genres       []string
+	Composer     string
+	Year         int
+	ReleaseDate  string // YYYY[-MM[-DD]]; written instead of Year if set
+	TrackNumber  int
+	DiscNumber   int
+	BPM          int
+	Lyrics       string // plain text, or LRC formatted if synced
+	CoverJPEG    []byte
+
+	MusicBrainzRecordingID string
+	MusicBrainzReleaseID   string
+
+	// Extra holds additional tags, written as TXXX frames in MP3
+	// or as Vorbis comments in FLAC, e.g. REPLAYGAIN_TRACK_GAIN.
+	Extra map[string]string
+}
+
+// Copy copies the audio file from r to w, embedding the given tags.
+// Existing tags in the file are replaced (MP3) or merged (FLAC).
+// If the format is not supported, the file is copied unchanged
+// and embedded is false.
+func Copy(w io.Writer, r io.Reader, tags *Tags) (embedded bool, err error) {
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(4)
+	switch {
+	case bytes.Equal(header, []byte("fLaC")):
+		return true, copyFLAC(w, br, tags)
+	case isMP3(header):
+		return true, copyMP3(w, br, tags)
+	default:
+		_, err := io.Copy(w, br)
+		return false, err
+	}
+}
+
+func isMP3(header []byte) bool {
+	if len(header) < 3 {
+		return false
+	}
+	if bytes.Equal(header[:3], []byte("ID3")) {
+		return true
+	}
+	// MPEG audio frame sync
+	return header[0] == 0xFF && header[1]&0xE0 == 0xE0
+}
@@ -0,0 +1,77 @@
+package tagwriter
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCopyMP3ReplacesTag(t *testing.T) {
+	oldTag, _ := buildID3v2(&Tags{Title: "Old"})
+	audio := []byte{0xFF, 0xFB, 0x90, 0x00, 1, 2, 3}
+	var out bytes.Buffer
+	embedded, err := Copy(&out, bytes.NewReader(append(oldTag, audio...)), &Tags{Title: "New", Artists: []string{"A", "B"}})
+	if err != nil || !embedded {
+		t.Fatalf("Copy: embedded %v, err %v", embedded, err)
+	}
+	b := out.Bytes()
+	size := decodeSyncsafe(b[6:10])
+	if !bytes.Equal(b[id3HeaderLen+size:], audio) {
+		t.Error("audio data not preserved after tag")
+	}
+	if bytes.Contains(b, []byte("Old")) || !bytes.Contains(b, []byte("New")) {
+		t.Error("old tag not replaced")
+	}
+	if !bytes.Contains(b, []byte("A\x00B")) {
+		t.Error("multiple artists not null separated")
+	}
+}
+
+func TestCopyFLACMergesComments(t *testing.T) {
+	var in bytes.Buffer
+	in.WriteString("fLaC")
+	in.Write([]byte{0, 0, 0, 2, 0xAA, 0xBB}) // STREAMINFO (truncated for the test)
+	vc := buildVorbisComment("vendor", []string{"TITLE=Old", "COMMENT=keep"})
+	in.Write([]byte{flacLastBlockFlag | flacBlockVorbisComment, 0, byte(len(vc) >> 8), byte(len(vc))})
+	in.Write(vc)
+	in.Write([]byte{0xFF, 0xF8, 9, 9}) // audio
+
+	var out bytes.Buffer
+	if _, err := Copy(&out, &in, &Tags{Title: "New", TrackNumber: 3}); err != nil {
+		t.Fatal(err)
+	}
+	b := out.Bytes()
+	if !bytes.HasSuffix(b, []byte{0xFF, 0xF8, 9, 9}) {
+		t.Error("audio data not preserved")
+	}
+	vcStart := 4 + 6 + 4
+	vendor, comments := parseVorbisComment(b[vcStart : len(b)-4])
+	if vendor != "vendor" {
+		t.Errorf("vendor not preserved: %q", vendor)
+	}
+	want := []string{"COMMENT=keep", "TITLE=New", "TRACKNUMBER=3"}
+	if len(comments) != len(want) {
+		t.Fatalf("got comments %v, want %v", comments, want)
+	}
+	for i := range want {
+		if comments[i] != want[i] {
+			t.Errorf("comment %d: got %q, want %q", i, comments[i], want[i])
+		}
+	}
+}
+
+func TestCopyUnsupportedFormat(t *testing.T) {
+	data := []byte("OggS and more")
+	var out bytes.Buffer
+	embedded, err := Copy(&out, bytes.NewReader(data), &Tags{Title: "x"})
+	if err != nil || embedded || !bytes.Equal(out.Bytes(), data) {
+		t.Errorf("expected unchanged copy, got embedded %v, err %v", embedded, err)
+	}
+}
+
+func TestVorbisCommentsReleaseDate(t *testing.T) {
+	c := vorbisComments(&Tags{AlbumArtists: []string{"A"}, Year: 2001, ReleaseDate: "2001-02-03"})
+	want := []string{"ALBUMARTIST=A", "DATE=2001-02-03"}
+	if len(c) != len(want) || c[0] != want[0] || c[1] != want[1] {
+		t.Errorf("got comments %v, want %v", c, want)
+	}
+}
@@ -0,0 +1,43 @@
+// Package taskbar shows playback progress and transport controls on the
+// application's taskbar button (Windows) or dock icon (macOS).
+package taskbar
+
+// PlaybackState is the playback state reflected on the taskbar.
+type PlaybackState int
+
+const (
+	Stopped PlaybackState = iota
+	Playing
+	Paused
+)
+
+// Commands are the callbacks invoked when the user
+// clicks one of the taskbar or dock menu controls.
+type Commands struct {
+	PlayPause func()
+	Previous  func()
+	Next      func()
+}
+
+// Taskbar is the OS-specific taskbar or dock integration.
+// Methods may be called from any goroutine.
+type Taskbar interface {
+	// SetState updates the play/pause controls and the style of the progress bar.
+	SetState(PlaybackState)
+	// SetProgress sets the progress of the current track, from 0 to 1.
+	SetProgress(fraction float64)
+}
+
+// New creates the taskbar integration for the current OS,
+// or returns an error if the OS is not supported.
+// windowTitle should return the current title of the main window,
+// which is used to locate its native window on Windows.
+func New(windowTitle func() string, cmds Commands) (Taskbar, error) {
+	return newTaskbar(windowTitle, cmds)
+}
+
+func (c Commands) invoke(f func()) {
+	if f != nil {
+		go f()
+	}
+}
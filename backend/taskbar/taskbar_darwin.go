@@ -0,0 +1,71 @@
+//go:build darwin
+
+package taskbar
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework Cocoa
+#include "taskbarbridge.h"
+*/
+import "C"
+
+import (
+	"math"
+	"sync"
+)
+
+// global recipient for dock menu callbacks, since Go pointers can't be passed into C.
+var dockCommands Commands
+
+//export dock_menu_command_callback
+func dock_menu_command_callback(command C.DockCommand) {
+	switch command {
+	case C.DOCK_PREVIOUS:
+		dockCommands.invoke(dockCommands.Previous)
+	case C.DOCK_PLAY_PAUSE:
+		dockCommands.invoke(dockCommands.PlayPause)
+	case C.DOCK_NEXT:
+		dockCommands.invoke(dockCommands.Next)
+	}
+}
+
+type dockTaskbar struct {
+	mu       sync.Mutex
+	state    PlaybackState
+	progress int // in percent; redrawing the dock tile is expensive
+}
+
+func newTaskbar(_ func() string, cmds Commands) (Taskbar, error) {
+	dockCommands = cmds
+	C.init_dock_integration()
+	return &dockTaskbar{progress: -1}, nil
+}
+
+func (d *dockTaskbar) SetState(state PlaybackState) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.state = state
+	C.set_dock_playing(C.int(boolToInt(state == Playing)))
+	if state == Stopped {
+		d.progress = -1
+		C.set_dock_progress(-1)
+	}
+}
+
+func (d *dockTaskbar) SetProgress(fraction float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	progress := int(math.Round(math.Max(0, math.Min(1, fraction)) * 100))
+	if d.state == Stopped || progress == d.progress {
+		return
+	}
+	d.progress = progress
+	C.set_dock_progress(C.double(float64(progress) / 100))
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,10 @@
+//go:build !windows && !darwin
+
+package taskbar
+
+import "errors"
+
+func newTaskbar(func() string, Commands) (Taskbar, error) {
+	// Linux desktops expose playback state through MPRIS instead.
+	return nil, errors.New("unsupported platform")
+}
@@ -0,0 +1,325 @@
+//go:build windows
+
+package taskbar
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	ole32  = syscall.NewLazyDLL("ole32.dll")
+	user32 = syscall.NewLazyDLL("user32.dll")
+
+	procCoInitializeEx           = ole32.NewProc("CoInitializeEx")
+	procCoCreateInstance         = ole32.NewProc("CoCreateInstance")
+	procEnumWindows              = user32.NewProc("EnumWindows")
+	procGetWindowThreadProcessId = user32.NewProc("GetWindowThreadProcessId")
+	procGetWindowTextW           = user32.NewProc("GetWindowTextW")
+	procSetWindowLongPtrW        = user32.NewProc("SetWindowLongPtrW")
+	procCallWindowProcW          = user32.NewProc("CallWindowProcW")
+	procRegisterWindowMessageW   = user32.NewProc("RegisterWindowMessageW")
+	procCreateIcon               = user32.NewProc("CreateIcon")
+)
+
+var (
+	clsidTaskbarList = syscall.GUID{Data1: 0x56FDF344, Data2: 0xFD6D, Data3: 0x11D0,
+		Data4: [8]byte{0x95, 0x8A, 0x00, 0x60, 0x97, 0xC9, 0xA0, 0x90}}
+	iidTaskbarList3 = syscall.GUID{Data1: 0xEA1AFB91, Data2: 0x9E28, Data3: 0x4B86,
+		Data4: [8]byte{0x90, 0xE9, 0x9E, 0x9F, 0x8A, 0x5E, 0xEF, 0xAF}}
+)
+
+// ITaskbarList3 vtable indices
+const (
+	vtRelease               = 2
+	vtHrInit                = 3
+	vtSetProgressValue      = 9
+	vtSetProgressState      = 10
+	vtThumbBarAddButtons    = 15
+	vtThumbBarUpdateButtons = 16
+)
+
+const (
+	coinitMultithreaded = 0x0
+	clsctxInprocServer  = 0x1
+	gwlpWndProc         = ^uintptr(3) // -4
+	wmCommand           = 0x0111
+	thbnClicked         = 0x1800
+
+	tbpfNoProgress = 0x0
+	tbpfNormal     = 0x2
+	tbpfPaused     = 0x8
+
+	thbIcon    = 0x2
+	thbTooltip = 0x4
+	thbFlags   = 0x8
+
+	// progress is reported to the taskbar in these units
+	progressTotal = 1000
+)
+
+// thumbnail toolbar button IDs
+const (
+	buttonPrevious = iota
+	buttonPlayPause
+	buttonNext
+)
+
+type thumbButton struct {
+	Mask   uint32
+	ID     uint32
+	Bitmap uint32
+	Icon   uintptr
+	Tip    [260]uint16
+	Flags  uint32
+}
+
+type windowsTaskbar struct {
+	cmds        Commands
+	windowTitle func() string
+	reqs        chan func()
+
+	// accessed only from the taskbar thread
+	list         unsafe.Pointer // *ITaskbarList3
+	hwnd         uintptr
+	prevWndProc  uintptr
+	buttonsAdded bool
+	state        PlaybackState
+	progress     int
+	icons        struct{ play, pause, previous, next uintptr }
+}
+
+// the window procedure can only be a package-level callback,
+// and there is only one taskbar button per app
+var (
+	activeTaskbar           *windowsTaskbar
+	wndProcCallback         = syscall.NewCallback(wndProc)
+	enumWindowsCallback     = syscall.NewCallback(enumWindowsProc)
+	enumWindowsTitle        string
+	enumWindowsResult       uintptr
+	taskbarButtonCreatedMsg uintptr
+)
+
+func newTaskbar(windowTitle func() string, cmds Commands) (Taskbar, error) {
+	t := &windowsTaskbar{cmds: cmds, windowTitle: windowTitle, reqs: make(chan func(), 8)}
+	errCh := make(chan error)
+	go t.run(errCh)
+	if err := <-errCh; err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// run owns the COM object; all calls into it happen on this locked OS thread.
+func (t *windowsTaskbar) run(errCh chan<- error) {
+	runtime.LockOSThread()
+	procCoInitializeEx.Call(0, coinitMultithreaded)
+	hr, _, _ := procCoCreateInstance.Call(
+		uintptr(unsafe.Pointer(&clsidTaskbarList)), 0, clsctxInprocServer,
+		uintptr(unsafe.Pointer(&iidTaskbarList3)), uintptr(unsafe.Pointer(&t.list)))
+	if hr != 0 {
+		errCh <- fmt.Errorf("failed to create ITaskbarList3: HRESULT %#x", hr)
+		return
+	}
+	if hr := t.call(vtHrInit); hr != 0 {
+		t.call(vtRelease)
+		errCh <- fmt.Errorf("ITaskbarList3.HrInit failed: HRESULT %#x", hr)
+		return
+	}
+	name, _ := syscall.UTF16PtrFromString("TaskbarButtonCreated")
+	taskbarButtonCreatedMsg, _, _ = procRegisterWindowMessageW.Call(uintptr(unsafe.Pointer(name)))
+	t.icons.play = createIcon(playShape)
+	t.icons.pause = createIcon(pauseShape)
+	t.icons.previous = createIcon(previousShape)
+	t.icons.next = createIcon(nextShape)
+	activeTaskbar = t
+	errCh <- nil
+
+	for f := range t.reqs {
+		f()
+	}
+}
+
+func (t *windowsTaskbar) SetState(state PlaybackState) {
+	t.reqs <- func() {
+		t.state = state
+		if t.ensureWindow() {
+			t.updateProgress()
+			t.updateButtons(vtThumbBarUpdateButtons)
+		}
+	}
+}
+
+func (t *windowsTaskbar) SetProgress(fraction float64) {
+	progress := int(math.Round(math.Max(0, math.Min(1, fraction)) * progressTotal))
+	select {
+	case t.reqs <- func() {
+		if progress != t.progress {
+			t.progress = progress
+			if t.ensureWindow() {
+				t.updateProgress()
+			}
+		}
+	}:
+	default:
+		// the next progress update will catch up
+	}
+}
+
+func (t *windowsTaskbar) call(method int, args ...uintptr) uintptr {
+	vtbl := *(**[21]uintptr)(t.list)
+	hr, _, _ := syscall.SyscallN(vtbl[method], append([]uintptr{uintptr(t.list)}, args...)...)
+	return hr
+}
+
+// ensureWindow locates the main window, if not done yet, and hooks its
+// window procedure to receive thumbnail toolbar button clicks.
+func (t *windowsTaskbar) ensureWindow() bool {
+	if t.hwnd != 0 {
+		return true
+	}
+	enumWindowsTitle = t.windowTitle()
+	enumWindowsResult = 0
+	procEnumWindows.Call(enumWindowsCallback, 0)
+	if enumWindowsResult == 0 {
+		return false
+	}
+	t.hwnd = enumWindowsResult
+	t.prevWndProc, _, _ = procSetWindowLongPtrW.Call(t.hwnd, gwlpWndProc, wndProcCallback)
+	t.updateButtons(vtThumbBarAddButtons)
+	return true
+}
+
+func (t *windowsTaskbar) updateProgress() {
+	switch t.state {
+	case Playing:
+		t.call(vtSetProgressState, t.hwnd, tbpfNormal)
+	case Paused:
+		t.call(vtSetProgressState, t.hwnd, tbpfPaused)
+	default:
+		t.call(vtSetProgressState, t.hwnd, tbpfNoProgress)
+		return
+	}
+	t.call(vtSetProgressValue, t.hwnd, uintptr(t.progress), progressTotal)
+}
+
+// updateButtons adds or updates the thumbnail toolbar buttons,
+// depending on whether method is vtThumbBarAddButtons or vtThumbBarUpdateButtons.
+func (t *windowsTaskbar) updateButtons(method int) {
+	if method == vtThumbBarUpdateButtons && !t.buttonsAdded {
+		return
+	}
+	playPauseIcon, playPauseTip := t.icons.play, "Play"
+	if t.state == Playing {
+		playPauseIcon, playPauseTip = t.icons.pause, "Pause"
+	}
+	buttons := []thumbButton{
+		newThumbButton(buttonPrevious, t.icons.previous, "Previous"),
+		newThumbButton(buttonPlayPause, playPauseIcon, playPauseTip),
+		newThumbButton(buttonNext, t.icons.next, "Next"),
+	}
+	hr := t.call(method, t.hwnd, uintptr(len(buttons)), uintptr(unsafe.Pointer(&buttons[0])))
+	if method == vtThumbBarAddButtons && hr == 0 {
+		t.buttonsAdded = true
+	}
+}
+
+func newThumbButton(id uint32, icon uintptr, tip string) thumbButton {
+	b := thumbButton{Mask: thbIcon | thbTooltip | thbFlags, ID: id, Icon: icon}
+	tip16, _ := syscall.UTF16FromString(tip)
+	copy(b.Tip[:len(b.Tip)-1], tip16)
+	return b
+}
+
+func wndProc(hwnd, msg, wParam, lParam uintptr) uintptr {
+	t := activeTaskbar
+	switch {
+	case msg == wmCommand && (wParam>>16)&0xFFFF == thbnClicked:
+		switch wParam & 0xFFFF {
+		case buttonPrevious:
+			t.cmds.invoke(t.cmds.Previous)
+		case buttonPlayPause:
+			t.cmds.invoke(t.cmds.PlayPause)
+		case buttonNext:
+			t.cmds.invoke(t.cmds.Next)
+		}
+		return 0
+	case msg == taskbarButtonCreatedMsg && taskbarButtonCreatedMsg != 0:
+		// Explorer restarted; the buttons need to be added again
+		go func() {
+			t.reqs <- func() {
+				t.buttonsAdded = false
+				t.updateButtons(vtThumbBarAddButtons)
+				t.updateProgress()
+			}
+		}()
+	}
+	r, _, _ := procCallWindowProcW.Call(t.prevWndProc, hwnd, msg, wParam, lParam)
+	return r
+}
+
+func enumWindowsProc(hwnd, _ uintptr) uintptr {
+	var pid uint32
+	procGetWindowThreadProcessId.Call(hwnd, uintptr(unsafe.Pointer(&pid)))
+	if int(pid) != os.Getpid() {
+		return 1 // continue
+	}
+	var title [512]uint16
+	procGetWindowTextW.Call(hwnd, uintptr(unsafe.Pointer(&title[0])), uintptr(len(title)))
+	if syscall.UTF16ToString(title[:]) != enumWindowsTitle {
+		return 1
+	}
+	enumWindowsResult = hwnd
+	return 0 // stop
+}
+
+const iconSize = 16
+
+// createIcon renders a white icon of the shape, which reports
+// whether the pixel at (x, y) in [0, iconSize) is inside it.
+func createIcon(inside func(x, y float64) bool) uintptr {
+	andMask := make([]byte, iconSize*iconSize/8) // all zeros: use the alpha channel
+	bgra := make([]byte, iconSize*iconSize*4)
+	for y := 0; y < iconSize; y++ {
+		for x := 0; x < iconSize; x++ {
+			if inside(float64(x)+0.5, float64(y)+0.5) {
+				i := (y*iconSize + x) * 4
+				bgra[i], bgra[i+1], bgra[i+2], bgra[i+3] = 0xFF, 0xFF, 0xFF, 0xFF
+			}
+		}
+	}
+	icon, _, _ := procCreateIcon.Call(0, iconSize, iconSize, 1, 32,
+		uintptr(unsafe.Pointer(&andMask[0])), uintptr(unsafe.Pointer(&bgra[0])))
+	return icon
+}
+
+// rightTriangle reports whether (x, y) is inside the right-pointing
+// triangle with its left edge at x0 and its tip at x1.
+func rightTriangle(x, y, x0, x1 float64) bool {
+	const top, bottom, mid = 3, 13, 8
+	if x < x0 || x > x1 || y < top || y > bottom {
+		return false
+	}
+	halfHeight := (mid - top) * (x1 - x) / (x1 - x0)
+	return math.Abs(y-mid) <= halfHeight
+}
+
+func playShape(x, y float64) bool {
+	return rightTriangle(x, y, 4, 13)
+}
+
+func pauseShape(x, y float64) bool {
+	return y >= 3 && y <= 13 && ((x >= 4 && x <= 7) || (x >= 9 && x <= 12))
+}
+
+func nextShape(x, y float64) bool {
+	return rightTriangle(x, y, 3, 11) || (x >= 11 && x <= 13 && y >= 3 && y <= 13)
+}
+
+func previousShape(x, y float64) bool {
+	return nextShape(iconSize-x, y)
+}
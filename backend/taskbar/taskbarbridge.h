@@ -0,0 +1,39 @@
+//go:build darwin
+
+/**
+ * taskbarbridge.h
+ *
+ * C bridge to AppKit for showing playback progress on the dock tile
+ * and transport controls in the dock menu.
+ */
+
+#include <AppKit/AppKit.h>
+
+/**
+* Dock menu command enumeration, accepted by 'dock_menu_command_callback'.
+*/
+typedef enum {
+    DOCK_PREVIOUS,
+    DOCK_PLAY_PAUSE,
+    DOCK_NEXT
+} DockCommand;
+
+/**
+* Installs the dock menu and the progress bar overlay on the dock tile.
+*/
+void init_dock_integration();
+
+/**
+* Go-backed callback that is called when a dock menu item is clicked.
+*/
+void dock_menu_command_callback(DockCommand command);
+
+/**
+* Sets the progress shown on the dock tile, from 0 to 1, or hides it if negative.
+*/
+void set_dock_progress(double fraction);
+
+/**
+* Updates the title of the play/pause dock menu item.
+*/
+void set_dock_playing(int playing);
@@ -0,0 +1,82 @@
+//go:build darwin
+
+#import <objc/runtime.h>
+#import "taskbarbridge.h"
+
+static NSMenu *dockMenu = nil;
+static NSMenuItem *playPauseItem = nil;
+static NSProgressIndicator *dockProgress = nil;
+
+@interface DockMenuTarget : NSObject
+- (void)dockMenuItemClicked:(NSMenuItem *)sender;
+@end
+
+@implementation DockMenuTarget
+- (void)dockMenuItemClicked:(NSMenuItem *)sender {
+    dock_menu_command_callback((DockCommand)sender.tag);
+}
+@end
+
+static DockMenuTarget *dockMenuTarget = nil;
+
+// implementation of -[NSApplicationDelegate applicationDockMenu:]
+// added to the app delegate class (owned by GLFW)
+static NSMenu *application_dock_menu(id self, SEL _cmd, NSApplication *sender) {
+    return dockMenu;
+}
+
+static NSMenuItem *add_dock_menu_item(NSString *title, DockCommand command) {
+    NSMenuItem *item = [dockMenu addItemWithTitle:title action:@selector(dockMenuItemClicked:) keyEquivalent:@""];
+    item.target = dockMenuTarget;
+    item.tag = command;
+    return item;
+}
+
+void init_dock_integration() {
+    dispatch_async(dispatch_get_main_queue(), ^{
+        dockMenuTarget = [[DockMenuTarget alloc] init];
+        dockMenu = [[NSMenu alloc] init];
+        playPauseItem = add_dock_menu_item(@"Play", DOCK_PLAY_PAUSE);
+        add_dock_menu_item(@"Next", DOCK_NEXT);
+        add_dock_menu_item(@"Previous", DOCK_PREVIOUS);
+
+        id delegate = [NSApp delegate];
+        if (delegate != nil) {
+            class_replaceMethod([delegate class], @selector(applicationDockMenu:),
+                (IMP)application_dock_menu, "@@:@");
+        }
+
+        NSDockTile *tile = [NSApp dockTile];
+        NSImageView *iconView = [NSImageView imageViewWithImage:[NSApp applicationIconImage]];
+        iconView.frame = NSMakeRect(0, 0, tile.size.width, tile.size.height);
+        dockProgress = [[NSProgressIndicator alloc] initWithFrame:NSMakeRect(
+            tile.size.width * 0.1, 0, tile.size.width * 0.8, 20)];
+        dockProgress.style = NSProgressIndicatorStyleBar;
+        dockProgress.indeterminate = NO;
+        dockProgress.minValue = 0;
+        dockProgress.maxValue = 1;
+        dockProgress.hidden = YES;
+        [iconView addSubview:dockProgress];
+        tile.contentView = iconView;
+        [tile display];
+    });
+}
+
+void set_dock_progress(double fraction) {
+    dispatch_async(dispatch_get_main_queue(), ^{
+        if (dockProgress == nil) {
+            return;
+        }
+        dockProgress.hidden = fraction < 0;
+        if (fraction >= 0) {
+            dockProgress.doubleValue = fraction;
+        }
+        [[NSApp dockTile] display];
+    });
+}
+
+void set_dock_playing(int playing) {
+    dispatch_async(dispatch_get_main_queue(), ^{
+        playPauseItem.title = playing ? @"Pause" : @"Play";
+    });
+}
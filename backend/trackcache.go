@@ -0,0 +1,307 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+)
+
+// TrackCache pre-downloads the next few tracks in the play queue to disk,
+// so that they play from the local copy. This makes playback resilient to a
+// flaky connection and skipping to the next track instant. The cache is pruned
+// to LocalPlaybackConfig.MaxTrackCacheSizeMB, least recently played first.
+// Each track is cached once per stream options it was fetched with, in a
+// directory named after the track, so that a copy transcoded with other
+// options than the current ones is not played.
+type TrackCache struct {
+	ctx     context.Context
+	sm      *ServerManager
+	pm      *PlaybackManager
+	cfg     *LocalPlaybackConfig
+	baseDir string
+	client  http.Client // no timeout, as tracks may be large
+
+	mu          sync.Mutex
+	wanted      []string // IDs of the tracks to cache, in play order
+	fetchingID  string
+	cancelFetch context.CancelFunc
+	workerBusy  bool
+}
+
+func NewTrackCache(ctx context.Context, sm *ServerManager, pm *PlaybackManager, cfg *LocalPlaybackConfig, baseDir string) *TrackCache {
+	t := &TrackCache{
+		ctx:     ctx,
+		sm:      sm,
+		pm:      pm,
+		cfg:     cfg,
+		baseDir: baseDir,
+	}
+	t.removeStaleFiles()
+	pm.OnSongChange(func(mediaprovider.MediaItem, *mediaprovider.Track) { t.update() })
+	pm.OnQueueChange(t.update)
+	pm.OnLoopModeChange(func(LoopMode) { t.update() })
+	return t
+}
+
+// LocalPath returns the path of the cached copy of the track, if it has
+// been cached with the stream options it would be played with now.
+func (t *TrackCache) LocalPath(trackID string) (string, bool) {
+	path := t.trackPath(trackID, t.sm.StreamOptions(trackID))
+	if !t.isCached(path) {
+		return "", false
+	}
+	// modTime is used as last access time when pruning
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return path, true
+}
+
+func (t *TrackCache) isCached(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (t *TrackCache) serverDir() string {
+	if t.sm.Server == nil {
+		return ""
+	}
+	return filepath.Join(t.baseDir, t.sm.ServerID.String())
+}
+
+func (t *TrackCache) trackDir(trackID string) string {
+	dir := t.serverDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, url.PathEscape(trackID))
+}
+
+// trackPath returns the path of the copy of the track streamed with opts,
+// e.g. "<track>/raw" or "<track>/opus-128".
+func (t *TrackCache) trackPath(trackID string, opts mediaprovider.StreamOptions) string {
+	dir := t.trackDir(trackID)
+	if dir == "" {
+		return ""
+	}
+	name := "raw"
+	if !opts.ForceRaw {
+		format := opts.Format
+		if format == "" {
+			format = "default"
+		}
+		name = fmt.Sprintf("%s-%d", url.PathEscape(format), opts.MaxBitRate)
+	}
+	return filepath.Join(dir, name)
+}
+
+// update sets the tracks to cache to the next ones in the play queue,
+// canceling the download of a track which is no longer wanted.
+func (t *TrackCache) update() {
+	wanted := t.nextTrackIDs(t.cfg.PrecacheTracks)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.wanted = wanted
+	if t.cancelFetch != nil && !slices.Contains(wanted, t.fetchingID) {
+		t.cancelFetch()
+	}
+	if len(wanted) > 0 && !t.workerBusy {
+		t.workerBusy = true
+		go t.runWorker()
+	}
+}
+
+// nextTrackIDs returns the IDs of the next n tracks to play after the current one.
+func (t *TrackCache) nextTrackIDs(n int) []string {
+	if n <= 0 || t.pm.GetLoopMode() == LoopOne {
+		return nil
+	}
+	if _, ok := t.pm.CurrentPlayer().(player.URLPlayer); !ok {
+		return nil // only local playback can play from the cache
+	}
+	queue := t.pm.GetPlayQueue()
+	idx := t.pm.NowPlayingIndex()
+	loop := t.pm.GetLoopMode() == LoopAll
+	var ids []string
+	for i := 1; i < len(queue) && len(ids) < n; i++ {
+		next := idx + i
+		if next >= len(queue) {
+			if !loop {
+				break
+			}
+			next -= len(queue)
+		}
+		if tr, ok := queue[next].(*mediaprovider.Track); ok && !slices.Contains(ids, tr.ID) {
+			ids = append(ids, tr.ID)
+		}
+	}
+	return ids
+}
+
+func (t *TrackCache) runWorker() {
+	for {
+		t.mu.Lock()
+		id := ""
+		for _, w := range t.wanted {
+			if !t.isCached(t.trackPath(w, t.sm.StreamOptions(w))) {
+				id = w
+				break
+			}
+		}
+		if id == "" || t.ctx.Err() != nil {
+			t.workerBusy = false
+			t.mu.Unlock()
+			return
+		}
+		ctx, cancel := context.WithCancel(t.ctx)
+		t.fetchingID, t.cancelFetch = id, cancel
+		t.mu.Unlock()
+
+		err := t.fetch(ctx, id)
+
+		t.mu.Lock()
+		t.fetchingID, t.cancelFetch = "", nil
+		t.mu.Unlock()
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("error pre-caching track: %v", err)
+				// don't retry the same track in a tight loop
+				t.mu.Lock()
+				t.wanted = slices.DeleteFunc(t.wanted, func(w string) bool { return w == id })
+				t.mu.Unlock()
+			}
+			continue
+		}
+		t.prune()
+	}
+}
+
+// fetch downloads the stream of the track, as it would be played, into the cache.
+func (t *TrackCache) fetch(ctx context.Context, trackID string) error {
+	server := t.sm.Server
+	opts := t.sm.StreamOptions(trackID)
+	path := t.trackPath(trackID, opts)
+	if server == nil || path == "" {
+		return nil
+	}
+	streamURL, err := t.sm.streamURL(trackID, opts)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		return err
+	}
+	for name, vals := range t.sm.StreamHeaders(trackID) {
+		req.Header[name] = vals
+	}
+	start := time.Now()
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("stream request failed: %s", resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	partPath := path + ".part"
+	f, err := os.Create(partPath)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, resp.Body)
+	if err == nil && t.sm.network != nil {
+		t.sm.network.ReportThroughput(n, time.Since(start))
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(partPath)
+		return err
+	}
+	return os.Rename(partPath, path)
+}
+
+// prune deletes the least recently played cached tracks until the cache
+// is under its size limit, keeping the now playing and wanted tracks.
+func (t *TrackCache) prune() {
+	type fileInfo struct {
+		path    string
+		size    int64
+		modTime int64
+	}
+	// the track directories to keep, with every copy in them,
+	// since the now playing one may have been fetched with other options
+	keep := make(map[string]bool)
+	if np := t.pm.NowPlaying(); np != nil {
+		keep[t.trackDir(np.Metadata().ID)] = true
+	}
+	t.mu.Lock()
+	for _, id := range t.wanted {
+		keep[t.trackDir(id)] = true
+	}
+	t.mu.Unlock()
+
+	var files []fileInfo
+	var totalSize int64
+	filepath.WalkDir(t.baseDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(path, ".part") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			files = append(files, fileInfo{path: path, size: info.Size(), modTime: info.ModTime().UnixMilli()})
+			totalSize += info.Size()
+		}
+		return nil
+	})
+
+	maxSize := int64(t.sm.MaxTrackCacheSizeMB()) * 1_048_576
+	if totalSize <= maxSize {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime < files[j].modTime
+	})
+	for i := 0; i < len(files) && totalSize > maxSize; i++ {
+		if keep[filepath.Dir(files[i].path)] {
+			continue
+		}
+		if err := os.Remove(files[i].path); err == nil {
+			totalSize -= files[i].size
+			os.Remove(filepath.Dir(files[i].path)) // only removed if empty
+		}
+	}
+}
+
+// removeStaleFiles removes downloads left incomplete when the app last quit.
+func (t *TrackCache) removeStaleFiles() {
+	filepath.WalkDir(t.baseDir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(path, ".part") {
+			os.Remove(path)
+		}
+		return nil
+	})
+}
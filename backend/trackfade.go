@@ -0,0 +1,136 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/player"
+)
+
+const trackFadeStepInterval = 50 * time.Millisecond
+
+// trackFader ramps the player volume down at the end of a track
+// and back up at the start of the next one. The player plays one stream
+// at a time, so the fades are sequential rather than overlapping.
+type trackFader struct {
+	cfg    *TrackFadeConfig
+	player player.BasePlayer
+	// volume ramps are only feasible on the local player,
+	// not on remote players controlled over the network
+	supported bool
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	userVol int // the volume to restore after the fade; -1 if not fading
+}
+
+func newTrackFader(cfg *TrackFadeConfig, p player.BasePlayer) *trackFader {
+	_, isURLPlayer := p.(player.URLPlayer)
+	return &trackFader{cfg: cfg, player: p, userVol: -1, supported: isURLPlayer}
+}
+
+func (c *trackFader) Enabled() bool {
+	return c.supported && c.cfg.Enabled && c.cfg.DurationSeconds > 0
+}
+
+func (c *trackFader) Duration() time.Duration {
+	return time.Duration(c.cfg.DurationSeconds * float64(time.Second))
+}
+
+// IsActive returns true if a fade out has begun and the
+// volume has not yet been restored by FadeIn or Cancel.
+func (c *trackFader) IsActive() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.userVol >= 0
+}
+
+// FadeOut ramps the volume down to zero over the given duration,
+// and then invokes onDone, if non-nil.
+func (c *trackFader) FadeOut(dur time.Duration, onDone func()) {
+	c.mu.Lock()
+	c.cancelLocked()
+	if c.userVol < 0 {
+		c.userVol = c.player.GetVolume()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	from := c.player.GetVolume()
+	c.mu.Unlock()
+
+	go func() {
+		c.ramp(ctx, from, 0, dur)
+		if ctx.Err() == nil && onDone != nil {
+			onDone()
+		}
+	}()
+}
+
+// FadeIn ramps the volume from zero back up to the user's volume
+// over the fade duration.
+func (c *trackFader) FadeIn() {
+	c.mu.Lock()
+	c.cancelLocked()
+	to := c.userVol
+	if to < 0 {
+		c.mu.Unlock()
+		return // no fade out preceded this
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.mu.Unlock()
+
+	go func() {
+		c.ramp(ctx, 0, to, c.Duration())
+		c.mu.Lock()
+		if ctx.Err() == nil {
+			c.userVol = -1
+		}
+		c.mu.Unlock()
+	}()
+}
+
+// Cancel stops any fade in progress and restores the user's volume.
+func (c *trackFader) Cancel() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cancelLocked()
+	if c.userVol >= 0 {
+		c.player.SetVolume(c.userVol)
+		c.userVol = -1
+	}
+}
+
+// Abort cancels any fade in progress without restoring the volume,
+// so that a user-set volume takes effect immediately.
+func (c *trackFader) Abort() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cancelLocked()
+	c.userVol = -1
+}
+
+func (c *trackFader) cancelLocked() {
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+}
+
+func (c *trackFader) ramp(ctx context.Context, from, to int, dur time.Duration) {
+	steps := int(dur / trackFadeStepInterval)
+	if steps < 1 {
+		steps = 1
+	}
+	ticker := time.NewTicker(trackFadeStepInterval)
+	defer ticker.Stop()
+	for i := 1; i <= steps; i++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.player.SetVolume(from + (to-from)*i/steps)
+		}
+	}
+}
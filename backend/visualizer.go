@@ -0,0 +1,299 @@
+package backend
+
+import (
+	"context"
+	"log"
+	"math"
+	"math/cmplx"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player"
+	"github.com/dweymouth/supersonic/backend/player/mpv"
+)
+
+const (
+	visualizerSampleRate = 11025
+	visualizerFFTSize    = 1024 // ~93 ms at visualizerSampleRate
+	visualizerMinFreq    = 40.0
+	visualizerFloorDB    = -60.0
+)
+
+// VisualizationFrame is a snapshot of the audio at the current playback position.
+type VisualizationFrame struct {
+	// Spectrum levels (0-1) of logarithmically spaced
+	// frequency bands, from low to high frequencies.
+	Bands []float32
+
+	// Peak and RMS levels (0-1) of the audio.
+	Peak float32
+	RMS  float32
+}
+
+// Visualizer delivers the spectrum and levels of the now playing audio
+// to registered consumers at VisualizerConfig.FrameRate. While there are
+// consumers, the now playing track is decoded in the background (from its
+// pre-cached copy if available, or else the stream), and frames are
+// computed from the decoded audio at the player's current position.
+// Because of this, the audio is analyzed before ReplayGain and the equalizer.
+type Visualizer struct {
+	ctx        context.Context
+	sm         *ServerManager
+	pm         *PlaybackManager
+	cfg        *VisualizerConfig
+	trackCache *TrackCache // may be nil
+	baseDir    string
+
+	mu         sync.Mutex
+	consumers  map[int]func(VisualizationFrame)
+	nextID     int
+	stopLoop   context.CancelFunc
+	track      *visualizerTrack
+	lastPaused bool
+}
+
+// the decoded audio of the now playing track
+type visualizerTrack struct {
+	id      string
+	cancel  context.CancelFunc
+	wav     *wavReader
+	decoded bool // decoding has finished, and all samples have been read
+}
+
+func NewVisualizer(ctx context.Context, sm *ServerManager, pm *PlaybackManager, cfg *VisualizerConfig, trackCache *TrackCache, baseDir string) *Visualizer {
+	v := &Visualizer{
+		ctx:        ctx,
+		sm:         sm,
+		pm:         pm,
+		cfg:        cfg,
+		trackCache: trackCache,
+		baseDir:    baseDir,
+		consumers:  make(map[int]func(VisualizationFrame)),
+	}
+	pm.OnSongChange(func(item mediaprovider.MediaItem, _ *mediaprovider.Track) {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		if len(v.consumers) > 0 {
+			v.setTrackLocked(item)
+		}
+	})
+	return v
+}
+
+// AddConsumer registers a callback to receive visualization frames,
+// and returns a function to unregister it. Frames are only delivered
+// while playing, and callbacks are invoked from a background goroutine.
+func (v *Visualizer) AddConsumer(cb func(VisualizationFrame)) (remove func()) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	id := v.nextID
+	v.nextID++
+	v.consumers[id] = cb
+	if len(v.consumers) == 1 {
+		v.startLocked()
+	}
+	return func() {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		if _, ok := v.consumers[id]; !ok {
+			return
+		}
+		delete(v.consumers, id)
+		if len(v.consumers) == 0 {
+			v.stopLocked()
+		}
+	}
+}
+
+// must be called with v.mu held
+func (v *Visualizer) startLocked() {
+	ctx, cancel := context.WithCancel(v.ctx)
+	v.stopLoop = cancel
+	v.setTrackLocked(v.pm.NowPlaying())
+	go v.runLoop(ctx)
+}
+
+// must be called with v.mu held
+func (v *Visualizer) stopLocked() {
+	v.stopLoop()
+	v.stopLoop = nil
+	v.setTrackLocked(nil)
+}
+
+// setTrackLocked begins decoding the given item, if it's a track
+// other than the current one, canceling the decoding of the previous one.
+// must be called with v.mu held
+func (v *Visualizer) setTrackLocked(item mediaprovider.MediaItem) {
+	tr, isTrack := item.(*mediaprovider.Track)
+	if isTrack && v.track != nil && v.track.id == tr.ID {
+		return
+	}
+	if v.track != nil {
+		v.track.cancel()
+		v.track = nil
+	}
+	if !isTrack {
+		return
+	}
+
+	var src string
+	var header http.Header
+	var err error
+	if v.trackCache != nil {
+		src, _ = v.trackCache.LocalPath(tr.ID)
+	}
+	if src == "" {
+		if src, err = v.sm.GetStreamURL(tr.ID); err != nil {
+			log.Printf("not visualizing track %s: %v", tr.ID, err)
+			return
+		}
+		header = v.sm.StreamHeaders(tr.ID)
+	}
+	if err := os.MkdirAll(v.baseDir, 0755); err != nil {
+		log.Printf("error creating visualizer dir: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(v.ctx)
+	path := filepath.Join(v.baseDir, url.PathEscape(tr.ID)+".wav")
+	t := &visualizerTrack{id: tr.ID, cancel: cancel, wav: &wavReader{path: path}}
+	v.track = t
+	go func() {
+		err := mpv.DecodeToWAV(ctx, src, header, path, visualizerSampleRate)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("error decoding track for visualizer: %v", err)
+		}
+		v.mu.Lock()
+		if ctx.Err() == nil {
+			_ = t.wav.readAvailable()
+		}
+		t.decoded = true
+		v.mu.Unlock()
+		os.Remove(path)
+	}()
+}
+
+func (v *Visualizer) runLoop(ctx context.Context) {
+	rate := max(v.cfg.FrameRate, 1)
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			v.deliverFrame()
+		}
+	}
+}
+
+func (v *Visualizer) deliverFrame() {
+	status := v.pm.PlayerStatus()
+	v.mu.Lock()
+	var frame VisualizationFrame
+	paused := status.State != player.Playing
+	if paused {
+		if v.lastPaused {
+			v.mu.Unlock()
+			return // already delivered a silent frame
+		}
+		frame = VisualizationFrame{Bands: make([]float32, v.numBands())}
+	} else if t := v.track; t != nil {
+		if !t.decoded {
+			_ = t.wav.readAvailable() // may not have started writing yet
+		}
+		end := int(status.TimePos * visualizerSampleRate)
+		frame = analyzeAudio(t.wav.samples, end, v.numBands())
+	} else {
+		frame = VisualizationFrame{Bands: make([]float32, v.numBands())}
+	}
+	v.lastPaused = paused
+	consumers := make([]func(VisualizationFrame), 0, len(v.consumers))
+	for _, cb := range v.consumers {
+		consumers = append(consumers, cb)
+	}
+	v.mu.Unlock()
+
+	for _, cb := range consumers {
+		cb(frame)
+	}
+}
+
+func (v *Visualizer) numBands() int {
+	return max(v.cfg.Bands, 1)
+}
+
+// analyzeAudio computes the visualization frame for the
+// visualizerFFTSize samples ending at sample index end.
+// Samples not yet decoded are treated as silence.
+func analyzeAudio(samples []int16, end, numBands int) VisualizationFrame {
+	frame := VisualizationFrame{Bands: make([]float32, numBands)}
+	start := end - visualizerFFTSize
+	buf := make([]complex128, visualizerFFTSize)
+	var peak, sumSquares, windowSum float64
+	for i := range buf {
+		var s float64
+		if idx := start + i; idx >= 0 && idx < len(samples) {
+			s = float64(samples[idx]) / 32768
+		}
+		peak = max(peak, math.Abs(s))
+		sumSquares += s * s
+		// Hann window
+		w := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(visualizerFFTSize-1))
+		windowSum += w
+		buf[i] = complex(s*w, 0)
+	}
+	frame.Peak = float32(min(peak, 1))
+	frame.RMS = float32(min(math.Sqrt(sumSquares/visualizerFFTSize), 1))
+
+	fft(buf)
+	binHz := float64(visualizerSampleRate) / visualizerFFTSize
+	maxFreq := float64(visualizerSampleRate) / 2
+	for b := range frame.Bands {
+		lo := visualizerMinFreq * math.Pow(maxFreq/visualizerMinFreq, float64(b)/float64(numBands))
+		hi := visualizerMinFreq * math.Pow(maxFreq/visualizerMinFreq, float64(b+1)/float64(numBands))
+		loBin := min(int(lo/binHz), visualizerFFTSize/2-1)
+		hiBin := max(min(int(hi/binHz), visualizerFFTSize/2), loBin+1)
+		var mag float64
+		for k := loBin; k < hiBin; k++ {
+			mag = max(mag, cmplx.Abs(buf[k]))
+		}
+		// normalize so a full scale sine is 0 dB
+		db := 20 * math.Log10(2*mag/windowSum+1e-12)
+		frame.Bands[b] = float32(min(max((db-visualizerFloorDB)/-visualizerFloorDB, 0), 1))
+	}
+	return frame
+}
+
+// fft computes the discrete Fourier transform of x in place.
+// len(x) must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+	// bit-reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		w := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			wk := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], x[start+k+size/2]*wk
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				wk *= w
+			}
+		}
+	}
+}
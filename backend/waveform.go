@@ -0,0 +1,305 @@
+package backend
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player/mpv"
+)
+
+// WaveformBins is the number of peak values in a Waveform.
+const WaveformBins = 400
+
+// the audio is decoded at a low sample rate, which is plenty for a coarse profile
+const waveformSampleRate = 2000
+
+// Waveform is a coarse peaks profile of a track,
+// for the UI to render behind the seek bar.
+type Waveform struct {
+	TrackID string
+
+	// Peak levels (0-1) of WaveformBins equal slices of the track.
+	// Slices not yet analyzed are 0.
+	Peaks []float32
+
+	// Fraction of the track analyzed so far; 1 when complete.
+	Progress float64
+}
+
+// WaveformGenerator computes the waveform of the now playing track,
+// by decoding its pre-cached copy if available, or else the stream.
+// Waveforms are updated progressively while decoding, and
+// completed waveforms are cached on disk (a few hundred bytes each).
+type WaveformGenerator struct {
+	ctx        context.Context
+	sm         *ServerManager
+	cfg        *WaveformConfig
+	trackCache *TrackCache // may be nil
+	baseDir    string
+
+	mu       sync.Mutex
+	trackID  string // ID of the track whose waveform is current or being generated
+	current  *Waveform
+	cancel   context.CancelFunc
+	onUpdate []func(*Waveform)
+}
+
+func NewWaveformGenerator(ctx context.Context, sm *ServerManager, pm *PlaybackManager, cfg *WaveformConfig, trackCache *TrackCache, baseDir string) *WaveformGenerator {
+	w := &WaveformGenerator{
+		ctx:        ctx,
+		sm:         sm,
+		cfg:        cfg,
+		trackCache: trackCache,
+		baseDir:    baseDir,
+	}
+	pm.OnSongChange(func(item mediaprovider.MediaItem, _ *mediaprovider.Track) {
+		w.update(item)
+	})
+	return w
+}
+
+// OnUpdate registers a callback invoked when the waveform of the now playing
+// track changes, including as it is progressively computed. The callback
+// receives nil if there is no waveform (e.g. stopped, or playing radio).
+// Callbacks are invoked from a background goroutine.
+func (w *WaveformGenerator) OnUpdate(cb func(*Waveform)) {
+	w.onUpdate = append(w.onUpdate, cb)
+}
+
+// Waveform returns the waveform of the now playing track,
+// which may be incomplete, or nil if there is none.
+func (w *WaveformGenerator) Waveform() *Waveform {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+func (w *WaveformGenerator) update(item mediaprovider.MediaItem) {
+	tr, isTrack := item.(*mediaprovider.Track)
+	w.mu.Lock()
+	if isTrack && w.trackID == tr.ID {
+		w.mu.Unlock()
+		return
+	}
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	hadWaveform := w.current != nil
+	w.current = nil
+	w.trackID = ""
+	if !isTrack || !w.cfg.Enabled {
+		w.mu.Unlock()
+		if hadWaveform {
+			w.invokeOnUpdate(nil)
+		}
+		return
+	}
+	ctx, cancel := context.WithCancel(w.ctx)
+	w.cancel = cancel
+	w.trackID = tr.ID
+	w.mu.Unlock()
+
+	go w.generate(ctx, tr)
+}
+
+func (w *WaveformGenerator) generate(ctx context.Context, tr *mediaprovider.Track) {
+	cachePath := w.cachePath(tr.ID)
+	if cachePath == "" {
+		return
+	}
+	if b, err := os.ReadFile(cachePath); err == nil && len(b) == WaveformBins {
+		peaks := make([]float32, WaveformBins)
+		for i, v := range b {
+			peaks[i] = float32(v) / 255
+		}
+		w.publish(ctx, &Waveform{TrackID: tr.ID, Peaks: peaks, Progress: 1})
+		return
+	}
+
+	src, header, err := w.sourceURL(tr.ID)
+	if err != nil {
+		log.Printf("not generating waveform for %s: %v", tr.ID, err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		log.Printf("error creating waveform cache dir: %v", err)
+		return
+	}
+	wavPath := cachePath + ".wav"
+	defer os.Remove(wavPath)
+
+	decodeErr := make(chan error, 1)
+	go func() {
+		decodeErr <- mpv.DecodeToWAV(ctx, src, header, wavPath, waveformSampleRate)
+	}()
+
+	expectedSamples := tr.Duration * waveformSampleRate
+	wav := &wavReader{path: wavPath}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-decodeErr:
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("error generating waveform for %s: %v", tr.ID, err)
+				}
+				return
+			}
+			if err := wav.readAvailable(); err != nil {
+				log.Printf("error reading decoded audio for waveform: %v", err)
+				return
+			}
+			peaks := computePeaks(wav.samples, len(wav.samples))
+			w.publish(ctx, &Waveform{TrackID: tr.ID, Peaks: peaks, Progress: 1})
+			b := make([]byte, len(peaks))
+			for i, p := range peaks {
+				b[i] = byte(p * 255)
+			}
+			if err := os.WriteFile(cachePath, b, 0644); err != nil {
+				log.Printf("error caching waveform: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := wav.readAvailable(); err != nil || len(wav.samples) == 0 || expectedSamples <= 0 {
+				continue // not started yet, or track duration unknown
+			}
+			total := max(expectedSamples, len(wav.samples))
+			w.publish(ctx, &Waveform{
+				TrackID:  tr.ID,
+				Peaks:    computePeaks(wav.samples, total),
+				Progress: float64(len(wav.samples)) / float64(total),
+			})
+		}
+	}
+}
+
+// sourceURL returns the path of the track's pre-cached copy if available,
+// or else the stream URL and the headers to request it with
+// if generating from streams is enabled.
+func (w *WaveformGenerator) sourceURL(trackID string) (string, http.Header, error) {
+	if w.trackCache != nil {
+		if path, ok := w.trackCache.LocalPath(trackID); ok {
+			return path, nil, nil
+		}
+	}
+	if !w.cfg.FromStream {
+		return "", nil, errors.New("track is not cached")
+	}
+	src, err := w.sm.GetStreamURL(trackID)
+	return src, w.sm.StreamHeaders(trackID), err
+}
+
+// publish sets the current waveform and notifies listeners,
+// unless generation has been canceled by a track change.
+func (w *WaveformGenerator) publish(ctx context.Context, wf *Waveform) {
+	w.mu.Lock()
+	if ctx.Err() != nil {
+		w.mu.Unlock()
+		return
+	}
+	w.current = wf
+	w.mu.Unlock()
+	w.invokeOnUpdate(wf)
+}
+
+func (w *WaveformGenerator) invokeOnUpdate(wf *Waveform) {
+	for _, cb := range w.onUpdate {
+		cb(wf)
+	}
+}
+
+func (w *WaveformGenerator) cachePath(trackID string) string {
+	if w.sm.Server == nil {
+		return ""
+	}
+	return filepath.Join(w.baseDir, w.sm.ServerID.String(), url.PathEscape(trackID))
+}
+
+// computePeaks returns the peak levels of WaveformBins equal slices
+// of a track of totalSamples, of which samples have been decoded.
+func computePeaks(samples []int16, totalSamples int) []float32 {
+	peaks := make([]float32, WaveformBins)
+	for i := range peaks {
+		start := i * totalSamples / WaveformBins
+		end := min((i+1)*totalSamples/WaveformBins, len(samples))
+		var peak int
+		for _, s := range samples[min(start, end):end] {
+			peak = max(peak, int(s), -int(s))
+		}
+		peaks[i] = min(float32(peak)/32768, 1)
+	}
+	return peaks
+}
+
+// wavReader incrementally reads the 16-bit mono samples
+// of a WAV file which is still being written.
+type wavReader struct {
+	path    string
+	dataOff int64 // offset of the data chunk, or 0 if not yet known
+	samples []int16
+}
+
+// readAvailable appends the samples written since the last read.
+func (r *wavReader) readAvailable() error {
+	f, err := os.Open(r.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if r.dataOff == 0 {
+		if r.dataOff, err = findWAVDataChunk(f); err != nil {
+			return err
+		}
+	}
+	if _, err := f.Seek(r.dataOff+int64(len(r.samples))*2, io.SeekStart); err != nil {
+		return err
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(b); i += 2 {
+		r.samples = append(r.samples, int16(binary.LittleEndian.Uint16(b[i:])))
+	}
+	return nil
+}
+
+// findWAVDataChunk returns the offset of the samples in the WAV file.
+func findWAVDataChunk(f io.ReadSeeker) (int64, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(f, riff[:]); err != nil {
+		return 0, err
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return 0, errors.New("not a WAV file")
+	}
+	off := int64(12)
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(f, hdr[:]); err != nil {
+			return 0, err
+		}
+		off += 8
+		if string(hdr[0:4]) == "data" {
+			return off, nil
+		}
+		size := int64(binary.LittleEndian.Uint32(hdr[4:]))
+		size += size & 1 // chunks are word-aligned
+		var err error
+		if off, err = f.Seek(size, io.SeekCurrent); err != nil {
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package backend
+
+import (
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2/theme"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// number of entries in each top list of a YearInReview
+const yearInReviewTopCount = 5
+
+// YearInReview summarizes a year of the local listening history,
+// like the yearly "wrapped" reports of streaming services.
+type YearInReview struct {
+	Year          int
+	ListenedSecs  float64
+	Plays         int
+	UniqueTracks  int
+	UniqueArtists int
+
+	TopArtists []StatsEntry
+	TopAlbums  []StatsEntry
+	TopTracks  []StatsEntry
+
+	// the day with the most listening time; zero Date if no listens
+	MostBingedDay DailyListeningTime
+	// share of listening time per genre, most listened first. Only
+	// listens recorded with the track's genres are counted.
+	Genres []GenreListeningTime
+	// listening time of each month, January first
+	MonthlyListenedSecs [12]float64
+}
+
+type GenreListeningTime struct {
+	Genre        string
+	ListenedSecs float64
+	Fraction     float64 // of the listening time of all genres
+}
+
+// ListenedMinutes returns the total listening time in whole minutes.
+func (y *YearInReview) ListenedMinutes() int {
+	return int(y.ListenedSecs / 60)
+}
+
+// Years returns the years which have listens in the history, newest first.
+func (h *ListeningHistory) Years() []int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	seen := make(map[int]bool)
+	var years []int
+	for _, r := range h.records {
+		if y := r.Time.Local().Year(); !seen[y] {
+			seen[y] = true
+			years = append(years, y)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(years)))
+	return years
+}
+
+// YearInReview computes the summary of the listens in the given year (local time).
+func (h *ListeningHistory) YearInReview(year int) *YearInReview {
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(1, 0, 0).Add(-time.Nanosecond)
+	y := &YearInReview{
+		Year:       year,
+		TopArtists: h.TopArtists(from, to, yearInReviewTopCount),
+		TopAlbums:  h.TopAlbums(from, to, yearInReviewTopCount),
+		TopTracks:  h.TopTracks(from, to, yearInReviewTopCount),
+	}
+
+	tracks := make(map[string]bool)
+	artists := make(map[string]bool)
+	genreSecs := make(map[string]float64)
+	var genreTotal float64
+	for _, r := range h.Records(from, to) {
+		y.ListenedSecs += r.ListenedSecs
+		y.MonthlyListenedSecs[r.Time.Local().Month()-1] += r.ListenedSecs
+		if r.CountsAsPlay() {
+			y.Plays++
+		}
+		tracks[r.ServerID+"\x00"+r.TrackID] = true
+		for _, a := range r.Artists {
+			artists[strings.ToLower(a)] = true
+		}
+		// a listen of a track with several genres is split between them
+		for _, g := range r.Genres {
+			genreSecs[g] += r.ListenedSecs / float64(len(r.Genres))
+		}
+		if len(r.Genres) > 0 {
+			genreTotal += r.ListenedSecs
+		}
+	}
+	y.UniqueTracks = len(tracks)
+	y.UniqueArtists = len(artists)
+
+	for g, secs := range genreSecs {
+		y.Genres = append(y.Genres, GenreListeningTime{Genre: g, ListenedSecs: secs, Fraction: secs / genreTotal})
+	}
+	sort.Slice(y.Genres, func(i, j int) bool { return y.Genres[i].ListenedSecs > y.Genres[j].ListenedSecs })
+
+	for _, d := range h.ListeningTimePerDay(from, to) {
+		if d.ListenedSecs > y.MostBingedDay.ListenedSecs {
+			y.MostBingedDay = d
+		}
+	}
+	return y
+}
+
+var yearInReviewTemplate = template.Must(template.New("review").Funcs(template.FuncMap{
+	"minutes": func(secs float64) int { return int(secs / 60) },
+	"percent": func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+	"month":   func(i int) string { return time.Month(i + 1).String()[:3] },
+	"barPct":  func(secs, max float64) float64 { return secs / max * 100 },
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Year}} in Music</title>
+<style>
+body { font-family: sans-serif; background: #141420; color: #eee; max-width: 720px; margin: 2em auto; padding: 0 1em; }
+h1 { font-size: 2.5em; margin-bottom: 0; }
+.big { font-size: 3em; font-weight: bold; color: #f0a030; }
+h2 { border-bottom: 1px solid #444; padding-bottom: .2em; margin-top: 1.5em; }
+ol li { margin: .3em 0; }
+.sub { color: #aaa; }
+.bar { background: #f0a030; height: 1em; display: inline-block; vertical-align: middle; }
+td { padding: .15em .5em .15em 0; }
+</style></head><body>
+<h1>{{.Year}} in Music</h1>
+<p><span class="big">{{.ListenedMinutes}}</span> minutes listened</p>
+<p>{{.Plays}} plays of {{.UniqueTracks}} different tracks by {{.UniqueArtists}} artists</p>
+{{if not .MostBingedDay.Date.IsZero}}<p>Most binged day: <b>{{.MostBingedDay.Date.Format "Monday, January 2"}}</b>, with {{minutes .MostBingedDay.ListenedSecs}} minutes</p>{{end}}
+{{with .TopArtists}}<h2>Top Artists</h2><ol>{{range .}}<li>{{.Name}} <span class="sub">{{.Plays}} plays</span></li>{{end}}</ol>{{end}}
+{{with .TopAlbums}}<h2>Top Albums</h2><ol>{{range .}}<li>{{.Name}} <span class="sub">{{.Artist}} &middot; {{.Plays}} plays</span></li>{{end}}</ol>{{end}}
+{{with .TopTracks}}<h2>Top Tracks</h2><ol>{{range .}}<li>{{.Name}} <span class="sub">{{.Artist}} &middot; {{.Plays}} plays</span></li>{{end}}</ol>{{end}}
+{{with .Genres}}<h2>Genres</h2><table>{{range .}}<tr><td>{{.Genre}}</td><td><span class="bar" style="width: {{barPct .Fraction 1 | printf "%.0f"}}%"></span> {{percent .Fraction}}</td></tr>{{end}}</table>{{end}}
+<h2>Minutes per Month</h2><table>{{$max := .MaxMonthlySecs}}{{range $i, $secs := .MonthlyListenedSecs}}<tr><td>{{month $i}}</td><td><span class="bar" style="width: {{barPct $secs $max | printf "%.0f"}}%"></span> {{minutes $secs}}</td></tr>{{end}}</table>
+</body></html>
+`))
+
+// MaxMonthlySecs returns the listening time of the month listened most.
+func (y *YearInReview) MaxMonthlySecs() float64 {
+	var m float64
+	for _, secs := range y.MonthlyListenedSecs {
+		m = max(m, secs)
+	}
+	return max(m, 1)
+}
+
+// WriteHTML writes the summary as a standalone HTML page.
+func (y *YearInReview) WriteHTML(w io.Writer) error {
+	return yearInReviewTemplate.Execute(w, y)
+}
+
+const (
+	reviewImageWidth  = 1080
+	reviewImageHeight = 1350
+	reviewImageMargin = 80
+)
+
+var (
+	reviewFontsOnce sync.Once
+	reviewFont      *opentype.Font
+	reviewBoldFont  *opentype.Font
+)
+
+// Image renders the summary as a shareable portrait card image.
+func (y *YearInReview) Image() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, reviewImageWidth, reviewImageHeight))
+	hue := placeholderHue(fmt.Sprint(y.Year))
+	from, to := hslToRGB(hue, 0.5, 0.3), hslToRGB(math.Mod(hue+40, 360), 0.5, 0.1)
+	for py := 0; py < reviewImageHeight; py++ {
+		draw.Draw(img, image.Rect(0, py, reviewImageWidth, py+1),
+			image.NewUniform(lerpRGB(from, to, float64(py)/reviewImageHeight)), image.Point{}, draw.Src)
+	}
+
+	reviewFontsOnce.Do(func() {
+		var err error
+		if reviewFont, err = opentype.Parse(theme.DefaultTextFont().Content()); err != nil {
+			log.Printf("error parsing year in review font: %v", err)
+		}
+		if reviewBoldFont, err = opentype.Parse(theme.DefaultTextBoldFont().Content()); err != nil {
+			log.Printf("error parsing year in review font: %v", err)
+		}
+	})
+	if reviewFont == nil || reviewBoldFont == nil {
+		return img
+	}
+	c := &cardWriter{img: img, y: reviewImageMargin}
+	defer c.close()
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	dim := color.RGBA{R: 255, G: 255, B: 255, A: 170}
+	accent := hslToRGB(math.Mod(hue+180, 360), 0.8, 0.65)
+
+	c.line(reviewBoldFont, 72, white, fmt.Sprintf("%d in Music", y.Year))
+	c.space(30)
+	c.line(reviewBoldFont, 110, accent, fmt.Sprintf("%d", y.ListenedMinutes()))
+	c.line(reviewFont, 36, dim, "minutes listened")
+	c.space(40)
+	section := func(title string, entries []StatsEntry, withArtist bool) {
+		if len(entries) == 0 {
+			return
+		}
+		c.line(reviewBoldFont, 40, accent, title)
+		for i, e := range entries {
+			text := fmt.Sprintf("%d. %s", i+1, e.Name)
+			if withArtist && e.Artist != "" {
+				text += " – " + e.Artist
+			}
+			c.line(reviewFont, 34, white, text)
+		}
+		c.space(30)
+	}
+	section("Top Artists", y.TopArtists, false)
+	section("Top Tracks", y.TopTracks, true)
+	if len(y.Genres) > 0 {
+		c.line(reviewBoldFont, 40, accent, "Top Genre")
+		c.line(reviewFont, 34, white, fmt.Sprintf("%s (%.0f%%)", y.Genres[0].Genre, y.Genres[0].Fraction*100))
+		c.space(30)
+	}
+	if !y.MostBingedDay.Date.IsZero() {
+		c.line(reviewBoldFont, 40, accent, "Most Binged Day")
+		c.line(reviewFont, 34, white, fmt.Sprintf("%s · %d minutes",
+			y.MostBingedDay.Date.Format("January 2"), int(y.MostBingedDay.ListenedSecs/60)))
+	}
+	return img
+}
+
+// cardWriter draws lines of text down an image, left aligned at the margin.
+type cardWriter struct {
+	img   *image.RGBA
+	y     int
+	faces []font.Face
+}
+
+func (c *cardWriter) line(f *opentype.Font, size float64, col color.Color, text string) {
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return
+	}
+	c.faces = append(c.faces, face)
+	m := face.Metrics()
+	c.y += m.Ascent.Ceil()
+	if c.y > reviewImageHeight-reviewImageMargin {
+		return // out of room
+	}
+	d := &font.Drawer{Dst: c.img, Src: image.NewUniform(col), Face: face}
+	text = truncateToWidth(d, text, fixed.I(reviewImageWidth-2*reviewImageMargin))
+	d.Dot = fixed.P(reviewImageMargin, c.y)
+	d.DrawString(text)
+	c.y += m.Descent.Ceil() + int(size*0.25)
+}
+
+func (c *cardWriter) space(px int) {
+	c.y += px
+}
+
+func (c *cardWriter) close() {
+	for _, f := range c.faces {
+		f.Close()
+	}
+}
+
+// truncateToWidth shortens text with an ellipsis to fit within width.
+func truncateToWidth(d *font.Drawer, text string, width fixed.Int26_6) string {
+	if d.MeasureString(text) <= width {
+		return text
+	}
+	r := []rune(text)
+	for len(r) > 0 && d.MeasureString(string(r)+"…") > width {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "…"
+}
@@ -10,9 +10,11 @@ import (
 
 	"github.com/dweymouth/supersonic/backend"
 	"github.com/dweymouth/supersonic/res"
+	"github.com/dweymouth/supersonic/sharedutil"
 	"github.com/dweymouth/supersonic/ui"
 
 	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/lang"
 )
 
 func main() {
@@ -26,6 +28,12 @@ func main() {
 		flag.Usage()
 		return
 	}
+	if *backend.FlagSnapcastControl {
+		if err := backend.RunSnapcastControl(os.Stdin, os.Stdout); err != nil {
+			log.Fatalf("Snapcast control error: %v", err)
+		}
+		return
+	}
 	// rest of flag actions are handled in backend.StartupApp
 
 	myApp, err := backend.StartupApp(res.AppName, res.DisplayName, res.AppVersionTag, res.LatestReleaseURL)
@@ -44,6 +52,7 @@ func main() {
 
 	fyneApp := app.New()
 	fyneApp.SetIcon(res.ResAppicon256Png)
+	sharedutil.SetCollationLocale(lang.SystemLocale().String())
 
 	mainWindow := ui.NewMainWindow(fyneApp, res.AppName, res.DisplayName, res.AppVersion, myApp)
 	myApp.OnReactivate = mainWindow.Show
@@ -12,6 +12,11 @@ const (
 	Copyright        = "Copyright © 2022–2024 Drew Weymouth and contributors"
 )
 
+// DiscordApplicationID is the Discord application that the Rich Presence
+// activity is published as when none is configured. Release builds set it with
+// -ldflags "-X github.com/dweymouth/supersonic/res.DiscordApplicationID=<id>".
+var DiscordApplicationID = ""
+
 var (
 	WhatsAdded = `
 ## Added
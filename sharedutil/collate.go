@@ -0,0 +1,38 @@
+package sharedutil
+
+import (
+	"sync"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+var (
+	collatorLock sync.Mutex // collate.Collator is not safe for concurrent use
+	collator     = newCollator(language.English)
+)
+
+func newCollator(tag language.Tag) *collate.Collator {
+	return collate.New(tag, collate.Loose, collate.Numeric)
+}
+
+// SetCollationLocale sets the locale (BCP 47 tag, e.g. "sv-SE")
+// whose sorting rules are used by CompareStrings.
+func SetCollationLocale(locale string) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return
+	}
+	collatorLock.Lock()
+	collator = newCollator(tag)
+	collatorLock.Unlock()
+}
+
+// CompareStrings compares two strings for sorting lists for display.
+// The comparison follows the collation rules of the user's locale,
+// ignores case and diacritics, and orders embedded numbers by value.
+func CompareStrings(a, b string) int {
+	collatorLock.Lock()
+	defer collatorLock.Unlock()
+	return collator.CompareString(a, b)
+}
@@ -3,6 +3,7 @@ package sharedutil
 import (
 	"slices"
 	"testing"
+	"time"
 
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
 )
@@ -84,3 +85,46 @@ func tracklistsEqual(t *testing.T, a, b []*mediaprovider.Track) bool {
 		return a.ID == b.ID
 	})
 }
+
+func Test_CompareStrings(t *testing.T) {
+	names := []string{"Track 10", "éclair", "Zebra", "track 2", "Eclipse", "Ångström"}
+	slices.SortFunc(names, CompareStrings)
+	want := []string{"Ångström", "éclair", "Eclipse", "track 2", "Track 10", "Zebra"}
+	if !slices.Equal(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+	SetCollationLocale("sv")
+	defer SetCollationLocale("en")
+	slices.SortFunc(names, CompareStrings)
+	if names[len(names)-1] != "Ångström" {
+		t.Errorf("expected Å to sort last in Swedish, got %v", names)
+	}
+}
+
+func Test_TTLCache(t *testing.T) {
+	now := time.Now()
+	c := NewTTLCache[string, int](time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("Get(b) found a value never set")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get(a) found an expired value")
+	}
+	c.Set("b", 2)
+	if len(c.entries) != 1 {
+		t.Errorf("expired entries not removed on Set: %v", c.entries)
+	}
+
+	c.Clear()
+	if _, ok := c.Get("b"); ok {
+		t.Error("Get(b) found a value after Clear")
+	}
+}
@@ -1,8 +1,13 @@
 package ui
 
 import (
+	"context"
+	"image"
+	"log"
+
 	"github.com/dweymouth/supersonic/backend"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/player/dlna"
 	"github.com/dweymouth/supersonic/ui/controller"
 	"github.com/dweymouth/supersonic/ui/layouts"
 	"github.com/dweymouth/supersonic/ui/util"
@@ -16,8 +21,12 @@ import (
 type BottomPanel struct {
 	widget.BaseWidget
 
+	im          *backend.ImageManager
 	imageLoader util.ThumbnailLoader
 
+	signalPath      *widgets.SignalPathPopup
+	signalPathPopUp *widget.PopUp
+
 	NowPlaying  *widgets.NowPlayingCard
 	Controls    *widgets.PlayerControls
 	AuxControls *widgets.AuxControls
@@ -28,7 +37,7 @@ type BottomPanel struct {
 var _ fyne.Widget = (*BottomPanel)(nil)
 
 func NewBottomPanel(pm *backend.PlaybackManager, im *backend.ImageManager, contr *controller.Controller) *BottomPanel {
-	bp := &BottomPanel{}
+	bp := &BottomPanel{im: im}
 	bp.ExtendBaseWidget(bp)
 
 	pm.OnSongChange(bp.onSongChange)
@@ -106,6 +115,15 @@ func NewBottomPanel(pm *backend.PlaybackManager, im *backend.ImageManager, contr
 	bp.AuxControls.OnChangeLoopMode(func() {
 		pm.SetNextLoopMode()
 	})
+	bp.AuxControls.OnShowSignalPath(func(btn fyne.CanvasObject) {
+		bp.showSignalPath(pm, btn)
+	})
+	bp.AuxControls.OnShowOutputDevices(func(btn fyne.CanvasObject) {
+		bp.showOutputDevices(contr, btn)
+	})
+	pm.OnPlayerChange(func() {
+		bp.AuxControls.SetRemoteOutput(contr.App.Renderers.ActiveDevice() != nil)
+	})
 
 	bp.imageLoader = util.NewThumbnailLoader(im, bp.NowPlaying.SetImage)
 
@@ -120,8 +138,65 @@ func (bp *BottomPanel) onSongChange(song mediaprovider.MediaItem, _ *mediaprovid
 		bp.imageLoader.Load("")
 	} else {
 		bp.NowPlaying.Update(song)
-		bp.imageLoader.Load(song.Metadata().CoverArtID)
+		if meta := song.Metadata(); meta.Type == mediaprovider.MediaItemTypeTrack {
+			fb := backend.CoverFallbackForItem(song)
+			bp.imageLoader.LoadWithFallback(fb.CoverArtID, func(cb func(image.Image, error)) context.CancelFunc {
+				return bp.im.GetCoverThumbnailWithFallbackAsync(fb, cb)
+			})
+		} else {
+			bp.imageLoader.Load(meta.CoverArtID)
+		}
+	}
+}
+
+func (bp *BottomPanel) showSignalPath(pm *backend.PlaybackManager, btn fyne.CanvasObject) {
+	if bp.signalPathPopUp == nil {
+		bp.signalPath = widgets.NewSignalPathPopup()
+		bp.signalPathPopUp = widget.NewPopUp(bp.signalPath,
+			fyne.CurrentApp().Driver().CanvasForObject(bp))
+	}
+	bp.signalPath.Update(pm.SignalPath())
+	bp.signalPathPopUp.Resize(bp.signalPathPopUp.MinSize())
+	pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(btn)
+	size := bp.signalPathPopUp.MinSize()
+	bp.signalPathPopUp.ShowAtPosition(fyne.NewPos(
+		pos.X+btn.Size().Width-size.Width, pos.Y-size.Height))
+}
+
+func (bp *BottomPanel) showOutputDevices(contr *controller.Controller, btn fyne.CanvasObject) {
+	r := contr.App.Renderers
+	active := r.ActiveDevice()
+	playTo := func(dev *dlna.Device) {
+		go func() {
+			if err := r.PlayTo(dev); err != nil {
+				log.Printf("error switching output device: %v", err)
+			}
+		}()
+	}
+
+	local := fyne.NewMenuItem("This computer", func() { playTo(nil) })
+	local.Checked = active == nil
+	items := []*fyne.MenuItem{local, fyne.NewMenuItemSeparator()}
+	for _, dev := range r.Devices() {
+		dev := dev
+		item := fyne.NewMenuItem(dev.Name, func() { playTo(&dev) })
+		item.Checked = active != nil && active.UDN == dev.UDN
+		items = append(items, item)
 	}
+	items = append(items, fyne.NewMenuItem("Search for devices...", func() {
+		go func() {
+			if _, err := r.Discover(context.Background()); err != nil {
+				log.Printf("error discovering renderers: %v", err)
+			}
+			bp.showOutputDevices(contr, btn)
+		}()
+	}))
+
+	pop := widget.NewPopUpMenu(fyne.NewMenu("", items...),
+		fyne.CurrentApp().Driver().CanvasForObject(bp))
+	pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(btn)
+	size := pop.MinSize()
+	pop.ShowAtPosition(fyne.NewPos(pos.X+btn.Size().Width-size.Width, pos.Y-size.Height))
 }
 
 func (bp *BottomPanel) CreateRenderer() fyne.WidgetRenderer {
@@ -3,10 +3,12 @@ package browsing
 import (
 	"fmt"
 	"log"
+	"slices"
 	"strconv"
 
 	"github.com/dweymouth/supersonic/backend"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
 	"github.com/dweymouth/supersonic/sharedutil"
 	"github.com/dweymouth/supersonic/ui/controller"
 	myTheme "github.com/dweymouth/supersonic/ui/theme"
@@ -29,10 +31,14 @@ type AlbumPage struct {
 	header       *AlbumPageHeader
 	tracks       []*mediaprovider.Track
 	tracklist    *widgets.Tracklist
+	similar      *widgets.AlbumShelf
 	nowPlayingID string
 	container    *fyne.Container
 }
 
+// max number of albums on the "You Might Also Like" shelf
+const similarAlbumsLimit = 12
+
 type albumPageState struct {
 	albumID string
 	sort    widgets.TracklistSort
@@ -94,8 +100,8 @@ func newAlbumPage(
 	}
 	a.tracklist.SetVisibleColumns(a.cfg.TracklistColumns)
 	a.tracklist.SetSorting(sort)
-	_, canRate := a.mp.(mediaprovider.SupportsRating)
-	_, canShare := a.mp.(mediaprovider.SupportsSharing)
+	_, canRate := mediaprovider.As[mediaprovider.SupportsRating](a.mp)
+	_, canShare := mediaprovider.As[mediaprovider.SupportsSharing](a.mp)
 	a.tracklist.Options.DisableRating = !canRate
 	a.tracklist.Options.DisableSharing = !canShare
 	a.tracklist.OnVisibleColumnsChanged = func(cols []string) {
@@ -103,9 +109,14 @@ func newAlbumPage(
 	}
 	a.contr.ConnectTracklistActionsWithReplayGainAlbum(a.tracklist)
 
+	a.similar = widgets.NewAlbumShelf("You Might Also Like", a.im)
+	a.similar.OnAlbumTapped = func(id string) { a.contr.NavigateTo(controller.AlbumRoute(id)) }
+	a.similar.Hide()
+
 	a.container = container.NewBorder(
 		container.New(&layout.CustomPaddedLayout{LeftPadding: 15, RightPadding: 15, TopPadding: 15, BottomPadding: 10}, a.header),
-		nil, nil, nil, container.New(&layout.CustomPaddedLayout{LeftPadding: 15, RightPadding: 15, BottomPadding: 15}, a.tracklist))
+		container.New(&layout.CustomPaddedLayout{LeftPadding: 15, RightPadding: 15, BottomPadding: 15}, a.similar),
+		nil, nil, container.New(&layout.CustomPaddedLayout{LeftPadding: 15, RightPadding: 15, BottomPadding: 15}, a.tracklist))
 
 	go a.load()
 	return a
@@ -138,6 +149,12 @@ func (a *AlbumPage) OnSongChange(track mediaprovider.MediaItem, lastScrobbledIfA
 	a.tracklist.IncrementPlayCount(sharedutil.MediaItemIDOrEmptyStr(lastScrobbledIfAny))
 }
 
+var _ CanShowRatingChange = (*AlbumPage)(nil)
+
+func (a *AlbumPage) OnRatingChange(trackIDs []string, rating int) {
+	a.tracklist.SetTrackRatings(trackIDs, rating)
+}
+
 func (a *AlbumPage) Reload() {
 	go a.load()
 }
@@ -167,10 +184,21 @@ func (a *AlbumPage) load() {
 		return
 	}
 	a.header.Update(album, a.im)
-	a.tracklist.Options.ShowDiscNumber = len(album.Tracks) > 0 && album.Tracks[0].DiscNumber != album.Tracks[len(album.Tracks)-1].DiscNumber
+	a.tracklist.Options.ShowDiscNumber = album.DiscCount() > 1
 	a.tracks = album.Tracks
 	a.tracklist.SetTracks(album.Tracks)
 	a.tracklist.SetNowPlaying(a.nowPlayingID)
+
+	similar, err := a.mp.GetSimilarAlbums(a.albumID, similarAlbumsLimit)
+	if err != nil {
+		log.Printf("Failed to get similar albums: %s", err.Error())
+		return
+	}
+	if a.disposed || len(similar) == 0 {
+		return
+	}
+	a.similar.SetAlbums(similar)
+	a.similar.Show()
 }
 
 type AlbumPageHeader struct {
@@ -178,6 +206,7 @@ type AlbumPageHeader struct {
 
 	albumID string
 	coverID string
+	album   *mediaprovider.Album
 
 	page *AlbumPage
 
@@ -189,6 +218,7 @@ type AlbumPageHeader struct {
 	genreLabel       *widgets.MultiHyperlink
 	miscLabel        *widget.Label
 	shareMenuItem    *fyne.MenuItem
+	discs            []mediaprovider.Disc // set if the album has multiple discs
 
 	toggleFavButton *widgets.FavoriteButton
 
@@ -232,9 +262,15 @@ func NewAlbumPageHeader(page *AlbumPage) *AlbumPageHeader {
 		a.page.pm.PlayFromBeginning()
 	})
 	var pop *widget.PopUpMenu
+	var popDiscs []mediaprovider.Disc
+	var editions *fyne.MenuItem
 	menuBtn := widget.NewButtonWithIcon("", theme.MoreHorizontalIcon(), nil)
 	menuBtn.OnTapped = func() {
-		if pop == nil {
+		// disc actions depend on the album, so rebuild if the header was reused
+		if pop == nil || !slices.EqualFunc(popDiscs, a.discs, func(a, b mediaprovider.Disc) bool {
+			return a.Number == b.Number && a.Title == b.Title
+		}) {
+			popDiscs = a.discs
 			playNext := fyne.NewMenuItem("Play next", func() {
 				go a.page.pm.LoadAlbum(a.albumID, backend.InsertNext, false /*shuffle*/)
 			})
@@ -256,15 +292,34 @@ func NewAlbumPageHeader(page *AlbumPage) *AlbumPageHeader {
 				a.page.contr.ShowAlbumInfoDialog(a.albumID, a.titleLabel.String(), a.cover.Image())
 			})
 			info.Icon = theme.InfoIcon()
+			editions = fyne.NewMenuItem("Other editions...", func() {
+				if a.album != nil {
+					a.page.contr.ShowEditionsDialog(a.album)
+				}
+			})
+			editions.Icon = myTheme.AlbumIcon
 			a.shareMenuItem = fyne.NewMenuItem("Share...", func() {
 				a.page.contr.ShowShareDialog(a.albumID)
 			})
 			a.shareMenuItem.Icon = myTheme.ShareIcon
-			menu := fyne.NewMenu("", playNext, queue, playlist, download, info, a.shareMenuItem)
+			items := []*fyne.MenuItem{playNext, queue}
+			if _, ok := mediaprovider.As[mediaprovider.SupportsInstantMix](a.page.mp); ok {
+				mix := fyne.NewMenuItem("Play album mix", func() {
+					go a.page.pm.PlayAlbumMix(a.albumID)
+				})
+				mix.Icon = myTheme.ShuffleIcon
+				items = append(items, mix)
+			}
+			items = append(items, playlist, download, info, editions, a.shareMenuItem)
+			if len(a.discs) > 1 {
+				items = append(items, fyne.NewMenuItemSeparator(), a.newDiscsMenuItem())
+			}
+			menu := fyne.NewMenu("", items...)
 			pop = widget.NewPopUpMenu(menu, fyne.CurrentApp().Driver().CanvasForObject(a))
 		}
-		_, canShare := page.mp.(mediaprovider.SupportsSharing)
+		_, canShare := mediaprovider.As[mediaprovider.SupportsSharing](page.mp)
 		a.shareMenuItem.Disabled = !canShare
+		editions.Disabled = helpers.IsSinglesAlbumID(a.albumID)
 		pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(menuBtn)
 		pop.ShowAtPosition(fyne.NewPos(pos.X, pos.Y+menuBtn.Size().Height))
 	}
@@ -299,9 +354,38 @@ func (a *AlbumPageHeader) CreateRenderer() fyne.WidgetRenderer {
 	return widget.NewSimpleRenderer(a.container)
 }
 
+// newDiscsMenuItem returns a menu item with per-disc actions for a multi-disc album.
+func (a *AlbumPageHeader) newDiscsMenuItem() *fyne.MenuItem {
+	shuffleWithin := fyne.NewMenuItem("Shuffle within discs", func() {
+		go a.page.pm.PlayAlbumShuffledWithinDiscs(a.albumID)
+	})
+	shuffleWithin.Icon = myTheme.ShuffleIcon
+	items := []*fyne.MenuItem{shuffleWithin}
+	for _, disc := range a.discs {
+		n := disc.Number
+		name := fmt.Sprintf("disc %d", n)
+		if disc.Title != "" {
+			name += fmt.Sprintf(" (%s)", disc.Title)
+		}
+		play := fyne.NewMenuItem("Play "+name, func() {
+			go a.page.pm.PlayAlbumDisc(a.albumID, n, false)
+		})
+		play.Icon = theme.MediaPlayIcon()
+		queue := fyne.NewMenuItem(fmt.Sprintf("Add %s to queue", name), func() {
+			go a.page.pm.LoadAlbumDisc(a.albumID, n, backend.Append, false)
+		})
+		queue.Icon = theme.ContentAddIcon()
+		items = append(items, play, queue)
+	}
+	discs := fyne.NewMenuItem("Discs", nil)
+	discs.ChildMenu = fyne.NewMenu("", items...)
+	return discs
+}
+
 func (a *AlbumPageHeader) Update(album *mediaprovider.AlbumWithTracks, im *backend.ImageManager) {
 	a.albumID = album.ID
 	a.coverID = album.CoverArtID
+	a.album = &album.Album
 	a.titleLabel.Segments[0].(*widget.TextSegment).Text = album.Name
 	a.releaseTypeLabel.Segments[0].(*widget.TextSegment).Text = util.DisplayReleaseType(album.ReleaseTypes)
 	a.releaseTypeLabel.Refresh() // needed so MinSize returns correct width below
@@ -309,11 +393,25 @@ func (a *AlbumPageHeader) Update(album *mediaprovider.AlbumWithTracks, im *backe
 	a.artistLabel.BuildSegments(album.ArtistNames, album.ArtistIDs)
 	a.genreLabel.BuildSegments(album.Genres, album.Genres)
 	a.miscLabel.SetText(formatMiscLabelStr(album))
+	a.discs = nil
+	if discs := album.Discs(); len(discs) > 1 {
+		a.discs = discs
+	}
 	a.toggleFavButton.IsFavorited = album.Favorite
 	a.Refresh()
 
+	fb := backend.CoverFallback{CoverArtID: album.CoverArtID, Name: album.Name}
+	if len(album.ArtistIDs) > 0 {
+		fb.ArtistID = album.ArtistIDs[0]
+	}
+	if len(album.ArtistNames) > 0 {
+		fb.Artist = album.ArtistNames[0]
+	}
+	if len(album.Genres) > 0 {
+		fb.Genre = album.Genres[0]
+	}
 	go func() {
-		if cover, err := im.GetCoverThumbnail(album.CoverArtID); err == nil {
+		if cover, err := im.GetCoverThumbnailWithFallback(fb); err == nil {
 			a.cover.SetImage(cover, true)
 			a.cover.Refresh()
 		} else {
@@ -337,7 +435,7 @@ func (a *AlbumPageHeader) Clear() {
 
 func (a *AlbumPageHeader) toggleFavorited() {
 	params := mediaprovider.RatingFavoriteParameters{AlbumIDs: []string{a.albumID}}
-	a.page.mp.SetFavorite(params, a.toggleFavButton.IsFavorited)
+	a.page.contr.SetFavorite(params, a.toggleFavButton.IsFavorited)
 }
 
 func (a *AlbumPageHeader) showPopUpCover() {
@@ -358,10 +456,8 @@ func (a *AlbumPageHeader) showPopUpCover() {
 
 func formatMiscLabelStr(a *mediaprovider.AlbumWithTracks) string {
 	var discs string
-	if len(a.Tracks) > 0 {
-		if discCount := a.Tracks[len(a.Tracks)-1].DiscNumber; discCount > 1 {
-			discs = fmt.Sprintf("%d discs · ", discCount)
-		}
+	if discCount := a.DiscCount(); discCount > 1 {
+		discs = fmt.Sprintf("%d discs · ", discCount)
 	}
 	tracks := "tracks"
 	if a.TrackCount == 1 {
@@ -369,7 +465,13 @@ func formatMiscLabelStr(a *mediaprovider.AlbumWithTracks) string {
 	}
 	yearStr := strconv.Itoa(a.Year)
 	if a.ReissueYear > a.Year {
-		yearStr += fmt.Sprintf(" (reissued %d)", a.ReissueYear)
+		reissued := strconv.Itoa(a.ReissueYear)
+		if a.ReleaseDate.Year == a.ReissueYear {
+			reissued = a.ReleaseDate.String()
+		}
+		yearStr += fmt.Sprintf(" (reissued %s)", reissued)
+	} else if a.ReleaseDate.Year == a.Year && a.ReleaseDate.Month > 0 {
+		yearStr = a.ReleaseDate.String()
 	}
 	return fmt.Sprintf("%s · %d %s · %s%s", yearStr, a.TrackCount, tracks, discs, util.SecondsToTimeString(float64(a.Duration)))
 }
@@ -7,6 +7,7 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/dweymouth/supersonic/backend"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
 	"github.com/dweymouth/supersonic/ui/controller"
 	myTheme "github.com/dweymouth/supersonic/ui/theme"
 	"github.com/dweymouth/supersonic/ui/util"
@@ -40,7 +41,7 @@ func (a *albumsPageAdapter) Filter() mediaprovider.AlbumFilter {
 
 func (a *albumsPageAdapter) FilterButton() widgets.FilterButton[mediaprovider.Album, mediaprovider.AlbumFilterOptions] {
 	if a.filterBtn == nil {
-		a.filterBtn = widgets.NewAlbumFilterButton(a.Filter(), a.mp.GetGenres)
+		a.filterBtn = widgets.NewAlbumFilterButton(a.Filter(), a.contr.App.Genres.GetGenres)
 	}
 	return a.filterBtn
 }
@@ -65,11 +66,18 @@ func (a *albumsPageAdapter) SaveSortOrder(order string) {
 func (a *albumsPageAdapter) ActionButton() *widget.Button { return nil }
 
 func (a *albumsPageAdapter) Iter(sortOrder string, filter mediaprovider.AlbumFilter) widgets.GridViewIterator {
-	return widgets.NewGridViewAlbumIterator(a.mp.IterateAlbums(sortOrder, filter))
+	iter := a.mp.IterateAlbums(sortOrder, a.contr.App.Genres.MapAlbumFilter(filter))
+	if a.cfg.CollapseSingles {
+		iter = helpers.NewCollapseSinglesIterator(iter)
+	}
+	if a.cfg.GroupEditions {
+		iter = helpers.NewGroupEditionsIterator(iter)
+	}
+	return widgets.NewGridViewAlbumIterator(iter)
 }
 
 func (a *albumsPageAdapter) SearchIter(query string, filter mediaprovider.AlbumFilter) widgets.GridViewIterator {
-	return widgets.NewGridViewAlbumIterator(a.mp.SearchAlbums(query, filter))
+	return widgets.NewGridViewAlbumIterator(a.mp.SearchAlbums(query, a.contr.App.Genres.MapAlbumFilter(filter)))
 }
 
 func (a *albumsPageAdapter) ConnectGridActions(gv *widgets.GridView) {
@@ -2,7 +2,9 @@ package browsing
 
 import (
 	"log"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/dweymouth/supersonic/backend"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
@@ -148,6 +150,14 @@ func (a *ArtistPage) OnSongChange(track mediaprovider.MediaItem, lastScrobbledIf
 	}
 }
 
+var _ CanShowRatingChange = (*ArtistPage)(nil)
+
+func (a *ArtistPage) OnRatingChange(trackIDs []string, rating int) {
+	if a.tracklistCtr != nil {
+		a.tracklistCtr.Objects[0].(*widgets.Tracklist).SetTrackRatings(trackIDs, rating)
+	}
+}
+
 var _ Scrollable = (*ArtistPage)(nil)
 
 func (g *ArtistPage) Scroll(scrollAmt float32) {
@@ -162,7 +172,13 @@ func (a *ArtistPage) playArtistRadio() {
 
 // should be called asynchronously
 func (a *ArtistPage) load() {
-	artist, err := a.mp.GetArtist(a.artistID)
+	var artist *mediaprovider.ArtistWithAlbums
+	var err error
+	if p, ok := mediaprovider.As[mediaprovider.SupportsGetArtistProgressive](a.mp); ok {
+		artist, err = p.GetArtistProgressive(a.artistID, a.showPartialArtist)
+	} else {
+		artist, err = a.mp.GetArtist(a.artistID)
+	}
 	if err != nil {
 		log.Printf("Failed to get artist: %s", err.Error())
 		return
@@ -170,20 +186,62 @@ func (a *ArtistPage) load() {
 	if a.disposed {
 		return
 	}
+	sortDiscography(artist.Albums)
 	a.artistInfo = artist
 	a.header.Update(artist)
+	if a.albumGrid != nil {
+		// replace the partially loaded discography
+		a.albumGrid.ResetFixed(albumGridModel(artist.Albums))
+	}
 	if a.activeView == 0 {
 		a.showAlbumGrid()
 	} else {
 		a.showTopTracks()
 	}
-	info, err := a.mp.GetArtistInfo(a.artistID)
+	info, err := a.contr.App.ArtistInfo.GetArtistInfo(artist.Artist)
 	if err != nil {
 		log.Printf("Failed to get artist info: %s", err.Error())
 	}
 	a.header.UpdateInfo(info)
 }
 
+// showPartialArtist shows the part of a large discography loaded so far
+func (a *ArtistPage) showPartialArtist(artist *mediaprovider.ArtistWithAlbums) {
+	if a.disposed {
+		return
+	}
+	sortDiscography(artist.Albums)
+	a.artistInfo = artist
+	a.header.Update(artist)
+	if a.albumGrid != nil {
+		a.albumGrid.ResetFixed(albumGridModel(artist.Albums))
+	} else if a.activeView == 0 {
+		a.showAlbumGrid()
+	}
+}
+
+// sortDiscography sorts albums in order of first release, so reissues sit with their era
+func sortDiscography(albums []*mediaprovider.Album) {
+	slices.SortStableFunc(albums, func(a, b *mediaprovider.Album) int {
+		return a.OriginalDate().Compare(b.OriginalDate())
+	})
+}
+
+func albumGridModel(albums []*mediaprovider.Album) []widgets.GridViewItemModel {
+	return sharedutil.MapSlice(albums, func(al *mediaprovider.Album) widgets.GridViewItemModel {
+		item := widgets.GridViewItemModel{
+			Name:       al.Name,
+			ID:         al.ID,
+			CoverArtID: al.CoverArtID,
+			Secondary:  []string{strconv.Itoa(al.Year)},
+		}
+		if len(al.Genres) > 0 {
+			item.Genre = al.Genres[0]
+		}
+		return item
+	})
+}
+
 func (a *ArtistPage) showAlbumGrid() {
 	if a.albumGrid == nil {
 		if a.artistInfo == nil {
@@ -191,14 +249,7 @@ func (a *ArtistPage) showAlbumGrid() {
 			a.activeView = 0 // if page still loading, will show discography view first
 			return
 		}
-		model := sharedutil.MapSlice(a.artistInfo.Albums, func(al *mediaprovider.Album) widgets.GridViewItemModel {
-			return widgets.GridViewItemModel{
-				Name:       al.Name,
-				ID:         al.ID,
-				CoverArtID: al.CoverArtID,
-				Secondary:  []string{strconv.Itoa(al.Year)},
-			}
-		})
+		model := albumGridModel(a.artistInfo.Albums)
 		if g := a.pool.Obtain(util.WidgetTypeGridView); g != nil {
 			a.albumGrid = g.(*widgets.GridView)
 			a.albumGrid.Placeholder = myTheme.AlbumIcon
@@ -219,7 +270,7 @@ func (a *ArtistPage) showTopTracks() {
 			a.activeView = 1 // if page still loading, will show tracks view first
 			return
 		}
-		ts, err := a.mp.GetTopTracks(a.artistInfo.Artist, 20)
+		ts, err := backend.GetTopTracks(a.mp, a.artistInfo.Artist, 20, a.contr.App.Config.Application.LastFMAPIKey)
 		if err != nil {
 			log.Printf("error getting top songs: %s", err.Error())
 			return
@@ -236,8 +287,8 @@ func (a *ArtistPage) showTopTracks() {
 			tl = widgets.NewTracklist(ts, a.im, false)
 		}
 		tl.Options = widgets.TracklistOptions{AutoNumber: true}
-		_, canRate := a.mp.(mediaprovider.SupportsRating)
-		_, canShare := a.mp.(mediaprovider.SupportsSharing)
+		_, canRate := mediaprovider.As[mediaprovider.SupportsRating](a.mp)
+		_, canShare := mediaprovider.As[mediaprovider.SupportsSharing](a.mp)
 		tl.Options.DisableRating = !canRate
 		tl.Options.DisableSharing = !canShare
 		tl.SetVisibleColumns(a.cfg.TracklistColumns)
@@ -291,6 +342,7 @@ type ArtistPageHeader struct {
 	titleDisp      *widget.RichText
 	biographyDisp  *widgets.MaxRowsLabel
 	similarArtists *fyne.Container
+	infoSourceDisp *widget.Label
 	favoriteBtn    *widgets.FavoriteButton
 	playBtn        *widget.Button
 	playRadioBtn   *widget.Button
@@ -307,6 +359,7 @@ func NewArtistPageHeader(page *ArtistPage) *ArtistPageHeader {
 		titleDisp:      widget.NewRichTextWithText(""),
 		biographyDisp:  widgets.NewMaxRowsLabel(5, artistBioNotAvailableStr),
 		similarArtists: container.NewHBox(),
+		infoSourceDisp: widget.NewLabel(""),
 	}
 	a.titleDisp.Segments[0].(*widget.TextSegment).Style = widget.RichTextStyle{
 		SizeName: theme.SizeNameHeadingText,
@@ -360,6 +413,9 @@ func NewArtistPageHeader(page *ArtistPage) *ArtistPageHeader {
 
 	a.biographyDisp.Wrapping = fyne.TextWrapWord
 	a.biographyDisp.Truncation = fyne.TextTruncateEllipsis
+	a.infoSourceDisp.Importance = widget.LowImportance
+	a.infoSourceDisp.Truncation = fyne.TextTruncateEllipsis
+	a.infoSourceDisp.Hide()
 	a.ExtendBaseWidget(a)
 	a.createContainer()
 	return a
@@ -373,6 +429,7 @@ func (a *ArtistPageHeader) Clear() {
 	for _, obj := range a.similarArtists.Objects {
 		obj.Hide()
 	}
+	a.infoSourceDisp.Hide()
 	a.artistImage.SetImage(nil, false)
 }
 
@@ -429,6 +486,17 @@ func (a *ArtistPageHeader) UpdateInfo(info *mediaprovider.ArtistInfo) {
 	}
 	a.similarArtists.Refresh()
 
+	var source []string
+	if len(info.Tags) > 0 {
+		source = append(source, strings.Join(info.Tags, ", "))
+	}
+	if len(info.Sources) > 0 {
+		source = append(source, "Info from "+strings.Join(info.Sources, " and "))
+	}
+	a.infoSourceDisp.SetText(strings.Join(source, " · "))
+	a.infoSourceDisp.Hidden = len(source) == 0
+	a.infoSourceDisp.Refresh()
+
 	if info.ImageURL != "" {
 		if a.artistImage.HaveImage() {
 			_ = a.artistPage.im.RefreshCachedArtistImageIfExpired(a.artistID, info.ImageURL)
@@ -443,7 +511,7 @@ func (a *ArtistPageHeader) UpdateInfo(info *mediaprovider.ArtistInfo) {
 
 func (a *ArtistPageHeader) toggleFavorited() {
 	params := mediaprovider.RatingFavoriteParameters{ArtistIDs: []string{a.artistID}}
-	a.artistPage.mp.SetFavorite(params, a.favoriteBtn.IsFavorited)
+	a.artistPage.contr.SetFavorite(params, a.favoriteBtn.IsFavorited)
 }
 
 func (a *ArtistPageHeader) createContainer() {
@@ -453,7 +521,7 @@ func (a *ArtistPageHeader) createContainer() {
 		container.NewBorder(nil, nil, a.artistImage, nil,
 			container.NewVBox(
 				container.New(layout.NewCustomPaddedVBoxLayout(theme.Padding()-10),
-					a.titleDisp, a.biographyDisp, a.similarArtists),
+					a.titleDisp, a.biographyDisp, a.similarArtists, a.infoSourceDisp),
 				btnContainer),
 		))
 }
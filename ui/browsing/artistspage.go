@@ -70,7 +70,7 @@ func (a *artistsPageAdapter) SearchIter(query string, filter mediaprovider.Artis
 
 func (a *artistsPageAdapter) ConnectGridActions(gv *widgets.GridView) {
 	canShareArtists := false
-	if r, canShare := a.mp.(mediaprovider.SupportsSharing); canShare {
+	if r, canShare := mediaprovider.As[mediaprovider.SupportsSharing](a.mp); canShare {
 		canShareArtists = r.CanShareArtists()
 	}
 	gv.DisableSharing = !canShareArtists
@@ -45,6 +45,12 @@ type CanShowNowPlaying interface {
 	OnSongChange(playing mediaprovider.MediaItem, lastScrobbledIfAny *mediaprovider.Track)
 }
 
+// Pages showing track ratings should implement this interface to update
+// them when a rating is set elsewhere (e.g. from the now playing card).
+type CanShowRatingChange interface {
+	OnRatingChange(trackIDs []string, rating int)
+}
+
 type CanShowPlayTime interface {
 	OnPlayTimeUpdate(curTime, totalTime float64, seeked bool)
 }
@@ -85,6 +91,7 @@ func NewBrowsingPane(app *backend.App, contr *controller.Controller, onGoHome fu
 	b.app.PlaybackManager.OnSongChange(b.onSongChange)
 	b.app.PlaybackManager.OnPlayTimeUpdate(b.onPlayTimeUpdate)
 	b.app.PlaybackManager.OnQueueChange(b.onQueueChange)
+	b.app.EventBus.Subscribe(backend.EventRatingChanged, b.onRatingChange)
 	bkgrnd := myTheme.NewThemedRectangle(myTheme.ColorNamePageBackground)
 	b.pageContainer = container.NewStack(bkgrnd, layout.NewSpacer())
 	b.settingsBtn = widget.NewButtonWithIcon("", theme.SettingsIcon(), func() {
@@ -130,9 +137,10 @@ func (b *BrowsingPane) ClearHistory() {
 	b.updateHistoryButtons()
 }
 
-func (b *BrowsingPane) AddSettingsMenuItem(label string, action func()) {
-	b.settingsMenu.Items = append(b.settingsMenu.Items,
-		fyne.NewMenuItem(label, action))
+func (b *BrowsingPane) AddSettingsMenuItem(label string, action func()) *fyne.MenuItem {
+	item := fyne.NewMenuItem(label, action)
+	b.settingsMenu.Items = append(b.settingsMenu.Items, item)
+	return item
 }
 
 func (b *BrowsingPane) AddSettingsMenuSeparator() {
@@ -224,6 +232,16 @@ func (b *BrowsingPane) onSongChange(song mediaprovider.MediaItem, lastScrobbledI
 	}
 }
 
+func (b *BrowsingPane) onRatingChange(e backend.Event) {
+	if b.curPage == nil {
+		return
+	}
+	if p, ok := b.curPage.(CanShowRatingChange); ok {
+		change := e.Data.(*backend.RatingChange)
+		p.OnRatingChange(change.TrackIDs, change.Rating)
+	}
+}
+
 func (b *BrowsingPane) onPlayTimeUpdate(cur, total float64, seeked bool) {
 	if b.curPage == nil {
 		return
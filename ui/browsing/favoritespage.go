@@ -3,9 +3,11 @@ package browsing
 import (
 	"fmt"
 	"log"
+	"slices"
 
 	"github.com/dweymouth/supersonic/backend"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
 	"github.com/dweymouth/supersonic/sharedutil"
 	"github.com/dweymouth/supersonic/ui/controller"
 	myTheme "github.com/dweymouth/supersonic/ui/theme"
@@ -28,7 +30,10 @@ type FavoritesPage struct {
 	pm    *backend.PlaybackManager
 	im    *backend.ImageManager
 	mp    mediaprovider.MediaProvider
+	fc    *backend.FavoritesCache
+	bus   *backend.EventBus
 
+	unsubscribe       func()
 	disposed          bool
 	trackSort         widgets.TracklistSort
 	filter            mediaprovider.AlbumFilter
@@ -41,14 +46,16 @@ type FavoritesPage struct {
 	searchGridState *widgets.GridViewState
 	artistGrid      *widgets.GridView
 	tracklistCtr    *fyne.Container
+	trackLoader     *widgets.TracklistLoader // if the server can page through favorite tracks
 	searcher        *widgets.SearchEntry
 	filterBtn       *widgets.AlbumFilterButton
+	sortOrder       *sortOrderSelect
 	titleDisp       *widget.RichText
 	toggleBtns      *widgets.ToggleButtonGroup
 	container       *fyne.Container
 }
 
-func NewFavoritesPage(cfg *backend.FavoritesPageConfig, pool *util.WidgetPool, contr *controller.Controller, mp mediaprovider.MediaProvider, pm *backend.PlaybackManager, im *backend.ImageManager) *FavoritesPage {
+func NewFavoritesPage(cfg *backend.FavoritesPageConfig, pool *util.WidgetPool, contr *controller.Controller, mp mediaprovider.MediaProvider, fc *backend.FavoritesCache, bus *backend.EventBus, pm *backend.PlaybackManager, im *backend.ImageManager) *FavoritesPage {
 	a := &FavoritesPage{
 		filter: mediaprovider.NewAlbumFilter(mediaprovider.AlbumFilterOptions{
 			ExcludeUnfavorited: true,
@@ -58,11 +65,14 @@ func NewFavoritesPage(cfg *backend.FavoritesPageConfig, pool *util.WidgetPool, c
 		contr: contr,
 		pm:    pm,
 		mp:    mp,
+		fc:    fc,
+		bus:   bus,
 		im:    im,
 	}
 	a.ExtendBaseWidget(a)
+	a.subscribeToChanges()
 	a.createHeader(0)
-	iter := widgets.NewGridViewAlbumIterator(mp.IterateAlbums("", a.filter))
+	iter := widgets.NewGridViewAlbumIterator(a.albumsIterator())
 	if g := pool.Obtain(util.WidgetTypeGridView); g != nil {
 		a.albumGrid = g.(*widgets.GridView)
 		a.albumGrid.Placeholder = myTheme.AlbumIcon
@@ -96,15 +106,17 @@ func (a *FavoritesPage) createHeader(activeBtnIdx int) {
 	a.searcher.PlaceHolder = "Search page"
 	a.searcher.OnSearched = a.OnSearched
 	a.searcher.Entry.Text = a.searchText
-	a.filterBtn = widgets.NewAlbumFilterButton(a.filter, a.mp.GetGenres)
+	a.filterBtn = widgets.NewAlbumFilterButton(a.filter, a.contr.App.Genres.GetGenres)
 	a.filterBtn.FavoriteDisabled = true
 	a.filterBtn.OnChanged = a.Reload
+	a.sortOrder = NewSortOrderSelect(sharedutil.MapSlice(mediaprovider.FavoritesSortOrders, mediaprovider.FavoritesSortOrder.String), a.onSortOrderChanged)
+	a.sortOrder.Selected = a.favoritesSortOrder().String()
 }
 
 func (a *FavoritesPage) createContainer(initialView fyne.CanvasObject) {
 	searchVbox := container.NewVBox(layout.NewSpacer(), a.searcher, layout.NewSpacer())
 	a.container = container.NewBorder(container.NewHBox(util.NewHSpace(9),
-		a.titleDisp, container.NewCenter(a.toggleBtns), layout.NewSpacer(), container.NewCenter(a.filterBtn), searchVbox, util.NewHSpace(15)),
+		a.titleDisp, container.NewCenter(a.toggleBtns), layout.NewSpacer(), container.NewCenter(a.sortOrder), container.NewCenter(a.filterBtn), searchVbox, util.NewHSpace(15)),
 		nil, nil, nil, initialView)
 }
 
@@ -115,6 +127,8 @@ func restoreFavoritesPage(saved *savedFavoritesPage) *FavoritesPage {
 		pool:            saved.pool,
 		pm:              saved.pm,
 		mp:              saved.mp,
+		fc:              saved.fc,
+		bus:             saved.bus,
 		im:              saved.im,
 		gridState:       saved.gridState,
 		searchGridState: saved.searchGridState,
@@ -123,6 +137,7 @@ func restoreFavoritesPage(saved *savedFavoritesPage) *FavoritesPage {
 		trackSort:       saved.trackSort,
 	}
 	a.ExtendBaseWidget(a)
+	a.subscribeToChanges()
 	a.createHeader(saved.activeToggleBtn)
 	state := saved.gridState
 	if saved.searchText != "" {
@@ -179,52 +194,159 @@ func (a *FavoritesPage) Tapped(*fyne.PointEvent) {
 }
 
 func (a *FavoritesPage) Reload() {
-	// reload favorite albums view
+	a.reloadAlbums()
+	// changes to favorite artists and songs are signaled via the event bus
+	go a.refreshFavorites()
+}
+
+func (a *FavoritesPage) reloadAlbums() {
 	if a.searchText != "" {
 		a.doSearchAlbums(a.searchText)
 	} else {
-		iter := a.mp.IterateAlbums("", a.filter)
-		a.albumGrid.Reset(widgets.NewGridViewAlbumIterator(iter))
+		a.albumGrid.Reset(widgets.NewGridViewAlbumIterator(a.albumsIterator()))
 	}
-	if a.tracklistCtr != nil || a.artistGrid != nil {
-		go func() {
-			// re-fetch starred info from server
-			starred, err := a.mp.GetFavorites()
+}
+
+func (a *FavoritesPage) albumsIterator() mediaprovider.AlbumIterator {
+	if sort := a.favoritesSortOrder(); sort != mediaprovider.FavoritesSortDefault {
+		// the server can't order albums by when they were favorited
+		return a.sortedAlbumsIterator(sort)
+	}
+	return a.mp.IterateAlbums("", a.contr.App.Genres.MapAlbumFilter(a.filter))
+}
+
+// sortedAlbumsIterator iterates over the favorite albums
+// from the FavoritesCache, in the given sort order.
+func (a *FavoritesPage) sortedAlbumsIterator(sort mediaprovider.FavoritesSortOrder) mediaprovider.AlbumIterator {
+	var albums []*mediaprovider.Album
+	return helpers.NewAlbumIterator(func(offs, limit int) ([]*mediaprovider.Album, error) {
+		if offs == 0 {
+			fav, err := a.fc.Get()
 			if err != nil {
-				log.Printf("error getting starred items: %s", err.Error())
-				return
-			}
-			if a.disposed {
-				return
-			}
-			if tr := a.tracklistOrNil(); tr != nil {
-				// refresh favorite songs view
-				tr.SetTracks(starred.Tracks)
-				if a.toggleBtns.ActivatedButtonIndex() == 2 {
-					// favorite songs view is visible
-					tr.Refresh()
-				}
-			}
-			if a.artistGrid != nil {
-				// refresh favorite artists view
-				a.artistGrid.ResetFixed(buildArtistGridViewModel(starred.Artists))
-				if a.toggleBtns.ActivatedButtonIndex() == 1 {
-					// favorite artists view is visible
-					a.artistGrid.Refresh()
-				}
+				return nil, err
 			}
-		}()
+			sorted := mediaprovider.Favorites{Albums: slices.Clone(fav.Albums)}
+			sorted.Sort(sort)
+			albums = sorted.Albums
+		}
+		if offs >= len(albums) {
+			return nil, nil
+		}
+		return albums[offs:min(offs+limit, len(albums))], nil
+	}, a.contr.App.Genres.MapAlbumFilter(a.filter), func(string) {})
+}
+
+func (a *FavoritesPage) subscribeToChanges() {
+	a.unsubscribe = a.bus.Subscribe(backend.EventFavoritesChanged, func(e backend.Event) {
+		if a.disposed {
+			return
+		}
+		diff := e.Data.(*backend.FavoritesDiff)
+		if len(diff.AddedAlbums) > 0 || len(diff.RemovedAlbums) > 0 {
+			a.reloadAlbums()
+		}
+		if len(diff.AddedTracks) > 0 || len(diff.RemovedTracks) > 0 {
+			a.reloadSongs()
+		}
+		if starred := a.fc.Cached(); starred != nil {
+			a.updateFromFavorites(starred)
+		}
+	})
+}
+
+// fetches the latest favorites from the server in the background.
+// If they have changed, the views are updated via the event bus subscription.
+func (a *FavoritesPage) refreshFavorites() {
+	if err := a.fc.Refresh(); err != nil {
+		log.Printf("error getting starred items: %s", err.Error())
+	}
+}
+
+// favoritesSortOrder returns the sort order of the artists and songs views.
+func (a *FavoritesPage) favoritesSortOrder() mediaprovider.FavoritesSortOrder {
+	for _, s := range mediaprovider.FavoritesSortOrders {
+		if s.String() == a.cfg.SortOrder {
+			return s
+		}
+	}
+	return mediaprovider.FavoritesSortDefault
+}
+
+func (a *FavoritesPage) onSortOrderChanged(s string) {
+	a.cfg.SortOrder = s // save setting
+	a.reloadAlbums()
+	a.reloadSongs()
+	if starred := a.fc.Cached(); starred != nil {
+		a.updateFromFavorites(starred)
+	}
+}
+
+// favoriteTracksIterator returns an iterator over the favorite tracks in the
+// selected sort order, if the server can page through them.
+func (a *FavoritesPage) favoriteTracksIterator() (mediaprovider.TrackIterator, bool) {
+	fi, ok := mediaprovider.As[mediaprovider.SupportsFavoriteTrackIterator](a.mp)
+	if !ok {
+		return nil, false
+	}
+	return fi.IterateFavoriteTracks(a.favoritesSortOrder()), true
+}
+
+// reloadSongs reloads the favorite songs view if it is loaded with an
+// iterator. Otherwise it is updated from the FavoritesCache.
+func (a *FavoritesPage) reloadSongs() {
+	tr := a.tracklistOrNil()
+	if tr == nil || a.trackLoader == nil {
+		return
+	}
+	iter, _ := a.favoriteTracksIterator()
+	a.trackLoader.Dispose()
+	tr.Clear()
+	loader := widgets.NewTracklistLoader(tr, iter)
+	a.trackLoader = &loader
+}
+
+// sorted returns a copy of the favorites in the selected sort order,
+// leaving the cached favorites unchanged.
+func (a *FavoritesPage) sorted(starred *mediaprovider.Favorites) *mediaprovider.Favorites {
+	fav := mediaprovider.Favorites{
+		Artists: slices.Clone(starred.Artists),
+		Tracks:  slices.Clone(starred.Tracks),
+	}
+	fav.Sort(a.favoritesSortOrder())
+	return &fav
+}
+
+func (a *FavoritesPage) updateFromFavorites(starred *mediaprovider.Favorites) {
+	starred = a.sorted(starred)
+	if tr := a.tracklistOrNil(); tr != nil && a.trackLoader == nil {
+		// refresh favorite songs view
+		tr.SetTracks(starred.Tracks)
+		if a.toggleBtns.ActivatedButtonIndex() == 2 {
+			// favorite songs view is visible
+			tr.Refresh()
+		}
+	}
+	if a.artistGrid != nil {
+		// refresh favorite artists view
+		a.artistGrid.ResetFixed(buildArtistGridViewModel(starred.Artists))
+		if a.toggleBtns.ActivatedButtonIndex() == 1 {
+			// favorite artists view is visible
+			a.artistGrid.Refresh()
+		}
 	}
 }
 
 func (a *FavoritesPage) Save() SavedPage {
 	a.disposed = true
+	a.unsubscribe()
 	sf := &savedFavoritesPage{
 		cfg:             a.cfg,
 		contr:           a.contr,
 		pool:            a.pool,
 		pm:              a.pm,
 		mp:              a.mp,
+		fc:              a.fc,
+		bus:             a.bus,
 		im:              a.im,
 		filter:          a.filter,
 		searchText:      a.searchText,
@@ -243,6 +365,9 @@ func (a *FavoritesPage) Save() SavedPage {
 		a.artistGrid.Clear()
 		a.pool.Release(util.WidgetTypeGridView, a.artistGrid)
 	}
+	if a.trackLoader != nil {
+		a.trackLoader.Dispose()
+	}
 	if tl := a.tracklistOrNil(); tl != nil {
 		sf.trackSort = tl.Sorting()
 		tl.Clear()
@@ -277,6 +402,14 @@ func (a *FavoritesPage) OnSongChange(item mediaprovider.MediaItem, lastScrobbled
 	}
 }
 
+var _ CanShowRatingChange = (*FavoritesPage)(nil)
+
+func (a *FavoritesPage) OnRatingChange(trackIDs []string, rating int) {
+	if tracklist := a.tracklistOrNil(); tracklist != nil {
+		tracklist.SetTrackRatings(trackIDs, rating)
+	}
+}
+
 var _ CanSelectAll = (*FavoritesPage)(nil)
 
 func (a *FavoritesPage) SelectAll() {
@@ -296,7 +429,7 @@ func (a *FavoritesPage) doSearchAlbums(query string) {
 	if a.searchText == "" {
 		a.gridState = a.albumGrid.SaveToState()
 	}
-	iter := widgets.NewGridViewAlbumIterator(a.mp.SearchAlbums(query, a.filter))
+	iter := widgets.NewGridViewAlbumIterator(a.mp.SearchAlbums(query, a.contr.App.Genres.MapAlbumFilter(a.filter)))
 	a.albumGrid.Reset(iter)
 }
 
@@ -304,6 +437,7 @@ func (a *FavoritesPage) onShowFavoriteAlbums() {
 	a.cfg.InitialView = "Albums" // save setting
 	a.searcher.Entry.Show()
 	a.filterBtn.Show()
+	a.sortOrder.Show()
 	a.container.Objects[0] = a.albumGrid
 	a.Refresh()
 }
@@ -312,6 +446,7 @@ func (a *FavoritesPage) onShowFavoriteArtists() {
 	a.cfg.InitialView = "Artists" // save setting
 	a.searcher.Entry.Hide()       // disable search on artists for now
 	a.filterBtn.Hide()
+	a.sortOrder.Show()
 	if a.artistGrid == nil {
 		if a.pendingViewSwitch {
 			return
@@ -321,7 +456,8 @@ func (a *FavoritesPage) onShowFavoriteArtists() {
 			a.createContainer(layout.NewSpacer())
 		}
 		go func() {
-			fav, err := a.mp.GetFavorites()
+			wasCached := a.fc.Cached() != nil
+			fav, err := a.fc.Get()
 			if err != nil {
 				log.Printf("error getting starred items: %s", err.Error())
 				return
@@ -329,7 +465,7 @@ func (a *FavoritesPage) onShowFavoriteArtists() {
 			if a.disposed {
 				return
 			}
-			model := buildArtistGridViewModel(fav.Artists)
+			model := buildArtistGridViewModel(a.sorted(fav).Artists)
 			if g := a.pool.Obtain(util.WidgetTypeGridView); g != nil {
 				a.artistGrid = g.(*widgets.GridView)
 				a.artistGrid.Placeholder = myTheme.ArtistIcon
@@ -338,7 +474,7 @@ func (a *FavoritesPage) onShowFavoriteArtists() {
 				a.artistGrid = widgets.NewFixedGridView(model, a.im, myTheme.ArtistIcon)
 			}
 			canShareArtists := false
-			if r, canShare := a.mp.(mediaprovider.SupportsSharing); canShare {
+			if r, canShare := mediaprovider.As[mediaprovider.SupportsSharing](a.mp); canShare {
 				canShareArtists = r.CanShareArtists()
 			}
 			a.artistGrid.DisableSharing = !canShareArtists
@@ -346,6 +482,10 @@ func (a *FavoritesPage) onShowFavoriteArtists() {
 			a.container.Objects[0] = a.artistGrid
 			a.Refresh()
 			a.pendingViewSwitch = false
+			if wasCached {
+				// pick up any changes made since favorites were cached
+				a.refreshFavorites()
+			}
 		}()
 	} else {
 		a.container.Objects[0] = a.artistGrid
@@ -374,6 +514,7 @@ func (a *FavoritesPage) onShowFavoriteSongs() {
 	a.cfg.InitialView = "Songs" // save setting
 	a.searcher.Entry.Hide()     // disable search on songs for now
 	a.filterBtn.Hide()
+	a.sortOrder.Show()
 	if a.tracklistCtr == nil {
 		if a.pendingViewSwitch {
 			return
@@ -383,10 +524,16 @@ func (a *FavoritesPage) onShowFavoriteSongs() {
 			a.createContainer(layout.NewSpacer())
 		}
 		go func() {
-			fav, err := a.mp.GetFavorites()
-			if err != nil {
-				log.Printf("error getting starred items: %s", err.Error())
-				return
+			iter, canIterate := a.favoriteTracksIterator()
+			wasCached := a.fc.Cached() != nil
+			var tracks []*mediaprovider.Track
+			if !canIterate {
+				fav, err := a.fc.Get()
+				if err != nil {
+					log.Printf("error getting starred items: %s", err.Error())
+					return
+				}
+				tracks = a.sorted(fav).Tracks
 			}
 			if a.disposed {
 				return
@@ -395,13 +542,13 @@ func (a *FavoritesPage) onShowFavoriteSongs() {
 			if tl := a.pool.Obtain(util.WidgetTypeTracklist); tl != nil {
 				tracklist = tl.(*widgets.Tracklist)
 				tracklist.Reset()
-				tracklist.SetTracks(fav.Tracks)
+				tracklist.SetTracks(tracks)
 			} else {
-				tracklist = widgets.NewTracklist(fav.Tracks, a.im, false)
+				tracklist = widgets.NewTracklist(tracks, a.im, false)
 			}
 			tracklist.Options = widgets.TracklistOptions{AutoNumber: true}
-			_, canRate := a.mp.(mediaprovider.SupportsRating)
-			_, canShare := a.mp.(mediaprovider.SupportsSharing)
+			_, canRate := mediaprovider.As[mediaprovider.SupportsRating](a.mp)
+			_, canShare := mediaprovider.As[mediaprovider.SupportsSharing](a.mp)
 			tracklist.Options.DisableRating = !canRate
 			tracklist.Options.DisableSharing = !canShare
 			tracklist.SetVisibleColumns(a.cfg.TracklistColumns)
@@ -414,9 +561,18 @@ func (a *FavoritesPage) onShowFavoriteSongs() {
 			a.tracklistCtr = container.New(
 				&layout.CustomPaddedLayout{LeftPadding: 15, RightPadding: 15, TopPadding: 5, BottomPadding: 15},
 				tracklist)
+			if canIterate {
+				// loads asynchronously as the list is scrolled
+				loader := widgets.NewTracklistLoader(tracklist, iter)
+				a.trackLoader = &loader
+			}
 			a.container.Objects[0] = a.tracklistCtr
 			a.Refresh()
 			a.pendingViewSwitch = false
+			if wasCached && !canIterate {
+				// pick up any changes made since favorites were cached
+				a.refreshFavorites()
+			}
 		}()
 	} else {
 		a.container.Objects[0] = a.tracklistCtr
@@ -435,6 +591,8 @@ type savedFavoritesPage struct {
 	pool            *util.WidgetPool
 	pm              *backend.PlaybackManager
 	mp              mediaprovider.MediaProvider
+	fc              *backend.FavoritesCache
+	bus             *backend.EventBus
 	im              *backend.ImageManager
 	gridState       *widgets.GridViewState
 	searchGridState *widgets.GridViewState
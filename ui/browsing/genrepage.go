@@ -56,16 +56,20 @@ func (g *genrePageAdapter) Route() controller.Route {
 }
 
 func (g *genrePageAdapter) ActionButton() *widget.Button {
+	if _, ok := mediaprovider.As[mediaprovider.SupportsInstantMix](g.mp); ok {
+		fn := func() { go g.pm.PlayGenreMix(g.genre) }
+		return widget.NewButtonWithIcon("Play mix", myTheme.ShuffleIcon, fn)
+	}
 	fn := func() { go g.pm.PlayRandomSongs(g.genre) }
 	return widget.NewButtonWithIcon("Play random", myTheme.ShuffleIcon, fn)
 }
 
 func (a *genrePageAdapter) Iter(sortOrder string, filter mediaprovider.AlbumFilter) widgets.GridViewIterator {
-	return widgets.NewGridViewAlbumIterator(a.mp.IterateAlbums(sortOrder, filter))
+	return widgets.NewGridViewAlbumIterator(a.mp.IterateAlbums(sortOrder, a.contr.App.Genres.MapAlbumFilter(filter)))
 }
 
 func (a *genrePageAdapter) SearchIter(query string, filter mediaprovider.AlbumFilter) widgets.GridViewIterator {
-	return widgets.NewGridViewAlbumIterator(a.mp.SearchAlbums(query, filter))
+	return widgets.NewGridViewAlbumIterator(a.mp.SearchAlbums(query, a.contr.App.Genres.MapAlbumFilter(filter)))
 }
 
 func (g *genrePageAdapter) ConnectGridActions(gv *widgets.GridView) {
@@ -59,7 +59,7 @@ func newGenresPage(contr *controller.Controller, mp mediaprovider.MediaProvider,
 
 // should be called asynchronously
 func (a *GenresPage) load(searchOnLoad bool, scrollPos float32) {
-	genres, err := a.mp.GetGenres()
+	genres, err := a.contr.App.Genres.GetGenres()
 	if err != nil {
 		log.Printf("error loading genres: %v", err.Error())
 	}
@@ -111,6 +111,7 @@ func (a *GenresPage) Route() controller.Route {
 }
 
 func (a *GenresPage) Reload() {
+	a.contr.App.Genres.Refresh()
 	go a.load(false, 0)
 }
 
@@ -267,7 +268,7 @@ func (g *GenreList) stringSort(fieldFn func(*mediaprovider.Genre) string) {
 	new := make([]*mediaprovider.Genre, len(g.genresOrigOrder))
 	copy(new, g.genresOrigOrder)
 	sort.SliceStable(new, func(i, j int) bool {
-		cmp := strings.Compare(fieldFn(new[i]), fieldFn(new[j]))
+		cmp := sharedutil.CompareStrings(fieldFn(new[i]), fieldFn(new[j]))
 		if g.sorting.Type == widgets.SortDescending {
 			return cmp > 0
 		}
@@ -114,7 +114,7 @@ func NewGridViewPage[M, F any](
 	gp.ExtendBaseWidget(gp)
 	gp.createTitleAndSort()
 
-	_, canShare := mp.(mediaprovider.SupportsSharing)
+	_, canShare := mediaprovider.As[mediaprovider.SupportsSharing](mp)
 	iter := adapter.Iter(gp.getSortOrder(), gp.getFilter())
 	if g := pool.Obtain(util.WidgetTypeGridView); g != nil {
 		gp.grid = g.(*widgets.GridView)
@@ -12,7 +12,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/cenkalti/dominantcolor"
 	"github.com/dweymouth/supersonic/backend"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
 	"github.com/dweymouth/supersonic/backend/player"
@@ -78,7 +77,7 @@ type nowPlayingPageState struct {
 	mp       mediaprovider.MediaProvider
 	canRate  bool
 	canShare bool
-	lrcLib   bool
+	lyrics   *backend.LyricsFetcher
 }
 
 func NewNowPlayingPage(
@@ -91,10 +90,10 @@ func NewNowPlayingPage(
 	mp mediaprovider.MediaProvider,
 	canRate bool,
 	canShare bool,
-	lrcLibEnabled bool,
+	lyrics *backend.LyricsFetcher,
 ) *NowPlayingPage {
 	state := nowPlayingPageState{
-		conf: conf, contr: contr, pool: pool, sm: sm, im: im, pm: pm, mp: mp, canRate: canRate, canShare: canShare, lrcLib: lrcLibEnabled,
+		conf: conf, contr: contr, pool: pool, sm: sm, im: im, pm: pm, mp: mp, canRate: canRate, canShare: canShare, lyrics: lyrics,
 	}
 	if page, ok := pool.Obtain(util.WidgetTypeNowPlayingPage).(*NowPlayingPage); ok && page != nil {
 		page.nowPlayingPageState = state
@@ -295,7 +294,11 @@ func (a *NowPlayingPage) onImageLoaded(img image.Image, err error) {
 		return
 	}
 	a.card.SetCoverImage(img)
-	c := dominantcolor.Find(img)
+	var coverID string
+	if a.nowPlaying != nil {
+		coverID = a.nowPlaying.Metadata().CoverArtID
+	}
+	c := a.contr.App.CoverPalettes.Extract(coverID, img).Dominant
 	if c == a.background.StartColor {
 		return
 	}
@@ -347,19 +350,7 @@ func (a *NowPlayingPage) updateLyrics() {
 }
 
 func (a *NowPlayingPage) fetchLyrics(ctx context.Context, song *mediaprovider.Track) {
-	var lyrics *mediaprovider.Lyrics
-	var err error
-	if lp, ok := a.sm.Server.(mediaprovider.LyricsProvider); ok {
-		if lyrics, err = lp.GetLyrics(song); err != nil {
-			log.Printf("Error fetching lyrics: %v", err)
-		}
-	}
-	if lyrics == nil {
-		lyrics, err = backend.FetchLrcLibLyrics(song.Title, song.ArtistNames[0], song.Album, song.Duration)
-		if err != nil {
-			log.Println(err.Error())
-		}
-	}
+	lyrics := a.lyrics.FetchLyrics(song)
 	select {
 	case <-ctx.Done():
 		return
@@ -443,7 +434,7 @@ func (a *NowPlayingPage) Reload() {
 }
 
 func (s *nowPlayingPageState) Restore() Page {
-	return NewNowPlayingPage(s.conf, s.contr, s.pool, s.sm, s.im, s.pm, s.mp, s.canRate, s.canShare, s.lrcLib)
+	return NewNowPlayingPage(s.conf, s.contr, s.pool, s.sm, s.im, s.pm, s.mp, s.canRate, s.canShare, s.lyrics)
 }
 
 var _ CanShowPlayTime = (*NowPlayingPage)(nil)
@@ -557,6 +548,10 @@ func (a *NowPlayingPage) formatStatusLine() {
 	mediaInfo := ""
 	if state != "Stopped" {
 		mediaInfo = a.formatMediaInfoStr(curPlayer)
+		if tr, ok := a.pm.NowPlaying().(*mediaprovider.Track); ok && mediaInfo == "" {
+			// e.g. playing on a remote player, which can't report the decoded stream info
+			mediaInfo = tr.AudioFormat()
+		}
 	}
 	if mediaInfo != "" {
 		mediaInfo = " · " + mediaInfo
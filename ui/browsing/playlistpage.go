@@ -3,6 +3,7 @@ package browsing
 import (
 	"fmt"
 	"log"
+	"slices"
 
 	"github.com/dweymouth/supersonic/backend"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
@@ -25,11 +26,15 @@ type PlaylistPage struct {
 	playlistPageState
 
 	disposed     bool
+	unsubscribe  func()
 	header       *PlaylistPageHeader
 	tracklist    *widgets.Tracklist
 	tracks       []*mediaprovider.Track
 	nowPlayingID string
 	container    *fyne.Container
+
+	// track editing actions, disabled if the playlist is read-only
+	editMenuItems []*fyne.MenuItem
 }
 
 type playlistPageState struct {
@@ -86,17 +91,18 @@ func newPlaylistPage(
 	a.tracklist.OnVisibleColumnsChanged = func(cols []string) {
 		conf.TracklistColumns = cols
 	}
-	_, canRate := a.sm.Server.(mediaprovider.SupportsRating)
-	_, canShare := a.sm.Server.(mediaprovider.SupportsSharing)
+	_, canRate := mediaprovider.As[mediaprovider.SupportsRating](a.sm.Server)
+	_, canShare := mediaprovider.As[mediaprovider.SupportsSharing](a.sm.Server)
 	remove := fyne.NewMenuItem("Remove from playlist", a.onRemoveSelectedFromPlaylist)
 	remove.Icon = theme.ContentClearIcon()
+	a.editMenuItems = []*fyne.MenuItem{
+		util.NewReorderTracksSubmenu(a.doSetNewTrackOrder),
+		remove,
+	}
 	a.tracklist.Options = widgets.TracklistOptions{
-		DisableRating:  !canRate,
-		DisableSharing: !canShare,
-		AuxiliaryMenuItems: []*fyne.MenuItem{
-			util.NewReorderTracksSubmenu(a.doSetNewTrackOrder),
-			remove,
-		},
+		DisableRating:      !canRate,
+		DisableSharing:     !canShare,
+		AuxiliaryMenuItems: a.editMenuItems,
 	}
 	// connect tracklist actions
 	a.contr.ConnectTracklistActions(a.tracklist)
@@ -104,6 +110,11 @@ func newPlaylistPage(
 	a.container = container.NewBorder(
 		container.New(&layout.CustomPaddedLayout{LeftPadding: 15, RightPadding: 15, TopPadding: 15, BottomPadding: 10}, a.header),
 		nil, nil, nil, container.New(&layout.CustomPaddedLayout{LeftPadding: 15, RightPadding: 15, BottomPadding: 15}, a.tracklist))
+	a.unsubscribe = contr.App.EventBus.Subscribe(backend.EventPlaylistChanged, func(e backend.Event) {
+		if id := e.Data.(string); id == "" || id == a.playlistID {
+			a.Reload()
+		}
+	})
 	go a.load()
 	return a
 }
@@ -114,6 +125,7 @@ func (a *PlaylistPage) CreateRenderer() fyne.WidgetRenderer {
 
 func (a *PlaylistPage) Save() SavedPage {
 	a.disposed = true
+	a.unsubscribe()
 	p := a.playlistPageState
 	p.trackSort = a.tracklist.Sorting()
 	p.widgetPool.Release(util.WidgetTypePlaylistPageHeader, a.header)
@@ -134,6 +146,12 @@ func (a *PlaylistPage) OnSongChange(item mediaprovider.MediaItem, lastScrobbledI
 	a.tracklist.IncrementPlayCount(sharedutil.MediaItemIDOrEmptyStr(lastScrobbledIfAny))
 }
 
+var _ CanShowRatingChange = (*PlaylistPage)(nil)
+
+func (a *PlaylistPage) OnRatingChange(trackIDs []string, rating int) {
+	a.tracklist.SetTrackRatings(trackIDs, rating)
+}
+
 func (a *PlaylistPage) Reload() {
 	go a.load()
 }
@@ -163,6 +181,9 @@ func (a *PlaylistPage) load() {
 		return
 	}
 	renumberTracks(playlist.Tracks)
+	for _, item := range a.editMenuItems {
+		item.Disabled = !playlist.CanEdit(a.sm.LoggedInUser)
+	}
 	a.tracks = playlist.Tracks
 	a.tracklist.SetTracks(playlist.Tracks)
 	a.tracklist.SetNowPlaying(a.nowPlayingID)
@@ -190,8 +211,14 @@ func (a *PlaylistPage) doSetNewTrackOrder(op sharedutil.TrackReorderOp) {
 		}
 	}
 	newTracks := sharedutil.ReorderItems(a.tracks, idxs, op)
-	ids := sharedutil.TracksToIDs(newTracks)
-	if err := a.sm.Server.ReplacePlaylistTracks(a.playlistID, ids); err != nil {
+	var err error
+	if mover, ok := mediaprovider.As[mediaprovider.SupportsPlaylistTrackMove](a.sm.Server); ok && len(idxs) == 1 {
+		toIdx := slices.Index(newTracks, a.tracks[idxs[0]])
+		err = mover.MovePlaylistTrack(a.playlistID, idxs[0], toIdx)
+	} else {
+		err = a.sm.Server.ReplacePlaylistTracks(a.playlistID, sharedutil.TracksToIDs(newTracks))
+	}
+	if err != nil {
 		log.Printf("error updating playlist: %s", err.Error())
 	} else {
 		renumberTracks(newTracks)
@@ -216,6 +243,15 @@ func (a *PlaylistPage) onRemoveSelectedFromPlaylist() {
 	a.Reload()
 }
 
+func (a *PlaylistPage) updateDescription(playlist *mediaprovider.PlaylistWithTracks, description string) {
+	err := a.sm.Server.EditPlaylist(playlist.ID, playlist.Name, description, playlist.Public)
+	if err != nil {
+		log.Printf("error updating playlist description: %v", err)
+	}
+	// reload to show the saved description, or revert on failure
+	a.Reload()
+}
+
 type PlaylistPageHeader struct {
 	widget.BaseWidget
 
@@ -223,12 +259,12 @@ type PlaylistPageHeader struct {
 	playlistInfo *mediaprovider.PlaylistWithTracks
 	image        *widgets.ImagePlaceholder
 
-	editButton       *widget.Button
-	titleLabel       *widget.RichText
-	descriptionLabel *widget.Label
-	createdAtLabel   *widget.Label
-	ownerLabel       *widget.Label
-	trackTimeLabel   *widget.Label
+	editButton     *widget.Button
+	titleLabel     *widget.RichText
+	description    *widgets.EditableDescription
+	createdAtLabel *widget.Label
+	ownerLabel     *widget.Label
+	trackTimeLabel *widget.Label
 
 	container *fyne.Container
 }
@@ -244,7 +280,12 @@ func NewPlaylistPageHeader(page *PlaylistPage) *PlaylistPageHeader {
 	a.titleLabel.Segments[0].(*widget.TextSegment).Style = widget.RichTextStyle{
 		SizeName: theme.SizeNameHeadingText,
 	}
-	a.descriptionLabel = util.NewTruncatingLabel()
+	a.description = widgets.NewEditableDescription(2)
+	a.description.OnSave = func(description string) {
+		if pl := a.playlistInfo; pl != nil {
+			go a.page.updateDescription(pl, description)
+		}
+	}
 	a.ownerLabel = util.NewTruncatingLabel()
 	a.createdAtLabel = widget.NewLabel("")
 	a.trackTimeLabel = widget.NewLabel("")
@@ -263,6 +304,7 @@ func NewPlaylistPageHeader(page *PlaylistPage) *PlaylistPageHeader {
 		a.page.pm.PlayFromBeginning()
 	})
 	var pop *widget.PopUpMenu
+	var removeDups, setCover, pin, noScrobble *fyne.MenuItem
 	menuBtn := widget.NewButtonWithIcon("", theme.MoreHorizontalIcon(), nil)
 	menuBtn.OnTapped = func() {
 		if pop == nil {
@@ -283,9 +325,46 @@ func NewPlaylistPageHeader(page *PlaylistPage) *PlaylistPageHeader {
 				a.page.contr.ShowDownloadDialog(a.page.tracks, a.titleLabel.String())
 			})
 			download.Icon = theme.DownloadIcon()
-			menu := fyne.NewMenu("", playNext, queue, playlist, download)
+			removeDups = fyne.NewMenuItem("Remove duplicates...", func() {
+				a.page.contr.DoRemovePlaylistDuplicatesWorkflow(a.page.playlistID, a.page.Reload)
+			})
+			removeDups.Icon = theme.ContentRemoveIcon()
+			setCover = fyne.NewMenuItem("Set cover image...", func() {
+				if pl := a.playlistInfo; pl != nil {
+					a.page.contr.DoUploadPlaylistCoverWorkflow(&pl.Playlist, a.page.Reload)
+				}
+			})
+			setCover.Icon = theme.FileImageIcon()
+			coverAlbum := fyne.NewMenuItem("Use album cover...", func() {
+				if pl := a.playlistInfo; pl != nil {
+					a.page.contr.DoChoosePlaylistCoverAlbumWorkflow(pl, a.page.Reload)
+				}
+			})
+			coverAlbum.Icon = myTheme.AlbumIcon
+			pin = fyne.NewMenuItem("Pin to top", func() {
+				folders := a.page.contr.App.PlaylistFolders
+				folders.SetPinned(a.page.playlistID, !folders.IsPinned(a.page.playlistID))
+			})
+			pin.Icon = theme.UploadIcon()
+			moveToFolder := fyne.NewMenuItem("Move to folder...", func() {
+				a.page.contr.DoMovePlaylistToFolderWorkflow(a.page.playlistID, nil)
+			})
+			moveToFolder.Icon = theme.FolderIcon()
+			noScrobble = fyne.NewMenuItem("Don't scrobble tracks", func() {
+				sm := a.page.sm
+				sm.SetPlaylistScrobbled(a.page.playlistID, !sm.IsPlaylistScrobbled(a.page.playlistID))
+			})
+			menu := fyne.NewMenu("", playNext, queue, playlist, download, removeDups,
+				fyne.NewMenuItemSeparator(), setCover, coverAlbum,
+				fyne.NewMenuItemSeparator(), pin, moveToFolder, noScrobble)
 			pop = widget.NewPopUpMenu(menu, fyne.CurrentApp().Driver().CanvasForObject(a))
 		}
+		removeDups.Disabled = a.playlistInfo == nil || !a.playlistInfo.CanEdit(a.page.sm.LoggedInUser)
+		_, canUploadCover := mediaprovider.As[mediaprovider.SupportsPlaylistCoverUpload](a.page.sm.Server)
+		setCover.Disabled = a.editButton.Hidden || !canUploadCover
+		pin.Checked = a.page.contr.App.PlaylistFolders.IsPinned(a.page.playlistID)
+		noScrobble.Checked = !a.page.sm.IsPlaylistScrobbled(a.page.playlistID)
+		pop.Refresh()
 		pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(menuBtn)
 		pop.ShowAtPosition(fyne.NewPos(pos.X, pos.Y+menuBtn.Size().Height))
 	}
@@ -293,7 +372,7 @@ func NewPlaylistPageHeader(page *PlaylistPage) *PlaylistPageHeader {
 	a.container = util.AddHeaderBackground(
 		container.NewBorder(nil, nil, a.image, nil,
 			container.NewVBox(a.titleLabel, container.New(layout.NewCustomPaddedVBoxLayout(theme.Padding()-10),
-				a.descriptionLabel,
+				a.description,
 				a.ownerLabel,
 				a.trackTimeLabel),
 				container.NewHBox(a.editButton, playButton, shuffleBtn, menuBtn),
@@ -304,7 +383,7 @@ func NewPlaylistPageHeader(page *PlaylistPage) *PlaylistPageHeader {
 func (a *PlaylistPageHeader) Clear() {
 	a.titleLabel.Segments[0].(*widget.TextSegment).Text = ""
 	a.createdAtLabel.Text = ""
-	a.descriptionLabel.Text = ""
+	a.description.SetText("")
 	a.ownerLabel.Text = ""
 	a.image.SetImage(nil, false)
 }
@@ -317,22 +396,19 @@ func (a *PlaylistPageHeader) Update(playlist *mediaprovider.PlaylistWithTracks)
 	a.playlistInfo = playlist
 	a.editButton.Hidden = playlist.Owner != a.page.sm.LoggedInUser
 	a.titleLabel.Segments[0].(*widget.TextSegment).Text = playlist.Name
-	a.descriptionLabel.SetText(playlist.Description)
+	a.description.SetEditable(!a.editButton.Hidden)
+	a.description.SetText(playlist.Description)
 	a.ownerLabel.SetText(a.formatPlaylistOwnerStr(playlist))
 	a.trackTimeLabel.SetText(a.formatPlaylistTrackTimeStr(playlist))
 	a.createdAtLabel.SetText("created at TODO")
 
-	var haveCover bool
-	if playlist.CoverArtID != "" {
-		if im, err := a.page.im.GetCoverThumbnail(playlist.CoverArtID); err == nil && im != nil {
-			a.image.SetImage(im, false /*tappable*/)
-			haveCover = true
-		}
+	coverID := playlist.CoverArtID
+	if coverID == "" {
+		coverID = playlist.ID
 	}
-	if !haveCover {
-		if im, err := a.page.im.GetCoverThumbnail(playlist.ID); err == nil && im != nil {
-			a.image.SetImage(im, false)
-		}
+	fb := backend.CoverFallback{CoverArtID: coverID, PlaylistID: playlist.ID, Name: playlist.Name}
+	if im, err := a.page.im.GetCoverThumbnailWithFallback(fb); err == nil && im != nil {
+		a.image.SetImage(im, false /*tappable*/)
 	}
 	a.Refresh()
 }
@@ -342,6 +418,9 @@ func (a *PlaylistPageHeader) formatPlaylistOwnerStr(p *mediaprovider.PlaylistWit
 	if !p.Public {
 		pubPriv = "Private"
 	}
+	if p.Collaborative {
+		pubPriv += " collaborative"
+	}
 	return fmt.Sprintf("%s playlist by %s", pubPriv, p.Owner)
 }
 
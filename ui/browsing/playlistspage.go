@@ -3,6 +3,7 @@ package browsing
 import (
 	"fmt"
 	"log"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -31,19 +32,24 @@ type PlaylistsPage struct {
 	cfg               *backend.PlaylistsPageConfig
 	contr             *controller.Controller
 	mp                mediaprovider.MediaProvider
-	playlists         []*mediaprovider.Playlist
+	allPlaylists      []*mediaprovider.Playlist
+	playlists         []*mediaprovider.Playlist // filtered by owner
 	searchedPlaylists []*mediaprovider.Playlist
 
-	viewToggle *widgets.ToggleButtonGroup
-	searcher   *widgets.SearchEntry
-	titleDisp  *widget.RichText
-	container  *fyne.Container
-	listView   *PlaylistList
-	listSort   widgets.ListHeaderSort
-	gridView   *widgets.GridView
+	viewToggle   *widgets.ToggleButtonGroup
+	ownerToggle  *widgets.ToggleButtonGroup
+	folderSelect *widget.Select
+	folderIDs    []string
+	searcher     *widgets.SearchEntry
+	titleDisp    *widget.RichText
+	container    *fyne.Container
+	listView     *PlaylistList
+	listSort     widgets.ListHeaderSort
+	gridView     *widgets.GridView
 
 	initialListScrollPos float32
 	initialGridScrollPos float32
+	unsubscribe          func()
 }
 
 func NewPlaylistsPage(contr *controller.Controller, pool *util.WidgetPool, cfg *backend.PlaylistsPageConfig, mp mediaprovider.MediaProvider) *PlaylistsPage {
@@ -85,6 +91,18 @@ func newPlaylistsPage(
 		widget.NewButtonWithIcon("", theme.NewThemedResource(res.ResListSvg), a.showListView),
 		widget.NewButtonWithIcon("", theme.NewThemedResource(res.ResGridSvg), a.showGridView))
 	a.viewToggle.SetActivatedButton(activeView)
+	ownerIdx := 0
+	if cfg.ShowOthersPlaylists {
+		ownerIdx = 1
+	}
+	a.ownerToggle = widgets.NewToggleButtonGroup(ownerIdx,
+		widget.NewButton("Mine", func() { a.setShowOthersPlaylists(false) }),
+		widget.NewButton("Others'", func() { a.setShowOthersPlaylists(true) }))
+	a.folderSelect = widget.NewSelect(nil, nil)
+	a.refreshFolders()
+	a.folderSelect.OnChanged = func(string) {
+		a.filterByOwner(a.searcher.Entry.Text != "")
+	}
 	if activeView == 0 {
 		a.createListView()
 		a.buildContainer(a.listView)
@@ -93,6 +111,9 @@ func newPlaylistsPage(
 		a.buildContainer(a.gridView)
 	}
 
+	a.unsubscribe = contr.App.EventBus.Subscribe(backend.EventPlaylistChanged, func(backend.Event) {
+		a.Reload()
+	})
 	go a.load(searchText != "")
 	return a
 }
@@ -112,12 +133,46 @@ func (a *PlaylistsPage) load(searchOnLoad bool) {
 	if err != nil {
 		log.Printf("error loading playlists: %v", err.Error())
 	}
-	a.playlists = playlists
-	if searchOnLoad {
+	a.allPlaylists = playlists
+	a.filterByOwner(searchOnLoad)
+}
+
+// filterByOwner shows either the user's own playlists (including those
+// shared with the user) or the public playlists of other users, which are read-only.
+func (a *PlaylistsPage) filterByOwner(search bool) {
+	user := a.contr.App.ServerManager.LoggedInUser
+	playlists := sharedutil.FilterSlice(a.allPlaylists, func(p *mediaprovider.Playlist) bool {
+		isOthers := p.Owner != user && !p.Collaborative
+		return isOthers == a.cfg.ShowOthersPlaylists
+	})
+	// order by the user's folders, pinning and custom ordering
+	var folderID string
+	if idx := a.folderSelect.SelectedIndex(); idx > 0 {
+		folderID = a.folderIDs[idx]
+	}
+	a.playlists = backend.FlattenPlaylistTree(a.contr.App.PlaylistFolders.Tree(playlists), folderID)
+	if search {
 		a.onSearched(a.searcher.Entry.Text)
 	} else {
-		a.refreshView(playlists)
+		a.refreshView(a.playlists)
+	}
+}
+
+// refreshFolders updates the folder options, keeping the selected folder
+func (a *PlaylistsPage) refreshFolders() {
+	var selected string
+	if idx := a.folderSelect.SelectedIndex(); idx > 0 {
+		selected = a.folderIDs[idx]
 	}
+	ids, names := a.contr.PlaylistFolderOptions("All folders")
+	a.folderIDs = ids
+	a.folderSelect.Options = names
+	a.folderSelect.SetSelectedIndex(max(0, slices.Index(ids, selected)))
+}
+
+func (a *PlaylistsPage) setShowOthersPlaylists(others bool) {
+	a.cfg.ShowOthersPlaylists = others // save setting
+	a.filterByOwner(a.searcher.Entry.Text != "")
 }
 
 func (a *PlaylistsPage) createListView() {
@@ -201,6 +256,7 @@ func createPlaylistGridViewModel(playlists []*mediaprovider.Playlist) []widgets.
 			Name:       pl.Name,
 			ID:         pl.ID,
 			CoverArtID: pl.CoverArtID,
+			PlaylistID: pl.ID,
 			Secondary:  []string{fmt.Sprintf("%d %s", pl.TrackCount, tracks)},
 		}
 	})
@@ -265,10 +321,12 @@ func (a *PlaylistsPage) Route() controller.Route {
 }
 
 func (a *PlaylistsPage) Reload() {
+	a.refreshFolders()
 	go a.load(a.searcher.Entry.Text != "")
 }
 
 func (a *PlaylistsPage) Save() SavedPage {
+	a.unsubscribe()
 	s := &savedPlaylistsPage{
 		contr:      a.contr,
 		pool:       a.pool,
@@ -309,7 +367,7 @@ func (a *PlaylistsPage) buildContainer(initialView fyne.CanvasObject) {
 	searchVbox := container.NewVBox(layout.NewSpacer(), a.searcher, layout.NewSpacer())
 	a.container = container.New(&layout.CustomPaddedLayout{LeftPadding: 15, RightPadding: 15, TopPadding: 5, BottomPadding: 15},
 		container.NewBorder(
-			container.NewHBox(a.titleDisp, container.NewCenter(a.viewToggle), layout.NewSpacer(), searchVbox),
+			container.NewHBox(a.titleDisp, container.NewCenter(a.viewToggle), container.NewCenter(a.ownerToggle), container.NewCenter(a.folderSelect), layout.NewSpacer(), searchVbox),
 			nil, nil, nil, initialView))
 }
 
@@ -423,7 +481,7 @@ func (p *PlaylistList) stringSort(fieldFn func(*mediaprovider.Playlist) string)
 	new := make([]*mediaprovider.Playlist, len(p.playlistsOrigOrder))
 	copy(new, p.playlistsOrigOrder)
 	sort.SliceStable(new, func(i, j int) bool {
-		cmp := strings.Compare(fieldFn(new[i]), fieldFn(new[j]))
+		cmp := sharedutil.CompareStrings(fieldFn(new[i]), fieldFn(new[j]))
 		if p.sorting.Type == widgets.SortDescending {
 			return cmp > 0
 		}
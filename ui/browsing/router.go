@@ -30,8 +30,8 @@ func NewRouter(app *backend.App, controller *controller.Controller, nav Navigati
 }
 
 func (r Router) CreatePage(rte controller.Route) Page {
-	_, canRate := r.App.ServerManager.Server.(mediaprovider.SupportsRating)
-	_, canShare := r.App.ServerManager.Server.(mediaprovider.SupportsSharing)
+	_, canRate := mediaprovider.As[mediaprovider.SupportsRating](r.App.ServerManager.Server)
+	_, canShare := mediaprovider.As[mediaprovider.SupportsSharing](r.App.ServerManager.Server)
 	switch rte.Page {
 	case controller.Album:
 		return NewAlbumPage(rte.Arg, &r.App.Config.AlbumPage, r.widgetPool, r.App.PlaybackManager, r.App.ServerManager.Server, r.App.ImageManager, r.Controller)
@@ -42,13 +42,13 @@ func (r Router) CreatePage(rte controller.Route) Page {
 	case controller.Artists:
 		return NewArtistsPage(&r.App.Config.ArtistsPage, r.widgetPool, r.Controller, r.App.PlaybackManager, r.App.ServerManager.Server, r.App.ImageManager)
 	case controller.Favorites:
-		return NewFavoritesPage(&r.App.Config.FavoritesPage, r.widgetPool, r.Controller, r.App.ServerManager.Server, r.App.PlaybackManager, r.App.ImageManager)
+		return NewFavoritesPage(&r.App.Config.FavoritesPage, r.widgetPool, r.Controller, r.App.ServerManager.Server, r.App.FavoritesCache, r.App.EventBus, r.App.PlaybackManager, r.App.ImageManager)
 	case controller.Genre:
 		return NewGenrePage(rte.Arg, r.widgetPool, r.Controller, r.App.PlaybackManager, r.App.ServerManager.Server, r.App.ImageManager)
 	case controller.Genres:
 		return NewGenresPage(r.Controller, r.App.ServerManager.Server)
 	case controller.NowPlaying:
-		return NewNowPlayingPage(&r.App.Config.NowPlayingConfig, r.Controller, r.widgetPool, r.App.ServerManager, r.App.ImageManager, r.App.PlaybackManager, r.App.ServerManager.Server, canRate, canShare, r.App.Config.Application.EnableLrcLib)
+		return NewNowPlayingPage(&r.App.Config.NowPlayingConfig, r.Controller, r.widgetPool, r.App.ServerManager, r.App.ImageManager, r.App.PlaybackManager, r.App.ServerManager.Server, canRate, canShare, r.App.Lyrics)
 	case controller.Playlist:
 		return NewPlaylistPage(rte.Arg, &r.App.Config.PlaylistPage, r.widgetPool, r.Controller, r.App.ServerManager, r.App.PlaybackManager, r.App.ImageManager)
 	case controller.Playlists:
@@ -57,7 +57,7 @@ func (r Router) CreatePage(rte controller.Route) Page {
 		return NewTracksPage(r.Controller, &r.App.Config.TracksPage, r.widgetPool, r.App.ServerManager.Server, r.App.ImageManager)
 	case controller.Radios:
 		var rp mediaprovider.RadioProvider
-		rp, _ = r.App.ServerManager.Server.(mediaprovider.RadioProvider)
+		rp, _ = mediaprovider.As[mediaprovider.RadioProvider](r.App.ServerManager.Server)
 		return NewRadiosPage(r.Controller, rp, r.App.PlaybackManager)
 	}
 	return nil
@@ -48,8 +48,8 @@ func NewTracksPage(contr *controller.Controller, conf *backend.TracksPageConfig,
 	t.ExtendBaseWidget(t)
 
 	t.tracklist = t.obtainTracklist()
-	_, t.canRate = mp.(mediaprovider.SupportsRating)
-	_, t.canShare = mp.(mediaprovider.SupportsSharing)
+	_, t.canRate = mediaprovider.As[mediaprovider.SupportsRating](mp)
+	_, t.canShare = mediaprovider.As[mediaprovider.SupportsSharing](mp)
 	t.tracklist.Options = widgets.TracklistOptions{
 		DisableSorting: true,
 		DisableRating:  !t.canRate,
@@ -110,6 +110,15 @@ func (t *TracksPage) OnSongChange(item mediaprovider.MediaItem, lastScrobbledIfA
 	}
 }
 
+var _ CanShowRatingChange = (*TracksPage)(nil)
+
+func (t *TracksPage) OnRatingChange(trackIDs []string, rating int) {
+	t.tracklist.SetTrackRatings(trackIDs, rating)
+	if t.searchTracklist != nil {
+		t.searchTracklist.SetTrackRatings(trackIDs, rating)
+	}
+}
+
 var _ Scrollable = (*TracksPage)(nil)
 
 func (g *TracksPage) Scroll(scrollAmt float32) {
@@ -1,32 +1,41 @@
 package controller
 
 import (
-	"archive/zip"
 	"context"
+	"errors"
 	"fmt"
 	"image"
+	"image/png"
 	"io"
 	"log"
 	"math/rand"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/dweymouth/supersonic/backend"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/demo"
 	"github.com/dweymouth/supersonic/backend/player"
 	"github.com/dweymouth/supersonic/backend/player/mpv"
+	"github.com/dweymouth/supersonic/res"
 	"github.com/dweymouth/supersonic/sharedutil"
 	"github.com/dweymouth/supersonic/ui/dialogs"
 	"github.com/dweymouth/supersonic/ui/util"
 	"github.com/dweymouth/supersonic/ui/widgets"
+	"github.com/google/uuid"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
@@ -37,13 +46,16 @@ type ReloadFunc func()
 
 type CurPageFunc func() Route
 
+type PerfOverlayFunc func(visible bool)
+
 type Controller struct {
-	AppVersion  string
-	MainWindow  fyne.Window
-	App         *backend.App
-	NavHandler  NavigationHandler
-	CurPageFunc CurPageFunc
-	ReloadFunc  ReloadFunc
+	AppVersion            string
+	MainWindow            fyne.Window
+	App                   *backend.App
+	NavHandler            NavigationHandler
+	CurPageFunc           CurPageFunc
+	ReloadFunc            ReloadFunc
+	SetPerfOverlayVisible PerfOverlayFunc
 
 	escapablePopUp   *widget.PopUp
 	haveModal        bool
@@ -116,6 +128,9 @@ func (m *Controller) connectTracklistActionsWithReplayGainMode(tracklist *widget
 	tracklist.OnPlaySelectionNext = func(tracks []*mediaprovider.Track) {
 		m.App.PlaybackManager.LoadTracks(tracks, backend.InsertNext, false)
 	}
+	tracklist.OnPlaySelectionAfterAlbum = func(tracks []*mediaprovider.Track) {
+		m.App.PlaybackManager.LoadTracks(tracks, backend.InsertAfterCurrentAlbum, false)
+	}
 	tracklist.OnAddToQueue = func(tracks []*mediaprovider.Track) {
 		m.App.PlaybackManager.LoadTracks(tracks, backend.Append, false)
 	}
@@ -141,6 +156,14 @@ func (m *Controller) connectTracklistActionsWithReplayGainMode(tracklist *widget
 	tracklist.OnShowArtistPage = func(artistID string) {
 		m.NavigateTo(ArtistRoute(artistID))
 	}
+	tracklist.OnShowOtherAlbums = m.ShowOtherAlbumsDialog
+	if _, ok := mediaprovider.As[mediaprovider.SupportsMetadataEditing](m.App.ServerManager.Server); ok {
+		tracklist.OnEditMetadata = m.DoEditTrackMetadataWorkflow
+	}
+	if _, ok := mediaprovider.As[mediaprovider.SupportsStreamOptions](m.App.ServerManager.Server); ok {
+		tracklist.OnSetStreamOriginal = m.App.ServerManager.SetTracksForceRaw
+		tracklist.IsStreamOriginal = m.App.ServerManager.IsTrackForceRaw
+	}
 	tracklist.OnColumnVisibilityMenuShown = func(pop *widget.PopUp) {
 		m.ClosePopUpOnEscape(pop)
 	}
@@ -304,13 +327,15 @@ func (m *Controller) PromptForFirstServer() {
 				pop.Hide()
 				m.doModalClosed()
 				conn := backend.ServerConnection{
-					ServerType:  d.ServerType,
-					Hostname:    d.Host,
-					AltHostname: d.AltHost,
-					Username:    d.Username,
-					LegacyAuth:  d.LegacyAuth,
+					ServerType:        d.ServerType,
+					Hostname:          d.Host,
+					AltHostname:       d.AltHost,
+					Username:          d.Username,
+					LegacyAuth:        d.LegacyAuth,
+					ForceDirectStream: d.ForceDirectStream,
 				}
 				server := m.App.ServerManager.AddServer(d.Nickname, conn)
+				server.NewMusicCheckMinutes = d.NewMusicCheckMinutes
 				if err := m.trySetPasswordAndConnectToServer(server, d.Password); err != nil {
 					log.Printf("error connecting to server: %s", err.Error())
 				}
@@ -318,6 +343,20 @@ func (m *Controller) PromptForFirstServer() {
 			d.EnableSubmit()
 		}()
 	}
+	d.OnTryDemo = func() {
+		pop.Hide()
+		m.doModalClosed()
+		conn := backend.ServerConnection{
+			ServerType: backend.ServerTypeDemo,
+			Username:   demo.Username,
+		}
+		server := m.App.ServerManager.AddServer("Demo Library", conn)
+		go func() {
+			if err := m.trySetPasswordAndConnectToServer(server, ""); err != nil {
+				log.Printf("error connecting to demo server: %s", err.Error())
+			}
+		}()
+	}
 	m.haveModal = true
 	pop.Show()
 }
@@ -335,27 +374,17 @@ func (m *Controller) DoAddTracksToPlaylistWorkflow(trackIDs []string) {
 	sp.SetOnNavigateTo(func(contentType mediaprovider.ContentType, id string) {
 		pop.Hide()
 		if id == "" /* creating new playlist */ {
-			go m.App.ServerManager.Server.CreatePlaylist(sp.SearchDialog.SearchQuery(), trackIDs)
+			go func() {
+				if err := m.App.ServerManager.Server.CreatePlaylist(sp.SearchDialog.SearchQuery(), trackIDs); err == nil {
+					m.notifyPlaylistChanged("")
+				}
+			}()
 		} else {
 			m.App.Config.Application.DefaultPlaylistID = id
 			if sp.SkipDuplicates {
-				go func() {
-					currentTrackIDs := make(map[string]struct{})
-					if selectedPlaylist, err := m.App.ServerManager.Server.GetPlaylist(id); err != nil {
-						log.Printf("error getting playlist: %s", err.Error())
-					} else {
-						for _, track := range selectedPlaylist.Tracks {
-							currentTrackIDs[track.ID] = struct{}{}
-						}
-						filterTrackIDs := sharedutil.FilterSlice(trackIDs, func(trackID string) bool {
-							_, ok := currentTrackIDs[trackID]
-							return !ok
-						})
-						m.App.ServerManager.Server.AddPlaylistTracks(id, filterTrackIDs)
-					}
-				}()
+				go m.addTracksToPlaylist(id, trackIDs, true)
 			} else {
-				go m.App.ServerManager.Server.AddPlaylistTracks(id, trackIDs)
+				go m.confirmAddDuplicatesToPlaylist(id, trackIDs)
 			}
 		}
 
@@ -370,15 +399,72 @@ func (m *Controller) DoAddTracksToPlaylistWorkflow(trackIDs []string) {
 	m.MainWindow.Canvas().Focus(sp.GetSearchEntry())
 }
 
+// ShowOtherAlbumsDialog shows a dialog listing the other albums
+// in the library which contain the same recording as the given track.
+func (m *Controller) ShowOtherAlbumsDialog(track *mediaprovider.Track) {
+	oa := dialogs.NewOtherAlbumsDialog(m.App.ServerManager.Server, m.App.ImageManager, track)
+	pop := widget.NewModalPopUp(oa.SearchDialog, m.MainWindow.Canvas())
+	oa.SearchDialog.OnDismiss = func() {
+		pop.Hide()
+		m.doModalClosed()
+	}
+	oa.SearchDialog.OnNavigateTo = func(_ mediaprovider.ContentType, id string) {
+		pop.Hide()
+		m.doModalClosed()
+		m.NavigateTo(AlbumRoute(id))
+	}
+	m.ClosePopUpOnEscape(pop)
+	m.haveModal = true
+	min := oa.SearchDialog.MinSize()
+	height := fyne.Max(min.Height, fyne.Min(min.Height*1.5, m.MainWindow.Canvas().Size().Height*0.7))
+	oa.SearchDialog.Show()
+	pop.Resize(fyne.NewSize(min.Width, height))
+	pop.Show()
+	m.MainWindow.Canvas().Focus(oa.SearchDialog.GetSearchEntry())
+}
+
+// ShowEditionsDialog shows a dialog listing the editions
+// in the library of the given album, to navigate to one of them.
+func (m *Controller) ShowEditionsDialog(album *mediaprovider.Album) {
+	ed := dialogs.NewEditionsDialog(m.App.ServerManager.Server, m.App.ImageManager, album)
+	pop := widget.NewModalPopUp(ed.SearchDialog, m.MainWindow.Canvas())
+	ed.SearchDialog.OnDismiss = func() {
+		pop.Hide()
+		m.doModalClosed()
+	}
+	ed.SearchDialog.OnNavigateTo = func(_ mediaprovider.ContentType, id string) {
+		pop.Hide()
+		m.doModalClosed()
+		m.NavigateTo(AlbumRoute(id))
+	}
+	m.ClosePopUpOnEscape(pop)
+	m.haveModal = true
+	min := ed.SearchDialog.MinSize()
+	height := fyne.Max(min.Height, fyne.Min(min.Height*1.5, m.MainWindow.Canvas().Size().Height*0.7))
+	ed.SearchDialog.Show()
+	pop.Resize(fyne.NewSize(min.Width, height))
+	pop.Show()
+	m.MainWindow.Canvas().Focus(ed.SearchDialog.GetSearchEntry())
+}
+
 func (m *Controller) DoEditPlaylistWorkflow(playlist *mediaprovider.Playlist) {
 	canMakePublic := m.App.ServerManager.Server.CanMakePublicPlaylist()
-	dlg := dialogs.NewEditPlaylistDialog(playlist, canMakePublic)
+	sharer, canShare := mediaprovider.As[mediaprovider.SupportsPlaylistSharing](m.App.ServerManager.Server)
+	canShare = canShare && sharer.CanSharePlaylists()
+	collab, canMakeCollaborative := mediaprovider.As[mediaprovider.SupportsCollaborativePlaylists](m.App.ServerManager.Server)
+	canMakeCollaborative = canMakeCollaborative && collab.CanMakeCollaborativePlaylist()
+	dlg := dialogs.NewEditPlaylistDialog(playlist, canMakePublic, canMakeCollaborative, canShare)
 	pop := widget.NewModalPopUp(dlg, m.MainWindow.Canvas())
 	m.ClosePopUpOnEscape(pop)
 	dlg.OnCanceled = func() {
 		pop.Hide()
 		m.doModalClosed()
 	}
+	dlg.OnShare = func() {
+		pop.Hide()
+		m.doModalClosed()
+		go m.doSharePlaylistWorkflow(sharer, playlist)
+	}
 	dlg.OnDeletePlaylist = func() {
 		pop.Hide()
 		dialog.ShowCustomConfirm("Confirm Delete Playlist", "OK", "Cancel", layout.NewSpacer(), /*custom content*/
@@ -390,10 +476,13 @@ func (m *Controller) DoEditPlaylistWorkflow(playlist *mediaprovider.Playlist) {
 					go func() {
 						if err := m.App.ServerManager.Server.DeletePlaylist(playlist.ID); err != nil {
 							log.Printf("error deleting playlist: %s", err.Error())
-						} else if rte := m.CurPageFunc(); rte.Page == Playlist && rte.Arg == playlist.ID {
+							return
+						}
+						if rte := m.CurPageFunc(); rte.Page == Playlist && rte.Arg == playlist.ID {
 							// navigate to playlists page if user is still on the page of the deleted playlist
 							m.NavigateTo(PlaylistsRoute())
 						}
+						m.notifyPlaylistChanged(playlist.ID)
 					}()
 				}
 			}, m.MainWindow)
@@ -403,21 +492,407 @@ func (m *Controller) DoEditPlaylistWorkflow(playlist *mediaprovider.Playlist) {
 		m.doModalClosed()
 		go func() {
 			err := m.App.ServerManager.Server.EditPlaylist(playlist.ID, dlg.Name, dlg.Description, dlg.IsPublic)
+			if err == nil && canMakeCollaborative && dlg.IsCollaborative != playlist.Collaborative {
+				err = collab.SetPlaylistCollaborative(playlist.ID, dlg.IsCollaborative)
+			}
 			if err != nil {
 				log.Printf("error updating playlist: %s", err.Error())
-			} else if rte := m.CurPageFunc(); rte.Page == Playlist && rte.Arg == playlist.ID {
-				// if user is on playlist page, reload to get the updates
+			} else {
+				// an open playlist page reloads to get the updates
+				m.notifyPlaylistChanged(playlist.ID)
+			}
+		}()
+	}
+	m.haveModal = true
+	pop.Show()
+}
+
+// number of tracks above which a progress dialog is shown when adding to a playlist
+const addToPlaylistProgressThreshold = 1000
+
+// confirmAddDuplicatesToPlaylist adds the tracks to the playlist, first asking
+// whether to skip or add those which are already in it, if any.
+func (m *Controller) confirmAddDuplicatesToPlaylist(playlistID string, trackIDs []string) {
+	dups, err := m.App.PlaylistTracks.Duplicates(playlistID, trackIDs)
+	if err != nil {
+		log.Printf("error checking playlist for duplicates: %v", err)
+	}
+	if len(dups) == 0 {
+		m.addTracksToPlaylist(playlistID, trackIDs, false)
+		return
+	}
+	msg := fmt.Sprintf("%d of the tracks are already in the playlist.", len(dups))
+	if len(trackIDs) == 1 {
+		msg = "The track is already in the playlist."
+	}
+	dlg := dialog.NewConfirm("Duplicate Tracks", msg, func(addAnyway bool) {
+		go m.addTracksToPlaylist(playlistID, trackIDs, !addAnyway)
+	}, m.MainWindow)
+	dlg.SetConfirmText("Add Anyway")
+	dlg.SetDismissText("Skip Duplicates")
+	dlg.Show()
+}
+
+func (m *Controller) addTracksToPlaylist(playlistID string, trackIDs []string, skipDuplicates bool) {
+	add := func(ctx context.Context, onProgress func(done, total int)) error {
+		return m.App.BatchOps.AddToPlaylist(ctx, playlistID, trackIDs, skipDuplicates, onProgress)
+	}
+	var err error
+	if len(trackIDs) > addToPlaylistProgressThreshold {
+		err = m.runBatchWithProgress("Adding to playlist", add)
+	} else {
+		err = add(context.Background(), nil)
+	}
+	var itemsErr *mediaprovider.ItemsError
+	if errors.As(err, &itemsErr) {
+		log.Printf("error adding tracks to playlist: %v", err)
+		m.showError(fmt.Sprintf("Failed to add %d of %d tracks to the playlist.", len(itemsErr.Errors), len(trackIDs)))
+	} else if err != nil && !errors.Is(err, context.Canceled) {
+		log.Printf("error adding tracks to playlist: %v", err)
+	}
+	if itemsErr == nil || len(itemsErr.Errors) < len(trackIDs) {
+		m.notifyPlaylistChanged(playlistID)
+	}
+}
+
+// notifyPlaylistChanged publishes an EventPlaylistChanged so that open views refresh.
+func (m *Controller) notifyPlaylistChanged(playlistID string) {
+	m.App.EventBus.Publish(backend.Event{Type: backend.EventPlaylistChanged, Data: playlistID})
+}
+
+// DoRemovePlaylistDuplicatesWorkflow confirms and removes duplicate tracks
+// from the playlist, calling onRemoved if any were removed.
+func (m *Controller) DoRemovePlaylistDuplicatesWorkflow(playlistID string, onRemoved func()) {
+	fuzzy := widget.NewCheck("Also match the same song from different albums", nil)
+	dialog.ShowCustomConfirm("Remove Duplicates", "Remove", "Cancel", fuzzy, func(ok bool) {
+		if !ok {
+			return
+		}
+		go func() {
+			n, err := backend.RemovePlaylistDuplicates(m.App.ServerManager.Server, playlistID, fuzzy.Checked)
+			if err != nil {
+				log.Printf("error removing playlist duplicates: %v", err)
+				m.showError("Failed to remove duplicate tracks from the playlist.")
+				return
+			}
+			dialog.ShowInformation("Remove Duplicates", fmt.Sprintf("Removed %d duplicate tracks.", n), m.MainWindow)
+			if n > 0 && onRemoved != nil {
+				onRemoved()
+			}
+		}()
+	}, m.MainWindow)
+}
+
+// PlaylistFolderOptions returns the IDs and display names of the
+// playlist folders in tree order, with the top level ("") first.
+func (m *Controller) PlaylistFolderOptions(topLevelName string) (ids, names []string) {
+	ids, names = []string{""}, []string{topLevelName}
+	var walk func([]*backend.PlaylistTreeNode, string)
+	walk = func(level []*backend.PlaylistTreeNode, prefix string) {
+		for _, n := range level {
+			if n.Folder != nil {
+				ids = append(ids, n.Folder.ID)
+				names = append(names, prefix+n.Folder.Name)
+				walk(n.Children, prefix+n.Folder.Name+" / ")
+			}
+		}
+	}
+	walk(m.App.PlaylistFolders.Tree(nil), "")
+	return ids, names
+}
+
+// DoMovePlaylistToFolderWorkflow prompts for the folder to organize
+// the playlist into, optionally creating a new one.
+func (m *Controller) DoMovePlaylistToFolderWorkflow(playlistID string, onDone func()) {
+	folders := m.App.PlaylistFolders
+	ids, names := m.PlaylistFolderOptions("(Top level)")
+	sel := widget.NewSelect(names, nil)
+	sel.SetSelectedIndex(max(0, slices.Index(ids, folders.FolderOf(playlistID))))
+	newFolder := widget.NewEntry()
+	newFolder.SetPlaceHolder("New folder name (optional)")
+	content := container.New(layout.NewFormLayout(),
+		widget.NewLabel("Folder"), sel,
+		widget.NewLabel("Create subfolder"), newFolder)
+	dialog.ShowCustomConfirm("Move to Folder", "Move", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		folderID := ids[sel.SelectedIndex()]
+		if name := strings.TrimSpace(newFolder.Text); name != "" {
+			folderID = folders.CreateFolder(name, folderID).ID
+		}
+		folders.MovePlaylist(playlistID, folderID)
+		if onDone != nil {
+			onDone()
+		}
+	}, m.MainWindow)
+}
+
+// DoUploadPlaylistCoverWorkflow prompts for an image file
+// and uploads it to the server as the playlist's cover.
+func (m *Controller) DoUploadPlaylistCoverWorkflow(playlist *mediaprovider.Playlist, onDone func()) {
+	uploader, ok := mediaprovider.As[mediaprovider.SupportsPlaylistCoverUpload](m.App.ServerManager.Server)
+	if !ok {
+		return
+	}
+	dlg := dialog.NewFileOpen(func(rc fyne.URIReadCloser, err error) {
+		if err != nil || rc == nil {
+			return
+		}
+		go func() {
+			defer rc.Close()
+			img, err := io.ReadAll(rc)
+			if err == nil {
+				err = uploader.SetPlaylistCover(playlist.ID, img)
+			}
+			if err != nil {
+				log.Printf("error setting playlist cover: %v", err)
+				m.showError("Failed to set the playlist cover.")
+				return
+			}
+			// the uploaded cover replaces any chosen album cover
+			m.App.ServerManager.SetPlaylistCoverAlbum(playlist.ID, "")
+			m.App.ImageManager.InvalidatePlaylistCover(playlist.ID, playlist.CoverArtID)
+			if onDone != nil {
+				onDone()
+			}
+		}()
+	}, m.MainWindow)
+	dlg.SetFilter(&storage.ExtensionFileFilter{Extensions: []string{".jpg", ".jpeg", ".png"}})
+	dlg.Show()
+}
+
+// DoChoosePlaylistCoverAlbumWorkflow prompts to choose one of the playlist's
+// albums whose cover to show for the playlist. The choice is saved locally.
+func (m *Controller) DoChoosePlaylistCoverAlbumWorkflow(playlist *mediaprovider.PlaylistWithTracks, onDone func()) {
+	options := []string{"Playlist cover"}
+	albumIDs := []string{""}
+	for _, tr := range playlist.Tracks {
+		if tr.AlbumID == "" || slices.Contains(albumIDs, tr.AlbumID) {
+			continue
+		}
+		name := tr.Album
+		if len(tr.ArtistNames) > 0 {
+			name = fmt.Sprintf("%s - %s", tr.Album, tr.ArtistNames[0])
+		}
+		options = append(options, name)
+		albumIDs = append(albumIDs, tr.AlbumID)
+	}
+	sel := widget.NewSelect(options, nil)
+	sel.SetSelectedIndex(max(0, slices.Index(albumIDs, m.App.ServerManager.PlaylistCoverAlbum(playlist.ID))))
+	dialog.ShowCustomConfirm("Playlist Cover", "Save", "Cancel", sel, func(ok bool) {
+		if !ok {
+			return
+		}
+		m.App.ServerManager.SetPlaylistCoverAlbum(playlist.ID, albumIDs[sel.SelectedIndex()])
+		m.App.ImageManager.InvalidatePlaylistCover(playlist.ID, "")
+		if onDone != nil {
+			onDone()
+		}
+	}, m.MainWindow)
+}
+
+func (m *Controller) doSharePlaylistWorkflow(sharer mediaprovider.SupportsPlaylistSharing, playlist *mediaprovider.Playlist) {
+	users, err := sharer.GetServerUsers()
+	if err != nil {
+		log.Printf("error getting server users: %v", err)
+		m.showError("Failed to get the list of users from the server.")
+		return
+	}
+	shares, err := sharer.GetPlaylistShares(playlist.ID)
+	if err != nil {
+		log.Printf("error getting playlist shares: %v", err)
+		m.showError("Failed to get the users this playlist is shared with.")
+		return
+	}
+
+	dlg := dialogs.NewSharePlaylistDialog(playlist.Name, users, shares)
+	pop := widget.NewModalPopUp(dlg, m.MainWindow.Canvas())
+	m.ClosePopUpOnEscape(pop)
+	dlg.OnCanceled = func() {
+		pop.Hide()
+		m.doModalClosed()
+	}
+	dlg.OnSubmit = func(shares []mediaprovider.PlaylistShare) {
+		pop.Hide()
+		m.doModalClosed()
+		go func() {
+			if err := sharer.SetPlaylistShares(playlist.ID, shares); err != nil {
+				log.Printf("error sharing playlist: %v", err)
+				m.showError("Failed to update the users this playlist is shared with.")
+			}
+		}()
+	}
+	m.haveModal = true
+	pop.Show()
+}
+
+// ShowSmartPlaylistsDialog shows the dialog for managing,
+// playing, and saving smart playlists for the current server.
+func (m *Controller) ShowSmartPlaylistsDialog() {
+	spm := m.App.SmartPlaylists
+	dlg := dialogs.NewSmartPlaylistsDialog(spm.SmartPlaylists())
+	pop := widget.NewModalPopUp(dlg, m.MainWindow.Canvas())
+	m.ClosePopUpOnEscape(pop)
+	closeDlg := func() {
+		pop.Hide()
+		m.doModalClosed()
+	}
+	dlg.OnDismiss = closeDlg
+	dlg.OnNew = func() {
+		closeDlg()
+		m.doEditSmartPlaylistWorkflow(&backend.SmartPlaylist{})
+	}
+	dlg.OnEdit = func(sp *backend.SmartPlaylist) {
+		closeDlg()
+		m.doEditSmartPlaylistWorkflow(sp)
+	}
+	dlg.OnDelete = func(sp *backend.SmartPlaylist) {
+		spm.Delete(sp.ID)
+		closeDlg()
+		m.ShowSmartPlaylistsDialog()
+	}
+	dlg.OnPlay = func(sp *backend.SmartPlaylist) {
+		closeDlg()
+		go func() {
+			tracks, err := spm.Evaluate(sp)
+			if err != nil {
+				log.Printf("error evaluating smart playlist: %v", err)
+				m.showError("Failed to get the tracks for the smart playlist.")
+				return
+			}
+			if len(tracks) == 0 {
+				dialog.ShowInformation("Smart Playlist", "No tracks match the smart playlist's rules.", m.MainWindow)
+				return
+			}
+			m.App.PlaybackManager.LoadTracks(tracks, backend.Replace, false)
+			m.App.PlaybackManager.PlayFromBeginning()
+		}()
+	}
+	dlg.OnSaveToServer = func(sp *backend.SmartPlaylist) {
+		closeDlg()
+		go func() {
+			if err := spm.Materialize(sp); err != nil {
+				log.Printf("error saving smart playlist to server: %v", err)
+				m.showError("Failed to save the smart playlist to the server.")
+				return
+			}
+			if rte := m.CurPageFunc(); rte.Page == Playlists ||
+				(rte.Page == Playlist && rte.Arg == sp.MaterializedPlaylistID) {
 				m.ReloadFunc()
 			}
 		}()
 	}
 	m.haveModal = true
+	min := dlg.MinSize()
+	pop.Resize(fyne.NewSize(min.Width, fyne.Max(min.Height, m.MainWindow.Canvas().Size().Height*0.5)))
+	pop.Show()
+}
+
+// ShowRediscoverDialog shows the dialog for generating playlists of
+// music the user has drifted away from, to play or save to the server.
+func (m *Controller) ShowRediscoverDialog() {
+	const limit = 100
+	periods := []int{1, 3, 6, 12, 24}
+	periodOpts := []string{"1 month", "3 months", "6 months", "1 year", "2 years"}
+	kindOpts := sharedutil.MapSlice(backend.RediscoverKinds, backend.RediscoverKind.String)
+
+	kindSelect := widget.NewSelect(kindOpts, nil)
+	periodSelect := widget.NewSelect(periodOpts, nil)
+	name := widget.NewEntry()
+	selection := func() (backend.RediscoverKind, int) {
+		return backend.RediscoverKinds[max(0, kindSelect.SelectedIndex())], periods[max(0, periodSelect.SelectedIndex())]
+	}
+	updateName := func(_ string) {
+		kind, months := selection()
+		name.SetText(kind.PlaylistName(months))
+	}
+	kindSelect.OnChanged = updateName
+	periodSelect.OnChanged = updateName
+	kindSelect.SetSelectedIndex(0)
+	periodSelect.SetSelectedIndex(2)
+
+	var dlg *dialog.CustomDialog
+	generate := func(onTracks func([]*mediaprovider.Track)) {
+		kind, months := selection()
+		dlg.Hide()
+		go func() {
+			tracks, err := m.App.Rediscover.Generate(kind, months, limit)
+			if err != nil {
+				log.Printf("error generating rediscover playlist: %v", err)
+				m.showError("Failed to generate the playlist.")
+				return
+			}
+			if len(tracks) == 0 {
+				dialog.ShowInformation("Rediscover", "No tracks were found. Try a shorter period, or listen some more!", m.MainWindow)
+				return
+			}
+			onTracks(tracks)
+		}()
+	}
+	play := widget.NewButtonWithIcon("Play", theme.MediaPlayIcon(), func() {
+		generate(func(tracks []*mediaprovider.Track) {
+			m.App.PlaybackManager.LoadTracks(tracks, backend.Replace, false)
+			m.App.PlaybackManager.PlayFromBeginning()
+		})
+	})
+	create := widget.NewButtonWithIcon("Create Playlist", theme.ContentAddIcon(), func() {
+		plName := name.Text
+		generate(func(tracks []*mediaprovider.Track) {
+			if err := m.App.ServerManager.Server.CreatePlaylist(plName, sharedutil.TracksToIDs(tracks)); err != nil {
+				log.Printf("error creating playlist: %v", err)
+				m.showError("Failed to create the playlist.")
+				return
+			}
+			m.notifyPlaylistChanged("")
+		})
+	})
+	name.OnChanged = func(text string) {
+		if text == "" {
+			create.Disable()
+		} else {
+			create.Enable()
+		}
+	}
+
+	content := container.NewVBox(
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel("Playlist"), kindSelect,
+			widget.NewLabel("Not played in"), periodSelect,
+			widget.NewLabel("Name"), name,
+		),
+		container.NewHBox(layout.NewSpacer(), play, create),
+	)
+	dlg = dialog.NewCustom("Rediscover", "Close", content, m.MainWindow)
+	dlg.Show()
+}
+
+func (m *Controller) doEditSmartPlaylistWorkflow(sp *backend.SmartPlaylist) {
+	var genreNames []string
+	if genres, err := m.App.ServerManager.Server.GetGenres(); err == nil {
+		genreNames = sharedutil.MapSlice(genres, func(g *mediaprovider.Genre) string { return g.Name })
+	}
+	dlg := dialogs.NewEditSmartPlaylistDialog(sp, genreNames)
+	pop := widget.NewModalPopUp(dlg, m.MainWindow.Canvas())
+	m.ClosePopUpOnEscape(pop)
+	dlg.OnCanceled = func() {
+		pop.Hide()
+		m.doModalClosed()
+		m.ShowSmartPlaylistsDialog()
+	}
+	dlg.OnSubmit = func(edited *backend.SmartPlaylist) {
+		pop.Hide()
+		m.doModalClosed()
+		m.App.SmartPlaylists.Save(edited)
+		m.ShowSmartPlaylistsDialog()
+	}
+	m.haveModal = true
 	pop.Show()
 }
 
 // DoConnectToServerWorkflow does the workflow for connecting to the last active server on startup
 func (c *Controller) DoConnectToServerWorkflow(server *backend.ServerConfig) {
-	pass, err := c.App.ServerManager.GetServerPassword(server.ID)
+	pass, err := c.App.ServerManager.GetServerPassword(server.ActiveAccount().ID)
 	if err != nil {
 		log.Printf("error getting password from keyring: %v", err)
 		c.PromptForLoginAndConnect()
@@ -442,6 +917,14 @@ func (c *Controller) DoConnectToServerWorkflow(server *backend.ServerConfig) {
 		c.haveModal = false
 		if canceled {
 			c.PromptForLoginAndConnect()
+		} else if errors.Is(err, mediaprovider.ErrTokenAuthNotSupported) {
+			c.confirmLegacyAuthFallback(server, func(allowed bool) {
+				if allowed {
+					c.DoConnectToServerWorkflow(server)
+				} else {
+					c.PromptForLoginAndConnect()
+				}
+			})
 		} else {
 			// connection failure
 			dlg := dialog.NewError(err, c.MainWindow)
@@ -470,6 +953,13 @@ func (m *Controller) PromptForLoginAndConnect() {
 			err := m.App.ServerManager.TestConnectionAndAuth(ctx, server.ServerConnection, password)
 			if err == backend.ErrUnreachable {
 				d.SetErrorText("Server unreachable")
+			} else if errors.Is(err, mediaprovider.ErrTokenAuthNotSupported) {
+				d.SetErrorText("Server requires legacy authentication")
+				m.confirmLegacyAuthFallback(server, func(allowed bool) {
+					if allowed {
+						d.OnSubmit(server, password)
+					}
+				})
 			} else if err != nil {
 				d.SetErrorText("Authentication failed")
 			} else {
@@ -495,6 +985,8 @@ func (m *Controller) PromptForLoginAndConnect() {
 					server.Nickname = editD.Nickname
 					server.Username = editD.Username
 					server.LegacyAuth = editD.LegacyAuth
+					server.ForceDirectStream = editD.ForceDirectStream
+					server.NewMusicCheckMinutes = editD.NewMusicCheckMinutes
 					m.trySetPasswordAndConnectToServer(server, editD.Password)
 					m.doModalClosed()
 				}
@@ -518,13 +1010,15 @@ func (m *Controller) PromptForLoginAndConnect() {
 					// connection is good
 					newPop.Hide()
 					conn := backend.ServerConnection{
-						ServerType:  newD.ServerType,
-						Hostname:    newD.Host,
-						AltHostname: newD.AltHost,
-						Username:    newD.Username,
-						LegacyAuth:  newD.LegacyAuth,
+						ServerType:        newD.ServerType,
+						Hostname:          newD.Host,
+						AltHostname:       newD.AltHost,
+						Username:          newD.Username,
+						LegacyAuth:        newD.LegacyAuth,
+						ForceDirectStream: newD.ForceDirectStream,
 					}
 					server := m.App.ServerManager.AddServer(newD.Nickname, conn)
+					server.NewMusicCheckMinutes = newD.NewMusicCheckMinutes
 					m.trySetPasswordAndConnectToServer(server, newD.Password)
 					m.doModalClosed()
 				}
@@ -572,7 +1066,7 @@ func (c *Controller) ShowAboutDialog() {
 }
 
 func (c *Controller) ShowSettingsDialog(themeUpdateCallbk func(), themeFiles map[string]string) {
-	devs, err := c.App.LocalPlayer.ListAudioDevices()
+	devs, err := c.App.AudioOutput.Devices()
 	if err != nil {
 		log.Printf("error listing audio devices: %v", err)
 		devs = []mpv.AudioDevice{{Name: "auto", Description: "Autoselect device"}}
@@ -581,14 +1075,17 @@ func (c *Controller) ShowSettingsDialog(themeUpdateCallbk func(), themeFiles map
 	curPlayer := c.App.PlaybackManager.CurrentPlayer()
 	_, isReplayGainPlayer := curPlayer.(player.ReplayGainPlayer)
 	_, isEqualizerPlayer := curPlayer.(*mpv.Player)
-	_, canSavePlayQueue := c.App.ServerManager.Server.(mediaprovider.CanSavePlayQueue)
+	_, canSavePlayQueue := mediaprovider.As[mediaprovider.CanSavePlayQueue](c.App.ServerManager.Server)
 	isLocalPlayer := isEqualizerPlayer
-	bands := c.App.LocalPlayer.Equalizer().BandFrequencies()
+	var streamServerConfig *backend.ServerConfig
+	if _, ok := mediaprovider.As[mediaprovider.SupportsStreamOptions](c.App.ServerManager.Server); ok {
+		streamServerConfig = c.App.ServerManager.CurrentServerConfig()
+	}
 	dlg := dialogs.NewSettingsDialog(c.App.Config,
-		devs, themeFiles, bands,
+		devs, themeFiles, c.App.Equalizer,
 		c.App.ServerManager.Server.ClientDecidesScrobble(),
 		isLocalPlayer, isReplayGainPlayer, isEqualizerPlayer, canSavePlayQueue,
-		c.MainWindow)
+		streamServerConfig, c.MainWindow)
 	dlg.OnReplayGainSettingsChanged = func() {
 		c.App.PlaybackManager.SetReplayGainOptions(c.App.Config.ReplayGain)
 	}
@@ -596,21 +1093,37 @@ func (c *Controller) ShowSettingsDialog(themeUpdateCallbk func(), themeFiles map
 		c.App.LocalPlayer.SetAudioExclusive(c.App.Config.LocalPlayback.AudioExclusive)
 	}
 	dlg.OnAudioDeviceSettingChanged = func() {
-		c.App.LocalPlayer.SetAudioDevice(c.App.Config.LocalPlayback.AudioDeviceName)
+		if err := c.App.AudioOutput.SetDevice(c.App.Config.LocalPlayback.AudioDeviceName); err != nil {
+			log.Printf("error setting audio device: %v", err)
+		}
 	}
 	dlg.OnThemeSettingChanged = themeUpdateCallbk
-	dlg.OnEqualizerSettingsChanged = func() {
-		// currently we only have one equalizer type
-		eq := c.App.LocalPlayer.Equalizer().(*mpv.ISO15BandEqualizer)
-		eq.Disabled = !c.App.Config.LocalPlayback.EqualizerEnabled
-		eq.EQPreamp = c.App.Config.LocalPlayback.EqualizerPreamp
-		copy(eq.BandGains[:], c.App.Config.LocalPlayback.GraphicEqualizerBands)
-		c.App.LocalPlayer.SetEqualizer(eq)
+	dlg.OnPerfOverlaySettingChanged = func() {
+		c.SetPerfOverlayVisible(c.App.Config.Application.ShowPerformanceOverlay)
+	}
+	dlg.OnContentFilterSettingChanged = func() {
+		c.App.ContentFilter.SetOptions(c.App.Config.ContentFilter.Options())
+	}
+	dlg.OnSnapcastSettingChanged = func() {
+		if err := c.App.Snapcast.Apply(); err != nil {
+			log.Printf("error applying Snapcast output: %v", err)
+			c.showError(fmt.Sprintf("Could not stream to Snapcast: %v", err))
+		}
+	}
+	dlg.OnAudioEventsSettingChanged = func() {
+		if err := c.App.AudioEvents.Apply(); err != nil {
+			log.Printf("error watching system audio events: %v", err)
+		}
 	}
+	dlg.OnRequestLogSettingChanged = func() {
+		c.App.RequestLog.SetEnabled(c.App.Config.Application.EnableRequestLog)
+	}
+	dlg.OnDiscordRPCSettingChanged = c.App.DiscordPresence.SettingsChanged
 	pop := widget.NewModalPopUp(dlg, c.MainWindow.Canvas())
 	dlg.OnDismiss = func() {
 		pop.Hide()
 		c.doModalClosed()
+		c.App.DiscordPresence.SettingsChanged() // pick up an edited application ID
 		c.App.SaveConfigFile()
 	}
 	c.ClosePopUpOnEscape(pop)
@@ -619,7 +1132,7 @@ func (c *Controller) ShowSettingsDialog(themeUpdateCallbk func(), themeFiles map
 }
 
 func (c *Controller) ShowQuickSearch() {
-	qs := dialogs.NewQuickSearch(c.App.ServerManager.Server, c.App.ImageManager)
+	qs := dialogs.NewQuickSearch(c.App.ServerManager.Server, c.App.SearchHistory, c.App.ImageManager)
 	pop := widget.NewModalPopUp(qs.SearchDialog, c.MainWindow.Canvas())
 	qs.SetOnDismiss(func() {
 		pop.Hide()
@@ -652,6 +1165,8 @@ func (c *Controller) ShowQuickSearch() {
 }
 
 func (c *Controller) trySetPasswordAndConnectToServer(server *backend.ServerConfig, password string) error {
+	// the login dialog logs in with the server's primary account
+	server.ActiveAccountID = uuid.UUID{}
 	if err := c.App.ServerManager.SetServerPassword(server, password); err != nil {
 		log.Printf("error setting keyring credentials: %v", err)
 		// Don't return an error; fall back to just using the password in-memory
@@ -664,7 +1179,7 @@ func (c *Controller) trySetPasswordAndConnectToServer(server *backend.ServerConf
 func (c *Controller) tryConnectToServer(ctx context.Context, server *backend.ServerConfig, password string) error {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
-	if err := c.App.ServerManager.TestConnectionAndAuth(ctx, server.ServerConnection, password); err != nil {
+	if err := c.App.ServerManager.TestConnectionAndAuth(ctx, server.ActiveConnection(), password); err != nil {
 		return err
 	}
 	if err := c.App.ServerManager.ConnectToServer(server, password); err != nil {
@@ -674,14 +1189,35 @@ func (c *Controller) tryConnectToServer(ctx context.Context, server *backend.Ser
 	return nil
 }
 
+// confirmLegacyAuthFallback warns the user that the server does not support
+// token authentication for their account and asks whether to fall back to
+// sending the password with each request. If allowed, the choice is saved
+// for the server so that future logins fall back automatically.
+func (c *Controller) confirmLegacyAuthFallback(server *backend.ServerConfig, onDone func(allowed bool)) {
+	msg := "The server does not support secure token authentication for this user\n" +
+		"(this is common for LDAP accounts). Supersonic can fall back to legacy\n" +
+		"authentication, which sends your password with every request.\n" +
+		"Only allow this if the server is accessed over HTTPS.\n\n" +
+		"Allow legacy authentication for this server?"
+	dlg := dialog.NewConfirm("Legacy Authentication Required", msg, func(ok bool) {
+		if ok {
+			server.AllowLegacyAuthFallback = true
+		}
+		onDone(ok)
+	}, c.MainWindow)
+	dlg.SetConfirmText("Allow")
+	dlg.Show()
+}
+
 func (c *Controller) testConnectionAndUpdateDialogText(dlg *dialogs.AddEditServerDialog) bool {
 	dlg.SetInfoText("Testing connection...")
 	conn := backend.ServerConnection{
-		ServerType:  dlg.ServerType,
-		Hostname:    dlg.Host,
-		AltHostname: dlg.AltHost,
-		Username:    dlg.Username,
-		LegacyAuth:  dlg.LegacyAuth,
+		ServerType:        dlg.ServerType,
+		Hostname:          dlg.Host,
+		AltHostname:       dlg.AltHost,
+		Username:          dlg.Username,
+		LegacyAuth:        dlg.LegacyAuth,
+		ForceDirectStream: dlg.ForceDirectStream,
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -689,6 +1225,9 @@ func (c *Controller) testConnectionAndUpdateDialogText(dlg *dialogs.AddEditServe
 	if err == backend.ErrUnreachable {
 		dlg.SetErrorText("Could not reach server (wrong hostname?)")
 		return false
+	} else if errors.Is(err, mediaprovider.ErrTokenAuthNotSupported) {
+		dlg.SetErrorText("Server requires legacy authentication for this user (e.g. LDAP)")
+		return false
 	} else if err != nil {
 		dlg.SetErrorText("Authentication failed (wrong username/password)")
 		return false
@@ -704,30 +1243,77 @@ func (c *Controller) doModalClosed() {
 	}
 }
 
+// number of tracks above which a progress dialog is shown when setting favorites
+const setFavoritesProgressThreshold = 50
+
 func (c *Controller) SetTrackFavorites(trackIDs []string, favorite bool) {
-	go c.App.ServerManager.Server.SetFavorite(mediaprovider.RatingFavoriteParameters{
-		TrackIDs: trackIDs,
-	}, favorite)
+	params := mediaprovider.RatingFavoriteParameters{TrackIDs: trackIDs}
+	if _, ok := mediaprovider.As[mediaprovider.SupportsSetFavoriteProgress](c.App.ServerManager.Server); ok && len(trackIDs) > setFavoritesProgressThreshold {
+		go c.setFavoritesWithProgress(params, favorite)
+		for _, id := range trackIDs {
+			c.App.PlaybackManager.OnTrackFavoriteStatusChanged(id, favorite)
+		}
+	} else {
+		c.SetFavorite(params, favorite)
+	}
+}
+
+// SetFavorite sets the favorite status of the items. The change is applied
+// locally immediately, and rolled back if it fails on the server.
+func (c *Controller) SetFavorite(params mediaprovider.RatingFavoriteParameters, favorite bool) {
+	c.App.RatingFavWriter.SetFavorite(params, favorite)
+}
 
-	for _, id := range trackIDs {
-		c.App.PlaybackManager.OnTrackFavoriteStatusChanged(id, favorite)
+func (c *Controller) refreshFavoritesIfCached() {
+	if c.App.FavoritesCache.Cached() != nil {
+		c.App.FavoritesCache.Refresh()
 	}
 }
 
-func (c *Controller) SetTrackRatings(trackIDs []string, rating int) {
-	r, ok := c.App.ServerManager.Server.(mediaprovider.SupportsRating)
-	if !ok {
-		return
+// setFavoritesWithProgress sets the favorite status of many items, showing a
+// cancelable progress dialog. Tracks which failed are reverted in the play queue.
+func (c *Controller) setFavoritesWithProgress(params mediaprovider.RatingFavoriteParameters, favorite bool) {
+	title := "Adding to favorites"
+	if !favorite {
+		title = "Removing from favorites"
 	}
-	go r.SetRating(mediaprovider.RatingFavoriteParameters{
-		TrackIDs: trackIDs,
-	}, rating)
+	err := c.runBatchWithProgress(title, func(ctx context.Context, onProgress func(done, total int)) error {
+		return c.App.BatchOps.SetFavorite(ctx, params, favorite, onProgress)
+	})
 
-	// Notify PlaybackManager of rating change to update
-	// the in-memory track models
-	for _, id := range trackIDs {
-		c.App.PlaybackManager.OnTrackRatingChanged(id, rating)
+	var itemsErr *mediaprovider.ItemsError
+	switch {
+	case errors.As(err, &itemsErr):
+		log.Printf("error setting favorites: %v", err)
+		for id := range itemsErr.Errors {
+			c.App.PlaybackManager.OnTrackFavoriteStatusChanged(id, !favorite)
+		}
+		c.showError(fmt.Sprintf("Failed to update %d of %d items.", len(itemsErr.Errors), len(params.TrackIDs)))
+	case err != nil && !errors.Is(err, context.Canceled):
+		log.Printf("error setting favorites: %v", err)
 	}
+	c.refreshFavoritesIfCached()
+}
+
+// runBatchWithProgress runs a BatchOperations operation, showing a
+// progress dialog which cancels the operation if closed.
+func (c *Controller) runBatchWithProgress(title string, op func(ctx context.Context, onProgress func(done, total int)) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bar := widget.NewProgressBar()
+	dlg := dialog.NewCustom(title, "Cancel", container.NewPadded(bar), c.MainWindow)
+	dlg.SetOnClosed(cancel)
+	dlg.Show()
+
+	err := op(ctx, func(done, total int) {
+		bar.SetValue(float64(done) / float64(total))
+	})
+	dlg.Hide()
+	return err
+}
+
+func (c *Controller) SetTrackRatings(trackIDs []string, rating int) {
+	c.App.RatingFavWriter.SetRating(trackIDs, rating)
 }
 
 func (c *Controller) ShowShareDialog(id string) {
@@ -759,7 +1345,7 @@ func (c *Controller) ShowShareDialog(id string) {
 }
 
 func (c *Controller) createShareURL(id string) (*url.URL, error) {
-	r, ok := c.App.ServerManager.Server.(mediaprovider.SupportsSharing)
+	r, ok := mediaprovider.As[mediaprovider.SupportsSharing](c.App.ServerManager.Server)
 	if !ok {
 		return nil, fmt.Errorf("server does not support sharing")
 	}
@@ -777,96 +1363,630 @@ func (c *Controller) createShareURL(id string) (*url.URL, error) {
 }
 
 func (c *Controller) ShowDownloadDialog(tracks []*mediaprovider.Track, downloadName string) {
-	numTracks := len(tracks)
-	var fileName string
-	if numTracks == 1 {
-		fileName = filepath.Base(tracks[0].FilePath)
-	} else {
-		fileName = "downloaded_tracks.zip"
+	if len(tracks) == 1 {
+		dg := dialog.NewFileSave(
+			func(file fyne.URIWriteCloser, err error) {
+				if err != nil {
+					log.Println(err)
+					return
+				}
+				if file == nil {
+					return
+				}
+				// the download manager creates the file itself
+				file.Close()
+				c.startDownload(c.App.Downloads.NewTrackJob(tracks[0], file.URI().Path()))
+			},
+			c.MainWindow)
+		dg.SetFileName(filepath.Base(tracks[0].FilePath))
+		dg.Show()
+		return
 	}
 
-	dg := dialog.NewFileSave(
-		func(file fyne.URIWriteCloser, err error) {
+	dg := dialog.NewFolderOpen(
+		func(folder fyne.ListableURI, err error) {
 			if err != nil {
 				log.Println(err)
 				return
 			}
-
-			if file == nil {
+			if folder == nil {
 				return
 			}
-			if numTracks == 1 {
-				go c.downloadTrack(tracks[0], file.URI().Path())
-			} else {
-				go c.downloadTracks(tracks, file.URI().Path(), downloadName)
+			c.startDownload(c.App.Downloads.NewBatchJob(downloadName, tracks, folder.Path()))
+		},
+		c.MainWindow)
+	dg.Show()
+}
+
+func (c *Controller) startDownload(job *backend.DownloadJob) {
+	var lastStatus backend.DownloadStatus
+	job.OnProgress = func(p backend.DownloadProgress) {
+		if p.Status == lastStatus {
+			return
+		}
+		lastStatus = p.Status
+		switch p.Status {
+		case backend.DownloadCompleted:
+			log.Printf("Saved %s to: %s\n", job.Name, job.Destination())
+			c.sendNotification(fmt.Sprintf("Download completed: %s", job.Name), fmt.Sprintf("Saved at: %s", job.Destination()))
+		case backend.DownloadFailed:
+			c.sendNotification(fmt.Sprintf("Download failed: %s", job.Name),
+				fmt.Sprintf("%d of %d tracks could not be downloaded", p.TracksTotal-p.TracksDone, p.TracksTotal))
+		}
+	}
+	c.App.Downloads.Start(job)
+}
+
+// ShowDownloadsDialog shows the progress of the download jobs,
+// allowing them to be canceled or retried.
+func (c *Controller) ShowDownloadsDialog() {
+	type jobRow struct {
+		bar    *widget.ProgressBar
+		status *widget.Label
+		cancel *widget.Button
+		retry  *widget.Button
+	}
+	list := container.NewVBox()
+	rows := make(map[*backend.DownloadJob]*jobRow)
+	var shownJobs []*backend.DownloadJob
+	var mu sync.Mutex // update is called from both the UI and the ticker goroutine
+
+	update := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		jobs := c.App.Downloads.Jobs()
+		if !slices.Equal(jobs, shownJobs) {
+			shownJobs = jobs
+			list.RemoveAll()
+			for _, job := range jobs {
+				job := job
+				row, ok := rows[job]
+				if !ok {
+					row = &jobRow{
+						bar:    widget.NewProgressBar(),
+						status: widget.NewLabel(""),
+						cancel: widget.NewButton("Cancel", func() { c.App.Downloads.Cancel(job) }),
+						retry:  widget.NewButton("Retry", func() { c.startDownload(job) }),
+					}
+					rows[job] = row
+				}
+				list.Add(container.NewBorder(
+					widget.NewLabel(job.Name), nil, nil, container.NewStack(row.cancel, row.retry),
+					container.NewVBox(row.bar, row.status),
+				))
 			}
+			if len(jobs) == 0 {
+				list.Add(widget.NewLabel("No downloads."))
+			}
+			list.Refresh()
+		}
+		for _, job := range jobs {
+			row := rows[job]
+			p := job.Progress()
+			if p.BytesTotal > 0 {
+				row.bar.SetValue(float64(p.BytesDone) / float64(p.BytesTotal))
+			} else if p.TracksTotal > 0 {
+				row.bar.SetValue(float64(p.TracksDone) / float64(p.TracksTotal))
+			}
+			row.status.SetText(fmt.Sprintf("%s - %d/%d tracks", p.Status, p.TracksDone, p.TracksTotal))
+			active := p.Status == backend.DownloadQueued || p.Status == backend.DownloadRunning
+			row.cancel.Hidden = !active
+			row.retry.Hidden = active || p.Status == backend.DownloadCompleted
+			row.cancel.Refresh()
+			row.retry.Refresh()
+		}
+	}
+	update()
 
-		},
+	scroll := container.NewVScroll(list)
+	scroll.SetMinSize(fyne.NewSize(450, 300))
+	clear := widget.NewButton("Clear Completed", func() {
+		c.App.Downloads.ClearFinished()
+		update()
+	})
+	dlg := dialog.NewCustom("Downloads", "Close",
+		container.NewBorder(nil, container.NewHBox(layout.NewSpacer(), clear), nil, nil, scroll),
 		c.MainWindow)
-	dg.SetFileName(fileName)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dlg.SetOnClosed(cancel)
+	go func() {
+		t := time.NewTicker(500 * time.Millisecond)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				update()
+			}
+		}
+	}()
+	dlg.Show()
+}
+
+// ShowExportQueueDialog shows a file save dialog to export the play queue
+// as a setlist. The format is chosen by the file extension (.csv, .txt or .html).
+func (c *Controller) ShowExportQueueDialog() {
+	queue := c.App.PlaybackManager.GetPlayQueue()
+	if len(queue) == 0 {
+		dialog.ShowInformation("Export Queue", "The play queue is empty.", c.MainWindow)
+		return
+	}
+	dg := dialog.NewFileSave(func(file fyne.URIWriteCloser, err error) {
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		if file == nil {
+			return
+		}
+		defer file.Close()
+		format := backend.QueueExportFormatForFile(file.URI().Name())
+		if err := backend.ExportQueue(file, queue, format); err != nil {
+			log.Printf("error exporting queue: %v", err)
+			c.showError("Failed to export the play queue.")
+		}
+	}, c.MainWindow)
+	dg.SetFileName("setlist.csv")
+	dg.SetFilter(storage.NewExtensionFileFilter([]string{".csv", ".txt", ".html"}))
 	dg.Show()
 }
 
-func (c *Controller) downloadTrack(track *mediaprovider.Track, filePath string) {
-	reader, err := c.App.ServerManager.Server.DownloadTrack(track.ID)
-	if err != nil {
-		log.Println(err)
+// ShowSelectMusicLibraryDialog lets the user choose which of the
+// server's music libraries to browse, on servers with several.
+func (c *Controller) ShowSelectMusicLibraryDialog() {
+	ml, ok := mediaprovider.As[mediaprovider.SupportsMusicLibraries](c.App.ServerManager.Server)
+	if !ok {
+		dialog.ShowInformation("Music Library", "This server does not support selecting a music library.", c.MainWindow)
 		return
 	}
+	go func() {
+		libraries, err := ml.GetMusicLibraries()
+		if err != nil {
+			log.Printf("error getting music libraries: %v", err)
+			c.showError("Failed to get the music libraries from the server.")
+			return
+		}
+		if len(libraries) <= 1 {
+			dialog.ShowInformation("Music Library", "This server has only one music library.", c.MainWindow)
+			return
+		}
 
-	file, err := os.Create(filePath)
-	if err != nil {
-		log.Println(err)
+		const allLibraries = "All libraries"
+		options := []string{allLibraries}
+		selected := allLibraries
+		current := ""
+		if conf := c.App.ServerManager.CurrentServerConfig(); conf != nil {
+			current = conf.MusicLibraryID
+		}
+		for _, lib := range libraries {
+			options = append(options, lib.Name)
+			if lib.ID == current {
+				selected = lib.Name
+			}
+		}
+		radio := widget.NewRadioGroup(options, nil)
+		radio.Required = true
+		radio.Selected = selected
+		dlg := dialog.NewCustomConfirm("Music Library", "OK", "Cancel", radio, func(ok bool) {
+			if !ok || radio.Selected == selected {
+				return
+			}
+			id := ""
+			if idx := slices.Index(options, radio.Selected); idx > 0 {
+				id = libraries[idx-1].ID
+			}
+			c.App.ServerManager.SetMusicLibrary(id)
+			c.ReloadFunc()
+		}, c.MainWindow)
+		dlg.Show()
+	}()
+}
+
+// ShowSwitchUserDialog lets the user switch between the accounts
+// saved for the connected server, and add or remove accounts.
+func (c *Controller) ShowSwitchUserDialog() {
+	conf := c.App.ServerManager.CurrentServerConfig()
+	if conf == nil {
 		return
 	}
-	defer file.Close()
+	accounts := conf.AllAccounts()
+	options := sharedutil.MapSlice(accounts, func(a backend.ServerAccount) string { return a.Username })
+	radio := widget.NewRadioGroup(options, nil)
+	radio.Required = true
+	current := conf.ActiveAccount()
+	radio.Selected = current.Username
 
-	_, err = io.Copy(file, reader)
+	var dlg *dialog.ConfirmDialog
+	addBtn := widget.NewButtonWithIcon("Add User...", theme.ContentAddIcon(), func() {
+		dlg.Hide()
+		c.showAddAccountDialog(conf)
+	})
+	removeBtn := widget.NewButtonWithIcon("Remove", theme.DeleteIcon(), func() {
+		idx := slices.Index(options, radio.Selected)
+		if idx <= 0 || accounts[idx].ID == current.ID {
+			return // can't remove the primary or logged in account
+		}
+		c.App.ServerManager.DeleteAccount(conf, accounts[idx].ID)
+		accounts = slices.Delete(accounts, idx, idx+1)
+		options = slices.Delete(options, idx, idx+1)
+		radio.Options = options
+		radio.Selected = current.Username
+		radio.Refresh()
+	})
+	content := container.NewVBox(radio, container.NewHBox(addBtn, removeBtn))
+	dlg = dialog.NewCustomConfirm("Switch User", "Switch", "Cancel", content, func(ok bool) {
+		idx := slices.Index(options, radio.Selected)
+		if !ok || idx < 0 || accounts[idx].ID == current.ID {
+			return
+		}
+		go c.switchAccount(accounts[idx].ID, "")
+	}, c.MainWindow)
+	dlg.Show()
+}
+
+func (c *Controller) showAddAccountDialog(conf *backend.ServerConfig) {
+	username := widget.NewEntry()
+	password := widget.NewPasswordEntry()
+	items := []*widget.FormItem{
+		widget.NewFormItem("Username", username),
+		widget.NewFormItem("Password", password),
+	}
+	dialog.ShowForm("Add User", "Add", "Cancel", items, func(ok bool) {
+		if !ok || username.Text == "" {
+			return
+		}
+		account, err := c.App.ServerManager.AddAccount(conf, username.Text, password.Text)
+		if err != nil {
+			// fall back to using the password in-memory, like the login dialog
+			log.Printf("error setting keyring credentials: %v", err)
+		}
+		go func() {
+			if err := c.switchAccount(account.ID, password.Text); err != nil {
+				c.App.ServerManager.DeleteAccount(conf, account.ID)
+			}
+		}()
+	}, c.MainWindow)
+}
+
+func (c *Controller) switchAccount(accountID uuid.UUID, password string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := c.App.ServerManager.SwitchAccount(ctx, accountID, password)
 	if err != nil {
-		log.Println(err)
+		log.Printf("error switching user: %v", err)
+		c.showError(fmt.Sprintf("Failed to log in as the user: %s", err.Error()))
+	}
+	return err
+}
+
+// ShowServerSettingsDialog shows the settings of the connected server
+// which override the global settings. Blank fields use the global setting.
+func (c *Controller) ShowServerSettingsDialog() {
+	conf := c.App.ServerManager.CurrentServerConfig()
+	if conf == nil {
 		return
 	}
+	o := conf.Overrides
+	global := c.App.Config
+
+	const useGlobal, on, off = "Use global setting", "On", "Off"
+	scrobble := widget.NewSelect([]string{useGlobal, on, off}, nil)
+	scrobble.SetSelected(useGlobal)
+	if o.ScrobbleEnabled != nil && *o.ScrobbleEnabled {
+		scrobble.SetSelected(on)
+	} else if o.ScrobbleEnabled != nil {
+		scrobble.SetSelected(off)
+	}
+	intEntry := func(val *int, globalVal int) *widget.Entry {
+		e := widget.NewEntry()
+		e.SetPlaceHolder(strconv.Itoa(globalVal))
+		if val != nil {
+			e.SetText(strconv.Itoa(*val))
+		}
+		e.Validator = func(s string) error {
+			if s == "" {
+				return nil
+			}
+			_, err := strconv.Atoi(s)
+			return err
+		}
+		return e
+	}
+	percent := intEntry(o.ScrobbleThresholdPercent, global.Scrobbling.ThresholdPercent)
+	seconds := intEntry(o.ScrobbleThresholdSeconds, global.Scrobbling.ThresholdTimeSeconds)
+	cacheSize := intEntry(o.MaxTrackCacheSizeMB, global.LocalPlayback.MaxTrackCacheSizeMB)
+
+	sections := widget.NewCheckGroup(backend.AllHomeSections, nil)
+	sections.Selected = slices.Clone(global.Home.Sections)
+	if o.HomeSections != nil {
+		sections.Selected = slices.Clone(o.HomeSections)
+	}
+	customSections := widget.NewCheck("Custom home sections", func(b bool) {
+		if b {
+			sections.Enable()
+		} else {
+			sections.Disable()
+		}
+	})
+	customSections.SetChecked(o.HomeSections != nil)
+	if o.HomeSections == nil {
+		sections.Disable()
+	}
+
+	parseInt := func(e *widget.Entry) *int {
+		i, err := strconv.Atoi(e.Text)
+		if err != nil {
+			return nil
+		}
+		return &i
+	}
+	items := []*widget.FormItem{
+		widget.NewFormItem("Scrobbling", scrobble),
+		widget.NewFormItem("Scrobble after (%)", percent),
+		widget.NewFormItem("Scrobble after (sec)", seconds),
+		widget.NewFormItem("Track cache size (MB)", cacheSize),
+		widget.NewFormItem("Home page", container.NewVBox(customSections, sections)),
+	}
+	dlg := dialog.NewForm(fmt.Sprintf("Settings for %s", conf.Nickname), "Save", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		var newO backend.ServerSettingsOverrides
+		if scrobble.Selected != useGlobal {
+			enabled := scrobble.Selected == on
+			newO.ScrobbleEnabled = &enabled
+		}
+		newO.ScrobbleThresholdPercent = parseInt(percent)
+		newO.ScrobbleThresholdSeconds = parseInt(seconds)
+		newO.MaxTrackCacheSizeMB = parseInt(cacheSize)
+		if customSections.Checked {
+			newO.HomeSections = slices.Clone(sections.Selected)
+			if newO.HomeSections == nil {
+				newO.HomeSections = []string{}
+			}
+		}
+		conf.Overrides = newO
+		c.App.SaveConfigFile()
+		if c.ReloadFunc != nil {
+			c.ReloadFunc()
+		}
+	}, c.MainWindow)
+	dlg.Resize(fyne.NewSize(450, 0))
+	dlg.Show()
+}
 
-	log.Printf("Saved song %s to: %s\n", track.Title, filePath)
-	c.sendNotification(fmt.Sprintf("Download completed: %s", track.Title), fmt.Sprintf("Saved at: %s", filePath))
+// ShowExportHistoryDialog exports the local listening history
+// as CSV or JSON, depending on the chosen file extension.
+func (c *Controller) ShowExportHistoryDialog() {
+	dg := dialog.NewFileSave(func(file fyne.URIWriteCloser, err error) {
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		if file == nil {
+			return
+		}
+		defer file.Close()
+		format := backend.HistoryExportCSV
+		if strings.EqualFold(file.URI().Extension(), ".json") {
+			format = backend.HistoryExportJSON
+		}
+		if err := c.App.History.Export(file, format); err != nil {
+			log.Printf("error exporting listening history: %v", err)
+			c.showError("Failed to export the listening history.")
+		}
+	}, c.MainWindow)
+	dg.SetFileName("listening-history.csv")
+	dg.SetFilter(storage.NewExtensionFileFilter([]string{".csv", ".json"}))
+	dg.Show()
 }
 
-func (c *Controller) downloadTracks(tracks []*mediaprovider.Track, filePath, downloadName string) {
-	zipFile, err := os.Create(filePath)
-	if err != nil {
-		log.Println(err)
+// ShowYearInReviewDialog shows the year-in-review summary of the local
+// listening history, which can be saved as an image or HTML page.
+func (c *Controller) ShowYearInReviewDialog() {
+	years := c.App.History.Years()
+	if len(years) == 0 {
+		dialog.ShowInformation("Year in Review", "There is no listening history yet.", c.MainWindow)
 		return
 	}
-	defer zipFile.Close()
+	var review *backend.YearInReview
+	preview := canvas.NewImageFromImage(nil)
+	preview.FillMode = canvas.ImageFillContain
+	preview.SetMinSize(fyne.NewSize(360, 450))
+	yearOpts := sharedutil.MapSlice(years, strconv.Itoa)
+	yearSelect := widget.NewSelect(yearOpts, func(y string) {
+		year, _ := strconv.Atoi(y)
+		review = c.App.History.YearInReview(year)
+		preview.Image = review.Image()
+		preview.Refresh()
+	})
+	yearSelect.SetSelected(yearOpts[0])
 
-	zipWriter := zip.NewWriter(zipFile)
-	defer zipWriter.Close()
+	save := widget.NewButtonWithIcon("Save...", theme.DocumentSaveIcon(), func() {
+		c.saveYearInReview(review)
+	})
+	content := container.NewBorder(
+		container.NewHBox(widget.NewLabel("Year"), yearSelect, layout.NewSpacer(), save),
+		nil, nil, nil, preview)
+	dialog.NewCustom("Year in Review", "Close", content, c.MainWindow).Show()
+}
 
-	for _, track := range tracks {
-		reader, err := c.App.ServerManager.Server.DownloadTrack(track.ID)
+// saveYearInReview saves the summary as a PNG image
+// or HTML page, depending on the chosen file extension.
+func (c *Controller) saveYearInReview(review *backend.YearInReview) {
+	dg := dialog.NewFileSave(func(file fyne.URIWriteCloser, err error) {
 		if err != nil {
 			log.Println(err)
-			continue
+			return
+		}
+		if file == nil {
+			return
+		}
+		defer file.Close()
+		ext := strings.ToLower(file.URI().Extension())
+		if ext == ".html" || ext == ".htm" {
+			err = review.WriteHTML(file)
+		} else {
+			err = png.Encode(file, review.Image())
+		}
+		if err != nil {
+			log.Printf("error saving year in review: %v", err)
+			c.showError("Failed to save the year in review.")
 		}
+	}, c.MainWindow)
+	dg.SetFileName(fmt.Sprintf("%d-in-music.png", review.Year))
+	dg.SetFilter(storage.NewExtensionFileFilter([]string{".png", ".html"}))
+	dg.Show()
+}
 
-		fileName := filepath.Base(track.FilePath)
+// ShowRequestLogDialog shows the recent API requests recorded in the
+// request log, which can be saved to a file to attach to bug reports.
+func (c *Controller) ShowRequestLogDialog() {
+	var entries []backend.RequestLogEntry
+	details := widget.NewLabel("")
+	details.Wrapping = fyne.TextWrapBreak
+	list := widget.NewList(
+		func() int { return len(entries) },
+		func() fyne.CanvasObject {
+			l := widget.NewLabel("")
+			l.Truncation = fyne.TextTruncateEllipsis
+			return l
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(entries[id].Summary())
+		})
+	list.OnSelected = func(id widget.ListItemID) {
+		details.SetText(entries[id].String())
+	}
+	refresh := func() {
+		entries = c.App.RequestLog.Entries()
+		slices.Reverse(entries) // newest first
+		list.UnselectAll()
+		list.Refresh()
+		details.SetText("")
+	}
+	refresh()
+
+	hint := widget.NewLabel("Recording is off. Enable it under Settings > Experimental to record new requests.")
+	hint.Wrapping = fyne.TextWrapWord
+	if c.App.RequestLog.Enabled() {
+		hint.Hide()
+	}
+	buttons := container.NewHBox(
+		widget.NewButtonWithIcon("Refresh", theme.ViewRefreshIcon(), refresh),
+		widget.NewButtonWithIcon("Clear", theme.DeleteIcon(), func() {
+			c.App.RequestLog.Clear()
+			refresh()
+		}),
+		layout.NewSpacer(),
+		widget.NewButtonWithIcon("Save...", theme.DocumentSaveIcon(), c.saveRequestLog),
+	)
+	split := container.NewHSplit(list, container.NewVScroll(details))
+	split.Offset = 0.45
+	dlg := dialog.NewCustom("API Request Log", "Close",
+		container.NewBorder(container.NewVBox(hint, buttons), nil, nil, nil, split), c.MainWindow)
+	dlg.Resize(fyne.NewSize(900, 550))
+	dlg.Show()
+}
 
-		fileWriter, err := zipWriter.Create(fileName)
+func (c *Controller) saveRequestLog() {
+	dg := dialog.NewFileSave(func(file fyne.URIWriteCloser, err error) {
 		if err != nil {
 			log.Println(err)
-			continue
+			return
 		}
+		if file == nil {
+			return
+		}
+		defer file.Close()
+		if err := c.App.RequestLog.Dump(file); err != nil {
+			log.Printf("error saving request log: %v", err)
+			c.showError("Failed to save the request log.")
+		}
+	}, c.MainWindow)
+	dg.SetFileName(fmt.Sprintf("%s-requests-%s.txt", res.AppName, time.Now().Format(time.DateOnly)))
+	dg.SetFilter(storage.NewExtensionFileFilter([]string{".txt"}))
+	dg.Show()
+}
 
-		_, err = io.Copy(fileWriter, reader)
+// ShowExportAppDataDialog saves an archive of the local app data
+// (settings, history, smart playlists, etc.) for backup or migration.
+func (c *Controller) ShowExportAppDataDialog() {
+	dg := dialog.NewFileSave(func(file fyne.URIWriteCloser, err error) {
 		if err != nil {
 			log.Println(err)
-			continue
+			return
 		}
+		if file == nil {
+			return
+		}
+		defer file.Close()
+		if err := c.App.ExportAppData(file); err != nil {
+			log.Printf("error exporting app data: %v", err)
+			c.showError("Failed to export the app data.")
+		}
+	}, c.MainWindow)
+	dg.SetFileName(fmt.Sprintf("%s-data-%s.zip", res.AppName, time.Now().Format(time.DateOnly)))
+	dg.SetFilter(storage.NewExtensionFileFilter([]string{".zip"}))
+	dg.Show()
+}
 
-		log.Printf("Saved song %s to: %s\n", track.Title, filePath)
-	}
+// ShowImportAppDataDialog imports an archive saved by ShowExportAppDataDialog,
+// which replaces the local app data when the app is restarted.
+func (c *Controller) ShowImportAppDataDialog() {
+	dialog.ShowConfirm("Import App Data",
+		fmt.Sprintf("Importing replaces the settings, listening history, and other local data\nof this computer when %s is restarted. Continue?", res.DisplayName),
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			dg := dialog.NewFileOpen(func(file fyne.URIReadCloser, err error) {
+				if err != nil {
+					log.Println(err)
+					return
+				}
+				if file == nil {
+					return
+				}
+				defer file.Close()
+				if err := c.App.ImportAppData(file); err != nil {
+					log.Printf("error importing app data: %v", err)
+					c.showError(fmt.Sprintf("Failed to import the app data: %s", err.Error()))
+					return
+				}
+				dialog.ShowInformation("Import App Data",
+					fmt.Sprintf("The data will be imported when %s is restarted.", res.DisplayName), c.MainWindow)
+			}, c.MainWindow)
+			dg.SetFilter(storage.NewExtensionFileFilter([]string{".zip"}))
+			dg.Show()
+		}, c.MainWindow)
+}
 
-	c.sendNotification(fmt.Sprintf("Download completed: %s", downloadName), fmt.Sprintf("Saved at: %s", filePath))
+// PrintQueueSetlist renders the play queue as a print-friendly
+// HTML setlist and opens it in the browser for printing.
+func (c *Controller) PrintQueueSetlist() {
+	queue := c.App.PlaybackManager.GetPlayQueue()
+	if len(queue) == 0 {
+		dialog.ShowInformation("Print Setlist", "The play queue is empty.", c.MainWindow)
+		return
+	}
+	f, err := os.CreateTemp("", "supersonic-setlist-*.html")
+	if err != nil {
+		log.Printf("error creating setlist file: %v", err)
+		return
+	}
+	err = backend.ExportQueue(f, queue, backend.QueueExportHTML)
+	f.Close()
+	if err != nil {
+		log.Printf("error exporting queue: %v", err)
+		return
+	}
+	u := storage.NewFileURI(f.Name())
+	if pu, err := url.Parse(u.String()); err == nil {
+		_ = fyne.CurrentApp().OpenURL(pu)
+	}
 }
 
 func (c *Controller) sendNotification(title, content string) {
@@ -883,25 +2003,97 @@ func (c *Controller) showError(content string) {
 
 func (c *Controller) ShowAlbumInfoDialog(albumID, albumName string, albumCover image.Image) {
 	go func() {
-		albumInfo, err := c.App.ServerManager.Server.GetAlbumInfo(albumID)
+		albumInfo, err := c.App.AlbumInfo.GetAlbumInfo(albumID)
 		if err != nil {
 			log.Print("Error getting album info: ", err)
 			return
 		}
-		dlg := dialogs.NewAlbumInfoDialog(albumInfo, albumName, albumCover)
+		editor, canEdit := mediaprovider.As[mediaprovider.SupportsMetadataEditing](c.App.ServerManager.Server)
+		canEdit = canEdit && editor.CanEditMetadata()
+		dlg := dialogs.NewAlbumInfoDialog(albumInfo, albumName, albumCover, canEdit)
 		pop := widget.NewModalPopUp(dlg, c.MainWindow.Canvas())
 		dlg.OnDismiss = func() {
 			pop.Hide()
 			c.doModalClosed()
 		}
+		dlg.OnEdit = func() {
+			pop.Hide()
+			c.doModalClosed()
+			go c.DoEditAlbumMetadataWorkflow(albumID)
+		}
 		c.ClosePopUpOnEscape(pop)
 		c.haveModal = true
 		pop.Show()
 	}()
 }
 
+// DoEditTrackMetadataWorkflow shows a dialog to edit
+// the track's metadata and updates it on the server.
+func (c *Controller) DoEditTrackMetadataWorkflow(track *mediaprovider.Track) {
+	editor, ok := mediaprovider.As[mediaprovider.SupportsMetadataEditing](c.App.ServerManager.Server)
+	if !ok {
+		return
+	}
+	go func() {
+		if !editor.CanEditMetadata() {
+			c.showError("You don't have permission to edit metadata on this server.")
+			return
+		}
+		c.showEditMetadataDialog("Edit Track Info", track.Title, track.Year, track.Genres,
+			func(update mediaprovider.MetadataUpdate) error {
+				return editor.UpdateTrackMetadata(track.ID, update)
+			})
+	}()
+}
+
+// DoEditAlbumMetadataWorkflow shows a dialog to edit
+// the album's metadata and updates it on the server.
+func (c *Controller) DoEditAlbumMetadataWorkflow(albumID string) {
+	editor, ok := mediaprovider.As[mediaprovider.SupportsMetadataEditing](c.App.ServerManager.Server)
+	if !ok {
+		return
+	}
+	album, err := c.App.ServerManager.Server.GetAlbum(albumID)
+	if err != nil {
+		log.Printf("error getting album: %s", err.Error())
+		return
+	}
+	c.showEditMetadataDialog("Edit Album Info", album.Name, album.Year, album.Genres,
+		func(update mediaprovider.MetadataUpdate) error {
+			return editor.UpdateAlbumMetadata(albumID, update)
+		})
+}
+
+func (c *Controller) showEditMetadataDialog(heading, title string, year int, genres []string, save func(mediaprovider.MetadataUpdate) error) {
+	dlg := dialogs.NewEditMetadataDialog(heading, title, year, genres)
+	pop := widget.NewModalPopUp(dlg, c.MainWindow.Canvas())
+	c.ClosePopUpOnEscape(pop)
+	dlg.OnCanceled = func() {
+		pop.Hide()
+		c.doModalClosed()
+	}
+	dlg.OnSubmit = func(update mediaprovider.MetadataUpdate) {
+		pop.Hide()
+		c.doModalClosed()
+		go func() {
+			if err := save(update); err != nil {
+				log.Printf("error updating metadata: %s", err.Error())
+				c.showError(fmt.Sprintf("Failed to update metadata: %s", err.Error()))
+				return
+			}
+			c.App.Genres.Refresh()
+			c.ReloadFunc()
+		}()
+	}
+	c.haveModal = true
+	pop.Show()
+}
+
 func (c *Controller) GetSongRadioTracks(sourceTrack *mediaprovider.Track) ([]*mediaprovider.Track, error) {
-	radioTracks, err := c.App.ServerManager.Server.GetSongRadio(sourceTrack.ID, 100)
+	radioTracks, err := c.App.RadioSeeds.FetchMix(backend.RadioSeedTrack, sourceTrack.ID, 100,
+		func(count int) ([]*mediaprovider.Track, error) {
+			return c.App.ServerManager.Server.GetSongRadio(sourceTrack.ID, count)
+		})
 	if err != nil {
 		return nil, fmt.Errorf("error getting song radio: %s", err.Error())
 	}
@@ -0,0 +1,361 @@
+package ui
+
+import (
+	"context"
+	"image"
+	"log"
+	"math"
+	"sync"
+
+	"github.com/dweymouth/supersonic/backend"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/sharedutil"
+	"github.com/dweymouth/supersonic/ui/controller"
+	"github.com/dweymouth/supersonic/ui/widgets"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+)
+
+// Views that can be detached into their own windows
+const (
+	DetachedViewQueue      = "Play Queue"
+	DetachedViewLyrics     = "Lyrics"
+	DetachedViewNowPlaying = "Now Playing"
+)
+
+// DetachedWindows manages views of the play queue, lyrics and now playing
+// track shown in separate OS windows, e.g. on a second monitor.
+// The set of open windows and their sizes is restored on the next launch.
+type DetachedWindows struct {
+	app            *backend.App
+	contr          *controller.Controller
+	displayAppName string
+
+	mu      sync.Mutex
+	windows map[string]*detachedWindow
+}
+
+type detachedWindow struct {
+	window fyne.Window
+	view   detachedView
+}
+
+type detachedView interface {
+	Content() fyne.CanvasObject
+	OnSongChange(mediaprovider.MediaItem)
+	OnQueueChange()
+	OnPlayTimeUpdate(curTime float64, seeked bool)
+	Close()
+}
+
+func NewDetachedWindows(app *backend.App, contr *controller.Controller, displayAppName string) *DetachedWindows {
+	d := &DetachedWindows{
+		app:            app,
+		contr:          contr,
+		displayAppName: displayAppName,
+		windows:        make(map[string]*detachedWindow),
+	}
+	pm := app.PlaybackManager
+	pm.OnSongChange(func(item mediaprovider.MediaItem, _ *mediaprovider.Track) {
+		d.forEachView(func(v detachedView) { v.OnSongChange(item) })
+	})
+	pm.OnQueueChange(func() {
+		d.forEachView(func(v detachedView) { v.OnQueueChange() })
+	})
+	pm.OnPlayTimeUpdate(func(curTime, _ float64, seeked bool) {
+		d.forEachView(func(v detachedView) { v.OnPlayTimeUpdate(curTime, seeked) })
+	})
+	return d
+}
+
+// Open shows the given view in its own window,
+// or focuses its window if already open.
+func (d *DetachedWindows) Open(view string) {
+	d.open(view, 0, 0)
+}
+
+// Restore reopens the windows that were open when the app last exited.
+func (d *DetachedWindows) Restore() {
+	for _, w := range d.app.Config.Application.DetachedWindows {
+		d.open(w.View, w.Width, w.Height)
+	}
+}
+
+// SaveLayout records the open windows and their sizes in the config.
+func (d *DetachedWindows) SaveLayout() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	windows := make([]backend.DetachedWindowConfig, 0, len(d.windows))
+	for _, view := range []string{DetachedViewQueue, DetachedViewLyrics, DetachedViewNowPlaying} {
+		if w, ok := d.windows[view]; ok {
+			size := w.window.Canvas().Size()
+			windows = append(windows, backend.DetachedWindowConfig{
+				View: view,
+				// round sizes to even to avoid Wayland issues with 2x scaling factor
+				Width:  int(math.RoundToEven(float64(size.Width))),
+				Height: int(math.RoundToEven(float64(size.Height))),
+			})
+		}
+	}
+	d.app.Config.Application.DetachedWindows = windows
+}
+
+func (d *DetachedWindows) open(view string, width, height int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if w, ok := d.windows[view]; ok {
+		w.window.RequestFocus()
+		return
+	}
+
+	var v detachedView
+	defaultSize := fyne.NewSize(400, 600)
+	switch view {
+	case DetachedViewQueue:
+		v = newDetachedQueueView(d.app, d.contr)
+	case DetachedViewLyrics:
+		v = newDetachedLyricsView(d.app)
+	case DetachedViewNowPlaying:
+		v = newDetachedNowPlayingView(d.app, d.contr)
+		defaultSize = fyne.NewSize(400, 500)
+	default:
+		log.Printf("unknown detached view: %s", view)
+		return
+	}
+
+	win := fyne.CurrentApp().NewWindow(view + " · " + d.displayAppName)
+	win.SetContent(v.Content())
+	if width > 1 && height > 1 {
+		win.Resize(fyne.NewSize(float32(width), float32(height)))
+	} else {
+		win.Resize(defaultSize)
+	}
+	win.SetOnClosed(func() {
+		v.Close()
+		d.mu.Lock()
+		delete(d.windows, view)
+		d.mu.Unlock()
+	})
+	d.windows[view] = &detachedWindow{window: win, view: v}
+	v.OnSongChange(d.app.PlaybackManager.NowPlaying())
+	v.OnQueueChange()
+	win.Show()
+}
+
+func (d *DetachedWindows) forEachView(f func(detachedView)) {
+	d.mu.Lock()
+	views := make([]detachedView, 0, len(d.windows))
+	for _, w := range d.windows {
+		views = append(views, w.view)
+	}
+	d.mu.Unlock()
+	for _, v := range views {
+		f(v)
+	}
+}
+
+// focusMainWindow brings the main window forward after navigating
+// to a page from a detached window.
+func focusMainWindow(contr *controller.Controller) {
+	contr.MainWindow.Show()
+	contr.MainWindow.RequestFocus()
+}
+
+type detachedQueueView struct {
+	pm    *backend.PlaybackManager
+	list  *widgets.PlayQueueList
+	queue []mediaprovider.MediaItem
+}
+
+func newDetachedQueueView(app *backend.App, contr *controller.Controller) *detachedQueueView {
+	q := &detachedQueueView{
+		pm:   app.PlaybackManager,
+		list: widgets.NewPlayQueueList(app.ImageManager, false),
+	}
+	_, canRate := mediaprovider.As[mediaprovider.SupportsRating](app.ServerManager.Server)
+	q.list.DisableRating = !canRate
+	q.list.DisableSharing = true
+	q.list.OnPlayItemAt = func(idx int) { _ = q.pm.PlayTrackAt(idx) }
+	q.list.OnRemoveFromQueue = func(ids []string) {
+		q.list.UnselectAll()
+		q.pm.RemoveTracksFromQueue(ids)
+	}
+	q.list.OnReorderItems = q.reorderItems
+	q.list.OnAddToPlaylist = contr.DoAddTracksToPlaylistWorkflow
+	q.list.OnDownload = contr.ShowDownloadDialog
+	q.list.OnSetRating = contr.SetTrackRatings
+	q.list.OnSetFavorite = contr.SetTrackFavorites
+	q.list.OnShowArtistPage = func(artistID string) {
+		contr.NavigateTo(controller.ArtistRoute(artistID))
+		focusMainWindow(contr)
+	}
+	return q
+}
+
+func (q *detachedQueueView) Content() fyne.CanvasObject { return q.list }
+
+func (q *detachedQueueView) OnSongChange(item mediaprovider.MediaItem) {
+	q.list.SetNowPlaying(sharedutil.MediaItemIDOrEmptyStr(item))
+}
+
+func (q *detachedQueueView) OnQueueChange() {
+	q.queue = q.pm.GetPlayQueue()
+	q.list.SetItems(q.queue)
+}
+
+func (q *detachedQueueView) OnPlayTimeUpdate(float64, bool) {}
+
+func (q *detachedQueueView) Close() {}
+
+func (q *detachedQueueView) reorderItems(ids []string, op sharedutil.TrackReorderOp) {
+	idSet := sharedutil.ToSet(ids)
+	idxs := make([]int, 0, len(ids))
+	for i, item := range q.queue {
+		if _, ok := idSet[item.Metadata().ID]; ok {
+			idxs = append(idxs, i)
+		}
+	}
+	q.pm.UpdatePlayQueue(sharedutil.ReorderItems(q.queue, idxs, op))
+}
+
+type detachedLyricsView struct {
+	app    *backend.App
+	viewer *widgets.LyricsViewer
+
+	mu          sync.Mutex
+	trackID     string
+	lastPlayPos float64
+	fetchCancel context.CancelFunc
+}
+
+func newDetachedLyricsView(app *backend.App) *detachedLyricsView {
+	return &detachedLyricsView{app: app, viewer: widgets.NewLyricsViewer()}
+}
+
+func (l *detachedLyricsView) Content() fyne.CanvasObject { return l.viewer }
+
+func (l *detachedLyricsView) OnSongChange(item mediaprovider.MediaItem) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	id := sharedutil.MediaItemIDOrEmptyStr(item)
+	if id == l.trackID && id != "" {
+		return
+	}
+	l.trackID = id
+	if l.fetchCancel != nil {
+		l.fetchCancel()
+	}
+	tr, ok := item.(*mediaprovider.Track)
+	if !ok {
+		l.viewer.SetLyrics(nil)
+		return
+	}
+	// show an empty (not nil) lyric during fetch
+	// to keep it from showing "Lyrics not available"
+	l.viewer.SetLyrics(&mediaprovider.Lyrics{Synced: true,
+		Lines: []mediaprovider.LyricLine{{Text: ""}}})
+	ctx, cancel := context.WithCancel(context.Background())
+	l.fetchCancel = cancel
+	go func() {
+		lyrics := l.app.Lyrics.FetchLyrics(tr)
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if ctx.Err() == nil {
+			l.viewer.SetLyrics(lyrics)
+			if lyrics != nil {
+				l.viewer.OnSeeked(l.lastPlayPos)
+			}
+		}
+	}()
+}
+
+func (l *detachedLyricsView) OnQueueChange() {}
+
+func (l *detachedLyricsView) OnPlayTimeUpdate(curTime float64, seeked bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastPlayPos = curTime
+	if seeked {
+		l.viewer.OnSeeked(curTime)
+	} else {
+		l.viewer.UpdatePlayPos(curTime)
+	}
+}
+
+func (l *detachedLyricsView) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.fetchCancel != nil {
+		l.fetchCancel()
+	}
+}
+
+type detachedNowPlayingView struct {
+	im          *backend.ImageManager
+	card        *widgets.LargeNowPlayingCard
+	content     fyne.CanvasObject
+	nowPlaying  mediaprovider.MediaItem
+	imageCancel context.CancelFunc
+}
+
+func newDetachedNowPlayingView(app *backend.App, contr *controller.Controller) *detachedNowPlayingView {
+	n := &detachedNowPlayingView{im: app.ImageManager, card: widgets.NewLargeNowPlayingCard()}
+	_, canRate := mediaprovider.As[mediaprovider.SupportsRating](app.ServerManager.Server)
+	n.card.DisableRating = !canRate
+	n.card.OnAlbumNameTapped = func() {
+		if n.nowPlaying != nil {
+			contr.NavigateTo(controller.AlbumRoute(n.nowPlaying.Metadata().AlbumID))
+			focusMainWindow(contr)
+		}
+	}
+	n.card.OnArtistNameTapped = func(artistID string) {
+		contr.NavigateTo(controller.ArtistRoute(artistID))
+		focusMainWindow(contr)
+	}
+	n.card.OnSetFavorite = func(fav bool) {
+		if n.nowPlaying != nil {
+			contr.SetTrackFavorites([]string{n.nowPlaying.Metadata().ID}, fav)
+		}
+	}
+	n.card.OnSetRating = func(rating int) {
+		if n.nowPlaying != nil {
+			contr.SetTrackRatings([]string{n.nowPlaying.Metadata().ID}, rating)
+		}
+	}
+	n.content = container.NewPadded(container.NewVBox(layout.NewSpacer(), n.card, layout.NewSpacer()))
+	return n
+}
+
+func (n *detachedNowPlayingView) Content() fyne.CanvasObject { return n.content }
+
+func (n *detachedNowPlayingView) OnSongChange(item mediaprovider.MediaItem) {
+	n.nowPlaying = item
+	if n.imageCancel != nil {
+		n.imageCancel()
+		n.imageCancel = nil
+	}
+	n.card.Update(item)
+	if item == nil {
+		n.card.SetCoverImage(nil)
+		return
+	}
+	n.imageCancel = n.im.GetFullSizeCoverArtAsync(item.Metadata().CoverArtID, func(img image.Image, err error) {
+		if err != nil {
+			log.Printf("error loading cover art: %v", err)
+			return
+		}
+		n.card.SetCoverImage(img)
+	})
+}
+
+func (n *detachedNowPlayingView) OnQueueChange() {}
+
+func (n *detachedNowPlayingView) OnPlayTimeUpdate(float64, bool) {}
+
+func (n *detachedNowPlayingView) Close() {
+	if n.imageCancel != nil {
+		n.imageCancel()
+	}
+}
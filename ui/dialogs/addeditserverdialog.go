@@ -21,8 +21,14 @@ type AddEditServerDialog struct {
 	Username   string
 	Password   string
 	LegacyAuth bool
-	OnSubmit   func()
-	OnCancel   func()
+	// Jellyfin only
+	ForceDirectStream bool
+	// 0 if disabled
+	NewMusicCheckMinutes int
+	OnSubmit             func()
+	OnCancel             func()
+	// called from the "Try Demo" button, shown if not cancelable
+	OnTryDemo func()
 
 	passField  *widget.Entry
 	submitBtn  *widget.Button
@@ -42,26 +48,45 @@ func NewAddEditServerDialog(title string, cancelable bool, prefillServer *backen
 		a.AltHost = prefillServer.AltHostname
 		a.Username = prefillServer.Username
 		a.LegacyAuth = prefillServer.LegacyAuth
+		a.ForceDirectStream = prefillServer.ForceDirectStream
+		a.NewMusicCheckMinutes = prefillServer.NewMusicCheckMinutes
 	}
 
 	titleLabel := widget.NewLabel(title)
 	titleLabel.TextStyle.Bold = true
 	legacyAuthCheck := widget.NewCheckWithData("Use legacy authentication", binding.BindBool(&a.LegacyAuth))
-	serverTypeChoice := widget.NewRadioGroup([]string{"Subsonic", "Jellyfin"}, func(s string) {
-		a.ServerType = backend.ServerType(s)
-		if s == string(backend.ServerTypeSubsonic) {
-			legacyAuthCheck.Show()
+	directStreamCheck := widget.NewCheckWithData("Always direct stream (no transcoding)", binding.BindBool(&a.ForceDirectStream))
+	serverTypes := []string{"Subsonic", "Jellyfin", string(backend.ServerTypeNetworkShare)}
+	if a.ServerType == backend.ServerTypeDemo {
+		serverTypes = append(serverTypes, string(backend.ServerTypeDemo))
+	}
+	hostField := widget.NewEntryWithData(binding.BindString(&a.Host))
+	setHostPlaceholder := func(t backend.ServerType) {
+		if t == backend.ServerTypeNetworkShare {
+			hostField.SetPlaceHolder("https://my-nas.local/webdav/music or /mnt/music")
 		} else {
-			legacyAuthCheck.Hide()
+			hostField.SetPlaceHolder("http://localhost:4533")
 		}
+	}
+	serverTypeChoice := widget.NewRadioGroup(serverTypes, func(s string) {
+		a.ServerType = backend.ServerType(s)
+		legacyAuthCheck.Hidden = s != string(backend.ServerTypeSubsonic)
+		directStreamCheck.Hidden = s != string(backend.ServerTypeJellyfin)
+		legacyAuthCheck.Refresh()
+		directStreamCheck.Refresh()
+		setHostPlaceholder(a.ServerType)
 	})
 	serverTypeChoice.Required = true
 	serverTypeChoice.Horizontal = true
 	selected := backend.ServerTypeSubsonic
-	if a.ServerType == backend.ServerTypeJellyfin {
-		selected = backend.ServerTypeJellyfin
+	if a.ServerType == backend.ServerTypeJellyfin || a.ServerType == backend.ServerTypeNetworkShare ||
+		a.ServerType == backend.ServerTypeDemo {
+		selected = a.ServerType
 	}
 	serverTypeChoice.Selected = string(selected)
+	legacyAuthCheck.Hidden = selected != backend.ServerTypeSubsonic
+	directStreamCheck.Hidden = selected != backend.ServerTypeJellyfin
+	setHostPlaceholder(selected)
 	a.passField = widget.NewPasswordEntry()
 	a.passField.OnSubmitted = func(_ string) { a.doSubmit() }
 	userField := widget.NewEntryWithData(binding.BindString(&a.Username))
@@ -69,12 +94,11 @@ func NewAddEditServerDialog(title string, cancelable bool, prefillServer *backen
 	altHostField := widget.NewEntryWithData(binding.BindString(&a.AltHost))
 	altHostField.SetPlaceHolder("(optional) https://my-external-domain.net/music")
 	altHostField.OnSubmitted = func(_ string) { focusHandler(userField) }
-	hostField := widget.NewEntryWithData(binding.BindString(&a.Host))
-	hostField.SetPlaceHolder("http://localhost:4533")
 	hostField.OnSubmitted = func(_ string) { focusHandler(altHostField) }
 	nickField := widget.NewEntryWithData(binding.BindString(&a.Nickname))
 	nickField.SetPlaceHolder("My Server")
 	nickField.OnSubmitted = func(_ string) { focusHandler(hostField) }
+	newMusicSelect := a.newMusicCheckSelect()
 	a.submitBtn = widget.NewButton("Enter", a.doSubmit)
 	a.submitBtn.Importance = widget.HighImportance
 	a.promptText = widget.NewRichTextWithText("")
@@ -91,6 +115,7 @@ func NewAddEditServerDialog(title string, cancelable bool, prefillServer *backen
 		bottomRow = container.NewHBox(
 			a.promptText,
 			layout.NewSpacer(),
+			widget.NewButton("Try Demo", a.onTryDemo),
 			a.submitBtn)
 	}
 
@@ -109,14 +134,44 @@ func NewAddEditServerDialog(title string, cancelable bool, prefillServer *backen
 			userField,
 			widget.NewLabel("Password"),
 			a.passField,
+			widget.NewLabel("New music alerts"),
+			newMusicSelect,
 		),
-		container.NewHBox(layout.NewSpacer(), legacyAuthCheck),
+		container.NewHBox(layout.NewSpacer(), legacyAuthCheck, directStreamCheck),
 		widget.NewSeparator(),
 		bottomRow,
 	)
 	return a
 }
 
+var newMusicCheckIntervals = []struct {
+	name    string
+	minutes int
+}{
+	{"Off", 0},
+	{"Every 15 minutes", 15},
+	{"Every hour", 60},
+	{"Every 6 hours", 360},
+	{"Daily", 1440},
+}
+
+func (a *AddEditServerDialog) newMusicCheckSelect() *widget.Select {
+	var opts []string
+	selected := 0
+	for i, interval := range newMusicCheckIntervals {
+		opts = append(opts, interval.name)
+		if interval.minutes == a.NewMusicCheckMinutes {
+			selected = i
+		}
+	}
+	sel := widget.NewSelect(opts, nil)
+	sel.SetSelectedIndex(selected)
+	sel.OnChanged = func(_ string) {
+		a.NewMusicCheckMinutes = newMusicCheckIntervals[sel.SelectedIndex()].minutes
+	}
+	return sel
+}
+
 func (a *AddEditServerDialog) SetInfoText(text string) {
 	a.doSetPromptText(text, theme.ColorNameForeground)
 }
@@ -148,6 +203,12 @@ func (a *AddEditServerDialog) onCancel() {
 	}
 }
 
+func (a *AddEditServerDialog) onTryDemo() {
+	if a.OnTryDemo != nil {
+		a.OnTryDemo()
+	}
+}
+
 func (a *AddEditServerDialog) doSetPromptText(text string, color fyne.ThemeColorName) {
 	ts := a.promptText.Segments[0].(*widget.TextSegment)
 	ts.Text = text
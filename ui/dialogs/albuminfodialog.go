@@ -23,18 +23,27 @@ type AlbumInfoDialog struct {
 	widget.BaseWidget
 
 	OnDismiss func()
+	OnEdit    func()
 
 	content fyne.CanvasObject
 }
 
-func NewAlbumInfoDialog(albumInfo *mediaprovider.AlbumInfo, albumName string, albumCover image.Image) *AlbumInfoDialog {
+func NewAlbumInfoDialog(albumInfo *mediaprovider.AlbumInfo, albumName string, albumCover image.Image, showEditButton bool) *AlbumInfoDialog {
 	a := &AlbumInfoDialog{}
 	a.ExtendBaseWidget(a)
 
+	editBtn := widget.NewButton("Edit...", func() {
+		if a.OnEdit != nil {
+			a.OnEdit()
+		}
+	})
+	editBtn.Hidden = !showEditButton
+
 	a.content = container.NewVBox(
 		a.buildMainContainer(albumInfo, albumName, albumCover),
 		widget.NewSeparator(),
 		container.NewHBox(
+			editBtn,
 			layout.NewSpacer(),
 			widget.NewButton("Close", func() {
 				if a.OnDismiss != nil {
@@ -66,6 +75,9 @@ func (a *AlbumInfoDialog) buildMainContainer(albumInfo *mediaprovider.AlbumInfo,
 	if albumInfo.Notes != "" {
 		infoContent = a.infoLabel(albumInfo.Notes)
 	}
+	sourceLabel := widget.NewLabel("Notes from " + strings.Join(albumInfo.Sources, " and "))
+	sourceLabel.Importance = widget.LowImportance
+	sourceLabel.Hidden = len(albumInfo.Sources) == 0
 
 	urlContainer := a.buildUrlContainer(albumInfo.LastFmUrl, albumInfo.MusicBrainzID)
 
@@ -75,6 +87,7 @@ func (a *AlbumInfoDialog) buildMainContainer(albumInfo *mediaprovider.AlbumInfo,
 			iconImage,
 			title,
 			infoContent,
+			sourceLabel,
 			urlContainer,
 		),
 	)
@@ -0,0 +1,80 @@
+package dialogs
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/deluan/sanitize"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
+	"github.com/dweymouth/supersonic/sharedutil"
+	"github.com/dweymouth/supersonic/ui/util"
+)
+
+// Editions is a dialog listing the editions in the library
+// of a given album, e.g. the original release and a remaster.
+type Editions struct {
+	SearchDialog *SearchDialog
+	mp           mediaprovider.MediaProvider
+	album        *mediaprovider.Album
+	allResults   []*mediaprovider.SearchResult
+}
+
+func NewEditionsDialog(mp mediaprovider.MediaProvider, im util.ImageFetcher, album *mediaprovider.Album) *Editions {
+	e := &Editions{mp: mp, album: album}
+	sd := NewSearchDialog(
+		im,
+		fmt.Sprintf("Editions of \"%s\"", album.Name),
+		"Close",
+		e.onSearched,
+	)
+	sd.PlaceholderText = "Filter editions"
+	e.SearchDialog = sd
+	return e
+}
+
+func (e *Editions) fetchEditions() {
+	editions, err := helpers.FindEditions(e.mp, e.album)
+	if err != nil {
+		log.Printf("error finding editions: %s", err.Error())
+	}
+	e.allResults = sharedutil.MapSlice(editions, func(al *mediaprovider.Album) *mediaprovider.SearchResult {
+		return &mediaprovider.SearchResult{
+			Name:       al.Name,
+			ID:         al.ID,
+			CoverID:    al.CoverArtID,
+			Type:       mediaprovider.ContentTypeAlbum,
+			ArtistName: editionDescription(al),
+		}
+	})
+}
+
+// editionDescription returns e.g. "2011 · Deluxe Edition · 18 tracks"
+func editionDescription(al *mediaprovider.Album) string {
+	var parts []string
+	if year := max(al.ReissueYear, al.Year); year > 0 {
+		parts = append(parts, strconv.Itoa(year))
+	}
+	if name := helpers.EditionName(al); name != "" {
+		parts = append(parts, name)
+	}
+	if al.TrackCount > 0 {
+		parts = append(parts, fmt.Sprintf("%d tracks", al.TrackCount))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func (e *Editions) onSearched(query string) []*mediaprovider.SearchResult {
+	if e.allResults == nil {
+		e.fetchEditions()
+	}
+	if query == "" {
+		return e.allResults
+	}
+	query = sanitize.Accents(strings.ToLower(query))
+	return sharedutil.FilterSlice(e.allResults, func(r *mediaprovider.SearchResult) bool {
+		return strings.Contains(sanitize.Accents(strings.ToLower(r.Name+" "+r.ArtistName)), query)
+	})
+}
@@ -0,0 +1,96 @@
+package dialogs
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/ui/widgets"
+)
+
+// EditMetadataDialog is a dialog to edit the title,
+// year and genres of a track or album on the server.
+type EditMetadataDialog struct {
+	widget.BaseWidget
+
+	OnCanceled func()
+	OnSubmit   func(mediaprovider.MetadataUpdate)
+
+	container *fyne.Container
+}
+
+func NewEditMetadataDialog(heading, title string, year int, genres []string) *EditMetadataDialog {
+	e := &EditMetadataDialog{}
+	e.ExtendBaseWidget(e)
+
+	titleEntry := widget.NewEntry()
+	titleEntry.SetText(title)
+	yearEntry := widgets.NewTextRestrictedEntry(func(text, selText string, r rune) bool {
+		return unicode.IsDigit(r) && len(text)-len(selText) < 4
+	})
+	yearEntry.SetMinCharWidth(4)
+	if year > 0 {
+		yearEntry.SetText(strconv.Itoa(year))
+	}
+	genresEntry := widget.NewEntry()
+	genresEntry.SetPlaceHolder("Comma-separated")
+	genresEntry.SetText(strings.Join(genres, ", "))
+
+	submitBtn := widget.NewButton("OK", func() {
+		if e.OnSubmit == nil {
+			return
+		}
+		update := mediaprovider.MetadataUpdate{Title: strings.TrimSpace(titleEntry.Text)}
+		update.Year, _ = strconv.Atoi(yearEntry.Text)
+		for _, g := range strings.Split(genresEntry.Text, ",") {
+			if g = strings.TrimSpace(g); g != "" {
+				update.Genres = append(update.Genres, g)
+			}
+		}
+		e.OnSubmit(update)
+	})
+	submitBtn.Importance = widget.HighImportance
+	titleEntry.OnChanged = func(s string) {
+		if strings.TrimSpace(s) == "" {
+			submitBtn.Disable()
+		} else {
+			submitBtn.Enable()
+		}
+	}
+	cancelBtn := widget.NewButton("Cancel", func() {
+		if e.OnCanceled != nil {
+			e.OnCanceled()
+		}
+	})
+
+	e.container = container.NewVBox(
+		container.NewHBox(layout.NewSpacer(), widget.NewLabel(heading), layout.NewSpacer()),
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel("Title"),
+			titleEntry,
+			widget.NewLabel("Year"),
+			container.NewHBox(yearEntry),
+			widget.NewLabel("Genres"),
+			genresEntry,
+		),
+		widget.NewSeparator(),
+		container.NewHBox(
+			layout.NewSpacer(),
+			cancelBtn, submitBtn),
+	)
+
+	return e
+}
+
+func (e *EditMetadataDialog) MinSize() fyne.Size {
+	return fyne.NewSize(350, e.BaseWidget.MinSize().Height)
+}
+
+func (e *EditMetadataDialog) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(e.container)
+}
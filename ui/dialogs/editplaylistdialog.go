@@ -15,31 +15,46 @@ type EditPlaylistDialog struct {
 	OnCanceled       func()
 	OnDeletePlaylist func()
 	OnUpdateMetadata func()
+	OnShare          func()
 
-	IsPublic    bool
-	Name        string
-	Description string
+	IsPublic        bool
+	IsCollaborative bool
+	Name            string
+	Description     string
 
 	container *fyne.Container
 }
 
-func NewEditPlaylistDialog(playlist *mediaprovider.Playlist, showPublicCheck bool) *EditPlaylistDialog {
+func NewEditPlaylistDialog(playlist *mediaprovider.Playlist, showPublicCheck, showCollaborativeCheck, showShareButton bool) *EditPlaylistDialog {
 	e := &EditPlaylistDialog{
-		IsPublic:    playlist.Public,
-		Name:        playlist.Name,
-		Description: playlist.Description,
+		IsPublic:        playlist.Public,
+		IsCollaborative: playlist.Collaborative,
+		Name:            playlist.Name,
+		Description:     playlist.Description,
 	}
 	e.ExtendBaseWidget(e)
 
 	isPublicCheck := widget.NewCheckWithData("Public", binding.BindBool(&e.IsPublic))
 	isPublicCheck.Hidden = !showPublicCheck
+	isCollaborativeCheck := widget.NewCheckWithData("Collaborative", binding.BindBool(&e.IsCollaborative))
+	isCollaborativeCheck.Hidden = !showCollaborativeCheck
 	nameEntry := widget.NewEntryWithData(binding.BindString(&e.Name))
 	descriptionEntry := widget.NewEntryWithData(binding.BindString(&e.Description))
+	// multi-line, to preserve the formatting of markdown descriptions
+	descriptionEntry.MultiLine = true
+	descriptionEntry.Wrapping = fyne.TextWrapWord
+	descriptionEntry.SetMinRowsVisible(3)
 	deleteBtn := widget.NewButton("Delete Playlist", func() {
 		if e.OnDeletePlaylist != nil {
 			e.OnDeletePlaylist()
 		}
 	})
+	shareBtn := widget.NewButton("Share...", func() {
+		if e.OnShare != nil {
+			e.OnShare()
+		}
+	})
+	shareBtn.Hidden = !showShareButton
 	submitBtn := widget.NewButton("OK", func() {
 		if e.OnUpdateMetadata != nil {
 			e.OnUpdateMetadata()
@@ -60,7 +75,7 @@ func NewEditPlaylistDialog(playlist *mediaprovider.Playlist, showPublicCheck boo
 			widget.NewLabel("Description"),
 			descriptionEntry,
 		),
-		container.NewHBox(isPublicCheck, layout.NewSpacer(), deleteBtn),
+		container.NewHBox(isPublicCheck, isCollaborativeCheck, layout.NewSpacer(), shareBtn, deleteBtn),
 		widget.NewSeparator(),
 		container.NewHBox(
 			layout.NewSpacer(),
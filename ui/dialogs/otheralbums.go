@@ -0,0 +1,64 @@
+package dialogs
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/deluan/sanitize"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
+	"github.com/dweymouth/supersonic/sharedutil"
+	"github.com/dweymouth/supersonic/ui/util"
+)
+
+// OtherAlbums is a dialog listing other albums in the library
+// which contain the same recording as a given track.
+type OtherAlbums struct {
+	SearchDialog *SearchDialog
+	mp           mediaprovider.MediaProvider
+	track        *mediaprovider.Track
+	allResults   []*mediaprovider.SearchResult
+}
+
+func NewOtherAlbumsDialog(mp mediaprovider.MediaProvider, im util.ImageFetcher, track *mediaprovider.Track) *OtherAlbums {
+	o := &OtherAlbums{mp: mp, track: track}
+	sd := NewSearchDialog(
+		im,
+		fmt.Sprintf("Other albums containing \"%s\"", track.Title),
+		"Close",
+		o.onSearched,
+	)
+	sd.PlaceholderText = "Filter albums"
+	o.SearchDialog = sd
+	return o
+}
+
+func (o *OtherAlbums) fetchOtherAlbums() {
+	albums, err := helpers.FindOtherReleases(o.mp, o.track)
+	if err != nil {
+		log.Printf("error finding other albums: %s", err.Error())
+	}
+	o.allResults = sharedutil.MapSlice(albums, func(al *mediaprovider.Album) *mediaprovider.SearchResult {
+		return &mediaprovider.SearchResult{
+			Name:       al.Name,
+			ID:         al.ID,
+			CoverID:    al.CoverArtID,
+			Type:       mediaprovider.ContentTypeAlbum,
+			ArtistName: strings.Join(al.ArtistNames, ", "),
+		}
+	})
+}
+
+func (o *OtherAlbums) onSearched(query string) []*mediaprovider.SearchResult {
+	if o.allResults == nil {
+		o.fetchOtherAlbums()
+	}
+	if query == "" {
+		return o.allResults
+	}
+	query = sanitize.Accents(strings.ToLower(query))
+	return sharedutil.FilterSlice(o.allResults, func(r *mediaprovider.SearchResult) bool {
+		return strings.Contains(sanitize.Accents(strings.ToLower(r.Name)), query)
+	})
+}
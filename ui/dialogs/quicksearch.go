@@ -4,25 +4,103 @@ import (
 	"log"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/dweymouth/supersonic/backend"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/mediaprovider/helpers"
 	"github.com/dweymouth/supersonic/ui/util"
 )
 
+const (
+	quickSearchMaxResults = 20
+	// max results when searching a single content type
+	quickSearchMaxResultsOfType = 50
+	// max suggestions from the search history
+	quickSearchMaxSuggestions = 5
+	// max recent queries in the history menu
+	quickSearchMaxRecentQueries = 10
+)
+
+// content types which the quick search can be restricted to
+var quickSearchTypes = []struct {
+	name        string
+	contentType mediaprovider.ContentType
+}{
+	{"Artists", mediaprovider.ContentTypeArtist},
+	{"Albums", mediaprovider.ContentTypeAlbum},
+	{"Tracks", mediaprovider.ContentTypeTrack},
+	{"Playlists", mediaprovider.ContentTypePlaylist},
+	{"Genres", mediaprovider.ContentTypeGenre},
+}
+
 type QuickSearch struct {
 	SearchDialog *SearchDialog
 	mp           mediaprovider.MediaProvider
+	history      *backend.SearchHistory
+	typeSelect   *widget.Select
 }
 
-func NewQuickSearch(mp mediaprovider.MediaProvider, im util.ImageFetcher) *QuickSearch {
-	q := &QuickSearch{mp: mp}
+func NewQuickSearch(mp mediaprovider.MediaProvider, history *backend.SearchHistory, im util.ImageFetcher) *QuickSearch {
+	q := &QuickSearch{mp: mp, history: history}
 	q.SearchDialog = NewSearchDialog(im, "Quick Search", "Close", q.onSearched)
+	q.SearchDialog.OnSuggest = q.onSuggest
+	opts := []string{"All"}
+	for _, t := range quickSearchTypes {
+		opts = append(opts, t.name)
+	}
+	q.typeSelect = widget.NewSelect(opts, nil)
+	q.typeSelect.SetSelectedIndex(0)
+	q.typeSelect.OnChanged = func(_ string) {
+		go q.SearchDialog.onSearched(q.SearchDialog.SearchQuery())
+	}
+	var recentBtn *widget.Button
+	recentBtn = widget.NewButtonWithIcon("", theme.HistoryIcon(), func() {
+		q.showRecentQueries(recentBtn)
+	})
+	q.SearchDialog.ActionItem = container.NewHBox(q.typeSelect, recentBtn)
 	return q
 }
 
+// showRecentQueries shows a menu of recent queries below the button, to re-run one.
+func (q *QuickSearch) showRecentQueries(btn *widget.Button) {
+	var items []*fyne.MenuItem
+	for _, query := range q.history.RecentQueries(quickSearchMaxRecentQueries) {
+		query := query
+		items = append(items, fyne.NewMenuItem(query, func() { q.SearchDialog.SetSearchQuery(query) }))
+	}
+	if len(items) == 0 {
+		items = append(items, &fyne.MenuItem{Label: "No recent searches", Disabled: true})
+	} else {
+		items = append(items, fyne.NewMenuItemSeparator(), fyne.NewMenuItem("Clear History", q.history.Clear))
+	}
+	canvas := fyne.CurrentApp().Driver().CanvasForObject(btn)
+	pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(btn).AddXY(0, btn.Size().Height)
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), canvas, pos)
+}
+
+func (q *QuickSearch) onSuggest(query string) []*mediaprovider.SearchResult {
+	if q.typeSelect.SelectedIndex() > 0 {
+		return nil // suggestions aren't restricted to a type
+	}
+	return q.history.Suggestions(query, quickSearchMaxSuggestions).Results
+}
+
+func (q *QuickSearch) search(query string) ([]*mediaprovider.SearchResult, error) {
+	idx := q.typeSelect.SelectedIndex()
+	if idx <= 0 {
+		return q.mp.SearchAll(query, quickSearchMaxResults)
+	}
+	t := quickSearchTypes[idx-1].contentType
+	opts := mediaprovider.SearchOptions{Limits: map[mediaprovider.ContentType]int{t: quickSearchMaxResultsOfType}}
+	return helpers.SearchWithOptions(q.mp, query, opts)
+}
+
 func (q *QuickSearch) onSearched(query string) []*mediaprovider.SearchResult {
 	var results []*mediaprovider.SearchResult
 	if query != "" {
-		if res, err := q.mp.SearchAll(query, 20); err != nil {
+		if res, err := q.search(query); err != nil {
 			log.Printf("Error searching: %s", err.Error())
 		} else {
 			results = res
@@ -36,7 +114,13 @@ func (q *QuickSearch) SetOnDismiss(onDismiss func()) {
 }
 
 func (q *QuickSearch) SetOnNavigateTo(onNavigateTo func(mediaprovider.ContentType, string)) {
-	q.SearchDialog.OnNavigateTo = onNavigateTo
+	q.SearchDialog.OnNavigateTo = func(contentType mediaprovider.ContentType, id string) {
+		if r := q.SearchDialog.result(contentType, id); r != nil {
+			q.history.RecordQuery(q.SearchDialog.SearchQuery())
+			q.history.RecordOpened(r)
+		}
+		onNavigateTo(contentType, id)
+	}
 }
 
 func (q *QuickSearch) MinSize() fyne.Size {
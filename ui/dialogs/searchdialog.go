@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"image"
 	"log"
+	"slices"
 	"sync"
 
 	"fyne.io/fyne/v2"
@@ -32,6 +33,9 @@ type SearchDialog struct {
 	OnDismiss    func()
 	OnNavigateTo func(mediaprovider.ContentType, string)
 	OnSearched   func(string) []*mediaprovider.SearchResult
+	// If set, returns suggestions from local data for the query, which are
+	// shown while OnSearched runs, and ahead of the results it returns.
+	OnSuggest func(string) []*mediaprovider.SearchResult
 
 	imgSource     util.ImageFetcher
 	resultsMutex  sync.RWMutex
@@ -159,14 +163,41 @@ func (sd *SearchDialog) setResults(results []*mediaprovider.SearchResult) {
 	sd.list.Select(0)
 }
 
+// SetSearchQuery sets the text of the search entry, which runs the search.
+func (sd *SearchDialog) SetSearchQuery(query string) {
+	sd.searchEntry.SetText(query)
+}
+
+// result returns the current search result with the given type and ID, if any.
+func (sd *SearchDialog) result(contentType mediaprovider.ContentType, id string) *mediaprovider.SearchResult {
+	sd.resultsMutex.RLock()
+	defer sd.resultsMutex.RUnlock()
+	for _, r := range sd.searchResults {
+		if r.Type == contentType && r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
 func (sd *SearchDialog) onSearched(query string) {
+	var suggested []*mediaprovider.SearchResult
+	if sd.OnSuggest != nil {
+		suggested = sd.OnSuggest(query)
+		sd.setResults(suggested)
+	}
 	sd.loadingDots.Start()
-	var results []*mediaprovider.SearchResult
+	results := suggested
 	res := sd.OnSearched(query)
-	if len(res) == 0 {
+	if len(res) == 0 && len(suggested) == 0 {
 		log.Println("No results matched the query.")
-	} else {
-		results = res
+	}
+	for _, r := range res {
+		if !slices.ContainsFunc(suggested, func(s *mediaprovider.SearchResult) bool {
+			return s.Type == r.Type && s.ID == r.ID
+		}) {
+			results = append(results, r)
+		}
 	}
 	sd.loadingDots.Stop()
 	sd.setResults(results)
@@ -48,7 +48,7 @@ func (sp *SelectPlaylist) fetchUserOwnedPlaylists() {
 		log.Printf("error getting playlists: %s", err.Error())
 	}
 	userPlaylists := sharedutil.FilterSlice(playlists, func(playlist *mediaprovider.Playlist) bool {
-		return playlist.Owner == sp.loggedInUser
+		return playlist.CanEdit(sp.loggedInUser)
 	})
 	sp.allPlaylistResuts = sharedutil.MapSlice(userPlaylists, sp.playlistToSearchResult)
 }
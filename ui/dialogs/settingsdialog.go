@@ -2,8 +2,11 @@ package dialogs
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"os"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -32,17 +35,25 @@ type SettingsDialog struct {
 	OnAudioExclusiveSettingChanged func()
 	OnAudioDeviceSettingChanged    func()
 	OnThemeSettingChanged          func()
+	OnPerfOverlaySettingChanged    func()
+	OnRequestLogSettingChanged     func()
+	OnContentFilterSettingChanged  func()
+	OnSnapcastSettingChanged       func()
+	OnAudioEventsSettingChanged    func()
+	OnDiscordRPCSettingChanged     func()
 	OnDismiss                      func()
-	OnEqualizerSettingsChanged     func()
 
 	config       *backend.Config
+	equalizer    *backend.EqualizerManager
 	audioDevices []mpv.AudioDevice
 	themeFiles   map[string]string // filename -> displayName
 	promptText   *widget.RichText
 
 	clientDecidesScrobble bool
+	streamServerConfig    *backend.ServerConfig
 
-	content fyne.CanvasObject
+	content          fyne.CanvasObject
+	refreshEqualizer func()
 }
 
 // TODO: having this depend on the mpv package for the AudioDevice type is kinda gross. Refactor.
@@ -50,15 +61,16 @@ func NewSettingsDialog(
 	config *backend.Config,
 	audioDeviceList []mpv.AudioDevice,
 	themeFileList map[string]string,
-	equalizerBands []string,
+	equalizer *backend.EqualizerManager,
 	clientDecidesScrobble bool,
 	isLocalPlayer bool,
 	isReplayGainPlayer bool,
 	isEqualizerPlayer bool,
 	canSavePlayQueue bool,
+	streamServerConfig *backend.ServerConfig, // nil if the server doesn't support stream options
 	window fyne.Window,
 ) *SettingsDialog {
-	s := &SettingsDialog{config: config, audioDevices: audioDeviceList, themeFiles: themeFileList, clientDecidesScrobble: clientDecidesScrobble}
+	s := &SettingsDialog{config: config, equalizer: equalizer, audioDevices: audioDeviceList, themeFiles: themeFileList, clientDecidesScrobble: clientDecidesScrobble, streamServerConfig: streamServerConfig}
 	s.ExtendBaseWidget(s)
 
 	// TODO: Once Fyne supports disableable sliders, it's probably a nicer UX
@@ -68,7 +80,7 @@ func NewSettingsDialog(
 		tabs = container.NewAppTabs(
 			s.createGeneralTab(canSavePlayQueue),
 			s.createPlaybackTab(isLocalPlayer, isReplayGainPlayer),
-			s.createEqualizerTab(equalizerBands),
+			s.createEqualizerTab(),
 			s.createExperimentalTab(window),
 		)
 	} else {
@@ -169,8 +181,15 @@ func (s *SettingsDialog) createGeneralTab(canSaveQueueToServer bool) *container.
 	if s.config.Application.SaveQueueToServer {
 		saveToServer.Selected = "To server"
 	}
-	saveQueue := widget.NewCheck("Save play queue on exit", func(save bool) {
+	resumePlayback := widget.NewCheckWithData("Resume playback on startup if it was playing",
+		binding.BindBool(&s.config.Application.ResumePlaybackOnStartup))
+	saveQueue := widget.NewCheck("Save play queue", func(save bool) {
 		s.config.Application.SavePlayQueue = save
+		if save {
+			resumePlayback.Enable()
+		} else {
+			resumePlayback.Disable()
+		}
 		if save && canSaveQueueToServer {
 			saveToServer.Enable()
 		} else if canSaveQueueToServer {
@@ -178,6 +197,9 @@ func (s *SettingsDialog) createGeneralTab(canSaveQueueToServer bool) *container.
 		}
 	})
 	saveQueue.Checked = s.config.Application.SavePlayQueue
+	if !s.config.Application.SavePlayQueue {
+		resumePlayback.Disable()
+	}
 	saveQueueHBox := container.NewHBox(saveQueue)
 	if canSaveQueueToServer {
 		saveQueueHBox.Add(saveToServer)
@@ -186,6 +208,33 @@ func (s *SettingsDialog) createGeneralTab(canSaveQueueToServer bool) *container.
 	trackNotif := widget.NewCheckWithData("Show notification on track change",
 		binding.BindBool(&s.config.Application.ShowTrackChangeNotification))
 
+	recordHistory := widget.NewCheckWithData("Record listening history on this computer",
+		binding.BindBool(&s.config.Application.RecordListeningHistory))
+
+	embedTags := widget.NewCheckWithData("Embed tags, lyrics and cover art in downloaded files",
+		binding.BindBool(&s.config.Application.EmbedTagsInDownloads))
+
+	maxDownloads := widget.NewSelect([]string{"1", "2", "3", "4", "5"}, func(str string) {
+		s.config.Downloads.MaxConcurrent, _ = strconv.Atoi(str)
+	})
+	maxDownloads.SetSelected(strconv.Itoa(s.config.Downloads.MaxConcurrent))
+	downloadLimit := widgets.NewTextRestrictedEntry(func(text, selText string, r rune) bool {
+		return unicode.IsDigit(r) && len(text)-len(selText) < 6
+	})
+	downloadLimit.SetMinCharWidth(5)
+	downloadLimit.SetPlaceHolder("none")
+	if kbps := s.config.Downloads.MaxKBPerSecond; kbps > 0 {
+		downloadLimit.Text = strconv.Itoa(kbps)
+	}
+	downloadLimit.OnChanged = func(str string) {
+		s.config.Downloads.MaxKBPerSecond, _ = strconv.Atoi(str)
+	}
+
+	collapseSingles := widget.NewCheckWithData("Collapse single-track albums into \"Singles\" on Albums page",
+		binding.BindBool(&s.config.AlbumsPage.CollapseSingles))
+	groupEditions := widget.NewCheckWithData("Group multiple editions of an album on Albums page",
+		binding.BindBool(&s.config.AlbumsPage.GroupEditions))
+
 	// Scrobble settings
 
 	twoDigitValidator := func(text, selText string, r rune) bool {
@@ -265,6 +314,86 @@ func (s *SettingsDialog) createGeneralTab(canSaveQueueToServer bool) *container.
 	})
 	scrobbleEnabled.Checked = s.config.Scrobbling.Enabled
 
+	threeDigitValidator := func(text, selText string, r rune) bool {
+		return unicode.IsDigit(r) && len(text)-len(selText) < 3
+	}
+	minListenEntry := widgets.NewTextRestrictedEntry(threeDigitValidator)
+	minListenEntry.SetMinCharWidth(3)
+	minListenEntry.OnChanged = func(str string) {
+		if i, err := strconv.Atoi(str); err == nil {
+			s.config.Scrobbling.MinListenSeconds = i
+		} else if str == "" {
+			s.config.Scrobbling.MinListenSeconds = 0
+		}
+	}
+	if secs := s.config.Scrobbling.MinListenSeconds; secs > 0 {
+		minListenEntry.Text = strconv.Itoa(secs)
+	}
+	skipWhenMuted := widget.NewCheckWithData("Don't count time played while muted",
+		binding.BindBool(&s.config.Scrobbling.SkipWhenMuted))
+	ignoredGenres := widget.NewEntry()
+	ignoredGenres.SetPlaceHolder("e.g. White Noise, Audiobook")
+	ignoredGenres.SetText(strings.Join(s.config.Scrobbling.IgnoredGenres, ", "))
+	ignoredGenres.OnChanged = func(text string) {
+		s.config.Scrobbling.IgnoredGenres = splitCommaList(text)
+	}
+
+	// Discord settings
+	discordChanged := func() {
+		if s.OnDiscordRPCSettingChanged != nil {
+			s.OnDiscordRPCSettingChanged()
+		}
+	}
+	discordAppID := widget.NewEntry()
+	discordAppID.SetPlaceHolder("Discord application ID (optional)")
+	discordAppID.SetText(s.config.DiscordRPC.ApplicationID)
+	discordAppID.OnChanged = func(text string) {
+		s.config.DiscordRPC.ApplicationID = strings.TrimSpace(text)
+	}
+	discordAppID.OnSubmitted = func(_ string) { discordChanged() }
+	discordArt := widget.NewCheck("Look up album art online", func(b bool) {
+		s.config.DiscordRPC.LookUpAlbumArt = b
+		discordChanged()
+	})
+	discordArt.Checked = s.config.DiscordRPC.LookUpAlbumArt
+	discordEnabled := widget.NewCheck("Show now playing in Discord", func(b bool) {
+		s.config.DiscordRPC.Enabled = b
+		discordChanged()
+	})
+	discordEnabled.Checked = s.config.DiscordRPC.Enabled
+
+	// Content filter settings
+	cf := &s.config.ContentFilter
+	contentFilterChanged := func() {
+		if s.OnContentFilterSettingChanged != nil {
+			s.OnContentFilterSettingChanged()
+		}
+	}
+	hiddenGenres := widget.NewEntry()
+	hiddenGenres.SetPlaceHolder("e.g. Horrorcore, Comedy")
+	hiddenGenres.SetText(strings.Join(cf.Genres, ", "))
+	hiddenGenres.OnChanged = func(text string) {
+		cf.Genres = splitCommaList(text)
+		contentFilterChanged()
+	}
+	hiddenKeywords := widget.NewEntry()
+	hiddenKeywords.SetPlaceHolder("words in titles or artist names")
+	hiddenKeywords.SetText(strings.Join(cf.Keywords, ", "))
+	hiddenKeywords.OnChanged = func(text string) {
+		cf.Keywords = splitCommaList(text)
+		contentFilterChanged()
+	}
+	hideExplicit := widget.NewCheck("Hide explicit content (if reported by the server)", func(b bool) {
+		cf.HideExplicit = b
+		contentFilterChanged()
+	})
+	hideExplicit.Checked = cf.HideExplicit
+	contentFilterEnabled := widget.NewCheck("Hide matching content from browsing, search and playback", func(b bool) {
+		cf.Enabled = b
+		contentFilterChanged()
+	})
+	contentFilterEnabled.Checked = cf.Enabled
+
 	return container.NewTabItem("General", container.NewVBox(
 		container.NewBorder(nil, nil, widget.NewLabel("Theme"), /*left*/
 			container.NewHBox(widget.NewLabel("Mode"), themeModeSelect, util.NewHSpace(5)), // right
@@ -275,7 +404,16 @@ func (s *SettingsDialog) createGeneralTab(canSaveQueueToServer bool) *container.
 		),
 		container.NewHBox(systemTrayEnable, closeToTray),
 		saveQueueHBox,
+		resumePlayback,
 		trackNotif,
+		recordHistory,
+		embedTags,
+		container.NewHBox(
+			widget.NewLabel("Simultaneous downloads"), maxDownloads,
+			widget.NewLabel("Speed limit"), downloadLimit, widget.NewLabel("KB/s"),
+		),
+		collapseSingles,
+		groupEditions,
 		s.newSectionSeparator(),
 
 		widget.NewRichText(&widget.TextSegment{Text: "Scrobbling", Style: util.BoldRichTextStyle}),
@@ -290,9 +428,41 @@ func (s *SettingsDialog) createGeneralTab(canSaveQueueToServer bool) *container.
 			durationEntry,
 			widget.NewLabel("minutes of track have been played"),
 		),
+		container.NewHBox(
+			widget.NewLabel("Never scrobble when less than"),
+			minListenEntry,
+			widget.NewLabel("seconds have been played"),
+		),
+		skipWhenMuted,
+		container.New(layout.NewFormLayout(), widget.NewLabel("Don't scrobble genres"), ignoredGenres),
+		s.newSectionSeparator(),
+
+		widget.NewRichText(&widget.TextSegment{Text: "Discord", Style: util.BoldRichTextStyle}),
+		container.NewHBox(discordEnabled, discordArt),
+		container.New(layout.NewFormLayout(), widget.NewLabel("Application ID"), discordAppID),
+		s.newSectionSeparator(),
+
+		widget.NewRichText(&widget.TextSegment{Text: "Content Filter", Style: util.BoldRichTextStyle}),
+		contentFilterEnabled,
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel("Hidden genres"), hiddenGenres,
+			widget.NewLabel("Hidden keywords"), hiddenKeywords,
+		),
+		hideExplicit,
 	))
 }
 
+// splitCommaList returns the trimmed, non-empty items of a comma-separated list.
+func splitCommaList(text string) []string {
+	var items []string
+	for _, item := range strings.Split(text, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func (s *SettingsDialog) createPlaybackTab(isLocalPlayer, isReplayGainPlayer bool) *container.TabItem {
 	disableTranscode := widget.NewCheckWithData("Disable server transcoding", binding.BindBool(&s.config.Transcoding.ForceRawFile))
 	deviceList := make([]string, len(s.audioDevices))
@@ -311,6 +481,10 @@ func (s *SettingsDialog) createPlaybackTab(isLocalPlayer, isReplayGainPlayer boo
 		if s.OnAudioDeviceSettingChanged != nil {
 			s.OnAudioDeviceSettingChanged()
 		}
+		// equalizer settings are saved per device
+		if s.refreshEqualizer != nil {
+			s.refreshEqualizer()
+		}
 	}
 
 	replayGainSelect := widget.NewSelect([]string{"None", "Album", "Track", "Auto"}, nil)
@@ -366,20 +540,170 @@ func (s *SettingsDialog) createPlaybackTab(isLocalPlayer, isReplayGainPlayer boo
 	})
 	preventClipping.Checked = s.config.ReplayGain.PreventClipping
 
+	loudnessFallback := widget.NewCheck("", func(checked bool) {
+		s.config.ReplayGain.LoudnessFallback = checked
+		s.onReplayGainSettingsChanged()
+	})
+	loudnessFallback.Checked = s.config.ReplayGain.LoudnessFallback
+
 	audioExclusive := widget.NewCheck("Audio exclusive mode", func(checked bool) {
 		s.config.LocalPlayback.AudioExclusive = checked
 		s.onAudioExclusiveSettingsChanged()
 	})
 	audioExclusive.Checked = s.config.LocalPlayback.AudioExclusive
 
+	fadeEnabled := widget.NewCheckWithData("", binding.BindBool(&s.config.TrackFade.Enabled))
+	fadeOnSkip := widget.NewCheckWithData("", binding.BindBool(&s.config.TrackFade.ApplyOnManualSkip))
+	fadeDuration := widgets.NewTextRestrictedEntry(func(curText, _ string, r rune) bool {
+		return unicode.IsDigit(r) && len(curText) < 2
+	})
+	fadeDuration.SetMinCharWidth(2)
+	fadeDuration.OnChanged = func(text string) {
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			s.config.TrackFade.DurationSeconds = f
+		}
+	}
+	fadeDuration.Text = strconv.Itoa(int(math.Round(s.config.TrackFade.DurationSeconds)))
+
+	snapcast := &s.config.Snapcast
+	snapcastChanged := func() {
+		if s.OnSnapcastSettingChanged != nil {
+			s.OnSnapcastSettingChanged()
+		}
+	}
+	snapcastPipe := widget.NewEntryWithData(binding.BindString(&snapcast.PipePath))
+	snapcastAddress := widget.NewEntryWithData(binding.BindString(&snapcast.TCPAddress))
+	snapcastAddress.SetPlaceHolder("host:port")
+	snapcastMode := widget.NewRadioGroup([]string{backend.SnapcastModePipe, backend.SnapcastModeTCP}, nil)
+	snapcastMode.Horizontal = true
+	snapcastMode.Selected = snapcast.Mode
+	snapcastMode.Required = true
+	snapcastMode.OnChanged = func(choice string) {
+		snapcast.Mode = choice
+		snapcastChanged()
+	}
+	snapcastEnabled := widget.NewCheck("", func(b bool) {
+		snapcast.Enabled = b
+		snapcastChanged()
+	})
+	snapcastEnabled.Checked = snapcast.Enabled
+	snapcastApply := widget.NewButton("Apply", snapcastChanged)
+	snapcastHint := widget.NewLabel("Plays through the Snapcast server instead of the audio device. " +
+		"To show track info and control playback from Snapcast clients, " +
+		"set the stream's controlscript to supersonic with controlscriptparams=-snapcast-control.")
+	snapcastHint.Wrapping = fyne.TextWrapWord
+	snapcastHint.Importance = widget.LowImportance
+
+	audioEvents := &s.config.AudioEvents
+	audioEventsChanged := func() {
+		if s.OnAudioEventsSettingChanged != nil {
+			s.OnAudioEventsSettingChanged()
+		}
+	}
+	callActionOptions := []string{"Do nothing", "Lower volume", "Pause"}
+	callActions := []string{backend.AudioEventActionNone, backend.AudioEventActionDuck, backend.AudioEventActionPause}
+	callAction := widget.NewSelect(callActionOptions, nil)
+	callAction.SetSelectedIndex(max(0, slices.Index(callActions, audioEvents.CallAction)))
+	callAction.OnChanged = func(_ string) {
+		audioEvents.CallAction = callActions[callAction.SelectedIndex()]
+		audioEventsChanged()
+	}
+	resumeAfterCall := widget.NewCheckWithData("", binding.BindBool(&audioEvents.ResumeAfterCall))
+	duckNotifications := widget.NewCheck("", func(b bool) {
+		audioEvents.DuckForNotifications = b
+		audioEventsChanged()
+	})
+	duckNotifications.Checked = audioEvents.DuckForNotifications
+	duckVolume := widgets.NewTextRestrictedEntry(func(curText, selText string, r rune) bool {
+		return unicode.IsDigit(r) && len(curText)-len(selText) < 3
+	})
+	duckVolume.SetMinCharWidth(3)
+	duckVolume.OnChanged = func(text string) {
+		if i, err := strconv.Atoi(text); err == nil {
+			audioEvents.DuckVolumePercent = min(i, 100)
+			audioEventsChanged()
+		}
+	}
+	duckVolume.Text = strconv.Itoa(audioEvents.DuckVolumePercent)
+	pauseOnOutputChange := widget.NewCheck("", func(b bool) {
+		audioEvents.PauseOnOutputChange = b
+		audioEventsChanged()
+	})
+	pauseOnOutputChange.Checked = audioEvents.PauseOnOutputChange
+	if runtime.GOOS != "linux" {
+		// calls and notifications are detected through PulseAudio stream roles
+		callAction.Disable()
+		resumeAfterCall.Disable()
+		duckNotifications.Disable()
+		duckVolume.Disable()
+	}
+
+	radioMemoryOptions := []string{"Off", "Last 5 mixes", "Last 20 mixes", "Last 50 mixes"}
+	radioMemoryCounts := []int{0, 5, 20, 50}
+	radioMemory := widget.NewSelect(radioMemoryOptions, nil)
+	for i, n := range radioMemoryCounts {
+		if s.config.Radio.RecentMixesToRemember >= n {
+			radioMemory.SetSelectedIndex(i)
+		}
+	}
+	radioMemory.OnChanged = func(choice string) {
+		s.config.Radio.RecentMixesToRemember = radioMemoryCounts[slices.Index(radioMemoryOptions, choice)]
+	}
+
+	precacheOptions := []string{"Off", "Next track", "Next 3 tracks", "Next 5 tracks"}
+	precacheCounts := []int{0, 1, 3, 5}
+	precache := widget.NewSelect(precacheOptions, nil)
+	for i, n := range precacheCounts {
+		if s.config.LocalPlayback.PrecacheTracks >= n {
+			precache.SetSelectedIndex(i)
+		}
+	}
+	precache.OnChanged = func(choice string) {
+		s.config.LocalPlayback.PrecacheTracks = precacheCounts[slices.Index(precacheOptions, choice)]
+	}
+	trackCacheSize := widgets.NewTextRestrictedEntry(func(curText, selText string, r rune) bool {
+		return unicode.IsDigit(r) && len(curText)-len(selText) < 5
+	})
+	trackCacheSize.SetMinCharWidth(4)
+	trackCacheSize.OnChanged = func(text string) {
+		if i, err := strconv.Atoi(text); err == nil {
+			s.config.LocalPlayback.MaxTrackCacheSizeMB = i
+		}
+	}
+	trackCacheSize.Text = strconv.Itoa(s.config.LocalPlayback.MaxTrackCacheSizeMB)
+
+	var streamFormat, streamBitRate, lowBandwidthFormat, lowBandwidthBitRate *widget.Select
+	if conf := s.streamServerConfig; conf != nil {
+		streamFormat = newStreamFormatSelect(&conf.StreamFormat)
+		streamBitRate = newStreamBitRateSelect(&conf.StreamMaxBitRate, "Server default")
+		lowBandwidthFormat = newStreamFormatSelect(&conf.LowBandwidthStreamFormat)
+		lowBandwidthBitRate = newStreamBitRateSelect(&conf.LowBandwidthStreamMaxBitRate, "Server default")
+	} else {
+		streamFormat, lowBandwidthFormat = newStreamFormatSelect(nil), newStreamFormatSelect(nil)
+		streamBitRate, lowBandwidthBitRate = newStreamBitRateSelect(nil, "Server default"), newStreamBitRateSelect(nil, "Server default")
+	}
+	lowBandwidthMode := widget.NewCheckWithData("", binding.BindBool(&s.config.Transcoding.LowBandwidthMode))
+	adaptiveBitRate := widget.NewCheckWithData("", binding.BindBool(&s.config.Transcoding.AdaptiveBitRate))
+	adaptiveMin := newStreamBitRateSelect(&s.config.Transcoding.AdaptiveMinBitRate, "No limit")
+	adaptiveMax := newStreamBitRateSelect(&s.config.Transcoding.AdaptiveMaxBitRate, "No limit")
+
 	if !isLocalPlayer {
 		deviceSelect.Disable()
 		audioExclusive.Disable()
+		precache.Disable()
+		snapcastEnabled.Disable()
+		snapcastApply.Disable()
+	}
+	if runtime.GOOS == "windows" {
+		snapcastEnabled.Disable()
+		snapcastApply.Disable()
+		snapcastHint.SetText("Snapcast output is not supported on Windows.")
 	}
 	if !isReplayGainPlayer {
 		replayGainSelect.Disable()
 		preventClipping.Disable()
 		preampGain.Disable()
+		loudnessFallback.Disable()
 	}
 
 	return container.NewTabItem("Playback", container.NewVBox(
@@ -388,43 +712,180 @@ func (s *SettingsDialog) createPlaybackTab(isLocalPlayer, isReplayGainPlayer boo
 			container.New(layout.NewFormLayout(),
 				widget.NewLabel("Audio device"), container.NewBorder(nil, nil, nil, util.NewHSpace(70), deviceSelect),
 				layout.NewSpacer(), audioExclusive,
+				widget.NewLabel("Pre-download"), container.NewGridWithColumns(2, precache),
+				widget.NewLabel("Max cache size"), container.NewHBox(trackCacheSize, widget.NewLabel("MB")),
 			)),
 		s.newSectionSeparator(),
 
+		widget.NewRichText(&widget.TextSegment{Text: "Streaming", Style: util.BoldRichTextStyle}),
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel("Format"), container.NewGridWithColumns(2, streamFormat),
+			widget.NewLabel("Max bit rate"), container.NewGridWithColumns(2, streamBitRate),
+			widget.NewLabel("Low bandwidth mode"), lowBandwidthMode,
+			widget.NewLabel("Low bandwidth format"), container.NewGridWithColumns(2, lowBandwidthFormat),
+			widget.NewLabel("Low bandwidth max bit rate"), container.NewGridWithColumns(2, lowBandwidthBitRate),
+			widget.NewLabel("Adapt bit rate to network"), adaptiveBitRate,
+			widget.NewLabel("Adaptive bit rate range"), container.NewGridWithColumns(2, adaptiveMin, adaptiveMax),
+		),
+		s.newSectionSeparator(),
+
 		widget.NewRichText(&widget.TextSegment{Text: "ReplayGain", Style: util.BoldRichTextStyle}),
 		container.New(layout.NewFormLayout(),
 			widget.NewLabel("ReplayGain mode"), container.NewGridWithColumns(2, replayGainSelect),
 			widget.NewLabel("ReplayGain preamp"), container.NewHBox(preampGain, widget.NewLabel("dB")),
 			widget.NewLabel("Prevent clipping"), preventClipping,
+			widget.NewLabel("Normalize untagged tracks"), loudnessFallback,
+		),
+		s.newSectionSeparator(),
+
+		widget.NewRichText(&widget.TextSegment{Text: "Fade Between Tracks", Style: util.BoldRichTextStyle}),
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel("Fade out and in"), fadeEnabled,
+			widget.NewLabel("Fade duration"), container.NewHBox(fadeDuration, widget.NewLabel("seconds")),
+			widget.NewLabel("Fade on manual skip"), fadeOnSkip,
+		),
+		s.newSectionSeparator(),
+
+		widget.NewRichText(&widget.TextSegment{Text: "Snapcast", Style: util.BoldRichTextStyle}),
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel("Stream to Snapcast"), container.NewHBox(snapcastEnabled, snapcastApply),
+			widget.NewLabel("Source type"), snapcastMode,
+			widget.NewLabel("Pipe path"), snapcastPipe,
+			widget.NewLabel("TCP source address"), snapcastAddress,
+		),
+		snapcastHint,
+		s.newSectionSeparator(),
+
+		widget.NewRichText(&widget.TextSegment{Text: "System Audio Events", Style: util.BoldRichTextStyle}),
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel("During calls"), container.NewGridWithColumns(2, callAction),
+			widget.NewLabel("Resume after call"), resumeAfterCall,
+			widget.NewLabel("Lower volume for notifications"), duckNotifications,
+			widget.NewLabel("Lowered volume"), container.NewHBox(duckVolume, widget.NewLabel("%")),
+			widget.NewLabel("Pause when headphones are unplugged"), pauseOnOutputChange,
+		),
+		s.newSectionSeparator(),
+
+		widget.NewRichText(&widget.TextSegment{Text: "Radio", Style: util.BoldRichTextStyle}),
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel("Avoid tracks from"), container.NewGridWithColumns(2, radioMemory),
 		),
 	))
 }
 
-func (s *SettingsDialog) createEqualizerTab(eqBands []string) *container.TabItem {
-	enabled := widget.NewCheck("Enabled", func(b bool) {
-		s.config.LocalPlayback.EqualizerEnabled = b
-		if s.OnEqualizerSettingsChanged != nil {
-			s.OnEqualizerSettingsChanged()
+var (
+	streamFormatOptions = []string{"Server default", "mp3", "opus", "aac", "ogg"}
+	streamBitRates      = []int{0, 320, 256, 192, 128, 96, 64}
+)
+
+// newStreamFormatSelect returns a select bound to the transcoding format,
+// or a disabled one if format is nil.
+func newStreamFormatSelect(format *string) *widget.Select {
+	sel := widget.NewSelect(streamFormatOptions, nil)
+	sel.SetSelectedIndex(0)
+	if format == nil {
+		sel.Disable()
+		return sel
+	}
+	if idx := slices.Index(streamFormatOptions, *format); idx > 0 {
+		sel.SetSelectedIndex(idx)
+	}
+	sel.OnChanged = func(_ string) {
+		*format = ""
+		if idx := sel.SelectedIndex(); idx > 0 {
+			*format = streamFormatOptions[idx]
 		}
+	}
+	return sel
+}
+
+// newStreamBitRateSelect returns a select bound to the bit rate in kbps,
+// or a disabled one if bitRate is nil. unsetLabel is the option for 0.
+func newStreamBitRateSelect(bitRate *int, unsetLabel string) *widget.Select {
+	options := []string{unsetLabel}
+	for _, r := range streamBitRates[1:] {
+		options = append(options, fmt.Sprintf("%d kbps", r))
+	}
+	sel := widget.NewSelect(options, nil)
+	sel.SetSelectedIndex(0)
+	if bitRate == nil {
+		sel.Disable()
+		return sel
+	}
+	if idx := slices.Index(streamBitRates, *bitRate); idx > 0 {
+		sel.SetSelectedIndex(idx)
+	}
+	sel.OnChanged = func(_ string) {
+		*bitRate = streamBitRates[sel.SelectedIndex()]
+	}
+	return sel
+}
+
+func (s *SettingsDialog) createEqualizerTab() *container.TabItem {
+	enabled := widget.NewCheck("Enabled", func(b bool) {
+		s.equalizer.SetEnabled(b)
 	})
-	enabled.Checked = s.config.LocalPlayback.EqualizerEnabled
-	geq := NewGraphicEqualizer(s.config.LocalPlayback.EqualizerPreamp,
-		eqBands,
-		s.config.LocalPlayback.GraphicEqualizerBands)
+	enabled.Checked = s.equalizer.Enabled()
+
+	typeNames := []string{"10-band", "15-band"}
+	typeSelect := widget.NewSelect(typeNames, nil)
+	presetSelect := widget.NewSelect(s.equalizer.PresetNames(), nil)
+	presetSelect.PlaceHolder = "Custom"
+	geqContainer := container.NewStack()
+
 	debouncer := util.NewDebouncer(350*time.Millisecond, func() {
-		if s.OnEqualizerSettingsChanged != nil {
-			s.OnEqualizerSettingsChanged()
-		}
+		s.equalizer.Apply()
 	})
-	geq.OnChanged = func(b int, g float64) {
-		s.config.LocalPlayback.GraphicEqualizerBands[b] = g
-		debouncer()
+	// whether the selects are being updated programmatically
+	var updating bool
+	rebuild := func() {
+		updating = true
+		defer func() { updating = false }()
+		enabled.SetChecked(s.equalizer.Enabled())
+		typeSelect.SetSelectedIndex(slices.Index(backend.SupportedEqualizerTypes, s.equalizer.Type()))
+		if p := s.equalizer.CurrentPreset(); p != "" {
+			presetSelect.SetSelected(p)
+		} else {
+			presetSelect.ClearSelected()
+		}
+		geq := NewGraphicEqualizer(s.equalizer.Preamp(),
+			s.equalizer.BandFrequencies(),
+			s.equalizer.BandGains())
+		geq.OnChanged = func(b int, g float64) {
+			s.equalizer.SetBandGain(b, g)
+			presetSelect.ClearSelected()
+			debouncer()
+		}
+		geq.OnPreampChanged = func(g float64) {
+			s.equalizer.SetPreamp(g)
+			presetSelect.ClearSelected()
+			debouncer()
+		}
+		geqContainer.Objects = []fyne.CanvasObject{geq}
+		geqContainer.Refresh()
+	}
+	s.refreshEqualizer = rebuild
+
+	typeSelect.OnChanged = func(_ string) {
+		if updating {
+			return
+		}
+		s.equalizer.SetType(backend.SupportedEqualizerTypes[typeSelect.SelectedIndex()])
+		rebuild()
 	}
-	geq.OnPreampChanged = func(g float64) {
-		s.config.LocalPlayback.EqualizerPreamp = g
-		debouncer()
+	presetSelect.OnChanged = func(name string) {
+		if updating || name == "" {
+			return
+		}
+		s.equalizer.ApplyPreset(name)
+		rebuild()
 	}
-	cont := container.NewBorder(enabled, nil, nil, nil, geq)
+	rebuild()
+
+	top := container.NewHBox(enabled, layout.NewSpacer(),
+		widget.NewLabel("Preset"), presetSelect,
+		widget.NewLabel("Bands"), typeSelect)
+	cont := container.NewBorder(top, nil, nil, nil, geqContainer)
 	return container.NewTabItem("Equalizer", cont)
 }
 
@@ -473,9 +934,83 @@ func (s *SettingsDialog) createExperimentalTab(window fyne.Window) *container.Ta
 	} else {
 		uiScaleRadio.Selected = "Normal"
 	}
+	perfOverlay := widget.NewCheck("Show performance overlay", func(b bool) {
+		s.config.Application.ShowPerformanceOverlay = b
+		if s.OnPerfOverlaySettingChanged != nil {
+			s.OnPerfOverlaySettingChanged()
+		}
+	})
+	perfOverlay.Checked = s.config.Application.ShowPerformanceOverlay
+	requestLog := widget.NewCheck("Record API requests for debugging", func(b bool) {
+		s.config.Application.EnableRequestLog = b
+		if s.OnRequestLogSettingChanged != nil {
+			s.OnRequestLogSettingChanged()
+		}
+	})
+	requestLog.Checked = s.config.Application.EnableRequestLog
+	largeLibrary := widget.NewCheck("Large library mode (100k+ tracks)", func(b bool) {
+		s.config.Application.LargeLibraryMode = b
+		s.setRestartRequired()
+	})
+	largeLibrary.Checked = s.config.Application.LargeLibraryMode
+
+	rc := &s.config.RemoteControl
+	remoteToken := widget.NewEntry()
+	remoteToken.SetText(rc.CurrentToken())
+	remoteToken.Disable()
+	copyToken := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		window.Clipboard().SetContent(rc.CurrentToken())
+	})
+	remotePort := widget.NewEntry()
+	remotePort.SetText(strconv.Itoa(rc.Port))
+	remotePort.Validator = func(text string) error {
+		if p, err := strconv.Atoi(text); err != nil || p < 1 || p > 65535 {
+			return errors.New("invalid port")
+		}
+		return nil
+	}
+	remotePort.OnChanged = func(text string) {
+		if remotePort.Validate() == nil {
+			rc.Port, _ = strconv.Atoi(text)
+			s.setRestartRequired()
+		}
+	}
+	remoteAllInterfaces := widget.NewCheck("Allow connections from other devices", func(b bool) {
+		rc.ListenOnAllInterfaces = b
+		s.setRestartRequired()
+	})
+	remoteAllInterfaces.Checked = rc.ListenOnAllInterfaces
+	remoteEnabled := widget.NewCheck("Enable remote control API", func(b bool) {
+		rc.Enabled = b
+		if b {
+			remoteToken.SetText(rc.EnsureToken())
+		}
+		s.setRestartRequired()
+	})
+	remoteEnabled.Checked = rc.Enabled
+	serverSessionControl := widget.NewCheck("Allow control from other Jellyfin apps", func(b bool) {
+		rc.AllowServerSessionControl = b
+		s.setRestartRequired()
+	})
+	serverSessionControl.Checked = rc.AllowServerSessionControl
+
 	return container.NewTabItem("Experimental", container.NewVBox(
 		warningLabel,
 		s.newSectionSeparator(),
+		widget.NewRichText(&widget.TextSegment{Text: "Performance", Style: util.BoldRichTextStyle}),
+		largeLibrary,
+		perfOverlay,
+		requestLog,
+		s.newSectionSeparator(),
+		widget.NewRichText(&widget.TextSegment{Text: "Remote Control", Style: util.BoldRichTextStyle}),
+		remoteEnabled,
+		remoteAllInterfaces,
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel("Port"), remotePort,
+			widget.NewLabel("Token"), container.NewBorder(nil, nil, nil, copyToken, remoteToken),
+		),
+		serverSessionControl,
+		s.newSectionSeparator(),
 		widget.NewRichText(&widget.TextSegment{Text: "UI Scaling", Style: util.BoldRichTextStyle}),
 		uiScaleRadio,
 		s.newSectionSeparator(),
@@ -0,0 +1,95 @@
+package dialogs
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+)
+
+// SharePlaylistDialog lets the user choose which server users
+// a playlist is shared with, and whether they can edit it.
+type SharePlaylistDialog struct {
+	widget.BaseWidget
+
+	OnCanceled func()
+	OnSubmit   func(shares []mediaprovider.PlaylistShare)
+
+	container *fyne.Container
+}
+
+func NewSharePlaylistDialog(playlistName string, users []*mediaprovider.ServerUser, shares []mediaprovider.PlaylistShare) *SharePlaylistDialog {
+	s := &SharePlaylistDialog{}
+	s.ExtendBaseWidget(s)
+
+	existing := make(map[string]mediaprovider.PlaylistShare, len(shares))
+	for _, sh := range shares {
+		existing[sh.UserID] = sh
+	}
+
+	userChecks := make([]*widget.Check, len(users))
+	editChecks := make([]*widget.Check, len(users))
+	rows := container.New(layout.NewGridLayout(2))
+	for i, u := range users {
+		editCheck := widget.NewCheck("Can edit", nil)
+		userCheck := widget.NewCheck(u.Name, func(shared bool) {
+			if shared {
+				editCheck.Enable()
+			} else {
+				editCheck.SetChecked(false)
+				editCheck.Disable()
+			}
+		})
+		sh, ok := existing[u.ID]
+		userCheck.SetChecked(ok)
+		editCheck.SetChecked(sh.CanEdit)
+		if !ok {
+			editCheck.Disable()
+		}
+		userChecks[i], editChecks[i] = userCheck, editCheck
+		rows.Add(userCheck)
+		rows.Add(editCheck)
+	}
+	var content fyne.CanvasObject = container.NewVScroll(rows)
+	if len(users) == 0 {
+		content = widget.NewLabel("There are no other users on this server.")
+	}
+
+	submitBtn := widget.NewButton("OK", func() {
+		if s.OnSubmit == nil {
+			return
+		}
+		var newShares []mediaprovider.PlaylistShare
+		for i, u := range users {
+			if userChecks[i].Checked {
+				newShares = append(newShares, mediaprovider.PlaylistShare{UserID: u.ID, CanEdit: editChecks[i].Checked})
+			}
+		}
+		s.OnSubmit(newShares)
+	})
+	submitBtn.Importance = widget.HighImportance
+	cancelBtn := widget.NewButton("Cancel", func() {
+		if s.OnCanceled != nil {
+			s.OnCanceled()
+		}
+	})
+
+	title := widget.NewLabel("Share " + playlistName)
+	title.Truncation = fyne.TextTruncateEllipsis
+	s.container = container.NewBorder(
+		container.NewHBox(layout.NewSpacer(), title, layout.NewSpacer()),
+		container.NewVBox(widget.NewSeparator(), container.NewHBox(layout.NewSpacer(), cancelBtn, submitBtn)),
+		nil, nil,
+		content,
+	)
+	return s
+}
+
+func (s *SharePlaylistDialog) MinSize() fyne.Size {
+	return fyne.NewSize(350, 300)
+}
+
+func (s *SharePlaylistDialog) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(s.container)
+}
@@ -0,0 +1,200 @@
+package dialogs
+
+import (
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/dweymouth/supersonic/backend"
+	"github.com/dweymouth/supersonic/sharedutil"
+)
+
+// SmartPlaylistsDialog lists the user's smart playlists
+// with actions to play, edit, delete, or save them to the server.
+type SmartPlaylistsDialog struct {
+	widget.BaseWidget
+
+	OnDismiss      func()
+	OnNew          func()
+	OnPlay         func(*backend.SmartPlaylist)
+	OnEdit         func(*backend.SmartPlaylist)
+	OnDelete       func(*backend.SmartPlaylist)
+	OnSaveToServer func(*backend.SmartPlaylist)
+
+	container *fyne.Container
+}
+
+func NewSmartPlaylistsDialog(playlists []*backend.SmartPlaylist) *SmartPlaylistsDialog {
+	s := &SmartPlaylistsDialog{}
+	s.ExtendBaseWidget(s)
+
+	rows := container.NewVBox()
+	for _, sp := range playlists {
+		sp := sp
+		name := widget.NewLabel(sp.Name)
+		name.Truncation = fyne.TextTruncateEllipsis
+		if sp.MaterializedPlaylistID != "" {
+			name.SetText(sp.Name + " (on server)")
+		}
+		playBtn := widget.NewButtonWithIcon("", theme.MediaPlayIcon(), func() { s.callback(s.OnPlay, sp) })
+		saveBtn := widget.NewButtonWithIcon("", theme.UploadIcon(), func() { s.callback(s.OnSaveToServer, sp) })
+		editBtn := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), func() { s.callback(s.OnEdit, sp) })
+		deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() { s.callback(s.OnDelete, sp) })
+		rows.Add(container.NewBorder(nil, nil, nil,
+			container.NewHBox(playBtn, saveBtn, editBtn, deleteBtn), name))
+	}
+	var content fyne.CanvasObject = container.NewVScroll(rows)
+	if len(playlists) == 0 {
+		content = widget.NewLabel("No smart playlists yet.")
+	}
+
+	newBtn := widget.NewButtonWithIcon("New", theme.ContentAddIcon(), func() {
+		if s.OnNew != nil {
+			s.OnNew()
+		}
+	})
+	closeBtn := widget.NewButton("Close", func() {
+		if s.OnDismiss != nil {
+			s.OnDismiss()
+		}
+	})
+
+	s.container = container.NewBorder(
+		container.NewHBox(layout.NewSpacer(), widget.NewLabel("Smart Playlists"), layout.NewSpacer()),
+		container.NewVBox(widget.NewSeparator(), container.NewHBox(newBtn, layout.NewSpacer(), closeBtn)),
+		nil, nil,
+		content,
+	)
+	return s
+}
+
+func (s *SmartPlaylistsDialog) callback(cb func(*backend.SmartPlaylist), sp *backend.SmartPlaylist) {
+	if cb != nil {
+		cb(sp)
+	}
+}
+
+func (s *SmartPlaylistsDialog) MinSize() fyne.Size {
+	return fyne.NewSize(400, 300)
+}
+
+func (s *SmartPlaylistsDialog) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(s.container)
+}
+
+// EditSmartPlaylistDialog edits the rules of a smart playlist.
+type EditSmartPlaylistDialog struct {
+	widget.BaseWidget
+
+	OnCanceled func()
+	OnSubmit   func(*backend.SmartPlaylist)
+
+	container *fyne.Container
+}
+
+// NewEditSmartPlaylistDialog creates the dialog for editing sp,
+// which is not modified until the dialog is submitted.
+func NewEditSmartPlaylistDialog(sp *backend.SmartPlaylist, genres []string) *EditSmartPlaylistDialog {
+	e := &EditSmartPlaylistDialog{}
+	e.ExtendBaseWidget(e)
+
+	intStr := func(i int) string {
+		if i == 0 {
+			return ""
+		}
+		return strconv.Itoa(i)
+	}
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(sp.Name)
+	genreEntry := widget.NewSelectEntry(genres)
+	genreEntry.SetText(strings.Join(sp.Genres, ", "))
+	genreEntry.SetPlaceHolder("Any (comma-separated)")
+	minYearEntry := widget.NewEntry()
+	minYearEntry.SetText(intStr(sp.MinYear))
+	maxYearEntry := widget.NewEntry()
+	maxYearEntry.SetText(intStr(sp.MaxYear))
+	ratingSelect := widget.NewSelect([]string{"Any", "1", "2", "3", "4", "5"}, nil)
+	ratingSelect.SetSelectedIndex(sp.MinRating)
+	notPlayedEntry := widget.NewEntry()
+	notPlayedEntry.SetText(intStr(sp.NotPlayedInDays))
+	notPlayedEntry.SetPlaceHolder("days")
+	addedEntry := widget.NewEntry()
+	addedEntry.SetText(intStr(sp.AddedInDays))
+	addedEntry.SetPlaceHolder("days")
+	limitEntry := widget.NewEntry()
+	limitEntry.SetText(intStr(sp.Limit))
+	limitEntry.SetPlaceHolder("Default")
+	favoritesCheck := widget.NewCheck("Favorites only", nil)
+	favoritesCheck.SetChecked(sp.FavoritesOnly)
+	shuffleCheck := widget.NewCheck("Random selection", nil)
+	shuffleCheck.SetChecked(sp.Shuffle)
+
+	submitBtn := widget.NewButton("OK", func() {
+		if e.OnSubmit == nil {
+			return
+		}
+		atoi := func(s string) int {
+			i, _ := strconv.Atoi(strings.TrimSpace(s))
+			return max(i, 0)
+		}
+		edited := *sp
+		edited.Name = strings.TrimSpace(nameEntry.Text)
+		edited.Genres = sharedutil.FilterMapSlice(strings.Split(genreEntry.Text, ","), func(g string) (string, bool) {
+			g = strings.TrimSpace(g)
+			return g, g != ""
+		})
+		edited.MinYear = atoi(minYearEntry.Text)
+		edited.MaxYear = atoi(maxYearEntry.Text)
+		edited.MinRating = max(ratingSelect.SelectedIndex(), 0)
+		edited.NotPlayedInDays = atoi(notPlayedEntry.Text)
+		edited.AddedInDays = atoi(addedEntry.Text)
+		edited.Limit = atoi(limitEntry.Text)
+		edited.FavoritesOnly = favoritesCheck.Checked
+		edited.Shuffle = shuffleCheck.Checked
+		e.OnSubmit(&edited)
+	})
+	submitBtn.Importance = widget.HighImportance
+	submitBtn.Disable()
+	nameEntry.OnChanged = func(s string) {
+		if strings.TrimSpace(s) == "" {
+			submitBtn.Disable()
+		} else {
+			submitBtn.Enable()
+		}
+	}
+	nameEntry.OnChanged(nameEntry.Text)
+	cancelBtn := widget.NewButton("Cancel", func() {
+		if e.OnCanceled != nil {
+			e.OnCanceled()
+		}
+	})
+
+	e.container = container.NewVBox(
+		container.NewHBox(layout.NewSpacer(), widget.NewLabel("Edit Smart Playlist"), layout.NewSpacer()),
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel("Name"), nameEntry,
+			widget.NewLabel("Genres"), genreEntry,
+			widget.NewLabel("Year"), container.NewGridWithColumns(3, minYearEntry, widget.NewLabel("to"), maxYearEntry),
+			widget.NewLabel("Minimum rating"), ratingSelect,
+			widget.NewLabel("Not played in"), notPlayedEntry,
+			widget.NewLabel("Added in the last"), addedEntry,
+			widget.NewLabel("Max tracks"), limitEntry,
+		),
+		container.NewHBox(favoritesCheck, shuffleCheck),
+		widget.NewSeparator(),
+		container.NewHBox(layout.NewSpacer(), cancelBtn, submitBtn),
+	)
+	return e
+}
+
+func (e *EditSmartPlaylistDialog) MinSize() fyne.Size {
+	return fyne.NewSize(350, e.BaseWidget.MinSize().Height)
+}
+
+func (e *EditSmartPlaylistDialog) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(e.container)
+}
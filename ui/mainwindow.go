@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -9,17 +10,20 @@ import (
 
 	"github.com/dweymouth/supersonic/backend"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	"github.com/dweymouth/supersonic/backend/taskbar"
 	"github.com/dweymouth/supersonic/res"
 	"github.com/dweymouth/supersonic/ui/browsing"
 	"github.com/dweymouth/supersonic/ui/controller"
 	"github.com/dweymouth/supersonic/ui/dialogs"
 	"github.com/dweymouth/supersonic/ui/os"
 	"github.com/dweymouth/supersonic/ui/theme"
+	"github.com/dweymouth/supersonic/ui/widgets"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 )
 
@@ -51,10 +55,13 @@ type MainWindow struct {
 	BrowsingPane *browsing.BrowsingPane
 	BottomPanel  *BottomPanel
 
+	DetachedWindows *DetachedWindows
+
 	theme            *theme.MyTheme
 	haveSystemTray   bool
 	alreadyConnected bool // tracks if we have already connected to a server before
 	container        *fyne.Container
+	perfOverlay      *widgets.PerfOverlay
 
 	// needs to bes shown/hidden when switching between servers based on whether they support radio
 	radioBtn *widget.Button
@@ -74,6 +81,7 @@ func NewMainWindow(fyneApp fyne.App, appName, displayAppName, appVersion string,
 	if app.Config.Application.EnableSystemTray {
 		m.SetupSystemTrayMenu(displayAppName, fyneApp)
 	}
+	m.setupTaskbar()
 	m.Controller = &controller.Controller{
 		AppVersion: appVersion,
 		MainWindow: m.Window,
@@ -85,10 +93,13 @@ func NewMainWindow(fyneApp fyne.App, appName, displayAppName, appVersion string,
 	m.Controller.NavHandler = m.Router.NavigateTo
 	m.Controller.ReloadFunc = m.BrowsingPane.Reload
 	m.Controller.CurPageFunc = m.BrowsingPane.CurrentPage
+	m.Controller.SetPerfOverlayVisible = m.SetPerfOverlayVisible
 
 	m.BottomPanel = NewBottomPanel(app.PlaybackManager, app.ImageManager, m.Controller)
-	m.container = container.NewBorder(nil, m.BottomPanel, nil, nil, m.BrowsingPane)
+	m.DetachedWindows = NewDetachedWindows(app, m.Controller, displayAppName)
+	m.container = container.NewStack(container.NewBorder(nil, m.BottomPanel, nil, nil, m.BrowsingPane))
 	m.Window.SetContent(m.container)
+	m.SetPerfOverlayVisible(app.Config.Application.ShowPerformanceOverlay)
 
 	w := float32(app.Config.Application.WindowWidth)
 	if w <= 1 {
@@ -118,6 +129,24 @@ func NewMainWindow(fyneApp fyne.App, appName, displayAppName, appVersion string,
 			})
 		}
 	})
+	app.EventBus.Subscribe(backend.EventNewAlbums, func(e backend.Event) {
+		albums := e.Data.([]*mediaprovider.Album)
+		// TODO: Once Fyne issue #2935 is resolved, show album cover
+		n := &fyne.Notification{Title: "New music added"}
+		if len(albums) == 1 {
+			n.Content = fmt.Sprintf("%s – %s", albums[0].Name, strings.Join(albums[0].ArtistNames, ", "))
+		} else {
+			n.Content = fmt.Sprintf("%s and %d more albums", albums[0].Name, len(albums)-1)
+		}
+		fyne.CurrentApp().SendNotification(n)
+	})
+	app.EventBus.Subscribe(backend.EventLocalChangeRolledBack, func(e backend.Event) {
+		msg := "Failed to update the favorite status on the server."
+		if e.Data.(*backend.RollbackInfo).Favorites == nil {
+			msg = "Failed to update the rating on the server."
+		}
+		dialog.ShowError(errors.New(msg), m.Window)
+	})
 	app.ServerManager.OnServerConnected(func() {
 		go m.RunOnServerConnectedTasks(app, displayAppName)
 	})
@@ -125,11 +154,51 @@ func NewMainWindow(fyneApp fyne.App, appName, displayAppName, appVersion string,
 		m.BrowsingPane.DisableNavigationButtons()
 		m.BrowsingPane.SetPage(nil)
 		m.BrowsingPane.ClearHistory()
-		m.Controller.PromptForLoginAndConnect()
+		if !app.ServerManager.SwitchingAccount() {
+			m.Controller.PromptForLoginAndConnect()
+		}
 	})
 	m.BrowsingPane.AddSettingsMenuItem("Log Out", func() { app.ServerManager.Logout(true) })
 	m.BrowsingPane.AddSettingsMenuItem("Switch Servers", func() { app.ServerManager.Logout(false) })
-	m.BrowsingPane.AddSettingsMenuItem("Rescan Library", func() { app.ServerManager.Server.RescanLibrary() })
+	m.BrowsingPane.AddSettingsMenuItem("Switch User...", m.Controller.ShowSwitchUserDialog)
+	m.BrowsingPane.AddSettingsMenuItem("Server Settings...", m.Controller.ShowServerSettingsDialog)
+	rescan := m.BrowsingPane.AddSettingsMenuItem("Rescan Library", func() {
+		if err := app.ServerManager.Server.RescanLibrary(); err == nil {
+			app.EventBus.Publish(backend.Event{Type: backend.EventLibraryRescanned})
+		}
+	})
+	app.EventBus.Subscribe(backend.EventLibraryScanProgress, func(e backend.Event) {
+		status := e.Data.(mediaprovider.ScanStatus)
+		rescan.Label = "Scanning Library..."
+		if status.Progress >= 0 {
+			rescan.Label = fmt.Sprintf("Scanning Library... %d%%", int(status.Progress*100))
+		} else if status.Count > 0 {
+			rescan.Label = fmt.Sprintf("Scanning Library... (%d items)", status.Count)
+		}
+		rescan.Disabled = true
+	})
+	app.EventBus.Subscribe(backend.EventLibraryScanCompleted, func(backend.Event) {
+		rescan.Label = "Rescan Library"
+		rescan.Disabled = false
+		m.BrowsingPane.Reload()
+	})
+	m.BrowsingPane.AddSettingsMenuItem("Select Music Library...", m.Controller.ShowSelectMusicLibraryDialog)
+	m.BrowsingPane.AddSettingsMenuItem("Downloads...", m.Controller.ShowDownloadsDialog)
+	m.BrowsingPane.AddSettingsMenuSeparator()
+	m.BrowsingPane.AddSettingsMenuItem("Export Queue...", m.Controller.ShowExportQueueDialog)
+	m.BrowsingPane.AddSettingsMenuItem("Print Setlist...", m.Controller.PrintQueueSetlist)
+	m.BrowsingPane.AddSettingsMenuItem("Export Listening History...", m.Controller.ShowExportHistoryDialog)
+	m.BrowsingPane.AddSettingsMenuItem("Year in Review...", m.Controller.ShowYearInReviewDialog)
+	m.BrowsingPane.AddSettingsMenuItem("Export App Data...", m.Controller.ShowExportAppDataDialog)
+	m.BrowsingPane.AddSettingsMenuItem("Import App Data...", m.Controller.ShowImportAppDataDialog)
+	m.BrowsingPane.AddSettingsMenuItem("Smart Playlists...", m.Controller.ShowSmartPlaylistsDialog)
+	m.BrowsingPane.AddSettingsMenuItem("Rediscover...", m.Controller.ShowRediscoverDialog)
+	m.BrowsingPane.AddSettingsMenuItem("API Request Log...", m.Controller.ShowRequestLogDialog)
+	m.BrowsingPane.AddSettingsMenuSeparator()
+	for _, view := range []string{DetachedViewQueue, DetachedViewLyrics, DetachedViewNowPlaying} {
+		view := view
+		m.BrowsingPane.AddSettingsMenuItem(view+" in New Window", func() { m.DetachedWindows.Open(view) })
+	}
 	m.BrowsingPane.AddSettingsMenuSeparator()
 	m.BrowsingPane.AddSettingsMenuItem("Check for Updates", func() {
 		go func() {
@@ -165,7 +234,7 @@ func (m *MainWindow) RunOnServerConnectedTasks(app *backend.App, displayAppName
 	time.Sleep(1 * time.Millisecond) // ensure this runs after sync tasks
 	m.BrowsingPane.EnableNavigationButtons()
 	m.Router.NavigateTo(m.StartupPage())
-	_, canRate := m.App.ServerManager.Server.(mediaprovider.SupportsRating)
+	_, canRate := mediaprovider.As[mediaprovider.SupportsRating](m.App.ServerManager.Server)
 	m.BottomPanel.NowPlaying.DisableRating = !canRate
 
 	if app.Config.Application.SavePlayQueue {
@@ -176,7 +245,7 @@ func (m *MainWindow) RunOnServerConnectedTasks(app *backend.App, displayAppName
 		}()
 	}
 
-	_, supportsRadio := m.App.ServerManager.Server.(mediaprovider.RadioProvider)
+	_, supportsRadio := mediaprovider.As[mediaprovider.RadioProvider](m.App.ServerManager.Server)
 	m.radioBtn.Hidden = !supportsRadio
 	m.radioBtn.Refresh()
 
@@ -187,6 +256,8 @@ func (m *MainWindow) RunOnServerConnectedTasks(app *backend.App, displayAppName
 		return
 	}
 
+	m.DetachedWindows.Restore()
+
 	// check if launching new version, else if found available update on startup
 	if l := app.Config.Application.LastLaunchedVersion; app.VersionTag() != l {
 		if !app.IsFirstLaunch() {
@@ -244,6 +315,28 @@ func (m *MainWindow) SetupSystemTrayMenu(appName string, fyneApp fyne.App) {
 	}
 }
 
+// setupTaskbar mirrors the playback state and progress on the
+// Windows taskbar button or macOS dock icon, if supported.
+func (m *MainWindow) setupTaskbar() {
+	pm := m.App.PlaybackManager
+	tb, err := taskbar.New(m.Window.Title, taskbar.Commands{
+		PlayPause: func() { _ = pm.PlayPause() },
+		Previous:  func() { _ = pm.SeekBackOrPrevious() },
+		Next:      func() { _ = pm.SeekNext() },
+	})
+	if err != nil {
+		return // unsupported OS
+	}
+	pm.OnPlaying(func() { tb.SetState(taskbar.Playing) })
+	pm.OnPaused(func() { tb.SetState(taskbar.Paused) })
+	pm.OnStopped(func() { tb.SetState(taskbar.Stopped) })
+	pm.OnPlayTimeUpdate(func(cur, total float64, _ bool) {
+		if total > 0 {
+			tb.SetProgress(cur / total)
+		}
+	})
+}
+
 func (m *MainWindow) HaveSystemTray() bool {
 	return m.haveSystemTray
 }
@@ -336,6 +429,12 @@ func (m *MainWindow) addShortcuts() {
 	m.Canvas().AddShortcut(&fyne.ShortcutSelectAll{}, func(_ fyne.Shortcut) {
 		m.BrowsingPane.SelectAll()
 	})
+	m.Canvas().AddShortcut(&fyne.ShortcutUndo{}, func(_ fyne.Shortcut) {
+		// undo accidental play queue changes, e.g. clearing or a bad drag
+		if !m.Controller.HaveModal() {
+			m.App.PlaybackManager.UndoQueueChange()
+		}
+	})
 	m.Canvas().AddShortcut(&ShortcutCloseWindow, func(_ fyne.Shortcut) {
 		if m.App.Config.Application.CloseToSystemTray && m.HaveSystemTray() {
 			m.Window.Hide()
@@ -372,6 +471,24 @@ func (m *MainWindow) showSettingsDialog() {
 	}, m.theme.ListThemeFiles())
 }
 
+// SetPerfOverlayVisible shows or hides the performance overlay
+// in the top right corner of the window.
+func (m *MainWindow) SetPerfOverlayVisible(visible bool) {
+	if visible == (m.perfOverlay != nil) {
+		return
+	}
+	if visible {
+		m.perfOverlay = widgets.NewPerfOverlay(m.App.Metrics)
+		m.container.Add(container.NewVBox(container.NewHBox(layout.NewSpacer(), m.perfOverlay)))
+		m.perfOverlay.Start()
+	} else {
+		m.perfOverlay.Stop()
+		m.perfOverlay = nil
+		m.container.Objects = m.container.Objects[:1]
+		m.container.Refresh()
+	}
+}
+
 func (m *MainWindow) Show() {
 	m.Window.Show()
 }
@@ -398,4 +515,5 @@ func (m *MainWindow) SaveWindowSize() {
 	// https://github.com/dweymouth/supersonic/issues/212
 	m.App.Config.Application.WindowHeight = int(math.RoundToEven(float64(m.Window.Canvas().Size().Height)))
 	m.App.Config.Application.WindowWidth = int(math.RoundToEven(float64(m.Window.Canvas().Size().Width)))
+	m.DetachedWindows.SaveLayout()
 }
@@ -0,0 +1,80 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	mdImageRegex = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLinkRegex  = regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]*)[^)]*\)`)
+)
+
+// SanitizeMarkdown prepares user-provided text, such as a playlist description,
+// for display as markdown. HTML formatting, which some servers and clients
+// store in descriptions, is converted to the markdown equivalent and
+// other HTML is removed. Images are replaced with their alt text so that
+// they are not fetched, and links are kept only if they are web links.
+func SanitizeMarkdown(s string) string {
+	var sb strings.Builder
+	tokr := html.NewTokenizer(strings.NewReader(s))
+	var href string
+	var skip bool // inside an element whose content is not displayed
+	for {
+		tt := tokr.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		t := tokr.Token()
+		switch tt {
+		case html.TextToken:
+			if !skip {
+				sb.WriteString(t.Data)
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			start := tt != html.EndTagToken
+			switch t.Data {
+			case "script", "style":
+				skip = tt == html.StartTagToken
+			case "br":
+				sb.WriteString("\n")
+			case "p", "div":
+				if !start {
+					sb.WriteString("\n\n")
+				}
+			case "b", "strong":
+				sb.WriteString("**")
+			case "i", "em":
+				sb.WriteString("*")
+			case "li":
+				if start {
+					sb.WriteString("\n- ")
+				}
+			case "a":
+				if start {
+					href = ""
+					for _, attr := range t.Attr {
+						if attr.Key == "href" {
+							href = attr.Val
+						}
+					}
+					sb.WriteString("[")
+				} else {
+					sb.WriteString("](" + href + ")")
+				}
+			}
+		}
+	}
+
+	md := mdImageRegex.ReplaceAllString(sb.String(), "$1")
+	md = mdLinkRegex.ReplaceAllStringFunc(md, func(link string) string {
+		m := mdLinkRegex.FindStringSubmatch(link)
+		if u := strings.ToLower(m[2]); strings.HasPrefix(u, "https://") || strings.HasPrefix(u, "http://") {
+			return link
+		}
+		return m[1]
+	})
+	return strings.TrimSpace(md)
+}
@@ -56,6 +56,32 @@ func (i *ThumbnailLoader) Load(coverID string) {
 	})
 }
 
+// LoadWithFallback is like Load, but if the cover is not cached, loads it
+// with fetchAsync, which can fall back to related covers or a generated
+// placeholder if the item's own cover is missing.
+func (i *ThumbnailLoader) LoadWithFallback(coverID string, fetchAsync func(func(image.Image, error)) context.CancelFunc) {
+	if i.prevLoadCancel != nil {
+		i.prevLoadCancel()
+	}
+	if coverID != "" {
+		if img, ok := i.im.GetCoverThumbnailFromCache(coverID); ok {
+			i.callOnLoaded(img)
+			return
+		}
+	}
+	if i.OnBeforeLoad != nil {
+		i.OnBeforeLoad()
+	}
+	i.prevLoadCancel = fetchAsync(func(img image.Image, err error) {
+		if err != nil {
+			log.Printf("Error loading cover image: %s", err.Error())
+		} else {
+			i.callOnLoaded(img)
+		}
+		i.prevLoadCancel()
+	})
+}
+
 func (i *ThumbnailLoader) callOnLoaded(im image.Image) {
 	if i.OnLoaded != nil {
 		i.OnLoaded(im)
@@ -99,6 +99,14 @@ func BytesToSizeString(bytes int64) string {
 	return fmt.Sprintf(fmtStringForThreeSigFigs(num)+" %s", num, suffix)
 }
 
+// DateString formats the date part of t in local time, or "" if t is zero.
+func DateString(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(time.DateOnly)
+}
+
 func fmtStringForThreeSigFigs(num float64) string {
 	switch {
 	case num >= 100:
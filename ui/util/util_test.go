@@ -23,3 +23,19 @@ func TestSecondsToTimeString(t *testing.T) {
 		}
 	}
 }
+
+func TestSanitizeMarkdown(t *testing.T) {
+	tests := map[string]string{
+		"Just **text**":                                              "Just **text**",
+		"Line one<br>Line <b>two</b>":                                "Line one\nLine **two**",
+		"<p>First</p><p>Second</p>":                                  "First\n\nSecond",
+		"See ![cover](http://example.com/a.png)":                     "See cover",
+		"[site](https://example.com) and [file](file:///etc/passwd)": "[site](https://example.com) and file",
+		`<a href="https://example.com">link</a><script>x</script>`:   "[link](https://example.com)",
+	}
+	for input, want := range tests {
+		if got := SanitizeMarkdown(input); got != want {
+			t.Errorf("SanitizeMarkdown(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
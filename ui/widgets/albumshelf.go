@@ -0,0 +1,80 @@
+package widgets
+
+import (
+	"image"
+
+	"github.com/dweymouth/supersonic/backend/mediaprovider"
+	myTheme "github.com/dweymouth/supersonic/ui/theme"
+	"github.com/dweymouth/supersonic/ui/util"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+const albumShelfCoverSize = 120
+
+var _ fyne.Widget = (*AlbumShelf)(nil)
+
+// AlbumShelf is a titled, horizontally scrolling row of album covers.
+type AlbumShelf struct {
+	widget.BaseWidget
+
+	OnAlbumTapped func(albumID string)
+
+	im        util.ImageFetcher
+	title     *widget.Label
+	row       *fyne.Container
+	container *fyne.Container
+	loaders   []util.ThumbnailLoader
+}
+
+func NewAlbumShelf(title string, im util.ImageFetcher) *AlbumShelf {
+	s := &AlbumShelf{
+		im:    im,
+		title: widget.NewLabelWithStyle(title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		row:   container.NewHBox(),
+	}
+	s.ExtendBaseWidget(s)
+	s.container = container.NewBorder(s.title, nil, nil, nil, container.NewHScroll(s.row))
+	return s
+}
+
+// SetAlbums replaces the albums on the shelf.
+func (s *AlbumShelf) SetAlbums(albums []*mediaprovider.Album) {
+	s.row.RemoveAll()
+	s.loaders = make([]util.ThumbnailLoader, len(albums))
+	for i, al := range albums {
+		s.row.Add(s.newItem(al, &s.loaders[i]))
+	}
+	s.row.Refresh()
+}
+
+func (s *AlbumShelf) newItem(al *mediaprovider.Album, loader *util.ThumbnailLoader) fyne.CanvasObject {
+	onTapped := func() {
+		if s.OnAlbumTapped != nil {
+			s.OnAlbumTapped(al.ID)
+		}
+	}
+	cover := NewImagePlaceholder(myTheme.AlbumIcon, albumShelfCoverSize)
+	cover.OnTapped = func(*fyne.PointEvent) { onTapped() }
+	*loader = util.NewThumbnailLoader(s.im, func(img image.Image) { cover.SetImage(img, true /*tappable*/) })
+	loader.Load(al.CoverArtID)
+
+	name := widget.NewHyperlink(al.Name, nil)
+	name.OnTapped = onTapped
+	name.Truncation = fyne.TextTruncateEllipsis
+	artist := widget.NewLabel("")
+	if len(al.ArtistNames) > 0 {
+		artist.SetText(al.ArtistNames[0])
+	}
+	artist.Truncation = fyne.TextTruncateEllipsis
+	artist.Importance = widget.LowImportance
+
+	size := fyne.NewSize(albumShelfCoverSize, albumShelfCoverSize+name.MinSize().Height+artist.MinSize().Height)
+	return container.NewGridWrap(size, container.NewBorder(nil, container.NewVBox(name, artist), nil, nil, cover))
+}
+
+func (s *AlbumShelf) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(s.container)
+}
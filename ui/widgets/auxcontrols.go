@@ -13,12 +13,14 @@ import (
 )
 
 // The "aux" controls for playback, positioned to the right
-// of the BottomPanel. Currently only volume control.
+// of the BottomPanel. Volume control, loop mode, output device and signal path info.
 type AuxControls struct {
 	widget.BaseWidget
 
 	VolumeControl *VolumeControl
 	loop          *IconButton
+	signalPath    *IconButton
+	output        *IconButton
 
 	container *fyne.Container
 }
@@ -27,14 +29,18 @@ func NewAuxControls(initialVolume int) *AuxControls {
 	a := &AuxControls{
 		VolumeControl: NewVolumeControl(initialVolume),
 		loop:          NewIconButton(myTheme.RepeatIcon, nil),
+		signalPath:    NewIconButton(theme.InfoIcon(), nil),
+		output:        NewIconButton(theme.ComputerIcon(), nil),
 	}
 	a.loop.IconSize = IconButtonSizeSmaller
+	a.signalPath.IconSize = IconButtonSizeSmaller
+	a.output.IconSize = IconButtonSizeSmaller
 	a.container = container.NewHBox(
 		layout.NewSpacer(),
 		container.NewVBox(
 			layout.NewSpacer(),
 			a.VolumeControl,
-			container.NewHBox(layout.NewSpacer(), a.loop, util.NewHSpace(5)),
+			container.NewHBox(layout.NewSpacer(), a.output, a.signalPath, a.loop, util.NewHSpace(5)),
 			layout.NewSpacer(),
 		),
 	)
@@ -50,6 +56,25 @@ func (a *AuxControls) OnChangeLoopMode(f func()) {
 	a.loop.OnTapped = f
 }
 
+// Sets the callback invoked when the signal path button is tapped.
+// The button is passed so that a popup can be positioned relative to it.
+func (a *AuxControls) OnShowSignalPath(f func(btn fyne.CanvasObject)) {
+	a.signalPath.OnTapped = func() { f(a.signalPath) }
+}
+
+// Sets the callback invoked when the output device button is tapped.
+// The button is passed so that a menu can be positioned relative to it.
+func (a *AuxControls) OnShowOutputDevices(f func(btn fyne.CanvasObject)) {
+	a.output.OnTapped = func() { f(a.output) }
+}
+
+// SetRemoteOutput highlights the output device button
+// when playing to a device other than this computer.
+func (a *AuxControls) SetRemoteOutput(remote bool) {
+	a.output.Highlighted = remote
+	a.output.Refresh()
+}
+
 func (a *AuxControls) SetLoopMode(mode backend.LoopMode) {
 	switch mode {
 	case backend.LoopAll:
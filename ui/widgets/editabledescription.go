@@ -0,0 +1,131 @@
+package widgets
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/dweymouth/supersonic/ui/util"
+)
+
+// EditableDescription displays a description rendered as markdown,
+// and if editable, allows editing it in place with a live preview.
+type EditableDescription struct {
+	widget.BaseWidget
+
+	// Called with the new description when the user saves an edit.
+	OnSave func(description string)
+
+	text     string
+	editable bool
+	editing  bool
+
+	view       *widget.RichText
+	viewScroll *container.Scroll
+	editBtn    *widget.Button
+	entry      *widget.Entry
+	preview    *widget.RichText
+	viewMode   *fyne.Container
+	editMode   *fyne.Container
+	container  *fyne.Container
+}
+
+// NewEditableDescription creates a new EditableDescription which shows up to
+// maxRows rows of the rendered description, scrolling if it's longer.
+func NewEditableDescription(maxRows int) *EditableDescription {
+	e := &EditableDescription{}
+	e.ExtendBaseWidget(e)
+
+	e.view = widget.NewRichText()
+	e.view.Wrapping = fyne.TextWrapWord
+	e.viewScroll = container.NewVScroll(e.view)
+	e.viewScroll.SetMinSize(fyne.NewSize(0, NewMaxRowsLabel(maxRows, "").MinSize().Height))
+	e.editBtn = widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), e.startEditing)
+	e.editBtn.Importance = widget.LowImportance
+	e.viewMode = container.NewBorder(nil, nil, nil, container.NewVBox(e.editBtn), e.viewScroll)
+
+	e.entry = widget.NewMultiLineEntry()
+	e.entry.Wrapping = fyne.TextWrapWord
+	e.entry.SetMinRowsVisible(maxRows)
+	e.entry.SetPlaceHolder("Description (markdown)")
+	e.preview = widget.NewRichText()
+	e.preview.Wrapping = fyne.TextWrapWord
+	e.entry.OnChanged = func(s string) {
+		e.preview.ParseMarkdown(util.SanitizeMarkdown(s))
+	}
+	saveBtn := widget.NewButton("Save", func() {
+		e.stopEditing()
+		if e.entry.Text != e.text {
+			e.SetText(e.entry.Text)
+			if e.OnSave != nil {
+				e.OnSave(e.text)
+			}
+		}
+	})
+	saveBtn.Importance = widget.HighImportance
+	cancelBtn := widget.NewButton("Cancel", e.stopEditing)
+	e.editMode = container.NewBorder(nil,
+		container.NewHBox(layout.NewSpacer(), cancelBtn, saveBtn), nil, nil,
+		container.NewGridWithColumns(2, e.entry, container.NewVScroll(e.preview)))
+	e.editMode.Hide()
+
+	e.container = container.NewStack(e.viewMode, e.editMode)
+	e.updateView()
+	return e
+}
+
+// SetText sets the (markdown) description text, cancelling any edit in progress.
+func (e *EditableDescription) SetText(text string) {
+	e.text = text
+	if e.editing {
+		e.stopEditing()
+	}
+	e.updateView()
+}
+
+// SetEditable sets whether the user can edit the description.
+func (e *EditableDescription) SetEditable(editable bool) {
+	e.editable = editable
+	if !editable && e.editing {
+		e.stopEditing()
+	}
+	e.updateView()
+}
+
+func (e *EditableDescription) updateView() {
+	e.editBtn.Hidden = !e.editable
+	if e.text == "" && e.editable {
+		e.view.Segments = []widget.RichTextSegment{&widget.TextSegment{
+			Text:  "Add a description...",
+			Style: widget.RichTextStyle{ColorName: theme.ColorNamePlaceHolder, Inline: true},
+		}}
+		e.view.Refresh()
+	} else {
+		e.view.ParseMarkdown(util.SanitizeMarkdown(e.text))
+	}
+	e.viewScroll.ScrollToTop()
+	e.viewMode.Refresh()
+}
+
+func (e *EditableDescription) startEditing() {
+	e.editing = true
+	e.entry.SetText(e.text)
+	e.viewMode.Hide()
+	e.editMode.Show()
+	if c := fyne.CurrentApp().Driver().CanvasForObject(e); c != nil {
+		c.Focus(e.entry)
+	}
+	e.Refresh()
+}
+
+func (e *EditableDescription) stopEditing() {
+	e.editing = false
+	e.editMode.Hide()
+	e.viewMode.Show()
+	e.Refresh()
+}
+
+func (e *EditableDescription) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(e.container)
+}
@@ -3,8 +3,10 @@ package widgets
 import (
 	"context"
 	"fmt"
+	"image"
 	"sync"
 
+	"github.com/dweymouth/supersonic/backend"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
 	"github.com/dweymouth/supersonic/sharedutil"
 	myTheme "github.com/dweymouth/supersonic/ui/theme"
@@ -18,6 +20,26 @@ import (
 
 const batchFetchSize = 6
 
+// coverFallbackFetcher is an ImageFetcher which can fall back
+// to related covers if an item's own cover is missing.
+// impl: backend.ImageManager
+type coverFallbackFetcher interface {
+	GetCoverThumbnailWithFallbackAsync(backend.CoverFallback, func(image.Image, error)) context.CancelFunc
+}
+
+// loadCoverWithFallback loads the cover described by fb with the loader,
+// falling back to related covers if im supports it.
+func loadCoverWithFallback(loader *util.ThumbnailLoader, im util.ImageFetcher, fb backend.CoverFallback) {
+	f, ok := im.(coverFallbackFetcher)
+	if !ok {
+		loader.Load(fb.CoverArtID)
+		return
+	}
+	loader.LoadWithFallback(fb.CoverArtID, func(cb func(image.Image, error)) context.CancelFunc {
+		return f.GetCoverThumbnailWithFallbackAsync(fb, cb)
+	})
+}
+
 type BatchingIterator[M any] struct {
 	iter mediaprovider.MediaIterator[M]
 }
@@ -57,10 +79,18 @@ func (g gridViewAlbumIterator) NextN(n int) []GridViewItemModel {
 			CoverArtID:   al.CoverArtID,
 			Secondary:    al.ArtistNames,
 			SecondaryIDs: al.ArtistIDs,
+			Genre:        firstGenre(al),
 		}
 	})
 }
 
+func firstGenre(al *mediaprovider.Album) string {
+	if len(al.Genres) > 0 {
+		return al.Genres[0]
+	}
+	return ""
+}
+
 func NewGridViewAlbumIterator(iter mediaprovider.AlbumIterator) GridViewIterator {
 	return gridViewAlbumIterator{iter: NewBatchingIterator(iter)}
 }
@@ -329,7 +359,11 @@ func (g *GridView) doUpdateItemCard(itemIdx int, card *GridViewItem) {
 	}
 	g.stateMutex.Unlock()
 	card.Update(item)
-	card.ImgLoader.Load(item.CoverArtID)
+	fb := backend.CoverFallback{CoverArtID: item.CoverArtID, PlaylistID: item.PlaylistID, Name: item.Name, Genre: item.Genre}
+	if len(item.SecondaryIDs) > 0 && len(item.Secondary) > 0 {
+		fb.Artist = item.Secondary[0]
+	}
+	loadCoverWithFallback(&card.ImgLoader, g.imageFetcher, fb)
 
 	// if user has scrolled near the bottom, fetch more
 	if itemIdx > g.lenItems()-10 {
@@ -131,6 +131,13 @@ type GridViewItemModel struct {
 	CoverArtID   string
 	Secondary    []string
 	SecondaryIDs []string
+
+	// Used to color the generated placeholder if the item has no cover
+	Genre string
+
+	// Set for playlists, to show the album cover chosen for the playlist
+	// or a mosaic of its album covers if it has no cover
+	PlaylistID string
 }
 
 type GridViewItem struct {
@@ -0,0 +1,140 @@
+package widgets
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/dweymouth/supersonic/backend"
+	myTheme "github.com/dweymouth/supersonic/ui/theme"
+	"github.com/dweymouth/supersonic/ui/util"
+)
+
+const (
+	perfOverlayUpdateInterval = 500 * time.Millisecond
+	// number of API endpoints shown, those with the most total request time
+	perfOverlayMaxEndpoints = 5
+)
+
+// PerfOverlay displays frame timings, API request stats by endpoint,
+// and cache hit rates, to help diagnose slowness.
+type PerfOverlay struct {
+	widget.BaseWidget
+
+	metrics *backend.Metrics
+
+	mu        sync.Mutex
+	lastFrame time.Time
+	frames    int
+	sumFrame  time.Duration
+	maxFrame  time.Duration
+
+	frameTicker *fyne.Animation
+	stop        chan struct{}
+
+	text      *widget.Label
+	container *fyne.Container
+}
+
+func NewPerfOverlay(metrics *backend.Metrics) *PerfOverlay {
+	p := &PerfOverlay{metrics: metrics, text: widget.NewLabel("")}
+	p.text.TextStyle.Monospace = true
+	p.ExtendBaseWidget(p)
+	bg := myTheme.NewThemedRectangle(theme.ColorNameOverlayBackground)
+	bg.CornerRadiusName = theme.SizeNameInputRadius
+	p.container = container.NewStack(bg, p.text)
+	return p
+}
+
+// Start begins measuring frame timings and periodically updating the display.
+func (p *PerfOverlay) Start() {
+	if p.stop != nil {
+		return
+	}
+	p.stop = make(chan struct{})
+	// an animation's tick func is invoked once per rendered frame
+	p.frameTicker = fyne.NewAnimation(time.Second, func(float32) { p.recordFrame() })
+	p.frameTicker.RepeatCount = fyne.AnimationRepeatForever
+	p.frameTicker.Start()
+	go p.updateLoop(p.stop)
+}
+
+// Stop stops measuring and updating.
+func (p *PerfOverlay) Stop() {
+	if p.stop == nil {
+		return
+	}
+	p.frameTicker.Stop()
+	close(p.stop)
+	p.stop = nil
+}
+
+func (p *PerfOverlay) recordFrame() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if !p.lastFrame.IsZero() {
+		d := now.Sub(p.lastFrame)
+		p.frames++
+		p.sumFrame += d
+		if d > p.maxFrame {
+			p.maxFrame = d
+		}
+	}
+	p.lastFrame = now
+}
+
+func (p *PerfOverlay) updateLoop(stop chan struct{}) {
+	t := time.NewTicker(perfOverlayUpdateInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			p.text.SetText(p.statsText())
+		}
+	}
+}
+
+func (p *PerfOverlay) statsText() string {
+	p.mu.Lock()
+	var avgFrame time.Duration
+	if p.frames > 0 {
+		avgFrame = p.sumFrame / time.Duration(p.frames)
+	}
+	maxFrame := p.maxFrame
+	p.frames, p.sumFrame, p.maxFrame = 0, 0, 0
+	p.mu.Unlock()
+
+	var sb strings.Builder
+	fps := 0.0
+	if avgFrame > 0 {
+		fps = float64(time.Second) / float64(avgFrame)
+	}
+	fmt.Fprintf(&sb, "Frame: %.1f fps, avg %s, max %s\n", fps,
+		avgFrame.Round(100*time.Microsecond), maxFrame.Round(100*time.Microsecond))
+
+	m := p.metrics.Snapshot()
+	fmt.Fprintf(&sb, "API: %d in flight, %d total, avg %s",
+		m.InFlightRequests, m.TotalRequests, m.AverageRequestTime.Round(time.Millisecond))
+	for _, e := range m.Endpoints[:min(len(m.Endpoints), perfOverlayMaxEndpoints)] {
+		fmt.Fprintf(&sb, "\n  %s: %d, avg %s, max %s, %.0f%% errors, %s",
+			e.Endpoint, e.Requests, e.AverageTime().Round(time.Millisecond),
+			e.MaxTime.Round(time.Millisecond), e.ErrorRate()*100, util.BytesToSizeString(e.Bytes))
+	}
+	for _, c := range m.Caches {
+		fmt.Fprintf(&sb, "\n%s: %.0f%% hits (%d/%d)", c.Name, c.HitRate()*100, c.Hits, c.Hits+c.Misses)
+	}
+	return sb.String()
+}
+
+func (p *PerfOverlay) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(p.container)
+}
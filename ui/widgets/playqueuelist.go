@@ -388,6 +388,7 @@ type PlayQueueListRow struct {
 
 	OnTappedSecondary func(e *fyne.PointEvent, trackIdx int)
 
+	im            *backend.ImageManager
 	imageLoader   util.ThumbnailLoader
 	playQueueList *PlayQueueList
 	trackID       string
@@ -403,6 +404,7 @@ type PlayQueueListRow struct {
 
 func NewPlayQueueListRow(playQueueList *PlayQueueList, im *backend.ImageManager, playingIcon fyne.CanvasObject) *PlayQueueListRow {
 	p := &PlayQueueListRow{
+		im:            im,
 		playingIcon:   playingIcon,
 		playQueueList: playQueueList,
 		num:           widget.NewLabel(""),
@@ -470,7 +472,11 @@ func (p *PlayQueueListRow) Update(tm *util.TrackListModel, rowNum int) {
 		} else {
 			p.cover.PlaceholderIcon = myTheme.TracksIcon
 		}
-		p.imageLoader.Load(meta.CoverArtID)
+		if meta.Type == mediaprovider.MediaItemTypeTrack {
+			loadCoverWithFallback(&p.imageLoader, p.im, backend.CoverFallbackForItem(tm.Item))
+		} else {
+			p.imageLoader.Load(meta.CoverArtID)
+		}
 		p.EnsureUnfocused()
 		p.trackID = meta.ID
 		p.title.Text = meta.Name
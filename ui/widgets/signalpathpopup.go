@@ -0,0 +1,52 @@
+package widgets
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/dweymouth/supersonic/backend"
+)
+
+// SignalPathPopup displays the stages of the current playback's signal path.
+type SignalPathPopup struct {
+	widget.BaseWidget
+
+	container *fyne.Container
+}
+
+func NewSignalPathPopup() *SignalPathPopup {
+	s := &SignalPathPopup{container: container.NewVBox()}
+	s.ExtendBaseWidget(s)
+	return s
+}
+
+func (s *SignalPathPopup) Update(stages []backend.SignalPathStage) {
+	s.container.RemoveAll()
+	if len(stages) == 0 {
+		s.container.Add(widget.NewLabel("Nothing is playing"))
+		s.container.Refresh()
+		return
+	}
+
+	title := widget.NewLabel("Signal Path")
+	title.TextStyle.Bold = true
+	s.container.Add(title)
+	for i, stage := range stages {
+		if i > 0 {
+			s.container.Add(container.NewCenter(widget.NewIcon(theme.MoveDownIcon())))
+		}
+		name := widget.NewLabel(stage.Name)
+		name.TextStyle.Bold = true
+		if stage.Lossy {
+			name.Importance = widget.WarningImportance
+		}
+		s.container.Add(container.NewBorder(nil, nil, name, nil, widget.NewLabel(stage.Description)))
+	}
+	s.container.Refresh()
+}
+
+func (s *SignalPathPopup) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(s.container)
+}
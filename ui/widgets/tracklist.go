@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/dweymouth/supersonic/backend"
 	"github.com/dweymouth/supersonic/backend/mediaprovider"
@@ -67,16 +68,23 @@ type Tracklist struct {
 	OnPlayTrackAt       func(int)
 	OnPlaySelection     func(tracks []*mediaprovider.Track, shuffle bool)
 	OnPlaySelectionNext func(trackIDs []*mediaprovider.Track)
-	OnAddToQueue        func(trackIDs []*mediaprovider.Track)
-	OnAddToPlaylist     func(trackIDs []string)
-	OnSetFavorite       func(trackIDs []string, fav bool)
-	OnSetRating         func(trackIDs []string, rating int)
-	OnDownload          func(tracks []*mediaprovider.Track, downloadName string)
-	OnShare             func(trackID string)
-	OnPlaySongRadio     func(track *mediaprovider.Track)
-
-	OnShowArtistPage func(artistID string)
-	OnShowAlbumPage  func(albumID string)
+	// Play the selection after the rest of the currently playing album
+	OnPlaySelectionAfterAlbum func(tracks []*mediaprovider.Track)
+	OnAddToQueue              func(trackIDs []*mediaprovider.Track)
+	OnAddToPlaylist           func(trackIDs []string)
+	OnSetFavorite             func(trackIDs []string, fav bool)
+	OnSetRating               func(trackIDs []string, rating int)
+	OnDownload                func(tracks []*mediaprovider.Track, downloadName string)
+	OnShare                   func(trackID string)
+	OnPlaySongRadio           func(track *mediaprovider.Track)
+	// Set whether the tracks always stream as the original file, bypassing transcoding
+	OnSetStreamOriginal func(trackIDs []string, original bool)
+	IsStreamOriginal    func(trackID string) bool
+
+	OnShowArtistPage  func(artistID string)
+	OnShowAlbumPage   func(albumID string)
+	OnShowOtherAlbums func(track *mediaprovider.Track)
+	OnEditMetadata    func(track *mediaprovider.Track)
 
 	OnColumnVisibilityMenuShown func(*widget.PopUp)
 	OnVisibleColumnsChanged     func([]string)
@@ -89,15 +97,18 @@ type Tracklist struct {
 	tracks          []*util.TrackListModel
 	tracksOrigOrder []*util.TrackListModel
 
-	nowPlayingID      string
-	colLayout         *layouts.ColumnsLayout
-	hdr               *ListHeader
-	list              *FocusList
-	ctxMenu           *fyne.Menu
-	ratingSubmenu     *fyne.MenuItem
-	shareMenuItem     *fyne.MenuItem
-	songRadioMenuItem *fyne.MenuItem
-	container         *fyne.Container
+	nowPlayingID        string
+	colLayout           *layouts.ColumnsLayout
+	hdr                 *ListHeader
+	list                *FocusList
+	ctxMenu             *fyne.Menu
+	ratingSubmenu       *fyne.MenuItem
+	shareMenuItem       *fyne.MenuItem
+	songRadioMenuItem   *fyne.MenuItem
+	otherAlbumsMenuItem *fyne.MenuItem
+	streamOrigMenuItem  *fyne.MenuItem
+	editInfoMenuItem    *fyne.MenuItem
+	container           *fyne.Container
 }
 
 func NewTracklist(tracks []*mediaprovider.Track, im *backend.ImageManager, useCompactRows bool) *Tracklist {
@@ -294,6 +305,19 @@ func (t *Tracklist) IncrementPlayCount(trackID string) {
 	}
 }
 
+// Sets the rating of the given tracks and updates the list rendering
+func (t *Tracklist) SetTrackRatings(trackIDs []string, rating int) {
+	for _, id := range trackIDs {
+		t.tracksMutex.RLock()
+		tr, idx := util.FindItemByID(t.tracks, id)
+		t.tracksMutex.RUnlock()
+		if tr != nil && tr.(*mediaprovider.Track).Rating != rating {
+			tr.(*mediaprovider.Track).Rating = rating
+			t.list.RefreshItem(idx)
+		}
+	}
+}
+
 // Remove all tracks from the tracklist. Does not issue Refresh call. Thread-safe.
 func (t *Tracklist) Clear() {
 	t.tracksMutex.Lock()
@@ -388,7 +412,7 @@ func (t *Tracklist) stringSort(fieldFn func(*util.TrackListModel) string) {
 	new := make([]*util.TrackListModel, len(t.tracksOrigOrder))
 	copy(new, t.tracksOrigOrder)
 	sort.SliceStable(new, func(i, j int) bool {
-		cmp := strings.Compare(fieldFn(new[i]), fieldFn(new[j]))
+		cmp := sharedutil.CompareStrings(fieldFn(new[i]), fieldFn(new[j]))
 		if t.sorting.SortOrder == SortDescending {
 			return cmp > 0
 		}
@@ -443,6 +467,12 @@ func (t *Tracklist) doSortTracks() {
 		t.stringSort(func(tr *util.TrackListModel) string { return tr.Track().Comment })
 	case ColumnBitrate:
 		t.intSort(func(tr *util.TrackListModel) int64 { return int64(tr.Track().BitRate) })
+	case ColumnAdded:
+		t.intSort(func(tr *util.TrackListModel) int64 { return tr.Track().CreatedAt.Unix() })
+	case ColumnLastPlayed:
+		t.intSort(func(tr *util.TrackListModel) int64 { return tr.Track().LastPlayed.Unix() })
+	case ColumnFavorited:
+		t.intSort(func(tr *util.TrackListModel) int64 { return tr.Track().FavoritedAt.Unix() })
 	case ColumnFavorite:
 		t.intSort(func(tr *util.TrackListModel) int64 {
 			if tr.Track().Favorite {
@@ -525,6 +555,12 @@ func (t *Tracklist) onShowContextMenu(e *fyne.PointEvent, trackIdx int) {
 				}
 			})
 			playNext.Icon = myTheme.PlayNextIcon
+			playAfterAlbum := fyne.NewMenuItem("Play after current album", func() {
+				if t.OnPlaySelectionAfterAlbum != nil {
+					t.OnPlaySelectionAfterAlbum(t.selectedTracks())
+				}
+			})
+			playAfterAlbum.Icon = myTheme.PlayNextIcon
 			add := fyne.NewMenuItem("Add to queue", func() {
 				if t.OnPlaySelection != nil {
 					t.OnAddToQueue(t.selectedTracks())
@@ -536,7 +572,7 @@ func (t *Tracklist) onShowContextMenu(e *fyne.PointEvent, trackIdx int) {
 			})
 			t.songRadioMenuItem.Icon = myTheme.RadioIcon
 			t.ctxMenu.Items = append(t.ctxMenu.Items,
-				play, shuffle, playNext, add, t.songRadioMenuItem)
+				play, shuffle, playNext, playAfterAlbum, add, t.songRadioMenuItem)
 		}
 		playlist := fyne.NewMenuItem("Add to playlist...", func() {
 			if t.OnAddToPlaylist != nil {
@@ -563,6 +599,26 @@ func (t *Tracklist) onShowContextMenu(e *fyne.PointEvent, trackIdx int) {
 		})
 		t.shareMenuItem.Icon = myTheme.ShareIcon
 		t.ctxMenu.Items = append(t.ctxMenu.Items, t.shareMenuItem)
+		t.otherAlbumsMenuItem = fyne.NewMenuItem("Show other albums...", func() {
+			if tracks := t.selectedTracks(); len(tracks) > 0 && t.OnShowOtherAlbums != nil {
+				t.OnShowOtherAlbums(tracks[0])
+			}
+		})
+		t.otherAlbumsMenuItem.Icon = myTheme.AlbumIcon
+		t.ctxMenu.Items = append(t.ctxMenu.Items, t.otherAlbumsMenuItem)
+		t.editInfoMenuItem = fyne.NewMenuItem("Edit info...", func() {
+			if tracks := t.selectedTracks(); len(tracks) > 0 && t.OnEditMetadata != nil {
+				t.OnEditMetadata(tracks[0])
+			}
+		})
+		t.editInfoMenuItem.Icon = theme.DocumentCreateIcon()
+		t.ctxMenu.Items = append(t.ctxMenu.Items, t.editInfoMenuItem)
+		t.streamOrigMenuItem = fyne.NewMenuItem("Stream original file", func() {
+			if t.OnSetStreamOriginal != nil {
+				t.OnSetStreamOriginal(t.SelectedTrackIDs(), !t.streamOrigMenuItem.Checked)
+			}
+		})
+		t.ctxMenu.Items = append(t.ctxMenu.Items, t.streamOrigMenuItem)
 		t.ctxMenu.Items = append(t.ctxMenu.Items, fyne.NewMenuItemSeparator())
 		t.ctxMenu.Items = append(t.ctxMenu.Items, favorite, unfavorite)
 		t.ratingSubmenu = util.NewRatingSubmenu(func(rating int) {
@@ -576,6 +632,11 @@ func (t *Tracklist) onShowContextMenu(e *fyne.PointEvent, trackIdx int) {
 	}
 	t.ratingSubmenu.Disabled = t.Options.DisableRating
 	t.shareMenuItem.Disabled = t.Options.DisableSharing || len(t.selectedTracks()) != 1
+	t.otherAlbumsMenuItem.Disabled = len(t.selectedTracks()) != 1
+	t.editInfoMenuItem.Disabled = t.OnEditMetadata == nil || len(t.selectedTracks()) != 1
+	t.streamOrigMenuItem.Disabled = t.OnSetStreamOriginal == nil
+	t.streamOrigMenuItem.Checked = t.IsStreamOriginal != nil &&
+		!slices.ContainsFunc(t.SelectedTrackIDs(), func(id string) bool { return !t.IsStreamOriginal(id) })
 	widget.ShowPopUpMenuAtPosition(t.ctxMenu, fyne.CurrentApp().Driver().CanvasForObject(t), e.AbsolutePosition)
 }
 
@@ -589,6 +650,11 @@ func (t *Tracklist) onSetFavorite(trackID string, fav bool) {
 func (t *Tracklist) onSetFavorites(tracks []*mediaprovider.Track, fav bool, needRefresh bool) {
 	for _, tr := range tracks {
 		tr.Favorite = fav
+		if !fav {
+			tr.FavoritedAt = time.Time{}
+		} else if tr.FavoritedAt.IsZero() {
+			tr.FavoritedAt = time.Now()
+		}
 	}
 	if needRefresh {
 		t.Refresh()
@@ -37,6 +37,9 @@ const (
 	ColumnComment     = "Comment"
 	ColumnBitrate     = "Bitrate"
 	ColumnSize        = "Size"
+	ColumnAdded       = "Added"
+	ColumnLastPlayed  = "Last Played"
+	ColumnFavorited   = "Favorited"
 	ColumnPath        = "Path"
 )
 
@@ -53,11 +56,14 @@ var (
 		{Name: ColumnComment, Col: ListColumn{Text: "Comment", Alignment: fyne.TextAlignLeading, CanToggleVisible: true}},
 		{Name: ColumnBitrate, Col: ListColumn{Text: "Bitrate", Alignment: fyne.TextAlignTrailing, CanToggleVisible: true}},
 		{Name: ColumnSize, Col: ListColumn{Text: "Size", Alignment: fyne.TextAlignTrailing, CanToggleVisible: true}},
+		{Name: ColumnAdded, Col: ListColumn{Text: "Added", Alignment: fyne.TextAlignTrailing, CanToggleVisible: true}},
+		{Name: ColumnLastPlayed, Col: ListColumn{Text: "Last Played", Alignment: fyne.TextAlignTrailing, CanToggleVisible: true}},
+		{Name: ColumnFavorited, Col: ListColumn{Text: "Favorited", Alignment: fyne.TextAlignTrailing, CanToggleVisible: true}},
 		{Name: ColumnPath, Col: ListColumn{Text: "File Path", Alignment: fyne.TextAlignLeading, CanToggleVisible: true}},
 	}
 
-	// #, Title/Artist, Album, Time, Year, Favorite, Rating, Plays, Comment, Bitrate, Size, Path
-	ExpandedTracklistRowColumnWidths = []float32{40, -1, -1, 60, 60, 55, 100, 65, -1, 75, 75, -1}
+	// #, Title/Artist, Album, Time, Year, Favorite, Rating, Plays, Comment, Bitrate, Size, Added, Last Played, Favorited, Path
+	ExpandedTracklistRowColumnWidths = []float32{40, -1, -1, 60, 60, 55, 100, 65, -1, 75, 75, 100, 100, 100, -1}
 
 	CompactTracklistRowColumns = []TracklistColumn{
 		{Name: ColumnNum, Col: ListColumn{Text: "#", Alignment: fyne.TextAlignTrailing, CanToggleVisible: false}},
@@ -72,11 +78,14 @@ var (
 		{Name: ColumnComment, Col: ListColumn{Text: "Comment", Alignment: fyne.TextAlignLeading, CanToggleVisible: true}},
 		{Name: ColumnBitrate, Col: ListColumn{Text: "Bitrate", Alignment: fyne.TextAlignTrailing, CanToggleVisible: true}},
 		{Name: ColumnSize, Col: ListColumn{Text: "Size", Alignment: fyne.TextAlignTrailing, CanToggleVisible: true}},
+		{Name: ColumnAdded, Col: ListColumn{Text: "Added", Alignment: fyne.TextAlignTrailing, CanToggleVisible: true}},
+		{Name: ColumnLastPlayed, Col: ListColumn{Text: "Last Played", Alignment: fyne.TextAlignTrailing, CanToggleVisible: true}},
+		{Name: ColumnFavorited, Col: ListColumn{Text: "Favorited", Alignment: fyne.TextAlignTrailing, CanToggleVisible: true}},
 		{Name: ColumnPath, Col: ListColumn{Text: "File Path", Alignment: fyne.TextAlignLeading, CanToggleVisible: true}},
 	}
 
-	// #, Title, Artist, Album, Time, Year, Favorite, Rating, Plays, Comment, Bitrate, Size, Path
-	CompactTracklistRowColumnWidths = []float32{40, -1, -1, -1, 60, 60, 55, 100, 65, -1, 75, 75, -1}
+	// #, Title, Artist, Album, Time, Year, Favorite, Rating, Plays, Comment, Bitrate, Size, Added, Last Played, Favorited, Path
+	CompactTracklistRowColumnWidths = []float32{40, -1, -1, -1, 60, 60, 55, 100, 65, -1, 75, 75, 100, 100, 100, -1}
 )
 
 type tracklistRowBase struct {
@@ -99,19 +108,22 @@ type tracklistRowBase struct {
 	isFavorite bool
 	playCount  int
 
-	num      *widget.Label
-	name     *widget.RichText // for bold support
-	artist   *MultiHyperlink
-	album    *MultiHyperlink // for disabled support, if albumID is ""
-	dur      *widget.Label
-	year     *widget.Label
-	favorite *fyne.Container
-	rating   *StarRating
-	bitrate  *widget.Label
-	plays    *widget.Label
-	comment  *widget.Label
-	size     *widget.Label
-	path     *widget.Label
+	num       *widget.Label
+	name      *widget.RichText // for bold support
+	artist    *MultiHyperlink
+	album     *MultiHyperlink // for disabled support, if albumID is ""
+	dur       *widget.Label
+	year      *widget.Label
+	favorite  *fyne.Container
+	rating    *StarRating
+	bitrate   *widget.Label
+	plays     *widget.Label
+	comment   *widget.Label
+	size      *widget.Label
+	added     *widget.Label
+	played    *widget.Label
+	favorited *widget.Label
+	path      *widget.Label
 
 	// must be injected by extending widget
 	setColVisibility func(int, bool) bool
@@ -131,6 +143,7 @@ type ExpandedTracklistRow struct {
 	tracklistRowBase
 
 	img         *ImagePlaceholder
+	im          *backend.ImageManager
 	imageLoader util.ThumbnailLoader
 }
 
@@ -144,7 +157,7 @@ var (
 )
 
 func NewExpandedTracklistRow(tracklist *Tracklist, im *backend.ImageManager, playingIcon fyne.CanvasObject) *ExpandedTracklistRow {
-	t := &ExpandedTracklistRow{}
+	t := &ExpandedTracklistRow{im: im}
 	t.ExtendBaseWidget(t)
 	t.tracklistRowBase.create(tracklist)
 	t.playingIcon = playingIcon
@@ -165,7 +178,7 @@ func NewExpandedTracklistRow(tracklist *Tracklist, im *backend.ImageManager, pla
 
 	v := makeVerticallyCentered // func alias
 	container := container.New(tracklist.colLayout,
-		v(t.num), titleArtistImg, v(t.album), v(t.dur), v(t.year), v(t.favorite), v(t.rating), v(t.plays), v(t.comment), v(t.bitrate), v(t.size), v(t.path))
+		v(t.num), titleArtistImg, v(t.album), v(t.dur), v(t.year), v(t.favorite), v(t.rating), v(t.plays), v(t.comment), v(t.bitrate), v(t.size), v(t.added), v(t.played), v(t.favorited), v(t.path))
 	t.Content = container
 	t.setColVisibility = func(colNum int, vis bool) bool {
 		c := container.Objects[colNum].(*fyne.Container)
@@ -178,7 +191,7 @@ func NewExpandedTracklistRow(tracklist *Tracklist, im *backend.ImageManager, pla
 
 func (t *ExpandedTracklistRow) Update(tm *util.TrackListModel, rowNum int) {
 	if t.trackID != tm.Track().ID {
-		t.imageLoader.Load(tm.Track().CoverArtID)
+		loadCoverWithFallback(&t.imageLoader, t.im, backend.CoverFallbackForItem(tm.Track()))
 	}
 	t.tracklistRowBase.Update(tm, rowNum)
 }
@@ -191,7 +204,7 @@ func NewCompactTracklistRow(tracklist *Tracklist, playingIcon fyne.CanvasObject)
 	t.playingIcon = playingIcon
 
 	t.Content = container.New(tracklist.colLayout,
-		t.num, t.name, t.artist, t.album, t.dur, t.year, t.favorite, t.rating, t.plays, t.comment, t.bitrate, t.size, t.path)
+		t.num, t.name, t.artist, t.album, t.dur, t.year, t.favorite, t.rating, t.plays, t.comment, t.bitrate, t.size, t.added, t.played, t.favorited, t.path)
 
 	colHiddenPtrMap := map[int]*bool{
 		2:  &t.artist.Hidden,
@@ -204,7 +217,10 @@ func NewCompactTracklistRow(tracklist *Tracklist, playingIcon fyne.CanvasObject)
 		9:  &t.comment.Hidden,
 		10: &t.bitrate.Hidden,
 		11: &t.size.Hidden,
-		12: &t.path.Hidden,
+		12: &t.added.Hidden,
+		13: &t.played.Hidden,
+		14: &t.favorited.Hidden,
+		15: &t.path.Hidden,
 	}
 	t.setColVisibility = func(colNum int, vis bool) bool {
 		ptr, ok := colHiddenPtrMap[colNum]
@@ -239,6 +255,9 @@ func (t *tracklistRowBase) create(tracklist *Tracklist) {
 	t.comment = util.NewTruncatingLabel()
 	t.bitrate = util.NewTrailingAlignLabel()
 	t.size = util.NewTrailingAlignLabel()
+	t.added = util.NewTrailingAlignLabel()
+	t.played = util.NewTrailingAlignLabel()
+	t.favorited = util.NewTrailingAlignLabel()
 	t.path = util.NewTruncatingLabel()
 }
 
@@ -272,6 +291,9 @@ func (t *tracklistRowBase) Update(tm *util.TrackListModel, rowNum int) {
 		t.comment.Text = tr.Comment
 		t.bitrate.Text = strconv.Itoa(tr.BitRate)
 		t.size.Text = util.BytesToSizeString(tr.Size)
+		t.added.Text = util.DateString(tr.CreatedAt)
+		t.played.Text = util.DateString(tr.LastPlayed)
+		t.favorited.Text = util.DateString(tr.FavoritedAt)
 		t.path.Text = tr.FilePath
 		changed = true
 	}